@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LastResultReference in a chat message attaches the last query result to it
+const LastResultReference = "#last"
+
+// Limits on what a result attachment may add to a message
+const (
+	DefaultAttachmentRows = 5
+	MaxAttachmentRows     = 20
+	maxAttachmentCell     = 60
+	maxAttachmentChars    = 4000
+)
+
+const redactedValue = "[redacted]"
+
+// sensitiveColumnPattern matches column names whose values never leave the terminal
+var sensitiveColumnPattern = regexp.MustCompile(`(?i)(passw|pwd|secret|token|api_?key|salt|hash|ssn|social_security|credit_?card|card_?num|cvv|iban|email|phone|mobile)`)
+
+// sensitiveValuePatterns match values that look personal regardless of column name
+var sensitiveValuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), // Email address
+	regexp.MustCompile(`\b\d{4}[ -]\d{4}[ -]\d{4}[ -]\d{1,7}\b`),         // Grouped card numbers
+}
+
+// ResultAttachment is the shape of a query result offered to the AI as context
+type ResultAttachment struct {
+	Query     string
+	Columns   []string
+	Types     []string
+	Rows      [][]string
+	TotalRows int // Rows the query returned, which may exceed len(Rows)
+}
+
+// Format renders the attachment as a compact markdown block for a chat message.
+// Sensitive columns and values are redacted, long cells are shortened and at most
+// maxRows rows are included, fewer if the block would grow past the size limit.
+func (r *ResultAttachment) Format(maxRows int) string {
+	if maxRows <= 0 {
+		maxRows = DefaultAttachmentRows
+	}
+	maxRows = min(maxRows, MaxAttachmentRows, len(r.Rows))
+
+	redactColumn := make([]bool, len(r.Columns))
+	for i, col := range r.Columns {
+		redactColumn[i] = sensitiveColumnPattern.MatchString(col)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Result of the previous query (%s):\n", LastResultReference))
+	sb.WriteString("```sql\n" + strings.TrimSpace(r.Query) + "\n```\n")
+
+	header := make([]string, len(r.Columns))
+	for i, col := range r.Columns {
+		header[i] = col
+		if i < len(r.Types) && r.Types[i] != "" {
+			header[i] += " (" + strings.ToLower(r.Types[i]) + ")"
+		}
+	}
+	sb.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	sb.WriteString("|" + strings.Repeat("---|", len(r.Columns)) + "\n")
+
+	shown := 0
+	for _, row := range r.Rows[:maxRows] {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = attachmentCell(cell, i < len(redactColumn) && redactColumn[i])
+		}
+		line := "| " + strings.Join(cells, " | ") + " |\n"
+		if sb.Len()+len(line) > maxAttachmentChars {
+			break
+		}
+		sb.WriteString(line)
+		shown++
+	}
+
+	sb.WriteString(fmt.Sprintf("(%d of %d rows shown", shown, r.TotalRows))
+	if shown < r.TotalRows {
+		sb.WriteString("; the query returned more rows")
+	}
+	sb.WriteString(")\n")
+	return sb.String()
+}
+
+// attachmentCell redacts and shortens one value for the attachment table
+func attachmentCell(value string, redact bool) string {
+	if redact && value != "" {
+		return redactedValue
+	}
+	for _, pattern := range sensitiveValuePatterns {
+		value = pattern.ReplaceAllString(value, redactedValue)
+	}
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > maxAttachmentCell {
+		value = string(runes[:maxAttachmentCell-3]) + "..."
+	}
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+// AttachToMessage appends a formatted attachment to a user message
+func AttachToMessage(message, attachment string) string {
+	return message + "\n\n" + attachment
+}
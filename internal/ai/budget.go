@@ -0,0 +1,246 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"sqlterm/internal/config"
+)
+
+// Default context windows when the model does not report one. Local servers load
+// models with small windows unless configured otherwise.
+const (
+	defaultOllamaContextWindow   = 4096
+	defaultLMStudioContextWindow = 4096
+)
+
+// conversationMaxTokens is the answer length requested by conversational calls
+const conversationMaxTokens = 4000
+
+// contextWarningRatio is the share of the prompt budget above which usage is reported
+const contextWarningRatio = 0.8
+
+// sectionKind orders what the budgeter may drop: samples first, then whole tables
+type sectionKind int
+
+const (
+	sectionFixed  sectionKind = iota // Instructions and the request, always kept
+	sectionTable                     // One table's schema
+	sectionSample                    // Sample data or inferred structure belonging to a table
+)
+
+// promptSection is a piece of a system prompt the budgeter can keep or drop
+type promptSection struct {
+	kind      sectionKind
+	table     string
+	relevance float64
+	text      string
+}
+
+func fixedSection(text string) promptSection {
+	return promptSection{kind: sectionFixed, text: text}
+}
+
+// PromptBudget reports how much of the model's context window a prompt used
+type PromptBudget struct {
+	Tokens         int      // Estimated prompt tokens, including the user message
+	Requested      int      // Estimated tokens before anything was dropped
+	Limit          int      // Tokens available to the prompt; 0 when the window is unknown
+	DroppedSamples int      // Sample sections removed to fit
+	DroppedTables  []string // Tables removed to fit, least relevant first
+}
+
+// Trimmed reports whether context was dropped to fit the window
+func (b PromptBudget) Trimmed() bool {
+	return b.DroppedSamples > 0 || len(b.DroppedTables) > 0
+}
+
+// Usage returns the share of the limit used, or 0 when the limit is unknown
+func (b PromptBudget) Usage() float64 {
+	if b.Limit <= 0 {
+		return 0
+	}
+	return float64(b.Tokens) / float64(b.Limit)
+}
+
+// NearLimit reports whether the prompt is close enough to the limit to warn about
+func (b PromptBudget) NearLimit() bool {
+	return b.Usage() >= contextWarningRatio
+}
+
+// charsPerToken approximates tokenizer density for Latin text. OpenAI-style BPE
+// vocabularies used behind OpenRouter pack about four characters per token; the
+// SentencePiece vocabularies of common local models are a little less dense.
+func charsPerToken(provider config.Provider) float64 {
+	switch provider {
+	case config.ProviderOllama, config.ProviderLMStudio:
+		return 3.5
+	default:
+		return 4
+	}
+}
+
+// EstimateTokens approximates how many tokens text takes for a provider's models.
+// CJK characters are counted as a token each since tokenizers rarely merge them.
+func EstimateTokens(text string, provider config.Provider) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			wide++
+		} else {
+			other++
+		}
+	}
+	return wide + int(math.Ceil(float64(other)/charsPerToken(provider)))
+}
+
+// fitPrompt joins sections in order, dropping sample sections and then the least
+// relevant tables until the estimate fits within limit. A limit of 0 keeps everything.
+func fitPrompt(sections []promptSection, limit, reserved int, count func(string) int) (string, PromptBudget) {
+	budget := PromptBudget{Limit: limit}
+	tokens := make([]int, len(sections))
+	total := reserved
+	for i, s := range sections {
+		tokens[i] = count(s.text)
+		total += tokens[i]
+	}
+	budget.Requested = total
+
+	dropped := make([]bool, len(sections))
+	drop := func(kind sectionKind) {
+		order := make([]int, 0, len(sections))
+		for i, s := range sections {
+			if s.kind == kind && !dropped[i] {
+				order = append(order, i)
+			}
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return sections[order[a]].relevance < sections[order[b]].relevance
+		})
+		for _, i := range order {
+			if total <= limit {
+				return
+			}
+			dropped[i] = true
+			total -= tokens[i]
+			if kind == sectionSample {
+				budget.DroppedSamples++
+				continue
+			}
+			budget.DroppedTables = append(budget.DroppedTables, sections[i].table)
+			// A dropped table takes its samples with it
+			for j, s := range sections {
+				if s.kind == sectionSample && s.table == sections[i].table && !dropped[j] {
+					dropped[j] = true
+					total -= tokens[j]
+				}
+			}
+		}
+	}
+	if limit > 0 && total > limit {
+		drop(sectionSample)
+		drop(sectionTable)
+	}
+
+	var sb strings.Builder
+	for i, s := range sections {
+		if !dropped[i] {
+			sb.WriteString(s.text)
+		}
+	}
+	budget.Tokens = total
+	return sb.String(), budget
+}
+
+// contextWindow returns the current model's context window in tokens, or 0 when unknown.
+// The configured value wins, then what the provider reports, then provider defaults.
+func (m *Manager) contextWindow() int {
+	if m.config.AI.ContextWindow > 0 {
+		return m.config.AI.ContextWindow
+	}
+
+	window := 0
+	if info := m.modelInfo(m.config.AI.Model); info != nil {
+		window = info.ContextLength
+	}
+	switch m.config.AI.Provider {
+	case config.ProviderOllama:
+		// Ollama reports the length a model was trained on, but loads it with a smaller
+		// window unless num_ctx is raised on the server
+		if window == 0 || window > defaultOllamaContextWindow {
+			window = defaultOllamaContextWindow
+		}
+	case config.ProviderLMStudio:
+		if window == 0 {
+			window = defaultLMStudioContextWindow
+		}
+	}
+	return window
+}
+
+// modelInfo returns what the current provider reports about a model, looked up once
+// per model. It returns nil when the provider does not list the model.
+func (m *Manager) modelInfo(model string) *ModelInfo {
+	if info, ok := m.modelInfos[model]; ok {
+		return info
+	}
+
+	var info *ModelInfo
+	if m.client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if found, err := m.client.GetModelInfo(ctx, model); err == nil {
+			info = found
+		}
+		cancel()
+	}
+
+	if m.modelInfos == nil {
+		m.modelInfos = make(map[string]*ModelInfo)
+	}
+	m.modelInfos[model] = info
+	return info
+}
+
+// ContextShortfall compares the last conversational prompt, before any trimming, with
+// what the current model can take. short is false when it fits or nothing was asked yet.
+func (m *Manager) ContextShortfall() (needed, limit int, short bool) {
+	needed = m.lastBudget.Requested
+	limit = m.promptLimit(conversationMaxTokens)
+	return needed, limit, needed > 0 && limit > 0 && needed > limit
+}
+
+// promptLimit returns the tokens available to the system prompt and user message,
+// keeping room for the answer
+func (m *Manager) promptLimit(maxTokens int) int {
+	window := m.contextWindow()
+	if window <= 0 {
+		return 0
+	}
+	return window - min(maxTokens, window/4)
+}
+
+// countTokens estimates tokens for the configured provider
+func (m *Manager) countTokens(text string) int {
+	return EstimateTokens(text, m.config.AI.Provider)
+}
+
+// LastPromptBudget returns the context usage of the most recent conversational prompt
+func (m *Manager) LastPromptBudget() PromptBudget {
+	return m.lastBudget
+}
+
+// reportPromptBudget warns when context was trimmed to fit or the prompt is close to the limit
+func (m *Manager) reportPromptBudget(budget PromptBudget) {
+	switch {
+	case budget.Trimmed():
+		fmt.Printf(m.i18nMgr.Get("ai_context_trimmed"), budget.Tokens, budget.Limit, budget.Usage()*100,
+			budget.DroppedSamples, len(budget.DroppedTables), strings.Join(budget.DroppedTables, ", "))
+	case budget.NearLimit():
+		fmt.Printf(m.i18nMgr.Get("ai_context_usage_high"), budget.Tokens, budget.Limit, budget.Usage()*100)
+	}
+}
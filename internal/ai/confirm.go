@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"slices"
+
+	"sqlterm/internal/config"
+)
+
+// estimatedAnswerTokens is the length of a typical conversational answer, used to price
+// a message before it is sent
+const estimatedAnswerTokens = 500
+
+// SendEstimate is the expected size and price of a chat message on the current provider
+type SendEstimate struct {
+	Provider     config.Provider
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	Cost         float64
+}
+
+// EstimateChat prices a chat message before it is sent. The system prompt is not built
+// yet, so the previous turn's prompt stands in for it, or the table list when the
+// conversation is new.
+func (m *Manager) EstimateChat(userMessage string, allTables []string) SendEstimate {
+	input := m.countTokens(userMessage)
+	if m.conversationCtx != nil && len(m.conversationCtx.ConversationHistory) > 0 {
+		history := m.conversationCtx.ConversationHistory
+		input += m.countTokens(history[len(history)-1].SystemPrompt)
+	} else {
+		for _, table := range allTables {
+			input += m.countTokens(table + ", ")
+		}
+	}
+
+	provider, model := m.config.AI.Provider, m.config.AI.Model
+	return SendEstimate{
+		Provider:     provider,
+		Model:        model,
+		InputTokens:  input,
+		OutputTokens: estimatedAnswerTokens,
+		Cost:         m.calculateCost(provider, model, input, estimatedAnswerTokens),
+	}
+}
+
+// NeedsSendConfirmation reports whether chat messages to the current provider should be
+// confirmed before they are sent
+func (m *Manager) NeedsSendConfirmation() bool {
+	return m.config.ConfirmsSend(m.config.AI.Provider)
+}
+
+// SetSendConfirmation turns confirmation of chat messages on or off for a provider
+func (m *Manager) SetSendConfirmation(provider config.Provider, enabled bool) error {
+	confirm := slices.DeleteFunc(slices.Clone(m.config.AI.ConfirmSend), func(p config.Provider) bool {
+		return p == provider
+	})
+	if enabled {
+		confirm = append(confirm, provider)
+	}
+	m.config.AI.ConfirmSend = confirm
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
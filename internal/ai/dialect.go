@@ -0,0 +1,60 @@
+package ai
+
+import "fmt"
+
+// dialectNames are the display names of the database types
+var dialectNames = map[string]string{
+	"mysql":      "MySQL",
+	"postgres":   "PostgreSQL",
+	"sqlite":     "SQLite",
+	"duckdb":     "DuckDB",
+	"clickhouse": "ClickHouse",
+	"mariadb":    "MariaDB",
+	"tidb":       "TiDB",
+}
+
+// dialectHints steer models away from the constructs they most often get wrong per dialect
+var dialectHints = map[string]string{
+	"mysql": "Quote identifiers with backticks, page with LIMIT n, and use MySQL functions such as " +
+		"IFNULL, DATE_FORMAT and GROUP_CONCAT. There is no ILIKE, FULL OUTER JOIN or :: cast.",
+	"postgres": "Quote identifiers with double quotes, page with LIMIT n OFFSET m, and use PostgreSQL " +
+		"functions such as COALESCE, DATE_TRUNC, TO_CHAR and STRING_AGG. Backticks are not valid.",
+	"sqlite": "Quote identifiers with double quotes, page with LIMIT n OFFSET m, and use SQLite " +
+		"functions such as datetime('now'), strftime and group_concat. There is no ILIKE, NOW(), " +
+		"DATE_TRUNC or :: cast.",
+	"duckdb": "Quote identifiers with double quotes, page with LIMIT n OFFSET m, and use DuckDB " +
+		"functions such as date_trunc, strftime and string_agg. Files can be queried directly, " +
+		"e.g. FROM 'data.parquet'.",
+	"clickhouse": "Quote identifiers with backticks or double quotes, always add a LIMIT, and use " +
+		"ClickHouse functions such as toStartOfDay, formatDateTime, ifNull and groupArray. There " +
+		"are no transactions or RETURNING, and rows are changed with ALTER TABLE ... UPDATE.",
+	"mariadb": "Quote identifiers with backticks, page with LIMIT n, and use MariaDB functions " +
+		"such as IFNULL, DATE_FORMAT and GROUP_CONCAT. Read JSON with JSON_VALUE or JSON_EXTRACT, " +
+		"not the -> and ->> operators. Sequences use NEXT VALUE FOR seq, and INSERT and DELETE " +
+		"accept RETURNING. There is no ILIKE, FULL OUTER JOIN or :: cast.",
+	"tidb": "Quote identifiers with backticks, page with LIMIT n, and write MySQL 8 compatible " +
+		"SQL. Prefer AUTO_RANDOM to AUTO_INCREMENT for new primary keys; stored procedures, " +
+		"triggers and events are not supported, and CHECK constraints are ignored by default. " +
+		"There is no ILIKE, FULL OUTER JOIN or :: cast.",
+}
+
+// SetDialect records the dialect name, a database type or server flavor such as
+// mariadb, and the server version of the active connection
+func (m *Manager) SetDialect(dialect, serverVersion string) {
+	m.dialect = dialect
+	m.serverVersion = serverVersion
+}
+
+// dialectInstruction tells the model which database the SQL is for, or nothing when
+// no connection is open
+func (m *Manager) dialectInstruction() string {
+	name, ok := dialectNames[m.dialect]
+	if !ok {
+		return ""
+	}
+	target := name
+	if m.serverVersion != "" {
+		target += " " + m.serverVersion
+	}
+	return fmt.Sprintf("\nThe database is %s. Write SQL for this dialect only. %s\n", target, dialectHints[m.dialect])
+}
@@ -0,0 +1,133 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Few-shot retrieval limits
+const (
+	maxFewShotExamples   = 3
+	minExampleSimilarity = 0.3
+)
+
+// QueryExample is a question and the SQL that answered it, kept once the user
+// accepted the SQL by running it successfully or with /ai good
+type QueryExample struct {
+	Question   string
+	SQL        string
+	UseCount   int
+	Similarity float64
+}
+
+// generatedQuery is a query the model wrote, kept to recognise it when it is run
+type generatedQuery struct {
+	normalized string
+	question   string
+}
+
+// AddQueryExample stores an accepted question and SQL pair, counting repeats
+func (vs *VectorStore) AddQueryExample(question, sql string) error {
+	embeddingJSON, _ := json.Marshal(vs.generateEmbedding(question))
+	now := time.Now()
+	_, err := vs.db.Exec(`INSERT INTO query_examples (question, sql_text, embedding, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(question, sql_text) DO UPDATE SET use_count = use_count + 1, updated_at = excluded.updated_at`,
+		question, sql, string(embeddingJSON), now, now)
+	return err
+}
+
+// SimilarExamples returns the stored examples whose questions are most similar to question
+func (vs *VectorStore) SimilarExamples(question string, limit int) ([]QueryExample, error) {
+	rows, err := vs.db.Query(`SELECT question, sql_text, embedding, use_count FROM query_examples`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query examples: %w", err)
+	}
+	defer rows.Close()
+
+	queryEmbedding := vs.generateEmbedding(question)
+	var examples []QueryExample
+	for rows.Next() {
+		var example QueryExample
+		var embeddingJSON string
+		if err := rows.Scan(&example.Question, &example.SQL, &embeddingJSON, &example.UseCount); err != nil {
+			continue
+		}
+		var embedding []float64
+		json.Unmarshal([]byte(embeddingJSON), &embedding)
+		example.Similarity = vs.cosineSimilarity(queryEmbedding, embedding)
+		if example.Similarity >= minExampleSimilarity {
+			examples = append(examples, example)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Prefer similar questions, then examples accepted more often
+	sort.SliceStable(examples, func(i, j int) bool {
+		if examples[i].Similarity != examples[j].Similarity {
+			return examples[i].Similarity > examples[j].Similarity
+		}
+		return examples[i].UseCount > examples[j].UseCount
+	})
+	if limit > 0 && len(examples) > limit {
+		examples = examples[:limit]
+	}
+	return examples, nil
+}
+
+// AcceptQuery records a generated query that ran successfully as a few-shot example
+// for its question. Queries the model did not write are ignored.
+func (m *Manager) AcceptQuery(query string) {
+	normalized := normalizeQuery(query)
+	for _, generated := range m.generatedSQL {
+		if generated.normalized == normalized {
+			if m.storeExample(generated.question, strings.TrimSpace(query)) && m.conversationCtx != nil {
+				if tables := rankLoadedTables(m.conversationCtx); len(tables) > 0 {
+					m.store().RecordPatternFeedback(generated.question, tables, true)
+				}
+			}
+			return
+		}
+	}
+}
+
+// storeExample saves a question and SQL pair as a few-shot example
+func (m *Manager) storeExample(question, sql string) bool {
+	vectorStore := m.store()
+	if vectorStore == nil || strings.TrimSpace(question) == "" {
+		return false
+	}
+	if err := vectorStore.AddQueryExample(question, sql); err != nil {
+		fmt.Printf(m.i18nMgr.Get("failed_record_example_warning"), err)
+		return false
+	}
+	return true
+}
+
+// examplesSections returns earlier accepted queries similar to the request as
+// droppable prompt sections, most similar first
+func (m *Manager) examplesSections(question string) []promptSection {
+	vectorStore := m.store()
+	if vectorStore == nil || strings.TrimSpace(question) == "" {
+		return nil
+	}
+	examples, err := vectorStore.SimilarExamples(question, maxFewShotExamples)
+	if err != nil || len(examples) == 0 {
+		return nil
+	}
+
+	sections := []promptSection{fixedSection("\nQuestions on this database answered correctly before:\n")}
+	for _, example := range examples {
+		sections = append(sections, promptSection{
+			kind:      sectionSample,
+			relevance: example.Similarity,
+			text:      fmt.Sprintf("Q: %s\n```sql\n%s\n```\n", example.Question, example.SQL),
+		})
+	}
+	return sections
+}
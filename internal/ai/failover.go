@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"sqlterm/internal/config"
+)
+
+// chatResult is a chat response with the provider and model that produced it
+type chatResult struct {
+	*ChatResponse
+	provider config.Provider
+	model    string
+	latency  time.Duration
+}
+
+// chatTarget is one provider and model in the failover chain
+type chatTarget struct {
+	provider config.Provider
+	model    string
+}
+
+// failoverChain returns the configured provider followed by its fallbacks, without repeats
+func (m *Manager) failoverChain() []chatTarget {
+	chain := []chatTarget{{provider: m.config.AI.Provider, model: m.config.AI.Model}}
+	for _, fallback := range m.config.AI.Fallbacks {
+		target := chatTarget{provider: fallback.Provider, model: m.config.FallbackModel(fallback)}
+		if target.model != "" && !containsTarget(chain, target) {
+			chain = append(chain, target)
+		}
+	}
+	return chain
+}
+
+func containsTarget(chain []chatTarget, target chatTarget) bool {
+	for _, existing := range chain {
+		if existing == target {
+			return true
+		}
+	}
+	return false
+}
+
+// send runs a chat request on the configured provider and, when it fails or its paid
+// budget for the day is spent, on each fallback in turn. A notice names the provider
+// that answered whenever it was not the first.
+func (m *Manager) send(ctx context.Context, request ChatRequest) (*chatResult, error) {
+	var failures []string
+	var lastErr error
+	for i, target := range m.failoverChain() {
+		if m.overBudget(target.provider) {
+			failures = append(failures, fmt.Sprintf(m.i18nMgr.Get("ai_failover_over_budget"), target.provider, m.config.AI.DailyBudget))
+			lastErr = fmt.Errorf(m.i18nMgr.Get("ai_daily_budget_reached"), m.config.AI.DailyBudget)
+			continue
+		}
+
+		client := m.client
+		if i > 0 || client == nil {
+			var err error
+			if client, err = m.newClient(target.provider); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", target.provider, err))
+				lastErr = err
+				continue
+			}
+			defer client.Close()
+		}
+
+		request.Model = target.model
+		start := time.Now()
+		response, err := m.chatOnce(ctx, client, request)
+		if err == nil && len(response.Choices) == 0 {
+			err = errors.New(m.i18nMgr.Get("no_response_choices_returned"))
+		}
+		if err == nil {
+			if len(failures) > 0 {
+				fmt.Printf(m.i18nMgr.Get("ai_failover_answered"), target.provider, target.model, strings.Join(failures, "; "))
+			}
+			return &chatResult{ChatResponse: response, provider: target.provider, model: target.model, latency: time.Since(start)}, nil
+		}
+
+		lastErr = err
+		if ctx.Err() != nil {
+			// No time left for the rest of the chain
+			break
+		}
+		failures = append(failures, fmt.Sprintf("%s/%s: %v", target.provider, target.model, err))
+	}
+	if lastErr == nil {
+		lastErr = errors.New(m.i18nMgr.Get("ai_client_not_configured"))
+	}
+	return nil, lastErr
+}
+
+// chatOnce sends a request to one client, retrying without tools when the model
+// does not support tool calling
+func (m *Manager) chatOnce(ctx context.Context, client Client, request ChatRequest) (*ChatResponse, error) {
+	response, err := client.Chat(ctx, request)
+	if err != nil && len(request.Tools) > 0 && isToolsUnsupported(err) {
+		// The model has no tool calling; the prompt already explains the JSON block
+		if m.noToolModels == nil {
+			m.noToolModels = make(map[string]bool)
+		}
+		m.noToolModels[request.Model] = true
+		request.Tools = nil
+		response, err = client.Chat(ctx, request)
+	}
+	return response, err
+}
+
+// overBudget reports whether today's spend has reached the daily budget for a paid provider
+func (m *Manager) overBudget(provider config.Provider) bool {
+	if m.config.AI.DailyBudget <= 0 || !provider.IsPaid() {
+		return false
+	}
+	usageStore := m.usage()
+	if usageStore == nil {
+		return false
+	}
+	spent, err := usageStore.TodayCost()
+	return err == nil && spent >= m.config.AI.DailyBudget
+}
+
+// SetFallbacks replaces the failover chain tried after the configured provider
+func (m *Manager) SetFallbacks(fallbacks []config.FallbackConfig) error {
+	m.config.AI.Fallbacks = fallbacks
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
+// SetDailyBudget sets the daily spend on paid providers; 0 removes the limit
+func (m *Manager) SetDailyBudget(budget float64) error {
+	m.config.AI.DailyBudget = budget
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
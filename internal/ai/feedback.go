@@ -0,0 +1,115 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Rating is the user's feedback on an AI response
+type Rating int
+
+const (
+	RatingNone Rating = 0
+	RatingGood Rating = 1
+	RatingBad  Rating = -1
+)
+
+// ErrNothingToRate is returned when no AI response has been received yet
+var ErrNothingToRate = errors.New("no AI response to rate")
+
+// feedbackFloor is the weight kept by a table whose patterns were all rated bad
+const feedbackFloor = 0.5
+
+// RecordPatternFeedback stores a question with the tables used to answer it and
+// whether the answer was good, for weighting later table searches
+func (vs *VectorStore) RecordPatternFeedback(question string, tables []string, good bool) error {
+	embeddingJSON, _ := json.Marshal(vs.generateEmbedding(question))
+	tablesJSON, _ := json.Marshal(tables)
+	successRate := 0.0
+	if good {
+		successRate = 1.0
+	}
+
+	now := time.Now()
+	_, err := vs.db.Exec(`INSERT INTO query_patterns (query_text, tables, embedding, success_rate, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, question, string(tablesJSON), string(embeddingJSON), successRate, now, now)
+	return err
+}
+
+// RemoveQueryExample forgets an example, used when its answer was rated bad
+func (vs *VectorStore) RemoveQueryExample(question, sql string) error {
+	_, err := vs.db.Exec(`DELETE FROM query_examples WHERE question = ? AND sql_text = ?`, question, sql)
+	return err
+}
+
+// tableFeedbackWeights returns a weight per table from the ratings of patterns similar
+// to the query: 1 for tables only seen in good answers, down to feedbackFloor for
+// tables only seen in bad ones. Tables without similar patterns are not listed.
+func (vs *VectorStore) tableFeedbackWeights(queryEmbedding []float64) (map[string]float64, error) {
+	rows, err := vs.db.Query(`SELECT tables, embedding, success_rate FROM query_patterns`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for rows.Next() {
+		var tablesJSON, embeddingJSON string
+		var successRate float64
+		if err := rows.Scan(&tablesJSON, &embeddingJSON, &successRate); err != nil {
+			continue
+		}
+		var embedding []float64
+		json.Unmarshal([]byte(embeddingJSON), &embedding)
+		if vs.cosineSimilarity(queryEmbedding, embedding) < minExampleSimilarity {
+			continue
+		}
+		var tables []string
+		json.Unmarshal([]byte(tablesJSON), &tables)
+		for _, table := range tables {
+			sums[table] += successRate
+			counts[table]++
+		}
+	}
+
+	weights := make(map[string]float64, len(sums))
+	for table, sum := range sums {
+		weights[table] = feedbackFloor + (1-feedbackFloor)*sum/float64(counts[table])
+	}
+	return weights, rows.Err()
+}
+
+// RateLastResponse records feedback on the last AI response. The rating is kept in the
+// prompt history and with the question's tables; a good answer's SQL becomes a few-shot
+// example and a bad answer's SQL is removed from them. It returns the number of
+// queries the rating applied to.
+func (m *Manager) RateLastResponse(rating Rating) (int, error) {
+	if m.promptHistory == nil || len(m.promptHistory.Entries) == 0 {
+		return 0, ErrNothingToRate
+	}
+	entry := &m.promptHistory.Entries[len(m.promptHistory.Entries)-1]
+	entry.Rating = rating
+
+	vectorStore := m.store()
+	if vectorStore == nil || m.conversationCtx == nil {
+		return 0, nil
+	}
+	question := m.conversationCtx.OriginalQuery
+	if tables := rankLoadedTables(m.conversationCtx); len(tables) > 0 {
+		vectorStore.RecordPatternFeedback(question, tables, rating == RatingGood)
+	}
+
+	applied := 0
+	for _, query := range ExtractSQL(entry.AIResponse) {
+		if rating == RatingGood {
+			if m.storeExample(question, query) {
+				applied++
+			}
+		} else if vectorStore.RemoveQueryExample(question, query) == nil {
+			applied++
+		}
+	}
+	return applied, nil
+}
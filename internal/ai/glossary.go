@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GlossaryFile is the per-connection glossary in the connection's session directory
+const GlossaryFile = "glossary.yaml"
+
+// GlossaryTerm maps a business term to how it is computed in this database
+type GlossaryTerm struct {
+	Term       string   `yaml:"term"`
+	Aliases    []string `yaml:"aliases,omitempty"`
+	Definition string   `yaml:"definition"`
+	Tables     []string `yaml:"tables,omitempty"` // Tables the definition uses
+}
+
+// Glossary is the set of business terms defined for a connection
+type Glossary struct {
+	Terms []GlossaryTerm `yaml:"terms"`
+}
+
+// LoadGlossary reads a glossary file. A missing file gives an empty glossary.
+func LoadGlossary(path string) (*Glossary, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Glossary{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var glossary Glossary
+	if err := yaml.Unmarshal(data, &glossary); err != nil {
+		return nil, err
+	}
+	for i, term := range glossary.Terms {
+		if strings.TrimSpace(term.Term) == "" || strings.TrimSpace(term.Definition) == "" {
+			return nil, fmt.Errorf("entry %d needs both a term and a definition", i+1)
+		}
+	}
+	return &glossary, nil
+}
+
+// Match returns the terms mentioned in text, by name or alias, ignoring case
+func (g *Glossary) Match(text string) []GlossaryTerm {
+	if g == nil {
+		return nil
+	}
+	lower := strings.ToLower(text)
+	var matched []GlossaryTerm
+	for _, term := range g.Terms {
+		for _, name := range append([]string{term.Term}, term.Aliases...) {
+			if mentions(lower, strings.ToLower(strings.TrimSpace(name))) {
+				matched = append(matched, term)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// mentions reports whether text contains name as whole words, allowing a plural s.
+// Names in scripts without spaces between words, like Chinese, match as substrings.
+func mentions(text, name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return strings.Contains(text, name)
+		}
+	}
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `s?\b`).MatchString(text)
+}
+
+// SetGlossary sets the business terms of the active connection
+func (m *Manager) SetGlossary(glossary *Glossary) {
+	m.glossary = glossary
+}
+
+// glossaryInstruction lists the definitions of the terms a request mentions
+func (m *Manager) glossaryInstruction(text string) string {
+	matched := m.glossary.Match(text)
+	if len(matched) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\nBusiness definitions for this database. Use them exactly rather than inventing your own:\n")
+	for _, term := range matched {
+		sb.WriteString(fmt.Sprintf("- %s: %s", term.Term, strings.TrimSpace(term.Definition)))
+		if len(term.Tables) > 0 {
+			sb.WriteString(fmt.Sprintf(" (tables: %s)", strings.Join(term.Tables, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,135 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"sqlterm/internal/config"
+)
+
+// healthTimeout bounds each request of a health probe, so a stopped service fails fast
+const healthTimeout = 3 * time.Second
+
+// ProviderHealth is the result of probing a local provider's endpoint
+type ProviderHealth struct {
+	Provider     config.Provider
+	BaseURL      string
+	Reachable    bool
+	Latency      time.Duration
+	Version      string   // Empty when the provider does not report one
+	LoadedModels []string // Models in memory and ready to answer
+	Installed    []string // Models available to load; Ollama only
+	Err          error
+}
+
+// IsLoaded reports whether a model is in memory on the provider
+func (h *ProviderHealth) IsLoaded(model string) bool {
+	return matchesModel(h.LoadedModels, model)
+}
+
+// HasModel reports whether a model is loaded or installed on the provider
+func (h *ProviderHealth) HasModel(model string) bool {
+	return h.IsLoaded(model) || matchesModel(h.Installed, model)
+}
+
+// matchesModel finds a model by name; Ollama reports llama3.2 as llama3.2:latest
+func matchesModel(models []string, model string) bool {
+	return slices.Contains(models, model) || slices.Contains(models, model+":latest")
+}
+
+// CheckProviderHealth probes the current provider's endpoint. It reports false for
+// cloud providers, which have no local service to check.
+func (m *Manager) CheckProviderHealth(ctx context.Context) (*ProviderHealth, bool) {
+	provider := m.config.AI.Provider
+	switch provider {
+	case config.ProviderOllama:
+		return probeOllama(ctx, NewOllamaClient(m.config.GetBaseURL(provider)).baseURL), true
+	case config.ProviderLMStudio:
+		return probeLMStudio(ctx, NewLMStudioClient(m.config.GetBaseURL(provider), m.i18nMgr).baseURL), true
+	default:
+		return nil, false
+	}
+}
+
+// probeOllama reads the server version, the models in memory and the installed models
+func probeOllama(ctx context.Context, baseURL string) *ProviderHealth {
+	health := &ProviderHealth{Provider: config.ProviderOllama, BaseURL: baseURL}
+	client := &http.Client{Timeout: healthTimeout}
+
+	var version struct {
+		Version string `json:"version"`
+	}
+	start := time.Now()
+	if health.Err = getJSON(ctx, client, baseURL+"/api/version", &version); health.Err != nil {
+		return health
+	}
+	health.Reachable = true
+	health.Latency = time.Since(start)
+	health.Version = version.Version
+
+	var running, installed struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := getJSON(ctx, client, baseURL+"/api/ps", &running); err == nil {
+		for _, model := range running.Models {
+			health.LoadedModels = append(health.LoadedModels, model.Name)
+		}
+	}
+	if err := getJSON(ctx, client, baseURL+"/api/tags", &installed); err == nil {
+		for _, model := range installed.Models {
+			health.Installed = append(health.Installed, model.Name)
+		}
+	}
+	return health
+}
+
+// probeLMStudio lists the loaded models; LM Studio's API does not report its version
+func probeLMStudio(ctx context.Context, baseURL string) *ProviderHealth {
+	health := &ProviderHealth{Provider: config.ProviderLMStudio, BaseURL: baseURL}
+	client := &http.Client{Timeout: healthTimeout}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	start := time.Now()
+	if health.Err = getJSON(ctx, client, baseURL+"/v1/models", &models); health.Err != nil {
+		return health
+	}
+	health.Reachable = true
+	health.Latency = time.Since(start)
+	for _, model := range models.Data {
+		health.LoadedModels = append(health.LoadedModels, model.ID)
+	}
+	return health
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// ActionNeedSchema is the structured action a model uses to ask for table schemas,
+// either as a JSON block in its answer or as a native tool call
+const ActionNeedSchema = "need_schema"
+
+// schemaActionAliases are other action names models produce for the same request
+var schemaActionAliases = []string{ActionNeedSchema, "request_schema", "get_schema", "schema"}
+
+// schemaRequestExample is shown to the model as the preferred way to ask for schemas
+const schemaRequestExample = "```json\n{\"action\": \"" + ActionNeedSchema + "\", \"tables\": [\"table1\", \"table2\"]}\n```"
+
+// needSchemaTool offers the schema request as a function to providers with native tool calling
+var needSchemaTool = Tool{
+	Type: "function",
+	Function: ToolFunction{
+		Name:        ActionNeedSchema,
+		Description: "Request the column, key and relationship details of database tables before writing SQL.",
+		Parameters: json.RawMessage(`{"type":"object","properties":{"tables":{"type":"array","items":{"type":"string"},` +
+			`"description":"Exact names of the tables to describe"}},"required":["tables"]}`),
+	},
+}
+
+// toolResponse is a structured action in a model response. Tables is kept raw since
+// models send both a list and a comma separated string.
+type toolResponse struct {
+	Action string          `json:"action"`
+	Tables json.RawMessage `json:"tables"`
+}
+
+var (
+	jsonBlockPattern  = regexp.MustCompile("(?s)```(?:json|JSON)?\\s*(\\{.*?\\})\\s*```")
+	inlineJSONPattern = regexp.MustCompile(`\{[^{}]*"action"[^{}]*\}`)
+	trailingComma     = regexp.MustCompile(`,\s*([\]}])`)
+
+	// Phrasings models use when they ignore the JSON format, matched case-insensitively
+	schemaRequestPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)I need (?:the )?(?:detailed )?schemas? (?:information )?for(?: (?:the )?related tables)?\s*:\s*([^\n]+)`),
+		regexp.MustCompile(`(?i)(?:please )?provide (?:the )?(?:detailed )?schemas? for\s*:\s*([^\n]+)`),
+		regexp.MustCompile(`(?i)need (?:the )?table structures? for\s*:\s*([^\n]+)`),
+	}
+
+	tableListSeparator = regexp.MustCompile(`\s*(?:,|;|\band\b)\s*`)
+)
+
+// parseSchemaRequest returns the tables a model response asks schemas for. A JSON
+// need_schema action is preferred; the fixed phrases are a fallback for models
+// that answer in prose.
+func parseSchemaRequest(response string) []string {
+	var candidates []string
+	for _, match := range jsonBlockPattern.FindAllStringSubmatch(response, -1) {
+		candidates = append(candidates, match[1])
+	}
+	candidates = append(candidates, inlineJSONPattern.FindAllString(response, -1)...)
+
+	for _, candidate := range candidates {
+		var tool toolResponse
+		if decodeLenient(candidate, &tool) != nil || !isSchemaAction(tool.Action) {
+			continue
+		}
+		if tables := parseTableArgument(tool.Tables); len(tables) > 0 {
+			return tables
+		}
+	}
+
+	for _, pattern := range schemaRequestPatterns {
+		if match := pattern.FindStringSubmatch(response); match != nil {
+			if tables := splitTableList(match[1]); len(tables) > 0 {
+				return tables
+			}
+		}
+	}
+	return nil
+}
+
+// toolCallsText renders native tool calls as the JSON blocks the text protocol uses,
+// so they are parsed and shown in history the same way
+func toolCallsText(calls []ToolCall) string {
+	var sb strings.Builder
+	for _, call := range calls {
+		var args map[string]any
+		if decodeLenient(call.Function.Arguments, &args) != nil {
+			args = map[string]any{}
+		}
+		args["action"] = call.Function.Name
+		data, err := json.Marshal(args)
+		if err != nil {
+			continue
+		}
+		sb.WriteString("```json\n" + string(data) + "\n```\n")
+	}
+	return sb.String()
+}
+
+// isToolsUnsupported reports whether a chat error means the model rejected the tools
+// parameter, so the request can be retried with the text protocol alone
+func isToolsUnsupported(err error) bool {
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, "tool") &&
+		(strings.Contains(message, "support") || strings.Contains(message, "status 400") || strings.Contains(message, "status 404"))
+}
+
+// decodeLenient unmarshals JSON after removing the trailing commas models often leave
+func decodeLenient(data string, v any) error {
+	if err := json.Unmarshal([]byte(data), v); err == nil {
+		return nil
+	}
+	return json.Unmarshal([]byte(trailingComma.ReplaceAllString(data, "$1")), v)
+}
+
+func isSchemaAction(action string) bool {
+	action = strings.ToLower(strings.TrimSpace(action))
+	for _, alias := range schemaActionAliases {
+		if action == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTableArgument accepts a list of names or a single separated string
+func parseTableArgument(raw json.RawMessage) []string {
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		var tables []string
+		for _, table := range list {
+			tables = append(tables, splitTableList(table)...)
+		}
+		return tables
+	}
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return splitTableList(text)
+	}
+	return nil
+}
+
+func splitTableList(text string) []string {
+	var tables []string
+	for _, part := range tableListSeparator.Split(text, -1) {
+		if table := cleanTableName(part); table != "" {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// cleanTableName strips markdown and punctuation models wrap table names in
+func cleanTableName(name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "*`'\"[]()<>.:")
+	return strings.TrimSpace(name)
+}
@@ -0,0 +1,52 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageNames maps interface language codes to the language named in prompts
+var languageNames = map[string]string{
+	"en_au": "English",
+	"zh_cn": "Simplified Chinese",
+}
+
+// LanguageName returns the prompt name for a language code. Unknown values are taken
+// to be a language name already, so /lang Japanese works without a translation file.
+func LanguageName(language string) string {
+	if name, ok := languageNames[strings.ToLower(language)]; ok {
+		return name
+	}
+	return language
+}
+
+// languageInstruction tells the model which language to answer in. English needs no
+// instruction, which keeps prompts for the default configuration unchanged.
+func languageInstruction(language string) string {
+	name := LanguageName(language)
+	if name == "" || name == "English" || strings.HasPrefix(strings.ToLower(language), "en") {
+		return ""
+	}
+	return fmt.Sprintf("\nRespond in %s. Keep SQL keywords, table names, column names and code blocks unchanged; "+
+		"only translate explanations.\n", name)
+}
+
+// ResponseLanguage returns the language AI answers are requested in: the /lang override
+// for the current conversation if set, otherwise the configured interface language
+func (m *Manager) ResponseLanguage() string {
+	if m.languageOverride != "" {
+		return m.languageOverride
+	}
+	return m.config.Language
+}
+
+// SetResponseLanguage overrides the answer language until the conversation is cleared.
+// An empty language removes the override.
+func (m *Manager) SetResponseLanguage(language string) {
+	m.languageOverride = language
+}
+
+// withLanguage appends the answer language instruction to a system prompt
+func (m *Manager) withLanguage(prompt string) string {
+	return prompt + languageInstruction(m.ResponseLanguage())
+}
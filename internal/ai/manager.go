@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"sqlterm/internal/config"
@@ -18,18 +20,32 @@ import (
 
 // Manager manages AI clients and configuration
 type Manager struct {
-	config          *config.Config
-	configDir       string
-	client          Client
-	promptHistory   *PromptHistory
-	recentTables    []string             // Session memory for recently mentioned tables
-	maxTables       int                  // Maximum tables to include in context
-	vectorStore     *VectorStore         // Vector database for semantic search
-	conversationCtx *ConversationContext // Current conversation context
-	i18nMgr         *i18n.Manager        // Internationalization manager
-	usageStore      *UsageStore          // Usage tracking store
-	sessionID       string               // Current session ID for usage tracking
-	idGen           *utils.IDGen
+	config           *config.Config
+	configDir        string
+	dataDir          string // Holds the sessions/ folders with the vector stores, see SetDataDir
+	client           Client
+	promptHistory    *PromptHistory
+	recentTables     []string             // Session memory for recently mentioned tables
+	maxTables        int                  // Maximum tables to include in context
+	vectorStore      *VectorStore         // Vector database for semantic search
+	conversationCtx  *ConversationContext // Current conversation context
+	i18nMgr          *i18n.Manager        // Internationalization manager
+	usageStore       *UsageStore          // Usage tracking store
+	sessionID        string               // Current session ID for usage tracking
+	idGen            *utils.IDGen
+	jsonStructures   map[string][]core.JSONField // Inferred JSON key paths keyed by table.column
+	languageOverride string                      // Answer language set with /lang for this conversation
+	modelInfos       map[string]*ModelInfo       // Provider details per model, looked up once
+	reindex          *reindexJob                 // Latest table embedding job for the connection
+	lastBudget       PromptBudget                // Context usage of the last conversational prompt
+	noToolModels     map[string]bool             // Models that rejected native tool calling
+	generatedSQL     []generatedQuery            // Queries the model wrote, newest last
+	dialect          string                      // Database type of the active connection
+	serverVersion    string                      // Server version of the active connection
+	glossary         *Glossary                   // Business terms of the active connection
+	sensitive        *core.SensitiveColumns      // Columns of the active connection kept out of sample data
+	pendingStore     *pendingVectorStore         // Connection whose vector store opens on first use
+	storeMu          sync.Mutex
 }
 
 // NewManager creates a new AI manager
@@ -47,6 +63,7 @@ func NewManager(configDir string) (*Manager, error) {
 	manager := &Manager{
 		config:    config,
 		configDir: configDir,
+		dataDir:   configDir,
 		promptHistory: &PromptHistory{
 			Entries: make([]PromptEntry, 0),
 			MaxSize: 100, // Keep last 100 prompts
@@ -58,13 +75,17 @@ func NewManager(configDir string) (*Manager, error) {
 	}
 	manager.sessionID = manager.generateSessionID()
 
-	// Try to initialize client, but don't fail if it's not possible
-	// This allows the manager to be created even if API keys aren't configured yet
-	_ = manager.initializeClient()
-
+	// The client is created on first use, so starting without API keys or a running
+	// local provider costs nothing until the AI is asked something
 	return manager, nil
 }
 
+// SetDataDir sets where session data is kept when it is apart from the config, see
+// config.ResolveDirs. It must be called before a connection is set.
+func (m *Manager) SetDataDir(dataDir string) {
+	m.dataDir = dataDir
+}
+
 // NewManagerWithValidation creates a new AI manager and requires valid client initialization
 func NewManagerWithValidation(configDir string) (*Manager, error) {
 	i18nMgr, config, err := config.LoadConfig(configDir)
@@ -75,6 +96,7 @@ func NewManagerWithValidation(configDir string) (*Manager, error) {
 	manager := &Manager{
 		config:    config,
 		configDir: configDir,
+		dataDir:   configDir,
 		promptHistory: &PromptHistory{
 			Entries: make([]PromptEntry, 0),
 			MaxSize: 100, // Keep last 100 prompts
@@ -95,29 +117,46 @@ func NewManagerWithValidation(configDir string) (*Manager, error) {
 
 // initializeClient initializes the appropriate client based on current provider
 func (m *Manager) initializeClient() error {
-	switch m.config.AI.Provider {
+	client, err := m.newClient(m.config.AI.Provider)
+	if err != nil {
+		return err
+	}
+	m.client = client
+	return nil
+}
+
+// newClient creates a client for a provider from its configured key or base URL
+func (m *Manager) newClient(provider config.Provider) (Client, error) {
+	switch provider {
 	case config.ProviderOpenRouter:
 		apiKey := m.config.GetAPIKey(config.ProviderOpenRouter)
 		if apiKey == "" {
-			return errors.New(m.i18nMgr.Get("openrouter_api_key_not_configured"))
+			return nil, errors.New(m.i18nMgr.Get("openrouter_api_key_not_configured"))
 		}
-		m.client = NewOpenRouterClient(apiKey)
+		return NewOpenRouterClient(apiKey), nil
 	case config.ProviderOllama:
 		baseURL := m.config.GetBaseURL(config.ProviderOllama)
-		m.client = NewOllamaClient(baseURL)
+		return NewOllamaClient(baseURL), nil
 	case config.ProviderLMStudio:
 		baseURL := m.config.GetBaseURL(config.ProviderLMStudio)
-		m.client = NewLMStudioClient(baseURL, m.i18nMgr)
+		return NewLMStudioClient(baseURL, m.i18nMgr), nil
 	default:
-		return fmt.Errorf(m.i18nMgr.Get("unsupported_provider"), m.config.AI.Provider)
+		return nil, fmt.Errorf(m.i18nMgr.Get("unsupported_provider"), provider)
 	}
+}
 
-	return nil
+// currentClient returns the client for the current provider, creating it on first use.
+// It returns nil when the provider is not configured.
+func (m *Manager) currentClient() Client {
+	if m.client == nil {
+		_ = m.initializeClient()
+	}
+	return m.client
 }
 
 // IsConfigured checks if the AI manager is properly configured and ready to use
 func (m *Manager) IsConfigured() bool {
-	return m.client != nil
+	return m.currentClient() != nil
 }
 
 // EnsureConfigured reinitializes the client if needed
@@ -154,29 +193,22 @@ func (m *Manager) Chat(ctx context.Context, message string, systemPrompt string)
 		MaxTokens:   4000,
 	}
 
-	response, err := m.client.Chat(ctx, request)
+	response, err := m.send(ctx, request)
 	if err != nil {
 		return "", fmt.Errorf(m.i18nMgr.Get("chat_request_failed"), err)
 	}
 
-	if len(response.Choices) == 0 {
-		return "", errors.New(m.i18nMgr.Get("no_response_choices_returned"))
-	}
-
-	// Calculate cost and update usage
-	cost := m.calculateCost(response.Usage.PromptTokens, response.Usage.CompletionTokens)
-
 	// Add to prompt history
 	aiResponse := response.Choices[0].Message.Content
-	m.addToPromptHistory(message, systemPrompt, aiResponse, response.Usage.PromptTokens, response.Usage.CompletionTokens, cost)
+	m.addToPromptHistory(message, systemPrompt, aiResponse, response)
 
 	return aiResponse, nil
 }
 
-// calculateCost calculates the cost based on token usage and current model
-func (m *Manager) calculateCost(inputTokens, outputTokens int) float64 {
+// calculateCost calculates the cost based on token usage and the model that answered
+func (m *Manager) calculateCost(provider config.Provider, model string, inputTokens, outputTokens int) float64 {
 	// Only calculate cost for OpenRouter (others are free/local)
-	if m.config.AI.Provider != config.ProviderOpenRouter {
+	if !provider.IsPaid() {
 		return 0.0
 	}
 
@@ -200,7 +232,7 @@ func (m *Manager) calculateCost(inputTokens, outputTokens int) float64 {
 		},
 	}
 
-	modelPricing, exists := pricing[m.config.AI.Model]
+	modelPricing, exists := pricing[model]
 	if !exists {
 		// Default pricing if model not found
 		return float64(inputTokens)*0.001/1000 + float64(outputTokens)*0.003/1000
@@ -262,6 +294,62 @@ func (m *Manager) SetLanguage(language string) error {
 	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
 }
 
+// SetDisplayConfig updates the result display preferences
+func (m *Manager) SetDisplayConfig(display config.DisplayConfig) error {
+	m.config.Display = display
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
+// SetREPLConfig updates how typed lines are handled
+func (m *Manager) SetREPLConfig(repl config.REPLConfig) error {
+	m.config.REPL = repl
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
+// SetSafetyConfig updates the safeguards taken before statements that change data
+func (m *Manager) SetSafetyConfig(safety config.SafetyConfig) error {
+	m.config.Safety = safety
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
+// SetIntegrationsConfig updates the credentials for exporting to other services
+func (m *Manager) SetIntegrationsConfig(integrations config.IntegrationsConfig) error {
+	m.config.Integrations = integrations
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
+// EncryptSecrets encrypts the API keys and other secrets in config.yaml with a new
+// passphrase, or turns encryption off when the passphrase is empty, and saves the config
+func (m *Manager) EncryptSecrets(passphrase string) error {
+	previous := m.config.Secrets
+	if passphrase == "" {
+		config.DisableEncryption(m.config)
+	} else if err := config.EnableEncryption(m.config, passphrase); err != nil {
+		return err
+	}
+	if err := config.SaveConfig(m.config, m.configDir, m.i18nMgr); err != nil {
+		m.config.Secrets = previous
+		return err
+	}
+	return nil
+}
+
+// SetAliases replaces the user's slash command shortcuts
+func (m *Manager) SetAliases(aliases map[string]string) error {
+	m.config.Aliases = aliases
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
+// SetSelfCorrect turns correction of failing generated queries on or off. Attempts
+// of 0 keep the current setting.
+func (m *Manager) SetSelfCorrect(enabled bool, attempts int) error {
+	m.config.AI.SelfCorrect = enabled
+	if attempts > 0 {
+		m.config.AI.SelfCorrectAttempts = attempts
+	}
+	return config.SaveConfig(m.config, m.configDir, m.i18nMgr)
+}
+
 // GenerateSystemPrompt creates a system prompt with database context
 func (m *Manager) GenerateSystemPrompt(tables []string, currentTable string) string {
 	var prompt strings.Builder
@@ -294,8 +382,9 @@ func (m *Manager) GenerateSystemPrompt(tables []string, currentTable string) str
 	prompt.WriteString("- Include comments for complex queries\n")
 	prompt.WriteString("- Consider performance implications\n")
 	prompt.WriteString("- Validate against available tables and expected schema\n")
+	prompt.WriteString(m.dialectInstruction())
 
-	return prompt.String()
+	return m.withLanguage(prompt.String())
 }
 
 // ParseModelString parses a model string and determines the provider
@@ -335,17 +424,20 @@ func ParseFloat(s string) (float64, error) {
 }
 
 // addToPromptHistory adds a prompt entry to the history
-func (m *Manager) addToPromptHistory(userMessage, systemPrompt, aiResponse string, inputTokens, outputTokens int, cost float64) {
+func (m *Manager) addToPromptHistory(userMessage, systemPrompt, aiResponse string, result *chatResult) {
+	inputTokens, outputTokens := result.Usage.PromptTokens, result.Usage.CompletionTokens
+	cost := m.calculateCost(result.provider, result.model, inputTokens, outputTokens)
 	entry := PromptEntry{
 		Timestamp:    time.Now(),
 		UserMessage:  userMessage,
 		SystemPrompt: systemPrompt,
 		AIResponse:   aiResponse,
-		Provider:     m.config.AI.Provider,
-		Model:        m.config.AI.Model,
+		Provider:     result.provider,
+		Model:        result.model,
 		InputTokens:  inputTokens,
 		OutputTokens: outputTokens,
 		Cost:         cost,
+		Latency:      result.latency,
 	}
 
 	m.promptHistory.Entries = append(m.promptHistory.Entries, entry)
@@ -356,33 +448,21 @@ func (m *Manager) addToPromptHistory(userMessage, systemPrompt, aiResponse strin
 	}
 
 	// Record usage statistics in the database
-	if m.usageStore != nil {
-		err := m.usageStore.RecordUsage(m.sessionID, m.config.AI.Provider, m.config.AI.Model,
-			inputTokens, outputTokens, cost, userMessage, aiResponse, systemPrompt)
+	if usageStore := m.usage(); usageStore != nil {
+		err := usageStore.RecordUsage(m.sessionID, result.provider, result.model,
+			inputTokens, outputTokens, cost, result.latency, userMessage, aiResponse, systemPrompt)
 		if err != nil {
 			fmt.Printf(m.i18nMgr.Get("failed_record_usage_warning"), err)
 		}
 	}
-
-	// Learn from successful queries for vector store
-	if m.vectorStore != nil {
-		m.learnFromQuery(userMessage)
-	}
 }
 
-// learnFromQuery extracts table usage patterns for machine learning
-func (m *Manager) learnFromQuery(userMessage string) {
-	// Extract table names that were likely used in the response
-	// This is a simplified implementation - in practice, you'd parse the AI response
-	// to extract actual SQL queries and table usage
-
-	go func() {
-		// Get recent tables as proxy for what was likely used
-		recentTables, err := m.vectorStore.GetRecentTables(5)
-		if err == nil && len(recentTables) > 0 {
-			m.vectorStore.AddQueryPattern(userMessage, recentTables)
-		}
-	}()
+// LastPromptEntry returns the most recent AI call, if any
+func (m *Manager) LastPromptEntry() (PromptEntry, bool) {
+	if m.promptHistory == nil || len(m.promptHistory.Entries) == 0 {
+		return PromptEntry{}, false
+	}
+	return m.promptHistory.Entries[len(m.promptHistory.Entries)-1], true
 }
 
 // GetPromptHistory returns the prompt history
@@ -393,39 +473,71 @@ func (m *Manager) GetPromptHistory() []PromptEntry {
 	return m.promptHistory.Entries
 }
 
-// InitializeVectorStore sets up vector database for a database connection
-func (m *Manager) InitializeVectorStore(connectionName string, connection core.Connection) error {
-	if m.vectorStore != nil {
-		m.vectorStore.Close()
+// pendingVectorStore is a connection whose vector store has not been opened yet
+type pendingVectorStore struct {
+	name   string
+	conn   core.Connection
+	dbType core.DatabaseType
+}
+
+// InitializeVectorStore sets up the vector database for a database connection. The store
+// is opened, and the connection's tables indexed, the first time AI features need it, so
+// connecting does not wait for it.
+func (m *Manager) InitializeVectorStore(connectionName string, connection core.Connection, dbType core.DatabaseType) error {
+	m.CloseVectorStore()
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
+	m.pendingStore = &pendingVectorStore{name: connectionName, conn: connection, dbType: dbType}
+	return nil
+}
+
+// store returns the vector store, opening the pending connection's on first use. It
+// returns nil without a connection, or when the store could not be opened.
+func (m *Manager) store() *VectorStore {
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
+	if m.vectorStore != nil || m.pendingStore == nil {
+		return m.vectorStore
 	}
+	pending := m.pendingStore
+	m.pendingStore = nil
 
-	vectorStore, err := NewVectorStore(m.configDir, connectionName, connection)
+	vectorStore, err := NewVectorStore(m.dataDir, pending.name, pending.conn)
 	if err != nil {
-		return fmt.Errorf("failed to initialize vector store: %w", err)
+		fmt.Printf(m.i18nMgr.Get("vector_db_init_warning"), err)
+		return nil
 	}
-
-	m.vectorStore = vectorStore
+	vectorStore.SetDatabaseType(pending.dbType)
+	vectorStore.sensitive.Store(m.sensitive)
 
 	// Initialize usage store with the vector store
-	usageStore, err := NewUsageStore(vectorStore)
+	usageStore, err := NewUsageStore(vectorStore, m.config.AI.UsageRetentionDays)
 	if err != nil {
-		return fmt.Errorf("failed to initialize usage store: %w", err)
+		vectorStore.Close()
+		fmt.Printf(m.i18nMgr.Get("vector_db_init_warning"), err)
+		return nil
 	}
+	m.vectorStore = vectorStore
 	m.usageStore = usageStore
 
-	// Update embeddings in background
-	go func() {
-		ctx := context.Background()
-		if err := m.vectorStore.UpdateTableEmbeddings(ctx); err != nil {
-			fmt.Printf("Warning: failed to update table embeddings: %v\n", err)
-		}
-	}()
+	// Embed new and changed tables in background; /reindex status shows progress
+	m.startReindex(vectorStore, true)
+	return vectorStore
+}
 
-	return nil
+// usage returns the usage store, which opens with the vector store
+func (m *Manager) usage() *UsageStore {
+	m.store()
+	return m.usageStore
 }
 
 // CloseVectorStore closes the vector store
 func (m *Manager) CloseVectorStore() error {
+	m.CancelReindex()
+	m.reindex = nil
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
+	m.pendingStore = nil
 	if m.vectorStore != nil {
 		err := m.vectorStore.Close()
 		m.vectorStore = nil
@@ -434,6 +546,41 @@ func (m *Manager) CloseVectorStore() error {
 	return nil
 }
 
+// CachedSchema returns every table's definition from the session's schema cache
+func (m *Manager) CachedSchema() (map[string]*core.TableInfo, error) {
+	vectorStore := m.store()
+	if vectorStore == nil {
+		return nil, fmt.Errorf("vector store not initialized")
+	}
+	graph, err := vectorStore.schemaGraph()
+	if err != nil {
+		return nil, err
+	}
+	return graph.tables, nil
+}
+
+// SimilarTableNames returns up to limit indexed tables whose embeddings are closest to
+// text, for names that are not a misspelling but a different word for the same thing
+func (m *Manager) SimilarTableNames(text string, limit int) []string {
+	vectorStore := m.store()
+	if vectorStore == nil {
+		return nil
+	}
+	// Embeddings are built from words, so split snake_case names into them
+	text = strings.NewReplacer("_", " ", ".", " ").Replace(text)
+	results, err := vectorStore.SearchSimilarTables(context.Background(), text, limit)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, result := range results {
+		if result.Similarity > 0 {
+			names = append(names, result.Table.TableName)
+		}
+	}
+	return names
+}
+
 // extractTableNames extracts table names mentioned in user query
 func (m *Manager) extractTableNames(userQuery string, allTables []string) []string {
 	var mentioned []string
@@ -528,7 +675,7 @@ func (m *Manager) GenerateVectorSystemPrompt(userQuery string, allTables []strin
 	}
 
 	// Use vector search if available, otherwise fall back to simple method
-	if m.vectorStore != nil {
+	if m.store() != nil {
 		return m.generateVectorBasedPrompt(userQuery, allTables)
 	}
 
@@ -544,7 +691,7 @@ func (m *Manager) generateVectorBasedPrompt(userQuery string, allTables []string
 	prompt.WriteString(fmt.Sprintf("You have access to a database with %d total tables. ", len(allTables)))
 
 	ctx := context.Background()
-	results, err := m.vectorStore.SearchSimilarTables(ctx, userQuery, m.maxTables)
+	results, err := m.store().SearchSimilarTables(ctx, userQuery, m.maxTables)
 	if err != nil {
 		// Fallback to simple method on error
 		fmt.Printf("Warning: vector search failed, falling back to simple method: %v\n", err)
@@ -586,7 +733,7 @@ func (m *Manager) generateVectorBasedPrompt(userQuery string, allTables []string
 		for _, result := range results {
 			accessedTables = append(accessedTables, result.Table.TableName)
 		}
-		m.vectorStore.RecordTableAccess(accessedTables)
+		m.store().RecordTableAccess(accessedTables)
 
 		if len(allTables) > len(results) {
 			prompt.WriteString(fmt.Sprintf("(%d additional tables available but not shown for brevity)\n\n",
@@ -739,6 +886,11 @@ func (m *Manager) isCommonTableName(tableName string) bool {
 
 // addGuidelines adds the standard AI guidelines to the prompt
 func (m *Manager) addGuidelines(prompt *strings.Builder) string {
+	if custom, ok := m.renderPromptTemplate(TemplateGuidelines, m.promptData(m.conversationCtx, nil)); ok {
+		prompt.WriteString(custom)
+		return m.withLanguage(prompt.String() + m.dialectInstruction())
+	}
+
 	prompt.WriteString("Guidelines:\n")
 	prompt.WriteString("- Generate accurate SQL queries based on user requests\n")
 	prompt.WriteString("- Explain your reasoning when helpful\n")
@@ -752,8 +904,9 @@ func (m *Manager) addGuidelines(prompt *strings.Builder) string {
 	prompt.WriteString("- Include comments for complex queries\n")
 	prompt.WriteString("- Consider performance implications\n")
 	prompt.WriteString("- Validate against available tables and expected schema\n")
+	prompt.WriteString(m.dialectInstruction())
 
-	return prompt.String()
+	return m.withLanguage(prompt.String())
 }
 
 // StartConversation begins a new multi-turn conversation
@@ -767,9 +920,15 @@ func (m *Manager) GetCurrentConversation() *ConversationContext {
 	return m.conversationCtx
 }
 
+// RestoreConversation resumes a conversation context saved by an earlier run
+func (m *Manager) RestoreConversation(convCtx *ConversationContext) {
+	m.conversationCtx = convCtx
+}
+
 // ClearConversation clears the current conversation context
 func (m *Manager) ClearConversation() {
 	m.conversationCtx = nil
+	m.languageOverride = ""
 }
 
 // ChatWithConversation handles chat with conversation context
@@ -782,12 +941,18 @@ func (m *Manager) ChatWithConversation(ctx context.Context, userMessage string,
 	if m.conversationCtx == nil {
 		m.conversationCtx = NewConversationContext(userMessage)
 	}
+	// Conversations started by /load-schema or /phase take their request from the first message
+	if m.conversationCtx.OriginalQuery == "" {
+		m.conversationCtx.OriginalQuery = userMessage
+	}
 
 	// Generate system prompt based on conversation phase
-	systemPrompt, err := m.generateConversationalPrompt(m.conversationCtx, allTables)
+	systemPrompt, budget, err := m.generateConversationalPrompt(m.conversationCtx, allTables, userMessage, conversationMaxTokens)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate prompt: %w", err)
 	}
+	m.lastBudget = budget
+	m.reportPromptBudget(budget)
 
 	// Send chat request
 	messages := []ChatMessage{
@@ -799,19 +964,21 @@ func (m *Manager) ChatWithConversation(ctx context.Context, userMessage string,
 		Model:       m.config.AI.Model,
 		Messages:    messages,
 		Temperature: 0.7,
-		MaxTokens:   4000,
+		MaxTokens:   conversationMaxTokens,
+		Tools:       m.conversationTools(m.conversationCtx.CurrentPhase),
 	}
 
-	response, err := m.client.Chat(ctx, request)
+	response, err := m.send(ctx, request)
 	if err != nil {
 		return "", fmt.Errorf(m.i18nMgr.Get("chat_request_failed"), err)
 	}
 
-	if len(response.Choices) == 0 {
-		return "", errors.New(m.i18nMgr.Get("no_response_choices_returned"))
+	aiResponse := response.Choices[0].Message.Content
+	if calls := response.Choices[0].Message.ToolCalls; len(calls) > 0 {
+		aiResponse = strings.TrimSpace(aiResponse + "\n\n" + toolCallsText(calls))
 	}
 
-	aiResponse := response.Choices[0].Message.Content
+	m.rememberGeneratedSQL(aiResponse)
 
 	// Parse AI response for requested tables/actions
 	requestedInfo := m.parseAIResponse(aiResponse, m.conversationCtx.CurrentPhase)
@@ -833,11 +1000,8 @@ func (m *Manager) ChatWithConversation(ctx context.Context, userMessage string,
 		fmt.Printf(m.i18nMgr.Get("conversation_turn_warning"), err)
 	}
 
-	// Calculate cost and update usage
-	cost := m.calculateCost(response.Usage.PromptTokens, response.Usage.CompletionTokens)
-
-	// Add to prompt history
-	m.addToPromptHistory(userMessage, systemPrompt, aiResponse, response.Usage.PromptTokens, response.Usage.CompletionTokens, cost)
+	// Add to prompt history with the cost of the model that answered
+	m.addToPromptHistory(userMessage, systemPrompt, aiResponse, response)
 
 	// If schemas were loaded, automatically continue the conversation
 	if len(requestedInfo) > 0 {
@@ -873,18 +1037,33 @@ func (m *Manager) ChatWithConversation(ctx context.Context, userMessage string,
 	return aiResponse, nil
 }
 
-// generateConversationalPrompt creates phase-specific prompts
-func (m *Manager) generateConversationalPrompt(convCtx *ConversationContext, allTables []string) (string, error) {
-	switch convCtx.CurrentPhase {
-	case PhaseDiscovery:
-		return m.generateDiscoveryPrompt(convCtx, allTables), nil
-	case PhaseSchemaAnalysis:
-		return m.generateSchemaAnalysisPrompt(convCtx), nil
-	case PhaseSQLGeneration:
-		return m.generateSQLGenerationPrompt(convCtx), nil
-	default:
-		return "", fmt.Errorf("unknown conversation phase: %v", convCtx.CurrentPhase)
+// generateConversationalPrompt creates phase-specific prompts, trimmed to fit the
+// model's context window alongside the user message
+func (m *Manager) generateConversationalPrompt(convCtx *ConversationContext, allTables []string, userMessage string, maxTokens int) (string, PromptBudget, error) {
+	var sections []promptSection
+	if custom, ok := m.renderPromptTemplate(phaseTemplate(convCtx.CurrentPhase), m.promptData(convCtx, allTables)); ok {
+		// Custom templates decide their own content, so they are kept whole
+		sections = []promptSection{fixedSection(custom)}
+	} else {
+		switch convCtx.CurrentPhase {
+		case PhaseDiscovery:
+			sections = []promptSection{fixedSection(m.generateDiscoveryPrompt(convCtx, allTables))}
+		case PhaseSchemaAnalysis:
+			sections = m.generateSchemaAnalysisPrompt(convCtx)
+		case PhaseSQLGeneration:
+			sections = m.generateSQLGenerationPrompt(convCtx)
+		default:
+			return "", PromptBudget{}, fmt.Errorf("unknown conversation phase: %v", convCtx.CurrentPhase)
+		}
 	}
+	sections = append(sections, m.examplesSections(convCtx.OriginalQuery)...)
+	sections = append(sections,
+		fixedSection(m.glossaryInstruction(convCtx.OriginalQuery+"\n"+userMessage)),
+		fixedSection(m.dialectInstruction()),
+		fixedSection(languageInstruction(m.ResponseLanguage())))
+
+	prompt, budget := fitPrompt(sections, m.promptLimit(maxTokens), m.countTokens(userMessage), m.countTokens)
+	return prompt, budget, nil
 }
 
 // generateDiscoveryPrompt creates prompt for table discovery phase
@@ -895,9 +1074,9 @@ func (m *Manager) generateDiscoveryPrompt(convCtx *ConversationContext, allTable
 	prompt.WriteString(fmt.Sprintf("The user wants to: %s\n\n", convCtx.OriginalQuery))
 
 	// Use vector search to find most relevant tables
-	if m.vectorStore != nil && len(allTables) > 0 {
+	if vectorStore := m.store(); vectorStore != nil && len(allTables) > 0 {
 		ctx := context.Background()
-		results, err := m.vectorStore.SearchSimilarTables(ctx, convCtx.OriginalQuery, 10)
+		results, err := vectorStore.SearchSimilarTables(ctx, convCtx.OriginalQuery, 10)
 		if err == nil && len(results) > 0 {
 			prompt.WriteString(fmt.Sprintf("Database has %d tables total. Most relevant tables for this query:\n\n", len(allTables)))
 			for i, result := range results {
@@ -926,27 +1105,31 @@ func (m *Manager) generateDiscoveryPrompt(convCtx *ConversationContext, allTable
 
 	prompt.WriteString("\nYour task:\n")
 	prompt.WriteString("1. Analyze the user's request and identify which tables you need detailed schema information for\n")
-	prompt.WriteString("2. Respond with: 'I need detailed schema for: [table1], [table2], [table3]' to request specific table structures\n")
+	prompt.WriteString("2. Request the table structures you need with the need_schema tool or the JSON block shown below\n")
 	prompt.WriteString("3. Be selective - only request tables that are directly relevant to the query\n")
 	prompt.WriteString("4. If you can answer with the information already provided, do so\n\n")
 
-	prompt.WriteString("Important: If you need table schemas, use EXACTLY this format:\n")
-	prompt.WriteString("'I need detailed schema for: table1, table2, table3'\n")
+	prompt.WriteString("Important: If you need table schemas, reply with EXACTLY this JSON block:\n")
+	prompt.WriteString(schemaRequestExample + "\n")
+	prompt.WriteString("or, if you cannot produce JSON, the line 'I need detailed schema for: table1, table2, table3'\n")
 
 	return prompt.String()
 }
 
 // generateSchemaAnalysisPrompt creates prompt for schema analysis phase
-func (m *Manager) generateSchemaAnalysisPrompt(convCtx *ConversationContext) string {
+func (m *Manager) generateSchemaAnalysisPrompt(convCtx *ConversationContext) []promptSection {
 	var prompt strings.Builder
 
 	prompt.WriteString("You are an AI assistant helping with SQL queries. ")
 	prompt.WriteString(fmt.Sprintf("The user wants to: %s\n\n", convCtx.OriginalQuery))
 
 	prompt.WriteString("You have requested detailed schema information. Here are the table structures:\n\n")
+	sections := []promptSection{fixedSection(prompt.String())}
 
 	// Include loaded table schemas
-	for tableName, tableInfo := range convCtx.LoadedTables {
+	for _, tableName := range rankLoadedTables(convCtx) {
+		tableInfo := convCtx.LoadedTables[tableName]
+		prompt.Reset()
 		prompt.WriteString(fmt.Sprintf("## Table: %s\n", tableName))
 		prompt.WriteString("Columns:\n")
 		for _, col := range tableInfo.Columns {
@@ -968,34 +1151,38 @@ func (m *Manager) generateSchemaAnalysisPrompt(convCtx *ConversationContext) str
 				prompt.WriteString(fmt.Sprintf("- %s → %s.%s\n", fk.Column, fk.ReferencedTable, fk.ReferencedColumn))
 			}
 		}
-		prompt.WriteString("\n")
+		sections = append(sections, m.tableSections(convCtx, tableName, prompt.String())...)
 	}
 
 	// Add information about available related tables
+	prompt.Reset()
 	m.addRelatedTableSuggestions(&prompt, convCtx)
 
 	prompt.WriteString("Your task:\n")
 	prompt.WriteString("1. Analyze the provided schemas and relationships\n")
-	prompt.WriteString("2. If you need information about related tables (via foreign keys), request them using: 'I need schema for related tables: [table1], [table2]'\n")
+	prompt.WriteString("2. If you need information about related tables (via foreign keys), request them with:\n" + schemaRequestExample + "\n")
 	prompt.WriteString("3. If you have sufficient information, generate the SQL query\n")
 	prompt.WriteString("4. Include explanations for complex queries\n\n")
 
 	prompt.WriteString("Use ```sql blocks for any SQL queries you generate.\n")
 
-	return prompt.String()
+	return append(sections, fixedSection(prompt.String()))
 }
 
 // generateSQLGenerationPrompt creates prompt for final SQL generation
-func (m *Manager) generateSQLGenerationPrompt(convCtx *ConversationContext) string {
+func (m *Manager) generateSQLGenerationPrompt(convCtx *ConversationContext) []promptSection {
 	var prompt strings.Builder
 
 	prompt.WriteString("You are an AI assistant specialized in SQL query generation. ")
 	prompt.WriteString(fmt.Sprintf("The user wants to: %s\n\n", convCtx.OriginalQuery))
 
 	prompt.WriteString("You have complete schema information for the following tables:\n\n")
+	sections := []promptSection{fixedSection(prompt.String())}
 
 	// Include all loaded table information
-	for tableName, tableInfo := range convCtx.LoadedTables {
+	for _, tableName := range rankLoadedTables(convCtx) {
+		tableInfo := convCtx.LoadedTables[tableName]
+		prompt.Reset()
 		prompt.WriteString(fmt.Sprintf("## %s\n", tableName))
 		for _, col := range tableInfo.Columns {
 			nullable := "NOT NULL"
@@ -1011,9 +1198,10 @@ func (m *Manager) generateSQLGenerationPrompt(convCtx *ConversationContext) stri
 				prompt.WriteString(fmt.Sprintf("- %s → %s.%s\n", fk.Column, fk.ReferencedTable, fk.ReferencedColumn))
 			}
 		}
-		prompt.WriteString("\n")
+		sections = append(sections, m.tableSections(convCtx, tableName, prompt.String())...)
 	}
 
+	prompt.Reset()
 	prompt.WriteString("Generate the complete SQL query to fulfill the user's request.\n")
 	prompt.WriteString("Include:\n")
 	prompt.WriteString("- Proper JOINs based on foreign key relationships\n")
@@ -1023,40 +1211,83 @@ func (m *Manager) generateSQLGenerationPrompt(convCtx *ConversationContext) stri
 
 	prompt.WriteString("Use ```sql blocks for your query.\n")
 
-	return prompt.String()
+	return append(sections, fixedSection(prompt.String()))
 }
 
-// parseAIResponse extracts requested information from AI response
-func (m *Manager) parseAIResponse(response string, phase ConversationPhase) []string {
-	var requested []string
+// tableSections wraps a table's schema, its inferred JSON structure and its column
+// profiles as separate sections, so the budgeter can drop the extras before the schema
+func (m *Manager) tableSections(convCtx *ConversationContext, tableName, schema string) []promptSection {
+	relevance := tableRelevance(convCtx, tableName)
+	sections := []promptSection{{kind: sectionTable, table: tableName, relevance: relevance, text: schema}}
 
-	switch phase {
-	case PhaseDiscovery, PhaseSchemaAnalysis:
-		// Look for table requests in various formats
-		patterns := []string{
-			`I need detailed schema for:\s*([^.]+)`,
-			`I need schema for related tables:\s*([^.]+)`,
-			`Please provide schema for:\s*([^.]+)`,
-			`Need table structure for:\s*([^.]+)`,
-		}
+	var structures strings.Builder
+	m.writeJSONStructures(&structures, tableName)
+	if structures.Len() > 0 {
+		sections = append(sections, promptSection{kind: sectionSample, table: tableName, relevance: relevance, text: structures.String()})
+	}
+	var profiles strings.Builder
+	m.writeColumnProfiles(&profiles, tableName)
+	if profiles.Len() > 0 {
+		sections = append(sections, promptSection{kind: sectionSample, table: tableName, relevance: relevance, text: profiles.String()})
+	}
+	return append(sections, fixedSection("\n"))
+}
 
-		for _, pattern := range patterns {
-			re := regexp.MustCompile(pattern)
-			if matches := re.FindStringSubmatch(response); len(matches) > 1 {
-				// Parse comma-separated table names
-				tableNames := strings.Split(matches[1], ",")
-				for _, name := range tableNames {
-					name = strings.TrimSpace(name)
-					if name != "" {
-						requested = append(requested, name)
-					}
-				}
-				break
-			}
+// tableRelevance scores a loaded table: named in the request, then requested by the
+// model, then found by search, then pulled in through relationships
+func tableRelevance(convCtx *ConversationContext, tableName string) float64 {
+	switch {
+	case strings.Contains(strings.ToLower(convCtx.OriginalQuery), strings.ToLower(tableName)):
+		return 3
+	case slices.Contains(convCtx.RequestedTables, tableName):
+		return 2
+	case slices.Contains(convCtx.DiscoveredTables, tableName):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// rankLoadedTables orders loaded tables by relevance, then name, for a stable prompt
+func rankLoadedTables(convCtx *ConversationContext) []string {
+	names := make([]string, 0, len(convCtx.LoadedTables))
+	for name := range convCtx.LoadedTables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, rj := tableRelevance(convCtx, names[i]), tableRelevance(convCtx, names[j])
+		if ri != rj {
+			return ri > rj
 		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// conversationTools returns the functions offered to the model in a phase, none when
+// the provider says the model lacks tool calling or the model has rejected it
+func (m *Manager) conversationTools(phase ConversationPhase) []Tool {
+	model := m.config.AI.Model
+	if m.noToolModels[model] {
+		return nil
+	}
+	if info := m.modelInfo(model); info != nil && info.Tools == CapabilityNo {
+		return nil
 	}
+	switch phase {
+	case PhaseDiscovery, PhaseSchemaAnalysis:
+		return []Tool{needSchemaTool}
+	}
+	return nil
+}
 
-	return requested
+// parseAIResponse extracts requested information from AI response
+func (m *Manager) parseAIResponse(response string, phase ConversationPhase) []string {
+	switch phase {
+	case PhaseDiscovery, PhaseSchemaAnalysis:
+		return parseSchemaRequest(response)
+	}
+	return nil
 }
 
 // processConversationTurn handles the AI's requests and advances conversation
@@ -1070,34 +1301,14 @@ func (m *Manager) processConversationTurn(requestedInfo []string, allTables []st
 	}
 
 	// Process table schema requests
-	if m.vectorStore != nil && m.vectorStore.connection != nil {
+	if vectorStore := m.store(); vectorStore != nil && vectorStore.connection != nil {
 		for _, tableName := range requestedInfo {
 			// Verify table exists
 			if !m.contains(allTables, tableName) {
 				continue
 			}
-
-			// Skip if already loaded
-			if m.conversationCtx.HasTableLoaded(tableName) {
-				continue
-			}
-
-			// Load table schema
-			tableInfo, err := m.vectorStore.connection.DescribeTable(tableName)
-			if err != nil {
+			if err := m.loadTable(vectorStore.connection, tableName); err != nil {
 				fmt.Printf("Warning: failed to describe table %s: %v\n", tableName, err)
-				continue
-			}
-
-			// Add to conversation context
-			m.conversationCtx.AddLoadedTable(tableName, tableInfo)
-			m.conversationCtx.RequestedTables = append(m.conversationCtx.RequestedTables, tableName)
-
-			// Find related tables via foreign keys
-			for _, fk := range tableInfo.ForeignKeys {
-				if !m.contains(m.conversationCtx.RelatedTables, fk.ReferencedTable) {
-					m.conversationCtx.RelatedTables = append(m.conversationCtx.RelatedTables, fk.ReferencedTable)
-				}
 			}
 		}
 
@@ -1110,9 +1321,128 @@ func (m *Manager) processConversationTurn(requestedInfo []string, allTables []st
 	return nil
 }
 
+// loadTable describes a table into the conversation context, along with its JSON
+// column structures and foreign key neighbours. Already loaded tables are skipped.
+func (m *Manager) loadTable(conn core.Connection, tableName string) error {
+	if m.conversationCtx.HasTableLoaded(tableName) {
+		return nil
+	}
+
+	// The vector store caches the connection's schema for the session
+	describe := conn.DescribeTable
+	if vectorStore := m.store(); vectorStore != nil && vectorStore.connection == conn {
+		describe = vectorStore.describeTable
+	}
+	tableInfo, err := describe(tableName)
+	if err != nil {
+		return err
+	}
+
+	// Sample JSON columns so the AI knows which keys it can extract
+	m.loadJSONStructures(conn, tableInfo)
+
+	// Add to conversation context
+	m.conversationCtx.AddLoadedTable(tableName, tableInfo)
+	m.conversationCtx.RequestedTables = append(m.conversationCtx.RequestedTables, tableName)
+
+	// Find related tables via foreign keys
+	for _, fk := range tableInfo.ForeignKeys {
+		if !m.contains(m.conversationCtx.RelatedTables, fk.ReferencedTable) {
+			m.conversationCtx.RelatedTables = append(m.conversationCtx.RelatedTables, fk.ReferencedTable)
+		}
+	}
+	return nil
+}
+
+// LoadSchemas loads table schemas into the conversation chosen by the user rather than
+// the model, starting a conversation if needed, and moves past discovery so the next
+// message is answered with those schemas in context
+func (m *Manager) LoadSchemas(conn core.Connection, tables []string) ([]string, error) {
+	if m.conversationCtx == nil {
+		m.conversationCtx = NewConversationContext("")
+	}
+
+	var loaded []string
+	for _, tableName := range tables {
+		if err := m.loadTable(conn, tableName); err != nil {
+			return loaded, fmt.Errorf("%s: %w", tableName, err)
+		}
+		loaded = append(loaded, tableName)
+	}
+
+	if len(m.conversationCtx.LoadedTables) > 0 && m.conversationCtx.CurrentPhase == PhaseDiscovery {
+		m.conversationCtx.AdvancePhase()
+	}
+	return loaded, nil
+}
+
+// SetPhase moves the current conversation to a phase, starting one if needed
+func (m *Manager) SetPhase(phase ConversationPhase) {
+	if m.conversationCtx == nil {
+		m.conversationCtx = NewConversationContext("")
+	}
+	m.conversationCtx.CurrentPhase = phase
+	m.conversationCtx.IsComplete = false
+	m.conversationCtx.UpdatedAt = time.Now()
+}
+
+// SetJSONStructure records the inferred key structure of a JSON column for use in prompts
+func (m *Manager) SetJSONStructure(table, column string, fields []core.JSONField) {
+	if m.jsonStructures == nil {
+		m.jsonStructures = make(map[string][]core.JSONField)
+	}
+	m.jsonStructures[table+"."+column] = fields
+}
+
+// loadJSONStructures samples JSON columns of a table that have not been inspected yet
+func (m *Manager) loadJSONStructures(conn core.Connection, tableInfo *core.TableInfo) {
+	for _, col := range tableInfo.Columns {
+		if !core.IsJSONType(col.Type) {
+			continue
+		}
+		if _, ok := m.jsonStructures[tableInfo.Name+"."+col.Name]; ok {
+			continue
+		}
+		if m.sensitive.Tagged(tableInfo.Name, col.Name) {
+			continue
+		}
+		fields, _, err := core.SampleJSONStructure(conn, tableInfo.Name, col.Name, 20)
+		if err != nil {
+			continue
+		}
+		m.SetJSONStructure(tableInfo.Name, col.Name, fields)
+	}
+}
+
+// writeJSONStructures describes known JSON column key paths of a table
+func (m *Manager) writeJSONStructures(prompt *strings.Builder, tableName string) {
+	var keys []string
+	for key := range m.jsonStructures {
+		if strings.HasPrefix(key, tableName+".") && !m.sensitive.Tagged(tableName, strings.TrimPrefix(key, tableName+".")) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	prompt.WriteString("JSON columns (use ->/->> on PostgreSQL, JSON_EXTRACT/->> on MySQL and SQLite):\n")
+	for _, key := range keys {
+		prompt.WriteString(fmt.Sprintf("- %s:\n", strings.TrimPrefix(key, tableName+".")))
+		for _, field := range m.jsonStructures[key] {
+			if field.Path == "$" {
+				continue
+			}
+			prompt.WriteString(fmt.Sprintf("  - %s (%s)\n", field.Path, strings.Join(field.Types, "|")))
+		}
+	}
+}
+
 // addRelatedTableSuggestions adds information about available related tables to prompt
 func (m *Manager) addRelatedTableSuggestions(prompt *strings.Builder, convCtx *ConversationContext) {
-	if m.vectorStore == nil {
+	vectorStore := m.store()
+	if vectorStore == nil {
 		return
 	}
 
@@ -1128,13 +1458,13 @@ func (m *Manager) addRelatedTableSuggestions(prompt *strings.Builder, convCtx *C
 
 	// Find related tables using enhanced relationship discovery
 	ctx := context.Background()
-	relatedTables, err := m.vectorStore.SearchRelatedTablesForQuery(ctx, loadedTableNames, 5)
+	relatedTables, err := vectorStore.SearchRelatedTablesForQuery(ctx, loadedTableNames, 5)
 	if err != nil || len(relatedTables) == 0 {
 		return
 	}
 
 	// Also get detailed relationship mapping
-	relationships, err := m.vectorStore.FindRelatedTables(loadedTableNames)
+	relationships, err := vectorStore.FindRelatedTables(loadedTableNames)
 	if err == nil && len(relationships) > 0 {
 		prompt.WriteString("## Available Related Tables\n\n")
 		prompt.WriteString("The following tables are related to your loaded tables and might be useful:\n\n")
@@ -1189,7 +1519,7 @@ func (m *Manager) addRelatedTableSuggestions(prompt *strings.Builder, convCtx *C
 
 // GetUsageStore returns the usage store for accessing usage statistics
 func (m *Manager) GetUsageStore() *UsageStore {
-	return m.usageStore
+	return m.usage()
 }
 
 // GetSessionID returns the current session ID
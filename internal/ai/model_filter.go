@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ModelFilter keeps the models with at least the given context and features
+type ModelFilter struct {
+	MinContext int // Tokens; 0 keeps every model
+	Tools      bool
+	JSONMode   bool
+}
+
+// Matches reports whether a model passes the filter. Models whose provider does not
+// report a value are dropped by a filter on that value.
+func (f ModelFilter) Matches(model ModelInfo) bool {
+	if f.MinContext > 0 && model.ContextLength < f.MinContext {
+		return false
+	}
+	if f.Tools && model.Tools != CapabilityYes {
+		return false
+	}
+	if f.JSONMode && model.JSONMode != CapabilityYes {
+		return false
+	}
+	return true
+}
+
+// Filter returns the models that pass the filter, in their original order
+func (f ModelFilter) Filter(models []ModelInfo) []ModelInfo {
+	var kept []ModelInfo
+	for _, model := range models {
+		if f.Matches(model) {
+			kept = append(kept, model)
+		}
+	}
+	return kept
+}
+
+// ParseTokenCount reads a token count such as 8192, 32k or 1m. The suffixes are
+// decimal, the way providers advertise context lengths.
+func ParseTokenCount(input string) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(input))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1000, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		multiplier, s = 1000000, strings.TrimSuffix(s, "m")
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("invalid token count: %q", input)
+	}
+	return int(value * float64(multiplier)), nil
+}
+
+// FormatTokenCount shows a token count the way ParseTokenCount reads it
+func FormatTokenCount(tokens int) string {
+	switch {
+	case tokens >= 1000000 && tokens%100000 == 0:
+		return strconv.FormatFloat(float64(tokens)/1000000, 'f', -1, 64) + "M"
+	case tokens >= 1000:
+		return strconv.FormatFloat(float64(tokens)/1000, 'f', 0, 64) + "k"
+	default:
+		return strconv.Itoa(tokens)
+	}
+}
@@ -37,11 +37,13 @@ func (c *OllamaClient) Chat(ctx context.Context, request ChatRequest) (*ChatResp
 		Messages []ChatMessage          `json:"messages"`
 		Stream   bool                   `json:"stream"`
 		Options  map[string]interface{} `json:"options,omitempty"`
+		Tools    []Tool                 `json:"tools,omitempty"`
 	}{
 		Model:    request.Model,
 		Messages: request.Messages,
 		Stream:   false,
 		Options:  make(map[string]interface{}),
+		Tools:    request.Tools,
 	}
 
 	if request.Temperature > 0 {
@@ -78,8 +80,14 @@ func (c *OllamaClient) Chat(ctx context.Context, request ChatRequest) (*ChatResp
 		Model     string `json:"model"`
 		CreatedAt string `json:"created_at"`
 		Message   struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string `json:"role"`
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"` // An object, unlike OpenAI's string
+				} `json:"function"`
+			} `json:"tool_calls"`
 		} `json:"message"`
 		Done               bool  `json:"done"`
 		TotalDuration      int64 `json:"total_duration"`
@@ -100,34 +108,30 @@ func (c *OllamaClient) Chat(ctx context.Context, request ChatRequest) (*ChatResp
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
 		Model:   ollamaResponse.Model,
-		Choices: []struct {
-			Index   int `json:"index"`
-			Message struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
-			} `json:"message"`
-			FinishReason string `json:"finish_reason"`
-		}{{
-			Index: 0,
-			Message: struct {
-				Role    string `json:"role"`
-				Content string `json:"content"`
-			}{
-				Role:    ollamaResponse.Message.Role,
-				Content: ollamaResponse.Message.Content,
-			},
-			FinishReason: "stop",
-		}},
-		Usage: struct {
-			PromptTokens     int `json:"prompt_tokens"`
-			CompletionTokens int `json:"completion_tokens"`
-			TotalTokens      int `json:"total_tokens"`
-		}{
-			PromptTokens:     ollamaResponse.PromptEvalCount,
-			CompletionTokens: ollamaResponse.EvalCount,
-			TotalTokens:      ollamaResponse.PromptEvalCount + ollamaResponse.EvalCount,
-		},
 	}
+	response.Choices = make([]struct {
+		Index   int `json:"index"`
+		Message struct {
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	}, 1)
+	choice := &response.Choices[0]
+	choice.Message.Role = ollamaResponse.Message.Role
+	choice.Message.Content = ollamaResponse.Message.Content
+	choice.FinishReason = "stop"
+	for _, call := range ollamaResponse.Message.ToolCalls {
+		var toolCall ToolCall
+		toolCall.Type = "function"
+		toolCall.Function.Name = call.Function.Name
+		toolCall.Function.Arguments = string(call.Function.Arguments)
+		choice.Message.ToolCalls = append(choice.Message.ToolCalls, toolCall)
+	}
+	response.Usage.PromptTokens = ollamaResponse.PromptEvalCount
+	response.Usage.CompletionTokens = ollamaResponse.EvalCount
+	response.Usage.TotalTokens = ollamaResponse.PromptEvalCount + ollamaResponse.EvalCount
 
 	return response, nil
 }
@@ -171,6 +175,12 @@ func (c *OllamaClient) ListModels(ctx context.Context) ([]ModelInfo, error) {
 			Name:        model.Name,
 			Description: fmt.Sprintf("Local Ollama model (Size: %.1fGB)", float64(model.Size)/(1024*1024*1024)),
 			Provider:    "ollama",
+			JSONMode:    CapabilityYes, // Every model accepts format: json
+		}
+		// Details are best effort; the model is still listed without them
+		if contextLength, tools, err := c.ShowModel(ctx, model.Name); err == nil {
+			models[i].ContextLength = contextLength
+			models[i].Tools = tools
 		}
 	}
 
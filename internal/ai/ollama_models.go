@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"sqlterm/internal/config"
+)
+
+// InstalledModel is a model downloaded to the local Ollama server
+type InstalledModel struct {
+	Name          string
+	Size          int64
+	ModifiedAt    time.Time
+	Family        string
+	ParameterSize string
+	Quantization  string
+}
+
+// PullProgress is one status update while Ollama downloads a model
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Percent returns how much of the current layer has downloaded, or -1 when unknown
+func (p PullProgress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return float64(p.Completed) * 100 / float64(p.Total)
+}
+
+// InstalledModels lists the models downloaded to the Ollama server
+func (c *OllamaClient) InstalledModels(ctx context.Context) ([]InstalledModel, error) {
+	url := fmt.Sprintf("%s/api/tags", c.baseURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		Models []struct {
+			Name       string    `json:"name"`
+			ModifiedAt time.Time `json:"modified_at"`
+			Size       int64     `json:"size"`
+			Details    struct {
+				Family            string `json:"family"`
+				ParameterSize     string `json:"parameter_size"`
+				QuantizationLevel string `json:"quantization_level"`
+			} `json:"details"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	models := make([]InstalledModel, len(response.Models))
+	for i, model := range response.Models {
+		models[i] = InstalledModel{
+			Name:          model.Name,
+			Size:          model.Size,
+			ModifiedAt:    model.ModifiedAt,
+			Family:        model.Details.Family,
+			ParameterSize: model.Details.ParameterSize,
+			Quantization:  model.Details.QuantizationLevel,
+		}
+	}
+	return models, nil
+}
+
+// ShowModel reads a model's trained context length and capabilities from Ollama.
+// Servers older than the capabilities field report tool support as unknown.
+func (c *OllamaClient) ShowModel(ctx context.Context, model string) (contextLength int, tools Capability, err error) {
+	url := fmt.Sprintf("%s/api/show", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]string{"model": model, "name": model})
+	if err != nil {
+		return 0, CapabilityUnknown, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, CapabilityUnknown, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, CapabilityUnknown, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, CapabilityUnknown, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var response struct {
+		ModelInfo    map[string]interface{} `json:"model_info"`
+		Capabilities []string               `json:"capabilities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return 0, CapabilityUnknown, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// The key is prefixed with the architecture, e.g. llama.context_length
+	for key, value := range response.ModelInfo {
+		if length, ok := value.(float64); ok && strings.HasSuffix(key, ".context_length") {
+			contextLength = int(length)
+		}
+	}
+	tools = CapabilityUnknown
+	if response.Capabilities != nil {
+		tools = capabilityOf(slices.Contains(response.Capabilities, "tools"))
+	}
+	return contextLength, tools, nil
+}
+
+// PullModel downloads a model to the Ollama server, reporting each status update.
+// Downloads can take a long time, so only ctx bounds the request.
+func (c *OllamaClient) PullModel(ctx context.Context, model string, progress func(PullProgress)) error {
+	url := fmt.Sprintf("%s/api/pull", c.baseURL)
+
+	jsonData, err := json.Marshal(map[string]interface{}{"name": model, "stream": true})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	// The response is one JSON object per line until the pull succeeds or fails
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var update PullProgress
+		if err := json.Unmarshal(scanner.Bytes(), &update); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+		if update.Error != "" {
+			return errors.New(update.Error)
+		}
+		if progress != nil {
+			progress(update)
+		}
+		if update.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	return errors.New("pull ended before it succeeded")
+}
+
+// ollamaClient returns a client for the configured Ollama server, whichever provider
+// is active, so local models can be prepared before switching to them
+func (m *Manager) ollamaClient() *OllamaClient {
+	return NewOllamaClient(m.config.GetBaseURL(config.ProviderOllama))
+}
+
+// InstalledOllamaModels lists the models downloaded to the Ollama server
+func (m *Manager) InstalledOllamaModels(ctx context.Context) ([]InstalledModel, error) {
+	return m.ollamaClient().InstalledModels(ctx)
+}
+
+// PullOllamaModel downloads a model to the Ollama server
+func (m *Manager) PullOllamaModel(ctx context.Context, model string, progress func(PullProgress)) error {
+	return m.ollamaClient().PullModel(ctx, model, progress)
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -87,11 +88,14 @@ func (c *OpenRouterClient) ListModels(ctx context.Context) ([]ModelInfo, error)
 
 	var response struct {
 		Data []struct {
-			ID          string `json:"id"`
-			Name        string `json:"name"`
-			Created     int64  `json:"created"`
-			Description string `json:"description"`
-			Pricing     *struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			Created       int64  `json:"created"`
+			Description   string `json:"description"`
+			ContextLength int    `json:"context_length"`
+			// Request parameters the model accepts, such as tools and response_format
+			SupportedParameters []string `json:"supported_parameters"`
+			Pricing             *struct {
 				Prompt     string `json:"prompt"`
 				Completion string `json:"completion"`
 			} `json:"pricing"`
@@ -105,10 +109,16 @@ func (c *OpenRouterClient) ListModels(ctx context.Context) ([]ModelInfo, error)
 	models := make([]ModelInfo, len(response.Data))
 	for i, model := range response.Data {
 		models[i] = ModelInfo{
-			ID:          model.ID,
-			Name:        model.Name,
-			Description: model.Description,
-			Provider:    "openrouter",
+			ID:            model.ID,
+			Name:          model.Name,
+			Description:   model.Description,
+			Provider:      "openrouter",
+			ContextLength: model.ContextLength,
+		}
+		if model.SupportedParameters != nil {
+			models[i].Tools = capabilityOf(slices.Contains(model.SupportedParameters, "tools"))
+			models[i].JSONMode = capabilityOf(slices.Contains(model.SupportedParameters, "response_format") ||
+				slices.Contains(model.SupportedParameters, "structured_outputs"))
 		}
 
 		// Parse pricing if available
@@ -0,0 +1,83 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ColumnProfile is a stored one-line summary of a column's values, from /profile
+type ColumnProfile struct {
+	Column  string
+	Summary string
+}
+
+// SaveColumnProfiles stores column summaries for a table, replacing earlier ones
+func (vs *VectorStore) SaveColumnProfiles(table string, profiles []ColumnProfile) error {
+	tx, err := vs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, profile := range profiles {
+		_, err := tx.Exec(`INSERT OR REPLACE INTO column_profiles (table_name, column_name, summary, updated_at)
+			VALUES (?, ?, ?, ?)`, table, profile.Column, profile.Summary, now)
+		if err != nil {
+			return fmt.Errorf("failed to save profile of %s.%s: %w", table, profile.Column, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ColumnProfiles returns the stored column summaries of a table, by column name
+func (vs *VectorStore) ColumnProfiles(table string) ([]ColumnProfile, error) {
+	rows, err := vs.db.Query(`SELECT column_name, summary FROM column_profiles WHERE table_name = ? ORDER BY column_name`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []ColumnProfile
+	for rows.Next() {
+		var profile ColumnProfile
+		if err := rows.Scan(&profile.Column, &profile.Summary); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles, rows.Err()
+}
+
+// RememberProfiles keeps column summaries as context for later requests on the table
+func (m *Manager) RememberProfiles(table string, profiles []ColumnProfile) error {
+	vectorStore := m.store()
+	if vectorStore == nil {
+		return fmt.Errorf("vector store not initialized")
+	}
+	return vectorStore.SaveColumnProfiles(table, profiles)
+}
+
+// writeColumnProfiles adds the stored summaries of a table's columns to a prompt
+func (m *Manager) writeColumnProfiles(prompt *strings.Builder, tableName string) {
+	vectorStore := m.store()
+	if vectorStore == nil {
+		return
+	}
+	profiles, err := vectorStore.ColumnProfiles(tableName)
+	if err != nil {
+		return
+	}
+	// Profiles list common values, so those of columns tagged since are dropped too
+	profiles = slices.DeleteFunc(profiles, func(p ColumnProfile) bool { return m.sensitive.Tagged(tableName, p.Column) })
+	if len(profiles) == 0 {
+		return
+	}
+
+	prompt.WriteString("Column values (profiled):\n")
+	for _, profile := range profiles {
+		prompt.WriteString(fmt.Sprintf("- %s: %s\n", profile.Column, profile.Summary))
+	}
+}
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Retry schedule for questions queued while the provider is unreachable
+const (
+	QueueRetryInitial  = 15 * time.Second
+	QueueRetryMax      = 5 * time.Minute
+	QueueMaxAttempts   = 10
+	queueRetryIncrease = 2
+)
+
+// QueueStatus is the state of a queued question
+type QueueStatus int
+
+const (
+	QueuePending QueueStatus = iota
+	QueueAnswered
+	QueueFailed
+)
+
+// QueuedQuestion is a chat message waiting for the provider to come back
+type QueuedQuestion struct {
+	ID         int
+	Message    string
+	Tables     []string
+	QueuedAt   time.Time
+	Attempts   int
+	LastError  string
+	Status     QueueStatus
+	Response   string
+	AnsweredAt time.Time
+}
+
+// Queue holds the questions of a session that could not reach the provider. It is
+// safe for use by the REPL and its background retry worker at the same time.
+type Queue struct {
+	mu      sync.Mutex
+	items   []*QueuedQuestion
+	nextID  int
+	working bool
+}
+
+// Add queues a message and returns its copy
+func (q *Queue) Add(message string, tables []string) QueuedQuestion {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	item := &QueuedQuestion{ID: q.nextID, Message: message, Tables: tables, QueuedAt: time.Now()}
+	q.items = append(q.items, item)
+	return *item
+}
+
+// Items returns copies of all queued questions, oldest first
+func (q *Queue) Items() []QueuedQuestion {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := make([]QueuedQuestion, len(q.items))
+	for i, item := range q.items {
+		items[i] = *item
+	}
+	return items
+}
+
+// Get returns a copy of the queued question with the ID
+func (q *Queue) Get(id int) (QueuedQuestion, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if item := q.find(id); item != nil {
+		return *item, true
+	}
+	return QueuedQuestion{}, false
+}
+
+// NextPending returns the oldest question still waiting for an answer
+func (q *Queue) NextPending() (QueuedQuestion, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, item := range q.items {
+		if item.Status == QueuePending {
+			return *item, true
+		}
+	}
+	return QueuedQuestion{}, false
+}
+
+// Resolve stores the answer to a queued question
+func (q *Queue) Resolve(id int, response string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if item := q.find(id); item != nil {
+		item.Attempts++
+		item.Status = QueueAnswered
+		item.Response = response
+		item.AnsweredAt = time.Now()
+	}
+}
+
+// Retry records a failed attempt. The question fails for good when the error is not
+// an outage or it ran out of attempts; Retry reports whether it is still pending.
+func (q *Queue) Retry(id int, err error) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item := q.find(id)
+	if item == nil {
+		return false
+	}
+	item.Attempts++
+	item.LastError = err.Error()
+	if !IsProviderUnavailable(err) || item.Attempts >= QueueMaxAttempts {
+		item.Status = QueueFailed
+	}
+	return item.Status == QueuePending
+}
+
+// Clear drops answered and failed questions, or every question when all is set
+func (q *Queue) Clear(all bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	kept := q.items[:0]
+	for _, item := range q.items {
+		if !all && item.Status == QueuePending {
+			kept = append(kept, item)
+		}
+	}
+	removed := len(q.items) - len(kept)
+	q.items = kept
+	return removed
+}
+
+// StartWorker claims the retry worker; it returns false when one is already running
+func (q *Queue) StartWorker() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.working {
+		return false
+	}
+	q.working = true
+	return true
+}
+
+// StopWorkerIfIdle releases the retry worker once no question is pending
+func (q *Queue) StopWorkerIfIdle() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, item := range q.items {
+		if item.Status == QueuePending {
+			return false
+		}
+	}
+	q.working = false
+	return true
+}
+
+func (q *Queue) find(id int) *QueuedQuestion {
+	for _, item := range q.items {
+		if item.ID == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// NextRetryDelay doubles a retry delay up to QueueRetryMax
+func NextRetryDelay(delay time.Duration) time.Duration {
+	return min(delay*queueRetryIncrease, QueueRetryMax)
+}
+
+// IsProviderUnavailable reports whether a chat error means the provider could not be
+// reached or is temporarily down, rather than rejecting the request
+func IsProviderUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"timeout", "deadline exceeded", "connection refused", "connection reset",
+		"no such host", "network is unreachable", ": eof",
+		"status 429", "status 502", "status 503", "status 504",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// describeInterval spaces out DescribeTable calls so indexing a large schema does not
+// load the live database
+const describeInterval = 200 * time.Millisecond
+
+// ErrNoVectorStore is returned when indexing is requested without a connection
+var ErrNoVectorStore = errors.New("no vector store for the current connection")
+
+// TableFailure is a table that could not be indexed
+type TableFailure struct {
+	Table string
+	Err   error
+}
+
+// ReindexStatus is a snapshot of the background job that embeds a connection's tables
+type ReindexStatus struct {
+	Running    bool
+	Cancelled  bool
+	Total      int
+	Done       int    // Tables processed, including failures
+	Current    string // Table being indexed while running
+	Failures   []TableFailure
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Err        error // Why the job stopped before it started on the tables
+}
+
+// reindexJob runs UpdateTableEmbeddings until it finishes or is cancelled
+type reindexJob struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status ReindexStatus
+}
+
+func (j *reindexJob) snapshot() ReindexStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	status.Failures = append([]TableFailure(nil), j.status.Failures...)
+	return status
+}
+
+func (j *reindexJob) update(fn func(*ReindexStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
+
+// StartReindex reloads the schema and embeds the current connection's tables in the
+// background, replacing any job already running
+func (m *Manager) StartReindex() error {
+	vectorStore := m.store()
+	if vectorStore == nil {
+		return ErrNoVectorStore
+	}
+	m.startReindex(vectorStore, false)
+	return nil
+}
+
+// startReindex runs the indexing job for a vector store. With onlyChanged, tables
+// whose description matches the stored embedding are skipped without sampling rows.
+func (m *Manager) startReindex(vectorStore *VectorStore, onlyChanged bool) {
+	m.CancelReindex()
+	// Pick up tables and columns changed since the schema was cached
+	vectorStore.InvalidateSchema()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &reindexJob{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: ReindexStatus{Running: true, StartedAt: time.Now()},
+	}
+	m.reindex = job
+
+	go func() {
+		defer close(job.done)
+		err := vectorStore.updateTableEmbeddings(ctx, describeInterval, onlyChanged, func(p EmbeddingProgress) {
+			job.update(func(s *ReindexStatus) {
+				s.Total, s.Done, s.Current = p.Total, p.Done, p.Next
+				if p.Err != nil {
+					s.Failures = append(s.Failures, TableFailure{Table: p.Table, Err: p.Err})
+				}
+			})
+		})
+		job.update(func(s *ReindexStatus) {
+			s.Running = false
+			s.Current = ""
+			s.FinishedAt = time.Now()
+			switch {
+			case errors.Is(err, context.Canceled):
+				s.Cancelled = true
+			case err != nil:
+				s.Err = err
+			}
+		})
+	}()
+}
+
+// CancelReindex stops the indexing job and waits for it, so the connection it reads
+// can be closed safely. It reports whether a job was running.
+func (m *Manager) CancelReindex() bool {
+	job := m.reindex
+	if job == nil {
+		return false
+	}
+	running := job.snapshot().Running
+	job.cancel()
+	<-job.done
+	return running
+}
+
+// ReindexStatus returns the progress of the latest indexing job, false when none has run
+func (m *Manager) ReindexStatus() (ReindexStatus, bool) {
+	if m.reindex == nil {
+		return ReindexStatus{}, false
+	}
+	return m.reindex.snapshot(), true
+}
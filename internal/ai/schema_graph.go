@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"sqlterm/internal/core"
+)
+
+// schemaGraph holds every table's definition and who references it, so relationship
+// lookups do not describe the whole schema again
+type schemaGraph struct {
+	tables       map[string]*core.TableInfo
+	referencedBy map[string][]string // Tables with a foreign key to the keyed table, sorted
+}
+
+func newSchemaGraph(tables map[string]*core.TableInfo) *schemaGraph {
+	graph := &schemaGraph{tables: tables, referencedBy: make(map[string][]string)}
+	for name, table := range tables {
+		for _, fk := range table.ForeignKeys {
+			referencing := graph.referencedBy[fk.ReferencedTable]
+			if fk.ReferencedTable != name && !slices.Contains(referencing, name) {
+				graph.referencedBy[fk.ReferencedTable] = append(referencing, name)
+			}
+		}
+	}
+	for _, referencing := range graph.referencedBy {
+		sort.Strings(referencing)
+	}
+	return graph
+}
+
+// SetDatabaseType lets the store load the whole schema with a few catalog queries
+// instead of describing tables one at a time
+func (vs *VectorStore) SetDatabaseType(dbType core.DatabaseType) {
+	vs.schemaMu.Lock()
+	defer vs.schemaMu.Unlock()
+	vs.dbType = dbType
+	vs.bulkSchema = true
+}
+
+// InvalidateSchema drops the cached schema, so the next lookup reads it from the database
+func (vs *VectorStore) InvalidateSchema() {
+	vs.schemaMu.Lock()
+	defer vs.schemaMu.Unlock()
+	vs.schema = nil
+}
+
+// schemaGraph returns the session's schema, loading it on first use
+func (vs *VectorStore) schemaGraph() (*schemaGraph, error) {
+	vs.schemaMu.Lock()
+	defer vs.schemaMu.Unlock()
+	if vs.schema != nil {
+		return vs.schema, nil
+	}
+	if vs.connection == nil {
+		return nil, fmt.Errorf("no database connection")
+	}
+
+	tables, err := vs.loadTables()
+	if err != nil {
+		return nil, err
+	}
+	vs.schema = newSchemaGraph(tables)
+	return vs.schema, nil
+}
+
+// loadTables reads every table in bulk when the dialect is known, falling back to
+// describing each table for servers whose catalog cannot be queried that way
+func (vs *VectorStore) loadTables() (map[string]*core.TableInfo, error) {
+	if vs.bulkSchema {
+		if tables, err := core.LoadSchema(vs.connection, vs.dbType); err == nil {
+			return tables, nil
+		}
+	}
+
+	names, err := vs.connection.ListTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	tables := make(map[string]*core.TableInfo, len(names))
+	for _, name := range names {
+		if info, err := vs.connection.DescribeTable(name); err == nil {
+			tables[name] = info
+		}
+	}
+	return tables, nil
+}
+
+// describeTable returns a table's definition from the cached schema, asking the
+// database directly for tables created since it was loaded
+func (vs *VectorStore) describeTable(name string) (*core.TableInfo, error) {
+	if graph, err := vs.schemaGraph(); err == nil {
+		if info, ok := graph.tables[name]; ok {
+			return info, nil
+		}
+	}
+	if vs.connection == nil {
+		return nil, fmt.Errorf("no database connection")
+	}
+	return vs.connection.DescribeTable(name)
+}
@@ -0,0 +1,87 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxGeneratedQueries bounds how many generated queries are remembered for self-correction
+const maxGeneratedQueries = 20
+
+var sqlBlockPattern = regexp.MustCompile("(?s)```(?:sql|SQL)\\s*\\n(.*?)```")
+
+// ExtractSQL returns the contents of the ```sql blocks in a model response
+func ExtractSQL(response string) []string {
+	var queries []string
+	for _, match := range sqlBlockPattern.FindAllStringSubmatch(response, -1) {
+		if query := strings.TrimSpace(match[1]); query != "" {
+			queries = append(queries, query)
+		}
+	}
+	return queries
+}
+
+// normalizeQuery reduces a query to a form that survives reformatting and copying:
+// lower case without whitespace or a trailing semicolon
+func normalizeQuery(query string) string {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	return strings.ToLower(strings.Join(strings.Fields(query), ""))
+}
+
+// rememberGeneratedSQL records the queries in a response so a run of one can be
+// recognised as generated, along with the question it answers
+func (m *Manager) rememberGeneratedSQL(response string) {
+	question := ""
+	if m.conversationCtx != nil {
+		question = m.conversationCtx.OriginalQuery
+	}
+	for _, query := range ExtractSQL(response) {
+		m.generatedSQL = append(m.generatedSQL, generatedQuery{normalized: normalizeQuery(query), question: question})
+	}
+	if len(m.generatedSQL) > maxGeneratedQueries {
+		m.generatedSQL = m.generatedSQL[len(m.generatedSQL)-maxGeneratedQueries:]
+	}
+}
+
+// IsGeneratedQuery reports whether a query was written by the model in this session
+func (m *Manager) IsGeneratedQuery(query string) bool {
+	normalized := normalizeQuery(query)
+	for _, generated := range m.generatedSQL {
+		if generated.normalized == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// CorrectQuery sends a failing query and the database error back to the model and
+// returns its corrected query. The request is recorded in usage like any other chat.
+func (m *Manager) CorrectQuery(ctx context.Context, query, dbError string) (string, error) {
+	var sections []promptSection
+	if m.conversationCtx != nil && len(m.conversationCtx.LoadedTables) > 0 {
+		sections = m.generateSQLGenerationPrompt(m.conversationCtx)
+	} else {
+		sections = []promptSection{fixedSection("You are an AI assistant specialized in SQL query generation.\n")}
+	}
+	sections = append(sections, fixedSection(m.dialectInstruction()), fixedSection(
+		"\nA query you wrote failed. Fix it using the schemas above and the error.\n"+
+			"Reply with the corrected query in a single ```sql block and one sentence on what was wrong.\n"))
+
+	message := fmt.Sprintf("This query failed:\n```sql\n%s\n```\nDatabase error:\n%s", strings.TrimSpace(query), dbError)
+	const maxTokens = 4000
+	systemPrompt, _ := fitPrompt(sections, m.promptLimit(maxTokens), m.countTokens(message), m.countTokens)
+
+	response, err := m.Chat(ctx, message, systemPrompt)
+	if err != nil {
+		return "", err
+	}
+	queries := ExtractSQL(response)
+	if len(queries) == 0 {
+		return "", errors.New(m.i18nMgr.Get("self_correct_no_query"))
+	}
+	m.rememberGeneratedSQL(response)
+	return queries[0], nil
+}
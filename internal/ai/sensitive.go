@@ -0,0 +1,26 @@
+package ai
+
+import "sqlterm/internal/core"
+
+// SetSensitiveColumns sets the columns of the active connection whose values are kept
+// out of sample data, JSON structures and column profiles
+func (m *Manager) SetSensitiveColumns(sensitive *core.SensitiveColumns) {
+	m.storeMu.Lock()
+	defer m.storeMu.Unlock()
+	m.sensitive = sensitive
+	if m.vectorStore != nil {
+		m.vectorStore.sensitive.Store(sensitive)
+	}
+}
+
+// ForgetSampleData drops the sample rows stored for a table, or for every table when
+// table is empty, as they may include a column tagged sensitive since. /reindex samples
+// the tables again without it.
+func (m *Manager) ForgetSampleData(table string) error {
+	vectorStore := m.store()
+	if vectorStore == nil {
+		return nil
+	}
+	_, err := vectorStore.db.Exec(`UPDATE table_embeddings SET sample_data = '' WHERE ? = '' OR table_name = ?`, table, table)
+	return err
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"sqlterm/internal/core"
+)
+
+// PromptsDir is the directory under the config dir holding prompt template overrides
+const PromptsDir = "prompts"
+
+// Prompt templates that can be overridden with <config dir>/prompts/<name>.tmpl
+const (
+	TemplateDiscovery      = "discovery"
+	TemplateSchemaAnalysis = "schema_analysis"
+	TemplateSQLGeneration  = "sql_generation"
+	TemplateGuidelines     = "guidelines"
+)
+
+// PromptData is what prompt templates can refer to
+type PromptData struct {
+	Request       string        // The user's original request
+	Dialect       string        // Database type of the connection, e.g. postgres
+	ServerVersion string        // Version the database server reports, may be empty
+	AllTables     []string      // Every table in the database
+	Tables        []PromptTable // Loaded table schemas, most relevant first
+	RelatedTables []string      // Tables referenced by loaded ones but not loaded yet
+	SchemaRequest string        // The JSON block the model replies with to ask for schemas
+}
+
+// PromptTable is a loaded table schema as seen by templates
+type PromptTable struct {
+	Name        string
+	Columns     []core.ColumnInfo
+	ForeignKeys []core.ForeignKeyInfo
+}
+
+var promptTemplateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// phaseTemplate returns the template name for a conversation phase
+func phaseTemplate(phase ConversationPhase) string {
+	switch phase {
+	case PhaseDiscovery:
+		return TemplateDiscovery
+	case PhaseSchemaAnalysis:
+		return TemplateSchemaAnalysis
+	default:
+		return TemplateSQLGeneration
+	}
+}
+
+// promptData collects the template variables for a conversation
+func (m *Manager) promptData(convCtx *ConversationContext, allTables []string) PromptData {
+	data := PromptData{
+		Dialect:       m.dialect,
+		ServerVersion: m.serverVersion,
+		AllTables:     allTables,
+		SchemaRequest: schemaRequestExample,
+	}
+	if convCtx == nil {
+		return data
+	}
+
+	data.Request = convCtx.OriginalQuery
+	for _, name := range rankLoadedTables(convCtx) {
+		info := convCtx.LoadedTables[name]
+		data.Tables = append(data.Tables, PromptTable{Name: name, Columns: info.Columns, ForeignKeys: info.ForeignKeys})
+	}
+	for _, name := range convCtx.RelatedTables {
+		if _, loaded := convCtx.LoadedTables[name]; !loaded && !m.contains(data.RelatedTables, name) {
+			data.RelatedTables = append(data.RelatedTables, name)
+		}
+	}
+	return data
+}
+
+// renderPromptTemplate renders a user template in place of a built-in prompt. It
+// reports false when there is no template or it fails, so the built-in is used.
+func (m *Manager) renderPromptTemplate(name string, data PromptData) (string, bool) {
+	if m.configDir == "" {
+		return "", false
+	}
+
+	path := filepath.Join(m.configDir, PromptsDir, name+".tmpl")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			fmt.Printf(m.i18nMgr.Get("prompt_template_warning"), path, err)
+		}
+		return "", false
+	}
+
+	tmpl, err := template.New(name).Funcs(promptTemplateFuncs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		fmt.Printf(m.i18nMgr.Get("prompt_template_warning"), path, err)
+		return "", false
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		fmt.Printf(m.i18nMgr.Get("prompt_template_warning"), path, err)
+		return "", false
+	}
+	return sb.String(), true
+}
@@ -2,8 +2,10 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
 	"sqlterm/internal/config"
 	"sqlterm/internal/core"
+	"strings"
 	"time"
 )
 
@@ -30,6 +32,30 @@ type ChatRequest struct {
 	Temperature float64       `json:"temperature,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	Stream      bool          `json:"stream,omitempty"`
+	Tools       []Tool        `json:"tools,omitempty"`
+}
+
+// Tool describes a function the model may call instead of answering in text
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction is the name and JSON Schema parameters of a callable function
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+// ToolCall is a function call returned by the model. Arguments holds a JSON object.
+type ToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
 }
 
 // ChatResponse represents a chat completion response
@@ -41,8 +67,9 @@ type ChatResponse struct {
 	Choices []struct {
 		Index   int `json:"index"`
 		Message struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
+			Role      string     `json:"role"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
 	} `json:"choices"`
@@ -55,11 +82,30 @@ type ChatResponse struct {
 
 // ModelInfo represents model information
 type ModelInfo struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Provider    string   `json:"provider"`
-	Pricing     *Pricing `json:"pricing,omitempty"`
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Description   string     `json:"description"`
+	Provider      string     `json:"provider"`
+	Pricing       *Pricing   `json:"pricing,omitempty"`
+	ContextLength int        `json:"context_length,omitempty"` // Tokens; 0 when the provider does not say
+	Tools         Capability `json:"tools,omitempty"`          // Function calling
+	JSONMode      Capability `json:"json_mode,omitempty"`      // Structured JSON output
+}
+
+// Capability records whether a model supports a feature, as far as its provider says
+type Capability int
+
+const (
+	CapabilityUnknown Capability = iota
+	CapabilityYes
+	CapabilityNo
+)
+
+func capabilityOf(supported bool) Capability {
+	if supported {
+		return CapabilityYes
+	}
+	return CapabilityNo
 }
 
 // Pricing represents model pricing information
@@ -79,6 +125,8 @@ type PromptEntry struct {
 	InputTokens  int             `json:"input_tokens"`
 	OutputTokens int             `json:"output_tokens"`
 	Cost         float64         `json:"cost"`
+	Latency      time.Duration   `json:"latency"`
+	Rating       Rating          `json:"rating,omitempty"` // Set with /ai good or /ai bad
 }
 
 // PromptHistory holds the history of AI prompts
@@ -152,6 +200,21 @@ func (p ConversationPhase) String() string {
 	}
 }
 
+// ParseConversationPhase accepts a phase name as shown by String or its short form
+// (discovery, schema, sql)
+func ParseConversationPhase(name string) (ConversationPhase, bool) {
+	switch strings.ToLower(name) {
+	case "discovery":
+		return PhaseDiscovery, true
+	case "schema", "schemaanalysis", "schema-analysis":
+		return PhaseSchemaAnalysis, true
+	case "sql", "sqlgeneration", "sql-generation":
+		return PhaseSQLGeneration, true
+	default:
+		return 0, false
+	}
+}
+
 // ConversationTurn represents a single turn in the conversation
 type ConversationTurn struct {
 	UserMessage   string            `json:"user_message"`
@@ -1,10 +1,23 @@
 package ai
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+	"sqlterm/internal/i18n"
 )
 
 func TestParseModelString(t *testing.T) {
@@ -442,3 +455,1050 @@ func BenchmarkFormatPrice(b *testing.B) {
 		FormatPrice(price)
 	}
 }
+
+func TestManager_ResponseLanguage(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured string
+		override   string
+		expected   string
+	}{
+		{name: "English adds no instruction", configured: "en_au", expected: ""},
+		{name: "Configured Chinese", configured: "zh_cn", expected: "Respond in Simplified Chinese."},
+		{name: "Override wins", configured: "zh_cn", override: "en_au", expected: ""},
+		{name: "Free-form language name", configured: "en_au", override: "Japanese", expected: "Respond in Japanese."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &Manager{config: &config.Config{Language: tc.configured}}
+			m.SetResponseLanguage(tc.override)
+
+			prompt := m.GenerateSystemPrompt([]string{"users"}, "")
+			if tc.expected == "" {
+				if strings.Contains(prompt, "Respond in") {
+					t.Errorf("Expected no language instruction, got:\n%s", prompt)
+				}
+				return
+			}
+			if !strings.Contains(prompt, tc.expected) || !strings.Contains(prompt, "column names and code blocks unchanged") {
+				t.Errorf("Expected %q in prompt, got:\n%s", tc.expected, prompt)
+			}
+
+			m.ClearConversation()
+			if m.ResponseLanguage() != tc.configured {
+				t.Errorf("Expected override cleared with the conversation, got %q", m.ResponseLanguage())
+			}
+		})
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		provider config.Provider
+		expected int
+	}{
+		{name: "Empty", text: "", provider: config.ProviderOpenRouter, expected: 0},
+		{name: "Latin on OpenRouter", text: "SELECT * FROM users", provider: config.ProviderOpenRouter, expected: 5},
+		{name: "Latin on Ollama", text: "SELECT * FROM users", provider: config.ProviderOllama, expected: 6},
+		{name: "CJK counts per character", text: "查询用户", provider: config.ProviderOpenRouter, expected: 4},
+		{name: "Mixed", text: "用户 users", provider: config.ProviderOpenRouter, expected: 4},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := EstimateTokens(tc.text, tc.provider); got != tc.expected {
+				t.Errorf("Expected %d tokens, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFitPrompt(t *testing.T) {
+	// One token per character keeps the arithmetic readable
+	count := func(s string) int { return len(s) }
+	sections := []promptSection{
+		fixedSection("head|"),
+		{kind: sectionTable, table: "orders", relevance: 3, text: "orders|"},
+		{kind: sectionSample, table: "orders", relevance: 3, text: "orders-json|"},
+		{kind: sectionTable, table: "audit", relevance: 0, text: "audit|"},
+		{kind: sectionSample, table: "audit", relevance: 0, text: "audit-json|"},
+		fixedSection("tail"),
+	}
+
+	testCases := []struct {
+		name           string
+		limit          int
+		expected       string
+		droppedSamples int
+		droppedTables  []string
+	}{
+		{name: "Unknown window keeps everything", limit: 0, expected: "head|orders|orders-json|audit|audit-json|tail"},
+		{name: "Fits", limit: 100, expected: "head|orders|orders-json|audit|audit-json|tail"},
+		{name: "Least relevant sample goes first", limit: 40, expected: "head|orders|orders-json|audit|tail", droppedSamples: 1},
+		{name: "All samples before tables", limit: 28, expected: "head|orders|audit|tail", droppedSamples: 2},
+		{name: "Then least relevant tables", limit: 20, expected: "head|orders|tail", droppedSamples: 2, droppedTables: []string{"audit"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prompt, budget := fitPrompt(sections, tc.limit, 0, count)
+			if prompt != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, prompt)
+			}
+			if budget.DroppedSamples != tc.droppedSamples || !reflect.DeepEqual(budget.DroppedTables, tc.droppedTables) {
+				t.Errorf("Expected %d samples and tables %v dropped, got %+v", tc.droppedSamples, tc.droppedTables, budget)
+			}
+			if budget.Tokens != len(prompt) {
+				t.Errorf("Expected %d tokens, got %d", len(prompt), budget.Tokens)
+			}
+		})
+	}
+}
+
+func TestResultAttachment_Format(t *testing.T) {
+	attachment := &ResultAttachment{
+		Query:   "SELECT id, email, note, api_key FROM users",
+		Columns: []string{"id", "contact_email", "note", "api_key"},
+		Types:   []string{"INTEGER", "TEXT", "TEXT", "TEXT"},
+		Rows: [][]string{
+			{"1", "a@example.com", "paid with 4111 1111 1111 1111", "sk-123"},
+			{"2", "b@example.com", "ping me at c@example.org | " + strings.Repeat("x", 80), "sk-456"},
+			{"3", "", "NULL", ""},
+		},
+		TotalRows: 120,
+	}
+
+	formatted := attachment.Format(2)
+	for _, leaked := range []string{"a@example.com", "c@example.org", "4111", "sk-123"} {
+		if strings.Contains(formatted, leaked) {
+			t.Errorf("Expected %q to be redacted:\n%s", leaked, formatted)
+		}
+	}
+	for _, expected := range []string{"| id (integer) | contact_email (text) |", "paid with [redacted]", "ping me at [redacted] \\|", "...", "(2 of 120 rows shown"} {
+		if !strings.Contains(formatted, expected) {
+			t.Errorf("Expected %q in attachment:\n%s", expected, formatted)
+		}
+	}
+	if strings.Contains(formatted, "| 3 |") {
+		t.Errorf("Expected only 2 rows:\n%s", formatted)
+	}
+}
+
+func TestParseSchemaRequest(t *testing.T) {
+	testCases := []struct {
+		name     string
+		response string
+		expected []string
+	}{
+		{
+			name:     "json block",
+			response: "I will need two tables.\n```json\n{\"action\": \"need_schema\", \"tables\": [\"orders\", \"customers\",]}\n```",
+			expected: []string{"orders", "customers"},
+		},
+		{
+			name:     "inline json",
+			response: `Sure: {"action":"request_schema","tables":["public.users"]}`,
+			expected: []string{"public.users"},
+		},
+		{
+			name:     "tables as a string",
+			response: `{"action": "need_schema", "tables": "orders, customers"}`,
+			expected: []string{"orders", "customers"},
+		},
+		{
+			name:     "prose with markdown",
+			response: "To answer this, I need schema information for: **orders**, `order_items` and customers.",
+			expected: []string{"orders", "order_items", "customers"},
+		},
+		{
+			name:     "other json action falls back to prose",
+			response: "```json\n{\"action\": \"answer\"}\n```\nPlease provide the schema for: products",
+			expected: []string{"products"},
+		},
+		{
+			name:     "no request",
+			response: "Here is your query:\n```sql\nSELECT 1;\n```",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := parseSchemaRequest(tc.response); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseConversationPhase(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected ConversationPhase
+		ok       bool
+	}{
+		{"discovery", PhaseDiscovery, true},
+		{"Schema", PhaseSchemaAnalysis, true},
+		{"sql-generation", PhaseSQLGeneration, true},
+		{"done", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			phase, ok := ParseConversationPhase(tc.name)
+			if phase != tc.expected || ok != tc.ok {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tc.expected, tc.ok, phase, ok)
+			}
+		})
+	}
+}
+
+func TestToolCallsText(t *testing.T) {
+	var call ToolCall
+	call.Function.Name = ActionNeedSchema
+	call.Function.Arguments = `{"tables": ["orders", "customers"]}`
+
+	text := toolCallsText([]ToolCall{call})
+	if got := parseSchemaRequest(text); !reflect.DeepEqual(got, []string{"orders", "customers"}) {
+		t.Errorf("Expected tool call tables, got %v from %q", got, text)
+	}
+}
+
+func TestIsToolsUnsupported(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New(`API request failed with status 404: {"error":{"message":"No endpoints found that support tool use"}}`), true},
+		{errors.New(`API request failed with status 400: {"error":"llama2 does not support tools"}`), true},
+		{errors.New("API request failed with status 401: unauthorized"), false},
+		{errors.New("request failed: context deadline exceeded"), false},
+	}
+
+	for _, tc := range testCases {
+		if got := isToolsUnsupported(tc.err); got != tc.expected {
+			t.Errorf("isToolsUnsupported(%q) = %v, expected %v", tc.err, got, tc.expected)
+		}
+	}
+}
+
+func TestManager_IsGeneratedQuery(t *testing.T) {
+	m := &Manager{}
+	m.rememberGeneratedSQL("Try this:\n```sql\nSELECT id, name\nFROM users\nWHERE active = 1;\n```\nand\n```SQL\nselect count(*) from orders\n```")
+
+	testCases := []struct {
+		query    string
+		expected bool
+	}{
+		{"SELECT id, name FROM users WHERE active = 1", true},
+		{"select id,name\n  from users\n where active = 1;", true},
+		{"SELECT COUNT(*) FROM orders;", true},
+		{"SELECT id FROM users", false},
+	}
+
+	for _, tc := range testCases {
+		if got := m.IsGeneratedQuery(tc.query); got != tc.expected {
+			t.Errorf("IsGeneratedQuery(%q) = %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestManager_RenderPromptTemplate(t *testing.T) {
+	configDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configDir, PromptsDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	template := "{{.Dialect}}: {{.Request}}\n{{range .Tables}}{{.Name}}({{range $i, $c := .Columns}}{{if $i}}, {{end}}{{$c.Name}}{{end}})\n{{end}}" +
+		"related: {{join .RelatedTables \", \"}}"
+	if err := os.WriteFile(filepath.Join(configDir, PromptsDir, TemplateSQLGeneration+".tmpl"), []byte(template), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{config: &config.Config{}, configDir: configDir, dialect: "postgres"}
+	convCtx := NewConversationContext("count orders per customer")
+	convCtx.LoadedTables["orders"] = &core.TableInfo{
+		Name:        "orders",
+		Columns:     []core.ColumnInfo{{Name: "id"}, {Name: "customer_id"}},
+		ForeignKeys: []core.ForeignKeyInfo{{Column: "customer_id", ReferencedTable: "customers", ReferencedColumn: "id"}},
+	}
+	convCtx.RelatedTables = []string{"customers"}
+	convCtx.CurrentPhase = PhaseSQLGeneration
+
+	prompt, _, err := m.generateConversationalPrompt(convCtx, nil, "go", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "postgres: count orders per customer\norders(id, customer_id)\nrelated: customers"
+	if !strings.HasPrefix(prompt, expected) {
+		t.Errorf("Expected prompt to start with %q, got %q", expected, prompt)
+	}
+	if !strings.Contains(prompt, "The database is PostgreSQL.") {
+		t.Errorf("Expected the dialect instruction after a custom template, got %q", prompt)
+	}
+
+	// Phases without a template keep the built-in prompt
+	convCtx.CurrentPhase = PhaseSchemaAnalysis
+	prompt, _, err = m.generateConversationalPrompt(convCtx, nil, "go", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "## Table: orders") {
+		t.Errorf("Expected the built-in schema analysis prompt, got %q", prompt)
+	}
+}
+
+func TestGlossary_Match(t *testing.T) {
+	path := filepath.Join(t.TempDir(), GlossaryFile)
+	content := `terms:
+  - term: revenue
+    definition: orders.total - orders.refund
+    tables: [orders]
+  - term: active user
+    aliases: [活跃用户]
+    definition: users.last_login within the last 30 days
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	glossary, err := LoadGlossary(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		text     string
+		expected []string
+	}{
+		{"Monthly Revenue by region", []string{"revenue"}},
+		{"how many active users signed up", []string{"active user"}},
+		{"上个月的活跃用户", []string{"active user"}},
+		{"revenues of active users", []string{"revenue", "active user"}},
+		{"revenuestream", nil},
+	}
+
+	for _, tc := range testCases {
+		var terms []string
+		for _, term := range glossary.Match(tc.text) {
+			terms = append(terms, term.Term)
+		}
+		if !reflect.DeepEqual(terms, tc.expected) {
+			t.Errorf("Match(%q) = %v, expected %v", tc.text, terms, tc.expected)
+		}
+	}
+
+	if _, err := LoadGlossary(filepath.Join(t.TempDir(), "missing.yaml")); err != nil {
+		t.Errorf("Expected a missing glossary to load empty, got %v", err)
+	}
+}
+
+func TestVectorStore_QueryExamples(t *testing.T) {
+	store, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	m := &Manager{vectorStore: store, conversationCtx: NewConversationContext("total revenue by month")}
+	m.rememberGeneratedSQL("```sql\nSELECT month, SUM(total) FROM orders GROUP BY month\n```")
+	m.AcceptQuery("select month, sum(total) from orders group by month;")
+	m.AcceptQuery("SELECT 1") // Not generated, so not stored
+	store.AddQueryExample("list customers in Melbourne", "SELECT * FROM customers WHERE city = 'Melbourne'")
+
+	examples, err := store.SimilarExamples("revenue by month for 2024", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 1 || examples[0].Question != "total revenue by month" ||
+		examples[0].SQL != "select month, sum(total) from orders group by month;" {
+		t.Fatalf("Expected the accepted revenue query, got %+v", examples)
+	}
+
+	m.AcceptQuery("SELECT month, SUM(total) FROM orders GROUP BY month")
+	examples, _ = store.SimilarExamples("total revenue by month", 3)
+	if len(examples) != 2 {
+		t.Errorf("Expected both spellings of the query to be kept, got %+v", examples)
+	}
+}
+
+func TestManager_ColumnProfiles(t *testing.T) {
+	store, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	m := &Manager{vectorStore: store}
+	if err := m.RememberProfiles("orders", []ColumnProfile{
+		{Column: "status", Summary: "0.0% null, 3 distinct"},
+		{Column: "total", Summary: "mean 12"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// A later profile of one column replaces only that column
+	if err := m.RememberProfiles("orders", []ColumnProfile{{Column: "total", Summary: "mean 15"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompt strings.Builder
+	m.writeColumnProfiles(&prompt, "orders")
+	want := "Column values (profiled):\n- status: 0.0% null, 3 distinct\n- total: mean 15\n"
+	if prompt.String() != want {
+		t.Errorf("Expected profiles\n%s\ngot\n%s", want, prompt.String())
+	}
+
+	prompt.Reset()
+	m.writeColumnProfiles(&prompt, "customers")
+	if prompt.Len() != 0 {
+		t.Errorf("Expected nothing for an unprofiled table, got %q", prompt.String())
+	}
+}
+
+func TestManager_RateLastResponse(t *testing.T) {
+	store, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	m := &Manager{vectorStore: store, promptHistory: &PromptHistory{MaxSize: 10}}
+	if _, err := m.RateLastResponse(RatingGood); err != ErrNothingToRate {
+		t.Fatalf("Expected ErrNothingToRate without history, got %v", err)
+	}
+
+	rate := func(question string, tables []string, rating Rating) int {
+		m.conversationCtx = NewConversationContext(question)
+		for _, table := range tables {
+			m.conversationCtx.LoadedTables[table] = &core.TableInfo{Name: table}
+		}
+		m.promptHistory.Entries = append(m.promptHistory.Entries, PromptEntry{
+			AIResponse: "```sql\nSELECT SUM(total) FROM " + tables[0] + "\n```",
+		})
+		stored, err := m.RateLastResponse(rating)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+
+	if stored := rate("total revenue by month", []string{"orders"}, RatingGood); stored != 1 {
+		t.Errorf("Expected a good answer to store 1 example, got %d", stored)
+	}
+	rate("total revenue by month", []string{"legacy_orders"}, RatingBad)
+	rate("total revenue by month", []string{"orders"}, RatingBad)
+
+	if got := m.promptHistory.Entries[2].Rating; got != RatingBad {
+		t.Errorf("Expected the last entry to be rated bad, got %d", got)
+	}
+	examples, _ := store.SimilarExamples("total revenue by month", 3)
+	if len(examples) != 0 {
+		t.Errorf("Expected the bad rating to remove the example, got %+v", examples)
+	}
+
+	weights, err := store.tableFeedbackWeights(store.generateEmbedding("revenue by month"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weights["legacy_orders"] != feedbackFloor || weights["orders"] != 0.75 {
+		t.Errorf("Expected legacy_orders at the floor and orders in between, got %v", weights)
+	}
+	if _, ok := weights["customers"]; ok {
+		t.Errorf("Expected unrated tables to be left out, got %v", weights)
+	}
+}
+
+func TestUsageStore_GetLatencyStats(t *testing.T) {
+	vectorStore, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vectorStore.Close()
+	store, err := NewUsageStore(vectorStore, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 20; i++ {
+		latency := time.Duration(i) * 100 * time.Millisecond
+		if err := store.RecordUsage("s1", config.ProviderOllama, "llama3:8b", 100, 20, 0, latency, "q", "a", "p"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Entries recorded before latency was tracked are left out
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 100, 20, 0.01, 0, "q", "a", "p")
+
+	stats, err := store.GetLatencyStats(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("Expected stats for one model, got %+v", stats)
+	}
+	got := stats[0]
+	if got.Model != "llama3:8b" || got.Requests != 20 || got.P50 != time.Second || got.P95 != 1900*time.Millisecond {
+		t.Errorf("Expected 20 requests with p50 1s and p95 1.9s, got %+v", got)
+	}
+
+	usage, _ := store.GetTodayUsage()
+	if len(usage) != 21 {
+		t.Fatalf("Expected 21 usage entries, got %d", len(usage))
+	}
+}
+
+func TestUsageStore_GetUsageSummary(t *testing.T) {
+	vectorStore, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vectorStore.Close()
+	store, err := NewUsageStore(vectorStore, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := store.GetUsageSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *summary != (UsageSummary{}) {
+		t.Errorf("Expected zero totals on an empty database, got %+v", summary)
+	}
+
+	// A session running past midnight leaves two earlier days in the details
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 100, 10, 0.10, 0, "q", "a", "p")
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 200, 20, 0.20, 0, "q", "a", "p")
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 400, 40, 0.40, 0, "q", "a", "p")
+	store.RecordUsage("s1", config.ProviderOllama, "llama3:8b", 800, 80, 0, 0, "q", "a", "p")
+	for id, daysAgo := range map[int]int{1: 2, 2: 1, 3: 10} {
+		if _, err := store.db.Exec(`UPDATE usage_details SET request_time = ? WHERE id = ?`,
+			time.Now().AddDate(0, 0, -daysAgo), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.lastProcessedDate = ""
+	if err := store.handleDayChange(); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err = store.GetUsageSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	today := UsageTotals{Requests: 1, InputTokens: 800, OutputTokens: 80}
+	if summary.Today != today {
+		t.Errorf("Expected only the Ollama request today, got %+v", summary.Today)
+	}
+	week := summary.Last7Days
+	if week.Requests != 3 || week.InputTokens != 1100 || week.OutputTokens != 110 || math.Abs(week.Cost-0.30) > 1e-9 {
+		t.Errorf("Expected both earlier days and today in the week, got %+v", week)
+	}
+
+	stats, err := store.GetProviderModelStats(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stats["openrouter"]["openai/gpt-4o"]; got.Requests != 2 {
+		t.Errorf("Expected 2 OpenRouter requests within 7 days, got %+v", got)
+	}
+	if got := stats["ollama"]["llama3:8b"]; got.Requests != 1 {
+		t.Errorf("Expected today's Ollama request, got %+v", got)
+	}
+}
+
+func TestUsageStore_Retention(t *testing.T) {
+	vectorStore, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vectorStore.Close()
+	store, err := NewUsageStore(vectorStore, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 100, 10, 0.10, 0, "recent", "a", "p")
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 200, 20, 0.20, 0, "old", "a", "p")
+	for id, daysAgo := range map[int]int{1: 2, 2: 5} {
+		if _, err := store.db.Exec(`UPDATE usage_details SET request_time = ? WHERE id = ?`,
+			time.Now().AddDate(0, 0, -daysAgo), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.lastProcessedDate = ""
+
+	day := func(daysAgo int) string { return time.Now().AddDate(0, 0, -daysAgo).Format("2006-01-02") }
+	recent, err := store.GetUsageDetails(day(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].UserMessage != "recent" {
+		t.Errorf("Expected the request within retention to keep its details, got %+v", recent)
+	}
+	if old, _ := store.GetUsageDetails(day(5)); len(old) != 0 {
+		t.Errorf("Expected details past retention to be dropped, got %+v", old)
+	}
+
+	// Both days were rolled up before the old details went
+	stats, err := store.GetDailyStats(day(5), day(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Errorf("Expected daily stats for both days, got %+v", stats)
+	}
+
+	// Rolling over again does not count a retained day twice
+	store.lastProcessedDate = ""
+	if err := store.handleDayChange(); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := store.GetDailyStats(day(2), day(2)); len(stats) != 1 || stats[0].TotalRequests != 1 {
+		t.Errorf("Expected one request on the retained day, got %+v", stats)
+	}
+}
+
+func TestIsProviderUnavailable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{context.DeadlineExceeded, true},
+		{errors.New(`request failed: Post "http://localhost:11434/api/chat": dial tcp [::1]:11434: connect: connection refused`), true},
+		{errors.New(`request failed: Post "https://openrouter.ai/api/v1/chat/completions": EOF`), true},
+		{errors.New("API request failed with status 503: overloaded"), true},
+		{errors.New("API request failed with status 401: invalid key"), false},
+		{errors.New("model llama3 not found"), false},
+	}
+	for _, tt := range tests {
+		if got := IsProviderUnavailable(tt.err); got != tt.want {
+			t.Errorf("IsProviderUnavailable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestQueue(t *testing.T) {
+	var q Queue
+	first := q.Add("top customers", nil)
+	second := q.Add("monthly revenue", []string{"orders"})
+	if !q.StartWorker() || q.StartWorker() {
+		t.Fatal("Expected only one worker to start")
+	}
+
+	if !q.Retry(first.ID, errors.New("request failed: connection refused")) {
+		t.Error("Expected an outage to keep the question pending")
+	}
+	if next, _ := q.NextPending(); next.ID != first.ID || next.Attempts != 1 {
+		t.Errorf("Expected the first question to stay next with 1 attempt, got %+v", next)
+	}
+	q.Resolve(first.ID, "SELECT 1")
+	if q.Retry(second.ID, errors.New("API request failed with status 400: bad request")) {
+		t.Error("Expected a rejected request to fail for good")
+	}
+
+	if _, ok := q.NextPending(); ok {
+		t.Error("Expected nothing pending")
+	}
+	if !q.StopWorkerIfIdle() {
+		t.Error("Expected the idle worker to stop")
+	}
+	if got, _ := q.Get(first.ID); got.Status != QueueAnswered || got.Response != "SELECT 1" {
+		t.Errorf("Expected the first question answered, got %+v", got)
+	}
+	q.Add("pending", nil)
+	if removed := q.Clear(false); removed != 2 || len(q.Items()) != 1 {
+		t.Errorf("Expected clear to keep only the pending question, removed %d, left %+v", removed, q.Items())
+	}
+}
+
+func TestManager_SendFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model loading", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"SELECT 1"}}],"usage":{"prompt_tokens":10,"completion_tokens":2}}`))
+	}))
+	defer up.Close()
+
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.SetProvider(config.ProviderOllama, "llama3.2")
+	cfg.SetBaseURL(config.ProviderOllama, down.URL)
+	cfg.SetBaseURL(config.ProviderLMStudio, up.URL)
+	cfg.AI.Fallbacks = []config.FallbackConfig{
+		{Provider: config.ProviderOllama}, // Same as the primary, so skipped
+		{Provider: config.ProviderLMStudio, Model: "qwen2.5-coder"},
+	}
+	m := &Manager{config: cfg, i18nMgr: i18nMgr, promptHistory: &PromptHistory{MaxSize: 10}}
+	if err := m.initializeClient(); err != nil {
+		t.Fatal(err)
+	}
+
+	if chain := m.failoverChain(); len(chain) != 2 {
+		t.Fatalf("Expected the primary and one fallback, got %+v", chain)
+	}
+	response, err := m.Chat(context.Background(), "count users", "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "SELECT 1" {
+		t.Errorf("Expected the fallback's answer, got %q", response)
+	}
+	entry, _ := m.LastPromptEntry()
+	if entry.Provider != config.ProviderLMStudio || entry.Model != "qwen2.5-coder" {
+		t.Errorf("Expected the history to name the fallback, got %s/%s", entry.Provider, entry.Model)
+	}
+
+	cfg.AI.Fallbacks = nil
+	if _, err := m.Chat(context.Background(), "count users", "system"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the primary's error without fallbacks, got %v", err)
+	}
+}
+
+func TestManager_OverBudget(t *testing.T) {
+	vectorStore, err := NewVectorStore(t.TempDir(), "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vectorStore.Close()
+	store, err := NewUsageStore(vectorStore, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.RecordUsage("s1", config.ProviderOpenRouter, "openai/gpt-4o", 1000, 200, 0.60, time.Second, "q", "a", "p")
+
+	m := &Manager{config: config.DefaultConfig(), usageStore: store}
+	if m.overBudget(config.ProviderOpenRouter) {
+		t.Error("Expected no limit without a budget")
+	}
+	m.config.AI.DailyBudget = 0.50
+	if !m.overBudget(config.ProviderOpenRouter) {
+		t.Error("Expected the paid provider to be over the $0.50 budget")
+	}
+	if m.overBudget(config.ProviderOllama) {
+		t.Error("Expected local providers to ignore the budget")
+	}
+}
+
+func TestManager_SendConfirmation(t *testing.T) {
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.DefaultConfig()
+	cfg.SetProvider(config.ProviderOpenRouter, "anthropic/claude-3.5-sonnet")
+	m := &Manager{config: cfg, configDir: t.TempDir(), i18nMgr: i18nMgr}
+
+	if m.NeedsSendConfirmation() {
+		t.Error("Expected no confirmation by default")
+	}
+	if err := m.SetSendConfirmation(config.ProviderOpenRouter, true); err != nil {
+		t.Fatal(err)
+	}
+	if !m.NeedsSendConfirmation() {
+		t.Error("Expected confirmation once turned on for the provider")
+	}
+
+	estimate := m.EstimateChat("how many orders shipped last week", []string{"orders", "customers"})
+	if estimate.Model != "anthropic/claude-3.5-sonnet" || estimate.InputTokens == 0 || estimate.Cost <= 0 {
+		t.Errorf("Expected a priced estimate, got %+v", estimate)
+	}
+
+	// Local providers are free, so they never ask even when listed
+	cfg.AI.ConfirmSend = append(cfg.AI.ConfirmSend, config.ProviderOllama)
+	cfg.SetProvider(config.ProviderOllama, "llama3.2")
+	if m.NeedsSendConfirmation() {
+		t.Error("Expected local providers to skip confirmation")
+	}
+
+	if err := m.SetSendConfirmation(config.ProviderOpenRouter, false); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(cfg.AI.ConfirmSend, config.ProviderOpenRouter) {
+		t.Errorf("Expected openrouter to be removed, got %v", cfg.AI.ConfirmSend)
+	}
+}
+
+func TestOllamaClient_PullModel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Name string `json:"name"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Name == "missing" {
+			w.Write([]byte(`{"status":"pulling manifest"}` + "\n" + `{"error":"pull model manifest: file does not exist"}` + "\n"))
+			return
+		}
+		w.Write([]byte(`{"status":"pulling manifest"}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a07","total":2000,"completed":500}
+{"status":"pulling 6a0746a1ec1a","digest":"sha256:6a07","total":2000,"completed":2000}
+{"status":"success"}
+`))
+	}))
+	defer server.Close()
+
+	client := NewOllamaClient(server.URL)
+	var percents []float64
+	err := client.PullModel(context.Background(), "llama3.2", func(p PullProgress) {
+		percents = append(percents, p.Percent())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(percents, []float64{-1, 25, 100, -1}) {
+		t.Errorf("Expected progress -1, 25, 100, -1, got %v", percents)
+	}
+
+	if err := client.PullModel(context.Background(), "missing", nil); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected the pull error from the stream, got %v", err)
+	}
+}
+
+func TestProbeOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			w.Write([]byte(`{"version":"0.3.12"}`))
+		case "/api/ps":
+			w.Write([]byte(`{"models":[{"name":"llama3.2:latest"}]}`))
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3.2:latest"},{"name":"qwen2.5-coder:7b"}]}`))
+		}
+	}))
+
+	health := probeOllama(context.Background(), server.URL)
+	if !health.Reachable || health.Version != "0.3.12" {
+		t.Fatalf("Expected a reachable 0.3.12 server, got %+v", health)
+	}
+	if !health.IsLoaded("llama3.2") || health.IsLoaded("qwen2.5-coder:7b") || !health.HasModel("qwen2.5-coder:7b") {
+		t.Errorf("Expected llama3.2 loaded and qwen2.5-coder installed, got %+v", health)
+	}
+	if health.HasModel("mistral") {
+		t.Error("Expected mistral to be missing")
+	}
+
+	server.Close()
+	health = probeOllama(context.Background(), server.URL)
+	if health.Reachable || health.Err == nil || !strings.Contains(health.Err.Error(), "connection refused") {
+		t.Errorf("Expected connection refused once stopped, got %+v", health)
+	}
+}
+
+func TestOllamaClient_ListModelsCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models":[{"name":"llama3.1:8b"},{"name":"gemma:2b"},{"name":"old:latest"}]}`))
+		case "/api/show":
+			var body struct {
+				Model string `json:"model"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			switch body.Model {
+			case "llama3.1:8b":
+				w.Write([]byte(`{"model_info":{"general.architecture":"llama","llama.context_length":131072},"capabilities":["completion","tools"]}`))
+			case "gemma:2b":
+				w.Write([]byte(`{"model_info":{"gemma.context_length":8192},"capabilities":["completion"]}`))
+			default:
+				w.Write([]byte(`{"model_info":{}}`))
+			}
+		}
+	}))
+	defer server.Close()
+
+	models, err := NewOllamaClient(server.URL).ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]struct {
+		context int
+		tools   Capability
+	}{
+		"llama3.1:8b": {131072, CapabilityYes},
+		"gemma:2b":    {8192, CapabilityNo},
+		"old:latest":  {0, CapabilityUnknown},
+	}
+	for _, model := range models {
+		want := expected[model.ID]
+		if model.ContextLength != want.context || model.Tools != want.tools || model.JSONMode != CapabilityYes {
+			t.Errorf("%s: expected context %d, tools %d and JSON mode, got %+v", model.ID, want.context, want.tools, model)
+		}
+	}
+
+	filtered := ModelFilter{MinContext: 32000, Tools: true}.Filter(models)
+	if len(filtered) != 1 || filtered[0].ID != "llama3.1:8b" {
+		t.Errorf("Expected only llama3.1:8b to have 32k context and tools, got %+v", filtered)
+	}
+}
+
+func TestParseTokenCount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+		wantErr  bool
+	}{
+		{"8192", 8192, false},
+		{"32k", 32000, false},
+		{"1.5K", 1500, false},
+		{"1m", 1000000, false},
+		{"", 0, true},
+		{"-4k", 0, true},
+		{"lots", 0, true},
+	}
+	for _, tc := range tests {
+		got, err := ParseTokenCount(tc.input)
+		if (err != nil) != tc.wantErr || got != tc.expected {
+			t.Errorf("ParseTokenCount(%q) = %d, %v; expected %d", tc.input, got, err, tc.expected)
+		}
+	}
+	if got := FormatTokenCount(131072); got != "131k" {
+		t.Errorf("Expected 131k, got %s", got)
+	}
+	if got := FormatTokenCount(1000000); got != "1M" {
+		t.Errorf("Expected 1M, got %s", got)
+	}
+}
+
+func TestManager_Reindex(t *testing.T) {
+	conn, err := core.NewConnection(&core.ConnectionConfig{
+		Name:         "test",
+		DatabaseType: core.SQLite,
+		Database:     filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER)",
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, sku TEXT)",
+	} {
+		result, err := conn.Execute(stmt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range result.Itor() {
+		}
+		result.Close()
+	}
+
+	vectorStore, err := NewVectorStore(t.TempDir(), "test", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vectorStore.Close()
+
+	var reports []EmbeddingProgress
+	if err := vectorStore.UpdateTableEmbeddings(context.Background(), 0, func(p EmbeddingProgress) {
+		reports = append(reports, p)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 4 || reports[0].Next == "" || reports[3].Done != 3 || reports[3].Total != 3 || reports[3].Next != "" {
+		t.Errorf("Expected a first report and one per table, got %+v", reports)
+	}
+
+	// Unchanged tables are skipped, so an incremental update does not wait between them
+	var updated int
+	if err := vectorStore.updateTableEmbeddings(context.Background(), time.Hour, true, func(p EmbeddingProgress) {
+		if p.Err != nil {
+			t.Errorf("Unexpected failure indexing %s: %v", p.Table, p.Err)
+		}
+		updated = p.Done
+	}); err != nil || updated != 3 {
+		t.Errorf("Expected an incremental update over 3 tables without waiting, got %d, %v", updated, err)
+	}
+
+	// A long interval keeps the job waiting after the first table until it is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- vectorStore.UpdateTableEmbeddings(ctx, time.Hour, nil)
+	}()
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled job to stop with context.Canceled, got %v", err)
+	}
+
+	m := &Manager{config: config.DefaultConfig(), vectorStore: vectorStore}
+	if _, ok := m.ReindexStatus(); ok {
+		t.Error("Expected no status before a job has run")
+	}
+	if err := m.StartReindex(); err != nil {
+		t.Fatal(err)
+	}
+	m.CancelReindex()
+	status, ok := m.ReindexStatus()
+	if !ok || status.Running || status.FinishedAt.IsZero() || status.Total != 3 {
+		t.Errorf("Expected a stopped job over 3 tables, got %+v", status)
+	}
+	if !status.Cancelled && status.Done != 3 {
+		t.Errorf("Expected the job to be cancelled or complete, got %+v", status)
+	}
+
+	if err := (&Manager{}).StartReindex(); !errors.Is(err, ErrNoVectorStore) {
+		t.Errorf("Expected ErrNoVectorStore without a connection, got %v", err)
+	}
+}
+
+func TestVectorStore_SchemaGraph(t *testing.T) {
+	conn, err := core.NewConnection(&core.ConnectionConfig{
+		Name:         "test",
+		DatabaseType: core.SQLite,
+		Database:     filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	exec := func(stmt string) {
+		result, err := conn.Execute(stmt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range result.Itor() {
+		}
+		result.Close()
+	}
+	exec("CREATE TABLE users (id INTEGER PRIMARY KEY)")
+	exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), parent_id INTEGER REFERENCES orders(id))")
+	exec("CREATE TABLE reviews (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id))")
+
+	vectorStore, err := NewVectorStore(t.TempDir(), "test", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vectorStore.Close()
+	vectorStore.SetDatabaseType(core.SQLite)
+
+	referencing, err := vectorStore.findTablesReferencingTable("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(referencing, []string{"orders", "reviews"}) {
+		t.Errorf("Expected orders and reviews to reference users, got %v", referencing)
+	}
+	if referencing, _ := vectorStore.findTablesReferencingTable("orders"); len(referencing) != 0 {
+		t.Errorf("Expected self-references to be ignored, got %v", referencing)
+	}
+
+	// The cached schema is kept until invalidated
+	exec("CREATE TABLE payments (id INTEGER PRIMARY KEY, order_id INTEGER REFERENCES orders(id))")
+	if referencing, _ := vectorStore.findTablesReferencingTable("orders"); len(referencing) != 0 {
+		t.Errorf("Expected the cached schema before invalidation, got %v", referencing)
+	}
+	if info, err := vectorStore.describeTable("payments"); err != nil || len(info.Columns) != 2 {
+		t.Errorf("Expected tables missing from the cache to be described directly, got %+v, %v", info, err)
+	}
+	vectorStore.InvalidateSchema()
+	if referencing, _ := vectorStore.findTablesReferencingTable("orders"); !reflect.DeepEqual(referencing, []string{"payments"}) {
+		t.Errorf("Expected payments after invalidation, got %v", referencing)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
 	"sqlterm/internal/config"
 	"time"
 )
@@ -17,6 +19,7 @@ type UsageDetails struct {
 	InputTokens  int             `json:"input_tokens"`
 	OutputTokens int             `json:"output_tokens"`
 	Cost         float64         `json:"cost"`
+	Latency      time.Duration   `json:"latency"`
 	RequestTime  time.Time       `json:"request_time"`
 	UserMessage  string          `json:"user_message"`
 	AIResponse   string          `json:"ai_response"`
@@ -36,23 +39,64 @@ type DailyUsageStats struct {
 	CreatedAt     time.Time       `json:"created_at"`
 }
 
+// LatencyStats summarises response times of one provider and model
+type LatencyStats struct {
+	Provider config.Provider `json:"provider"`
+	Model    string          `json:"model"`
+	Requests int             `json:"requests"`
+	P50      time.Duration   `json:"p50"`
+	P95      time.Duration   `json:"p95"`
+}
+
+// UsageTotals counts requests, tokens and cost over a period
+type UsageTotals struct {
+	Requests     int     `json:"requests"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// add accumulates other into t
+func (t *UsageTotals) add(other UsageTotals) {
+	t.Requests += other.Requests
+	t.InputTokens += other.InputTokens
+	t.OutputTokens += other.OutputTokens
+	t.Cost += other.Cost
+}
+
+// UsageSummary totals usage for today and the last 7 days, today included
+type UsageSummary struct {
+	Today     UsageTotals `json:"today"`
+	Last7Days UsageTotals `json:"last_7_days"`
+}
+
+// defaultUsageRetentionDays is how many days of per-request details are kept for auditing
+const defaultUsageRetentionDays = 30
+
 // UsageStore manages usage tracking in the vector database
 type UsageStore struct {
 	db                *sql.DB
 	lastProcessedDate string
+	retentionDays     int // Days of usage details kept, today included; negative keeps them all
 }
 
-// NewUsageStore creates a new usage store or gets existing one from vector store
-func NewUsageStore(vectorStore *VectorStore) (*UsageStore, error) {
+// NewUsageStore creates a new usage store or gets existing one from vector store.
+// retentionDays is how many days of per-request details to keep: 0 uses the default
+// and a negative value keeps every request.
+func NewUsageStore(vectorStore *VectorStore, retentionDays int) (*UsageStore, error) {
+	if retentionDays == 0 {
+		retentionDays = defaultUsageRetentionDays
+	}
 	store := &UsageStore{
-		db: vectorStore.db,
+		db:            vectorStore.db,
+		retentionDays: retentionDays,
 	}
 
 	if err := store.initializeUsageSchema(); err != nil {
 		return nil, fmt.Errorf("failed to initialize usage schema: %w", err)
 	}
 
-	// Truncate current day details if it's a new day
+	// Aggregate days that ended since the store was last used
 	if err := store.handleDayChange(); err != nil {
 		return nil, fmt.Errorf("failed to handle day change: %w", err)
 	}
@@ -71,6 +115,7 @@ func (us *UsageStore) initializeUsageSchema() error {
 			input_tokens INTEGER NOT NULL,
 			output_tokens INTEGER NOT NULL,
 			cost REAL NOT NULL,
+			latency_ms INTEGER NOT NULL DEFAULT 0,
 			request_time DATETIME NOT NULL,
 			user_message TEXT,
 			ai_response TEXT,
@@ -104,34 +149,57 @@ func (us *UsageStore) initializeUsageSchema() error {
 		}
 	}
 
-	// Add system_prompt column to existing tables if it doesn't exist
-	if err := us.migrateSystemPromptColumn(); err != nil {
+	// Add columns introduced after the table was first created
+	if err := us.migrateColumn("system_prompt", "TEXT"); err != nil {
 		return fmt.Errorf("failed to migrate system_prompt column: %w", err)
 	}
+	if err := us.migrateColumn("latency_ms", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return fmt.Errorf("failed to migrate latency_ms column: %w", err)
+	}
 
 	return nil
 }
 
-// handleDayChange processes statistics when date changes and truncates current day details
+// RetentionDays returns how many days of usage details are kept, negative for all
+func (us *UsageStore) RetentionDays() int {
+	return us.retentionDays
+}
+
+// handleDayChange aggregates the days that ended since the last run into daily stats,
+// then drops details older than the retention period. It runs once a day, on the first
+// request recorded or usage read after midnight.
 func (us *UsageStore) handleDayChange() error {
 	currentDate := time.Now().Format("2006-01-02")
 
-	// Check if we need to process the previous day's data
-	var lastDate sql.NullString
-	err := us.db.QueryRow(`SELECT MAX(date(request_time)) FROM usage_details`).Scan(&lastDate)
-	if err != nil && err != sql.ErrNoRows {
-		return fmt.Errorf("failed to get last usage date: %w", err)
+	// Every ended day after the latest aggregated one: a session running past midnight
+	// leaves the previous day behind today's requests
+	rows, err := us.db.Query(`SELECT DISTINCT date(request_time) FROM usage_details
+		WHERE date(request_time) < ?
+		AND date(request_time) > COALESCE((SELECT MAX(date) FROM daily_usage_stats), '')`, currentDate)
+	if err != nil {
+		return fmt.Errorf("failed to get previous usage dates: %w", err)
+	}
+	var previousDates []string
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to get previous usage dates: %w", err)
+		}
+		previousDates = append(previousDates, date)
 	}
+	rows.Close()
 
-	// If there's data from previous days that hasn't been aggregated, process it
-	if lastDate.Valid && lastDate.String != currentDate {
-		if err := us.aggregateDailyStats(lastDate.String); err != nil {
+	for _, date := range previousDates {
+		if err := us.aggregateDailyStats(date); err != nil {
 			return fmt.Errorf("failed to aggregate daily stats: %w", err)
 		}
+	}
 
-		// Clean up old usage details (keep only current day)
-		if err := us.truncateOldDetails(currentDate); err != nil {
-			return fmt.Errorf("failed to truncate old details: %w", err)
+	if us.retentionDays > 0 {
+		cutoff := time.Now().AddDate(0, 0, 1-us.retentionDays).Format("2006-01-02")
+		if err := us.purgeDetailsBefore(cutoff); err != nil {
+			return fmt.Errorf("failed to purge old details: %w", err)
 		}
 	}
 
@@ -139,30 +207,35 @@ func (us *UsageStore) handleDayChange() error {
 	return nil
 }
 
+// rollOver runs handleDayChange when the date has changed since it last ran
+func (us *UsageStore) rollOver() {
+	if us.lastProcessedDate == time.Now().Format("2006-01-02") {
+		return
+	}
+	if err := us.handleDayChange(); err != nil {
+		// Log error but don't fail the caller
+		fmt.Printf("Warning: failed to handle day change: %v\n", err)
+	}
+}
+
 // RecordUsage records a new usage entry
 func (us *UsageStore) RecordUsage(sessionID string, provider config.Provider, model string,
-	inputTokens, outputTokens int, cost float64, userMessage, aiResponse, systemPrompt string) error {
+	inputTokens, outputTokens int, cost float64, latency time.Duration, userMessage, aiResponse, systemPrompt string) error {
+
+	// Roll the previous day over before this request joins today's details
+	us.rollOver()
 
 	query := `INSERT INTO usage_details 
-		(session_id, provider, model, input_tokens, output_tokens, cost, request_time, user_message, ai_response, system_prompt)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		(session_id, provider, model, input_tokens, output_tokens, cost, latency_ms, request_time, user_message, ai_response, system_prompt)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	_, err := us.db.Exec(query, sessionID, string(provider), model, inputTokens, outputTokens,
-		cost, time.Now(), userMessage, aiResponse, systemPrompt)
+		cost, latency.Milliseconds(), time.Now(), userMessage, aiResponse, systemPrompt)
 
 	if err != nil {
 		return fmt.Errorf("failed to record usage: %w", err)
 	}
 
-	// Check if date has changed and handle accordingly
-	currentDate := time.Now().Format("2006-01-02")
-	if us.lastProcessedDate != currentDate {
-		if err := us.handleDayChange(); err != nil {
-			// Log error but don't fail the recording
-			fmt.Printf("Warning: failed to handle day change: %v\n", err)
-		}
-	}
-
 	return nil
 }
 
@@ -186,26 +259,32 @@ func (us *UsageStore) aggregateDailyStats(date string) error {
 	return err
 }
 
-// truncateOldDetails removes usage details from previous days, keeping only current day
-func (us *UsageStore) truncateOldDetails(currentDate string) error {
+// purgeDetailsBefore removes usage details from before a date, once aggregated
+func (us *UsageStore) purgeDetailsBefore(date string) error {
 	query := `DELETE FROM usage_details WHERE date(request_time) < ?`
-	_, err := us.db.Exec(query, currentDate)
+	_, err := us.db.Exec(query, date)
 	return err
 }
 
 // GetTodayUsage returns today's usage details
 func (us *UsageStore) GetTodayUsage() ([]UsageDetails, error) {
-	currentDate := time.Now().Format("2006-01-02")
+	return us.GetUsageDetails(time.Now().Format("2006-01-02"))
+}
+
+// GetUsageDetails returns the requests recorded on a date (YYYY-MM-DD), newest first.
+// Dates older than the retention period only have daily stats.
+func (us *UsageStore) GetUsageDetails(date string) ([]UsageDetails, error) {
+	us.rollOver()
 
 	query := `SELECT id, session_id, provider, model, input_tokens, output_tokens, 
-		cost, request_time, user_message, ai_response, system_prompt
+		cost, latency_ms, request_time, user_message, ai_response, system_prompt
 		FROM usage_details 
 		WHERE date(request_time) = ?
 		ORDER BY request_time DESC`
 
-	rows, err := us.db.Query(query, currentDate)
+	rows, err := us.db.Query(query, date)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get today's usage: %w", err)
+		return nil, fmt.Errorf("failed to get usage details: %w", err)
 	}
 	defer rows.Close()
 
@@ -213,19 +292,29 @@ func (us *UsageStore) GetTodayUsage() ([]UsageDetails, error) {
 	for rows.Next() {
 		var usage UsageDetails
 		var provider string
+		var latencyMs int64
 		err := rows.Scan(&usage.ID, &usage.SessionID, &provider, &usage.Model,
-			&usage.InputTokens, &usage.OutputTokens, &usage.Cost, &usage.RequestTime,
+			&usage.InputTokens, &usage.OutputTokens, &usage.Cost, &latencyMs, &usage.RequestTime,
 			&usage.UserMessage, &usage.AIResponse, &usage.SystemPrompt)
 		if err != nil {
 			continue
 		}
 		usage.Provider = config.Provider(provider)
+		usage.Latency = time.Duration(latencyMs) * time.Millisecond
 		usageList = append(usageList, usage)
 	}
 
 	return usageList, nil
 }
 
+// TodayCost returns what today's requests have cost so far
+func (us *UsageStore) TodayCost() (float64, error) {
+	var cost float64
+	err := us.db.QueryRow(`SELECT COALESCE(SUM(cost), 0) FROM usage_details WHERE date(request_time) = ?`,
+		time.Now().Format("2006-01-02")).Scan(&cost)
+	return cost, err
+}
+
 // GetDailyStats returns daily aggregated statistics for a date range
 func (us *UsageStore) GetDailyStats(startDate, endDate string) ([]DailyUsageStats, error) {
 	query := `SELECT id, date, provider, model, total_requests, input_tokens, 
@@ -257,68 +346,43 @@ func (us *UsageStore) GetDailyStats(startDate, endDate string) ([]DailyUsageStat
 	return statsList, nil
 }
 
-// GetUsageSummary returns a summary of usage for today and recent days
-func (us *UsageStore) GetUsageSummary() (map[string]interface{}, error) {
+// GetUsageSummary returns usage totals for today and the last 7 days. Earlier days come
+// from the daily stats and today from the details, which have not been aggregated yet.
+func (us *UsageStore) GetUsageSummary() (*UsageSummary, error) {
+	us.rollOver()
 	currentDate := time.Now().Format("2006-01-02")
-	weekAgo := time.Now().AddDate(0, 0, -7).Format("2006-01-02")
-
-	// Get today's totals
-	todayQuery := `SELECT 
-		COUNT(*) as requests,
-		COALESCE(SUM(input_tokens), 0) as input_tokens,
-		COALESCE(SUM(output_tokens), 0) as output_tokens,
-		COALESCE(SUM(cost), 0) as cost
-		FROM usage_details 
-		WHERE date(request_time) = ?`
-
-	var todayStats struct {
-		Requests     int     `json:"requests"`
-		InputTokens  int     `json:"input_tokens"`
-		OutputTokens int     `json:"output_tokens"`
-		Cost         float64 `json:"cost"`
-	}
-
-	err := us.db.QueryRow(todayQuery, currentDate).Scan(
-		&todayStats.Requests, &todayStats.InputTokens,
-		&todayStats.OutputTokens, &todayStats.Cost)
-	if err != nil && err != sql.ErrNoRows {
+	weekStart := time.Now().AddDate(0, 0, -6).Format("2006-01-02")
+
+	summary := &UsageSummary{}
+	err := us.db.QueryRow(`SELECT
+		COUNT(*),
+		COALESCE(SUM(input_tokens), 0),
+		COALESCE(SUM(output_tokens), 0),
+		COALESCE(SUM(cost), 0)
+		FROM usage_details
+		WHERE date(request_time) = ?`, currentDate).Scan(
+		&summary.Today.Requests, &summary.Today.InputTokens,
+		&summary.Today.OutputTokens, &summary.Today.Cost)
+	if err != nil {
 		return nil, fmt.Errorf("failed to get today's summary: %w", err)
 	}
 
-	// Get last 7 days from daily stats
-	weekQuery := `SELECT 
-		COALESCE(SUM(total_requests), 0) as requests,
-		COALESCE(SUM(input_tokens), 0) as input_tokens,
-		COALESCE(SUM(output_tokens), 0) as output_tokens,
-		COALESCE(SUM(total_cost), 0) as cost
-		FROM daily_usage_stats 
-		WHERE date BETWEEN ? AND ?`
-
-	var weekStats struct {
-		Requests     int     `json:"requests"`
-		InputTokens  int     `json:"input_tokens"`
-		OutputTokens int     `json:"output_tokens"`
-		Cost         float64 `json:"cost"`
-	}
-
-	err = us.db.QueryRow(weekQuery, weekAgo, currentDate).Scan(
-		&weekStats.Requests, &weekStats.InputTokens,
-		&weekStats.OutputTokens, &weekStats.Cost)
-	if err != nil && err != sql.ErrNoRows {
+	var earlier UsageTotals
+	err = us.db.QueryRow(`SELECT
+		COALESCE(SUM(total_requests), 0),
+		COALESCE(SUM(input_tokens), 0),
+		COALESCE(SUM(output_tokens), 0),
+		COALESCE(SUM(total_cost), 0)
+		FROM daily_usage_stats
+		WHERE date >= ? AND date < ?`, weekStart, currentDate).Scan(
+		&earlier.Requests, &earlier.InputTokens,
+		&earlier.OutputTokens, &earlier.Cost)
+	if err != nil {
 		return nil, fmt.Errorf("failed to get week summary: %w", err)
 	}
 
-	// Combine today's details with week totals (avoid double counting)
-	summary := map[string]interface{}{
-		"today": todayStats,
-		"last_7_days": map[string]interface{}{
-			"requests":      weekStats.Requests + todayStats.Requests,
-			"input_tokens":  weekStats.InputTokens + todayStats.InputTokens,
-			"output_tokens": weekStats.OutputTokens + todayStats.OutputTokens,
-			"cost":          weekStats.Cost + todayStats.Cost,
-		},
-	}
-
+	summary.Last7Days = summary.Today
+	summary.Last7Days.add(earlier)
 	return summary, nil
 }
 
@@ -353,104 +417,125 @@ func (us *UsageStore) exportCSV(stats []DailyUsageStats) ([]byte, error) {
 	return []byte(csv), nil
 }
 
-// GetProviderModelStats returns usage breakdown by provider and model
-func (us *UsageStore) GetProviderModelStats(days int) (map[string]map[string]interface{}, error) {
+// GetProviderModelStats returns usage totals by provider and then model over the last
+// days, today included
+func (us *UsageStore) GetProviderModelStats(days int) (map[string]map[string]UsageTotals, error) {
+	us.rollOver()
 	startDate := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
 	currentDate := time.Now().Format("2006-01-02")
 
-	query := `SELECT provider, model,
+	result := make(map[string]map[string]UsageTotals)
+	err := us.addProviderModelTotals(result, `SELECT provider, model,
 		COALESCE(SUM(total_requests), 0) as requests,
 		COALESCE(SUM(input_tokens), 0) as input_tokens,
 		COALESCE(SUM(output_tokens), 0) as output_tokens,
 		COALESCE(SUM(total_cost), 0) as cost
 		FROM daily_usage_stats 
 		WHERE date BETWEEN ? AND ?
-		GROUP BY provider, model
-		ORDER BY cost DESC`
+		GROUP BY provider, model`, startDate, currentDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider/model stats: %w", err)
+	}
 
-	rows, err := us.db.Query(query, startDate, currentDate)
+	// Add today's data from usage_details
+	err = us.addProviderModelTotals(result, `SELECT provider, model,
+		COUNT(*) as requests,
+		COALESCE(SUM(input_tokens), 0) as input_tokens,
+		COALESCE(SUM(output_tokens), 0) as output_tokens,
+		COALESCE(SUM(cost), 0) as cost
+		FROM usage_details 
+		WHERE date(request_time) = ?
+		GROUP BY provider, model`, currentDate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider/model stats: %w", err)
 	}
-	defer rows.Close()
 
-	result := make(map[string]map[string]interface{})
+	return result, nil
+}
+
+// addProviderModelTotals adds the rows of a provider, model and totals query to result
+func (us *UsageStore) addProviderModelTotals(result map[string]map[string]UsageTotals, query string, args ...any) error {
+	rows, err := us.db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
 	for rows.Next() {
 		var provider, model string
-		var requests, inputTokens, outputTokens int
-		var cost float64
-
-		err := rows.Scan(&provider, &model, &requests, &inputTokens, &outputTokens, &cost)
-		if err != nil {
+		var totals UsageTotals
+		if err := rows.Scan(&provider, &model, &totals.Requests, &totals.InputTokens, &totals.OutputTokens, &totals.Cost); err != nil {
 			continue
 		}
-
 		if result[provider] == nil {
-			result[provider] = make(map[string]interface{})
+			result[provider] = make(map[string]UsageTotals)
 		}
+		existing := result[provider][model]
+		existing.add(totals)
+		result[provider][model] = existing
+	}
+	return rows.Err()
+}
 
-		result[provider][model] = map[string]interface{}{
-			"requests":      requests,
-			"input_tokens":  inputTokens,
-			"output_tokens": outputTokens,
-			"cost":          cost,
-		}
+// GetLatencyStats returns the median and 95th percentile response times per provider
+// and model over the usage details of the last days still retained. Requests without a
+// latency are skipped.
+func (us *UsageStore) GetLatencyStats(days int) ([]LatencyStats, error) {
+	startDate := time.Now().AddDate(0, 0, -days).Format("2006-01-02")
+	rows, err := us.db.Query(`SELECT provider, model, latency_ms FROM usage_details
+		WHERE latency_ms > 0 AND date(request_time) >= ?
+		ORDER BY provider, model, latency_ms`, startDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latency stats: %w", err)
 	}
+	defer rows.Close()
 
-	// Add today's data from usage_details
-	todayQuery := `SELECT provider, model,
-		COUNT(*) as requests,
-		COALESCE(SUM(input_tokens), 0) as input_tokens,
-		COALESCE(SUM(output_tokens), 0) as output_tokens,
-		COALESCE(SUM(cost), 0) as cost
-		FROM usage_details 
-		WHERE date(request_time) = ?
-		GROUP BY provider, model`
-
-	todayRows, err := us.db.Query(todayQuery, currentDate)
-	if err == nil {
-		defer todayRows.Close()
-
-		for todayRows.Next() {
-			var provider, model string
-			var requests, inputTokens, outputTokens int
-			var cost float64
-
-			err := todayRows.Scan(&provider, &model, &requests, &inputTokens, &outputTokens, &cost)
-			if err != nil {
-				continue
-			}
-
-			if result[provider] == nil {
-				result[provider] = make(map[string]interface{})
-			}
-
-			// Add today's data to existing stats
-			if existing, exists := result[provider][model]; exists {
-				if existingMap, ok := existing.(map[string]interface{}); ok {
-					existingMap["requests"] = existingMap["requests"].(int) + requests
-					existingMap["input_tokens"] = existingMap["input_tokens"].(int) + inputTokens
-					existingMap["output_tokens"] = existingMap["output_tokens"].(int) + outputTokens
-					existingMap["cost"] = existingMap["cost"].(float64) + cost
-				}
-			} else {
-				result[provider][model] = map[string]interface{}{
-					"requests":      requests,
-					"input_tokens":  inputTokens,
-					"output_tokens": outputTokens,
-					"cost":          cost,
-				}
-			}
+	type key struct{ provider, model string }
+	latencies := make(map[key][]time.Duration)
+	var order []key
+	for rows.Next() {
+		var k key
+		var latencyMs int64
+		if err := rows.Scan(&k.provider, &k.model, &latencyMs); err != nil {
+			continue
+		}
+		if _, seen := latencies[k]; !seen {
+			order = append(order, k)
 		}
+		latencies[k] = append(latencies[k], time.Duration(latencyMs)*time.Millisecond)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	stats := make([]LatencyStats, 0, len(order))
+	for _, k := range order {
+		values := latencies[k]
+		stats = append(stats, LatencyStats{
+			Provider: config.Provider(k.provider),
+			Model:    k.model,
+			Requests: len(values),
+			P50:      percentile(values, 0.50),
+			P95:      percentile(values, 0.95),
+		})
+	}
+	return stats, nil
+}
+
+// percentile returns the nearest-rank percentile p (0-1] of the values
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
 }
 
-// migrateSystemPromptColumn adds the system_prompt column to existing usage_details tables
-func (us *UsageStore) migrateSystemPromptColumn() error {
-	// Check if system_prompt column already exists
+// migrateColumn adds a column to existing usage_details tables
+func (us *UsageStore) migrateColumn(column, definition string) error {
+	// Check if the column already exists
 	var columnExists bool
 	checkQuery := `PRAGMA table_info(usage_details)`
 	rows, err := us.db.Query(checkQuery)
@@ -467,17 +552,18 @@ func (us *UsageStore) migrateSystemPromptColumn() error {
 		if err != nil {
 			continue
 		}
-		if name == "system_prompt" {
+		if name == column {
 			columnExists = true
 			break
 		}
 	}
+	rows.Close()
 
 	// Add column if it doesn't exist
 	if !columnExists {
-		alterQuery := `ALTER TABLE usage_details ADD COLUMN system_prompt TEXT`
+		alterQuery := fmt.Sprintf(`ALTER TABLE usage_details ADD COLUMN %s %s`, column, definition)
 		if _, err := us.db.Exec(alterQuery); err != nil {
-			return fmt.Errorf("failed to add system_prompt column: %w", err)
+			return fmt.Errorf("failed to add %s column: %w", column, err)
 		}
 	}
 
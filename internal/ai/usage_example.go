@@ -61,10 +61,7 @@ func ExampleUsageTracking(manager *Manager) {
 	for provider, models := range breakdown {
 		fmt.Printf("\n%s:\n", provider)
 		for model, stats := range models {
-			if statsMap, ok := stats.(map[string]interface{}); ok {
-				fmt.Printf("  %s: %d requests, $%.6f cost\n",
-					model, int(statsMap["requests"].(int)), statsMap["cost"].(float64))
-			}
+			fmt.Printf("  %s: %d requests, $%.6f cost\n", model, stats.Requests, stats.Cost)
 		}
 	}
 
@@ -96,25 +93,11 @@ func ShowUsageStatistics(manager *Manager) error {
 	fmt.Println("📊 Usage Statistics:")
 	fmt.Printf("Session ID: %s\n", manager.GetSessionID())
 
-	if todayStats, ok := summary["today"]; ok {
-		if today, ok := todayStats.(map[string]interface{}); ok {
-			fmt.Printf("Today: %d requests, %d input tokens, %d output tokens, $%.6f\n",
-				int(today["requests"].(int)),
-				int(today["input_tokens"].(int)),
-				int(today["output_tokens"].(int)),
-				today["cost"].(float64))
-		}
-	}
-
-	if weekStats, ok := summary["last_7_days"]; ok {
-		if week, ok := weekStats.(map[string]interface{}); ok {
-			fmt.Printf("Last 7 days: %d requests, %d input tokens, %d output tokens, $%.6f\n",
-				int(week["requests"].(int)),
-				int(week["input_tokens"].(int)),
-				int(week["output_tokens"].(int)),
-				week["cost"].(float64))
-		}
-	}
+	today, week := summary.Today, summary.Last7Days
+	fmt.Printf("Today: %d requests, %d input tokens, %d output tokens, $%.6f\n",
+		today.Requests, today.InputTokens, today.OutputTokens, today.Cost)
+	fmt.Printf("Last 7 days: %d requests, %d input tokens, %d output tokens, $%.6f\n",
+		week.Requests, week.InputTokens, week.OutputTokens, week.Cost)
 
 	return nil
 }
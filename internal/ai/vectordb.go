@@ -9,6 +9,8 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"sqlterm/internal/core"
@@ -20,8 +22,15 @@ import (
 type VectorStore struct {
 	db             *sql.DB
 	connection     core.Connection
-	configDir      string
+	dataDir        string
 	connectionName string
+
+	schemaMu   sync.Mutex
+	schema     *schemaGraph      // Table definitions for the session, loaded on first use
+	dbType     core.DatabaseType // Dialect for bulk schema loading
+	bulkSchema bool              // Whether dbType is set
+
+	sensitive atomic.Pointer[core.SensitiveColumns] // Columns left out of sample data
 }
 
 // TableEmbedding represents a table with its vector embeddings
@@ -58,9 +67,9 @@ type VectorSearchResult struct {
 }
 
 // NewVectorStore creates a new vector store for a database connection
-func NewVectorStore(configDir, connectionName string, connection core.Connection) (*VectorStore, error) {
+func NewVectorStore(dataDir, connectionName string, connection core.Connection) (*VectorStore, error) {
 	// Create session directory for this connection
-	sessionDir := fmt.Sprintf("%s/sessions/%s", configDir, connectionName)
+	sessionDir := fmt.Sprintf("%s/sessions/%s", dataDir, connectionName)
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create session directory: %w", err)
 	}
@@ -68,11 +77,6 @@ func NewVectorStore(configDir, connectionName string, connection core.Connection
 	// New vector database path in session folder
 	dbPath := fmt.Sprintf("%s/vectors.db", sessionDir)
 
-	// Check for legacy vector database and migrate if exists
-	if err := migrateLegacyVectorDB(configDir, connectionName, dbPath); err != nil {
-		fmt.Printf("Warning: failed to migrate legacy vector database: %v\n", err)
-	}
-
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open vector database: %w", err)
@@ -81,7 +85,7 @@ func NewVectorStore(configDir, connectionName string, connection core.Connection
 	store := &VectorStore{
 		db:             db,
 		connection:     connection,
-		configDir:      configDir,
+		dataDir:        dataDir,
 		connectionName: connectionName,
 	}
 
@@ -92,31 +96,6 @@ func NewVectorStore(configDir, connectionName string, connection core.Connection
 	return store, nil
 }
 
-// migrateLegacyVectorDB moves old vector databases to new session folder structure
-func migrateLegacyVectorDB(configDir, connectionName, newPath string) error {
-	// Old vector database path
-	oldPath := fmt.Sprintf("%s/vectors_%s.db", configDir, connectionName)
-
-	// Check if old database exists
-	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
-		return nil // No old database to migrate
-	}
-
-	// Check if new database already exists
-	if _, err := os.Stat(newPath); err == nil {
-		// New database exists, remove old one
-		return os.Remove(oldPath)
-	}
-
-	// Move old database to new location
-	if err := os.Rename(oldPath, newPath); err != nil {
-		return fmt.Errorf("failed to move vector database from %s to %s: %w", oldPath, newPath, err)
-	}
-
-	fmt.Printf("📦 Migrated vector database for %s to session folder\n", connectionName)
-	return nil
-}
-
 // initializeSchema creates the necessary tables for vector storage
 func (vs *VectorStore) initializeSchema() error {
 	queries := []string{
@@ -144,6 +123,25 @@ func (vs *VectorStore) initializeSchema() error {
 			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)`,
 
+		`CREATE TABLE IF NOT EXISTS query_examples (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			question TEXT NOT NULL,
+			sql_text TEXT NOT NULL,
+			embedding TEXT, -- JSON array of float64 values
+			use_count INTEGER DEFAULT 1,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(question, sql_text)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS column_profiles (
+			table_name TEXT NOT NULL,
+			column_name TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (table_name, column_name)
+		)`,
+
 		`CREATE INDEX IF NOT EXISTS idx_table_name ON table_embeddings(table_name)`,
 		`CREATE INDEX IF NOT EXISTS idx_last_accessed ON table_embeddings(last_accessed DESC)`,
 		`CREATE INDEX IF NOT EXISTS idx_access_count ON table_embeddings(access_count DESC)`,
@@ -159,29 +157,75 @@ func (vs *VectorStore) initializeSchema() error {
 	return nil
 }
 
-// UpdateTableEmbeddings refreshes embeddings for all tables
-func (vs *VectorStore) UpdateTableEmbeddings(ctx context.Context) error {
+// EmbeddingProgress is reported before the first table and after each one
+type EmbeddingProgress struct {
+	Table string // Table just processed; empty in the first report
+	Err   error  // Why Table could not be indexed
+	Next  string // Table indexed next; empty after the last
+	Done  int
+	Total int
+}
+
+// UpdateTableEmbeddings refreshes embeddings for all tables, waiting interval between
+// tables to spare the database. A table that fails is reported and skipped.
+func (vs *VectorStore) UpdateTableEmbeddings(ctx context.Context, interval time.Duration, progress func(EmbeddingProgress)) error {
+	return vs.updateTableEmbeddings(ctx, interval, false, progress)
+}
+
+// updateTableEmbeddings refreshes embeddings like UpdateTableEmbeddings. With onlyChanged,
+// tables whose description is unchanged keep their embedding and sample rows, and
+// only the tables that were sampled are followed by a wait.
+func (vs *VectorStore) updateTableEmbeddings(ctx context.Context, interval time.Duration, onlyChanged bool, progress func(EmbeddingProgress)) error {
 	tables, err := vs.connection.ListTables()
 	if err != nil {
 		return fmt.Errorf("failed to list tables: %w", err)
 	}
 
-	for _, tableName := range tables {
-		if err := vs.updateTableEmbedding(ctx, tableName); err != nil {
-			// Log error but continue with other tables
-			fmt.Printf("Warning: failed to update embedding for table %s: %v\n", tableName, err)
+	report := func(p EmbeddingProgress) {
+		if progress != nil {
+			progress(p)
+		}
+	}
+	next := func(i int) string {
+		if i < len(tables) {
+			return tables[i]
+		}
+		return ""
+	}
+	report(EmbeddingProgress{Next: next(0), Total: len(tables)})
+
+	var limiter <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		limiter = ticker.C
+	}
+	wait := false
+	for i, tableName := range tables {
+		if wait && limiter != nil {
+			select {
+			case <-ctx.Done():
+			case <-limiter:
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
 		}
+		updated, err := vs.updateTableEmbedding(ctx, tableName, onlyChanged)
+		wait = updated || err != nil
+		report(EmbeddingProgress{Table: tableName, Err: err, Next: next(i + 1), Done: i + 1, Total: len(tables)})
 	}
 
 	return nil
 }
 
-// updateTableEmbedding creates or updates embedding for a single table
-func (vs *VectorStore) updateTableEmbedding(ctx context.Context, tableName string) error {
+// updateTableEmbedding creates or updates embedding for a single table. With onlyChanged
+// it leaves a table whose stored description is the same alone and reports false.
+func (vs *VectorStore) updateTableEmbedding(ctx context.Context, tableName string, onlyChanged bool) (bool, error) {
 	// Get table schema information
-	tableInfo, err := vs.connection.DescribeTable(tableName)
+	tableInfo, err := vs.describeTable(tableName)
 	if err != nil {
-		return fmt.Errorf("failed to describe table %s: %w", tableName, err)
+		return false, fmt.Errorf("failed to describe table %s: %w", tableName, err)
 	}
 
 	// Build description for embedding
@@ -203,6 +247,13 @@ func (vs *VectorStore) updateTableEmbedding(ctx context.Context, tableName strin
 	}
 
 	description := strings.Join(descParts, ". ")
+	if onlyChanged {
+		var stored string
+		err := vs.db.QueryRow(`SELECT description FROM table_embeddings WHERE table_name = ?`, tableName).Scan(&stored)
+		if err == nil && stored == description {
+			return false, nil
+		}
+	}
 
 	// Get sample data (first few rows)
 	sampleData, err := vs.getSampleData(tableName)
@@ -226,18 +277,19 @@ func (vs *VectorStore) updateTableEmbedding(ctx context.Context, tableName strin
 	_, err = vs.db.Exec(query, tableName, description, string(columnsJSON),
 		string(columnTypesJSON), sampleData, string(embeddingJSON), time.Now())
 
-	return err
+	return true, err
 }
 
 // getSampleData retrieves a few sample rows from the table
 func (vs *VectorStore) getSampleData(tableName string) (string, error) {
-	query := fmt.Sprintf("SELECT * FROM %s LIMIT 3", tableName)
+	query := fmt.Sprintf("SELECT * FROM %s LIMIT 3", core.QuoteConnectionIdentifier(vs.connection, tableName))
 	result, err := vs.connection.Execute(query)
 	if err != nil {
 		return "", err
 	}
 	defer result.Close()
 
+	sensitive := vs.sensitive.Load()
 	var samples []string
 	count := 0
 	for row := range result.Itor() {
@@ -246,7 +298,10 @@ func (vs *VectorStore) getSampleData(tableName string) (string, error) {
 		}
 
 		var rowStrings []string
-		for _, val := range row {
+		for i, val := range row {
+			if i < len(result.Columns) && sensitive.Tagged(tableName, result.Columns[i].Name) {
+				continue
+			}
 			rowStrings = append(rowStrings, val.String())
 		}
 		samples = append(samples, strings.Join(rowStrings, ", "))
@@ -354,6 +409,12 @@ func (vs *VectorStore) SearchSimilarTables(ctx context.Context, queryText string
 	}
 	defer rows.Close()
 
+	// Tables that took part in answers rated bad for similar questions rank lower
+	feedback, err := vs.tableFeedbackWeights(queryEmbedding)
+	if err != nil {
+		feedback = nil
+	}
+
 	var results []VectorSearchResult
 
 	for rows.Next() {
@@ -379,6 +440,9 @@ func (vs *VectorStore) SearchSimilarTables(ctx context.Context, queryText string
 
 		// Calculate similarity
 		similarity := vs.cosineSimilarity(queryEmbedding, te.Embedding)
+		if weight, ok := feedback[te.TableName]; ok {
+			similarity *= weight
+		}
 
 		// Determine reason for inclusion
 		reason := vs.determineRelevanceReason(queryText, te, similarity)
@@ -499,7 +563,7 @@ func (vs *VectorStore) FindRelatedTables(baseTableNames []string) (map[string][]
 
 	for _, tableName := range baseTableNames {
 		// Get table schema to analyze foreign keys
-		tableInfo, err := vs.connection.DescribeTable(tableName)
+		tableInfo, err := vs.describeTable(tableName)
 		if err != nil {
 			continue // Skip tables we can't describe
 		}
@@ -541,34 +605,11 @@ func (vs *VectorStore) FindRelatedTables(baseTableNames []string) (map[string][]
 
 // findTablesReferencingTable finds tables that have foreign keys pointing to the given table
 func (vs *VectorStore) findTablesReferencingTable(targetTableName string) ([]string, error) {
-	var referencingTables []string
-
-	// Get all tables to check their foreign keys
-	allTables, err := vs.connection.ListTables()
+	graph, err := vs.schemaGraph()
 	if err != nil {
 		return nil, err
 	}
-
-	for _, tableName := range allTables {
-		if tableName == targetTableName {
-			continue // Skip the target table itself
-		}
-
-		tableInfo, err := vs.connection.DescribeTable(tableName)
-		if err != nil {
-			continue // Skip tables we can't describe
-		}
-
-		// Check if this table has foreign keys pointing to target table
-		for _, fk := range tableInfo.ForeignKeys {
-			if fk.ReferencedTable == targetTableName {
-				referencingTables = append(referencingTables, tableName)
-				break // Found one FK, that's enough
-			}
-		}
-	}
-
-	return referencingTables, nil
+	return graph.referencedBy[targetTableName], nil
 }
 
 // findSimilarlyNamedTables finds tables with similar naming patterns
@@ -679,7 +720,7 @@ func (vs *VectorStore) GetTableRelationshipMap() (map[string][]string, error) {
 	for _, tableName := range allTables {
 		var relatedTables []string
 
-		tableInfo, err := vs.connection.DescribeTable(tableName)
+		tableInfo, err := vs.describeTable(tableName)
 		if err != nil {
 			continue
 		}
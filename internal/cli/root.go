@@ -1,22 +1,35 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"sqlterm/internal/ai"
 	"sqlterm/internal/config"
 	"sqlterm/internal/conversation"
 	"sqlterm/internal/core"
 	"sqlterm/internal/i18n"
+	"sqlterm/internal/session"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var (
-	cfgFile string
-	verbose bool
+	cfgFile        string
+	configDir      string
+	profileName    string
+	verbose        bool
+	profileStartup bool
+
+	// startedAt is when the process started, the start of --profile-startup's timings
+	startedAt = time.Now()
+	profile   *conversation.StartupProfile
 
 	// Version information (set from main)
 	Version   string = "dev"
@@ -61,6 +74,15 @@ func init() {
 		connectCmd.Short = i18nMgr.Get("connect_command_short")
 		listCmd.Short = i18nMgr.Get("list_command_short")
 		addCmd.Short = i18nMgr.Get("add_command_short")
+		connectionsCmd.Short = i18nMgr.Get("connections_command_short")
+		connectionsCopyCmd.Short = i18nMgr.Get("connections_copy_command_short")
+		configCmd.Short = i18nMgr.Get("config_command_short")
+		configValidateCmd.Short = i18nMgr.Get("config_validate_command_short")
+		auditCmd.Short = i18nMgr.Get("audit_command_short")
+		auditVerifyCmd.Short = i18nMgr.Get("audit_verify_command_short")
+		approveCmd.Short = i18nMgr.Get("approve_command_short")
+		testCmd.Short = i18nMgr.Get("test_command_short")
+		runCmd.Short = i18nMgr.Get("run_command_short")
 		versionCmd.Short = i18nMgr.Get("version_command_short")
 		versionCmd.Long = i18nMgr.Get("version_command_long")
 
@@ -69,12 +91,25 @@ func init() {
 	}
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", getI18nString(i18nMgr, "config_file_flag", "config file (default is $HOME/.sqlterm.yaml)"))
+	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", getI18nString(i18nMgr, "config_dir_flag", "directory for config and session data (default $XDG_CONFIG_HOME/sqlterm, or $SQLTERM_CONFIG_DIR)"))
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", getI18nString(i18nMgr, "profile_flag", "profile to use, each with its own config, connections, AI keys and sessions (or $SQLTERM_PROFILE)"))
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, getI18nString(i18nMgr, "verbose_output_flag", "verbose output"))
+	rootCmd.PersistentFlags().BoolVar(&profileStartup, "profile-startup", false, getI18nString(i18nMgr, "startup_profile_flag", "report how long each startup step takes"))
 
 	rootCmd.AddCommand(connectCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(connectionsCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(approveCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(versionCmd)
+
+	connectionsCmd.AddCommand(connectionsCopyCmd)
+	configCmd.AddCommand(configValidateCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
 }
 
 // getI18nString safely gets an i18n string with fallback
@@ -109,9 +144,18 @@ func updateFlagDescriptions(i18nMgr *i18n.Manager) {
 	if flag := connectCmd.Flags().Lookup("password"); flag != nil {
 		flag.Usage = i18nMgr.Get("flag_password")
 	}
+	if flag := testCmd.Flags().Lookup("timeout"); flag != nil {
+		flag.Usage = i18nMgr.Get("timeout_flag_desc")
+	}
 }
 
 func initConfig() {
+	config.SetConfigDir(configDir)
+	if profileName != "" {
+		cobra.CheckErr(config.ValidateProfileName(profileName))
+	}
+	config.SetProfile(profileName)
+
 	if cfgFile != "" {
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -132,8 +176,16 @@ func initConfig() {
 	}
 }
 
+// startupProfile returns the profile that --profile-startup reports, nil without the flag
+func startupProfile() *conversation.StartupProfile {
+	if profileStartup && profile == nil {
+		profile = conversation.NewStartupProfile(startedAt)
+	}
+	return profile
+}
+
 func runConversation() error {
-	app, err := conversation.NewApp()
+	app, err := conversation.NewAppWithProfile(startupProfile())
 	if err != nil {
 		return fmt.Errorf("failed to create conversation app: %w", err)
 	}
@@ -185,7 +237,8 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "", // Will be set in init()
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return listConnections()
+		tag, _ := cmd.Flags().GetString("tag")
+		return listConnections(tag)
 	},
 }
 
@@ -200,6 +253,13 @@ var addCmd = &cobra.Command{
 		port, _ := cmd.Flags().GetInt("port")
 		database, _ := cmd.Flags().GetString("database")
 		username, _ := cmd.Flags().GetString("username")
+		passwordCommand, _ := cmd.Flags().GetString("password-command")
+		askPassword, _ := cmd.Flags().GetBool("ask-password")
+		authMethod, _ := cmd.Flags().GetString("auth")
+		krbSrvName, _ := cmd.Flags().GetString("krbsrvname")
+		extensions, _ := cmd.Flags().GetStringArray("extension")
+		attach, _ := cmd.Flags().GetStringArray("attach")
+		replicas, _ := cmd.Flags().GetStringArray("replica")
 
 		dbTypeEnum, err := core.ParseDatabaseType(dbType)
 		if err != nil {
@@ -211,19 +271,154 @@ var addCmd = &cobra.Command{
 		}
 
 		config := &core.ConnectionConfig{
-			Name:         name,
-			DatabaseType: dbTypeEnum,
-			Host:         host,
-			Port:         port,
-			Database:     database,
-			Username:     username,
-			SSL:          false,
+			Name:            name,
+			DatabaseType:    dbTypeEnum,
+			Host:            host,
+			Port:            port,
+			Database:        database,
+			Username:        username,
+			PasswordCommand: passwordCommand,
+			AskPassword:     askPassword,
+			SSL:             false,
+			Extensions:      extensions,
+		}
+		for _, spec := range attach {
+			schema, path, ok := strings.Cut(spec, "=")
+			if !ok || schema == "" || path == "" {
+				return fmt.Errorf("invalid --attach %q; use schema=path", spec)
+			}
+			config.Attachments = append(config.Attachments, core.Attachment{Path: path, Schema: schema})
+		}
+		for _, address := range replicas {
+			replica, err := core.ParseReplica(address)
+			if err != nil {
+				return err
+			}
+			config.Replicas = append(config.Replicas, replica)
+		}
+		if dbTypeEnum != core.SQLite && (len(config.Extensions) > 0 || len(config.Attachments) > 0) {
+			return fmt.Errorf("--extension and --attach are only supported for sqlite connections")
+		}
+		switch authMethod {
+		case "", "password":
+		case core.AuthLDAP, core.AuthGSSAPI:
+			config.Auth = &core.AuthConfig{Method: authMethod, KrbSrvName: krbSrvName}
+		default:
+			return fmt.Errorf("unsupported --auth %q; use password, ldap or gssapi", authMethod)
 		}
 
 		return addConnection(config)
 	},
 }
 
+var connectionsCmd = &cobra.Command{
+	Use:   "connections",
+	Short: "", // Will be set in init()
+}
+
+var connectionsCopyCmd = &cobra.Command{
+	Use:   "copy [source] [target]",
+	Short: "", // Will be set in init()
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var overrides config.ConnectionOverrides
+		if cmd.Flags().Changed("host") {
+			host, _ := cmd.Flags().GetString("host")
+			overrides.Host = &host
+		}
+		if cmd.Flags().Changed("port") {
+			port, _ := cmd.Flags().GetInt("port")
+			overrides.Port = &port
+		}
+		if cmd.Flags().Changed("database") {
+			database, _ := cmd.Flags().GetString("database")
+			overrides.Database = &database
+		}
+		if cmd.Flags().Changed("username") {
+			username, _ := cmd.Flags().GetString("username")
+			overrides.Username = &username
+		}
+		if cmd.Flags().Changed("read-only") {
+			readOnly, _ := cmd.Flags().GetBool("read-only")
+			overrides.ReadOnly = &readOnly
+		}
+
+		return copyConnection(args[0], args[1], overrides)
+	},
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "", // Will be set in init()
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "", // Will be set in init()
+	Args:  cobra.NoArgs,
+	// Problems are listed by file; usage would only bury them
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return validateConfig()
+	},
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "", // Will be set in init()
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:          "verify [file]",
+	Short:        "", // Will be set in init()
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := ""
+		if len(args) > 0 {
+			path = args[0]
+		}
+		return verifyAuditLog(path)
+	},
+}
+
+var approveCmd = &cobra.Command{
+	Use:          "approve <bundle>",
+	Short:        "", // Will be set in init()
+	SilenceUsage: true,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if newKey, _ := cmd.Flags().GetBool("new-key"); newKey {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if newKey, _ := cmd.Flags().GetBool("new-key"); newKey {
+			return newApprovalKey()
+		}
+		return approveBundle(args[0])
+	},
+}
+
+var testCmd = &cobra.Command{
+	Use:   "test [connection]",
+	Short: "", // Will be set in init()
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		return testConnection(args[0], timeout)
+	},
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [connection] [script]",
+	Short: "", // Will be set in init()
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runScript(args[0], args[1])
+	},
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "", // Will be set in init()
@@ -258,9 +453,28 @@ func init() {
 	addCmd.Flags().IntP("port", "p", 0, "Port")
 	addCmd.Flags().StringP("database", "d", "", "Database name")
 	addCmd.Flags().StringP("username", "u", "", "Username")
+	addCmd.Flags().String("password-command", "", "Command printing the password, e.g. 'pass show db/prod'")
+	addCmd.Flags().Bool("ask-password", false, "Prompt for the password on each connect")
+	addCmd.Flags().String("auth", "", "PostgreSQL authentication: password, ldap or gssapi")
+	addCmd.Flags().String("krbsrvname", "", "Kerberos service name for --auth gssapi (default postgres)")
+	addCmd.Flags().StringArray("extension", nil, "SQLite extension library to load on connect (repeatable)")
+	addCmd.Flags().StringArray("attach", nil, "SQLite database to attach on connect, as schema=path (repeatable)")
+	addCmd.Flags().StringArray("replica", nil, "Read replica host[:port] that SELECTs are routed to (repeatable)")
 	addCmd.MarkFlagRequired("db-type")
 	addCmd.MarkFlagRequired("database")
 	addCmd.MarkFlagRequired("username")
+
+	listCmd.Flags().String("tag", "", "Only list connections with this tag (value or key=value)")
+
+	connectionsCopyCmd.Flags().StringP("host", "H", "", "Host")
+	connectionsCopyCmd.Flags().IntP("port", "p", 0, "Port")
+	connectionsCopyCmd.Flags().StringP("database", "d", "", "Database name")
+	connectionsCopyCmd.Flags().StringP("username", "u", "", "Username")
+	connectionsCopyCmd.Flags().Bool("read-only", false, "Only allow statements that read data")
+
+	testCmd.Flags().Duration("timeout", core.DefaultTestTimeout, "Timeout for each test step")
+
+	approveCmd.Flags().Bool("new-key", false, "Create the key approvals are signed with and print its public key for the policy file")
 }
 
 func connectAndRunConversation(connConfig *core.ConnectionConfig) error {
@@ -292,10 +506,12 @@ func connectAndRunConversation(connConfig *core.ConnectionConfig) error {
 		return fmt.Errorf("connection test failed: %w", err)
 	}
 
+	startupProfile().Mark("connect")
+
 	fmt.Printf(i18nMgr.Get("connected_successfully"), connConfig.Name)
 	fmt.Print(i18nMgr.Get("starting_conversation_mode"))
 
-	app, err := conversation.NewApp()
+	app, err := conversation.NewAppWithProfile(startupProfile())
 	if err != nil {
 		return fmt.Errorf("failed to create conversation app: %w", err)
 	}
@@ -304,7 +520,7 @@ func connectAndRunConversation(connConfig *core.ConnectionConfig) error {
 	return app.Run()
 }
 
-func listConnections() error {
+func listConnections(tag string) error {
 	// Initialize i18n
 	i18nMgr, err := i18n.NewManager("en_au")
 	if err != nil {
@@ -317,6 +533,16 @@ func listConnections() error {
 		return fmt.Errorf("failed to load connections: %w", err)
 	}
 
+	if tag != "" {
+		var matched []*core.ConnectionConfig
+		for _, conn := range connections {
+			if conn.HasTag(tag) {
+				matched = append(matched, conn)
+			}
+		}
+		connections = matched
+	}
+
 	if len(connections) == 0 {
 		fmt.Println(i18nMgr.Get("no_saved_connections_found"))
 		fmt.Println(i18nMgr.Get("add_connection_instruction"))
@@ -325,7 +551,7 @@ func listConnections() error {
 
 	fmt.Println(i18nMgr.Get("saved_connections_cli"))
 	for i, conn := range connections {
-		fmt.Printf("%d. %s (%s) - %s://%s:%d/%s\n",
+		fmt.Printf("%d. %s (%s) - %s://%s:%d/%s",
 			i+1,
 			conn.Name,
 			conn.DatabaseType,
@@ -333,6 +559,10 @@ func listConnections() error {
 			conn.Host,
 			conn.Port,
 			conn.Database)
+		if len(conn.Tags) > 0 {
+			fmt.Printf(" [%s]", conn.FormatTags())
+		}
+		fmt.Println()
 	}
 
 	return nil
@@ -347,7 +577,11 @@ func addConnection(cfg *core.ConnectionConfig) error {
 
 	fmt.Printf(i18nMgr.Get("testing_connection_cli"), cfg.Name)
 
-	conn, err := core.NewConnection(cfg)
+	resolved, err := config.ResolvePassword(cfg, promptPassword(i18nMgr))
+	if err != nil {
+		return fmt.Errorf("failed to get password: %w", err)
+	}
+	conn, err := core.NewConnection(resolved)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
@@ -369,3 +603,233 @@ func addConnection(cfg *core.ConnectionConfig) error {
 
 	return nil
 }
+
+func copyConnection(source, target string, overrides config.ConnectionOverrides) error {
+	// Initialize i18n
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		i18nMgr, _ = i18n.NewManager("en_au")
+	}
+
+	configManager := config.NewManager()
+	cfg, err := configManager.CopyConnection(source, target, overrides)
+	if err != nil {
+		return fmt.Errorf("failed to copy connection: %w", err)
+	}
+
+	fmt.Printf(i18nMgr.Get("connection_copied"), source, cfg.Name)
+	fmt.Println(i18nMgr.Get("use_list_instruction"))
+
+	return nil
+}
+
+// validateConfig checks config.yaml and every connection file, printing each problem
+// with its line and column
+func validateConfig() error {
+	i18nMgr, _ := i18n.NewManager("en_au")
+
+	configManager := config.NewManager()
+	results, err := configManager.ValidateAll()
+	if err != nil {
+		return err
+	}
+	files := make([]string, 0, len(results))
+	for file := range results {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	invalid := 0
+	for _, file := range files {
+		if results[file] == nil {
+			fmt.Printf(i18nMgr.Get("config_file_valid"), file)
+			continue
+		}
+		invalid++
+		fmt.Printf(i18nMgr.Get("config_file_invalid"), file)
+		var problems config.ValidationErrors
+		if errors.As(results[file], &problems) {
+			for _, problem := range problems {
+				fmt.Printf("   %s\n", problem)
+			}
+		} else {
+			fmt.Printf("   %v\n", results[file])
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf(i18nMgr.Get("config_files_invalid"), invalid)
+	}
+	return nil
+}
+
+// verifyAuditLog checks the hash chain of an audit log, by default the profile's
+func verifyAuditLog(path string) error {
+	i18nMgr, _ := i18n.NewManager("en_au")
+
+	if path == "" {
+		configManager := config.NewManager()
+		_, cfg, err := config.LoadConfig(configManager.GetConfigDir())
+		if err != nil {
+			return err
+		}
+		if path = cfg.Audit.LogPath(configManager.GetConfigDir()); path == "" {
+			return errors.New(i18nMgr.Get("audit_log_not_configured"))
+		}
+	}
+
+	count, err := session.VerifyAuditLog(path)
+	if err != nil {
+		return fmt.Errorf(i18nMgr.Get("audit_log_tampered"), path, count, err)
+	}
+	fmt.Printf(i18nMgr.Get("audit_log_intact"), path, count)
+	return nil
+}
+
+// approveBundle shows a statement waiting for approval and prints the token that lets
+// the requester run it, signed with this user's approval key. The approver is whoever
+// the policy file lists with the key's public half.
+func approveBundle(path string) error {
+	i18nMgr, _ := i18n.NewManager("en_au")
+
+	bundle, err := core.LoadApprovalBundle(path)
+	if err != nil {
+		return err
+	}
+	configManager := config.NewManager()
+	_, cfg, err := config.LoadConfig(configManager.GetConfigDir())
+	if err != nil {
+		return err
+	}
+	keyPath := cfg.Policy.SigningKeyPath(configManager.GetConfigDir())
+	key, err := core.LoadApprovalKey(keyPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf(i18nMgr.Get("approve_no_key"), keyPath)
+	}
+	if err != nil {
+		return err
+	}
+	policyPath := cfg.Policy.FilePath(configManager.GetConfigDir())
+	policy, err := config.LoadPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+	approver := ""
+	if policy != nil {
+		for name, public := range policy.Approvers {
+			if public == core.ApprovalPublicKey(key) {
+				approver = name
+			}
+		}
+	}
+	if approver == "" {
+		return fmt.Errorf(i18nMgr.Get("approve_key_not_listed"), policyPath, core.ApprovalPublicKey(key))
+	}
+
+	fmt.Printf(i18nMgr.Get("approve_bundle_summary"), bundle.Requester, bundle.Connection, bundle.Created.Local().Format(time.DateTime))
+	fmt.Printf("\n%s\n\n", strings.TrimSpace(bundle.Statement))
+	if bundle.Plan != "" {
+		fmt.Printf("%s\n", bundle.Plan)
+	}
+	if bundle.EstimatedRows > 0 {
+		fmt.Println(i18nMgr.GetWithArgs("approval_estimated_rows", fmt.Sprintf("%.0f", bundle.EstimatedRows)))
+	}
+	if strings.EqualFold(approver, bundle.Requester) {
+		return errors.New(i18nMgr.Get("approve_own_statement"))
+	}
+	fmt.Printf(i18nMgr.Get("approve_token"), approver, bundle.Requester, bundle.ApprovalToken(key))
+	return nil
+}
+
+// newApprovalKey creates this user's approval key and prints the line to add to the
+// team's policy file
+func newApprovalKey() error {
+	i18nMgr, _ := i18n.NewManager("en_au")
+
+	configManager := config.NewManager()
+	_, cfg, err := config.LoadConfig(configManager.GetConfigDir())
+	if err != nil {
+		return err
+	}
+	keyPath := cfg.Policy.SigningKeyPath(configManager.GetConfigDir())
+	public, err := core.GenerateApprovalKey(keyPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(i18nMgr.Get("approve_key_created"), keyPath, cfg.Policy.FilePath(configManager.GetConfigDir()), public)
+	return nil
+}
+
+func testConnection(name string, timeout time.Duration) error {
+	// Initialize i18n
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		i18nMgr, _ = i18n.NewManager("en_au")
+	}
+
+	configManager := config.NewManager()
+	cfg, err := configManager.LoadConnection(name)
+	if err != nil {
+		return fmt.Errorf("failed to load connection: %w", err)
+	}
+
+	resolved, err := config.ResolvePassword(cfg, promptPassword(i18nMgr))
+	if err != nil {
+		return fmt.Errorf("failed to get password: %w", err)
+	}
+	fmt.Printf(i18nMgr.Get("testing_saved_connection"), cfg.Name, timeout)
+	report := core.TestConnection(resolved, timeout)
+	fmt.Print(core.FormatConnectionTestReport(report, i18nMgr))
+
+	if !report.OK() {
+		return fmt.Errorf("connection test failed: %w", report.Err())
+	}
+	return nil
+}
+
+// runScript runs a script of prompt lines on a saved connection, failing when any line
+// or /assert check fails
+func runScript(name, path string) error {
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		i18nMgr, _ = i18n.NewManager("en_au")
+	}
+
+	configManager := config.NewManager()
+	cfg, err := configManager.LoadConnection(name)
+	if err != nil {
+		return fmt.Errorf("failed to load connection: %w", err)
+	}
+	resolved, err := config.ResolvePassword(cfg, promptPassword(i18nMgr))
+	if err != nil {
+		return fmt.Errorf("failed to get password: %w", err)
+	}
+
+	conn, err := core.NewConnection(resolved)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+	if err := conn.Ping(); err != nil {
+		return fmt.Errorf("connection test failed: %w", err)
+	}
+
+	app, err := conversation.NewApp()
+	if err != nil {
+		return fmt.Errorf("failed to create conversation app: %w", err)
+	}
+	app.SetConnection(conn, resolved)
+	return app.RunScript(path)
+}
+
+// promptPassword asks for an ask_password connection's password on the terminal without echo
+func promptPassword(i18nMgr *i18n.Manager) func(*core.ConnectionConfig) (string, error) {
+	return func(cfg *core.ConnectionConfig) (string, error) {
+		fmt.Print(i18nMgr.GetWithArgs("enter_password_for", cfg.Username, cfg.Host))
+		password, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		return string(password), nil
+	}
+}
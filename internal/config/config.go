@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sqlterm/internal/core"
 	"sqlterm/internal/i18n"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,6 +17,7 @@ const DefaultConfigFile = "config.yaml"
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
+		Version:  CurrentVersion,
 		Language: "en_au",
 		AI: AIConfig{
 			Provider: ProviderOpenRouter,
@@ -39,25 +43,19 @@ func LoadConfig(configDir string) (*i18n.Manager, *Config, error) {
 		return nil, nil, err
 	}
 	configPath := filepath.Join(configDir, DefaultConfigFile)
-	legacyConfigPath := filepath.Join(configDir, "ai.yaml")
 
-	// Handle migration from ai.yaml to config.yaml
+	// Bring files from older versions up to date before reading them
+	if err := UpgradeConfigDir(configDir, i18nMgr); err != nil {
+		return nil, nil, err
+	}
+
+	// Create default config if there is none yet
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		// Check if legacy ai.yaml exists
-		if _, err := os.Stat(legacyConfigPath); err == nil {
-			// Migrate from ai.yaml to config.yaml
-			if err := os.Rename(legacyConfigPath, configPath); err != nil {
-				return nil, nil, fmt.Errorf(i18nMgr.Get("failed_to_migrate_config"), err)
-			}
-			fmt.Print(i18nMgr.Get("config_migrated_cli"))
-		} else {
-			// Create default config if neither file exists
-			config := DefaultConfig()
-			if err := SaveConfig(config, configDir, i18nMgr); err != nil {
-				return nil, nil, fmt.Errorf(i18nMgr.Get("failed_to_create_default_config"), err)
-			}
-			return i18nMgr, config, nil
+		config := DefaultConfig()
+		if err := SaveConfig(config, configDir, i18nMgr); err != nil {
+			return nil, nil, fmt.Errorf(i18nMgr.Get("failed_to_create_default_config"), err)
 		}
+		return i18nMgr, config, nil
 	}
 
 	data, err := os.ReadFile(configPath)
@@ -65,10 +63,13 @@ func LoadConfig(configDir string) (*i18n.Manager, *Config, error) {
 		return nil, nil, fmt.Errorf(i18nMgr.Get("failed_to_read_config_file"), err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	config, err := ValidateConfig(configPath, data)
+	if err != nil {
 		return nil, nil, fmt.Errorf(i18nMgr.Get("failed_to_parse_config_file"), err)
 	}
+	if err := decryptSecrets(config, i18nMgr.Get("secrets_passphrase_prompt")); err != nil {
+		return nil, nil, fmt.Errorf(i18nMgr.Get("failed_to_decrypt_secrets"), err)
+	}
 
 	// Ensure maps are initialized
 	if config.AI.APIKeys == nil {
@@ -87,7 +88,7 @@ func LoadConfig(configDir string) (*i18n.Manager, *Config, error) {
 	}
 	i18nMgr.SetLanguage(config.Language)
 
-	return i18nMgr, &config, nil
+	return i18nMgr, config, nil
 }
 
 // SaveConfig saves AI configuration to file
@@ -98,6 +99,15 @@ func SaveConfig(config *Config, configDir string, i18nMgr *i18n.Manager) error {
 
 	configPath := filepath.Join(configDir, DefaultConfigFile)
 
+	// Secrets are only encrypted in the file; the caller's config keeps them readable
+	if config.Secrets.Encrypt {
+		encrypted, err := encryptedCopy(config, i18nMgr.Get("secrets_passphrase_prompt"))
+		if err != nil {
+			return fmt.Errorf(i18nMgr.Get("failed_to_encrypt_secrets"), err)
+		}
+		config = encrypted
+	}
+
 	data, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf(i18nMgr.Get("failed_to_marshal_config"), err)
@@ -161,6 +171,70 @@ func (c *Config) SetLanguage(language string) {
 	c.Language = language
 }
 
+// PolicyFor returns the statement policy for a connection
+func (c *Config) PolicyFor(conn *core.ConnectionConfig) string {
+	if conn == nil || !conn.IsProduction() {
+		return PolicyNone
+	}
+	switch c.Policy.Production {
+	case PolicyReadOnly, PolicyApprove, PolicyNone:
+		return c.Policy.Production
+	default:
+		return PolicyConfirm
+	}
+}
+
+// RetryPolicy returns the statement retry policy, filling unset values with defaults
+func (c *Config) RetryPolicy() core.RetryPolicy {
+	policy := core.DefaultRetryPolicy()
+	if c.Retry.MaxAttempts > 0 {
+		policy.MaxAttempts = c.Retry.MaxAttempts
+	}
+	if c.Retry.BackoffMs > 0 {
+		policy.Backoff = time.Duration(c.Retry.BackoffMs) * time.Millisecond
+	}
+	return policy
+}
+
+// DefaultSelfCorrectAttempts is how many corrections are tried for a failing AI query
+const DefaultSelfCorrectAttempts = 2
+
+// SelfCorrectAttempts returns the configured number of correction attempts
+func (c *Config) SelfCorrectAttempts() int {
+	if c.AI.SelfCorrectAttempts > 0 {
+		return c.AI.SelfCorrectAttempts
+	}
+	return DefaultSelfCorrectAttempts
+}
+
+// IsPaid reports whether a provider charges for requests; local providers are free
+func (p Provider) IsPaid() bool {
+	return p == ProviderOpenRouter
+}
+
+// ConfirmsSend reports whether chat messages to a provider need confirmation. Local
+// providers are free, so they never ask.
+func (c *Config) ConfirmsSend(provider Provider) bool {
+	return provider.IsPaid() && slices.Contains(c.AI.ConfirmSend, provider)
+}
+
+// IsKnown reports whether the provider is one sqlterm supports
+func (p Provider) IsKnown() bool {
+	switch p {
+	case ProviderOpenRouter, ProviderOllama, ProviderLMStudio:
+		return true
+	}
+	return false
+}
+
+// FallbackModel returns the model to use for a fallback, defaulting to the provider's default
+func (c *Config) FallbackModel(fallback FallbackConfig) string {
+	if fallback.Model != "" {
+		return fallback.Model
+	}
+	return c.GetDefaultModel(fallback.Provider)
+}
+
 // FormatProviderInfo returns formatted provider and model information
 func (c *Config) FormatProviderInfo() string {
 	return fmt.Sprintf("%s/%s", c.AI.Provider, c.AI.Model)
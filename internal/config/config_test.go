@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"sqlterm/internal/i18n"
@@ -377,6 +378,85 @@ func TestManager_GetConfigDir(t *testing.T) {
 	}
 }
 
+func TestResolveDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ConfigDirEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Cleanup(func() { SetConfigDir("") })
+
+	check := func(name, wantConfig, wantData string) {
+		t.Helper()
+		configDir, dataDir, err := ResolveDirs()
+		if err != nil || configDir != wantConfig || dataDir != wantData {
+			t.Errorf("%s: expected %s and %s, got %s and %s (%v)", name, wantConfig, wantData, configDir, dataDir, err)
+		}
+	}
+
+	defaultDir := filepath.Join(home, ".config", "sqlterm")
+	check("default", defaultDir, defaultDir)
+
+	t.Setenv("XDG_CONFIG_HOME", "relative")
+	check("relative XDG_CONFIG_HOME", defaultDir, defaultDir)
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, "data"))
+	check("XDG", filepath.Join(home, "cfg", "sqlterm"), filepath.Join(home, "data", "sqlterm"))
+
+	work := filepath.Join(home, "work")
+	t.Setenv(ConfigDirEnv, work)
+	check(ConfigDirEnv, work, work)
+
+	personal := filepath.Join(home, "personal")
+	SetConfigDir(personal)
+	check("--config-dir", personal, personal)
+}
+
+func TestProfiles(t *testing.T) {
+	base := t.TempDir()
+	SetConfigDir(base)
+	t.Setenv(ProfileEnv, "")
+	t.Cleanup(func() {
+		SetConfigDir("")
+		SetProfile("")
+	})
+
+	if profiles, err := ListProfiles(); err != nil || len(profiles) != 1 || profiles[0] != DefaultProfile {
+		t.Errorf("Expected only the default profile, got %v, %v", profiles, err)
+	}
+
+	t.Setenv(ProfileEnv, "work")
+	manager, err := OpenManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(base, ProfilesDir, "work"); manager.GetConfigDir() != want || manager.GetDataDir() != want || manager.GetProfile() != "work" {
+		t.Errorf("Expected the work profile in %s, got %s and %s (%s)", want, manager.GetConfigDir(), manager.GetDataDir(), manager.GetProfile())
+	}
+
+	SetProfile("client-b")
+	if _, err := OpenManager(); err != nil {
+		t.Fatal(err)
+	}
+	if profiles, _ := ListProfiles(); strings.Join(profiles, ",") != "default,client-b,work" {
+		t.Errorf("Expected the created profiles listed after the default, got %v", profiles)
+	}
+	if !ProfileExists("work") || ProfileExists("personal") {
+		t.Error("Expected only created profiles to exist")
+	}
+
+	SetProfile(DefaultProfile)
+	if configDir, _, _ := ResolveDirs(); configDir != base {
+		t.Errorf("Expected the default profile in the config directory itself, got %s", configDir)
+	}
+
+	SetProfile("../escape")
+	if _, err := OpenManager(); err == nil {
+		t.Error("Expected a profile name with a path to be refused")
+	}
+}
+
 // Helper function for string containment check
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) &&
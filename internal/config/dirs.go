@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ConfigDirEnv names the environment variable that picks a config directory, like the
+// --config-dir flag
+const ConfigDirEnv = "SQLTERM_CONFIG_DIR"
+
+// configDirOverride is the directory given with --config-dir, which wins over ConfigDirEnv
+var configDirOverride string
+
+// SetConfigDir makes managers created afterwards use dir for config and session data,
+// as --config-dir does. An empty dir restores the default.
+func SetConfigDir(dir string) {
+	configDirOverride = dir
+}
+
+// ResolveDirs returns the directory holding config.yaml and connections, and the one
+// holding session data (history, results and vector indexes under sessions/), for the
+// active profile.
+//
+// A directory from --config-dir or SQLTERM_CONFIG_DIR holds both, so each setup is
+// self-contained. Otherwise config lives in $XDG_CONFIG_HOME/sqlterm, ~/.config/sqlterm
+// when unset, and session data in $XDG_DATA_HOME/sqlterm when that is set, or next to
+// the config as it always has. Profiles other than the default live in profiles/<name>
+// below both.
+func ResolveDirs() (configDir, dataDir string, err error) {
+	configDir, dataDir, err = baseDirs()
+	if err != nil {
+		return "", "", err
+	}
+	if profile := ActiveProfile(); profile != DefaultProfile {
+		if err := ValidateProfileName(profile); err != nil {
+			return "", "", err
+		}
+		configDir = filepath.Join(configDir, ProfilesDir, profile)
+		dataDir = filepath.Join(dataDir, ProfilesDir, profile)
+	}
+	return configDir, dataDir, nil
+}
+
+// baseDirs returns the config and data directories of the default profile
+func baseDirs() (configDir, dataDir string, err error) {
+	custom := configDirOverride
+	if custom == "" {
+		custom = os.Getenv(ConfigDirEnv)
+	}
+	if custom != "" {
+		dir, err := filepath.Abs(custom)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid config directory %q: %w", custom, err)
+		}
+		return dir, dir, nil
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if !filepath.IsAbs(configHome) {
+		// The XDG spec says relative paths are to be ignored
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get user home directory: %w", err)
+		}
+		configHome = filepath.Join(home, ".config")
+	}
+	configDir = filepath.Join(configHome, "sqlterm")
+
+	dataDir = configDir
+	if dataHome := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(dataHome) {
+		dataDir = filepath.Join(dataHome, "sqlterm")
+	}
+	return configDir, dataDir, nil
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// PassphraseEnv names the environment variable that holds the passphrase for encrypted
+// secrets, for scripts that cannot be prompted
+const PassphraseEnv = "SQLTERM_PASSPHRASE"
+
+// encryptedPrefix marks a secret stored encrypted: enc:v1:<base64 nonce and ciphertext>
+const encryptedPrefix = "enc:v1:"
+
+// checkPlaintext is encrypted into secrets.check, so a wrong passphrase is caught even
+// before any secret has been saved
+const checkPlaintext = "sqlterm"
+
+// keyIterations is the PBKDF2-SHA256 work factor for deriving the key from the passphrase
+var keyIterations = 600_000
+
+// PassphrasePrompt asks for the passphrase without echo. The REPL replaces it once its
+// line editor owns the terminal; until then the terminal is read directly.
+var PassphrasePrompt = func(prompt string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("no terminal to ask for the passphrase; set %s or secrets.key_command", PassphraseEnv)
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	return string(passphrase), err
+}
+
+// secretKeys caches derived keys by salt, so the passphrase is asked for once a session
+var (
+	secretKeys   = make(map[string][]byte)
+	secretKeysMu sync.Mutex
+)
+
+// SecretsConfig turns on encryption at rest for the secrets in config.yaml: the AI API
+// keys and the integration passwords and tokens
+type SecretsConfig struct {
+	Encrypt    bool   `yaml:"encrypt,omitempty"`
+	Salt       string `yaml:"salt,omitempty"`        // Random; the key is derived from the passphrase with it
+	Check      string `yaml:"check,omitempty"`       // A known value encrypted with the key, to verify the passphrase
+	KeyCommand string `yaml:"key_command,omitempty"` // Prints the passphrase, such as from the OS keyring, instead of asking
+}
+
+// IsEncrypted reports whether a stored secret is encrypted
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// EnableEncryption turns on encryption of the config's secrets with a new passphrase.
+// They are written encrypted the next time the config is saved.
+func EnableEncryption(c *Config, passphrase string) error {
+	if passphrase == "" {
+		return errors.New("the passphrase must not be empty")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	secrets := SecretsConfig{Salt: base64.StdEncoding.EncodeToString(salt), KeyCommand: c.Secrets.KeyCommand}
+	key, err := deriveKey(passphrase, secrets.Salt)
+	if err != nil {
+		return err
+	}
+	if secrets.Check, err = encryptSecret(key, checkPlaintext); err != nil {
+		return err
+	}
+	secrets.Encrypt = true
+
+	secretKeysMu.Lock()
+	secretKeys[secrets.Salt] = key
+	secretKeysMu.Unlock()
+	c.Secrets = secrets
+	return nil
+}
+
+// DisableEncryption turns off encryption; the secrets are written in plain text the next
+// time the config is saved
+func DisableEncryption(c *Config) {
+	c.Secrets = SecretsConfig{KeyCommand: c.Secrets.KeyCommand}
+}
+
+// eachSecret replaces every secret in the config with fn's result, skipping empty ones
+func (c *Config) eachSecret(fn func(string) (string, error)) error {
+	for provider, value := range c.AI.APIKeys {
+		if value == "" {
+			continue
+		}
+		replaced, err := fn(value)
+		if err != nil {
+			return fmt.Errorf("api_keys.%s: %w", provider, err)
+		}
+		c.AI.APIKeys[provider] = replaced
+	}
+	fields := map[string]*string{
+		"integrations.smtp.password":        &c.Integrations.SMTP.Password,
+		"integrations.sheets.client_secret": &c.Integrations.Sheets.ClientSecret,
+		"integrations.sheets.refresh_token": &c.Integrations.Sheets.RefreshToken,
+	}
+	for name, field := range fields {
+		if *field == "" {
+			continue
+		}
+		replaced, err := fn(*field)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*field = replaced
+	}
+	return nil
+}
+
+// decryptSecrets decrypts the secrets of a loaded config in place, asking for the
+// passphrase with prompt unless this session already has the key
+func decryptSecrets(c *Config, prompt string) error {
+	encrypted := false
+	c.eachSecret(func(value string) (string, error) {
+		encrypted = encrypted || IsEncrypted(value)
+		return value, nil
+	})
+	if !encrypted {
+		return nil
+	}
+
+	key, err := unlock(c.Secrets, prompt)
+	if err != nil {
+		return err
+	}
+	return c.eachSecret(func(value string) (string, error) {
+		if !IsEncrypted(value) {
+			// Saved before encryption was turned on; encrypted on the next save
+			return value, nil
+		}
+		return decryptSecret(key, value)
+	})
+}
+
+// encryptedCopy returns a copy of the config for saving, with its secrets encrypted
+func encryptedCopy(c *Config, prompt string) (*Config, error) {
+	key, err := unlock(c.Secrets, prompt)
+	if err != nil {
+		return nil, err
+	}
+	saved := *c
+	saved.AI.APIKeys = maps.Clone(c.AI.APIKeys)
+	err = saved.eachSecret(func(value string) (string, error) {
+		if IsEncrypted(value) {
+			return value, nil
+		}
+		return encryptSecret(key, value)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// unlock returns the key for the secrets, from the cache, PassphraseEnv, key_command or
+// by asking up to three times
+func unlock(secrets SecretsConfig, prompt string) ([]byte, error) {
+	if secrets.Salt == "" || secrets.Check == "" {
+		return nil, errors.New("secrets.salt and secrets.check are missing; turn encryption off and on again")
+	}
+
+	secretKeysMu.Lock()
+	defer secretKeysMu.Unlock()
+	if key, ok := secretKeys[secrets.Salt]; ok {
+		return key, nil
+	}
+
+	attempts := 1
+	var source func() (string, error)
+	switch {
+	case os.Getenv(PassphraseEnv) != "":
+		source = func() (string, error) { return os.Getenv(PassphraseEnv), nil }
+	case secrets.KeyCommand != "":
+		source = func() (string, error) {
+			passphrase, err := RunPasswordCommand(secrets.KeyCommand)
+			if err != nil {
+				return "", fmt.Errorf("secrets.key_command failed: %w", err)
+			}
+			return passphrase, nil
+		}
+	default:
+		attempts = 3
+		source = func() (string, error) { return PassphrasePrompt(prompt) }
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		passphrase, err := source()
+		if err != nil {
+			return nil, err
+		}
+		key, err := deriveKey(passphrase, secrets.Salt)
+		if err != nil {
+			return nil, err
+		}
+		if check, err := decryptSecret(key, secrets.Check); err == nil && check == checkPlaintext {
+			secretKeys[secrets.Salt] = key
+			return key, nil
+		}
+	}
+	return nil, errors.New("wrong passphrase for the encrypted secrets")
+}
+
+func deriveKey(passphrase, salt string) ([]byte, error) {
+	rawSalt, err := base64.StdEncoding.DecodeString(salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets.salt: %w", err)
+	}
+	return pbkdf2.Key(sha256.New, passphrase, rawSalt, keyIterations, 32)
+}
+
+// encryptSecret seals a value with AES-256-GCM under a random nonce
+func encryptSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptSecret(key []byte, value string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("cannot decrypt value: wrong passphrase or damaged file")
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
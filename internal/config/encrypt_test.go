@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/i18n"
+)
+
+func TestSecretsEncryption(t *testing.T) {
+	iterations, prompt := keyIterations, PassphrasePrompt
+	keyIterations = 1000
+	t.Cleanup(func() { keyIterations, PassphrasePrompt = iterations, prompt })
+	forget := func() { secretKeys = make(map[string][]byte) }
+	t.Setenv(PassphraseEnv, "")
+
+	configDir := t.TempDir()
+	i18nMgr, _ := i18n.NewManager("en_au")
+	cfg := DefaultConfig()
+	cfg.SetAPIKey(ProviderOpenRouter, "sk-or-secret")
+	cfg.Integrations.SMTP.Password = "mail-secret"
+	if err := EnableEncryption(cfg, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveConfig(cfg, configDir, i18nMgr); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(configDir, DefaultConfigFile))
+	if strings.Contains(string(data), "sk-or-secret") || strings.Contains(string(data), "mail-secret") || strings.Count(string(data), encryptedPrefix) != 3 {
+		t.Errorf("Expected the key, password and check encrypted in the file, got\n%s", data)
+	}
+	if cfg.GetAPIKey(ProviderOpenRouter) != "sk-or-secret" {
+		t.Error("Expected the caller's config to keep the key readable")
+	}
+
+	// Asked for until the passphrase is right, then not again this session
+	forget()
+	var asked int
+	PassphrasePrompt = func(string) (string, error) {
+		asked++
+		if asked < 3 {
+			return "wrong", nil
+		}
+		return "correct horse", nil
+	}
+	_, loaded, err := LoadConfig(configDir)
+	if err != nil || loaded.GetAPIKey(ProviderOpenRouter) != "sk-or-secret" || loaded.Integrations.SMTP.Password != "mail-secret" {
+		t.Fatalf("Expected the secrets decrypted, got %+v, %v", loaded, err)
+	}
+	if _, _, err := LoadConfig(configDir); err != nil || asked != 3 {
+		t.Errorf("Expected the passphrase asked for once a session, asked %d times (%v)", asked, err)
+	}
+
+	forget()
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, _, err := LoadConfig(configDir); err == nil {
+		t.Error("Expected a wrong passphrase to be refused")
+	}
+
+	t.Setenv(PassphraseEnv, "correct horse")
+	DisableEncryption(loaded)
+	if err := SaveConfig(loaded, configDir, i18nMgr); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(configDir, DefaultConfigFile)); !strings.Contains(string(data), "sk-or-secret") || strings.Contains(string(data), encryptedPrefix) {
+		t.Errorf("Expected the secrets in plain text after turning encryption off, got\n%s", data)
+	}
+}
@@ -12,28 +12,59 @@ import (
 
 type Manager struct {
 	configDir string
+	dataDir   string // Holds sessions/, the same as configDir unless XDG_DATA_HOME is set
+	profile   string
 }
 
 func NewManager() *Manager {
-	home, err := os.UserHomeDir()
+	manager, err := OpenManager()
 	if err != nil {
-		panic(fmt.Sprintf("failed to get user home directory: %v", err))
+		panic(err.Error())
 	}
+	return manager
+}
 
-	configDir := filepath.Join(home, ".config", "sqlterm")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		panic(fmt.Sprintf("failed to create config directory: %v", err))
+// OpenManager creates a manager for the active profile, creating its directories if
+// this is the profile's first use
+func OpenManager() (*Manager, error) {
+	configDir, dataDir, err := ResolveDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range []string{configDir, dataDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create config directory: %w", err)
+		}
 	}
 
 	return &Manager{
 		configDir: configDir,
-	}
+		dataDir:   dataDir,
+		profile:   ActiveProfile(),
+	}, nil
 }
 
 func (m *Manager) GetConfigDir() string {
 	return m.configDir
 }
 
+// GetProfile returns the name of the profile the manager reads
+func (m *Manager) GetProfile() string {
+	if m.profile == "" {
+		return DefaultProfile
+	}
+	return m.profile
+}
+
+// GetDataDir returns the directory holding session data: history, results and vector indexes
+func (m *Manager) GetDataDir() string {
+	if m.dataDir == "" {
+		return m.configDir
+	}
+	return m.dataDir
+}
+
 func (m *Manager) SaveConnection(config *core.ConnectionConfig) error {
 	connectionsDir := filepath.Join(m.configDir, "connections")
 	if err := os.MkdirAll(connectionsDir, 0755); err != nil {
@@ -64,12 +95,12 @@ func (m *Manager) LoadConnection(name string) (*core.ConnectionConfig, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config core.ConnectionConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	config, err := ValidateConnection(filepath, data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid connection config:\n%w", err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 func (m *Manager) ListConnections() ([]*core.ConnectionConfig, error) {
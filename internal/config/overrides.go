@@ -0,0 +1,102 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// ConnectionOverrides holds optional field changes applied when deriving a connection
+// variant; nil fields keep the source value
+type ConnectionOverrides struct {
+	Host     *string
+	Port     *int
+	Database *string
+	Username *string
+	ReadOnly *bool
+}
+
+// Apply writes the set overrides onto a connection config
+func (o ConnectionOverrides) Apply(cfg *core.ConnectionConfig) {
+	if o.Host != nil {
+		cfg.Host = *o.Host
+	}
+	if o.Port != nil {
+		cfg.Port = *o.Port
+	}
+	if o.Database != nil {
+		cfg.Database = *o.Database
+	}
+	if o.Username != nil {
+		cfg.Username = *o.Username
+	}
+	if o.ReadOnly != nil {
+		cfg.ReadOnly = *o.ReadOnly
+	}
+}
+
+// ConnectionExists reports whether a saved connection with the given name exists
+func (m *Manager) ConnectionExists(name string) bool {
+	_, err := m.LoadConnection(name)
+	return err == nil
+}
+
+// CopyConnection saves a copy of an existing connection under a new name, keeping the
+// stored host and credentials and applying any overrides
+func (m *Manager) CopyConnection(source, target string, overrides ConnectionOverrides) (*core.ConnectionConfig, error) {
+	if m.ConnectionExists(target) {
+		return nil, fmt.Errorf("connection %q already exists", target)
+	}
+
+	cfg, err := m.LoadConnection(source)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Name = target
+	overrides.Apply(cfg)
+
+	if err := m.SaveConnection(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ValidateConnectionName checks that a name can be used as a saved connection's file name
+func ValidateConnectionName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("connection name is empty")
+	case strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, "."):
+		return fmt.Errorf("connection name %q cannot contain slashes or start with a dot", name)
+	}
+	return nil
+}
+
+// RenameConnection saves a connection under a new name and removes the old file
+func (m *Manager) RenameConnection(oldName, newName string) (*core.ConnectionConfig, error) {
+	if err := ValidateConnectionName(newName); err != nil {
+		return nil, err
+	}
+	if m.ConnectionExists(newName) {
+		return nil, fmt.Errorf("connection %q already exists", newName)
+	}
+
+	cfg, err := m.LoadConnection(oldName)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Name = newName
+	if err := m.SaveConnection(cfg); err != nil {
+		return nil, err
+	}
+	if err := m.DeleteConnection(oldName); err != nil {
+		// Keep a single copy rather than two connections with the same settings
+		m.DeleteConnection(newName)
+		return nil, err
+	}
+	return cfg, nil
+}
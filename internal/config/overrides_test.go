@@ -0,0 +1,126 @@
+package config
+
+import (
+	"testing"
+
+	"sqlterm/internal/core"
+)
+
+func TestManager_CopyConnection(t *testing.T) {
+	manager := &Manager{configDir: t.TempDir()}
+
+	source := &core.ConnectionConfig{
+		Name:         "prod",
+		DatabaseType: core.PostgreSQL,
+		Host:         "db.example.com",
+		Port:         5432,
+		Database:     "app",
+		Username:     "admin",
+		Password:     "secret",
+	}
+	if err := manager.SaveConnection(source); err != nil {
+		t.Fatalf("Failed to save source connection: %v", err)
+	}
+
+	readOnly := true
+	database := "analytics"
+	copied, err := manager.CopyConnection("prod", "prod-readonly", ConnectionOverrides{
+		ReadOnly: &readOnly,
+		Database: &database,
+	})
+	if err != nil {
+		t.Fatalf("CopyConnection failed: %v", err)
+	}
+
+	loaded, err := manager.LoadConnection("prod-readonly")
+	if err != nil {
+		t.Fatalf("Failed to load copied connection: %v", err)
+	}
+
+	for _, cfg := range []*core.ConnectionConfig{copied, loaded} {
+		if cfg.Name != "prod-readonly" {
+			t.Errorf("Expected name prod-readonly, got %s", cfg.Name)
+		}
+		if !cfg.ReadOnly || cfg.Database != "analytics" {
+			t.Errorf("Overrides not applied: %+v", cfg)
+		}
+		if cfg.Host != source.Host || cfg.Password != source.Password || cfg.Username != source.Username {
+			t.Errorf("Host and credentials should be copied: %+v", cfg)
+		}
+	}
+
+	// The source must be left untouched
+	original, err := manager.LoadConnection("prod")
+	if err != nil {
+		t.Fatalf("Failed to load source: %v", err)
+	}
+	if original.ReadOnly || original.Database != "app" {
+		t.Errorf("Source connection was modified: %+v", original)
+	}
+
+	if _, err := manager.CopyConnection("prod", "prod-readonly", ConnectionOverrides{}); err == nil {
+		t.Error("Expected error when target already exists")
+	}
+	if _, err := manager.CopyConnection("missing", "other", ConnectionOverrides{}); err == nil {
+		t.Error("Expected error when source does not exist")
+	}
+}
+
+func TestConfig_PolicyFor(t *testing.T) {
+	prod := &core.ConnectionConfig{Name: "prod", Tags: map[string]string{"env": "production"}}
+	dev := &core.ConnectionConfig{Name: "dev", Tags: map[string]string{"env": "dev"}}
+
+	testCases := []struct {
+		name     string
+		policy   string
+		conn     *core.ConnectionConfig
+		expected string
+	}{
+		{name: "Default confirms on prod", policy: "", conn: prod, expected: PolicyConfirm},
+		{name: "Read-only on prod", policy: PolicyReadOnly, conn: prod, expected: PolicyReadOnly},
+		{name: "Disabled on prod", policy: PolicyNone, conn: prod, expected: PolicyNone},
+		{name: "Unknown value falls back to confirm", policy: "bogus", conn: prod, expected: PolicyConfirm},
+		{name: "Non-prod is unguarded", policy: PolicyReadOnly, conn: dev, expected: PolicyNone},
+		{name: "No connection", policy: PolicyReadOnly, conn: nil, expected: PolicyNone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Policy.Production = tc.policy
+			if got := cfg.PolicyFor(tc.conn); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestManager_RenameConnection(t *testing.T) {
+	manager := &Manager{configDir: t.TempDir()}
+	for _, name := range []string{"prod", "staging"} {
+		if err := manager.SaveConnection(&core.ConnectionConfig{Name: name, DatabaseType: core.SQLite, Database: name + ".db"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	renamed, err := manager.RenameConnection("prod", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renamed.Name != "production" || renamed.Database != "prod.db" {
+		t.Errorf("Expected the settings under the new name, got %+v", renamed)
+	}
+	if manager.ConnectionExists("prod") || !manager.ConnectionExists("production") {
+		t.Error("Expected only the new name to exist")
+	}
+
+	if _, err := manager.RenameConnection("production", "staging"); err == nil {
+		t.Error("Expected renaming onto an existing connection to fail")
+	}
+	if _, err := manager.RenameConnection("production", "../escape"); err == nil {
+		t.Error("Expected a name with a slash to be rejected")
+	}
+	if _, err := manager.RenameConnection("missing", "other"); err == nil {
+		t.Error("Expected renaming a missing connection to fail")
+	}
+}
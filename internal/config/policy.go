@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"sqlterm/internal/core"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPolicyFile is the policy file looked for next to config.yaml and connections/
+const DefaultPolicyFile = "policy.yaml"
+
+// DefaultSigningKey is the private key approvals are signed with, next to config.yaml
+const DefaultSigningKey = "approval.key"
+
+// StatementWrite in deny_statements matches every statement that is not a read, the
+// same statements the production safety prompt asks about
+const StatementWrite = "write"
+
+// Policy restricts commands and statements per connection, so a team can ship rules
+// such as "no DML on env=prod" alongside its connection files
+type Policy struct {
+	Rules     []PolicyRule      `yaml:"rules"`
+	Approvers map[string]string `yaml:"approvers,omitempty"` // Name to public key of who may approve statements, see core.VerifyApproval
+}
+
+// PolicyRule denies statements and commands on the connections it applies to
+type PolicyRule struct {
+	Tags           []string `yaml:"tags,omitempty"`            // key=value or bare tags a connection must all have; empty applies everywhere
+	DenyStatements []string `yaml:"deny_statements,omitempty"` // Statement classes (read, dml, ddl, dcl, transaction, other, write) or leading keywords such as DROP
+	DenyCommands   []string `yaml:"deny_commands,omitempty"`   // Commands such as /import
+	Message        string   `yaml:"message,omitempty"`         // Shown instead of the default reason when the rule denies something
+}
+
+var (
+	policyKeywordPattern = regexp.MustCompile(`^[A-Za-z]+$`)
+	policyCommandPattern = regexp.MustCompile(`^/?[a-z][a-z0-9-]*$`)
+)
+
+// LoadPolicy reads and validates a policy file. A missing file is no policy.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return ValidatePolicy(path, data)
+}
+
+// ValidatePolicy checks a policy file's keys and values and decodes it. Problems are
+// returned as ValidationErrors with their line and column.
+func ValidatePolicy(file string, data []byte) (*Policy, error) {
+	v := &validator{file: file}
+	var policy Policy
+	root := v.parse(data, &policy)
+
+	if rules := lookup(root, "rules"); rules != nil && rules.Kind == yaml.SequenceNode {
+		for i, rule := range rules.Content {
+			path := fmt.Sprintf("rules[%d]", i)
+			if lookup(rule, "deny_statements") == nil && lookup(rule, "deny_commands") == nil {
+				v.add(rule, "%s denies nothing: expected deny_statements or deny_commands", path)
+			}
+			if denied := lookup(rule, "deny_statements"); denied != nil && denied.Kind == yaml.SequenceNode {
+				for j, node := range denied.Content {
+					if !policyKeywordPattern.MatchString(node.Value) {
+						v.add(node, "invalid value %q for %s.deny_statements[%d]: expected one of %s, or a leading keyword such as DROP",
+							node.Value, path, j, strings.Join(append(slices.Clone(core.StatementClasses), StatementWrite), ", "))
+					}
+				}
+			}
+			if denied := lookup(rule, "deny_commands"); denied != nil && denied.Kind == yaml.SequenceNode {
+				for j, node := range denied.Content {
+					if !policyCommandPattern.MatchString(node.Value) {
+						v.add(node, "invalid value %q for %s.deny_commands[%d]: expected a command such as /import", node.Value, path, j)
+					}
+				}
+			}
+		}
+	}
+
+	if approvers := lookup(root, "approvers"); approvers != nil && approvers.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(approvers.Content); i += 2 {
+			if _, err := core.ParseApprovalPublicKey(approvers.Content[i+1].Value); err != nil {
+				v.add(approvers.Content[i+1], "invalid value for approvers.%s: %v", approvers.Content[i].Value, err)
+			}
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// appliesTo reports whether the rule covers a connection; rules with tags never cover
+// the time before a connection is made
+func (r *PolicyRule) appliesTo(conn *core.ConnectionConfig) bool {
+	if len(r.Tags) == 0 {
+		return true
+	}
+	if conn == nil {
+		return false
+	}
+	for _, tag := range r.Tags {
+		if !conn.HasTag(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// DeniedStatement returns the first rule that denies a statement on a connection, or nil
+func (p *Policy) DeniedStatement(conn *core.ConnectionConfig, query string) *PolicyRule {
+	if p == nil {
+		return nil
+	}
+	class := core.ClassifyStatement(query)
+	// Every statement's keyword counts, so a DROP cannot hide behind a SELECT
+	keywords := core.StatementKeywords(query)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.appliesTo(conn) {
+			continue
+		}
+		for _, denied := range rule.DenyStatements {
+			if strings.EqualFold(denied, class) || slices.ContainsFunc(keywords, func(keyword string) bool {
+				return strings.EqualFold(denied, keyword)
+			}) ||
+				(strings.EqualFold(denied, StatementWrite) && class != core.StatementRead) {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// DeniedCommand returns the first rule that denies a command, such as /import, on a
+// connection, or nil
+func (p *Policy) DeniedCommand(conn *core.ConnectionConfig, command string) *PolicyRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.appliesTo(conn) {
+			continue
+		}
+		for _, denied := range rule.DenyCommands {
+			if "/"+strings.TrimPrefix(denied, "/") == command {
+				return rule
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sqlterm/internal/core"
+)
+
+func TestPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultPolicyFile)
+	if policy, err := LoadPolicy(path); policy != nil || err != nil {
+		t.Fatalf("A missing policy file should be no policy, got %v, %v", policy, err)
+	}
+
+	data := `rules:
+  - tags: [env=prod]
+    deny_statements: [dml, ddl]
+    message: ask the on-call DBA
+  - deny_commands: [/import]
+  - tags: [env=staging, team=billing]
+    deny_statements: [write]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := LoadPolicy(path)
+	if err != nil {
+		t.Fatalf("LoadPolicy failed: %v", err)
+	}
+
+	prod := &core.ConnectionConfig{Name: "prod", Tags: map[string]string{"env": "prod"}}
+	staging := &core.ConnectionConfig{Name: "staging", Tags: map[string]string{"env": "staging", "team": "billing"}}
+	dev := &core.ConnectionConfig{Name: "dev", Tags: map[string]string{"env": "dev"}}
+
+	if rule := policy.DeniedStatement(prod, "DELETE FROM users"); rule == nil || rule.Message != "ask the on-call DBA" {
+		t.Errorf("Expected DML to be denied on prod, got %+v", rule)
+	}
+	if rule := policy.DeniedStatement(prod, "SELECT * FROM users"); rule != nil {
+		t.Errorf("Reads should be allowed on prod, got %+v", rule)
+	}
+	if rule := policy.DeniedStatement(staging, "SET search_path TO billing"); rule == nil {
+		t.Error("Expected write to deny every statement that is not a read")
+	}
+	if rule := policy.DeniedStatement(prod, "SELECT 1; DROP TABLE users"); rule == nil {
+		t.Error("Expected a change after a read in the same input to be denied")
+	}
+	if rule := policy.DeniedStatement(prod, "EXPLAIN ANALYZE DELETE FROM users"); rule == nil {
+		t.Error("Expected EXPLAIN ANALYZE to be judged by the statement it runs")
+	}
+	if rule := policy.DeniedStatement(dev, "DROP TABLE users"); rule != nil {
+		t.Errorf("Untagged connections should not match tagged rules, got %+v", rule)
+	}
+	if policy.DeniedCommand(nil, "/import") == nil || policy.DeniedCommand(dev, "/import") == nil {
+		t.Error("Rules without tags should apply everywhere, even before connecting")
+	}
+	if policy.DeniedCommand(dev, "/export") != nil {
+		t.Error("Commands not listed should be allowed")
+	}
+
+	var none *Policy
+	if none.DeniedStatement(prod, "DROP TABLE users") != nil || none.DeniedCommand(prod, "/import") != nil {
+		t.Error("A nil policy should deny nothing")
+	}
+
+	for _, invalid := range []string{
+		"rules:\n  - tags: [env=prod]\n",
+		"rules:\n  - deny_statements: [\"DROP TABLE\"]\n",
+		"rules:\n  - deny_commands: [import data]\n",
+		"rules:\n  - deny_comands: [/import]\n",
+		"approvers:\n  bob: not-a-key\n",
+	} {
+		if _, err := ValidatePolicy("policy.yaml", []byte(invalid)); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestPolicyApprovers(t *testing.T) {
+	public, err := core.GenerateApprovalKey(filepath.Join(t.TempDir(), DefaultSigningKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := ValidatePolicy("policy.yaml", []byte("approvers:\n  bob: "+public+"\n"))
+	if err != nil {
+		t.Fatalf("ValidatePolicy failed: %v", err)
+	}
+	if policy.Approvers["bob"] != public {
+		t.Errorf("Expected bob's public key, got %v", policy.Approvers)
+	}
+}
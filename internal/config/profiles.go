@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+)
+
+const (
+	// DefaultProfile is the profile kept directly in the config directory
+	DefaultProfile = "default"
+	// ProfilesDir holds the other profiles, each a config directory of its own with its
+	// config.yaml, AI keys, connections and sessions
+	ProfilesDir = "profiles"
+	// ProfileEnv names the environment variable that picks a profile, like --profile
+	ProfileEnv = "SQLTERM_PROFILE"
+)
+
+// profileOverride is the profile given with --profile or switched to with /profile
+// switch, which wins over ProfileEnv
+var profileOverride string
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// SetProfile makes managers created afterwards use the named profile. An empty name
+// falls back to SQLTERM_PROFILE, then the default profile.
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// ActiveProfile returns the name of the profile managers are created for
+func ActiveProfile() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	if name := os.Getenv(ProfileEnv); name != "" {
+		return name
+	}
+	return DefaultProfile
+}
+
+// ValidateProfileName checks that a profile name can be used as a directory name
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// ListProfiles returns the default profile and the profiles created so far, sorted
+func ListProfiles() ([]string, error) {
+	configDir, _, err := baseDirs()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(configDir, ProfilesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read profiles directory: %w", err)
+	}
+
+	var profiles []string
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != DefaultProfile && ValidateProfileName(entry.Name()) == nil {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+	slices.Sort(profiles)
+	return append([]string{DefaultProfile}, profiles...), nil
+}
+
+// ProfileExists reports whether a profile has been created
+func ProfileExists(name string) bool {
+	profiles, err := ListProfiles()
+	return err == nil && slices.Contains(profiles, name)
+}
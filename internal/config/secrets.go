@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+
+	"sqlterm/internal/core"
+)
+
+// RunPasswordCommand runs a password_command and returns the first line it prints
+func RunPasswordCommand(command string) (string, error) {
+	password, err := core.RunSecretCommand(command)
+	if err != nil {
+		return "", fmt.Errorf("password_command failed: %w", err)
+	}
+	return password, nil
+}
+
+// ResolvePassword returns a copy of a connection with its password filled in for
+// connecting, so secrets fetched at connect time are never written back to its file.
+// A stored password wins, then password_command, then prompt for ask_password
+// connections. IAM and GSSAPI connections need no password, and a nil prompt leaves
+// the password empty.
+func ResolvePassword(cfg *core.ConnectionConfig, prompt func(*core.ConnectionConfig) (string, error)) (*core.ConnectionConfig, error) {
+	resolved := *cfg
+	switch {
+	case cfg.Password != "", !cfg.UsesPassword():
+	case cfg.PasswordCommand != "":
+		password, err := RunPasswordCommand(cfg.PasswordCommand)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Password = password
+	case cfg.AskPassword && prompt != nil:
+		password, err := prompt(cfg)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Password = password
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/core"
+)
+
+func TestResolvePassword(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("password_command tests use sh")
+	}
+	prompted := 0
+	prompt := func(*core.ConnectionConfig) (string, error) {
+		prompted++
+		return "typed", nil
+	}
+
+	cfg := &core.ConnectionConfig{Name: "prod", PasswordCommand: "printf 's3cret\\nsecond line'"}
+	resolved, err := ResolvePassword(cfg, prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved.Password != "s3cret" {
+		t.Errorf("Expected the first line of the command, got %q", resolved.Password)
+	}
+	if cfg.Password != "" {
+		t.Error("Expected the saved config to keep no password")
+	}
+
+	cfg = &core.ConnectionConfig{Name: "prod", PasswordCommand: "echo locked >&2; exit 1"}
+	if _, err := ResolvePassword(cfg, prompt); err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("Expected the command's error output, got %v", err)
+	}
+
+	cfg = &core.ConnectionConfig{Name: "prod", AskPassword: true}
+	resolved, err = ResolvePassword(cfg, prompt)
+	if err != nil || resolved.Password != "typed" || prompted != 1 {
+		t.Errorf("Expected the prompted password, got %q, %v", resolved.Password, err)
+	}
+
+	cfg = &core.ConnectionConfig{Name: "prod", Password: "stored", AskPassword: true}
+	resolved, err = ResolvePassword(cfg, prompt)
+	if err != nil || resolved.Password != "stored" || prompted != 1 {
+		t.Errorf("Expected the stored password without prompting, got %q, %v", resolved.Password, err)
+	}
+
+	cfg = &core.ConnectionConfig{Name: "prod", AskPassword: true, Auth: &core.AuthConfig{Method: core.AuthIAM, Provider: core.IAMProviderAWS}}
+	resolved, err = ResolvePassword(cfg, prompt)
+	if err != nil || resolved.Password != "" || prompted != 1 {
+		t.Errorf("Expected IAM connections not to prompt, got %q, %v", resolved.Password, err)
+	}
+
+	cancelled := errors.New("cancelled")
+	cfg = &core.ConnectionConfig{Name: "prod", AskPassword: true}
+	if _, err := ResolvePassword(cfg, func(*core.ConnectionConfig) (string, error) { return "", cancelled }); !errors.Is(err, cancelled) {
+		t.Errorf("Expected the prompt error, got %v", err)
+	}
+}
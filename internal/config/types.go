@@ -1,5 +1,7 @@
 package config
 
+import "path/filepath"
+
 // Provider represents different AI providers
 type Provider string
 
@@ -11,15 +13,161 @@ const (
 
 // AIConfig holds AI-specific configuration
 type AIConfig struct {
-	Provider      Provider          `yaml:"provider"`
-	Model         string            `yaml:"model"`
-	APIKeys       map[string]string `yaml:"api_keys"`
-	BaseURLs      map[string]string `yaml:"base_urls"`
-	DefaultModels map[string]string `yaml:"default_models"`
+	Provider            Provider          `yaml:"provider"`
+	Model               string            `yaml:"model"`
+	APIKeys             map[string]string `yaml:"api_keys"`
+	BaseURLs            map[string]string `yaml:"base_urls"`
+	DefaultModels       map[string]string `yaml:"default_models"`
+	ContextWindow       int               `yaml:"context_window,omitempty"`        // Tokens; 0 uses what the model reports
+	SelfCorrect         bool              `yaml:"self_correct,omitempty"`          // Ask the model to fix its failing queries
+	SelfCorrectAttempts int               `yaml:"self_correct_attempts,omitempty"` // Corrections per failing query; 0 uses the default
+	Fallbacks           []FallbackConfig  `yaml:"fallbacks,omitempty"`             // Tried in order when the provider fails or is over budget
+	DailyBudget         float64           `yaml:"daily_budget,omitempty"`          // USD per day on paid providers; 0 is unlimited
+	UsageRetentionDays  int               `yaml:"usage_retention_days,omitempty"`  // Days of per-request usage kept; 0 uses the default, negative keeps all
+	ConfirmSend         []Provider        `yaml:"confirm_send,omitempty"`          // Paid providers that ask before each chat message is sent
+}
+
+// FallbackConfig is a provider tried when the ones before it in the chain fail
+type FallbackConfig struct {
+	Provider Provider `yaml:"provider"`
+	Model    string   `yaml:"model,omitempty"` // Empty uses the provider's default model
+}
+
+// DisplayConfig holds result rendering preferences
+type DisplayConfig struct {
+	GeometryBBox bool   `yaml:"geometry_bbox"`
+	Timezone     string `yaml:"timezone,omitempty"` // utc, local or an IANA zone; empty keeps driver values
+	// Estimated rows above which SELECT * of a whole table shows a preview first; 0 uses
+	// the default, negative turns the preview off
+	PreviewThreshold int64 `yaml:"preview_threshold,omitempty"`
+	// Seconds a query runs before its end is announced; 0 uses the default, negative
+	// turns the notice off
+	NotifyAfter int    `yaml:"notify_after,omitempty"`
+	Notify      string `yaml:"notify,omitempty"` // bell (default), desktop or both
+}
+
+// REPLConfig holds preferences for how typed lines are handled
+type REPLConfig struct {
+	BareSQL    bool   `yaml:"bare_sql,omitempty"`    // Run lines starting with an SQL keyword instead of sending them to the AI
+	QueriesDir string `yaml:"queries_dir,omitempty"` // Query library for @name and /queries; empty uses ./queries
+}
+
+// Production connection policies
+const (
+	PolicyConfirm  = "confirm"   // Ask before running statements that modify data
+	PolicyReadOnly = "read-only" // Reject statements that modify data
+	PolicyApprove  = "approve"   // Run statements that modify data once a second person approves them
+	PolicyNone     = "none"      // No extra guard
+)
+
+// PolicyConfig controls how connections tagged env=prod are guarded
+type PolicyConfig struct {
+	Production string `yaml:"production,omitempty"`  // confirm (default), read-only, approve or none
+	File       string `yaml:"file,omitempty"`        // Policy file of command and statement rules; defaults to policy.yaml
+	SigningKey string `yaml:"signing_key,omitempty"` // Private key this user signs approvals with; defaults to approval.key
+}
+
+// FilePath returns the policy file, resolving a relative path against the config directory
+func (p PolicyConfig) FilePath(configDir string) string {
+	file := p.File
+	if file == "" {
+		file = DefaultPolicyFile
+	}
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(configDir, file)
+}
+
+// SigningKeyPath returns the approval signing key, resolving a relative path against
+// the config directory
+func (p PolicyConfig) SigningKeyPath(configDir string) string {
+	file := p.SigningKey
+	if file == "" {
+		file = DefaultSigningKey
+	}
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(configDir, file)
+}
+
+// RetryConfig controls automatic retries of read-only statements after transient errors
+type RetryConfig struct {
+	MaxAttempts int `yaml:"max_attempts,omitempty"` // Total attempts; 1 disables retries, 0 uses the default
+	BackoffMs   int `yaml:"backoff_ms,omitempty"`   // Initial delay, doubled on each retry
+}
+
+// AuditConfig controls the audit log of executed statements
+type AuditConfig struct {
+	Path           string `yaml:"path,omitempty"`            // JSON lines file; relative paths are in the config directory
+	Syslog         bool   `yaml:"syslog,omitempty"`          // Also send each entry to the local syslog
+	ProductionOnly bool   `yaml:"production_only,omitempty"` // Only audit connections tagged env=prod
+}
+
+// Enabled reports whether statements are audited at all
+func (a AuditConfig) Enabled() bool {
+	return a.Path != "" || a.Syslog
+}
+
+// LogPath returns the audit log file, resolving a relative path against the config
+// directory, or "" when entries only go to syslog
+func (a AuditConfig) LogPath(configDir string) string {
+	if a.Path == "" || filepath.IsAbs(a.Path) {
+		return a.Path
+	}
+	return filepath.Join(configDir, a.Path)
+}
+
+// Where pre-images of changed rows are kept
+const (
+	PreImageCSV   = "csv"   // A CSV file in the connection's session directory
+	PreImageTable = "table" // A backup table in the database
+)
+
+// SafetyConfig controls the safeguards taken before statements that change data
+type SafetyConfig struct {
+	PreImage       bool   `yaml:"pre_image,omitempty"`        // Save the rows an UPDATE or DELETE changes first, for /undo
+	PreImageTarget string `yaml:"pre_image_target,omitempty"` // csv (default) or table
+}
+
+// IntegrationsConfig holds credentials for services results can be exported to
+type IntegrationsConfig struct {
+	Sheets SheetsConfig `yaml:"sheets,omitempty"`
+	SMTP   SMTPConfig   `yaml:"smtp,omitempty"`
+}
+
+// SMTPConfig is the mail server for > file --email, with Go templates for the message
+type SMTPConfig struct {
+	Host            string `yaml:"host,omitempty"`
+	Port            int    `yaml:"port,omitempty"`
+	Username        string `yaml:"username,omitempty"`
+	Password        string `yaml:"password,omitempty"`
+	PasswordCommand string `yaml:"password_command,omitempty"` // Prints the password, instead of storing it
+	From            string `yaml:"from,omitempty"`
+	Subject         string `yaml:"subject,omitempty"` // Template with .Query, .Rows, .Duration, .File and .Connection
+	Body            string `yaml:"body,omitempty"`
+}
+
+// SheetsConfig is the Google OAuth client and refresh token for > sheets:// exports
+type SheetsConfig struct {
+	ClientID     string `yaml:"client_id,omitempty"`
+	ClientSecret string `yaml:"client_secret,omitempty"`
+	RefreshToken string `yaml:"refresh_token,omitempty"` // Saved by /config integrations sheets after signing in
 }
 
 // Config holds the main configuration with AI section
 type Config struct {
-	Language string   `yaml:"language"`
-	AI       AIConfig `yaml:"ai"`
+	Version      int                `yaml:"version"` // Layout of the config directory, see CurrentVersion
+	Language     string             `yaml:"language"`
+	AI           AIConfig           `yaml:"ai"`
+	Display      DisplayConfig      `yaml:"display"`
+	Policy       PolicyConfig       `yaml:"policy"`
+	Retry        RetryConfig        `yaml:"retry"`
+	Audit        AuditConfig        `yaml:"audit,omitempty"`
+	Safety       SafetyConfig       `yaml:"safety,omitempty"`
+	REPL         REPLConfig         `yaml:"repl"`
+	Integrations IntegrationsConfig `yaml:"integrations,omitempty"`
+	Secrets      SecretsConfig      `yaml:"secrets,omitempty"`
+	Aliases      map[string]string  `yaml:"aliases,omitempty"` // Slash command shortcuts, such as /t: /tables
 }
@@ -0,0 +1,243 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/i18n"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the layout of the config directory this build reads, kept as
+// version: in config.yaml. Older layouts are upgraded by the steps in upgrades.
+const CurrentVersion = 2
+
+// upgrade moves the config directory from the version before it to its version.
+// Steps must be safe to run again on a directory that is already upgraded.
+type upgrade struct {
+	version int
+	run     func(u *upgrader) error
+}
+
+var upgrades = []upgrade{
+	{version: 1, run: moveAIConfig},
+	{version: 2, run: moveSessionFiles},
+}
+
+// upgrader runs upgrade steps on a config directory, backing up files before they are
+// overwritten or removed
+type upgrader struct {
+	configDir string
+	backupDir string // Created on the first backup
+}
+
+// UpgradeConfigDir brings an older config directory up to CurrentVersion, one step at
+// a time, and records the version in config.yaml. Files a step overwrites or removes
+// are copied to backups/<timestamp>/ first. A new directory is left alone.
+func UpgradeConfigDir(configDir string, i18nMgr *i18n.Manager) error {
+	version, err := configVersion(configDir)
+	if err != nil || version >= CurrentVersion {
+		return err
+	}
+
+	u := &upgrader{configDir: configDir}
+	for _, step := range upgrades {
+		if step.version <= version {
+			continue
+		}
+		if err := step.run(u); err != nil {
+			return fmt.Errorf(i18nMgr.Get("config_upgrade_failed"), step.version, err)
+		}
+	}
+	if err := u.setVersion(CurrentVersion); err != nil {
+		return err
+	}
+
+	fmt.Printf(i18nMgr.Get("config_upgraded"), version, CurrentVersion)
+	if u.backupDir != "" {
+		fmt.Printf(i18nMgr.Get("config_upgrade_backup"), u.backupDir)
+	}
+	return nil
+}
+
+// configVersion reads the version of the config directory: the version: in config.yaml,
+// 0 for files from before it was recorded, and CurrentVersion for a new directory
+func configVersion(configDir string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, DefaultConfigFile))
+	if os.IsNotExist(err) {
+		if _, err := os.Stat(filepath.Join(configDir, "ai.yaml")); err == nil {
+			return 0, nil
+		}
+		return CurrentVersion, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var header struct {
+		Version int `yaml:"version"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		// Reported with its position when the file is validated
+		return CurrentVersion, nil
+	}
+	if header.Version > CurrentVersion {
+		return 0, fmt.Errorf("%s is version %d, newer than this sqlterm reads (%d); upgrade sqlterm", DefaultConfigFile, header.Version, CurrentVersion)
+	}
+	return header.Version, nil
+}
+
+// backup copies a file into the backup directory under its path in the config directory
+func (u *upgrader) backup(path string) error {
+	if u.backupDir == "" {
+		u.backupDir = filepath.Join(u.configDir, "backups", time.Now().Format("20060102_150405"))
+	}
+	rel, err := filepath.Rel(u.configDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	target := filepath.Join(u.backupDir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// remove backs a file up and deletes it
+func (u *upgrader) remove(path string) error {
+	if err := u.backup(path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	return os.Remove(path)
+}
+
+// setVersion records the version at the top of config.yaml, keeping the rest of the
+// file as it is
+func (u *upgrader) setVersion(version int) error {
+	path := filepath.Join(u.configDir, DefaultConfigFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s is not a mapping", DefaultConfigFile)
+	}
+
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if node := lookup(root, "version"); node != nil {
+		*node = *value
+	} else {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+		if len(root.Content) > 0 {
+			// Keep a comment at the top of the file above the new key
+			key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+		}
+		root.Content = append([]*yaml.Node{key, value}, root.Content...)
+	}
+
+	if err := u.backup(path); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, out, 0644)
+}
+
+// moveAIConfig renames ai.yaml, where the AI settings were kept before the other
+// sections were added, to config.yaml
+func moveAIConfig(u *upgrader) error {
+	legacyPath := filepath.Join(u.configDir, "ai.yaml")
+	if _, err := os.Stat(legacyPath); os.IsNotExist(err) {
+		return nil
+	}
+	configPath := filepath.Join(u.configDir, DefaultConfigFile)
+	if _, err := os.Stat(configPath); err == nil {
+		return u.remove(legacyPath)
+	}
+	return os.Rename(legacyPath, configPath)
+}
+
+// moveSessionFiles moves the per-connection files once kept at the top level into the
+// connections' session folders: vectors_<name>.db becomes sessions/<name>/vectors.db,
+// and the shared sessions/history.txt seeds each connection's history.txt
+func moveSessionFiles(u *upgrader) error {
+	sessionsDir := filepath.Join(u.configDir, "sessions")
+
+	vectorDBs, err := filepath.Glob(filepath.Join(u.configDir, "vectors_*.db"))
+	if err != nil {
+		return err
+	}
+	for _, oldPath := range vectorDBs {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(oldPath), "vectors_"), ".db")
+		newPath := filepath.Join(sessionsDir, name, "vectors.db")
+		if _, err := os.Stat(newPath); err == nil {
+			if err := u.remove(oldPath); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(oldPath, newPath); err != nil {
+			return fmt.Errorf("failed to move vector database from %s to %s: %w", oldPath, newPath, err)
+		}
+	}
+
+	legacyHistory := filepath.Join(sessionsDir, "history.txt")
+	history, err := os.ReadFile(legacyHistory)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	connections, err := filepath.Glob(filepath.Join(u.configDir, "connections", "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, connection := range connections {
+		name := strings.TrimSuffix(filepath.Base(connection), ".yaml")
+		historyFile := filepath.Join(sessionsDir, name, "history.txt")
+		if _, err := os.Stat(historyFile); err == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(historyFile), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(historyFile, history, 0644); err != nil {
+			return err
+		}
+	}
+	return u.remove(legacyHistory)
+}
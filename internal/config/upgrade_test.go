@@ -0,0 +1,110 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/i18n"
+)
+
+func TestUpgradeConfigDir(t *testing.T) {
+	configDir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(configDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(rel string) string {
+		data, _ := os.ReadFile(filepath.Join(configDir, rel))
+		return string(data)
+	}
+
+	// A directory from before versions were recorded, with files at the old places
+	write(DefaultConfigFile, "# my settings\nlanguage: zh_cn\nai:\n  provider: ollama\n")
+	write("vectors_shop.db", "old shop index")
+	write("vectors_crm.db", "stale crm index")
+	write("sessions/crm/vectors.db", "current crm index")
+	write("sessions/history.txt", "SELECT 1\n")
+	write("connections/shop.yaml", "name: shop\n")
+	write("connections/crm.yaml", "name: crm\n")
+	write("sessions/crm/history.txt", "SELECT 2\n")
+
+	i18nMgr, _ := i18n.NewManager("en_au")
+	if err := UpgradeConfigDir(configDir, i18nMgr); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read("sessions/shop/vectors.db"); got != "old shop index" {
+		t.Errorf("Expected the shop index to move to its session folder, got %q", got)
+	}
+	if got := read("sessions/crm/vectors.db"); got != "current crm index" {
+		t.Errorf("Expected the current crm index to be kept, got %q", got)
+	}
+	if got := read("sessions/shop/history.txt"); got != "SELECT 1\n" {
+		t.Errorf("Expected the shared history to seed shop's, got %q", got)
+	}
+	if got := read("sessions/crm/history.txt"); got != "SELECT 2\n" {
+		t.Errorf("Expected crm's own history to be kept, got %q", got)
+	}
+	for _, rel := range []string{"vectors_shop.db", "vectors_crm.db", "sessions/history.txt"} {
+		if _, err := os.Stat(filepath.Join(configDir, rel)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone", rel)
+		}
+	}
+
+	config := read(DefaultConfigFile)
+	if !strings.HasPrefix(config, "# my settings\nversion: 2\n") || !strings.Contains(config, "provider: ollama") {
+		t.Errorf("Expected the version added with the settings and comment kept, got\n%s", config)
+	}
+
+	// Removed and rewritten files are backed up
+	backups, _ := filepath.Glob(filepath.Join(configDir, "backups", "*"))
+	if len(backups) != 1 {
+		t.Fatalf("Expected one backup directory, got %v", backups)
+	}
+	for rel, want := range map[string]string{
+		"vectors_crm.db":       "stale crm index",
+		"sessions/history.txt": "SELECT 1\n",
+		DefaultConfigFile:      "# my settings\nlanguage: zh_cn\nai:\n  provider: ollama\n",
+	} {
+		if data, _ := os.ReadFile(filepath.Join(backups[0], rel)); string(data) != want {
+			t.Errorf("Expected %s backed up as %q, got %q", rel, want, data)
+		}
+	}
+
+	// Upgraded directories are left alone
+	if err := UpgradeConfigDir(configDir, i18nMgr); err != nil {
+		t.Fatal(err)
+	}
+	if read(DefaultConfigFile) != config {
+		t.Error("Expected an upgraded config.yaml to be left alone")
+	}
+
+	write(DefaultConfigFile, "version: 99\n")
+	if err := UpgradeConfigDir(configDir, i18nMgr); err == nil {
+		t.Error("Expected a config from a newer sqlterm to be refused")
+	}
+}
+
+func TestUpgradeConfigDir_NewDirectory(t *testing.T) {
+	configDir := t.TempDir()
+	i18nMgr, _ := i18n.NewManager("en_au")
+	if err := UpgradeConfigDir(configDir, i18nMgr); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(configDir); len(entries) != 0 {
+		t.Errorf("Expected nothing written to a new directory, got %v", entries)
+	}
+
+	_, config, err := LoadConfig(configDir)
+	if err != nil || config.Version != CurrentVersion {
+		t.Errorf("Expected a new config at version %d, got %+v, %v", CurrentVersion, config, err)
+	}
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem in a config file, at the line and column it was found
+type ValidationError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors are all the problems found in a file, in the order they appear
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// validator collects the problems of one file
+type validator struct {
+	file string
+	errs ValidationErrors
+}
+
+func (v *validator) add(node *yaml.Node, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{File: v.file, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(v.errs, func(a, b ValidationError) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return v.errs
+}
+
+// parse reads a YAML document and checks its keys and values against out's type,
+// then decodes it into out. It returns the document's top-level mapping, or nil when
+// the file is empty or does not parse.
+func (v *validator) parse(data []byte, out any) *yaml.Node {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// Syntax errors only carry a line, as "yaml: line 3: ..."
+		message := strings.TrimPrefix(err.Error(), "yaml: ")
+		line := 1
+		if n, _ := fmt.Sscanf(message, "line %d:", &line); n == 1 {
+			_, message, _ = strings.Cut(message, ": ")
+		}
+		v.errs = append(v.errs, ValidationError{File: v.file, Line: line, Column: 1, Message: message})
+		return nil
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	v.check(root, reflect.TypeOf(out).Elem(), "")
+	if len(v.errs) == 0 {
+		if err := root.Decode(out); err != nil {
+			v.add(root, "%v", err)
+		}
+	}
+	return root
+}
+
+// check walks a node against the Go type it decodes into, reporting unknown keys with
+// the closest known ones and values of the wrong type
+func (v *validator) check(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, "%s must be a mapping", describePath(path))
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			field, ok := fields[key.Value]
+			if !ok {
+				names := make([]string, 0, len(fields))
+				for name := range fields {
+					names = append(names, name)
+				}
+				slices.Sort(names)
+				if closest := core.ClosestNames(key.Value, names, 1); len(closest) > 0 {
+					v.add(key, "unknown key %q in %s, did you mean %q?", key.Value, describePath(path), closest[0])
+				} else {
+					v.add(key, "unknown key %q in %s", key.Value, describePath(path))
+				}
+				continue
+			}
+			v.check(value, field, joinPath(path, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(node, "%s must be a mapping", describePath(path))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.check(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(node, "%s must be a list", describePath(path))
+			return
+		}
+		for i, item := range node.Content {
+			v.check(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			v.add(node, "%s must be a single value", describePath(path))
+			return
+		}
+		value := reflect.New(t).Interface()
+		if err := node.Decode(value); err != nil {
+			if _, ok := value.(yaml.Unmarshaler); ok {
+				v.add(node, "invalid value %q for %s: %v", node.Value, describePath(path), err)
+			} else {
+				v.add(node, "invalid value %q for %s: expected %s", node.Value, describePath(path), describeKind(t))
+			}
+		}
+	}
+}
+
+// require reports keys missing from a mapping, at the mapping's position
+func (v *validator) require(node *yaml.Node, path string, keys ...string) {
+	if node == nil {
+		return
+	}
+	for _, key := range keys {
+		if lookup(node, key) == nil {
+			v.add(node, "missing required key %q in %s", key, describePath(path))
+		}
+	}
+}
+
+// oneOf reports a set value that is not one of the allowed ones
+func (v *validator) oneOf(node *yaml.Node, path string, allowed ...string) {
+	if node == nil || node.Kind != yaml.ScalarNode || node.Value == "" || slices.Contains(allowed, node.Value) {
+		return
+	}
+	v.add(node, "invalid value %q for %s: expected one of %s", node.Value, describePath(path), strings.Join(allowed, ", "))
+}
+
+// lookup follows a dotted path of keys through nested mappings
+func lookup(node *yaml.Node, path string) *yaml.Node {
+	for _, key := range strings.Split(path, ".") {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// yamlFields maps the YAML keys of a struct to their field types, following inline fields
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(opts, "inline") {
+			for key, typ := range yamlFields(field.Type) {
+				fields[key] = typ
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func describePath(path string) string {
+	if path == "" {
+		return "the file"
+	}
+	return path
+}
+
+func describeKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	default:
+		return "text"
+	}
+}
+
+// ValidateConfig checks config.yaml's keys and values and decodes it. Problems are
+// returned as ValidationErrors with their line and column.
+func ValidateConfig(file string, data []byte) (*Config, error) {
+	v := &validator{file: file}
+	var config Config
+	root := v.parse(data, &config)
+
+	providers := []string{string(ProviderOpenRouter), string(ProviderOllama), string(ProviderLMStudio)}
+	v.oneOf(lookup(root, "ai.provider"), "ai.provider", providers...)
+	if fallbacks := lookup(root, "ai.fallbacks"); fallbacks != nil && fallbacks.Kind == yaml.SequenceNode {
+		for i, fallback := range fallbacks.Content {
+			path := fmt.Sprintf("ai.fallbacks[%d]", i)
+			v.require(fallback, path, "provider")
+			v.oneOf(lookup(fallback, "provider"), path+".provider", providers...)
+		}
+	}
+	if confirm := lookup(root, "ai.confirm_send"); confirm != nil && confirm.Kind == yaml.SequenceNode {
+		for i, provider := range confirm.Content {
+			v.oneOf(provider, fmt.Sprintf("ai.confirm_send[%d]", i), providers...)
+		}
+	}
+	v.oneOf(lookup(root, "language"), "language", "en_au", "zh_cn")
+	v.oneOf(lookup(root, "safety.pre_image_target"), "safety.pre_image_target", PreImageCSV, PreImageTable)
+	v.oneOf(lookup(root, "policy.production"), "policy.production", PolicyConfirm, PolicyReadOnly, PolicyApprove, PolicyNone)
+	if zone := lookup(root, "display.timezone"); zone != nil && zone.Kind == yaml.ScalarNode {
+		if _, err := time.LoadLocation(zone.Value); err != nil && zone.Value != "local" && zone.Value != "utc" {
+			v.add(zone, "invalid value %q for display.timezone: expected utc, local or an IANA zone such as Europe/Berlin", zone.Value)
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ValidateConnection checks a connection file's keys and values and decodes it.
+// Problems are returned as ValidationErrors with their line and column.
+func ValidateConnection(file string, data []byte) (*core.ConnectionConfig, error) {
+	v := &validator{file: file}
+	var config core.ConnectionConfig
+	root := v.parse(data, &config)
+
+	v.require(root, "", "name", "database_type", "database")
+	if root != nil && len(v.errs) == 0 && !config.DatabaseType.IsFileBased() {
+		v.require(root, "", "host")
+	}
+	v.oneOf(lookup(root, "estimate_rows"), "estimate_rows", core.EstimateRowsCount, core.EstimateRowsExplain)
+	if auth := lookup(root, "auth"); auth != nil && auth.Kind == yaml.MappingNode {
+		v.require(auth, "auth", "method")
+		v.oneOf(lookup(auth, "method"), "auth.method", core.AuthMethods...)
+	}
+	if attachments := lookup(root, "attach"); attachments != nil && attachments.Kind == yaml.SequenceNode {
+		for i, attachment := range attachments.Content {
+			v.require(attachment, fmt.Sprintf("attach[%d]", i), "path", "as")
+		}
+	}
+	if replicas := lookup(root, "replicas"); replicas != nil && replicas.Kind == yaml.SequenceNode {
+		for i, replica := range replicas.Content {
+			v.require(replica, fmt.Sprintf("replicas[%d]", i), "host")
+		}
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ValidateAll checks config.yaml, the policy file and every connection file, returning
+// the problems by file. Files that cannot be read are reported as errors.
+func (m *Manager) ValidateAll() (map[string]error, error) {
+	results := make(map[string]error)
+
+	configPath := filepath.Join(m.configDir, DefaultConfigFile)
+	policyPath := PolicyConfig{}.FilePath(m.configDir)
+	if data, err := os.ReadFile(configPath); err == nil {
+		var config *Config
+		config, results[configPath] = ValidateConfig(configPath, data)
+		if config != nil {
+			policyPath = config.Policy.FilePath(m.configDir)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	if data, err := os.ReadFile(policyPath); err == nil {
+		_, results[policyPath] = ValidatePolicy(policyPath, data)
+	} else if !os.IsNotExist(err) {
+		results[policyPath] = err
+	}
+
+	connectionsDir := filepath.Join(m.configDir, "connections")
+	entries, err := os.ReadDir(connectionsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read connections directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		path := filepath.Join(connectionsDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			results[path] = err
+			continue
+		}
+		_, results[path] = ValidateConnection(path, data)
+	}
+	return results, nil
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/core"
+	"sqlterm/internal/i18n"
+
+	"gopkg.in/yaml.v3"
+)
+
+// problems returns the validation errors as "line:column: message"
+func problems(t *testing.T, err error) []string {
+	t.Helper()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Expected ValidationErrors, got %v", err)
+	}
+	var lines []string
+	for _, e := range errs {
+		lines = append(lines, strings.TrimPrefix(e.Error(), e.File+":"))
+	}
+	return lines
+}
+
+func TestValidateConnection(t *testing.T) {
+	data := []byte(`name: shop
+database_type: postgres
+host: localhost
+port: abc
+databse: shop
+auth:
+  method: kerberos
+`)
+	_, err := ValidateConnection("shop.yaml", data)
+	want := []string{
+		`1:1: missing required key "database" in the file`,
+		`4:7: invalid value "abc" for port: expected a whole number`,
+		`5:1: unknown key "databse" in the file, did you mean "database"?`,
+		`7:11: invalid value "kerberos" for auth.method: expected one of iam, gssapi, ldap`,
+	}
+	if got := problems(t, err); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	cfg, err := ValidateConnection("local.yaml", []byte("name: local\ndatabase_type: sqlite\ndatabase: /tmp/local.db\n"))
+	if err != nil || cfg.DatabaseType != core.SQLite {
+		t.Errorf("Expected a SQLite connection named by type without a host, got %+v, %v", cfg, err)
+	}
+	if _, err := ValidateConnection("pg.yaml", []byte("name: pg\ndatabase_type: 1\ndatabase: app\n")); err == nil || !strings.Contains(err.Error(), `missing required key "host"`) {
+		t.Errorf("Expected a server connection to need a host, got %v", err)
+	}
+	if _, err := ValidateConnection("bad.yaml", []byte("name: bad\ndatabase_type: 9\ndatabase: x\n")); err == nil || !strings.Contains(err.Error(), "2:16:") {
+		t.Errorf("Expected the unknown database type at its value, got %v", err)
+	}
+
+	// Files written by SaveConnection load back
+	saved, _ := yaml.Marshal(&core.ConnectionConfig{Name: "db", DatabaseType: core.MySQL, Host: "h", Database: "d", Username: "u"})
+	if _, err := ValidateConnection("db.yaml", saved); err != nil {
+		t.Errorf("Expected a saved connection to be valid, got %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	data := []byte(`language: en_au
+ai:
+  provider: openrouter
+  modle: gpt
+  fallbacks:
+    - model: llama3.2
+retry:
+  max_attempts: lots
+policy:
+  production: readonly
+`)
+	_, err := ValidateConfig("config.yaml", data)
+	want := []string{
+		`4:3: unknown key "modle" in ai, did you mean "model"?`,
+		`6:7: missing required key "provider" in ai.fallbacks[0]`,
+		`8:17: invalid value "lots" for retry.max_attempts: expected a whole number`,
+		`10:15: invalid value "readonly" for policy.production: expected one of confirm, read-only, approve, none`,
+	}
+	if got := problems(t, err); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if _, err := ValidateConfig("config.yaml", []byte("ai:\n\tprovider: openrouter\n")); err == nil || !strings.HasPrefix(problems(t, err)[0], "2:1:") {
+		t.Errorf("Expected a syntax error with its line, got %v", err)
+	}
+}
+
+func TestManager_ValidateAll(t *testing.T) {
+	configDir := t.TempDir()
+	i18nMgr, _ := i18n.NewManager("en_au")
+	if err := SaveConfig(DefaultConfig(), configDir, i18nMgr); err != nil {
+		t.Fatal(err)
+	}
+	manager := &Manager{configDir: configDir}
+	if err := manager.SaveConnection(&core.ConnectionConfig{Name: "good", DatabaseType: core.SQLite, Database: "good.db"}); err != nil {
+		t.Fatal(err)
+	}
+	badPath := filepath.Join(configDir, "connections", "bad.yaml")
+	if err := os.WriteFile(badPath, []byte("name: bad\ndatabase_type: 2\ndatabase: bad.db\nsssl: true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := manager.ValidateAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[filepath.Join(configDir, DefaultConfigFile)] != nil || results[filepath.Join(configDir, "connections", "good.yaml")] != nil {
+		t.Errorf("Expected the default config and saved connection to be valid, got %v", results)
+	}
+	if results[badPath] == nil {
+		t.Error("Expected the misspelt key to be reported")
+	}
+	if _, err := manager.LoadConnection("bad"); err == nil {
+		t.Error("Expected loading an invalid connection to fail")
+	}
+}
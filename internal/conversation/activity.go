@@ -0,0 +1,180 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+const defaultActivityInterval = 2 * time.Second
+
+// handleActivity lists running sessions on the server, optionally refreshing until Ctrl+C
+func (a *App) handleActivity(args []string) error {
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	includeIdle := false
+	watch := false
+	interval := defaultActivityInterval
+	for _, arg := range args {
+		switch {
+		case arg == "--all":
+			includeIdle = true
+		case arg == "--watch":
+			watch = true
+		default:
+			parsed, err := time.ParseDuration(arg)
+			if err != nil || parsed <= 0 {
+				fmt.Println(a.i18nMgr.Get("usage_activity"))
+				return nil
+			}
+			watch = true
+			interval = parsed
+		}
+	}
+
+	if !watch {
+		return a.showActivity(includeIdle)
+	}
+
+	return a.watch(interval, func() error { return a.showActivity(includeIdle) })
+}
+
+// watch redraws a view every interval until Ctrl+C, clearing the screen so each
+// refresh replaces the previous one, like top
+func (a *App) watch(interval time.Duration, show func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		fmt.Print("\033[H\033[2J")
+		if err := show(); err != nil {
+			return err
+		}
+		fmt.Printf(a.i18nMgr.Get("watch_footer"), interval)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (a *App) showActivity(includeIdle bool) error {
+	sessions, err := core.ListActivity(a.connection, a.config.DatabaseType, includeIdle)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_list_activity"), err)
+	}
+	return a.displayMarkdown(a.formatActivity(sessions))
+}
+
+func (a *App) formatActivity(sessions []core.SessionActivity) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 📈 %s\n\n", a.i18nMgr.GetWithArgs("activity_header", a.config.Name, time.Now().Format("15:04:05"))))
+	if len(sessions) == 0 {
+		sb.WriteString(a.i18nMgr.Get("activity_no_sessions"))
+		return sb.String()
+	}
+
+	sb.WriteString(a.i18nMgr.Get("activity_table_header"))
+	sb.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, s := range sessions {
+		query := strings.Join(strings.Fields(s.Query), " ")
+		query = strings.ReplaceAll(a.truncateQuery(query), "|", "\\|")
+		if query != "" {
+			query = "`" + query + "`"
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %v | %s | %s |\n",
+			s.PID, s.User, s.Database, s.State, s.Duration.Round(time.Second), s.Wait, query))
+	}
+	sb.WriteString("\n" + a.i18nMgr.Get("activity_kill_hint"))
+	return sb.String()
+}
+
+// handleKill terminates a server session, or cancels its running query with --query
+func (a *App) handleKill(args []string) error {
+	queryOnly := false
+	var pid string
+	for _, arg := range args {
+		if arg == "--query" {
+			queryOnly = true
+			continue
+		}
+		pid = arg
+	}
+	if pid == "" {
+		fmt.Println(a.i18nMgr.Get("usage_kill"))
+		return nil
+	}
+
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	statement, err := core.KillStatement(a.config.DatabaseType, pid, queryOnly)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_kill_session"), err)
+	}
+
+	question := a.i18nMgr.GetWithArgs("kill_session_question", pid, a.config.Name)
+	if queryOnly {
+		question = a.i18nMgr.GetWithArgs("cancel_query_question", pid, a.config.Name)
+	}
+	if !a.confirm(question) {
+		return errors.New(a.i18nMgr.Get("statement_cancelled"))
+	}
+
+	start := time.Now()
+	result, err := a.connection.Execute(statement)
+	if err != nil {
+		a.audit(statement, start, 0, err)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_kill_session"), err)
+	}
+	for range result.Itor() {
+	}
+	result.Close()
+	a.audit(statement, start, result.RowCount(), result.Error())
+	if result.Error() != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_kill_session"), result.Error())
+	}
+
+	fmt.Printf(a.i18nMgr.Get("session_killed"), pid)
+	return nil
+}
+
+// handleLocks shows lock waits as blocker → blocked chains
+func (a *App) handleLocks() error {
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	waits, err := core.ListLockWaits(a.connection, a.config.DatabaseType)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_list_locks"), err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🔒 %s\n\n", a.i18nMgr.GetWithArgs("locks_header", a.config.Name)))
+	if len(waits) == 0 {
+		sb.WriteString(a.i18nMgr.Get("locks_none"))
+		return a.displayMarkdown(sb.String())
+	}
+
+	roots := core.BuildBlockingTree(waits)
+	sb.WriteString(a.i18nMgr.GetWithArgs("locks_summary", len(waits), len(roots)))
+	sb.WriteString("\n```text\n")
+	sb.WriteString(core.RenderBlockingTree(roots))
+	sb.WriteString("```\n\n")
+	sb.WriteString(a.i18nMgr.Get("locks_kill_hint"))
+	return a.displayMarkdown(sb.String())
+}
@@ -0,0 +1,138 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/ai"
+)
+
+// offerToQueue asks whether to retry a message in the background after the provider
+// could not be reached, and starts the retry worker when accepted
+func (a *App) offerToQueue(message string, tables []string) bool {
+	fmt.Print(a.i18nMgr.Get("ai_provider_unreachable"))
+	if !a.confirm(a.i18nMgr.Get("ai_queue_question")) {
+		return false
+	}
+
+	item := a.aiQueue.Add(message, tables)
+	fmt.Printf(a.i18nMgr.Get("ai_queued"), item.ID)
+	if a.aiQueue.StartWorker() {
+		go a.runAIQueue()
+	}
+	return true
+}
+
+// runAIQueue retries queued questions oldest first, backing off while the provider
+// stays unreachable, and stops once nothing is pending
+func (a *App) runAIQueue() {
+	delay := ai.QueueRetryInitial
+	for {
+		time.Sleep(delay)
+
+		for {
+			item, ok := a.aiQueue.NextPending()
+			if !ok {
+				break
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+			a.aiMu.Lock()
+			response, err := a.aiManager.ChatWithConversation(ctx, item.Message, item.Tables)
+			a.aiMu.Unlock()
+			cancel()
+
+			if err == nil {
+				a.aiQueue.Resolve(item.ID, response)
+				fmt.Fprintf(a.notifyWriter(), a.i18nMgr.Get("ai_queue_answered"), item.ID, a.truncateQuery(oneLine(item.Message)), item.ID)
+				delay = ai.QueueRetryInitial
+				continue
+			}
+			if a.aiQueue.Retry(item.ID, err) {
+				// Still unreachable; wait longer before trying again
+				delay = ai.NextRetryDelay(delay)
+				break
+			}
+			fmt.Fprintf(a.notifyWriter(), a.i18nMgr.Get("ai_queue_gave_up"), item.ID, err)
+		}
+
+		if a.aiQueue.StopWorkerIfIdle() {
+			return
+		}
+	}
+}
+
+// notifyWriter returns where background notices go, redrawing the prompt after them
+func (a *App) notifyWriter() io.Writer {
+	if a.rl != nil {
+		return a.rl.Stdout()
+	}
+	return os.Stdout
+}
+
+// handleAIQueue handles /ai queue [id|clear [--all]]
+func (a *App) handleAIQueue(args []string) error {
+	if len(args) == 0 {
+		a.showAIQueue()
+		return nil
+	}
+
+	if args[0] == "clear" {
+		all := len(args) > 1 && args[1] == "--all"
+		fmt.Printf(a.i18nMgr.Get("ai_queue_cleared"), a.aiQueue.Clear(all))
+		return nil
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_ai"))
+		return nil
+	}
+	item, ok := a.aiQueue.Get(id)
+	if !ok {
+		fmt.Printf(a.i18nMgr.Get("ai_queue_not_found"), id)
+		return nil
+	}
+
+	fmt.Printf("❓ %s\n", item.Message)
+	switch item.Status {
+	case ai.QueueAnswered:
+		a.displayAIResponse(item.Response)
+	case ai.QueueFailed:
+		fmt.Printf(a.i18nMgr.Get("ai_queue_failed_detail"), item.Attempts, item.LastError)
+	default:
+		fmt.Printf(a.i18nMgr.Get("ai_queue_pending_detail"), item.Attempts)
+	}
+	return nil
+}
+
+// showAIQueue lists the queued questions of this session with their state
+func (a *App) showAIQueue() {
+	items := a.aiQueue.Items()
+	if len(items) == 0 {
+		fmt.Println(a.i18nMgr.Get("ai_queue_empty"))
+		return
+	}
+
+	fmt.Println(a.i18nMgr.Get("ai_queue_header"))
+	for _, item := range items {
+		status := a.i18nMgr.Get("ai_queue_status_pending")
+		switch item.Status {
+		case ai.QueueAnswered:
+			status = a.i18nMgr.Get("ai_queue_status_answered")
+		case ai.QueueFailed:
+			status = a.i18nMgr.Get("ai_queue_status_failed")
+		}
+		fmt.Printf("  #%d  %s  %s  %s\n", item.ID, item.QueuedAt.Format("15:04:05"), status, a.truncateQuery(oneLine(item.Message)))
+	}
+}
+
+// oneLine collapses whitespace, including newlines, to single spaces
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
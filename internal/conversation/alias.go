@@ -0,0 +1,125 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// userAliases returns the slash command shortcuts from the config, keyed with the slash
+// even when the config file leaves it off
+func (a *App) userAliases() map[string]string {
+	if a.aiManager == nil {
+		return nil
+	}
+	cfg := a.aiManager.GetConfig()
+	if cfg == nil || len(cfg.Aliases) == 0 {
+		return nil
+	}
+	aliases := make(map[string]string, len(cfg.Aliases))
+	for name, expansion := range cfg.Aliases {
+		aliases[slashName(name)] = expansion
+	}
+	return aliases
+}
+
+// expandAlias replaces a leading user alias with the command it stands for, keeping
+// the arguments typed after it. Built-in commands cannot be shadowed, and expansions
+// are not expanded again, so aliases cannot loop.
+func (a *App) expandAlias(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	name, rest, _ := strings.Cut(trimmed, " ")
+	if _, ok := lookupCommand(name); ok {
+		return line
+	}
+	expansion, ok := a.userAliases()[name]
+	if !ok {
+		return line
+	}
+	if rest == "" && !strings.HasSuffix(trimmed, " ") {
+		return expansion
+	}
+	return expansion + " " + rest
+}
+
+// aliasNames lists the user's aliases in name order
+func (a *App) aliasNames() []string {
+	return slices.Sorted(maps.Keys(a.userAliases()))
+}
+
+// handleAlias lists, defines or removes slash command shortcuts
+func (a *App) handleAlias(args []string) error {
+	if len(args) == 0 {
+		a.listAliases()
+		return nil
+	}
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+
+	aliases := maps.Clone(a.userAliases())
+	if aliases == nil {
+		aliases = make(map[string]string)
+	}
+
+	if args[0] == "--remove" {
+		if len(args) != 2 {
+			fmt.Println(a.i18nMgr.Get("usage_alias"))
+			return nil
+		}
+		name := slashName(args[1])
+		if _, ok := aliases[name]; !ok {
+			fmt.Println(a.i18nMgr.GetWithArgs("alias_not_found", name))
+			return nil
+		}
+		delete(aliases, name)
+		if err := a.aiManager.SetAliases(aliases); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_save_aliases"), err)
+		}
+		fmt.Println(a.i18nMgr.GetWithArgs("alias_removed", name))
+		return nil
+	}
+
+	if len(args) < 2 {
+		fmt.Println(a.i18nMgr.Get("usage_alias"))
+		return nil
+	}
+	name := slashName(args[0])
+	if _, ok := lookupCommand(name); ok {
+		return errors.New(a.i18nMgr.GetWithArgs("alias_shadows_command", name))
+	}
+	expansion := strings.Join(args[1:], " ")
+	if _, ok := lookupCommand(strings.Fields(expansion)[0]); !ok {
+		return errors.New(a.i18nMgr.GetWithArgs("alias_unknown_command", expansion))
+	}
+
+	aliases[name] = expansion
+	if err := a.aiManager.SetAliases(aliases); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_save_aliases"), err)
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("alias_saved", name, expansion))
+	return nil
+}
+
+func (a *App) listAliases() {
+	names := a.aliasNames()
+	if len(names) == 0 {
+		fmt.Println(a.i18nMgr.Get("alias_none"))
+		return
+	}
+	aliases := a.userAliases()
+	fmt.Println(a.i18nMgr.Get("alias_list_title"))
+	for _, name := range names {
+		fmt.Printf("  %-12s → %s\n", name, aliases[name])
+	}
+}
+
+// slashName adds the slash users may leave off when naming an alias
+func slashName(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return "/" + name
+}
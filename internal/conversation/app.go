@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"sqlterm/internal/ai"
@@ -23,24 +25,66 @@ import (
 )
 
 type App struct {
-	rl         *readline.Instance
-	connection core.Connection
-	config     *core.ConnectionConfig
-	configMgr  *config.Manager
-	sessionMgr *session.Manager
-	aiManager  *ai.Manager
-	i18nMgr    *i18n.Manager
+	rl            *readline.Instance
+	connection    core.Connection
+	config        *core.ConnectionConfig
+	configMgr     *config.Manager
+	sessionMgr    *session.Manager
+	aiManager     *ai.Manager
+	i18nMgr       *i18n.Manager
+	queryLog      *session.QueryLog
+	lastResult    *ai.ResultAttachment   // Shape and first rows of the last query, for /ai attach-result
+	attachRows    int                    // Rows to attach to the next AI message; 0 when none is pending
+	onPrimary     bool                   // Set while /exec --primary runs, so reads skip the replicas
+	vars          map[string]string      // Session variables set with /set, see core.ExpandVariables
+	scratch       []string               // Temporary tables made with /scratch, dropped on disconnect
+	started       time.Time              // When the connection was opened, see /export-session
+	transcript    []core.ReportQuery     // Statements run since then, with their first rows
+	aiMu          sync.Mutex             // Serialises AI chats between the REPL and the queue worker
+	aiQueue       ai.Queue               // Questions waiting for an unreachable provider, see /ai queue
+	auditLog      *session.AuditLog      // Executed statements, when audit: is set in config.yaml
+	policy        *config.Policy         // Commands and statements denied per connection by the policy file
+	approved      *approvedStatement     // Approval of the statement being run, for the audit log
+	undoing       bool                   // Set while /undo runs its statements, which need no pre-image
+	displaying    bool                   // Set while processQuery runs a query whose result is only shown
+	paste         *pasteReader           // Input of the line editor, holding back multi-line pastes
+	drafts        []string               // Input lines stashed with Ctrl+S, newest last
+	draftsMu      sync.Mutex             // The line editor stashes from its own goroutine
+	profile       *StartupProfile        // Startup timings to report before the first prompt, nil unless asked for
+	draft         string                 // Input being run or typed, saved if a command panics
+	inTransaction bool                   // A BEGIN has run without its COMMIT or ROLLBACK
+	stats         *session.SessionStats  // Statements, rows and exports of the connection's session, see /status
+	lastColumns   *resultColumns         // Column metadata of the last query, see /columns
+	sensitive     *core.SensitiveColumns // Columns masked in results and AI sample data, see /sensitive
+	unmaskNext    bool                   // Set by /unmask to show sensitive columns in the next query
+	jobs          core.Jobs              // Statements run in the background with a trailing &, see /jobs
 }
 
 func NewApp() (*App, error) {
-	configMgr := config.NewManager()
+	return NewAppWithProfile(nil)
+}
 
-	// Initialize AI manager first
-	aiManager, err := ai.NewManager(configMgr.GetConfigDir())
-	if err != nil {
-		// AI manager initialization failed completely
-		aiManager = nil
-	}
+// NewAppWithProfile creates the app, marking each startup step in profile, which may be nil
+func NewAppWithProfile(profile *StartupProfile) (*App, error) {
+	configMgr := config.NewManager()
+	sessionsDir := filepath.Join(configMgr.GetDataDir(), "sessions")
+
+	// Load the AI config while the sessions directory is made; the AI client and the
+	// vector store are only opened on first use
+	var aiManager *ai.Manager
+	var aiErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		aiManager, aiErr = ai.NewManager(configMgr.GetConfigDir())
+		if aiErr == nil {
+			aiManager.SetDataDir(configMgr.GetDataDir())
+		}
+	}()
+	sessionsErr := os.MkdirAll(sessionsDir, 0755)
+	wg.Wait()
+	profile.Mark("config")
 
 	// Initialize i18n manager
 	language := "en_au" // Default language
@@ -55,42 +99,69 @@ func NewApp() (*App, error) {
 		// Fallback to default language if i18n fails
 		i18nMgr, _ = i18n.NewManager("en_au")
 	}
+	profile.Mark("i18n")
+	if aiErr != nil {
+		// Typos in config.yaml are reported rather than ignored; sqlterm runs without AI
+		fmt.Printf(i18nMgr.Get("ai_config_invalid_warning"), aiErr)
+	}
+
+	// Ensure sessions directory exists for history file
+	if sessionsErr != nil {
+		return nil, fmt.Errorf(i18nMgr.Get("failed_to_create_sessions_dir"), sessionsErr)
+	}
 
 	// Initialize session manager with i18n manager
-	sessionMgr := session.NewManager(configMgr.GetConfigDir(), i18nMgr)
+	sessionMgr := session.NewManager(configMgr.GetDataDir(), i18nMgr)
+
+	// A policy file that does not load stops sqlterm rather than being ignored
+	policy, err := loadPolicy(aiManager, configMgr.GetConfigDir())
+	if err != nil {
+		return nil, fmt.Errorf(i18nMgr.Get("failed_to_load_policy"), err)
+	}
+
+	// Statements are not run unaudited when an audit log is required
+	auditLog, err := openAuditLog(aiManager, configMgr.GetConfigDir())
+	if err != nil {
+		return nil, fmt.Errorf(i18nMgr.Get("failed_to_open_audit_log"), err)
+	}
 
 	app := &App{
 		configMgr:  configMgr,
 		sessionMgr: sessionMgr,
 		aiManager:  aiManager,
 		i18nMgr:    i18nMgr,
-	}
-
-	// Ensure sessions directory exists for history file
-	sessionsDir := filepath.Join(configMgr.GetConfigDir(), "sessions")
-	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
-		return nil, fmt.Errorf(i18nMgr.Get("failed_to_create_sessions_dir"), err)
+		profile:    profile,
+		auditLog:   auditLog,
+		policy:     policy,
 	}
 
 	// Set up dynamic autocomplete
 	completer := NewAutoCompleter(app)
 
-	rl, err := readline.NewEx(&readline.Config{
+	rl, err := app.newReadline(&readline.Config{
 		Prompt:       "sqlterm > ",
 		AutoComplete: completer,
-		HistoryFile:  filepath.Join(configMgr.GetConfigDir(), "sessions", "global_history.txt"),
+		HistoryFile:  sessionMgr.HistoryFile(""),
 	})
 	if err != nil {
 		return nil, fmt.Errorf(i18nMgr.Get("failed_to_create_readline"), err)
 	}
+	profile.Mark("readline")
 
 	app.rl = rl
+	app.updatePrompt()
+	// From here the line editor owns the terminal, so passphrases are read through it
+	config.PassphrasePrompt = app.readSecret
 	return app, nil
 }
 
 func (a *App) SetConnection(conn core.Connection, config *core.ConnectionConfig) {
+	a.finishSessionStats()
 	a.connection = conn
 	a.config = config
+	a.started = time.Now()
+	a.stats = session.NewSessionStats(config.Name, auditUser(), a.started)
+	a.transcript = nil
 	a.updatePrompt()
 
 	// Ensure session directory and configuration exist
@@ -103,23 +174,53 @@ func (a *App) SetConnection(conn core.Connection, config *core.ConnectionConfig)
 		fmt.Printf(a.i18nMgr.Get("session_history_warning"), err)
 	}
 
+	// Record executed statements in the connection's session database
+	if a.queryLog != nil {
+		a.queryLog.Close()
+		a.queryLog = nil
+	}
+	if queryLog, err := a.sessionMgr.OpenQueryLog(config.Name); err != nil {
+		fmt.Printf(a.i18nMgr.Get("query_log_warning"), err)
+	} else {
+		a.queryLog = queryLog
+	}
+
+	if err := a.loadSensitiveColumns(); err != nil {
+		fmt.Printf(a.i18nMgr.Get("sensitive_warning"), a.sensitivePath(), err)
+	}
+
 	// Initialize vector store for AI context if AI manager is available
 	if a.aiManager != nil {
-		fmt.Printf(a.i18nMgr.Get("initializing_vector_db"), config.Name)
-		if err := a.aiManager.InitializeVectorStore(config.Name, conn); err != nil {
+		// The version helps the model pick syntax; connections that cannot report one still work
+		version, _ := core.ServerVersion(conn, config.DatabaseType)
+		a.aiManager.SetDialect(core.DialectName(config.DatabaseType, core.ServerFlavor(config.DatabaseType, version)), version)
+		if _, err := a.loadGlossary(); err != nil {
+			fmt.Printf(a.i18nMgr.Get("glossary_warning"), a.glossaryPath(), err)
+		}
+		// The vector store opens, and indexes changed tables, on first use
+		if err := a.aiManager.InitializeVectorStore(config.Name, conn, config.DatabaseType); err != nil {
 			fmt.Printf(a.i18nMgr.Get("vector_db_init_warning"), err)
-		} else {
-			fmt.Printf(a.i18nMgr.Get("vector_db_ready"), config.Name)
 		}
 	}
+	a.profile.Mark("session")
 }
 
 func (a *App) updatePrompt() {
+	// Profiles other than the default are named, so a client's database is not
+	// mistaken for another's
+	name := "sqlterm"
+	if a.configMgr != nil && a.configMgr.GetProfile() != config.DefaultProfile {
+		name += "@" + a.configMgr.GetProfile()
+	}
+
 	var prompt string
 	if a.config != nil {
-		prompt = fmt.Sprintf("sqlterm (%s) > ", a.config.Database)
+		prompt = fmt.Sprintf("%s (%s) > ", name, a.config.Database)
+		if env := a.config.Environment(); env != "" {
+			prompt = fmt.Sprintf("%s %s[%s]\033[0m (%s) > ", name, environmentColor(env), env, a.config.Database)
+		}
 	} else {
-		prompt = "sqlterm > "
+		prompt = name + " > "
 	}
 
 	if a.rl != nil {
@@ -130,19 +231,14 @@ func (a *App) updatePrompt() {
 // switchToSessionHistory changes the readline history file to be session-specific
 func (a *App) switchToSessionHistory(connectionName string) error {
 	// Create session-specific history file path
-	sessionDir := filepath.Join(a.configMgr.GetConfigDir(), "sessions", connectionName)
-	historyFile := filepath.Join(sessionDir, "history.txt")
+	sessionDir := a.sessionMgr.GetSessionDir(connectionName)
+	historyFile := a.sessionMgr.HistoryFile(connectionName)
 
 	// Ensure the session directory exists
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_session_dir"), err)
 	}
 
-	// Migrate legacy global history if this is the first time using session histories
-	if err := a.migrateLegacyHistory(historyFile); err != nil {
-		fmt.Printf(a.i18nMgr.Get("failed_migrate_legacy_history_warning"), err)
-	}
-
 	// Update the readline config with the new history file
 	// Note: The chzyer/readline library doesn't support changing history file after creation,
 	// so we need to manage this manually by closing and recreating the instance
@@ -160,10 +256,10 @@ func (a *App) switchToSessionHistory(connectionName string) error {
 		HistoryFile:  historyFile,
 	}
 
-	rl, err := readline.NewEx(newConfig)
+	rl, err := a.newReadline(newConfig)
 	if err != nil {
 		// Fallback: recreate with old config if new one fails
-		a.rl, _ = readline.NewEx(oldConfig)
+		a.rl, _ = a.newReadline(oldConfig)
 		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_readline_session_history"), err)
 	}
 
@@ -171,36 +267,9 @@ func (a *App) switchToSessionHistory(connectionName string) error {
 	return nil
 }
 
-// migrateLegacyHistory copies the old global history.txt to session-specific history if it exists
-func (a *App) migrateLegacyHistory(sessionHistoryFile string) error {
-	legacyHistoryFile := filepath.Join(a.configMgr.GetConfigDir(), "sessions", "history.txt")
-
-	// Check if legacy history file exists
-	if _, err := os.Stat(legacyHistoryFile); os.IsNotExist(err) {
-		return nil // No legacy history to migrate
-	}
-
-	// Check if session history file already exists
-	if _, err := os.Stat(sessionHistoryFile); err == nil {
-		return nil // Session history already exists, don't overwrite
-	}
-
-	// Copy legacy history to session history
-	input, err := os.ReadFile(legacyHistoryFile)
-	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_legacy_history"), err)
-	}
-
-	if err := os.WriteFile(sessionHistoryFile, input, 0644); err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_write_session_history"), err)
-	}
-
-	fmt.Printf("📦 Migrated command history to session folder\n")
-	return nil
-}
-
 // ClearConnection clears the current database connection and switches back to global history
 func (a *App) ClearConnection() error {
+	a.finishSessionStats()
 	a.connection = nil
 	a.config = nil
 	a.updatePrompt()
@@ -208,6 +277,8 @@ func (a *App) ClearConnection() error {
 	// Close vector store if active
 	if a.aiManager != nil {
 		a.aiManager.CloseVectorStore()
+		a.aiManager.SetDialect("", "")
+		a.aiManager.SetGlossary(nil)
 	}
 
 	// Switch back to global history
@@ -216,7 +287,7 @@ func (a *App) ClearConnection() error {
 
 // switchToGlobalHistory switches back to the global history file
 func (a *App) switchToGlobalHistory() error {
-	globalHistoryFile := filepath.Join(a.configMgr.GetConfigDir(), "sessions", "global_history.txt")
+	globalHistoryFile := a.sessionMgr.HistoryFile("")
 
 	// Update the readline config with the global history file
 	if a.rl == nil {
@@ -233,10 +304,10 @@ func (a *App) switchToGlobalHistory() error {
 		HistoryFile:  globalHistoryFile,
 	}
 
-	rl, err := readline.NewEx(newConfig)
+	rl, err := a.newReadline(newConfig)
 	if err != nil {
 		// Fallback: recreate with old config if new one fails
-		a.rl, _ = readline.NewEx(oldConfig)
+		a.rl, _ = a.newReadline(oldConfig)
 		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_readline_global_history"), err)
 	}
 
@@ -244,20 +315,30 @@ func (a *App) switchToGlobalHistory() error {
 	return nil
 }
 
-func (a *App) Run() error {
+func (a *App) Run() (err error) {
 	defer a.rl.Close()
 	defer func() {
 		if a.aiManager != nil {
 			a.aiManager.CloseVectorStore()
 		}
+		a.jobs.CancelAll()
+		if a.connection != nil {
+			a.dropScratchTables()
+		}
+		a.finishSessionStats()
 	}()
+	defer a.recoverPanic(&err)
 
 	fmt.Println(a.i18nMgr.Get("sqlterm_conversation_mode"))
 	fmt.Println(a.i18nMgr.Get("prompt_welcome"))
 	fmt.Println()
+	a.profile.Report(os.Stderr, a.i18nMgr)
+	a.profile = nil
+	a.offerRecovery()
+	defer enableBracketedPaste()()
 
 	for {
-		line, err := a.rl.Readline()
+		line, pasted, err := a.readLine()
 		if err == readline.ErrInterrupt {
 			continue
 		} else if err == io.EOF {
@@ -269,62 +350,53 @@ func (a *App) Run() error {
 			continue
 		}
 
-		if err := a.processLine(line); err != nil {
+		a.draft = line
+		process := a.processLine
+		if pasted {
+			process = a.processPaste
+		}
+		if err := process(line); err != nil {
 			fmt.Printf(a.i18nMgr.Get("generic_error"), err)
+			a.printErrorHint(err)
 		}
+		a.draft = ""
 	}
 
 	return nil
 }
 
 func (a *App) processLine(line string) error {
-	if strings.HasPrefix(line, "/") {
+	switch {
+	case strings.HasPrefix(line, "/"):
 		return a.processCommand(line)
-	} else if strings.HasPrefix(line, "@") {
+	case strings.HasPrefix(line, "@"):
 		return a.processQueryFile(line)
-	} else {
+	case strings.HasPrefix(line, "?"):
+		// Always a question for the AI, even when it starts with an SQL keyword
+		return a.processAIMessage(strings.TrimSpace(line[1:]))
+	case a.replConfig().BareSQL && core.IsSQLStatement(line):
+		return a.runQueryLine(line)
+	default:
 		// Handle as AI chat
 		return a.processAIChat(line)
 	}
 }
 
 func (a *App) processCommand(line string) error {
-	parts := strings.Fields(line)
+	parts := strings.Fields(a.expandAlias(line))
 	if len(parts) == 0 {
 		return nil
 	}
 
-	command := parts[0]
-	args := parts[1:]
-
-	switch command {
-	case "/help":
-		return a.handleHelp(args)
-	case "/quit", "/exit":
-		os.Exit(0)
-	case "/connect":
-		return a.handleConnect(args)
-	case "/list-connections":
-		return a.handleListConnections()
-	case "/tables":
-		return a.handleListTables()
-	case "/describe":
-		return a.handleDescribeTable(args)
-	case "/status":
-		a.handleStatus()
-	case "/exec":
-		return a.handleExecQuery(args)
-	case "/config":
-		return a.handleConfig(args)
-	case "/last-ai-call":
-		return a.handleShowPrompts(args)
-	case "/clear-conversation":
-		return a.handleClearConversation()
-	default:
-		fmt.Printf(a.i18nMgr.Get("unknown_command"), command)
+	cmd, ok := lookupCommand(parts[0])
+	if !ok {
+		fmt.Printf(a.i18nMgr.Get("unknown_command"), parts[0])
+		return nil
 	}
-
-	return nil
+	if err := a.checkCommandPolicy(cmd); err != nil {
+		return err
+	}
+	return cmd.run(a, parts[1:])
 }
 
 func (a *App) processQueryFile(line string) error {
@@ -339,10 +411,33 @@ func (a *App) processQueryFile(line string) error {
 	}
 
 	filename := parts[0][1:] // Remove @ prefix
+	queryRange, vars, err := a.parseFileArgs(parts[1:])
+	if err != nil {
+		return err
+	}
+	return a.executeFile(filename, queryRange, vars)
+}
+
+// parseFileArgs reads what follows @file: a query number or range such as 2-5, and
+// --var name=value for the file's template values
+func (a *App) parseFileArgs(args []string) ([]int, map[string]string, error) {
 	var queryRange []int
+	vars := map[string]string{}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--var" {
+			if i+1 == len(args) {
+				return nil, nil, errors.New(a.i18nMgr.Get("usage_template_var"))
+			}
+			name, value, ok := strings.Cut(args[i+1], "=")
+			if !ok || !core.ValidVariableName(name) {
+				return nil, nil, errors.New(a.i18nMgr.Get("usage_template_var"))
+			}
+			vars[name] = value
+			i++
+			continue
+		}
 
-	if len(parts) > 1 {
-		rangeStr := parts[1]
+		rangeStr := args[i]
 		if strings.Contains(rangeStr, "-") {
 			rangeParts := strings.Split(rangeStr, "-")
 			if len(rangeParts) == 2 {
@@ -358,8 +453,34 @@ func (a *App) processQueryFile(line string) error {
 			}
 		}
 	}
+	return queryRange, vars, nil
+}
+
+// loadQueryFile reads the statements of an @ file, rendering it first when it is a
+// template
+func (a *App) loadQueryFile(filename string, vars map[string]string) ([]string, error) {
+	// Try to find the file in current directory or the query library
+	path, ok := a.resolveQueryFile(filename)
+	if !ok {
+		return nil, fmt.Errorf(a.i18nMgr.Get("file_not_found"), filename)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf(a.i18nMgr.Get("failed_to_open_file"), err)
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf(a.i18nMgr.Get("failed_to_read_file"), err)
+	}
 
-	return a.executeFile(filename, queryRange)
+	text, err := a.renderQueryFile(string(content), vars)
+	if err != nil {
+		return nil, err
+	}
+	return core.SplitStatements(text), nil
 }
 
 func (a *App) processQuery(query string, resultWriter io.Writer) error {
@@ -368,9 +489,15 @@ func (a *App) processQuery(query string, resultWriter io.Writer) error {
 		return nil
 	}
 
-	result, err := a.connection.Execute(query)
+	// Only results shown here are capped on ClickHouse; exports and copies get every row
+	a.displaying = true
+	defer func() { a.displaying = false }()
+	result, err := a.executeQuery(query)
+	if err != nil && a.shouldSelfCorrect(query) {
+		query, result, err = a.selfCorrect(query, err)
+	}
 	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("query_execution_failed"), err)
+		return err
 	}
 
 	// Save as markdown and display with glamour
@@ -382,6 +509,9 @@ func (a *App) processQuery(query string, resultWriter io.Writer) error {
 			if err != nil {
 				fmt.Printf(a.i18nMgr.Get("failed_save_markdown_warning"), err)
 			}
+			if a.config.DatabaseType == core.ClickHouse && result.RowCount() >= core.ClickHouseMaxResultRows {
+				fmt.Println(a.i18nMgr.GetWithArgs("clickhouse_rows_capped", core.ClickHouseMaxResultRows))
+			}
 		}
 	}
 
@@ -393,9 +523,7 @@ func (a *App) prepareQueryResultMarkdown() (string, *os.File, error) {
 		return "", nil, fmt.Errorf(a.i18nMgr.Get("failed_to_create_session_dir"), err)
 	}
 	// Generate filename with timestamp
-	configDir := a.configMgr.GetConfigDir()
-	// Create sessions directory structure
-	resultsDir := filepath.Join(configDir, "sessions", a.config.Name, "results")
+	resultsDir := filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), "results")
 	if err := os.MkdirAll(resultsDir, 0755); err != nil {
 		return "", nil, fmt.Errorf("%s: %w", a.i18nMgr.Get("failed_to_create_results_dir"), err)
 	}
@@ -413,6 +541,24 @@ func (a *App) prepareQueryResultMarkdown() (string, *os.File, error) {
 	return filename, writer, err
 }
 
+// showReport saves a markdown report to the results directory as <prefix>_<timestamp>.md,
+// displays it and prints where it was saved
+func (a *App) showReport(prefix, markdown string) error {
+	resultsDir := filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), "results")
+	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+		return fmt.Errorf("%s: %w", a.i18nMgr.Get("failed_to_create_results_dir"), err)
+	}
+	mdPath := filepath.Join(resultsDir, fmt.Sprintf("%s_%s.md", prefix, time.Now().Format("20060102_150405")))
+	if err := os.WriteFile(mdPath, []byte(markdown), 0644); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_save_report"), err)
+	}
+	if err := a.sessionMgr.ViewMarkdown(mdPath); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	}
+	fmt.Printf("📍 %s: %s\n", a.i18nMgr.Get("file_location"), mdPath)
+	return nil
+}
+
 func (a *App) preparePromptHistoryMarkdown() (string, *os.File, error) {
 	if a.config == nil {
 		return "", nil, errors.New(a.i18nMgr.Get("no_connection_for_session_dir"))
@@ -423,9 +569,7 @@ func (a *App) preparePromptHistoryMarkdown() (string, *os.File, error) {
 	}
 
 	// Generate filename with timestamp
-	configDir := a.configMgr.GetConfigDir()
-	// Create sessions directory structure
-	resultsDir := filepath.Join(configDir, "sessions", a.config.Name, "results")
+	resultsDir := filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), "results")
 	if err := os.MkdirAll(resultsDir, 0755); err != nil {
 		return "", nil, fmt.Errorf("%s: %w", a.i18nMgr.Get("failed_to_create_results_dir"), err)
 	}
@@ -447,34 +591,16 @@ func (a *App) preparePromptHistoryMarkdown() (string, *os.File, error) {
 	return filename, writer, err
 }
 
-func (a *App) executeFile(filename string, queryRange []int) error {
+func (a *App) executeFile(filename string, queryRange []int, vars map[string]string) error {
 	if a.connection == nil {
 		fmt.Println(a.i18nMgr.Get("no_database_connection"))
 		return nil
 	}
 
-	// Try to find the file in current directory or queries directory
-	var filepath string
-	if _, err := os.Stat(filename); err == nil {
-		filepath = filename
-	} else if _, err := os.Stat("queries/" + filename); err == nil {
-		filepath = "queries/" + filename
-	} else {
-		return fmt.Errorf(a.i18nMgr.Get("file_not_found"), filename)
-	}
-
-	file, err := os.Open(filepath)
+	queries, err := a.loadQueryFile(filename, vars)
 	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_open_file"), err)
+		return err
 	}
-	defer file.Close()
-
-	content, err := io.ReadAll(file)
-	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_file"), err)
-	}
-
-	queries := a.parseQueries(string(content))
 	fmt.Printf(a.i18nMgr.Get("executing_sql_file"), filename)
 	fmt.Printf(a.i18nMgr.Get("found_queries_in_file"), len(queries))
 
@@ -497,7 +623,7 @@ func (a *App) executeFile(filename string, queryRange []int) error {
 
 		err = a.processQuery(query, writer)
 		if err != nil {
-			fmt.Printf(a.i18nMgr.Get("query_failed"), err)
+			a.reportQueryError(query, err, nil)
 		}
 	}
 	writer.Close()
@@ -510,38 +636,9 @@ func (a *App) executeFile(filename string, queryRange []int) error {
 	return nil
 }
 
-func (a *App) parseQueries(content string) []string {
-	var queries []string
-	var currentQuery strings.Builder
-
-	scanner := bufio.NewScanner(strings.NewReader(content))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "--") {
-			continue
-		}
-
-		currentQuery.WriteString(line)
-		currentQuery.WriteString(" ")
-
-		if strings.HasSuffix(line, ";") {
-			queries = append(queries, strings.TrimSuffix(currentQuery.String(), ";"))
-			currentQuery.Reset()
-		}
-	}
-
-	if currentQuery.Len() > 0 {
-		queries = append(queries, currentQuery.String())
-	}
-
-	return queries
-}
-
 func (a *App) truncateQuery(query string) string {
-	if len(query) > 50 {
-		return query[:47] + "..."
-	}
-	return query
+	// Statements from files keep their line breaks; show them on one line
+	return core.TruncateWidth(strings.Join(strings.Fields(query), " "), 50)
 }
 
 func (a *App) handleHelp(args []string) error {
@@ -552,28 +649,18 @@ func (a *App) handleHelp(args []string) error {
 	}
 
 	// Handle specific command help
-	command := args[0]
-	subArgs := args[1:]
-
-	switch command {
-	case "config":
-		return a.printConfigHelp(subArgs)
-	case "connect":
-		return a.printConnectHelp()
-	case "exec":
-		return a.printExecHelp()
-	case "tables":
-		return a.printTablesHelp()
-	case "describe":
-		return a.printDescribeHelp()
-	case "status":
-		return a.printStatusHelp()
-	case "prompts":
-		return a.printPromptsHelp()
-	default:
-		fmt.Printf(a.i18nMgr.Get("unknown_help_command"), command)
+	cmd, ok := lookupCommand(args[0])
+	if !ok {
+		fmt.Printf(a.i18nMgr.Get("unknown_help_command"), args[0])
 		return nil
 	}
+	if cmd.help != nil {
+		return cmd.help(a, args[1:])
+	}
+	if !a.printCommandSummary(cmd) {
+		fmt.Printf(a.i18nMgr.Get("unknown_help_command"), args[0])
+	}
+	return nil
 }
 
 func (a *App) printHelp() {
@@ -586,23 +673,31 @@ func (a *App) handleConnect(args []string) error {
 	}
 
 	name := args[0]
-	config, err := a.configMgr.LoadConnection(name)
-	if err != nil {
-		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", name, err))
+	config, ok := fileConnection(name)
+	if !ok {
+		var err error
+		config, err = a.configMgr.LoadConnection(name)
+		if err != nil {
+			return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", name, err))
+		}
 	}
 
 	fmt.Printf(a.i18nMgr.Get("connecting_to"), config.Name)
-	conn, err := core.NewConnection(config)
+	resolved, err := a.resolvePassword(config)
+	if err != nil {
+		return err
+	}
+	conn, err := core.NewConnection(resolved)
 	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_connect"), err)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_connect"), core.ClassifyError(err, resolved.DatabaseType))
 	}
 
 	if err := conn.Ping(); err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("connection_test_failed"), err)
+		return fmt.Errorf(a.i18nMgr.Get("connection_test_failed"), core.ClassifyError(err, resolved.DatabaseType))
 	}
 
 	if a.connection != nil {
-		a.connection.Close()
+		a.closeConnection()
 	}
 
 	a.SetConnection(conn, config)
@@ -611,6 +706,21 @@ func (a *App) handleConnect(args []string) error {
 	return nil
 }
 
+// fileConnection opens a database file without saving a connection, for targets such as
+// duckdb:./analytics.duckdb or sqlite:app.db. The connection is named after the file.
+func fileConnection(target string) (*core.ConnectionConfig, bool) {
+	scheme, path, ok := strings.Cut(target, ":")
+	if !ok || path == "" {
+		return nil, false
+	}
+	dbType, err := core.ParseDatabaseType(scheme)
+	if err != nil || !dbType.IsFileBased() {
+		return nil, false
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return &core.ConnectionConfig{Name: name, DatabaseType: dbType, Database: path}, true
+}
+
 func (a *App) interactiveConnect() error {
 	fmt.Println(a.i18nMgr.Get("interactive_connection_setup"))
 
@@ -624,6 +734,8 @@ func (a *App) interactiveConnect() error {
 	fmt.Println(a.i18nMgr.Get("mysql_option"))
 	fmt.Println(a.i18nMgr.Get("postgresql_option"))
 	fmt.Println(a.i18nMgr.Get("sqlite_option"))
+	fmt.Println(a.i18nMgr.Get("duckdb_option"))
+	fmt.Println(a.i18nMgr.Get("clickhouse_option"))
 	fmt.Print(a.i18nMgr.Get("enter_choice"))
 
 	choice, _ := reader.ReadString('\n')
@@ -637,6 +749,10 @@ func (a *App) interactiveConnect() error {
 		dbType = core.PostgreSQL
 	case "3":
 		dbType = core.SQLite
+	case "4":
+		dbType = core.DuckDB
+	case "5":
+		dbType = core.ClickHouse
 	default:
 		return fmt.Errorf(a.i18nMgr.Get("invalid_choice"), choice)
 	}
@@ -646,7 +762,7 @@ func (a *App) interactiveConnect() error {
 		DatabaseType: dbType,
 	}
 
-	if dbType != core.SQLite {
+	if !dbType.IsFileBased() {
 		fmt.Print(a.i18nMgr.Get("enter_host"))
 		host, _ := reader.ReadString('\n')
 		host = strings.TrimSpace(host)
@@ -672,28 +788,46 @@ func (a *App) interactiveConnect() error {
 		username, _ := reader.ReadString('\n')
 		config.Username = strings.TrimSpace(username)
 
-		fmt.Print(a.i18nMgr.Get("enter_password"))
-		password, _ := reader.ReadString('\n')
-		config.Password = strings.TrimSpace(password)
+		if dbType == core.PostgreSQL {
+			if err := a.promptPostgresAuth(config); err != nil {
+				return err
+			}
+		}
+		if config.UsesPassword() {
+			password, err := a.readSecret(a.i18nMgr.Get("enter_password"))
+			if err != nil {
+				return err
+			}
+			config.Password = password
+			config.AskPassword = password == ""
+		}
 	}
 
-	fmt.Print(a.i18nMgr.Get("enter_database_name"))
+	if dbType == core.DuckDB {
+		fmt.Print(a.i18nMgr.Get("enter_duckdb_file"))
+	} else {
+		fmt.Print(a.i18nMgr.Get("enter_database_name"))
+	}
 	database, _ := reader.ReadString('\n')
 	config.Database = strings.TrimSpace(database)
 
 	// Test connection
 	fmt.Printf(a.i18nMgr.Get("testing_connection"), config.Name)
-	conn, err := core.NewConnection(config)
+	resolved, err := a.resolvePassword(config)
+	if err != nil {
+		return err
+	}
+	conn, err := core.NewConnection(resolved)
 	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_connect"), err)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_connect"), core.ClassifyError(err, resolved.DatabaseType))
 	}
 
 	if err := conn.Ping(); err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("connection_test_failed"), err)
+		return fmt.Errorf(a.i18nMgr.Get("connection_test_failed"), core.ClassifyError(err, resolved.DatabaseType))
 	}
 
 	if a.connection != nil {
-		a.connection.Close()
+		a.closeConnection()
 	}
 
 	a.SetConnection(conn, config)
@@ -709,12 +843,22 @@ func (a *App) interactiveConnect() error {
 	return nil
 }
 
-func (a *App) handleListConnections() error {
+func (a *App) handleListConnections(args []string) error {
 	connections, err := a.configMgr.ListConnections()
 	if err != nil {
 		return fmt.Errorf(a.i18nMgr.Get("failed_to_list_connections"), err)
 	}
 
+	if filter := parseTagFilter(args); filter != "" {
+		var matched []*core.ConnectionConfig
+		for _, conn := range connections {
+			if conn.HasTag(filter) {
+				matched = append(matched, conn)
+			}
+		}
+		connections = matched
+	}
+
 	if len(connections) == 0 {
 		fmt.Println(a.i18nMgr.Get("no_saved_connections_found"))
 		return nil
@@ -722,7 +866,7 @@ func (a *App) handleListConnections() error {
 
 	fmt.Println(a.i18nMgr.Get("saved_connections"))
 	for i, conn := range connections {
-		fmt.Printf("  %d. %s (%s) - %s://%s:%d/%s\n",
+		fmt.Printf("  %d. %s (%s) - %s://%s:%d/%s",
 			i+1,
 			conn.Name,
 			conn.DatabaseType,
@@ -730,6 +874,13 @@ func (a *App) handleListConnections() error {
 			conn.Host,
 			conn.Port,
 			conn.Database)
+		if conn.ReadOnly {
+			fmt.Printf(" %s", a.i18nMgr.Get("read_only_marker"))
+		}
+		if len(conn.Tags) > 0 {
+			fmt.Printf(" [%s]", conn.FormatTags())
+		}
+		fmt.Println()
 	}
 
 	return nil
@@ -783,6 +934,47 @@ func (a *App) handleDescribeTable(args []string) error {
 	return a.displayMarkdown(markdown)
 }
 
+func (a *App) handleJSONStructure(args []string) error {
+	if len(args) == 0 || !strings.Contains(args[0], ".") {
+		fmt.Println(a.i18nMgr.Get("usage_json_structure"))
+		return nil
+	}
+
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	dot := strings.LastIndex(args[0], ".")
+	tableName, columnName := args[0][:dot], args[0][dot+1:]
+
+	fields, sampled, err := core.SampleJSONStructure(a.connection, tableName, columnName, 50)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_sample_json"), err)
+	}
+
+	if sampled == 0 {
+		fmt.Printf(a.i18nMgr.Get("json_no_values_found"), tableName, columnName)
+		return nil
+	}
+
+	// Make the structure available to AI prompts for this session
+	if a.aiManager != nil {
+		a.aiManager.SetJSONStructure(tableName, columnName, fields)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🧩 %s: %s.%s\n\n", a.i18nMgr.Get("json_structure_header"), tableName, columnName))
+	sb.WriteString(fmt.Sprintf("%s\n\n", a.i18nMgr.GetWithArgs("json_sampled_rows", sampled)))
+	sb.WriteString(a.i18nMgr.Get("json_structure_table_header"))
+	sb.WriteString(a.i18nMgr.Get("json_structure_table_separator"))
+	for _, field := range fields {
+		sb.WriteString(fmt.Sprintf("| `%s` | %s | %d/%d |\n", field.Path, strings.Join(field.Types, ", "), field.Count, sampled))
+	}
+
+	return a.displayMarkdown(sb.String())
+}
+
 func (a *App) generateTableMarkdown(tableInfo *core.TableInfo) string {
 	var sb strings.Builder
 
@@ -868,40 +1060,72 @@ func (a *App) handleStatus() {
 		return
 	}
 
-	fmt.Printf(a.i18nMgr.Get("status_connected"), a.config.Name)
-	fmt.Printf(a.i18nMgr.Get("database_info"), a.config.Database)
-	fmt.Printf(a.i18nMgr.Get("type_info"), a.config.DatabaseType)
-	if a.config.DatabaseType != core.SQLite {
-		fmt.Printf(a.i18nMgr.Get("host_info"), a.config.Host, a.config.Port)
-		fmt.Printf(a.i18nMgr.Get("username_info"), a.config.Username)
+	fmt.Println(a.i18nMgr.GetWithArgs("status_connected", a.config.Name))
+	fmt.Println(a.i18nMgr.GetWithArgs("database_info", a.config.Database))
+	fmt.Println(a.i18nMgr.GetWithArgs("type_info", a.config.DatabaseType))
+	if !a.config.DatabaseType.IsFileBased() {
+		fmt.Println(a.i18nMgr.GetWithArgs("host_info", a.config.Host, a.config.Port))
+		fmt.Println(a.i18nMgr.GetWithArgs("username_info", a.config.Username))
+	}
+	a.printSessionStats()
+	if stats, ok := core.StatementStats(a.connection); ok {
+		fmt.Println(a.i18nMgr.GetWithArgs("statement_cache_info", stats.Size, stats.Capacity,
+			stats.HitRate()*100, stats.Hits, stats.Misses, stats.Evictions))
+	}
+	for _, replica := range core.ReplicaLag(a.connection) {
+		if replica.Err != nil {
+			fmt.Println(a.i18nMgr.GetWithArgs("replica_lag_unknown", replica.Address, replica.Err))
+			continue
+		}
+		fmt.Println(a.i18nMgr.GetWithArgs("replica_lag_info", replica.Address, replica.Lag.Round(time.Millisecond)))
 	}
 }
 
 func (a *App) handleExecQuery(args []string) error {
+	if len(args) > 0 && args[0] == "--primary" {
+		a.onPrimary = true
+		defer func() { a.onPrimary = false }()
+		args = args[1:]
+	}
 	if len(args) == 0 {
 		return a.handleMultilineExec()
 	}
 
+	return a.runQueryLine(strings.Join(args, " "))
+}
+
+// runQueryLine runs a one-line query typed after /exec or as bare SQL, exporting the
+// result when the line ends with > file
+func (a *App) runQueryLine(line string) error {
 	if a.connection == nil {
 		fmt.Println(a.i18nMgr.Get("no_database_connection"))
 		return nil
 	}
 
-	line := strings.Join(args, " ")
-
+	if query, ok := core.SplitBackground(line); ok {
+		return a.startJob(query)
+	}
 	// Check if it's a CSV export
 	if strings.Contains(line, " > ") {
 		return a.processQueryWithCSVExport(line)
 	}
+	if handled, err := a.previewLargeTable(line); handled {
+		return err
+	}
+	return a.showQuery(line)
+}
+
+// showQuery runs a query, saving its result as markdown in the session and displaying it
+func (a *App) showQuery(query string) error {
 	mdPath, writer, err := a.prepareQueryResultMarkdown()
 	if err != nil {
 		fmt.Println("Warning:", err.Error())
 		return nil
 	}
-	err = a.processQuery(line, writer)
+	err = a.processQuery(query, writer)
 	writer.Close()
 	if err != nil {
-		fmt.Println("Warning:", err.Error())
+		a.reportQueryError(query, err, a.runQueryLine)
 		return nil
 	}
 	if err := a.sessionMgr.ViewMarkdown(mdPath); err != nil {
@@ -925,6 +1149,7 @@ func (a *App) handleMultilineExec() error {
 	fmt.Println()
 
 	var queryLines []string
+	var pastedQuery string
 	lineNumber := 1
 
 	// Temporarily disable history for multi-line input
@@ -936,18 +1161,30 @@ func (a *App) handleMultilineExec() error {
 		prompt := fmt.Sprintf("  %2d│ ", lineNumber)
 		a.rl.SetPrompt(prompt)
 
-		line, err := a.rl.Readline()
+		line, pasted, err := a.readLine()
 		if err != nil {
 			// User pressed Ctrl+C or EOF
 			fmt.Println(a.i18nMgr.Get("multi_line_input_cancelled"))
 			a.updatePrompt() // Restore original prompt
 			return nil
 		}
+		if pasted {
+			// A pasted block completes the query; its line breaks are kept so comments
+			// end where they did
+			pastedQuery = strings.TrimSpace(strings.Join(append(queryLines, line), "\n"))
+			if !a.confirmPaste(pastedQuery) {
+				fmt.Println(a.i18nMgr.Get("paste_discarded"))
+				a.updatePrompt()
+				return nil
+			}
+			break
+		}
 
 		line = strings.TrimSpace(line)
 
 		if line != "" {
 			queryLines = append(queryLines, line)
+			a.draft = "/exec " + strings.Join(queryLines, " ")
 
 			// Check if this line ends with semicolon - if so, we're done
 			// Also handle cases like "; -- comment" or "; > file.csv"
@@ -956,8 +1193,8 @@ func (a *App) handleMultilineExec() error {
 				lastSemi := strings.LastIndex(line, ";")
 				afterSemi := strings.TrimSpace(line[lastSemi+1:])
 
-				// If there's nothing after the semicolon, or only CSV export syntax, we're done
-				if afterSemi == "" || strings.HasPrefix(afterSemi, ">") || strings.HasPrefix(afterSemi, "--") {
+				// If there's nothing after the semicolon, or only CSV export syntax or &, we're done
+				if afterSemi == "" || afterSemi == "&" || strings.HasPrefix(afterSemi, ">") || strings.HasPrefix(afterSemi, "--") {
 					break
 				}
 			}
@@ -968,20 +1205,26 @@ func (a *App) handleMultilineExec() error {
 	// Restore original prompt
 	a.updatePrompt()
 
-	if len(queryLines) == 0 {
+	if len(queryLines) == 0 && pastedQuery == "" {
 		fmt.Println(a.i18nMgr.Get("no_query_entered"))
 		return nil
 	}
 
 	// Join all lines into a single query
 	fullQuery := strings.Join(queryLines, " ")
+	if pastedQuery != "" {
+		fullQuery = pastedQuery
+	}
 
 	// Add the complete multi-line query as a single history entry
-	historyEntry := "/exec " + fullQuery
+	historyEntry := "/exec " + strings.ReplaceAll(fullQuery, "\n", " ")
 	if err := a.rl.SaveHistory(historyEntry); err != nil {
 		fmt.Printf(a.i18nMgr.Get("failed_save_command_history_warning"), err)
 	}
 
+	if query, ok := core.SplitBackground(fullQuery); ok {
+		return a.startJob(query)
+	}
 	fmt.Print(a.i18nMgr.Get("executing_query"))
 	fmt.Printf(a.i18nMgr.Get("query_truncated"), a.truncateQuery(fullQuery))
 
@@ -991,22 +1234,10 @@ func (a *App) handleMultilineExec() error {
 	}
 
 	// Regular execution
-	mdPath, writer, err := a.prepareQueryResultMarkdown()
-	if err != nil {
-		fmt.Println("Warning:", err.Error())
-		return nil
+	if handled, err := a.previewLargeTable(fullQuery); handled {
+		return err
 	}
-	err = a.processQuery(fullQuery, writer)
-	writer.Close()
-	if err != nil {
-		fmt.Println("Warning:", err.Error())
-		return nil
-	}
-	if err := a.sessionMgr.ViewMarkdown(mdPath); err != nil {
-		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
-	}
-	fmt.Printf("📍 %s: %s\n", a.i18nMgr.Get("file_location"), mdPath)
-	return nil
+	return a.showQuery(fullQuery)
 }
 
 func (a *App) processQueryWithCSVExport(line string) error {
@@ -1021,24 +1252,62 @@ func (a *App) processQueryWithCSVExport(line string) error {
 	}
 
 	query := strings.TrimSpace(parts[0])
-	filename := strings.TrimSpace(parts[1])
+	target, format, err := a.splitFormatFlag(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return err
+	}
+	filename, recipients, err := a.splitEmailFlag(target)
+	if err != nil {
+		return err
+	}
 
 	fmt.Printf(a.i18nMgr.Get("executing_query_streaming"), filename)
 
-	result, err := a.connection.Execute(query)
+	started := time.Now()
+	result, err := a.executeQuery(query)
 	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("query_execution_failed"), err)
+		a.reportQueryError(query, err, func(fixed string) error {
+			return a.processQueryWithCSVExport(fixed + " > " + parts[1])
+		})
+		return nil
 	}
 
-	rows, err := core.SaveQueryResultAsStreamingCSV(result, filename)
+	rows, err := a.exportResult(result, filename, format)
 	if err != nil {
-		return fmt.Errorf("failed to save CSV: %w", err)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_export_results"), err)
 	}
 
 	fmt.Printf(a.i18nMgr.Get("exported_rows_to_file"), rows, filename)
+	if len(recipients) > 0 {
+		return a.emailResults(recipients, query, rows, time.Since(started), []string{filename})
+	}
 	return nil
 }
 
+// exportResult writes a result to the target after " > ": a file, an S3 or GCS object,
+// a Google Sheets tab, or a stream such as tcp://host:port or kafka://broker/topic that gets one JSON object
+// per row. format is a flag such as --fixed-width choosing the format of a file.
+func (a *App) exportResult(result *core.QueryResult, target, format string) (rows int, err error) {
+	var size int64
+	switch {
+	case core.IsStreamTarget(target):
+		rows, err = core.StreamQueryResult(result, target)
+	case core.IsSheetsTarget(target):
+		rows, err = a.exportToSheet(result, target)
+	case core.IsObjectStoreTarget(target):
+		rows, err = core.ExportToObjectStore(result, target)
+	default:
+		rows, err = saveWithFormat(result, target, format)
+		if info, statErr := os.Stat(target); statErr == nil {
+			size = info.Size()
+		}
+	}
+	if err == nil && a.stats != nil {
+		a.stats.RecordExport(rows, size)
+	}
+	return rows, err
+}
+
 func (a *App) processFileCommandWithCSVExport(line string) error {
 	if a.connection == nil {
 		fmt.Println(a.i18nMgr.Get("no_database_connection"))
@@ -1051,7 +1320,14 @@ func (a *App) processFileCommandWithCSVExport(line string) error {
 	}
 
 	fileCmd := strings.TrimSpace(parts[0])
-	csvFilename := strings.TrimSpace(parts[1])
+	target, format, err := a.splitFormatFlag(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return err
+	}
+	csvFilename, recipients, err := a.splitEmailFlag(target)
+	if err != nil {
+		return err
+	}
 
 	// Parse the file command
 	cmdParts := strings.Fields(fileCmd)
@@ -1060,57 +1336,25 @@ func (a *App) processFileCommandWithCSVExport(line string) error {
 	}
 
 	filename := cmdParts[0][1:] // Remove @ prefix
-	var queryRange []int
-
-	if len(cmdParts) > 1 {
-		rangeStr := cmdParts[1]
-		if strings.Contains(rangeStr, "-") {
-			rangeParts := strings.Split(rangeStr, "-")
-			if len(rangeParts) == 2 {
-				start, err1 := strconv.Atoi(rangeParts[0])
-				end, err2 := strconv.Atoi(rangeParts[1])
-				if err1 == nil && err2 == nil {
-					queryRange = []int{start, end}
-				}
-			}
-		} else {
-			if num, err := strconv.Atoi(rangeStr); err == nil {
-				queryRange = []int{num, num}
-			}
-		}
+	queryRange, vars, err := a.parseFileArgs(cmdParts[1:])
+	if err != nil {
+		return err
 	}
-
-	return a.executeFileWithCSVExport(filename, queryRange, csvFilename)
+	return a.executeFileWithCSVExport(filename, queryRange, vars, csvFilename, recipients, format)
 }
 
-func (a *App) executeFileWithCSVExport(filename string, queryRange []int, csvFilename string) error {
+// executeFileWithCSVExport exports each query of an @ file to the target, emailing the
+// exported files to recipients afterwards when there are any
+func (a *App) executeFileWithCSVExport(filename string, queryRange []int, vars map[string]string, csvFilename string, recipients []string, format string) error {
 	if a.connection == nil {
 		fmt.Println(a.i18nMgr.Get("no_database_connection"))
 		return nil
 	}
 
-	// Try to find the file in current directory or queries directory
-	var filepath string
-	if _, err := os.Stat(filename); err == nil {
-		filepath = filename
-	} else if _, err := os.Stat("queries/" + filename); err == nil {
-		filepath = "queries/" + filename
-	} else {
-		return fmt.Errorf(a.i18nMgr.Get("file_not_found"), filename)
-	}
-
-	file, err := os.Open(filepath)
-	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_open_file"), err)
-	}
-	defer file.Close()
-
-	content, err := io.ReadAll(file)
+	queries, err := a.loadQueryFile(filename, vars)
 	if err != nil {
-		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_file"), err)
+		return err
 	}
-
-	queries := a.parseQueries(string(content))
 	fmt.Printf(a.i18nMgr.Get("executing_sql_file"), filename)
 	fmt.Printf(a.i18nMgr.Get("found_queries_in_file"), len(queries))
 
@@ -1129,9 +1373,10 @@ func (a *App) executeFileWithCSVExport(filename string, queryRange []int, csvFil
 	}
 
 	// Track exported files and statistics
-	var exportedFiles []string
+	var exportedFiles, exportedQueries []string
 	var totalRowsExported int
 	queryNumber := 0
+	started := time.Now()
 
 	for i := start - 1; i < end && i < len(queries); i++ {
 		query := strings.TrimSpace(queries[i])
@@ -1140,31 +1385,42 @@ func (a *App) executeFileWithCSVExport(filename string, queryRange []int, csvFil
 		}
 
 		fmt.Printf(a.i18nMgr.Get("query_number_truncated_query"), i+1, a.truncateQuery(query))
-		result, err := a.connection.Execute(query)
+		result, err := a.executeQuery(query)
 		if err != nil {
-			fmt.Printf(a.i18nMgr.Get("query_failed"), err)
+			a.reportQueryError(query, err, nil)
 			continue
 		}
 
 		// Export each query to a separate CSV file
 		queryNumber++
 		var outputPath string
-		if len(queriesWithResults) == 1 {
-			// Single query in file - use original filename
+		switch {
+		case len(queriesWithResults) == 1 || core.IsStreamTarget(csvFilename):
+			// Single query in file, or a stream every query's rows go to - use original target
 			outputPath = csvFilename
-		} else {
+		case core.IsSheetsTarget(csvFilename):
+			// Multiple queries - one numbered tab each
+			outputPath = fmt.Sprintf("%s-%d", csvFilename, queryNumber)
+		case core.IsObjectStoreTarget(csvFilename):
+			// Multiple queries - number the object name, keeping the bucket and prefix
+			prefix, name := path.Split(csvFilename)
+			outputPath = prefix + core.GenerateNumberedCSVPath(name, queryNumber)
+		default:
 			// Multiple queries - use numbered filenames
 			outputPath = core.GenerateNumberedCSVPath(csvFilename, queryNumber)
 		}
 
-		rows, err := core.SaveQueryResultAsStreamingCSV(result, outputPath)
+		rows, err := a.exportResult(result, outputPath, format)
 		if err != nil {
-			fmt.Printf("❌ Failed to save CSV: %v\n", err)
+			fmt.Printf("❌ %s\n", a.i18nMgr.GetWithArgs("failed_to_export_results", err))
 			continue
 		}
 		fmt.Printf(a.i18nMgr.Get("query_executed_rows"), rows)
 
-		exportedFiles = append(exportedFiles, outputPath)
+		if !slices.Contains(exportedFiles, outputPath) {
+			exportedFiles = append(exportedFiles, outputPath)
+		}
+		exportedQueries = append(exportedQueries, query)
 		totalRowsExported += rows
 		fmt.Printf(a.i18nMgr.Get("exported_rows_to_file"), rows, outputPath)
 	}
@@ -1180,6 +1436,9 @@ func (a *App) executeFileWithCSVExport(filename string, queryRange []int, csvFil
 			}
 		}
 		fmt.Printf(a.i18nMgr.Get("total_rows_exported"), totalRowsExported)
+		if len(recipients) > 0 {
+			return a.emailResults(recipients, strings.Join(exportedQueries, "\n\n"), totalRowsExported, time.Since(started), exportedFiles)
+		}
 	} else {
 		fmt.Println(a.i18nMgr.Get("no_results_to_export"))
 	}
@@ -1193,14 +1452,6 @@ func (a *App) processAIChat(message string) error {
 		return nil
 	}
 
-	// Get current conversation or show thinking message
-	conversation := a.aiManager.GetCurrentConversation()
-	if conversation == nil {
-		fmt.Print(a.i18nMgr.Get("ai_starting_new_conversation"))
-	} else {
-		fmt.Printf(a.i18nMgr.Get("ai_processing_conversation"), conversation.CurrentPhase.String())
-	}
-
 	// Get database tables for context
 	var tables []string
 	if a.connection != nil {
@@ -1211,13 +1462,33 @@ func (a *App) processAIChat(message string) error {
 		}
 	}
 
+	if !a.confirmAISend(message, tables) {
+		fmt.Println(a.i18nMgr.Get("ai_send_cancelled"))
+		return nil
+	}
+
+	// Get current conversation or show thinking message
+	conversation := a.aiManager.GetCurrentConversation()
+	if conversation == nil {
+		fmt.Print(a.i18nMgr.Get("ai_starting_new_conversation"))
+	} else {
+		fmt.Printf(a.i18nMgr.Get("ai_processing_conversation"), conversation.CurrentPhase.String())
+	}
+
 	// Create context with timeout for AI requests
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
 	// Use new conversational chat system
+	message = a.attachLineage(a.attachResult(message))
+	a.aiMu.Lock()
 	response, err := a.aiManager.ChatWithConversation(ctx, message, tables)
+	a.aiMu.Unlock()
 	if err != nil {
+		// Offer to retry in the background while the provider is unreachable
+		if ai.IsProviderUnavailable(err) && a.offerToQueue(message, tables) {
+			return nil
+		}
 		// Provide more helpful error messages for common issues
 		if strings.Contains(err.Error(), "timeout") || strings.Contains(err.Error(), "deadline exceeded") {
 			fmt.Print(a.i18nMgr.Get("ai_timeout_message"))
@@ -1230,16 +1501,7 @@ func (a *App) processAIChat(message string) error {
 		return fmt.Errorf(a.i18nMgr.Get("ai_chat_failed"), err)
 	}
 
-	// Format any SQL code blocks in the response
-	formattedResponse := core.FormatSQLInMarkdown(response)
-
-	// Display response using markdown renderer
-	renderer := core.NewMarkdownRenderer(a.i18nMgr)
-	if err := renderer.RenderAndDisplay(formattedResponse); err != nil {
-		// Fallback to plain text if markdown rendering fails
-		fmt.Println(a.i18nMgr.Get("ai_response_header"))
-		fmt.Println(formattedResponse)
-	}
+	a.displayAIResponse(response)
 
 	// Show conversation status and AI info
 	conversation = a.aiManager.GetCurrentConversation()
@@ -1259,21 +1521,47 @@ func (a *App) processAIChat(message string) error {
 		usageInfo := a.i18nMgr.Get("usage_data_unavailable")
 		if a.aiManager.GetUsageStore() != nil {
 			if summary, err := a.aiManager.GetUsageStore().GetUsageSummary(); err == nil {
-				if todayStats, ok := summary["today"]; ok {
-					if today, ok := todayStats.(map[string]interface{}); ok {
-						usageInfo = fmt.Sprintf(a.i18nMgr.Get("usage_today_summary"),
-							int(today["requests"].(int)), today["cost"].(float64))
-					}
-				}
+				usageInfo = a.i18nMgr.GetWithArgs("usage_today_summary", summary.Today.Requests, summary.Today.Cost)
 			}
 		}
 		aiInfo := fmt.Sprintf("🤖 %s | %s", aiConfig.FormatProviderInfo(), usageInfo)
+		if lastCall := a.formatLastAICall(); lastCall != "" {
+			aiInfo = fmt.Sprintf("🤖 %s | %s | %s", a.answeredBy(aiConfig), lastCall, usageInfo)
+		}
 		fmt.Printf("%s\n", aiInfo)
 	}
 
 	return nil
 }
 
+// displayAIResponse renders an AI answer as markdown with its SQL formatted
+func (a *App) displayAIResponse(response string) {
+	formattedResponse := core.FormatSQLInMarkdown(response)
+
+	// Display response using markdown renderer
+	renderer := core.NewMarkdownRenderer(a.i18nMgr)
+	if err := renderer.RenderAndDisplay(formattedResponse); err != nil {
+		// Fallback to plain text if markdown rendering fails
+		fmt.Println(a.i18nMgr.Get("ai_response_header"))
+		fmt.Println(formattedResponse)
+	}
+	a.warnDialectIssues(response)
+}
+
+// warnDialectIssues flags constructs in the response's SQL that the connected database
+// does not support
+func (a *App) warnDialectIssues(response string) {
+	if a.config == nil {
+		return
+	}
+	flavor := core.FlavorOf(a.connection)
+	for _, query := range ai.ExtractSQL(response) {
+		for _, issue := range core.ValidateDialect(query, a.config.DatabaseType, flavor) {
+			fmt.Printf(a.i18nMgr.Get("dialect_issue_warning"), issue.Construct, core.DialectName(a.config.DatabaseType, flavor), issue.Alternative)
+		}
+	}
+}
+
 func (a *App) handleConfig(args []string) error {
 	if len(args) == 0 {
 		return a.printConfigHelp([]string{})
@@ -1287,6 +1575,16 @@ func (a *App) handleConfig(args []string) error {
 		return a.handleConfigAI(args[1:])
 	case "language":
 		return a.handleConfigLanguage(args[1:])
+	case "display":
+		return a.handleConfigDisplay(args[1:])
+	case "repl":
+		return a.handleConfigREPL(args[1:])
+	case "integrations":
+		return a.handleConfigIntegrations(args[1:])
+	case "secrets":
+		return a.handleConfigSecrets(args[1:])
+	case "safety":
+		return a.handleConfigSafety(args[1:])
 	default:
 		fmt.Printf(a.i18nMgr.Get("unknown_config_section"), section)
 		a.printConfigHelp([]string{})
@@ -1350,6 +1648,19 @@ func (a *App) handleConfigStatus() error {
 	}
 	fmt.Println()
 
+	// Display configuration
+	fmt.Println("🖥️  Display:")
+	fmt.Printf("   Geometry bounding box: %t\n", a.displayConfig().GeometryBBox)
+	if timezone := a.displayConfig().Timezone; timezone != "" {
+		fmt.Printf("   Timezone: %s\n", timezone)
+	}
+	fmt.Println()
+
+	// Integrations
+	fmt.Println("🔌 Integrations:")
+	fmt.Printf("   Google Sheets: %t\n", a.integrationsConfig().Sheets.RefreshToken != "")
+	fmt.Println()
+
 	// Connection status
 	fmt.Println("🔗 Database Connection:")
 	if a.connection == nil {
@@ -1358,7 +1669,7 @@ func (a *App) handleConfigStatus() error {
 	} else {
 		fmt.Printf("   Status: ✅ Connected to %s\n", a.config.Name)
 		fmt.Printf("   Database: %s (%s)\n", a.config.Database, a.config.DatabaseType)
-		if a.config.DatabaseType != core.SQLite {
+		if !a.config.DatabaseType.IsFileBased() {
 			fmt.Printf("   Host: %s:%d\n", a.config.Host, a.config.Port)
 			fmt.Printf("   Username: %s\n", a.config.Username)
 		}
@@ -1385,9 +1696,21 @@ func (a *App) handleConfigAI(args []string) error {
 	case "base-url":
 		return a.handleAIConfigBaseURL(args[1:])
 	case "list-models":
-		return a.handleAIConfigListModels()
+		return a.handleAIConfigListModels(args[1:])
+	case "models":
+		return a.handleAIConfigModels(args[1:])
+	case "pull":
+		return a.handleAIConfigPull(args[1:])
 	case "openrouter":
 		return a.handleConfigAIOpenRouter(args[1:])
+	case "self-correct":
+		return a.handleAIConfigSelfCorrect(args[1:])
+	case "fallback":
+		return a.handleAIConfigFallback(args[1:])
+	case "budget":
+		return a.handleAIConfigBudget(args[1:])
+	case "confirm":
+		return a.handleAIConfigConfirm(args[1:])
 	default:
 		fmt.Printf(a.i18nMgr.Get("unknown_ai_subcommand"), subcmd)
 		a.printAIConfigHelp()
@@ -1407,6 +1730,11 @@ func (a *App) printAIConfigHelp() {
 /config ai base-url <provider> <url> Set base URL for local providers
 /config language <lang>        Set interface language (en_au, zh_cn)
 /config ai list-models         List available models for current provider
+/config ai models --installed  List models downloaded to Ollama
+/config ai pull <model>        Download a model to Ollama with progress
+/config ai self-correct on|off [attempts]  Let AI fix its queries that fail to run
+/config ai fallback <provider[:model]> ...  Providers to try in order when the current one fails
+/config ai budget <usd>|off    Daily spend on paid providers before falling back
 
 Interactive Setup:
 Run /config ai without arguments to start the setup wizard that will:
@@ -1438,21 +1766,14 @@ func (a *App) handleAIConfigStatus() error {
 	fmt.Printf("   Provider: %s\n", config.AI.Provider)
 	fmt.Printf("   Model: %s\n", config.AI.Model)
 
+	// Probe local providers so problems show here rather than mid-chat
+	a.printProviderHealth(config.AI.Model)
+
 	// Show usage statistics from usage store if available
 	if a.aiManager.GetUsageStore() != nil {
 		if summary, err := a.aiManager.GetUsageStore().GetUsageSummary(); err == nil {
-			if todayStats, ok := summary["today"]; ok {
-				if today, ok := todayStats.(map[string]interface{}); ok {
-					fmt.Printf(a.i18nMgr.Get("todays_usage_display"),
-						int(today["requests"].(int)), today["cost"].(float64))
-				}
-			}
-			if weekStats, ok := summary["last_7_days"]; ok {
-				if week, ok := weekStats.(map[string]interface{}); ok {
-					fmt.Printf(a.i18nMgr.Get("last_7_days_display"),
-						int(week["requests"].(int)), week["cost"].(float64))
-				}
-			}
+			fmt.Printf("   %s\n", a.i18nMgr.GetWithArgs("todays_usage_display", summary.Today.Requests, summary.Today.Cost))
+			fmt.Printf("   %s\n", a.i18nMgr.GetWithArgs("last_7_days_display", summary.Last7Days.Requests, summary.Last7Days.Cost))
 		}
 	} else {
 		fmt.Printf("   Usage: %s\n", a.i18nMgr.Get("usage_not_available_no_db"))
@@ -1473,6 +1794,10 @@ func (a *App) handleAIConfigStatus() error {
 		}
 	}
 
+	// Show failover chain and budget
+	a.printFallbacks()
+	a.printDailyBudget()
+
 	return nil
 }
 
@@ -1518,6 +1843,9 @@ func (a *App) handleAIConfigModel(args []string) error {
 	}
 
 	fmt.Printf("✅ Set AI model to %s\n", model)
+	if needed, limit, short := a.aiManager.ContextShortfall(); short {
+		fmt.Printf(a.i18nMgr.Get("ai_model_context_short"), model, limit, needed, ai.FormatTokenCount(needed))
+	}
 	a.updatePrompt()
 
 	return nil
@@ -1676,17 +2004,35 @@ func (a *App) printConfigHelp(args []string) error {
 		return a.printConfigLanguageHelp()
 	case "status":
 		return a.printConfigStatusHelp()
+	case "display":
+		return a.printConfigDisplayHelp()
+	case "repl":
+		return a.printConfigREPLHelp()
+	case "integrations":
+		return a.printConfigIntegrationsHelp()
+	case "safety":
+		return a.printConfigSafetyHelp()
+	case "secrets":
+		fmt.Println(a.i18nMgr.Get("usage_config_secrets"))
+		return nil
 	default:
 		fmt.Printf(a.i18nMgr.Get("unknown_config_help_subcommand"), subcommand)
 		return nil
 	}
 }
 
-func (a *App) handleAIConfigListModels() error {
+// handleAIConfigListModels handles /config ai list-models [--min-context <tokens>] [--tools] [--json]
+func (a *App) handleAIConfigListModels(args []string) error {
 	if a.aiManager == nil {
 		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
 	}
 
+	filter, ok := parseModelFilter(args)
+	if !ok {
+		fmt.Println(a.i18nMgr.Get("usage_config_ai_list_models"))
+		return nil
+	}
+
 	fmt.Printf("🔍 Fetching available models...\n")
 
 	ctx := context.Background()
@@ -1699,10 +2045,17 @@ func (a *App) handleAIConfigListModels() error {
 		fmt.Println("No models available for current provider")
 		return nil
 	}
+	if models = filter.Filter(models); len(models) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_models_match_filter"))
+		return nil
+	}
 
 	fmt.Printf("📋 Available models for %s:\n", a.aiManager.GetConfig().AI.Provider)
 	for i, model := range models {
 		fmt.Printf("  %d. %s - %s\n", i+1, model.ID, model.Description)
+		if capabilities := a.formatModelCapabilities(model); capabilities != "" {
+			fmt.Printf("     %s\n", capabilities)
+		}
 		if model.Pricing != nil {
 			inputCost := ai.FormatPrice(model.Pricing.InputCostPerToken * 1000000)   // per 1M tokens
 			outputCost := ai.FormatPrice(model.Pricing.OutputCostPerToken * 1000000) // per 1M tokens
@@ -1807,6 +2160,7 @@ func (a *App) interactiveAIConfig() error {
 		if err != nil {
 			return fmt.Errorf(a.i18nMgr.Get("failed_to_initialize_ai_manager"), err)
 		}
+		a.aiManager.SetDataDir(a.configMgr.GetDataDir())
 	}
 
 	// Set API key if needed
@@ -2085,6 +2439,15 @@ func (a *App) handleShowPrompts(args []string) error {
 		} else {
 			writeOutput(a.i18nMgr.Get("cost_free"))
 		}
+		if entry.Latency > 0 {
+			writeOutput(a.i18nMgr.GetWithArgs("prompt_latency", entry.Latency.Round(10*time.Millisecond)))
+		}
+		switch entry.Rating {
+		case ai.RatingGood:
+			writeOutput(a.i18nMgr.Get("prompt_rated_good"))
+		case ai.RatingBad:
+			writeOutput(a.i18nMgr.Get("prompt_rated_bad"))
+		}
 		writeOutput("\n\n")
 
 		writeOutput(a.i18nMgr.Get("user_request"))
@@ -2150,6 +2513,30 @@ func (a *App) handleClearConversation() error {
 	return nil
 }
 
+// handleAnswerLanguage shows or overrides the language AI answers in for the current conversation
+func (a *App) handleAnswerLanguage(args []string) error {
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+
+	if len(args) == 0 {
+		fmt.Printf(a.i18nMgr.Get("answer_language_current"), ai.LanguageName(a.aiManager.ResponseLanguage()))
+		return nil
+	}
+
+	if args[0] == "reset" {
+		a.aiManager.SetResponseLanguage("")
+		fmt.Printf(a.i18nMgr.Get("answer_language_reset"), ai.LanguageName(a.aiManager.ResponseLanguage()))
+		return nil
+	}
+
+	language := strings.Join(args, " ")
+	a.aiManager.SetResponseLanguage(language)
+	fmt.Printf(a.i18nMgr.Get("answer_language_set"), ai.LanguageName(language))
+	return nil
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -1,16 +1,22 @@
 package conversation
 
 import (
+	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"sqlterm/internal/ai"
 	"sqlterm/internal/config"
 	"sqlterm/internal/core"
 	"sqlterm/internal/i18n"
 	"sqlterm/internal/session"
+
+	"github.com/chzyer/readline"
 )
 
 // Mock implementations for testing
@@ -20,6 +26,7 @@ type mockConnection struct {
 	tables    []string
 	dbType    core.DatabaseType
 	name      string
+	executed  []string
 }
 
 func (m *mockConnection) Connect() error {
@@ -37,6 +44,7 @@ func (m *mockConnection) IsConnected() bool {
 }
 
 func (m *mockConnection) Execute(query string) (*core.QueryResult, error) {
+	m.executed = append(m.executed, query)
 	// Mock query execution - return minimal QueryResult
 	return &core.QueryResult{
 		Columns: []core.Column{
@@ -250,61 +258,27 @@ func TestApp_processCommand(t *testing.T) {
 	}
 }
 
-func TestApp_parseQueries(t *testing.T) {
-	app := createTestApp(t)
-
-	testCases := []struct {
-		name     string
-		content  string
-		expected []string
-	}{
-		{
-			name:     "Single query",
-			content:  "SELECT * FROM users;",
-			expected: []string{"SELECT * FROM users; "},
-		},
-		{
-			name:     "Multiple queries",
-			content:  "SELECT * FROM users; SELECT * FROM posts;",
-			expected: []string{"SELECT * FROM users; SELECT * FROM posts; "},
-		},
-		{
-			name:     "Query with comments",
-			content:  "-- Get all users\nSELECT * FROM users;\n-- Get all posts\nSELECT * FROM posts;",
-			expected: []string{"SELECT * FROM users; ", "SELECT * FROM posts; "},
-		},
-		{
-			name:     "Empty content",
-			content:  "",
-			expected: []string{},
-		},
-		{
-			name:     "Only comments",
-			content:  "-- This is a comment\n-- Another comment",
-			expected: []string{},
-		},
-		{
-			name:     "Query without semicolon",
-			content:  "SELECT * FROM users",
-			expected: []string{"SELECT * FROM users"},
-		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result := app.parseQueries(tc.content)
-
-			if len(result) != len(tc.expected) {
-				t.Errorf("Expected %d queries, got %d", len(tc.expected), len(result))
-				return
+func TestCommandRegistry(t *testing.T) {
+	for _, lang := range []string{"en_au", "zh_cn"} {
+		i18nMgr, err := i18n.NewManager(lang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		app := &App{i18nMgr: i18nMgr}
+		for _, cmd := range commands {
+			if cmd.help == nil && !app.printCommandSummary(cmd) {
+				t.Errorf("%s: %s is not documented in help_full", lang, cmd.name)
 			}
+		}
+	}
 
-			for i, expected := range tc.expected {
-				if strings.TrimSpace(result[i]) != strings.TrimSpace(expected) {
-					t.Errorf("Query %d: expected '%s', got '%s'", i, expected, result[i])
-				}
-			}
-		})
+	// Aliases run the same command, with or without the slash
+	cmd, ok := lookupCommand("/last-ai-call")
+	if !ok || cmd.name != "/prompts" {
+		t.Errorf("Expected /last-ai-call to be an alias of /prompts, got %+v", cmd)
+	}
+	if cmd, ok := lookupCommand("exit"); !ok || cmd.name != "/quit" {
+		t.Errorf("Expected exit to find /quit, got %+v", cmd)
 	}
 }
 
@@ -336,6 +310,11 @@ func TestApp_truncateQuery(t *testing.T) {
 			query:    strings.Repeat("a", 100),
 			expected: strings.Repeat("a", 47) + "...",
 		},
+		{
+			name:     "Wide characters are cut by display width",
+			query:    "SELECT * FROM 用户 WHERE 名称 = '" + strings.Repeat("张", 30) + "'",
+			expected: "SELECT * FROM 用户 WHERE 名称 = '张张张张张张张...",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -512,17 +491,6 @@ func TestApp_handleDescribeTable(t *testing.T) {
 }
 
 // Benchmark tests
-func BenchmarkApp_parseQueries(b *testing.B) {
-	app := createTestApp(&testing.T{})
-
-	content := "SELECT * FROM users; SELECT * FROM posts; SELECT COUNT(*) FROM comments;"
-
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		app.parseQueries(content)
-	}
-}
-
 func BenchmarkApp_truncateQuery(b *testing.B) {
 	app := createTestApp(&testing.T{})
 
@@ -551,3 +519,819 @@ func BenchmarkApp_generateTableMarkdown(b *testing.B) {
 		app.generateTableMarkdown(tableInfo)
 	}
 }
+
+func TestParseConnectionOverrides(t *testing.T) {
+	overrides, positional, err := parseConnectionOverrides([]string{"prod", "prod-readonly", "--read-only", "--database", "analytics"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(positional) != 2 || positional[0] != "prod" || positional[1] != "prod-readonly" {
+		t.Errorf("Unexpected positional arguments: %v", positional)
+	}
+	if overrides.ReadOnly == nil || !*overrides.ReadOnly {
+		t.Error("Expected read-only override to be set")
+	}
+	if overrides.Database == nil || *overrides.Database != "analytics" {
+		t.Error("Expected database override to be set")
+	}
+	if overrides.Host != nil || overrides.Port != nil || overrides.Username != nil {
+		t.Error("Unset flags should not produce overrides")
+	}
+
+	if _, _, err := parseConnectionOverrides([]string{"a", "b", "--unknown"}); err == nil {
+		t.Error("Expected error for unknown flag")
+	}
+}
+
+func TestParsePingArgs(t *testing.T) {
+	name, samples, timeout, err := parsePingArgs([]string{"--samples", "10", "prod", "--timeout", "2s"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if name != "prod" || samples != 10 || timeout != 2*time.Second {
+		t.Errorf("Unexpected ping of %q, %d samples, timeout %v", name, samples, timeout)
+	}
+	if name, samples, _, _ := parsePingArgs(nil); name != "" || samples != core.DefaultPingSamples {
+		t.Errorf("Expected the current connection and default samples, got %q, %d", name, samples)
+	}
+	for _, bad := range [][]string{{"a", "b"}, {"--samples", "0"}, {"--timeout", "soon"}} {
+		if _, _, _, err := parsePingArgs(bad); err == nil {
+			t.Errorf("Expected an error for %v", bad)
+		}
+	}
+}
+
+func TestPreviewThreshold(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	for _, tc := range []struct{ configured, expected int64 }{
+		{0, core.DefaultPreviewThreshold},
+		{500, 500},
+		{-1, 0},
+	} {
+		app.aiManager.GetConfig().Display.PreviewThreshold = tc.configured
+		if got := app.previewThreshold(); got != tc.expected {
+			t.Errorf("Threshold %d: expected %d, got %d", tc.configured, tc.expected, got)
+		}
+	}
+	// Without a terminal to ask on, a large table is not previewed
+	if handled, _ := app.previewLargeTable("SELECT * FROM events"); handled {
+		t.Error("Expected no preview without a terminal")
+	}
+}
+
+func TestNotifySettings(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	display := &app.aiManager.GetConfig().Display
+	for _, tc := range []struct {
+		configured int
+		expected   time.Duration
+	}{
+		{0, core.DefaultNotifyAfter},
+		{600, 10 * time.Minute},
+		{-1, 0},
+	} {
+		display.NotifyAfter = tc.configured
+		if got := app.notifyAfter(); got != tc.expected {
+			t.Errorf("Notify after %d: expected %v, got %v", tc.configured, tc.expected, got)
+		}
+	}
+	if app.notifyMethod() != core.NotifyBell {
+		t.Errorf("Expected the bell by default, got %s", app.notifyMethod())
+	}
+	display.Notify = core.NotifyDesktop
+	if app.notifyMethod() != core.NotifyDesktop {
+		t.Errorf("Expected desktop notifications, got %s", app.notifyMethod())
+	}
+}
+
+func TestBackgroundJob(t *testing.T) {
+	app := createTestApp(t)
+	app.config = &core.ConnectionConfig{Name: "local", DatabaseType: core.SQLite, Database: filepath.Join(t.TempDir(), "local.db")}
+	conn, err := core.NewConnection(app.config)
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	app.connection = conn
+	for _, statement := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY)",
+		"INSERT INTO users (id) VALUES (1), (2), (3)",
+	} {
+		result, err := conn.Execute(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range result.Itor() {
+		}
+		result.Close()
+	}
+
+	if err := app.runQueryLine("SELECT id FROM users; &"); err != nil {
+		t.Fatalf("Failed to start job: %v", err)
+	}
+	var job core.Job
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if job, _ = app.jobs.Get(1); job.Status != core.JobRunning {
+			break
+		}
+	}
+	if job.Status != core.JobDone || job.Rows != 3 || job.Query != "SELECT id FROM users" {
+		t.Fatalf("Unexpected job %+v", job)
+	}
+	content, err := os.ReadFile(job.ResultPath)
+	if err != nil || !strings.Contains(string(content), "Job #1 Results") {
+		t.Errorf("Expected the result saved as markdown, got %q, %v", content, err)
+	}
+}
+
+func TestIsAISubcommand(t *testing.T) {
+	for _, line := range []string{
+		"good", "bad", "detach", "attach-result", "attach-result 20", "queue", "queue 3", "queue #3", "queue clear", "queue clear --all",
+	} {
+		if !isAISubcommand(strings.Fields(line)) {
+			t.Errorf("Expected /ai %s to be a subcommand", line)
+		}
+	}
+	// Questions that happen to start with a subcommand's name go to the AI
+	for _, line := range []string{
+		"bad debts by month", "good customers in 2024", "detach rate of subscribers", "queue length per worker",
+		"attach-result rows", "attach-result 0", "queue clear everything",
+	} {
+		if isAISubcommand(strings.Fields(line)) {
+			t.Errorf("Expected /ai %s to be sent as a question", line)
+		}
+	}
+}
+
+func TestEnforceConnectionPolicy(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	app.config = &core.ConnectionConfig{Name: "prod", Tags: map[string]string{"env": "prod"}}
+	app.aiManager.GetConfig().Policy.Production = config.PolicyReadOnly
+
+	if err := app.enforceConnectionPolicy("SELECT * FROM users"); err != nil {
+		t.Errorf("Read queries should be allowed, got %v", err)
+	}
+	// Writable CTEs and SELECT ... INTO start like reads but change data
+	writes := []string{
+		"DELETE FROM users",
+		"WITH d AS (DELETE FROM users RETURNING *) SELECT count(*) FROM d",
+		"WITH old AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM old)",
+		"SELECT * INTO users_copy FROM users",
+	}
+	for _, query := range writes {
+		if err := app.enforceConnectionPolicy(query); err == nil {
+			t.Errorf("Expected %q to be rejected on read-only production policy", query)
+		}
+	}
+
+	// Without a terminal the confirmation defaults to no
+	app.aiManager.GetConfig().Policy.Production = config.PolicyConfirm
+	for _, query := range writes {
+		if err := app.enforceConnectionPolicy(query); err == nil {
+			t.Errorf("Expected unconfirmed %q to be cancelled", query)
+		}
+	}
+
+	// No token could verify without approvers in the policy file
+	app.aiManager.GetConfig().Policy.Production = config.PolicyApprove
+	if err := app.enforceConnectionPolicy("DELETE FROM users"); err == nil || !strings.Contains(err.Error(), "approvers") {
+		t.Errorf("Expected approval to be refused without approvers, got %v", err)
+	}
+
+	// EXPLAIN ANALYZE runs the statement, so /plan --analyze only takes reads
+	app.connection = &mockConnection{}
+	if err := app.handlePlan([]string{"--analyze", writes[1]}); err == nil || !strings.Contains(err.Error(), "read") {
+		t.Errorf("Expected /plan --analyze to refuse a writable CTE, got %v", err)
+	}
+	app.connection = nil
+
+	app.config.Tags = map[string]string{"env": "dev"}
+	if err := app.enforceConnectionPolicy("DELETE FROM users"); err != nil {
+		t.Errorf("Non-production writes should be allowed, got %v", err)
+	}
+}
+
+func TestExecuteQueryPolicy(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	conn := &mockConnection{}
+	app.connection = conn
+	app.config = &core.ConnectionConfig{Name: "prod", Tags: map[string]string{"env": "prod"}}
+	app.aiManager.GetConfig().Policy.Production = config.PolicyReadOnly
+
+	// These start like reads but change data when they run
+	for _, query := range []string{
+		"EXPLAIN ANALYZE DELETE FROM users",
+		"EXPLAIN (ANALYZE) UPDATE users SET name = 'x'",
+		"SELECT 1; DROP TABLE users",
+	} {
+		if _, err := app.executeQuery(query); err == nil {
+			t.Errorf("Expected %q to be rejected on read-only production policy", query)
+		}
+	}
+	if len(conn.executed) != 0 {
+		t.Errorf("Expected nothing to reach the connection, got %q", conn.executed)
+	}
+	if _, err := app.executeQuery("SELECT id INTO @last FROM users"); err != nil {
+		t.Errorf("Expected a read into a variable to run, got %v", err)
+	}
+}
+
+func TestGuardChange(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	prod := &core.ConnectionConfig{Name: "prod", Tags: map[string]string{"env": "prod"}}
+	dev := &core.ConnectionConfig{Name: "dev"}
+
+	app.aiManager.GetConfig().Policy.Production = config.PolicyApprove
+	if err := app.guardChange(changeGuard{conn: prod}); err == nil || !strings.Contains(err.Error(), "cannot ask") {
+		t.Errorf("Expected a change that cannot be approved to be refused, got %v", err)
+	}
+	app.aiManager.GetConfig().Policy.Production = config.PolicyReadOnly
+	if err := app.guardChange(changeGuard{conn: prod, approval: "DELETE FROM users"}); err == nil {
+		t.Error("Expected a read-only production connection to refuse the change")
+	}
+
+	// Without a terminal the confirmation defaults to no
+	if err := app.guardChange(changeGuard{conn: dev}); err != nil {
+		t.Errorf("Expected no question without a policy, got %v", err)
+	}
+	if err := app.guardChange(changeGuard{conn: dev, question: "Undo 2 statements?", ask: true}); err == nil {
+		t.Error("Expected a question asked whatever the policy to be answered")
+	}
+}
+
+func TestPolicyFile(t *testing.T) {
+	app := createTestApp(t)
+	dir := t.TempDir()
+	data := "rules:\n  - deny_commands: [/exit]\n  - tags: [env=prod]\n    deny_statements: [dml]\n"
+	if err := os.WriteFile(filepath.Join(dir, config.DefaultPolicyFile), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := loadPolicy(nil, dir)
+	if err != nil {
+		t.Fatalf("loadPolicy failed: %v", err)
+	}
+	app.policy = policy
+
+	// Aliases resolve to the command they stand for
+	if err := app.processCommand("/quit"); err == nil {
+		t.Error("Expected /quit to be denied through its /exit alias")
+	}
+
+	app.config = &core.ConnectionConfig{Name: "prod", Tags: map[string]string{"env": "prod"}}
+	if err := app.enforceConnectionPolicy("UPDATE users SET name = 'x'"); err == nil {
+		t.Error("Expected DML to be denied on prod")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, config.DefaultPolicyFile), []byte("rules:\n  - deny_commands: [/nope]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadPolicy(nil, dir); err == nil {
+		t.Error("Expected an unknown command to be rejected")
+	}
+}
+
+func TestEstimateAffectedRows(t *testing.T) {
+	app := createTestApp(t)
+	app.config = &core.ConnectionConfig{Name: "local", DatabaseType: core.SQLite, Database: filepath.Join(t.TempDir(), "local.db")}
+	conn, err := core.NewConnection(app.config)
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	app.connection = conn
+	for _, statement := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)",
+		"INSERT INTO users (active) VALUES (1), (1), (0)",
+	} {
+		result, err := conn.Execute(statement)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range result.Itor() {
+		}
+		result.Close()
+	}
+
+	if _, ok := app.estimateAffectedRows("DELETE FROM users WHERE active = 1"); ok {
+		t.Error("Expected no estimate unless the connection asks for one")
+	}
+	app.config.EstimateRows = core.EstimateRowsCount
+	if rows, ok := app.estimateAffectedRows("DELETE FROM users WHERE active = 1"); !ok || rows != 2 {
+		t.Errorf("Expected 2 rows, got %v, %v", rows, ok)
+	}
+	if _, ok := app.estimateAffectedRows("INSERT INTO users (active) VALUES (1)"); ok {
+		t.Error("Only UPDATE and DELETE are estimated")
+	}
+	// Without a terminal the question defaults to no
+	if err := app.enforceConnectionPolicy("UPDATE users SET active = 0"); err == nil {
+		t.Error("Expected the unconfirmed update to be cancelled")
+	}
+
+	if got := formatRowCount(12340); got != "12,340" {
+		t.Errorf("formatRowCount(12340) = %q", got)
+	}
+}
+
+func TestAuditRowsAffected(t *testing.T) {
+	app := createTestApp(t)
+	app.config = &core.ConnectionConfig{Name: "local", DatabaseType: core.SQLite, Database: filepath.Join(t.TempDir(), "local.db")}
+	conn, err := core.NewConnection(app.config)
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	app.connection = conn
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if app.auditLog, err = session.OpenAuditLog(path, false); err != nil {
+		t.Fatal(err)
+	}
+	defer app.auditLog.Close()
+
+	for _, statement := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)",
+		"INSERT INTO users (active) VALUES (1), (1), (0)",
+		"UPDATE users SET active = 0 WHERE active = 1",
+	} {
+		result, err := app.executeQuery(statement)
+		if err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+		if err := drainResult(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var entry session.AuditEntry
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Rows != 2 {
+		t.Errorf("Expected the update audited with the 2 rows it changed, got %d", entry.Rows)
+	}
+}
+
+func TestPreImageUndo(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	app.config = &core.ConnectionConfig{Name: "local", DatabaseType: core.SQLite, Database: filepath.Join(t.TempDir(), "local.db")}
+	conn, err := core.NewConnection(app.config)
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	app.connection = conn
+	scalar := func(query string) string {
+		value, err := app.scalarQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	run := func(statement string) {
+		result, err := app.executeQuery(statement)
+		if err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+		if err := drainResult(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, active INTEGER)")
+	run("INSERT INTO users (name, active) VALUES ('ann', 1), ('bob', 1), (NULL, 0)")
+
+	if err := app.handleConfig([]string{"safety", "pre-image", "on"}); err != nil {
+		t.Fatal(err)
+	}
+	run("UPDATE users SET active = 0 WHERE active = 1")
+	// Undoing needs confirming, which defaults to no without a terminal
+	if err := app.handleUndo([]string{"last"}); err == nil {
+		t.Error("Expected the unconfirmed undo to be cancelled")
+	}
+	p, err := core.LastPreImage(app.preImageDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rows != 2 || p.File == "" || len(p.Key) != 1 || p.Key[0] != "id" {
+		t.Fatalf("Unexpected pre-image %+v", p)
+	}
+	columns, rows, err := app.readPreImage(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, err := p.UndoStatements(core.SQLite, columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.undoing = true
+	for _, statement := range statements {
+		run(statement)
+	}
+	app.undoing = false
+	if got := scalar("SELECT count(*) FROM users WHERE active = 1"); got != "2" {
+		t.Errorf("Expected the update to be undone, %s rows active", got)
+	}
+
+	if err := app.handleConfig([]string{"safety", "pre-image", "on", "table"}); err != nil {
+		t.Fatal(err)
+	}
+	run("DELETE FROM users WHERE name IS NULL")
+	p, err = core.LastPreImage(app.preImageDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Kind != "DELETE" || p.BackupTable == "" || p.Rows != 1 {
+		t.Fatalf("Unexpected pre-image %+v", p)
+	}
+	if got := scalar("SELECT count(*) FROM " + p.BackupTable); got != "1" {
+		t.Errorf("Expected the deleted row in %s, got %s", p.BackupTable, got)
+	}
+	columns, rows, err = app.readPreImage(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements, err = p.UndoStatements(core.SQLite, columns, rows)
+	if err != nil || len(statements) != 1 || !strings.Contains(statements[0], "NULL") {
+		t.Fatalf("Unexpected undo statements %v, %v", statements, err)
+	}
+}
+
+// chunkReader returns one chunk per Read, as a terminal delivers input
+type chunkReader struct{ chunks []string }
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+func (r *chunkReader) Close() error { return nil }
+
+func TestPasteReader(t *testing.T) {
+	in := &chunkReader{chunks: []string{
+		"ab\x1b[A",
+		"\x1b[20", "0~one line\x1b[201~\r",
+		"\x1b[200~SELECT *\r\nFROM users\r\n-- note\rWHERE id = 1\x1b[2", "01~",
+	}}
+	r := newPasteReader(in)
+	typed, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ab\x1b[Aone line\r\r"; string(typed) != want {
+		t.Errorf("Expected readline to get %q, got %q", want, typed)
+	}
+	if got, want := r.take(), "SELECT *\nFROM users\n-- note\nWHERE id = 1"; got != want {
+		t.Errorf("Expected the paste %q, got %q", want, got)
+	}
+	if got := r.take(); got != "" {
+		t.Errorf("Expected a paste to be taken once, got %q", got)
+	}
+}
+
+func TestDraftKeys(t *testing.T) {
+	app := createTestApp(t)
+	press := func(line string, key rune) string {
+		r, _ := draftKeys(key)
+		runes := append([]rune(line), r)
+		got, pos, ok := app.onDraftKey(runes, len(runes), r)
+		if !ok {
+			t.Fatalf("Expected %q to be handled", key)
+		}
+		if pos != len(got) {
+			t.Errorf("Expected the cursor at the end of %q, got %d", string(got), pos)
+		}
+		return string(got)
+	}
+
+	if got := press("SELECT * FROM orders WHERE", readline.CharFwdSearch); got != "" {
+		t.Errorf("Expected Ctrl+S to clear the line, got %q", got)
+	}
+	if got := press("", readline.CharCtrlU); got != "SELECT * FROM orders WHERE" {
+		t.Errorf("Expected Ctrl+U to bring the draft back, got %q", got)
+	}
+	if got := press("", readline.CharCtrlU); got != "" || len(app.drafts) != 0 {
+		t.Errorf("Expected nothing left to pop, got %q and %v", got, app.drafts)
+	}
+
+	press("first", readline.CharFwdSearch)
+	if got := press("second", readline.CharCtrlU); got != "first" || len(app.drafts) != 1 || app.drafts[0] != "second" {
+		t.Errorf("Expected the typed line to take the draft's place, got %q and %v", got, app.drafts)
+	}
+	if _, _, ok := app.onDraftKey([]rune("a"), 1, 'a'); ok {
+		t.Error("Expected other keys to be left alone")
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	app := createTestApp(t)
+	for name, content := range map[string]string{
+		"shop":  "/exec SELECT * FROM order_items;\n",
+		"stats": "/exec SELECT count(*) FROM ORDER_ITEMS;\n/tables\n",
+	} {
+		path := app.sessionMgr.HistoryFile(name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := app.historyNames(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"shop", "stats", ""}; !slices.Equal(names, want) {
+		t.Errorf("Expected every history, got %q", names)
+	}
+	if err := app.handleHistory([]string{"--all", "search", `"order_items"`}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without --all only the history in use is searched
+	if names, _ := app.historyNames(false); !slices.Equal(names, []string{""}) {
+		t.Errorf("Expected the global history while disconnected, got %q", names)
+	}
+	app.config = &core.ConnectionConfig{Name: "shop"}
+	if names, _ := app.historyNames(false); !slices.Equal(names, []string{"shop"}) {
+		t.Errorf("Expected the connection's history, got %q", names)
+	}
+}
+
+func TestResultColumns(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	app.config = &core.ConnectionConfig{Name: "local", DatabaseType: core.SQLite, Database: filepath.Join(t.TempDir(), "local.db")}
+	conn, err := core.NewConnection(app.config)
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	app.connection = conn
+	for _, statement := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(20) NOT NULL, nick TEXT)",
+		"INSERT INTO users (name, nick) VALUES ('ann', NULL), ('bartholomew', 'bart')",
+		"SELECT id, name, nick FROM users",
+	} {
+		result, err := app.executeQuery(statement)
+		if err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+		if err := drainResult(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	last := app.lastColumns
+	if last == nil || last.rows != 2 || len(last.columns) != 3 || len(last.observed) != 3 {
+		t.Fatalf("Unexpected columns %+v", last)
+	}
+	if last.columns[1].Type != "VARCHAR(20)" {
+		t.Errorf("Expected the declared type of name, got %q", last.columns[1].Type)
+	}
+	if last.observed[1].MaxWidth != len("bartholomew") || last.observed[1].Nulls {
+		t.Errorf("Unexpected observed name %+v", last.observed[1])
+	}
+	if !last.observed[2].Nulls {
+		t.Error("Expected a NULL seen in nick")
+	}
+	if out := app.formatResultColumns(last); !strings.Contains(out, "| 2 | name |") {
+		t.Errorf("Expected a row for name in:\n%s", out)
+	}
+}
+
+func TestSplitFormatFlag(t *testing.T) {
+	app := createTestApp(t)
+	testCases := []struct {
+		target   string
+		expected string
+		format   string
+		wantErr  bool
+	}{
+		{"out.jsonl", "out.jsonl", "", false},
+		{"out.jsonl --with-schema", "out.jsonl", withSchemaFlag, false},
+		{"out.ndjson --email a@b.com --with-schema", "out.ndjson --email a@b.com", withSchemaFlag, false},
+		{"out.csv --with-schema", "", "", true},
+		{"s3://bucket/out.jsonl --with-schema", "", "", true},
+		{"out.txt --fixed-width", "out.txt", fixedWidthFlag, false},
+		{"kafka://broker/topic --fixed-width", "", "", true},
+	}
+	for _, tc := range testCases {
+		target, format, err := app.splitFormatFlag(tc.target)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: unexpected error %v", tc.target, err)
+			continue
+		}
+		if target != tc.expected || format != tc.format {
+			t.Errorf("%q: expected %q %q, got %q %q", tc.target, tc.expected, tc.format, target, format)
+		}
+	}
+}
+
+func TestConfigREPL(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	if app.replConfig().BareSQL {
+		t.Fatal("Expected bare SQL to be off by default")
+	}
+
+	if err := app.handleConfig([]string{"repl", "bare-sql", "on"}); err != nil {
+		t.Fatal(err)
+	}
+	if !app.replConfig().BareSQL {
+		t.Error("Expected bare SQL to be on")
+	}
+
+	// An invalid value leaves the setting alone
+	if err := app.handleConfig([]string{"repl", "bare-sql", "maybe"}); err != nil {
+		t.Fatal(err)
+	}
+	if !app.replConfig().BareSQL {
+		t.Error("Expected bare SQL to stay on")
+	}
+
+	if err := app.handleConfig([]string{"repl", "bare-sql", "off"}); err != nil {
+		t.Fatal(err)
+	}
+	if app.replConfig().BareSQL {
+		t.Error("Expected bare SQL to be off")
+	}
+}
+
+func TestParseCopyTableArgs(t *testing.T) {
+	args := strings.Fields(`prod.public.users staging.users --where "created_at > now() - interval '1 day'" --batch 5000 --on-conflict upsert`)
+	parsed, err := parseCopyTableArgs(args)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed.source != "prod" || parsed.sourceTable != "public.users" || parsed.target != "staging" || parsed.targetTable != "users" {
+		t.Errorf("Unexpected tables %+v", parsed)
+	}
+	if parsed.where != "created_at > now() - interval '1 day'" || parsed.batch != 5000 || parsed.onConflict != core.CopyConflictUpsert {
+		t.Errorf("Unexpected options %+v", parsed)
+	}
+
+	for _, bad := range []string{"prod.users", "prod staging.users", "prod.users staging.users --on-conflict replace", "prod.users staging.users --batch 0"} {
+		if _, err := parseCopyTableArgs(strings.Fields(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestParseChunkedArgs(t *testing.T) {
+	parsed, err := parseChunkedArgs(strings.Fields("DELETE FROM events WHERE created_at < '2023-01-01'; --batch 10000 --sleep 500ms --resume"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed.statement != "DELETE FROM events WHERE created_at < '2023-01-01'" {
+		t.Errorf("Unexpected statement %q", parsed.statement)
+	}
+	if parsed.batch != 10000 || parsed.sleep != 500*time.Millisecond || !parsed.resume || parsed.key != "" {
+		t.Errorf("Unexpected options %+v", parsed)
+	}
+
+	for _, bad := range []string{"--batch 10", "DELETE FROM events --batch 0", "DELETE FROM events --sleep soon", "DELETE FROM events --key id extra"} {
+		if _, err := parseChunkedArgs(strings.Fields(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestParseBenchArgs(t *testing.T) {
+	opts, query, err := parseBenchArgs(strings.Fields("10 --warmup 2 --concurrency 4 SELECT * FROM users WHERE id = -1;"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if opts.Runs != 10 || opts.Warmup != 2 || opts.Concurrency != 4 || query != "SELECT * FROM users WHERE id = -1" {
+		t.Errorf("Unexpected benchmark %+v of %q", opts, query)
+	}
+	if opts, _, _ := parseBenchArgs(strings.Fields("5 SELECT 1")); opts.Concurrency != 1 || opts.Warmup != 0 {
+		t.Errorf("Unexpected defaults %+v", opts)
+	}
+
+	for _, bad := range []string{"10", "0 SELECT 1", "ten SELECT 1", "10 --concurrency 0 SELECT 1", "10 --warmup 2"} {
+		if _, _, err := parseBenchArgs(strings.Fields(bad)); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+	if got := formatChange(200, 150); got != "-25.0%" {
+		t.Errorf("Expected -25.0%%, got %s", got)
+	}
+}
+
+func TestParseDataDiffArgs(t *testing.T) {
+	parsed, err := parseDataDiffArgs([]string{"orders", "prod", "staging", "--key", "id", "--columns", "status, total", ">", "diff.csv"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if parsed.table != "orders" || parsed.left != "prod" || parsed.right != "staging" {
+		t.Errorf("Unexpected positional arguments: %+v", parsed)
+	}
+	if len(parsed.keys) != 1 || parsed.keys[0] != "id" {
+		t.Errorf("Unexpected keys: %v", parsed.keys)
+	}
+	if len(parsed.columns) != 2 || parsed.columns[1] != "total" {
+		t.Errorf("Unexpected columns: %v", parsed.columns)
+	}
+	if parsed.csvFile != "diff.csv" || parsed.limit != defaultDiffDisplayLimit {
+		t.Errorf("Unexpected output options: %+v", parsed)
+	}
+
+	if _, err := parseDataDiffArgs([]string{"orders", "prod"}); err == nil {
+		t.Error("Expected error for missing connection")
+	}
+}
+
+func TestApp_recoverPanic(t *testing.T) {
+	app := createTestApp(t)
+	app.config = &core.ConnectionConfig{Name: "test"}
+	app.draft = "/exec SELECT * FROM orders WHERE"
+	app.inTransaction = true
+
+	err := func() (err error) {
+		defer app.recoverPanic(&err)
+		panic("renderer failed")
+	}()
+	if err == nil || !strings.Contains(err.Error(), "renderer failed") {
+		t.Fatalf("Expected the panic as an error, got %v", err)
+	}
+
+	state, loadErr := app.sessionMgr.LoadRecovery()
+	if loadErr != nil || state == nil {
+		t.Fatalf("Expected saved recovery state, got %v", loadErr)
+	}
+	if state.Input != app.draft || !state.InTransaction || state.Connection != "test" || state.Panic != "renderer failed" {
+		t.Errorf("Expected the draft and transaction to be saved, got %+v", state)
+	}
+}
+
+func TestApp_switchProfile(t *testing.T) {
+	base := t.TempDir()
+	config.SetConfigDir(base)
+	t.Setenv(config.ProfileEnv, "")
+	t.Cleanup(func() {
+		config.SetConfigDir("")
+		config.SetProfile("")
+	})
+	if err := os.MkdirAll(filepath.Join(base, config.ProfilesDir, "work"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	app := createTestApp(t)
+	app.configMgr = config.NewManager()
+	app.lastResult = &ai.ResultAttachment{}
+	app.attachRows = 5
+	app.vars = map[string]string{"customer": "42"}
+	app.transcript = []core.ReportQuery{{Query: "SELECT * FROM customers"}}
+	if err := app.switchProfile("work"); err != nil {
+		t.Fatal(err)
+	}
+	if app.lastResult != nil || app.attachRows != 0 || app.vars != nil || app.transcript != nil {
+		t.Error("Expected the results, variables and AI attachments of the old profile to be forgotten")
+	}
+	workDir := filepath.Join(base, config.ProfilesDir, "work")
+	if app.configMgr.GetProfile() != "work" || app.configMgr.GetConfigDir() != workDir {
+		t.Errorf("Expected the work profile in %s, got %s", workDir, app.configMgr.GetConfigDir())
+	}
+	if got := app.sessionMgr.GetSessionDir("shop"); got != filepath.Join(workDir, "sessions", "shop") {
+		t.Errorf("Expected sessions kept in the profile, got %s", got)
+	}
+
+	if got := completeProfileNames(NewAutoCompleter(app), []string{"/use-profile", "d"}, "/use-profile d"); len(got) != 1 || got[0] != "efault" {
+		t.Errorf("Expected the default profile completed, got %v", got)
+	}
+	if err := app.switchProfile("../other"); err == nil {
+		t.Error("Expected an invalid profile name to be refused")
+	}
+}
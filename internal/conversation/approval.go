@@ -0,0 +1,81 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// approvedStatement is the approval of the statement being run, for the audit log
+type approvedStatement struct {
+	id       string
+	approver string
+}
+
+// requireApproval saves an approval bundle for a statement and waits for the token the
+// approver produced from it with sqlterm approve. explain adds the plan and the row
+// estimate, for statements the database can explain.
+func (a *App) requireApproval(statement string, explain bool) error {
+	var approvers map[string]string
+	if a.policy != nil {
+		approvers = a.policy.Approvers
+	}
+	if len(approvers) == 0 {
+		// No token could ever verify, so the statement is refused at once
+		return errors.New(a.i18nMgr.Get("approval_no_approvers"))
+	}
+	bundle, err := core.NewApprovalBundle(auditUser(), a.config, statement)
+	if err != nil {
+		return err
+	}
+	if explain {
+		if rows, ok := a.estimateAffectedRows(statement); ok {
+			bundle.EstimatedRows = rows
+		}
+		// DDL cannot be explained everywhere; the bundle goes out without a plan then
+		_ = bundle.AddPlan(a.connection, a.config.DatabaseType)
+	}
+	path, err := core.SaveApprovalBundle(filepath.Join(a.configMgr.GetDataDir(), "approvals"), bundle)
+	if err != nil {
+		return err
+	}
+	if err := a.displayMarkdown(a.formatApprovalBundle(bundle, path)); err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		token, err := a.readInput(a.i18nMgr.Get("approval_token_prompt"))
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			break
+		}
+		approver, err := bundle.VerifyApproval(approvers, token)
+		if err != nil {
+			fmt.Printf(a.i18nMgr.Get("approval_token_rejected"), err)
+			continue
+		}
+		a.approved = &approvedStatement{id: bundle.ID, approver: approver}
+		fmt.Printf(a.i18nMgr.Get("approval_accepted"), approver)
+		return nil
+	}
+	return errors.New(a.i18nMgr.Get("statement_cancelled"))
+}
+
+func (a *App) formatApprovalBundle(bundle *core.ApprovalBundle, path string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🛂 %s\n\n", a.i18nMgr.GetWithArgs("approval_required_header", bundle.Connection)))
+	sb.WriteString(fmt.Sprintf("```sql\n%s\n```\n\n", strings.TrimSpace(bundle.Statement)))
+	if bundle.Plan != "" {
+		sb.WriteString(fmt.Sprintf("```text\n%s```\n\n", bundle.Plan))
+	}
+	if bundle.EstimatedRows > 0 {
+		sb.WriteString(a.i18nMgr.GetWithArgs("approval_estimated_rows", fmt.Sprintf("%.0f", bundle.EstimatedRows)) + "\n\n")
+	}
+	sb.WriteString(a.i18nMgr.GetWithArgs("approval_instructions", path, path) + "\n")
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// errAssertionFailed is returned by /assert when the result differs from its snapshot,
+// so sqlterm run exits with an error
+var errAssertionFailed = errors.New("assertion failed")
+
+// handleAssert runs a query and compares its result with a saved snapshot:
+// /assert [--update] @expected.csv SELECT .... With --update the snapshot is written
+// from the result instead.
+func (a *App) handleAssert(args []string) error {
+	update := false
+	if len(args) > 0 && args[0] == "--update" {
+		update = true
+		args = args[1:]
+	}
+	if len(args) < 2 || !strings.HasPrefix(args[0], "@") || len(args[0]) == 1 {
+		fmt.Println(a.i18nMgr.Get("usage_assert"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	path := args[0][1:]
+	query := strings.TrimSuffix(strings.TrimSpace(strings.Join(args[1:], " ")), ";")
+
+	var expected *core.Snapshot
+	if !update {
+		var err error
+		if expected, err = core.LoadSnapshot(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf(a.i18nMgr.Get("assert_snapshot_missing"), path)
+			}
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_load_snapshot"), err)
+		}
+	}
+
+	result, err := a.executeQuery(query)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("query_execution_failed"), err)
+	}
+	actual, err := core.SnapshotFromResult(result)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("query_execution_failed"), err)
+	}
+
+	if update {
+		if err := core.SaveSnapshot(path, actual); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_save_snapshot"), err)
+		}
+		fmt.Printf(a.i18nMgr.Get("assert_snapshot_saved"), len(actual.Rows), path)
+		return nil
+	}
+
+	report := core.CompareSnapshot(expected, actual)
+	if report.Passed() {
+		fmt.Printf(a.i18nMgr.Get("assert_passed"), report.ActualRows, path)
+		return nil
+	}
+
+	fmt.Printf(a.i18nMgr.Get("assert_failed"), path)
+	if !report.ColumnsMatch() {
+		fmt.Printf(a.i18nMgr.Get("assert_columns_differ"),
+			strings.Join(report.ExpectedColumns, ", "), strings.Join(report.ActualColumns, ", "))
+		return errAssertionFailed
+	}
+	if report.ExpectedRows != report.ActualRows {
+		fmt.Printf(a.i18nMgr.Get("assert_row_count_differs"), report.ExpectedRows, report.ActualRows)
+	}
+	for _, m := range report.Mismatches {
+		fmt.Printf(a.i18nMgr.Get("assert_cell_differs"), m.Row, m.Column, m.Expected, m.Actual)
+	}
+	if more := report.MismatchCount - len(report.Mismatches); more > 0 {
+		fmt.Printf(a.i18nMgr.Get("assert_more_mismatches"), more)
+	}
+	return errAssertionFailed
+}
@@ -0,0 +1,163 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/core"
+)
+
+// rememberResult keeps the shape and first rows of a finished query for /ai attach-result
+func (a *App) rememberResult(query string, result *core.QueryResult) {
+	if result.Error() != nil || len(result.Columns) == 0 {
+		return
+	}
+
+	attachment := &ai.ResultAttachment{
+		Query:     query,
+		Columns:   result.ColumnNames(),
+		TotalRows: result.RowCount(),
+	}
+	for _, col := range result.Columns {
+		attachment.Types = append(attachment.Types, col.Type)
+	}
+	// Sensitive values stay out of AI messages even after /unmask showed them
+	attachment.Rows = sampleCells(result, a.sensitive.Mask(query, result.Columns))
+	a.lastResult = attachment
+}
+
+// sampleCells returns the rows a result kept with KeepSample as text, with the columns
+// of mask, which may be nil, masked
+func sampleCells(result *core.QueryResult, mask *core.MaskFormatter) [][]string {
+	var rows [][]string
+	for _, row := range result.Sample() {
+		cells := make([]string, len(row))
+		for i, val := range row {
+			if i < len(result.Columns) && mask.Masked(result.Columns[i].Name) {
+				cells[i] = core.MaskedValue
+			} else if val.IsNull() {
+				cells[i] = "NULL"
+			} else {
+				cells[i] = val.String()
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+// handleAICommand handles /ai attach-result [rows], /ai detach, /ai good|bad and /ai queue
+func (a *App) handleAICommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Println(a.i18nMgr.Get("usage_ai"))
+		return nil
+	}
+	if !isAISubcommand(args) {
+		return a.processAIMessage(strings.Join(args, " "))
+	}
+
+	switch args[0] {
+	case "attach-result":
+		rows := ai.DefaultAttachmentRows
+		if len(args) > 1 {
+			n, _ := strconv.Atoi(args[1])
+			rows = min(n, ai.MaxAttachmentRows)
+		}
+		if a.lastResult == nil {
+			fmt.Println(a.i18nMgr.Get("no_result_to_attach"))
+			return nil
+		}
+		a.attachRows = rows
+		fmt.Printf(a.i18nMgr.Get("result_will_be_attached"), len(a.lastResult.Columns), min(rows, len(a.lastResult.Rows)))
+		return nil
+	case "detach":
+		a.attachRows = 0
+		fmt.Println(a.i18nMgr.Get("result_detached"))
+		return nil
+	case "queue":
+		return a.handleAIQueue(args[1:])
+	case "good", "bad":
+		if a.aiManager == nil {
+			fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+			return nil
+		}
+		return a.rateLastResponse(args[0] == "good")
+	default:
+		return a.processAIMessage(strings.Join(args, " "))
+	}
+}
+
+// isAISubcommand reports whether /ai arguments are one of its subcommands with valid
+// arguments. Anything else, such as "/ai bad debts by month", is a question.
+func isAISubcommand(args []string) bool {
+	switch args[0] {
+	case "detach", "good", "bad":
+		return len(args) == 1
+	case "attach-result":
+		if len(args) == 1 {
+			return true
+		}
+		n, err := strconv.Atoi(args[1])
+		return len(args) == 2 && err == nil && n > 0
+	case "queue":
+		switch len(args) {
+		case 1:
+			return true
+		case 2:
+			_, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+			return err == nil || args[1] == "clear"
+		case 3:
+			return args[1] == "clear" && args[2] == "--all"
+		}
+	}
+	return false
+}
+
+// rateLastResponse records /ai good or /ai bad for the last AI answer
+func (a *App) rateLastResponse(good bool) error {
+	rating := ai.RatingBad
+	if good {
+		rating = ai.RatingGood
+	}
+	stored, err := a.aiManager.RateLastResponse(rating)
+	if errors.Is(err, ai.ErrNothingToRate) {
+		fmt.Println(a.i18nMgr.Get("no_ai_query_to_rate"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !good:
+		fmt.Println(a.i18nMgr.Get("ai_rated_bad"))
+	case stored > 0:
+		fmt.Printf(a.i18nMgr.Get("ai_examples_saved"), stored)
+	default:
+		fmt.Println(a.i18nMgr.Get("ai_rated_good"))
+	}
+	return nil
+}
+
+// attachResult adds the last query result to a chat message when it was requested with
+// /ai attach-result or the message mentions #last. A pending attachment is used once.
+func (a *App) attachResult(message string) string {
+	rows := a.attachRows
+	if rows == 0 && strings.Contains(message, ai.LastResultReference) {
+		rows = ai.DefaultAttachmentRows
+	}
+	if rows == 0 {
+		return message
+	}
+	a.attachRows = 0
+
+	if a.lastResult == nil {
+		fmt.Println(a.i18nMgr.Get("no_result_to_attach"))
+		return message
+	}
+	fmt.Printf(a.i18nMgr.Get("result_attached"), len(a.lastResult.Columns), min(rows, len(a.lastResult.Rows)))
+	return ai.AttachToMessage(message, a.lastResult.Format(rows))
+}
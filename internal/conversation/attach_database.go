@@ -0,0 +1,67 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// handleAttach attaches another database file to a SQLite connection:
+// /attach other.db AS archive. Without arguments it lists the attached databases.
+func (a *App) handleAttach(args []string) error {
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	if a.config.DatabaseType != core.SQLite {
+		return fmt.Errorf(a.i18nMgr.Get("attach_sqlite_only"), a.config.DatabaseType)
+	}
+
+	if len(args) == 0 {
+		attachments := core.Attachments(a.connection)
+		if len(attachments) == 0 {
+			fmt.Println(a.i18nMgr.Get("no_attachments"))
+			return nil
+		}
+		fmt.Println(a.i18nMgr.Get("attachments_header"))
+		for _, attachment := range attachments {
+			fmt.Printf("  %s  %s\n", attachment.Schema, attachment.Path)
+		}
+		return nil
+	}
+
+	path, schema, ok := parseAttachArgs(args)
+	if !ok {
+		fmt.Println(a.i18nMgr.Get("usage_attach"))
+		return nil
+	}
+	// ATTACH creates missing files, which would hide a mistyped path
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_attach"), err)
+	}
+	if err := core.Attach(a.connection, path, schema); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_attach"), err)
+	}
+	fmt.Printf(a.i18nMgr.Get("database_attached"), path, schema, schema)
+	return nil
+}
+
+// parseAttachArgs reads "file AS schema" or "file schema"; a lone file is attached under
+// its base name without the extension
+func parseAttachArgs(args []string) (path, schema string, ok bool) {
+	switch {
+	case len(args) == 1:
+		path = args[0]
+		schema = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	case len(args) == 2:
+		path, schema = args[0], args[1]
+	case len(args) == 3 && strings.EqualFold(args[1], "AS"):
+		path, schema = args[0], args[2]
+	default:
+		return "", "", false
+	}
+	return path, schema, schema != ""
+}
@@ -0,0 +1,87 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+	"sqlterm/internal/session"
+)
+
+// openAuditLog opens the audit log set up under audit: in a profile's config.yaml, or
+// returns nil when statements are not audited
+func openAuditLog(aiManager *ai.Manager, configDir string) (*session.AuditLog, error) {
+	if aiManager == nil || aiManager.GetConfig() == nil {
+		return nil, nil
+	}
+	audit := aiManager.GetConfig().Audit
+	if !audit.Enabled() {
+		return nil, nil
+	}
+	return session.OpenAuditLog(audit.LogPath(configDir), audit.Syslog)
+}
+
+// auditConfig returns the audit settings of the current profile
+func (a *App) auditConfig() config.AuditConfig {
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.Audit
+		}
+	}
+	return config.AuditConfig{}
+}
+
+// audit records an executed statement in the audit log. The statement has already run,
+// so a failure to record it is reported rather than returned.
+func (a *App) audit(query string, start time.Time, rows int, err error) {
+	approved := a.approved
+	a.approved = nil
+	a.auditOn(a.config, approved, query, start, rows, err)
+}
+
+// auditOn records a statement run on the given connection, which background jobs
+// need as the active connection may have changed by the time they finish
+func (a *App) auditOn(conn *core.ConnectionConfig, approved *approvedStatement, query string, start time.Time, rows int, err error) {
+	if a.auditLog == nil || conn == nil {
+		return
+	}
+	if a.auditConfig().ProductionOnly && !conn.IsProduction() {
+		return
+	}
+
+	entry := session.AuditEntry{
+		Time:       start,
+		User:       auditUser(),
+		Connection: conn.Name,
+		DBUser:     conn.Username,
+		Database:   conn.Database,
+		Statement:  query,
+		Rows:       rows,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	entry.Host, _ = os.Hostname()
+	if approved != nil {
+		entry.Approver, entry.Approval = approved.approver, approved.id
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if err := a.auditLog.Record(entry); err != nil {
+		fmt.Printf(a.i18nMgr.Get("audit_log_warning"), err)
+	}
+}
+
+// auditUser returns the name of the operating system user running sqlterm
+func auditUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	if name := os.Getenv("USER"); name != "" {
+		return name
+	}
+	return os.Getenv("USERNAME")
+}
@@ -16,6 +16,10 @@ func NewAutoCompleter(app *App) *AutoCompleter {
 
 func (ac *AutoCompleter) Do(line []rune, pos int) (newLine [][]rune, length int) {
 	lineStr := string(line)
+	if strings.HasPrefix(lineStr, "/") && strings.Contains(lineStr, " ") {
+		// Arguments after an alias complete like those of the command it stands for
+		lineStr = ac.app.expandAlias(lineStr)
+	}
 	words := strings.Fields(lineStr)
 
 	if len(words) == 0 {
@@ -27,21 +31,17 @@ func (ac *AutoCompleter) Do(line []rune, pos int) (newLine [][]rune, length int)
 	var completionLength int
 
 	switch {
-	case strings.HasPrefix(lineStr, "/connect ") && len(words) > 1:
-		candidates = ac.getConnectionCandidates(words, lineStr)
-		completionLength = ac.getCompletionLength(lineStr)
-	case strings.HasPrefix(lineStr, "/describe ") && len(words) > 1:
-		candidates = ac.getTableCandidates(words, lineStr)
-		completionLength = ac.getCompletionLength(lineStr)
-	case strings.HasPrefix(lineStr, "/config "):
-		candidates = ac.getConfigCandidates(words, lineStr)
+	case strings.HasPrefix(lineStr, "/") && strings.Contains(lineStr, " "):
+		// Arguments of a command complete the way the command registers
+		cmd, ok := lookupCommand(words[0])
+		if !ok || cmd.complete == nil {
+			return nil, 0
+		}
+		candidates = cmd.complete(ac, words, lineStr)
 		completionLength = ac.getCompletionLength(lineStr)
 	case strings.HasPrefix(lineStr, "@"):
 		candidates = ac.getFileCandidates(lineStr)
 		completionLength = ac.getCompletionLength(lineStr)
-	case strings.HasPrefix(lineStr, "/exec ") && strings.Contains(lineStr, " > "):
-		candidates = ac.getCSVCandidates(words, lineStr)
-		completionLength = ac.getCompletionLength(lineStr)
 	case strings.Contains(lineStr, " > ") && !strings.HasPrefix(lineStr, "/"):
 		candidates = ac.getCSVCandidates(words, lineStr)
 		completionLength = ac.getCompletionLength(lineStr)
@@ -57,19 +57,19 @@ func (ac *AutoCompleter) Do(line []rune, pos int) (newLine [][]rune, length int)
 }
 
 func (ac *AutoCompleter) getCommands() [][]rune {
-	commands := []string{
-		"/help", "/quit", "/exit", "/connect", "/list-connections",
-		"/tables", "/describe", "/status", "/exec", "/config",
-		"/prompts", "/clear-conversation",
-	}
-
-	result := make([][]rune, len(commands))
-	for i, cmd := range commands {
-		result[i] = []rune(cmd)
+	names := ac.commandNames()
+	result := make([][]rune, len(names))
+	for i, name := range names {
+		result[i] = []rune(name)
 	}
 	return result
 }
 
+// commandNames lists the built-in commands followed by the user's aliases
+func (ac *AutoCompleter) commandNames() []string {
+	return append(commandNames(), ac.app.aliasNames()...)
+}
+
 // processCompletions handles intelligent completion with common prefix
 func (ac *AutoCompleter) processCompletions(candidates []string, typedLength int) [][]rune {
 	if len(candidates) == 0 {
@@ -140,14 +140,8 @@ func (ac *AutoCompleter) findCommonPrefix(candidates []string) string {
 
 // New candidate-getting functions that return full matches for intelligent processing
 func (ac *AutoCompleter) getCommandCandidates(partial string) []string {
-	commands := []string{
-		"/help", "/quit", "/exit", "/connect", "/list-connections",
-		"/tables", "/describe", "/status", "/exec", "/config",
-		"/prompts", "/clear-conversation",
-	}
-
 	var candidates []string
-	for _, cmd := range commands {
+	for _, cmd := range ac.commandNames() {
 		if strings.HasPrefix(cmd, partial) {
 			// Return the completion part (what should be appended)
 			completion := cmd[len(partial):]
@@ -185,6 +179,42 @@ func (ac *AutoCompleter) getConnectionCandidates(words []string, line string) []
 	return candidates
 }
 
+// getConnectionCommandCandidates completes /connection subcommands and saved connection names
+func (ac *AutoCompleter) getConnectionCommandCandidates(words []string, line string) []string {
+	currentWord := ""
+	if !strings.HasSuffix(line, " ") {
+		currentWord = words[len(words)-1]
+	}
+	position := len(words)
+	if currentWord != "" {
+		position--
+	}
+
+	var options []string
+	switch {
+	case position == 1:
+		options = []string{"add", "remove", "rename", "show", "copy", "edit", "tag", "untag"}
+	case position == 2 && words[1] != "":
+		connections, err := ac.app.configMgr.ListConnections()
+		if err != nil {
+			return nil
+		}
+		for _, conn := range connections {
+			options = append(options, conn.Name)
+		}
+	case strings.HasPrefix(currentWord, "--") && words[1] == "copy":
+		options = []string{"--read-only", "--database", "--host", "--port", "--username"}
+	}
+
+	var candidates []string
+	for _, option := range options {
+		if strings.HasPrefix(option, currentWord) {
+			candidates = append(candidates, option[len(currentWord):])
+		}
+	}
+	return candidates
+}
+
 func (ac *AutoCompleter) getTableCandidates(words []string, line string) []string {
 	if len(words) < 2 || ac.app.connection == nil {
 		return nil
@@ -221,6 +251,7 @@ func (ac *AutoCompleter) getFileCandidates(line string) []string {
 	// If path is empty, show all .sql files and directories from current directory
 	if path == "" {
 		ac.addRecursiveFileCandidates(&candidates, ".", "", "")
+		ac.addLibraryCandidates(&candidates, path)
 		return candidates
 	}
 
@@ -250,6 +281,7 @@ func (ac *AutoCompleter) getFileCandidates(line string) []string {
 		ac.addRecursiveFileCandidates(&candidates, ".", baseName, "")
 	}
 
+	ac.addLibraryCandidates(&candidates, path)
 	return candidates
 }
 
@@ -462,7 +494,7 @@ func (ac *AutoCompleter) getConfigCandidates(words []string, line string) []stri
 
 	// Main config sections
 	if len(words) == 2 {
-		sections := []string{"ai", "language"}
+		sections := []string{"ai", "language", "display", "repl", "integrations", "secrets", "safety"}
 		var candidates []string
 		currentWord := words[1]
 		for _, section := range sections {
@@ -479,7 +511,7 @@ func (ac *AutoCompleter) getConfigCandidates(words []string, line string) []stri
 	case "ai":
 		if len(words) == 3 {
 			// AI subcommands
-			subcommands := []string{"provider", "model", "api-key", "base-url", "status", "list-models", "openrouter"}
+			subcommands := []string{"provider", "model", "api-key", "base-url", "status", "list-models", "models", "pull", "openrouter", "self-correct", "fallback", "budget", "confirm"}
 			var candidates []string
 			currentWord := words[2]
 			for _, subcmd := range subcommands {
@@ -539,6 +571,71 @@ func (ac *AutoCompleter) getConfigCandidates(words []string, line string) []stri
 				}
 			}
 		}
+	case "display":
+		if len(words) == 3 {
+			settings := []string{"status", "bbox", "timezone"}
+			var candidates []string
+			currentWord := words[2]
+			for _, setting := range settings {
+				if strings.HasPrefix(setting, currentWord) {
+					completion := setting[len(currentWord):]
+					candidates = append(candidates, completion)
+				}
+			}
+			return candidates
+		}
+	case "repl":
+		if len(words) == 3 {
+			settings := []string{"status", "bare-sql", "queries-dir"}
+			var candidates []string
+			currentWord := words[2]
+			for _, setting := range settings {
+				if strings.HasPrefix(setting, currentWord) {
+					completion := setting[len(currentWord):]
+					candidates = append(candidates, completion)
+				}
+			}
+			return candidates
+		}
+	case "integrations":
+		if len(words) == 3 {
+			settings := []string{"status", "sheets", "smtp"}
+			var candidates []string
+			currentWord := words[2]
+			for _, setting := range settings {
+				if strings.HasPrefix(setting, currentWord) {
+					completion := setting[len(currentWord):]
+					candidates = append(candidates, completion)
+				}
+			}
+			return candidates
+		}
+	case "safety":
+		if len(words) == 3 {
+			settings := []string{"status", "pre-image"}
+			var candidates []string
+			currentWord := words[2]
+			for _, setting := range settings {
+				if strings.HasPrefix(setting, currentWord) {
+					completion := setting[len(currentWord):]
+					candidates = append(candidates, completion)
+				}
+			}
+			return candidates
+		}
+	case "secrets":
+		if len(words) == 3 {
+			actions := []string{"status", "encrypt", "decrypt"}
+			var candidates []string
+			currentWord := words[2]
+			for _, action := range actions {
+				if strings.HasPrefix(action, currentWord) {
+					completion := action[len(currentWord):]
+					candidates = append(candidates, completion)
+				}
+			}
+			return candidates
+		}
 	case "language":
 		if len(words) == 3 {
 			languages := []string{"en_au", "zh_cn"}
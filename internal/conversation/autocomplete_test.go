@@ -3,6 +3,7 @@ package conversation
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"sqlterm/internal/core"
@@ -64,17 +65,17 @@ func TestAutoCompleter_getCommandCandidates(t *testing.T) {
 		{
 			name:     "Help command prefix",
 			partial:  "/h",
-			expected: []string{"elp"},
+			expected: []string{"elp", "ist", "istory"},
 		},
 		{
 			name:     "Connect command prefix",
 			partial:  "/con",
-			expected: []string{"nect", "fig"},
+			expected: []string{"nect", "fig", "nection"},
 		},
 		{
 			name:     "Multiple matches",
 			partial:  "/",
-			expected: []string{"help", "quit", "exit", "connect", "list-connections", "tables", "describe", "status", "exec", "config", "prompts", "clear-conversation"},
+			expected: []string{"help", "quit", "exit", "connect", "list-connections", "tables", "describe", "status", "exec", "config", "prompts", "last-ai-call", "clear-conversation", "json", "attach", "assert", "checks", "sample", "profile", "use-profile", "hist", "dashboard", "set", "scratch", "export-session", "queries", "jobs", "connection", "test", "ping", "plan", "slow", "lineage", "activity", "kill", "locks", "diff-data", "verify", "copy-table", "chunked", "bench", "migrate", "undo", "ddl", "schema", "lang", "phase", "load-schema", "glossary", "sensitive", "unmask", "ai", "usage", "reindex", "alias", "drafts", "history", "columns"},
 		},
 		{
 			name:     "No matches",
@@ -122,11 +123,17 @@ func TestAutoCompleter_getConfigCandidates(t *testing.T) {
 			line:     "/config a",
 			expected: []string{"i"},
 		},
+		{
+			name:     "REPL settings",
+			words:    []string{"/config", "repl", "b"},
+			line:     "/config repl b",
+			expected: []string{"are-sql"},
+		},
 		{
 			name:     "AI subcommands",
 			words:    []string{"/config", "ai", "p"},
 			line:     "/config ai p",
-			expected: []string{"rovider"},
+			expected: []string{"rovider", "ull"},
 		},
 		{
 			name:     "AI provider candidates",
@@ -609,12 +616,12 @@ func TestAutoCompleter_Do(t *testing.T) {
 			name:        "Empty line",
 			line:        "",
 			pos:         0,
-			expectCount: 12, // Number of commands
+			expectCount: 58, // Number of commands
 		},
 		{
 			name:        "Command completion",
-			line:        "/h",
-			pos:         2,
+			line:        "/he",
+			pos:         3,
 			expectCount: 1,
 		},
 		{
@@ -682,3 +689,44 @@ func BenchmarkAutoCompleter_processCompletions(b *testing.B) {
 		ac.processCompletions(candidates, 2)
 	}
 }
+
+func TestAutoCompleter_Aliases(t *testing.T) {
+	app := createTestApp(t)
+	if app.aiManager == nil {
+		t.Skip("AI manager unavailable")
+	}
+	app.aiManager.GetConfig().Aliases = map[string]string{
+		"/d":     "/describe",
+		"t":      "/tables",
+		"/cfg":   "/config ai",
+		"/help2": "/help",
+	}
+	ac := NewAutoCompleter(app)
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"/d users", "/describe users"},
+		{"/t", "/tables"},
+		{"/cfg model x", "/config ai model x"},
+		{"/d ", "/describe "},
+		{"/describe users", "/describe users"},
+		{"/unknown x", "/unknown x"},
+	}
+	for _, tt := range tests {
+		if got := app.expandAlias(tt.line); got != tt.want {
+			t.Errorf("expandAlias(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	// Aliases are offered after the built-in commands
+	if got := ac.getCommandCandidates("/c"); !slices.Contains(got, "fg") {
+		t.Errorf("Expected /cfg among the completions, got %v", got)
+	}
+	// and their arguments complete like the command they stand for
+	newLine, _ := ac.Do([]rune("/cfg pro"), len("/cfg pro"))
+	if len(newLine) != 1 || string(newLine[0]) != "vider" {
+		t.Errorf("Expected /cfg pro to complete provider, got %q", newLine)
+	}
+}
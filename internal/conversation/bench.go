@@ -0,0 +1,226 @@
+package conversation
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// benchListLimit is how many of the latest benchmarks /bench list shows
+const benchListLimit = 20
+
+// parseBenchArgs reads `<runs> [--warmup n] [--concurrency n] <query>`
+func parseBenchArgs(args []string) (core.BenchOptions, string, error) {
+	var opts core.BenchOptions
+	if len(args) < 2 {
+		return opts, "", errors.New("expected a number of runs and a query")
+	}
+	runs, err := strconv.Atoi(args[0])
+	if err != nil || runs <= 0 {
+		return opts, "", fmt.Errorf("expected a positive number of runs, got %s", args[0])
+	}
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	warmup := fs.Int("warmup", 0, "")
+	concurrency := fs.Int("concurrency", 1, "")
+	// The query starts at the first argument that is not a flag
+	if err := fs.Parse(args[1:]); err != nil {
+		return opts, "", err
+	}
+	if *warmup < 0 || *concurrency <= 0 {
+		return opts, "", errors.New("warmup must not be negative and concurrency must be positive")
+	}
+	query := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.Join(fs.Args(), " ")), ";"))
+	if query == "" {
+		return opts, "", errors.New("expected a query")
+	}
+
+	opts.Runs, opts.Warmup, opts.Concurrency = runs, *warmup, *concurrency
+	return opts, query, nil
+}
+
+// handleBench runs a query repeatedly and reports its latencies, or lists and compares
+// the benchmarks saved for the connection
+func (a *App) handleBench(args []string) error {
+	if len(args) == 0 {
+		fmt.Println(a.i18nMgr.Get("usage_bench"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	switch args[0] {
+	case "list":
+		return a.handleBenchList()
+	case "compare":
+		return a.handleBenchCompare(args[1:])
+	}
+
+	opts, query, err := parseBenchArgs(args)
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_bench"))
+		return err
+	}
+	if query, err = core.ExpandVariables(query, a.vars); err != nil {
+		return err
+	}
+	// Running a change over and over is never what a benchmark means
+	if !core.IsReadOnlyQuery(query) {
+		return errors.New(a.i18nMgr.Get("bench_needs_read_query"))
+	}
+	if err := a.enforceConnectionPolicy(query); err != nil {
+		return err
+	}
+
+	fmt.Println(a.i18nMgr.GetWithArgs("bench_starting", opts.Runs, opts.Warmup, opts.Concurrency))
+	result, err := core.RunBenchmark(a.connection, query, opts, func(done int) {
+		fmt.Printf("\r⏳ %s", a.i18nMgr.GetWithArgs("bench_progress", done, opts.Runs))
+	})
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("bench_failed"), err)
+	}
+	result.Connection = a.config.Name
+
+	number := 0
+	if err := a.sessionMgr.SaveBenchmark(result); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	} else if saved, err := a.sessionMgr.LoadBenchmarks(a.config.Name); err == nil {
+		number = len(saved)
+	}
+	return a.displayMarkdown(a.formatBenchResult(number, result))
+}
+
+func (a *App) formatBenchResult(number int, result *core.BenchResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# ⏱️ %s\n\n", a.i18nMgr.GetWithArgs("bench_title", number)))
+	sb.WriteString(fmt.Sprintf("```sql\n%s\n```\n\n", result.Query))
+	sb.WriteString(a.i18nMgr.Get("bench_table_header") + "\n")
+	sb.WriteString("|---:|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+	sb.WriteString(fmt.Sprintf("| %d | %d | %s | %s | %s | %s | %s | %s | %s |\n\n",
+		result.Runs, result.Concurrency, formatBenchDuration(result.Min), formatBenchDuration(result.Avg),
+		formatBenchDuration(result.P50), formatBenchDuration(result.P95), formatBenchDuration(result.Max),
+		formatRowCount(float64(result.Rows)), formatRowCount(result.RowsPerSecond)))
+	if number > 1 {
+		sb.WriteString(a.i18nMgr.GetWithArgs("bench_compare_hint", number-1, number) + "\n")
+	}
+	return sb.String()
+}
+
+// handleBenchList shows the latest benchmarks saved for the connection with their numbers
+func (a *App) handleBenchList() error {
+	results, err := a.sessionMgr.LoadBenchmarks(a.config.Name)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println(a.i18nMgr.Get("bench_none"))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# ⏱️ %s\n\n", a.i18nMgr.GetWithArgs("bench_list_title", a.config.Name)))
+	sb.WriteString(a.i18nMgr.Get("bench_list_header") + "\n")
+	sb.WriteString("|---:|---|---:|---:|---:|---|\n")
+	first := max(len(results)-benchListLimit, 0)
+	for i := first; i < len(results); i++ {
+		result := results[i]
+		query := strings.ReplaceAll(a.truncateQuery(strings.Join(strings.Fields(result.Query), " ")), "|", `\|`)
+		sb.WriteString(fmt.Sprintf("| %d | %s | %d | %s | %s | `%s` |\n", i+1, result.Started.Local().Format("2006-01-02 15:04"),
+			result.Runs, formatBenchDuration(result.Avg), formatBenchDuration(result.P95), strings.ReplaceAll(query, "`", "'")))
+	}
+	return a.displayMarkdown(sb.String())
+}
+
+// handleBenchCompare sets two saved benchmarks side by side: the given numbers, or the
+// latest two
+func (a *App) handleBenchCompare(args []string) error {
+	results, err := a.sessionMgr.LoadBenchmarks(a.config.Name)
+	if err != nil {
+		return err
+	}
+	var first, second int
+	switch len(args) {
+	case 0:
+		first, second = len(results)-1, len(results)
+	case 2:
+		first, _ = strconv.Atoi(args[0])
+		second, _ = strconv.Atoi(args[1])
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_bench"))
+		return nil
+	}
+	if len(results) < 2 && len(args) == 0 {
+		fmt.Println(a.i18nMgr.Get("bench_compare_needs_two"))
+		return nil
+	}
+	for _, n := range []int{first, second} {
+		if n < 1 || n > len(results) {
+			return errors.New(a.i18nMgr.GetWithArgs("bench_not_found", n, len(results)))
+		}
+	}
+	return a.displayMarkdown(a.formatBenchComparison(first, &results[first-1], second, &results[second-1]))
+}
+
+func (a *App) formatBenchComparison(firstNumber int, first *core.BenchResult, secondNumber int, second *core.BenchResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# ⚖️ %s\n\n", a.i18nMgr.GetWithArgs("bench_compare_title", firstNumber, secondNumber)))
+	sb.WriteString(fmt.Sprintf("**#%d**\n\n```sql\n%s\n```\n\n**#%d**\n\n```sql\n%s\n```\n\n", firstNumber, first.Query, secondNumber, second.Query))
+	sb.WriteString(a.i18nMgr.GetWithArgs("bench_compare_header", firstNumber, secondNumber) + "\n")
+	sb.WriteString("|---|---:|---:|---:|\n")
+	for _, row := range []struct {
+		name          string
+		before, after time.Duration
+	}{
+		{"min", first.Min, second.Min},
+		{"avg", first.Avg, second.Avg},
+		{"p50", first.P50, second.P50},
+		{"p95", first.P95, second.P95},
+		{"max", first.Max, second.Max},
+	} {
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s |\n", row.name, formatBenchDuration(row.before), formatBenchDuration(row.after),
+			formatChange(float64(row.before), float64(row.after))))
+	}
+	sb.WriteString(fmt.Sprintf("| rows/s | %s | %s | %s |\n\n", formatRowCount(first.RowsPerSecond), formatRowCount(second.RowsPerSecond),
+		formatChange(first.RowsPerSecond, second.RowsPerSecond)))
+
+	if first.Avg > 0 && second.Avg > 0 {
+		if second.Avg <= first.Avg {
+			sb.WriteString(a.i18nMgr.GetWithArgs("bench_faster", secondNumber, float64(first.Avg)/float64(second.Avg), firstNumber) + "\n")
+		} else {
+			sb.WriteString(a.i18nMgr.GetWithArgs("bench_slower", secondNumber, float64(second.Avg)/float64(first.Avg), firstNumber) + "\n")
+		}
+	}
+	if first.Runs != second.Runs || first.Concurrency != second.Concurrency {
+		sb.WriteString("\n" + a.i18nMgr.Get("bench_compare_unlike") + "\n")
+	}
+	return sb.String()
+}
+
+// formatBenchDuration rounds a latency to a precision that suits its size
+func formatBenchDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// formatChange shows how after differs from before, as a signed percentage
+func formatChange(before, after float64) string {
+	if before == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", (after-before)*100/before)
+}
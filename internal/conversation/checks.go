@@ -0,0 +1,58 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"sqlterm/internal/core"
+)
+
+// errChecksFailed is returned by /checks run when a check fails, so sqlterm run exits
+// with an error
+var errChecksFailed = errors.New("data quality checks failed")
+
+// checksPath returns the checks file of the active connection
+func (a *App) checksPath() string {
+	return filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), core.ChecksFile)
+}
+
+// handleChecks runs the data quality checks: /checks run [file], with the connection's
+// checks.yaml by default. The report is saved as markdown next to query results.
+func (a *App) handleChecks(args []string) error {
+	if len(args) == 0 || args[0] != "run" || len(args) > 2 {
+		fmt.Println(a.i18nMgr.Get("usage_checks"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	path := a.checksPath()
+	if len(args) == 2 {
+		path = args[1]
+	}
+	suite, err := core.LoadChecks(path)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_checks"), path, err)
+	}
+	if len(suite.Checks) == 0 {
+		fmt.Printf(a.i18nMgr.Get("checks_empty"), path)
+		return nil
+	}
+
+	results := core.RunChecks(a.connection, a.config.DatabaseType, suite)
+	report := core.FormatChecksReport(results, a.config.Name, a.i18nMgr)
+
+	if err := a.showReport("checks", report); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if !result.Passed {
+			return errChecksFailed
+		}
+	}
+	return nil
+}
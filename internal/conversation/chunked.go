@@ -0,0 +1,188 @@
+package conversation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// chunkedArgs holds the parsed /chunked arguments
+type chunkedArgs struct {
+	statement       string
+	key             string
+	batch           int
+	sleep           time.Duration
+	resume, restart bool
+}
+
+// chunkedFlags end the statement of a /chunked command line
+var chunkedFlags = []string{"--batch", "--sleep", "--key", "--resume", "--restart"}
+
+// parseChunkedArgs reads `<UPDATE or DELETE> [--batch n] [--sleep 500ms] [--key id]
+// [--resume|--restart]`, where the statement runs up to the first of the flags
+func parseChunkedArgs(args []string) (chunkedArgs, error) {
+	var parsed chunkedArgs
+
+	split := len(args)
+	for i, arg := range args {
+		name, _, _ := strings.Cut(arg, "=")
+		if slices.Contains(chunkedFlags, name) {
+			split = i
+			break
+		}
+	}
+	parsed.statement = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(strings.Join(args[:split], " ")), ";"))
+	if parsed.statement == "" {
+		return parsed, errors.New("expected an UPDATE or DELETE statement")
+	}
+
+	fs := flag.NewFlagSet("chunked", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	key := fs.String("key", "", "")
+	batch := fs.Int("batch", core.DefaultChunkSize, "")
+	sleep := fs.Duration("sleep", 0, "")
+	resume := fs.Bool("resume", false, "")
+	restart := fs.Bool("restart", false, "")
+	if err := fs.Parse(args[split:]); err != nil {
+		return parsed, err
+	}
+	if fs.NArg() > 0 {
+		return parsed, fmt.Errorf("unexpected argument %s after the flags", fs.Arg(0))
+	}
+	if *batch <= 0 {
+		return parsed, fmt.Errorf("batch size must be positive")
+	}
+	if *sleep < 0 {
+		return parsed, fmt.Errorf("sleep must not be negative")
+	}
+
+	parsed.key = *key
+	parsed.batch = *batch
+	parsed.sleep = *sleep
+	parsed.resume, parsed.restart = *resume, *restart
+	return parsed, nil
+}
+
+// chunkedCheckpointPath is where a chunked statement on the current connection keeps its
+// progress, named after its table and a hash of the statement
+func (a *App) chunkedCheckpointPath(table, statement string) string {
+	sum := sha256.Sum256([]byte(statement))
+	name := checkpointNameChars.ReplaceAllString(table, "_") + "-" + hex.EncodeToString(sum[:6])
+	return filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), "chunked", name+".json")
+}
+
+// handleChunked runs a large UPDATE or DELETE as a series of statements over consecutive
+// key ranges, pausing between them, so no single statement locks the table for long.
+// Progress is checkpointed after every chunk, so an interrupted run picks up after the
+// last chunk it finished.
+func (a *App) handleChunked(args []string) error {
+	parsed, err := parseChunkedArgs(args)
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_chunked"))
+		return err
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	// Inside a transaction the chunks would hold their locks until the COMMIT anyway
+	if a.inTransaction {
+		return errors.New(a.i18nMgr.Get("chunked_in_transaction"))
+	}
+	statement, err := core.ExpandVariables(parsed.statement, a.vars)
+	if err != nil {
+		return err
+	}
+	m, ok := core.ParseModification(statement)
+	if !ok {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_run_chunked"), core.ErrNotChunkable)
+	}
+
+	opts := core.ChunkOptions{Key: parsed.key, BatchSize: parsed.batch, Sleep: parsed.sleep}
+	if opts.Key == "" {
+		if info, err := a.connection.DescribeTable(m.Table); err == nil && len(info.PrimaryKeys) == 1 {
+			opts.Key = info.PrimaryKeys[0]
+		}
+	}
+	if opts.Key == "" {
+		return errors.New(a.i18nMgr.GetWithArgs("chunked_needs_key", m.Table))
+	}
+
+	checkpointPath := a.chunkedCheckpointPath(m.Name, statement)
+	var previous int64
+	var previousChunks int
+	if checkpoint, err := core.LoadChunkCheckpoint(checkpointPath); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	} else if checkpoint != nil && checkpoint.Matches(statement, opts.Key) && !parsed.restart &&
+		(parsed.resume || a.confirm(a.i18nMgr.GetWithArgs("chunked_resume_question", checkpoint.Chunks, checkpoint.Rows, checkpoint.LastKey, checkpoint.Updated.Local().Format("2006-01-02 15:04")))) {
+		opts.After, previous, previousChunks = checkpoint.LastKey, checkpoint.Rows, checkpoint.Chunks
+	}
+
+	total, err := core.CountChunkedRows(a.connection, statement, opts)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_run_chunked"), err)
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("chunked_starting", m.Kind, m.Table, total, opts.BatchSize, opts.Key, opts.Sleep))
+	if previous > 0 {
+		fmt.Println(a.i18nMgr.GetWithArgs("chunked_resuming", previous, opts.After))
+	}
+	if total == 0 {
+		if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+			fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+		}
+		fmt.Println(a.i18nMgr.Get("chunked_nothing_to_do"))
+		return nil
+	}
+	if err := a.enforceConnectionPolicy(statement); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	saved := false
+	changed, err := core.RunChunked(a.connection, statement, opts, func(p core.ChunkProgress) error {
+		rate := formatRowCount(float64(p.Rows) / math.Max(time.Since(started).Seconds(), 0.001))
+		fmt.Printf("\r⏳ %s", a.i18nMgr.GetWithArgs("chunked_progress", previousChunks+p.Chunks, formatRowCount(float64(p.Rows)), formatRowCount(float64(total)),
+			math.Min(float64(p.Rows)*100/float64(total), 100), rate))
+		if p.LastKey == "" {
+			return nil
+		}
+		checkpoint := &core.ChunkCheckpoint{
+			Statement: statement,
+			Key:       opts.Key,
+			LastKey:   p.LastKey,
+			Rows:      previous + p.Rows,
+			Chunks:    previousChunks + p.Chunks,
+			Updated:   time.Now(),
+		}
+		if err := core.SaveChunkCheckpoint(checkpointPath, checkpoint); err != nil {
+			return err
+		}
+		saved = true
+		return nil
+	})
+	fmt.Println()
+	a.logQuery(statement, started, int(changed), err)
+	if err != nil {
+		if saved {
+			fmt.Println(a.i18nMgr.GetWithArgs("chunked_interrupted", previous+changed))
+		}
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_run_chunked"), err)
+	}
+
+	if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("chunked_done", changed, m.Table, time.Since(started).Round(time.Millisecond)))
+	return nil
+}
@@ -0,0 +1,86 @@
+package conversation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// resultColumns is the column metadata of a finished result, for /columns
+type resultColumns struct {
+	query    string
+	rows     int
+	columns  []core.Column
+	observed []core.ObservedColumn
+}
+
+// rememberColumns keeps the metadata of a result once its rows have been read
+func (a *App) rememberColumns(query string, result *core.QueryResult) {
+	if result.Error() != nil || len(result.Columns) == 0 {
+		return
+	}
+	a.lastColumns = &resultColumns{
+		query:    query,
+		rows:     result.RowCount(),
+		columns:  result.Columns,
+		observed: result.Observed(),
+	}
+}
+
+// handleColumns shows the column metadata of the last result: the database's type name,
+// the Go type the driver scans into, nullability, declared sizes, and the widest value
+// and NULLs seen in the rows fetched
+func (a *App) handleColumns(args []string) error {
+	if len(args) > 0 {
+		fmt.Println(a.i18nMgr.Get("usage_columns"))
+		return nil
+	}
+	if a.lastColumns == nil {
+		fmt.Println(a.i18nMgr.Get("no_result_columns"))
+		return nil
+	}
+	return a.displayMarkdown(a.formatResultColumns(a.lastColumns))
+}
+
+func (a *App) formatResultColumns(last *resultColumns) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🧾 %s\n\n", a.i18nMgr.GetWithArgs("result_columns_title", len(last.columns), last.rows)))
+	sb.WriteString(fmt.Sprintf("```sql\n%s\n```\n\n", strings.TrimSpace(last.query)))
+	sb.WriteString(a.i18nMgr.Get("columns_table_header") + "\n")
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for i, column := range last.columns {
+		var observed core.ObservedColumn
+		if i < len(last.observed) {
+			observed = last.observed[i]
+		}
+		size := ""
+		switch {
+		case column.Precision > 0:
+			size = fmt.Sprintf("%d,%d", column.Precision, column.Scale)
+		case column.Length > 0:
+			size = strconv.FormatInt(column.Length, 10)
+		}
+		nullable := "?"
+		if column.Nullable != nil {
+			nullable = a.columnFlag(*column.Nullable)
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s | %s | %s | %d | %s |\n",
+			i+1, escapeColumnCell(column.Name), escapeColumnCell(column.Type), column.ScanType,
+			nullable, size, observed.MaxWidth, a.columnFlag(observed.Nulls)))
+	}
+	return sb.String()
+}
+
+// columnFlag shows a yes or no in the /columns table
+func (a *App) columnFlag(set bool) string {
+	if set {
+		return a.i18nMgr.Get("columns_yes")
+	}
+	return a.i18nMgr.Get("columns_no")
+}
+
+func escapeColumnCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
@@ -0,0 +1,168 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// command is a slash command. The registry below drives dispatch, autocomplete and
+// /help, so a command added here is available everywhere at once.
+type command struct {
+	name     string   // Including the slash
+	aliases  []string // Other names that run the same handler
+	run      func(a *App, args []string) error
+	help     func(a *App, args []string) error // Detailed /help <name>; nil shows the command's lines of the general help
+	complete func(ac *AutoCompleter, words []string, line string) []string
+}
+
+// commands lists the slash commands in the order autocomplete offers them
+var commands []*command
+
+// commandIndex maps every command name and alias to its command
+var commandIndex map[string]*command
+
+// The registry is filled in init because handlers such as /help read it back
+func init() {
+	commands = []*command{
+		{name: "/help", run: (*App).handleHelp},
+		{name: "/quit", aliases: []string{"/exit"}, run: func(a *App, _ []string) error {
+			a.finishSessionStats()
+			os.Exit(0)
+			return nil
+		}},
+		{name: "/connect", run: (*App).handleConnect, help: helpWithoutArgs((*App).printConnectHelp),
+			complete: completeConnections},
+		{name: "/list-connections", run: (*App).handleListConnections},
+		{name: "/tables", run: func(a *App, _ []string) error { return a.handleListTables() },
+			help: helpWithoutArgs((*App).printTablesHelp)},
+		{name: "/describe", run: (*App).handleDescribeTable, help: helpWithoutArgs((*App).printDescribeHelp),
+			complete: completeTables},
+		{name: "/status", run: func(a *App, _ []string) error {
+			a.handleStatus()
+			return nil
+		}, help: helpWithoutArgs((*App).printStatusHelp)},
+		{name: "/exec", run: (*App).handleExecQuery, help: helpWithoutArgs((*App).printExecHelp),
+			complete: completeExportFile},
+		{name: "/config", run: (*App).handleConfig, help: (*App).printConfigHelp,
+			complete: (*AutoCompleter).getConfigCandidates},
+		{name: "/prompts", aliases: []string{"/last-ai-call"}, run: (*App).handleShowPrompts,
+			help: helpWithoutArgs((*App).printPromptsHelp)},
+		{name: "/clear-conversation", run: func(a *App, _ []string) error { return a.handleClearConversation() }},
+		{name: "/json", run: (*App).handleJSONStructure, complete: completeTables},
+		{name: "/attach", run: (*App).handleAttach},
+		{name: "/assert", run: (*App).handleAssert},
+		{name: "/checks", run: (*App).handleChecks},
+		{name: "/sample", run: (*App).handleSample, complete: completeTables},
+		{name: "/profile", run: (*App).handleProfile, complete: completeTables},
+		{name: "/use-profile", run: (*App).handleUseProfile, complete: completeProfileNames},
+		{name: "/hist", run: (*App).handleHistogram, complete: completeTables},
+		{name: "/dashboard", run: (*App).handleDashboard},
+		{name: "/set", run: (*App).handleSet},
+		{name: "/scratch", run: (*App).handleScratch},
+		{name: "/export-session", run: (*App).handleExportSession},
+		{name: "/queries", run: (*App).handleQueries},
+		{name: "/jobs", run: (*App).handleJobs},
+		{name: "/connection", run: (*App).handleConnection, help: helpWithoutArgs((*App).printConnectionHelp),
+			complete: (*AutoCompleter).getConnectionCommandCandidates},
+		{name: "/test", run: (*App).handleTestConnection, help: helpWithoutArgs((*App).printConnectionHelp),
+			complete: completeConnections},
+		{name: "/ping", run: (*App).handlePing, complete: completeConnections},
+		{name: "/plan", run: (*App).handlePlan},
+		{name: "/slow", run: (*App).handleSlowQueries},
+		{name: "/lineage", run: (*App).handleLineage},
+		{name: "/activity", run: (*App).handleActivity},
+		{name: "/kill", run: (*App).handleKill},
+		{name: "/locks", run: func(a *App, _ []string) error { return a.handleLocks() }},
+		{name: "/diff-data", run: (*App).handleDataDiff},
+		{name: "/verify", run: (*App).handleVerify},
+		{name: "/copy-table", run: (*App).handleCopyTable},
+		{name: "/chunked", run: (*App).handleChunked},
+		{name: "/bench", run: (*App).handleBench},
+		{name: "/migrate", run: (*App).handleMigrate},
+		{name: "/undo", run: (*App).handleUndo},
+		{name: "/ddl", run: (*App).handleDDL, complete: completeTables},
+		{name: "/schema", run: (*App).handleSchema},
+		{name: "/lang", run: (*App).handleAnswerLanguage},
+		{name: "/phase", run: (*App).handlePhase},
+		{name: "/load-schema", run: (*App).handleLoadSchema},
+		{name: "/glossary", run: func(a *App, _ []string) error { return a.handleGlossary() }},
+		{name: "/sensitive", run: (*App).handleSensitive},
+		{name: "/unmask", run: func(a *App, _ []string) error { return a.handleUnmask() }},
+		{name: "/ai", run: (*App).handleAICommand},
+		{name: "/usage", run: (*App).handleUsage},
+		{name: "/reindex", run: (*App).handleReindex},
+		{name: "/alias", run: (*App).handleAlias},
+		{name: "/drafts", run: (*App).handleDrafts},
+		{name: "/history", run: (*App).handleHistory},
+		{name: "/columns", run: (*App).handleColumns},
+	}
+
+	commandIndex = make(map[string]*command)
+	for _, cmd := range commands {
+		for _, name := range cmd.names() {
+			if _, ok := commandIndex[name]; ok {
+				panic("duplicate command name " + name)
+			}
+			commandIndex[name] = cmd
+		}
+	}
+}
+
+// names returns the command's name followed by its aliases
+func (c *command) names() []string {
+	return append([]string{c.name}, c.aliases...)
+}
+
+// lookupCommand finds a command by name or alias, with or without the slash
+func lookupCommand(name string) (*command, bool) {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	cmd, ok := commandIndex[name]
+	return cmd, ok
+}
+
+// commandNames lists every command name and alias in registry order
+func commandNames() []string {
+	var names []string
+	for _, cmd := range commands {
+		names = append(names, cmd.names()...)
+	}
+	return names
+}
+
+func helpWithoutArgs(print func(*App) error) func(*App, []string) error {
+	return func(a *App, _ []string) error { return print(a) }
+}
+
+// printCommandSummary shows the general help lines that document a command
+func (a *App) printCommandSummary(cmd *command) bool {
+	found := false
+	for _, line := range strings.Split(a.i18nMgr.Get("help_full"), "\n") {
+		for _, name := range cmd.names() {
+			if line == name || strings.HasPrefix(line, name+" ") || strings.HasPrefix(line, name+",") {
+				fmt.Println(line)
+				found = true
+				break
+			}
+		}
+	}
+	return found
+}
+
+func completeConnections(ac *AutoCompleter, words []string, line string) []string {
+	return ac.getConnectionCandidates(words, line)
+}
+
+func completeTables(ac *AutoCompleter, words []string, line string) []string {
+	return ac.getTableCandidates(words, line)
+}
+
+// completeExportFile completes the file after > in /exec
+func completeExportFile(ac *AutoCompleter, words []string, line string) []string {
+	if !strings.Contains(line, " > ") {
+		return nil
+	}
+	return ac.getCSVCandidates(words, line)
+}
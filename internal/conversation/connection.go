@@ -0,0 +1,616 @@
+package conversation
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+func (a *App) handleConnection(args []string) error {
+	if len(args) == 0 {
+		return a.printConnectionHelp()
+	}
+
+	switch args[0] {
+	case "add":
+		return a.handleConnectionAdd(args[1:])
+	case "remove":
+		return a.handleConnectionRemove(args[1:])
+	case "rename":
+		return a.handleConnectionRename(args[1:])
+	case "show":
+		return a.handleConnectionShow(args[1:])
+	case "copy":
+		return a.handleConnectionCopy(args[1:])
+	case "edit":
+		return a.handleConnectionEdit(args[1:])
+	case "tag":
+		return a.handleConnectionTag(args[1:])
+	case "untag":
+		return a.handleConnectionUntag(args[1:])
+	default:
+		fmt.Printf(a.i18nMgr.Get("unknown_connection_subcommand"), args[0])
+		return a.printConnectionHelp()
+	}
+}
+
+// parseConnectionOverrides reads --host/--port/--database/--username/--read-only flags,
+// returning the remaining positional arguments
+func parseConnectionOverrides(args []string) (config.ConnectionOverrides, []string, error) {
+	var overrides config.ConnectionOverrides
+
+	fs := flag.NewFlagSet("connection", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	host := fs.String("host", "", "")
+	port := fs.Int("port", 0, "")
+	database := fs.String("database", "", "")
+	username := fs.String("username", "", "")
+	readOnly := fs.Bool("read-only", false, "")
+
+	// Allow flags before and after positional arguments
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return overrides, nil, err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			overrides.Host = host
+		case "port":
+			overrides.Port = port
+		case "database":
+			overrides.Database = database
+		case "username":
+			overrides.Username = username
+		case "read-only":
+			overrides.ReadOnly = readOnly
+		}
+	})
+
+	return overrides, positional, nil
+}
+
+func (a *App) handleConnectionCopy(args []string) error {
+	overrides, positional, err := parseConnectionOverrides(args)
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("invalid_connection_flags", err))
+	}
+	if len(positional) != 2 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_copy"))
+		return nil
+	}
+
+	cfg, err := a.configMgr.CopyConnection(positional[0], positional[1], overrides)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_copy_connection"), err)
+	}
+
+	fmt.Printf(a.i18nMgr.Get("connection_copied"), positional[0], cfg.Name)
+	a.printConnectionSummary(cfg)
+	return nil
+}
+
+// handleConnectionAdd saves a new connection from prompts without connecting to it
+func (a *App) handleConnectionAdd(args []string) error {
+	if len(args) > 1 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_add"))
+		return nil
+	}
+
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	} else {
+		input, err := a.readInput(a.i18nMgr.Get("enter_connection_name"))
+		if err != nil {
+			return err
+		}
+		name = input
+	}
+	if err := config.ValidateConnectionName(name); err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("invalid_connection_name", err))
+	}
+	if a.configMgr.ConnectionExists(name) {
+		return errors.New(a.i18nMgr.GetWithArgs("connection_already_exists", name))
+	}
+
+	typeName, err := a.readInput(a.i18nMgr.Get("enter_database_type"))
+	if err != nil {
+		return err
+	}
+	if typeName == "" {
+		typeName = core.PostgreSQL.String()
+	}
+	dbType, err := core.ParseDatabaseType(typeName)
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("invalid_database_type", typeName))
+	}
+
+	cfg := &core.ConnectionConfig{Name: name, DatabaseType: dbType}
+	if !dbType.IsFileBased() {
+		cfg.Host = "localhost"
+		cfg.Port = core.GetDefaultPort(dbType)
+	}
+	if err := a.editConnectionFields(cfg); err != nil {
+		return err
+	}
+
+	if err := a.configMgr.SaveConnection(cfg); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_save_connection_warning"), err)
+	}
+	fmt.Printf(a.i18nMgr.Get("connection_added"), cfg.Name)
+	a.printConnectionSummary(cfg)
+	return nil
+}
+
+// handleConnectionRemove deletes a saved connection after confirmation. Its session
+// data is kept, so adding it again under the same name picks the history back up.
+func (a *App) handleConnectionRemove(args []string) error {
+	if len(args) != 1 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_remove"))
+		return nil
+	}
+
+	name := args[0]
+	if !a.configMgr.ConnectionExists(name) {
+		return errors.New(a.i18nMgr.GetWithArgs("connection_not_found", name))
+	}
+	if a.config != nil && a.config.Name == name {
+		return errors.New(a.i18nMgr.GetWithArgs("connection_in_use", name))
+	}
+	if !a.confirm(a.i18nMgr.GetWithArgs("confirm_remove_connection", name)) {
+		return nil
+	}
+
+	if err := a.configMgr.DeleteConnection(name); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_remove_connection"), err)
+	}
+	fmt.Printf(a.i18nMgr.Get("connection_removed"), name, a.sessionMgr.GetSessionDir(name))
+	return nil
+}
+
+// handleConnectionRename renames a saved connection along with its session data
+func (a *App) handleConnectionRename(args []string) error {
+	if len(args) != 2 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_rename"))
+		return nil
+	}
+
+	oldName, newName := args[0], args[1]
+	if a.config != nil && a.config.Name == oldName {
+		return errors.New(a.i18nMgr.GetWithArgs("connection_in_use", oldName))
+	}
+	if _, err := a.configMgr.RenameConnection(oldName, newName); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_rename_connection"), err)
+	}
+	if err := a.sessionMgr.RenameSessionDir(oldName, newName); err != nil {
+		fmt.Printf(a.i18nMgr.Get("session_dir_not_renamed"), oldName, err)
+	}
+
+	fmt.Printf(a.i18nMgr.Get("connection_renamed"), oldName, newName)
+	return nil
+}
+
+// handleConnectionShow prints a saved connection's settings without its password
+func (a *App) handleConnectionShow(args []string) error {
+	if len(args) != 1 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_show"))
+		return nil
+	}
+
+	cfg, err := a.configMgr.LoadConnection(args[0])
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", args[0], err))
+	}
+
+	fmt.Printf(a.i18nMgr.Get("connection_show_title"), cfg.Name)
+	fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_type")+":", cfg.DatabaseType.String())
+	if !cfg.DatabaseType.IsFileBased() {
+		fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_host")+":", cfg.Host)
+		fmt.Printf("   %-12s %d\n", a.i18nMgr.Get("field_port")+":", cfg.Port)
+		fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_username")+":", cfg.Username)
+		password := a.i18nMgr.Get("password_not_set")
+		switch {
+		case cfg.Auth != nil && cfg.Auth.Method == core.AuthIAM:
+			password = a.i18nMgr.GetWithArgs("password_from_iam", cfg.Auth.Provider)
+		case cfg.Auth != nil && cfg.Auth.Method == core.AuthGSSAPI:
+			password = a.i18nMgr.Get("password_from_kerberos")
+		case cfg.Password != "":
+			password = a.i18nMgr.Get("password_set")
+		case cfg.PasswordCommand != "":
+			password = a.i18nMgr.GetWithArgs("password_from_command", cfg.PasswordCommand)
+		case cfg.AskPassword:
+			password = a.i18nMgr.Get("password_asked")
+		}
+		fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_password")+":", password)
+		if cfg.Auth != nil {
+			fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_auth")+":", describeAuth(cfg.Auth))
+		}
+		fmt.Printf("   %-12s %t\n", "SSL:", cfg.SSL)
+		if len(cfg.Replicas) > 0 {
+			addresses := make([]string, len(cfg.Replicas))
+			for i, replica := range cfg.Replicas {
+				addresses[i] = replica.Address(cfg.Port)
+			}
+			fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_replicas")+":", strings.Join(addresses, ", "))
+		}
+	}
+	fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_database")+":", cfg.Database)
+	fmt.Printf("   %-12s %t\n", a.i18nMgr.Get("field_read_only_short")+":", cfg.ReadOnly)
+	if cfg.EstimateRows != "" {
+		fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_estimate_rows")+":", cfg.EstimateRows)
+	}
+	if len(cfg.Tags) > 0 {
+		fmt.Printf("   %-12s %s\n", a.i18nMgr.Get("field_tags")+":", cfg.FormatTags())
+	}
+	return nil
+}
+
+func (a *App) handleConnectionEdit(args []string) error {
+	if len(args) != 1 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_edit"))
+		return nil
+	}
+
+	cfg, err := a.configMgr.LoadConnection(args[0])
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", args[0], err))
+	}
+
+	fmt.Printf(a.i18nMgr.Get("editing_connection"), cfg.Name)
+	if err := a.editConnectionFields(cfg); err != nil {
+		return err
+	}
+
+	if err := a.configMgr.SaveConnection(cfg); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_save_connection_warning"), err)
+	}
+
+	fmt.Printf(a.i18nMgr.Get("connection_updated"), cfg.Name)
+	if a.config != nil && a.config.Name == cfg.Name {
+		fmt.Printf(a.i18nMgr.Get("reconnect_to_apply"), cfg.Name)
+	}
+	return nil
+}
+
+// promptPostgresAuth asks how a PostgreSQL connection signs in. IAM connections are
+// set up in the connection file and keep their auth block.
+func (a *App) promptPostgresAuth(cfg *core.ConnectionConfig) error {
+	if cfg.Auth != nil && cfg.Auth.Method == core.AuthIAM {
+		return nil
+	}
+	current := "password"
+	if cfg.Auth != nil {
+		current = cfg.Auth.Method
+	}
+	method, err := a.readInput(a.i18nMgr.GetWithArgs("enter_auth_method", current))
+	if err != nil {
+		return err
+	}
+	if method == "" {
+		method = current
+	}
+
+	switch strings.ToLower(method) {
+	case "password":
+		cfg.Auth = nil
+	case core.AuthLDAP:
+		cfg.Auth = &core.AuthConfig{Method: core.AuthLDAP}
+	case core.AuthGSSAPI:
+		if cfg.Auth == nil || cfg.Auth.Method != core.AuthGSSAPI {
+			cfg.Auth = &core.AuthConfig{Method: core.AuthGSSAPI}
+		}
+		service := cfg.Auth.KrbSrvName
+		if service == "" {
+			service = "postgres"
+		}
+		input, err := a.readInput(a.i18nMgr.GetWithArgs("enter_krbsrvname", service))
+		if err != nil {
+			return err
+		}
+		if input != "" && input != "postgres" {
+			cfg.Auth.KrbSrvName = input
+		}
+		// The ticket replaces any saved password
+		cfg.Password, cfg.PasswordCommand, cfg.AskPassword = "", "", false
+	default:
+		return errors.New(a.i18nMgr.GetWithArgs("invalid_auth_method", method))
+	}
+	return nil
+}
+
+// describeAuth summarises an auth block for /connection show
+func describeAuth(auth *core.AuthConfig) string {
+	switch auth.Method {
+	case core.AuthIAM:
+		return auth.Method + " (" + auth.Provider + ")"
+	case core.AuthGSSAPI:
+		switch {
+		case auth.KrbSPN != "":
+			return auth.Method + " (" + auth.KrbSPN + ")"
+		case auth.KrbSrvName != "":
+			return auth.Method + " (" + auth.KrbSrvName + ")"
+		}
+	}
+	return auth.Method
+}
+
+// editConnectionFields prompts for each field, keeping the current value on empty input
+func (a *App) editConnectionFields(cfg *core.ConnectionConfig) error {
+	prompt := func(label, current string) string {
+		input, _ := a.readInput(fmt.Sprintf("%s [%s]: ", label, current))
+		if input == "" {
+			return current
+		}
+		return input
+	}
+
+	if !cfg.DatabaseType.IsFileBased() {
+		cfg.Host = prompt(a.i18nMgr.Get("field_host"), cfg.Host)
+
+		portStr := prompt(a.i18nMgr.Get("field_port"), strconv.Itoa(cfg.Port))
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("invalid_port"), portStr)
+		}
+		cfg.Port = port
+
+		host, username := cfg.Host, cfg.Username
+		cfg.Username = prompt(a.i18nMgr.Get("field_username"), cfg.Username)
+		if cfg.DatabaseType == core.PostgreSQL {
+			if err := a.promptPostgresAuth(cfg); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case cfg.Auth != nil && cfg.Auth.Method == core.AuthIAM:
+			fmt.Printf(a.i18nMgr.Get("password_iam_kept"), cfg.Auth.Provider)
+		case cfg.Auth != nil && cfg.Auth.Method == core.AuthGSSAPI:
+			fmt.Print(a.i18nMgr.Get("password_kerberos_kept"))
+		case cfg.PasswordCommand != "":
+			fmt.Printf(a.i18nMgr.Get("password_command_kept"), cfg.PasswordCommand)
+		case cfg.Password == "":
+			password, err := a.readSecret(a.i18nMgr.Get("enter_password"))
+			if err != nil {
+				return err
+			}
+			cfg.Password = password
+			cfg.AskPassword = password == ""
+		case cfg.Host != host || cfg.Username != username:
+			// A password saved for another server or user is not carried over
+			password, err := a.readSecret(a.i18nMgr.GetWithArgs("reenter_password", cfg.Username, cfg.Host))
+			if err != nil {
+				return err
+			}
+			cfg.Password = password
+			cfg.AskPassword = password == ""
+		default:
+			password, err := a.readSecret(a.i18nMgr.Get("enter_password_keep_current"))
+			if err != nil {
+				return err
+			}
+			if password != "" {
+				cfg.Password = password
+			}
+		}
+	}
+
+	cfg.Database = prompt(a.i18nMgr.Get("field_database"), cfg.Database)
+
+	readOnly := prompt(a.i18nMgr.Get("field_read_only"), strconv.FormatBool(cfg.ReadOnly))
+	value, err := strconv.ParseBool(readOnly)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("invalid_boolean"), readOnly)
+	}
+	cfg.ReadOnly = value
+
+	return nil
+}
+
+func (a *App) handleConnectionTag(args []string) error {
+	if len(args) < 2 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_tag"))
+		return nil
+	}
+
+	cfg, err := a.configMgr.LoadConnection(args[0])
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", args[0], err))
+	}
+
+	if cfg.Tags == nil {
+		cfg.Tags = make(map[string]string)
+	}
+	for _, tag := range args[1:] {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok || key == "" {
+			return errors.New(a.i18nMgr.GetWithArgs("invalid_tag", tag))
+		}
+		cfg.Tags[key] = value
+	}
+
+	return a.saveConnectionTags(cfg)
+}
+
+func (a *App) handleConnectionUntag(args []string) error {
+	if len(args) < 2 {
+		fmt.Println(a.i18nMgr.Get("usage_connection_untag"))
+		return nil
+	}
+
+	cfg, err := a.configMgr.LoadConnection(args[0])
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", args[0], err))
+	}
+
+	for _, key := range args[1:] {
+		delete(cfg.Tags, key)
+	}
+
+	return a.saveConnectionTags(cfg)
+}
+
+func (a *App) saveConnectionTags(cfg *core.ConnectionConfig) error {
+	if err := a.configMgr.SaveConnection(cfg); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_save_connection_warning"), err)
+	}
+
+	// Keep the active session in sync so prompt and policy follow the new tags
+	if a.config != nil && a.config.Name == cfg.Name {
+		a.config.Tags = cfg.Tags
+		a.updatePrompt()
+	}
+
+	fmt.Printf(a.i18nMgr.Get("connection_tags_updated"), cfg.Name, cfg.FormatTags())
+	return nil
+}
+
+// parseTagFilter extracts a --tag filter from command arguments
+func parseTagFilter(args []string) string {
+	for i, arg := range args {
+		if arg == "--tag" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if value, ok := strings.CutPrefix(arg, "--tag="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// environmentColor picks a prompt color for an environment tag
+func environmentColor(env string) string {
+	switch strings.ToLower(env) {
+	case "prod", "production":
+		return "\033[1;31m" // bold red
+	case "staging", "stage", "uat":
+		return "\033[33m" // yellow
+	default:
+		return "\033[32m" // green
+	}
+}
+
+func (a *App) printConnectionSummary(cfg *core.ConnectionConfig) {
+	if cfg.DatabaseType.IsFileBased() {
+		fmt.Printf("   %s://%s", cfg.DatabaseType.String(), cfg.Database)
+	} else {
+		fmt.Printf("   %s://%s@%s:%d/%s", cfg.DatabaseType.String(), cfg.Username, cfg.Host, cfg.Port, cfg.Database)
+	}
+	if cfg.ReadOnly {
+		fmt.Printf(" %s", a.i18nMgr.Get("read_only_marker"))
+	}
+	fmt.Println()
+}
+
+func (a *App) printConnectionHelp() error {
+	fmt.Print(a.i18nMgr.Get("help_connection_title"))
+	fmt.Print(a.i18nMgr.Get("help_connection_commands"))
+	return nil
+}
+
+// handleTestConnection checks a saved connection on a throwaway handle, leaving the active one untouched
+func (a *App) handleTestConnection(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Println(a.i18nMgr.Get("usage_test"))
+		return nil
+	}
+
+	timeout := core.DefaultTestTimeout
+	if len(args) == 2 {
+		parsed, err := time.ParseDuration(args[1])
+		if err != nil {
+			return errors.New(a.i18nMgr.GetWithArgs("invalid_timeout", args[1]))
+		}
+		timeout = parsed
+	}
+
+	cfg, err := a.configMgr.LoadConnection(args[0])
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", args[0], err))
+	}
+
+	resolved, err := a.resolvePassword(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(a.i18nMgr.Get("testing_saved_connection"), cfg.Name, timeout)
+	report := core.TestConnection(resolved, timeout)
+	fmt.Print(core.FormatConnectionTestReport(report, a.i18nMgr))
+	return nil
+}
+
+// parsePingArgs reads `[connection] [--samples n] [--timeout 10s]`
+func parsePingArgs(args []string) (name string, samples int, timeout time.Duration, err error) {
+	fs := flag.NewFlagSet("ping", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	samplesFlag := fs.Int("samples", core.DefaultPingSamples, "")
+	timeoutFlag := fs.Duration("timeout", core.DefaultTestTimeout, "")
+
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return "", 0, 0, err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	if len(positional) > 1 {
+		return "", 0, 0, errors.New("expected at most one connection")
+	}
+	if *samplesFlag <= 0 || *timeoutFlag <= 0 {
+		return "", 0, 0, errors.New("samples and timeout must be positive")
+	}
+	if len(positional) == 1 {
+		name = positional[0]
+	}
+	return name, *samplesFlag, *timeoutFlag, nil
+}
+
+// handlePing times the TCP connect, TLS handshake, login and a SELECT 1 round trip of a
+// saved connection, the current one by default, over several fresh connections
+func (a *App) handlePing(args []string) error {
+	name, samples, timeout, err := parsePingArgs(args)
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_ping"))
+		return err
+	}
+	if name == "" {
+		if a.config == nil {
+			fmt.Println(a.i18nMgr.Get("no_database_connection"))
+			return nil
+		}
+		name = a.config.Name
+	}
+
+	cfg, err := a.configMgr.LoadConnection(name)
+	if err != nil {
+		return errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", name, err))
+	}
+	resolved, err := a.resolvePassword(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(a.i18nMgr.Get("pinging_connection"), cfg.Name, samples)
+	report := core.PingConnection(resolved, samples, timeout)
+	fmt.Print(core.FormatPingReport(report, a.i18nMgr))
+	return nil
+}
@@ -0,0 +1,234 @@
+package conversation
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// copyTableArgs holds the parsed /copy-table arguments
+type copyTableArgs struct {
+	source, sourceTable string
+	target, targetTable string
+	where               string
+	keys                []string
+	batch               int
+	onConflict          string
+	resume, restart     bool
+}
+
+// checkpointNameChars are the characters kept in a checkpoint's file name
+var checkpointNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// quotedFields splits a command line at spaces, keeping double-quoted text, such as a
+// --where condition, as one field without its quotes
+func quotedFields(line string) []string {
+	var fields []string
+	var field strings.Builder
+	inQuotes, started := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			inQuotes, started = !inQuotes, true
+		case r == ' ' && !inQuotes:
+			if started {
+				fields = append(fields, field.String())
+				field.Reset()
+				started = false
+			}
+		default:
+			field.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// parseCopyTableArgs reads `<conn>.<table> <conn>.<table> [--where cond] [--key a,b]
+// [--batch n] [--on-conflict error|skip|upsert] [--resume|--restart]`
+func parseCopyTableArgs(args []string) (copyTableArgs, error) {
+	var parsed copyTableArgs
+
+	fs := flag.NewFlagSet("copy-table", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	where := fs.String("where", "", "")
+	keys := fs.String("key", "", "")
+	batch := fs.Int("batch", core.DefaultCopyBatchSize, "")
+	onConflict := fs.String("on-conflict", core.CopyConflictError, "")
+	resume := fs.Bool("resume", false, "")
+	restart := fs.Bool("restart", false, "")
+
+	var positional []string
+	args = quotedFields(strings.Join(args, " "))
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return parsed, err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	if len(positional) != 2 {
+		return parsed, errors.New("expected <connection>.<table> <connection>.<table>")
+	}
+	var ok bool
+	if parsed.source, parsed.sourceTable, ok = strings.Cut(positional[0], "."); !ok || parsed.source == "" || parsed.sourceTable == "" {
+		return parsed, fmt.Errorf("expected <connection>.<table>, got %s", positional[0])
+	}
+	if parsed.target, parsed.targetTable, ok = strings.Cut(positional[1], "."); !ok || parsed.target == "" || parsed.targetTable == "" {
+		return parsed, fmt.Errorf("expected <connection>.<table>, got %s", positional[1])
+	}
+	if !slices.Contains([]string{core.CopyConflictError, core.CopyConflictSkip, core.CopyConflictUpsert}, *onConflict) {
+		return parsed, fmt.Errorf("unknown conflict policy %q", *onConflict)
+	}
+	if *batch <= 0 {
+		return parsed, fmt.Errorf("batch size must be positive")
+	}
+
+	parsed.where = *where
+	parsed.keys = splitList(*keys)
+	parsed.batch = *batch
+	parsed.onConflict = *onConflict
+	parsed.resume, parsed.restart = *resume, *restart
+	return parsed, nil
+}
+
+// copyCheckpointPath is where a copy into the target connection keeps its progress
+func (a *App) copyCheckpointPath(parsed copyTableArgs) string {
+	name := checkpointNameChars.ReplaceAllString(fmt.Sprintf("%s.%s__%s.%s", parsed.source, parsed.sourceTable, parsed.target, parsed.targetTable), "_")
+	return filepath.Join(a.sessionMgr.GetSessionDir(parsed.target), "copies", name+".json")
+}
+
+// handleCopyTable streams rows from a table of one saved connection into a table of
+// another, creating the target table when it is missing. Progress is checkpointed after
+// every batch, so an interrupted copy picks up after the last row it wrote.
+func (a *App) handleCopyTable(args []string) error {
+	parsed, err := parseCopyTableArgs(args)
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_copy_table"))
+		return err
+	}
+
+	source, _, err := a.openSavedConnection(parsed.source)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, targetCfg, err := a.openSavedConnection(parsed.target)
+	if err != nil {
+		return err
+	}
+	defer target.Close()
+
+	if rule := a.policy.DeniedStatement(targetCfg, "INSERT INTO "+parsed.targetTable); rule != nil {
+		return policyDenial(a.i18nMgr.GetWithArgs("policy_statement_denied", "INSERT"), rule)
+	}
+
+	opts := core.CopyOptions{
+		SourceTable: parsed.sourceTable,
+		TargetTable: parsed.targetTable,
+		Where:       parsed.where,
+		Keys:        parsed.keys,
+		BatchSize:   parsed.batch,
+		OnConflict:  parsed.onConflict,
+	}
+	if len(opts.Keys) == 0 {
+		if info, err := source.DescribeTable(parsed.sourceTable); err == nil {
+			opts.Keys = info.PrimaryKeys
+		}
+	}
+	if info, err := target.DescribeTable(parsed.targetTable); err == nil && len(info.Columns) > 0 {
+		opts.ConflictKeys = info.PrimaryKeys
+	} else {
+		opts.Create = true
+		opts.ConflictKeys = opts.Keys
+	}
+	if opts.OnConflict != core.CopyConflictError && len(opts.ConflictKeys) == 0 {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_copy_table"), core.ErrCopyNeedsKey)
+	}
+
+	checkpointPath := a.copyCheckpointPath(parsed)
+	sourceRef, targetRef := parsed.source+"."+parsed.sourceTable, parsed.target+"."+parsed.targetTable
+	var previous int64
+	if len(opts.Keys) == 0 {
+		fmt.Println(a.i18nMgr.GetWithArgs("copy_table_no_key", parsed.sourceTable))
+	} else if checkpoint, err := core.LoadCopyCheckpoint(checkpointPath); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	} else if checkpoint != nil && checkpoint.Matches(sourceRef, targetRef, opts.Where, opts.Keys) && !parsed.restart &&
+		(parsed.resume || a.confirm(a.i18nMgr.GetWithArgs("copy_table_resume_question", checkpoint.Rows, strings.Join(checkpoint.LastKey, ", "), checkpoint.Updated.Local().Format("2006-01-02 15:04")))) {
+		opts.After, previous = checkpoint.LastKey, checkpoint.Rows
+	}
+
+	total, err := core.CountCopyRows(source, opts)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_copy_table"), err)
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("copy_table_starting", sourceRef, targetRef, total, opts.OnConflict, opts.BatchSize))
+	if opts.Create {
+		fmt.Println(a.i18nMgr.GetWithArgs("copy_table_creating", parsed.targetTable))
+	}
+	if previous > 0 {
+		fmt.Println(a.i18nMgr.GetWithArgs("copy_table_resuming", previous, strings.Join(opts.After, ", ")))
+	}
+	// Approvals are for statements on the active connection, so copies into a
+	// connection that needs one are refused
+	if err := a.guardChange(changeGuard{conn: targetCfg, question: a.i18nMgr.Get("copy_table_confirm_question")}); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	saved := false
+	copied, err := core.CopyTable(source, target, opts, func(p core.CopyProgress) error {
+		rate := formatRowCount(float64(p.Rows) / math.Max(time.Since(started).Seconds(), 0.001))
+		if total > 0 {
+			fmt.Printf("\r⏳ %s", a.i18nMgr.GetWithArgs("copy_table_progress_of", formatRowCount(float64(p.Rows)), formatRowCount(float64(total)), float64(p.Rows)*100/float64(total), rate))
+		} else {
+			fmt.Printf("\r⏳ %s", a.i18nMgr.GetWithArgs("copy_table_progress", formatRowCount(float64(p.Rows)), rate))
+		}
+		if p.LastKey == nil {
+			return nil
+		}
+		checkpoint := &core.CopyCheckpoint{
+			Source:  sourceRef,
+			Target:  targetRef,
+			Where:   opts.Where,
+			Keys:    opts.Keys,
+			LastKey: p.LastKey,
+			Rows:    previous + p.Rows,
+			Updated: time.Now(),
+		}
+		if err := core.SaveCopyCheckpoint(checkpointPath, checkpoint); err != nil {
+			return err
+		}
+		saved = true
+		return nil
+	})
+	fmt.Println()
+	if err != nil {
+		if saved {
+			fmt.Println(a.i18nMgr.GetWithArgs("copy_table_interrupted", previous+copied))
+		}
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_copy_table"), err)
+	}
+
+	if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("copy_table_done", copied, sourceRef, targetRef, time.Since(started).Round(time.Millisecond)))
+	return nil
+}
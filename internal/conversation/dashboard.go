@@ -0,0 +1,59 @@
+package conversation
+
+import (
+	"fmt"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// defaultDashboardInterval is how often /dashboard --watch refreshes without an interval
+const defaultDashboardInterval = 30 * time.Second
+
+// handleDashboard runs the queries of a dashboard file and shows them together:
+// /dashboard ops.yaml [--watch [30s]]
+func (a *App) handleDashboard(args []string) error {
+	var path string
+	var interval time.Duration
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--watch":
+			interval = defaultDashboardInterval
+			if i+1 < len(args) {
+				if parsed, err := time.ParseDuration(args[i+1]); err == nil {
+					if parsed <= 0 {
+						fmt.Println(a.i18nMgr.Get("usage_dashboard"))
+						return nil
+					}
+					interval = parsed
+					i++
+				}
+			}
+		case path == "":
+			path = args[i]
+		default:
+			fmt.Println(a.i18nMgr.Get("usage_dashboard"))
+			return nil
+		}
+	}
+	if path == "" {
+		fmt.Println(a.i18nMgr.Get("usage_dashboard"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	dashboard, err := core.LoadDashboard(path)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_dashboard"), path, err)
+	}
+	show := func() error {
+		return a.displayMarkdown(core.RenderDashboard(dashboard, a.executeQuery, a.i18nMgr))
+	}
+	if interval == 0 {
+		return show()
+	}
+	return a.watch(interval, show)
+}
@@ -0,0 +1,330 @@
+package conversation
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+const defaultDiffDisplayLimit = 20
+
+// dataDiffArgs holds the parsed /diff-data arguments
+type dataDiffArgs struct {
+	table   string
+	left    string
+	right   string
+	keys    []string
+	columns []string
+	limit   int
+	csvFile string
+}
+
+// parseDataDiffArgs reads `<table> <left> <right> [--key k] [--columns a,b] [--limit n] [> file.csv]`
+func parseDataDiffArgs(args []string) (dataDiffArgs, error) {
+	var parsed dataDiffArgs
+
+	if idx := slices.Index(args, ">"); idx >= 0 {
+		if idx != len(args)-2 {
+			return parsed, errors.New("expected a single file name after >")
+		}
+		parsed.csvFile = args[idx+1]
+		args = args[:idx]
+	}
+
+	fs := flag.NewFlagSet("diff-data", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	keys := fs.String("key", "", "")
+	columns := fs.String("columns", "", "")
+	limit := fs.Int("limit", defaultDiffDisplayLimit, "")
+
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			return parsed, err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	if len(positional) != 3 {
+		return parsed, errors.New("expected <table> <left-connection> <right-connection>")
+	}
+
+	parsed.table, parsed.left, parsed.right = positional[0], positional[1], positional[2]
+	parsed.keys = splitList(*keys)
+	parsed.columns = splitList(*columns)
+	parsed.limit = *limit
+	return parsed, nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// openSavedConnection opens a separate connection to a saved config, leaving the active one alone
+func (a *App) openSavedConnection(name string) (core.Connection, *core.ConnectionConfig, error) {
+	cfg, err := a.configMgr.LoadConnection(name)
+	if err != nil {
+		return nil, nil, errors.New(a.i18nMgr.GetWithArgs("failed_to_load_connection", name, err))
+	}
+	resolved, err := a.resolvePassword(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := core.NewConnection(resolved)
+	if err != nil {
+		return nil, nil, fmt.Errorf(a.i18nMgr.Get("failed_to_connect"), err)
+	}
+	return conn, cfg, nil
+}
+
+func (a *App) handleDataDiff(args []string) error {
+	parsed, err := parseDataDiffArgs(args)
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_diff_data"))
+		return err
+	}
+
+	left, _, err := a.openSavedConnection(parsed.left)
+	if err != nil {
+		return err
+	}
+	defer left.Close()
+
+	right, _, err := a.openSavedConnection(parsed.right)
+	if err != nil {
+		return err
+	}
+	defer right.Close()
+
+	if len(parsed.keys) == 0 {
+		info, err := left.DescribeTable(parsed.table)
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_diff_data"), err)
+		}
+		if len(info.PrimaryKeys) == 0 {
+			return errors.New(a.i18nMgr.GetWithArgs("diff_data_no_key", parsed.table))
+		}
+		parsed.keys = info.PrimaryKeys
+	}
+
+	fmt.Printf(a.i18nMgr.Get("diff_data_comparing"), parsed.table, parsed.left, parsed.right, strings.Join(parsed.keys, ", "))
+
+	diff, err := core.NewDataDiff(left, right, core.DataDiffOptions{
+		Table:   parsed.table,
+		Keys:    parsed.keys,
+		Columns: parsed.columns,
+	})
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_diff_data"), err)
+	}
+	defer diff.Close()
+
+	var csvWriter *core.DataDiffCSVWriter
+	if parsed.csvFile != "" {
+		csvWriter, err = core.NewDataDiffCSVWriter(parsed.csvFile, parsed.keys, diff.Columns)
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_diff_data"), err)
+		}
+	}
+
+	// Only the first differences are kept for display; the CSV gets all of them
+	var shown []core.RowDiff
+	summary, err := diff.Run(func(row core.RowDiff) error {
+		if len(shown) < parsed.limit {
+			shown = append(shown, row)
+		}
+		if csvWriter != nil {
+			return csvWriter.Write(row)
+		}
+		return nil
+	})
+	if csvWriter != nil {
+		if closeErr := csvWriter.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_diff_data"), err)
+	}
+
+	if err := a.displayMarkdown(a.formatDataDiff(parsed, diff.Columns, summary, shown)); err != nil {
+		return err
+	}
+	if csvWriter != nil {
+		fmt.Printf(a.i18nMgr.Get("diff_data_exported"), summary.Total(), parsed.csvFile)
+	}
+	return nil
+}
+
+func (a *App) formatDataDiff(parsed dataDiffArgs, columns []string, summary core.DataDiffSummary, shown []core.RowDiff) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🔀 %s\n\n", a.i18nMgr.GetWithArgs("diff_data_header", parsed.table, parsed.left, parsed.right)))
+	sb.WriteString(a.i18nMgr.GetWithArgs("diff_data_summary", summary.Same, summary.Changed, summary.Removed, summary.Added))
+
+	if summary.Total() == 0 {
+		sb.WriteString("\n" + a.i18nMgr.Get("diff_data_identical"))
+		return sb.String()
+	}
+
+	sb.WriteString("\n" + a.i18nMgr.Get("diff_data_table_header"))
+	sb.WriteString("|---|---|---|---|---|\n")
+	cell := func(value string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(value, "|", "\\|"), "\n", " ")
+	}
+	for _, row := range shown {
+		key := cell(strings.Join(row.Key, ", "))
+		switch row.Kind {
+		case core.RowChanged:
+			for i, col := range columns {
+				if !slices.Contains(row.Changed, col) {
+					continue
+				}
+				sb.WriteString(fmt.Sprintf("| ✏️ %s | %s | %s | %s | %s |\n", row.Kind, key, col, cell(row.Left[i].String()), cell(row.Right[i].String())))
+			}
+		case core.RowRemoved:
+			sb.WriteString(fmt.Sprintf("| ➖ %s | %s | | %s | |\n", row.Kind, key, cell(joinValues(row.Left))))
+		case core.RowAdded:
+			sb.WriteString(fmt.Sprintf("| ➕ %s | %s | | | %s |\n", row.Kind, key, cell(joinValues(row.Right))))
+		}
+	}
+
+	if summary.Total() > len(shown) {
+		sb.WriteString("\n" + a.i18nMgr.GetWithArgs("diff_data_truncated", len(shown), summary.Total()))
+	}
+	return sb.String()
+}
+
+func joinValues(values []core.Value) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = value.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// resolveTableRef splits a `connection:table` reference, opening the named connection.
+// Plain table names use the active connection; the returned closer is a no-op for it.
+func (a *App) resolveTableRef(ref string) (core.Connection, string, func(), error) {
+	name, table, found := strings.Cut(ref, ":")
+	if !found {
+		if a.connection == nil {
+			return nil, "", nil, errors.New(a.i18nMgr.Get("no_database_connection"))
+		}
+		return a.connection, ref, func() {}, nil
+	}
+
+	conn, _, err := a.openSavedConnection(name)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return conn, table, func() { conn.Close() }, nil
+}
+
+// handleVerify compares row counts and chunked checksums of two tables
+func (a *App) handleVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	keys := fs.String("key", "", "")
+	chunkSize := fs.Int("chunk", core.DefaultVerifyChunkSize, "")
+
+	var positional []string
+	for len(args) > 0 {
+		if err := fs.Parse(args); err != nil {
+			fmt.Println(a.i18nMgr.Get("usage_verify"))
+			return err
+		}
+		args = fs.Args()
+		if len(args) > 0 {
+			positional = append(positional, args[0])
+			args = args[1:]
+		}
+	}
+	if len(positional) != 2 {
+		fmt.Println(a.i18nMgr.Get("usage_verify"))
+		return nil
+	}
+
+	left, leftTable, closeLeft, err := a.resolveTableRef(positional[0])
+	if err != nil {
+		return err
+	}
+	defer closeLeft()
+	right, rightTable, closeRight, err := a.resolveTableRef(positional[1])
+	if err != nil {
+		return err
+	}
+	defer closeRight()
+
+	keyColumns := splitList(*keys)
+	if len(keyColumns) == 0 {
+		info, err := left.DescribeTable(leftTable)
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_verify_tables"), err)
+		}
+		if len(info.PrimaryKeys) == 0 {
+			return errors.New(a.i18nMgr.GetWithArgs("diff_data_no_key", leftTable))
+		}
+		keyColumns = info.PrimaryKeys
+	}
+
+	fmt.Printf(a.i18nMgr.Get("verify_comparing"), positional[0], positional[1])
+	report, err := core.VerifyTables(left, right, leftTable, rightTable, keyColumns, *chunkSize)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_verify_tables"), err)
+	}
+
+	return a.displayMarkdown(a.formatVerifyReport(positional[0], positional[1], report))
+}
+
+func (a *App) formatVerifyReport(leftRef, rightRef string, report *core.VerifyReport) string {
+	const maxChunksShown = 20
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🧮 %s\n\n", a.i18nMgr.GetWithArgs("verify_header", leftRef, rightRef)))
+	sb.WriteString(a.i18nMgr.Get("verify_table_header"))
+	sb.WriteString("|---|---|---|\n")
+	sb.WriteString(fmt.Sprintf("| %s | %d | %d |\n", a.i18nMgr.Get("verify_row_count"), report.LeftCount, report.RightCount))
+
+	mismatched := report.Mismatched()
+	sb.WriteString(fmt.Sprintf("| %s | %d | %d |\n\n", a.i18nMgr.Get("verify_chunks"), len(report.Chunks), len(report.Chunks)-len(mismatched)))
+
+	if report.OK() {
+		sb.WriteString(a.i18nMgr.Get("verify_ok"))
+		return sb.String()
+	}
+
+	if len(mismatched) == 0 {
+		return sb.String()
+	}
+
+	sb.WriteString(a.i18nMgr.GetWithArgs("verify_mismatched", len(mismatched)))
+	sb.WriteString(a.i18nMgr.Get("verify_chunk_header"))
+	sb.WriteString("|---|---|---|---|\n")
+	for i, chunk := range mismatched {
+		if i == maxChunksShown {
+			sb.WriteString("| … | | | |\n")
+			break
+		}
+		last := "∞"
+		if chunk.LastKey != nil {
+			last = strings.Join(chunk.LastKey, ", ")
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | %d |\n", strings.Join(chunk.FirstKey, ", "), last, chunk.LeftRows, chunk.RightRows))
+	}
+	sb.WriteString("\n" + a.i18nMgr.Get("verify_diff_hint"))
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// handleDDL prints the CREATE TABLE statement for a table, or writes it to a file with > schema.sql
+func (a *App) handleDDL(args []string) error {
+	var tables []string
+	outputFile := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == ">" && i+1 < len(args):
+			outputFile = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], ">") && len(args[i]) > 1:
+			outputFile = args[i][1:]
+		default:
+			tables = append(tables, args[i])
+		}
+	}
+	if len(tables) == 0 {
+		fmt.Println(a.i18nMgr.Get("usage_ddl"))
+		return nil
+	}
+
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	var script strings.Builder
+	for i, table := range tables {
+		ddl, err := core.GenerateDDL(a.connection, a.config.DatabaseType, table)
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_generate_ddl"), table, err)
+		}
+		if i > 0 {
+			script.WriteString("\n")
+		}
+		script.WriteString(ddl.String())
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, []byte(script.String()), 0644); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_write_file"), err)
+		}
+		fmt.Printf(a.i18nMgr.Get("ddl_written"), outputFile)
+		return nil
+	}
+
+	return a.displayMarkdown("```sql\n" + script.String() + "```\n")
+}
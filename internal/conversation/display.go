@@ -0,0 +1,347 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+	"sqlterm/internal/session"
+)
+
+// displayConfig returns the configured display preferences, or defaults when unavailable
+func (a *App) displayConfig() config.DisplayConfig {
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.Display
+		}
+	}
+	return config.DisplayConfig{}
+}
+
+// valueFormatters builds the result formatters for the current display preferences
+func (a *App) valueFormatters() []core.ValueFormatter {
+	display := a.displayConfig()
+	formatters := []core.ValueFormatter{
+		core.GeometryFormatter{ShowBBox: display.GeometryBBox},
+	}
+	if display.Timezone != "" {
+		if tz, err := core.NewTimezoneFormatter(display.Timezone); err == nil {
+			formatters = append(formatters, tz)
+		}
+	}
+	return formatters
+}
+
+// capRows limits the rows of a query run for display, see core.CapDisplayRows
+func (a *App) capRows(conn core.Connection) core.Connection {
+	if !a.displaying {
+		return conn
+	}
+	return core.CapDisplayRows(conn)
+}
+
+// executeQuery runs a query on the active connection with session variables expanded
+// and display formatting applied
+func (a *App) executeQuery(query string) (*core.QueryResult, error) {
+	// /unmask applies to this query only, whether or not it runs
+	unmask := a.unmaskNext
+	a.unmaskNext = false
+
+	query, steps := core.SplitPipeline(query)
+	query, err := core.ExpandVariables(query, a.vars)
+	if err != nil {
+		return nil, err
+	}
+	// /undo has confirmed its statements as a whole already
+	if !a.undoing {
+		if err := a.enforceConnectionPolicy(query); err != nil {
+			return nil, err
+		}
+		if err := a.capturePreImage(query); err != nil {
+			return nil, err
+		}
+	}
+
+	target := a.connection
+	if !a.onPrimary {
+		target = core.RouteQuery(a.connection, query, a.inTransaction)
+	}
+
+	start := time.Now()
+	result, err := core.ExecuteWithRetry(a.capRows(target), query, a.retryPolicy(), a.reportRetry)
+	if err != nil && target != a.connection && core.IsTransientError(err) {
+		// An unreachable replica should not stop reads; the primary can answer them
+		fmt.Printf(a.i18nMgr.Get("replica_fallback_warning"), err)
+		result, err = core.ExecuteWithRetry(a.capRows(a.connection), query, a.retryPolicy(), a.reportRetry)
+	}
+	if err != nil {
+		a.logQuery(query, start, 0, err)
+		a.recordForExport(query, start, nil, err)
+		a.notifyQueryDone(query, start, 0, err)
+		return nil, err
+	}
+	if opens, ends := core.TransactionChange(query); opens || ends {
+		a.inTransaction = opens
+	}
+	formatters := a.valueFormatters()
+	if mask := a.sensitive.Mask(query, result.Columns); mask != nil && !unmask {
+		formatters = append([]core.ValueFormatter{mask}, formatters...)
+	}
+	result.SetFormatters(formatters...)
+	if err := result.Pipe(steps...); err != nil {
+		result.Close()
+		a.logQuery(query, start, 0, err)
+		return nil, err
+	}
+	result.KeepSample(ai.MaxAttachmentRows)
+	// Rows stream lazily, so log once the caller has finished reading them
+	result.OnClose(func(r *core.QueryResult) {
+		// Changes return no rows; what they affected is what the logs should show
+		rows := r.RowCount()
+		if affected, ok := r.RowsAffected(); ok {
+			rows = int(affected)
+		}
+		a.logQuery(query, start, rows, r.Error())
+		a.recordForExport(query, start, r, r.Error())
+		a.notifyQueryDone(query, start, rows, r.Error())
+		a.rememberResult(query, r)
+		a.rememberColumns(query, r)
+		if r.Error() == nil && a.aiManager != nil {
+			a.aiManager.AcceptQuery(query)
+		}
+	})
+	return result, nil
+}
+
+// logQuery records an executed statement in the audit log and the session query log
+func (a *App) logQuery(query string, start time.Time, rows int, err error) {
+	a.audit(query, start, rows, err)
+	if a.stats != nil {
+		a.stats.RecordStatement(query, rows, err)
+	}
+	if err := recordQueryLog(a.queryLog, query, start, rows, err); err != nil {
+		fmt.Printf(a.i18nMgr.Get("query_log_warning"), err)
+	}
+}
+
+// recordQueryLog records a statement in a session query log, which may be nil
+func recordQueryLog(queryLog *session.QueryLog, query string, start time.Time, rows int, err error) error {
+	if queryLog == nil {
+		return nil
+	}
+	entry := session.QueryLogEntry{
+		Query:     query,
+		StartedAt: start,
+		Duration:  time.Since(start),
+		Rows:      rows,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return queryLog.Record(entry)
+}
+
+// retryPolicy returns the configured retry policy for transient errors
+func (a *App) retryPolicy() core.RetryPolicy {
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.RetryPolicy()
+		}
+	}
+	return core.DefaultRetryPolicy()
+}
+
+func (a *App) reportRetry(attempt, maxAttempts int, delay time.Duration, err error) {
+	fmt.Printf(a.i18nMgr.Get("query_retrying"), attempt, maxAttempts, err, delay)
+}
+
+// connectionPolicy returns the statement policy for the active connection
+func (a *App) connectionPolicy() string {
+	return a.policyFor(a.config)
+}
+
+// policyFor returns the statement policy for a connection, active or not
+func (a *App) policyFor(conn *core.ConnectionConfig) string {
+	if conn == nil || !conn.IsProduction() {
+		return config.PolicyNone
+	}
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.PolicyFor(conn)
+		}
+	}
+	return config.DefaultConfig().PolicyFor(conn)
+}
+
+// enforceConnectionPolicy applies the policy file's rules, then guards statements that
+// modify data on production connections. When the connection estimates the rows an
+// UPDATE or DELETE changes, the estimate is part of the one question asked.
+func (a *App) enforceConnectionPolicy(query string) error {
+	if err := a.checkStatementPolicy(query); err != nil {
+		return err
+	}
+	// Writable CTEs, SELECT ... INTO, EXPLAIN ANALYZE of a change and a write after a
+	// read in the same input are all guarded, though they start like reads
+	if core.ClassifyStatement(query) == core.StatementRead {
+		return nil
+	}
+
+	guard := changeGuard{conn: a.config, approval: query, explain: true}
+	// Without a policy that confirms, the question is only asked to show an estimate
+	if policy := a.connectionPolicy(); policy != config.PolicyReadOnly && policy != config.PolicyApprove {
+		if rows, ok := a.estimateAffectedRows(query); ok {
+			guard.question = a.i18nMgr.GetWithArgs("estimate_rows_question", formatRowCount(rows))
+			guard.ask = true
+		}
+	}
+	return a.guardChange(guard)
+}
+
+// changeGuard is a change to data or schema that guardChange applies a connection's
+// production policy to
+type changeGuard struct {
+	conn     *core.ConnectionConfig
+	approval string // What the approver signs; empty when the change cannot be approved from here
+	explain  bool   // Add the plan and row estimate to the approval bundle
+	question string // Asked before the change runs; defaults to policy_confirm_question
+	ask      bool   // Ask question whatever the policy, not only when it confirms
+}
+
+// guardChange is the one place production policies are applied: read-only refuses the
+// change, approve waits for an approver's token and confirm warns before asking
+func (a *App) guardChange(g changeGuard) error {
+	switch a.policyFor(g.conn) {
+	case config.PolicyReadOnly:
+		return errors.New(a.i18nMgr.GetWithArgs("policy_read_only_rejected", g.conn.Name))
+	case config.PolicyApprove:
+		if g.approval == "" {
+			return errors.New(a.i18nMgr.GetWithArgs("policy_approval_unavailable", g.conn.Name))
+		}
+		return a.requireApproval(g.approval, g.explain)
+	case config.PolicyConfirm:
+		fmt.Printf(a.i18nMgr.Get("policy_confirm_warning"), g.conn.Name)
+		g.ask = true
+	}
+	if !g.ask {
+		return nil
+	}
+	if g.question == "" {
+		g.question = a.i18nMgr.Get("policy_confirm_question")
+	}
+	if !a.confirm(g.question) {
+		return errors.New(a.i18nMgr.Get("statement_cancelled"))
+	}
+	return nil
+}
+
+func (a *App) handleConfigDisplay(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+
+	if len(args) == 0 || args[0] == "status" {
+		display := a.displayConfig()
+		fmt.Println(a.i18nMgr.Get("display_config_title"))
+		fmt.Printf(a.i18nMgr.Get("display_geometry_bbox_status"), display.GeometryBBox)
+		timezone := display.Timezone
+		if timezone == "" {
+			timezone = a.i18nMgr.Get("display_timezone_driver_default")
+		}
+		fmt.Printf(a.i18nMgr.Get("display_timezone_status"), timezone)
+		if threshold := a.previewThreshold(); threshold > 0 {
+			fmt.Printf(a.i18nMgr.Get("display_preview_status"), formatRowCount(float64(threshold)))
+		} else {
+			fmt.Print(a.i18nMgr.Get("display_preview_off_status"))
+		}
+		if after := a.notifyAfter(); after > 0 {
+			fmt.Printf(a.i18nMgr.Get("display_notify_status"), after, a.notifyMethod())
+		} else {
+			fmt.Print(a.i18nMgr.Get("display_notify_off_status"))
+		}
+		return nil
+	}
+
+	display := a.displayConfig()
+	switch args[0] {
+	case "bbox":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			fmt.Println(a.i18nMgr.Get("usage_config_display_bbox"))
+			return nil
+		}
+		display.GeometryBBox = args[1] == "on"
+	case "timezone":
+		if len(args) < 2 {
+			fmt.Println(a.i18nMgr.Get("usage_config_display_timezone"))
+			return nil
+		}
+		if args[1] == "off" {
+			display.Timezone = ""
+			break
+		}
+		if _, err := core.ParseTimezone(args[1]); err != nil {
+			return errors.New(a.i18nMgr.GetWithArgs("invalid_timezone", args[1], err))
+		}
+		display.Timezone = args[1]
+	case "preview":
+		if len(args) < 2 {
+			fmt.Println(a.i18nMgr.Get("usage_config_display_preview"))
+			return nil
+		}
+		if args[1] == "off" {
+			display.PreviewThreshold = -1
+			break
+		}
+		rows, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || rows <= 0 {
+			fmt.Println(a.i18nMgr.Get("usage_config_display_preview"))
+			return nil
+		}
+		display.PreviewThreshold = rows
+	case "notify":
+		if len(args) < 2 || len(args) > 3 {
+			fmt.Println(a.i18nMgr.Get("usage_config_display_notify"))
+			return nil
+		}
+		if args[1] == "off" {
+			display.NotifyAfter = -1
+			break
+		}
+		value := args[1]
+		if _, err := strconv.Atoi(value); err == nil {
+			value += "s" // A bare number is seconds
+		}
+		after, err := time.ParseDuration(value)
+		if err != nil || after < time.Second {
+			fmt.Println(a.i18nMgr.Get("usage_config_display_notify"))
+			return nil
+		}
+		display.NotifyAfter = int(after / time.Second)
+		if len(args) == 3 {
+			if args[2] != core.NotifyBell && args[2] != core.NotifyDesktop && args[2] != core.NotifyBoth {
+				fmt.Println(a.i18nMgr.Get("usage_config_display_notify"))
+				return nil
+			}
+			display.Notify = args[2]
+		}
+	default:
+		fmt.Printf(a.i18nMgr.Get("unknown_display_setting"), args[0])
+		return a.printConfigDisplayHelp()
+	}
+
+	if err := a.aiManager.SetDisplayConfig(display); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_display_config"), err)
+	}
+
+	fmt.Print(a.i18nMgr.Get("display_config_updated"))
+	return nil
+}
+
+func (a *App) printConfigDisplayHelp() error {
+	fmt.Print(a.i18nMgr.Get("help_config_display_title"))
+	fmt.Print(a.i18nMgr.Get("help_config_display_commands"))
+	return nil
+}
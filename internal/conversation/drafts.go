@@ -0,0 +1,87 @@
+package conversation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chzyer/readline"
+)
+
+// Ctrl+S and Ctrl+U reach the line editor's listener as these runes, which nothing types
+const (
+	stashDraftKey = '\uE000'
+	popDraftKey   = '\uE001'
+)
+
+// draftKeys replaces Ctrl+S (forward search) and Ctrl+U (delete to line start) with the
+// draft keys before readline handles them
+func draftKeys(r rune) (rune, bool) {
+	switch r {
+	case readline.CharFwdSearch:
+		return stashDraftKey, true
+	case readline.CharCtrlU:
+		return popDraftKey, true
+	}
+	return r, true
+}
+
+// onDraftKey stashes the input line on Ctrl+S and clears it; Ctrl+U puts the last
+// stashed line back, stashing what was typed in its place
+func (a *App) onDraftKey(line []rune, pos int, key rune) ([]rune, int, bool) {
+	if key != stashDraftKey && key != popDraftKey {
+		return nil, 0, false
+	}
+	// readline wrote the key into the line like any other rune
+	if i := slices.Index(line, key); i >= 0 {
+		line = slices.Delete(slices.Clone(line), i, i+1)
+		if pos > i {
+			pos--
+		}
+	}
+
+	a.draftsMu.Lock()
+	defer a.draftsMu.Unlock()
+	typed := strings.TrimSpace(string(line))
+	if key == stashDraftKey {
+		if typed != "" {
+			a.drafts = append(a.drafts, typed)
+		}
+		return []rune{}, 0, true
+	}
+	if len(a.drafts) == 0 {
+		return line, pos, true
+	}
+	draft := []rune(a.drafts[len(a.drafts)-1])
+	a.drafts = a.drafts[:len(a.drafts)-1]
+	if typed != "" {
+		a.drafts = append(a.drafts, typed)
+	}
+	return draft, len(draft), true
+}
+
+// handleDrafts lists the stashed input lines, newest last, or clears them
+func (a *App) handleDrafts(args []string) error {
+	a.draftsMu.Lock()
+	defer a.draftsMu.Unlock()
+
+	if len(args) == 1 && args[0] == "clear" {
+		a.drafts = nil
+		fmt.Println(a.i18nMgr.Get("drafts_cleared"))
+		return nil
+	}
+	if len(args) != 0 {
+		fmt.Println(a.i18nMgr.Get("usage_drafts"))
+		return nil
+	}
+	if len(a.drafts) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_drafts"))
+		return nil
+	}
+	fmt.Println(a.i18nMgr.Get("drafts_title"))
+	for i, draft := range a.drafts {
+		fmt.Printf("  %d. %s\n", i+1, a.truncateQuery(draft))
+	}
+	fmt.Println(a.i18nMgr.Get("drafts_pop_hint"))
+	return nil
+}
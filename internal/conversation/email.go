@@ -0,0 +1,88 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+// splitEmailFlag separates the recipients of --email from the export target after " > ",
+// as in report.xlsx --email team@corp.com,lead@corp.com
+func (a *App) splitEmailFlag(target string) (string, []string, error) {
+	target, addresses, found := strings.Cut(target, " --email")
+	if !found {
+		return target, nil, nil
+	}
+	target = strings.TrimSpace(target)
+	if core.IsStreamTarget(target) || core.IsSheetsTarget(target) || core.IsObjectStoreTarget(target) {
+		return "", nil, errors.New(a.i18nMgr.Get("email_needs_file"))
+	}
+
+	var recipients []string
+	for _, address := range strings.FieldsFunc(addresses, func(r rune) bool { return r == ',' || r == ' ' }) {
+		parsed, err := mail.ParseAddress(address)
+		if err != nil {
+			return "", nil, fmt.Errorf(a.i18nMgr.Get("invalid_email_address"), address)
+		}
+		recipients = append(recipients, parsed.Address)
+	}
+	if len(recipients) == 0 {
+		return "", nil, errors.New(a.i18nMgr.Get("usage_email"))
+	}
+	return target, recipients, nil
+}
+
+// emailResults sends exported files to the recipients of --email through the configured
+// SMTP server, with the subject and body templates filled in for the run
+func (a *App) emailResults(recipients []string, query string, rows int, duration time.Duration, files []string) error {
+	settings := a.integrationsConfig().SMTP
+	if settings.Host == "" {
+		return errors.New(a.i18nMgr.Get("smtp_not_configured"))
+	}
+	password := settings.Password
+	if settings.PasswordCommand != "" {
+		var err error
+		if password, err = config.RunPasswordCommand(settings.PasswordCommand); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_send_email"), err)
+		}
+	}
+
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = filepath.Base(file)
+	}
+	subject, body, err := core.RenderEmail(settings.Subject, settings.Body, core.EmailReport{
+		Query:      query,
+		Rows:       rows,
+		Duration:   duration,
+		File:       strings.Join(names, ", "),
+		Connection: a.config.Name,
+	})
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_send_email"), err)
+	}
+	message, err := core.ComposeEmail(settings.From, recipients, subject, body, files)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_send_email"), err)
+	}
+
+	fmt.Printf(a.i18nMgr.Get("sending_email"), strings.Join(recipients, ", "))
+	err = core.SendEmail(core.SMTPSettings{
+		Host:     settings.Host,
+		Port:     settings.Port,
+		Username: settings.Username,
+		Password: password,
+		From:     settings.From,
+	}, recipients, message)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_send_email"), err)
+	}
+	fmt.Println(a.i18nMgr.Get("email_sent"))
+	return nil
+}
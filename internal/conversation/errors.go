@@ -0,0 +1,169 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// maxNameSuggestions bounds the did-you-mean choices offered for an unknown name
+const maxNameSuggestions = 3
+
+// printErrorHint follows an error with advice for its kind when it came from the database
+func (a *App) printErrorHint(err error) {
+	var queryErr *core.QueryError
+	if !errors.As(err, &queryErr) {
+		return
+	}
+	if hint := queryErr.Hint(a.i18nMgr); hint != "" {
+		fmt.Printf("💡 %s\n", hint)
+	}
+}
+
+// reportQueryError shows why a statement failed with a hint for the database it ran on.
+// With rerun, a misspelt table or column can be swapped for a close match and the
+// statement run again, and other mistakes in the statement handed to the AI to correct.
+func (a *App) reportQueryError(query string, err error, rerun func(string) error) {
+	var queryErr *core.QueryError
+	if !errors.As(err, &queryErr) {
+		fmt.Printf(a.i18nMgr.Get("query_failed"), err)
+		return
+	}
+	fmt.Printf(a.i18nMgr.Get("query_failed_kind"), a.i18nMgr.Get("error_kind_"+string(queryErr.Kind)), queryErr.Err)
+
+	name, unknown := core.UnknownName(queryErr)
+	var suggestions []string
+	if unknown {
+		suggestions = a.suggestNames(queryErr.Kind, name, query)
+	}
+	if len(suggestions) == 0 {
+		a.printErrorHint(queryErr)
+	}
+	if rerun == nil {
+		if len(suggestions) > 0 {
+			fmt.Printf(a.i18nMgr.Get("did_you_mean"), "`"+strings.Join(suggestions, "`, `")+"`")
+		}
+		return
+	}
+
+	if len(suggestions) > 0 {
+		if fixed, ok := a.chooseSuggestion(query, name, suggestions); ok {
+			if err := rerun(fixed); err != nil {
+				fmt.Printf(a.i18nMgr.Get("generic_error"), err)
+			}
+			return
+		}
+	}
+
+	if !queryErr.Fixable() || a.aiManager == nil || !a.aiManager.IsConfigured() {
+		return
+	}
+	if !a.confirm(a.i18nMgr.Get("ask_ai_to_fix_question")) {
+		return
+	}
+	if err := a.processAIChat(a.i18nMgr.GetWithArgs("ask_ai_to_fix_message", query, queryErr.Err)); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_error"), err)
+	}
+}
+
+// chooseSuggestion asks whether to run the query again with a suggested name. Enter or
+// y takes the first suggestion and a number picks another.
+func (a *App) chooseSuggestion(query, name string, suggestions []string) (string, bool) {
+	question := a.i18nMgr.GetWithArgs("did_you_mean_rerun", "`"+suggestions[0]+"`")
+	if len(suggestions) > 1 {
+		var choices []string
+		for i, suggestion := range suggestions {
+			choices = append(choices, fmt.Sprintf("%d) %s", i+1, suggestion))
+		}
+		question = a.i18nMgr.GetWithArgs("did_you_mean_choose", strings.Join(choices, "  "))
+	}
+	answer, err := a.readInput(question)
+	if err != nil {
+		return "", false
+	}
+
+	choice := 0
+	switch answer = strings.ToLower(strings.TrimSpace(answer)); answer {
+	case "", "y", "yes":
+	default:
+		n, err := strconv.Atoi(answer)
+		if err != nil || n < 1 || n > len(suggestions) {
+			return "", false
+		}
+		choice = n - 1
+	}
+
+	dialect := core.SQLite
+	if a.config != nil {
+		dialect = a.config.DatabaseType
+	}
+	fixed := core.ReplaceIdentifier(dialect, query, name, suggestions[choice])
+	fmt.Printf(a.i18nMgr.Get("query_truncated"), a.truncateQuery(fixed))
+	return fixed, true
+}
+
+// suggestNames finds names close to an unknown table or column in the cached schema:
+// tables for a table, and for a column the columns of the tables the query names. Tables
+// whose indexed description is similar follow the misspellings.
+func (a *App) suggestNames(kind core.ErrorKind, name, query string) []string {
+	schema := a.schemaForSuggestions(kind, query)
+	lowerQuery := strings.ToLower(query)
+
+	var candidates []string
+	for table, info := range schema {
+		if kind == core.ErrorUnknownTable {
+			candidates = append(candidates, table)
+			continue
+		}
+		short := strings.ToLower(table[strings.LastIndex(table, ".")+1:])
+		if info == nil || !strings.Contains(lowerQuery, short) {
+			continue
+		}
+		for _, col := range info.Columns {
+			candidates = append(candidates, col.Name)
+		}
+	}
+	slices.Sort(candidates)
+	suggestions := core.ClosestNames(name, candidates, maxNameSuggestions)
+
+	if kind == core.ErrorUnknownTable && a.aiManager != nil && len(suggestions) < maxNameSuggestions {
+		for _, table := range a.aiManager.SimilarTableNames(name, maxNameSuggestions) {
+			if len(suggestions) < maxNameSuggestions && !slices.Contains(suggestions, table) && !strings.EqualFold(table, name) {
+				suggestions = append(suggestions, table)
+			}
+		}
+	}
+	return suggestions
+}
+
+// schemaForSuggestions returns the cached schema, or when there is none, the table
+// names from the database with the definitions of the tables the query names
+func (a *App) schemaForSuggestions(kind core.ErrorKind, query string) map[string]*core.TableInfo {
+	if a.aiManager != nil {
+		if schema, err := a.aiManager.CachedSchema(); err == nil {
+			return schema
+		}
+	}
+	if a.connection == nil {
+		return nil
+	}
+	tables, err := a.connection.ListTables()
+	if err != nil {
+		return nil
+	}
+	schema := make(map[string]*core.TableInfo, len(tables))
+	lowerQuery := strings.ToLower(query)
+	for _, table := range tables {
+		schema[table] = nil
+		if kind == core.ErrorUnknownColumn && strings.Contains(lowerQuery, strings.ToLower(table)) {
+			if info, err := a.connection.DescribeTable(table); err == nil {
+				schema[table] = info
+			}
+		}
+	}
+	return schema
+}
@@ -0,0 +1,73 @@
+package conversation
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// estimateAffectedRows returns about how many rows an UPDATE or DELETE will change, as
+// the connection's estimate_rows asks. ok is false when it is not set, for other
+// statements and when the database cannot tell.
+func (a *App) estimateAffectedRows(query string) (rows float64, ok bool) {
+	if a.connection == nil || a.config == nil || a.config.EstimateRows == "" {
+		return 0, false
+	}
+	switch core.LeadingKeyword(query) {
+	case "UPDATE", "DELETE":
+	default:
+		return 0, false
+	}
+
+	if a.config.EstimateRows == core.EstimateRowsCount {
+		if count, ok := core.AffectedRowsQuery(query); ok {
+			if rows, err := a.countRows(count); err == nil {
+				return rows, true
+			}
+		}
+	}
+	// Statements a count cannot predict fall back to the planner
+	root, err := core.ExplainQuery(a.connection, a.config.DatabaseType, query, false)
+	if err != nil {
+		return 0, false
+	}
+	return root.EstimatedRows(), true
+}
+
+// countRows runs a SELECT count(*) on the connection directly, so the estimate is not
+// logged as a statement of its own
+func (a *App) countRows(query string) (float64, error) {
+	result, err := a.connection.Execute(query)
+	if err != nil {
+		return 0, err
+	}
+	defer result.Close()
+
+	var count string
+	err = result.ForEachRow(func(row []core.Value) error {
+		count = row[0].String()
+		return core.ErrStopRows
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count == "" {
+		return 0, errors.New("count returned no rows")
+	}
+	return strconv.ParseFloat(count, 64)
+}
+
+// formatRowCount writes a row count with thousands separators, as 12,340
+func formatRowCount(rows float64) string {
+	digits := strconv.FormatFloat(rows, 'f', 0, 64)
+	var sb strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(d)
+	}
+	return sb.String()
+}
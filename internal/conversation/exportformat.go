@@ -0,0 +1,51 @@
+package conversation
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// Flags after the export target that choose a format its extension does not
+const (
+	withSchemaFlag = "--with-schema" // out.jsonl --with-schema: a schema line, then typed values
+	fixedWidthFlag = "--fixed-width" // out.txt --fixed-width: aligned plain-text columns
+)
+
+// splitFormatFlag takes a format flag out of the export target after " > ", wherever it
+// is among the other flags. The formats write local files, JSONL for --with-schema.
+func (a *App) splitFormatFlag(target string) (string, string, error) {
+	fields := strings.Fields(target)
+	i := slices.IndexFunc(fields, func(field string) bool {
+		return field == withSchemaFlag || field == fixedWidthFlag
+	})
+	if i < 0 {
+		return target, "", nil
+	}
+	format := fields[i]
+	target = strings.Join(slices.Delete(fields, i, i+1), " ")
+	file, _, _ := strings.Cut(target, " --")
+	remote := core.IsStreamTarget(file) || core.IsSheetsTarget(file) || core.IsObjectStoreTarget(file)
+	switch {
+	case format == withSchemaFlag && (remote || !core.IsJSONLFile(file)):
+		return "", "", errors.New(a.i18nMgr.Get("with_schema_needs_jsonl"))
+	case format == fixedWidthFlag && remote:
+		return "", "", errors.New(a.i18nMgr.Get("fixed_width_needs_file"))
+	}
+	return target, format, nil
+}
+
+// saveWithFormat writes a result to a local file in the format of a flag, or the one
+// its extension implies
+func saveWithFormat(result *core.QueryResult, target, format string) (int, error) {
+	switch format {
+	case withSchemaFlag:
+		return core.SaveQueryResultAsJSONL(result, target, true)
+	case fixedWidthFlag:
+		return core.SaveQueryResultAsFixedWidth(result, target)
+	default:
+		return core.SaveQueryResultToFile(result, target)
+	}
+}
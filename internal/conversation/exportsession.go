@@ -0,0 +1,111 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// maxTranscriptQueries is how many of the latest statements /export-session can include
+const maxTranscriptQueries = 500
+
+// recordForExport keeps a finished statement, with the first rows of its result when it
+// has one, for /export-session
+func (a *App) recordForExport(query string, start time.Time, result *core.QueryResult, err error) {
+	entry := core.ReportQuery{
+		Query:     query,
+		StartedAt: start,
+		Duration:  time.Since(start),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else if result != nil {
+		entry.Rows = result.RowCount()
+		entry.Columns = result.ColumnNames()
+		entry.Sample = sampleCells(result, a.sensitive.Mask(query, result.Columns))
+	}
+	a.transcript = append(a.transcript, entry)
+	if len(a.transcript) > maxTranscriptQueries {
+		a.transcript = a.transcript[len(a.transcript)-maxTranscriptQueries:]
+	}
+}
+
+// handleExportSession writes the session so far - statements with their timings and first
+// rows, and the AI exchanges - to one markdown or HTML document with a table of contents:
+// /export-session [file.md|file.html] [--html]
+func (a *App) handleExportSession(args []string) error {
+	var path string
+	asHTML := false
+	for _, arg := range args {
+		switch {
+		case arg == "--html":
+			asHTML = true
+		case path == "" && !strings.HasPrefix(arg, "--"):
+			path = arg
+		default:
+			fmt.Println(a.i18nMgr.Get("usage_export_session"))
+			return nil
+		}
+	}
+	if a.config == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	if path == "" {
+		resultsDir := filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), "results")
+		if err := os.MkdirAll(resultsDir, 0755); err != nil {
+			return fmt.Errorf("%s: %w", a.i18nMgr.Get("failed_to_create_results_dir"), err)
+		}
+		ext := ".md"
+		if asHTML {
+			ext = ".html"
+		}
+		path = filepath.Join(resultsDir, "session_"+time.Now().Format("20060102_150405")+ext)
+	} else if ext := strings.ToLower(filepath.Ext(path)); ext == ".html" || ext == ".htm" {
+		asHTML = true
+	}
+
+	content := core.FormatSessionReport(a.sessionReport(), a.i18nMgr)
+	if asHTML {
+		var err error
+		title := a.i18nMgr.Get("session_report_title") + " - " + a.config.Name
+		if content, err = core.MarkdownToHTML(title, content); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_export_session"), err)
+		}
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_export_session"), err)
+	}
+	fmt.Printf(a.i18nMgr.Get("session_exported"), len(a.transcript), path)
+	return nil
+}
+
+// sessionReport gathers the statements and AI exchanges since the connection was opened
+func (a *App) sessionReport() *core.SessionReport {
+	report := &core.SessionReport{
+		Connection: a.config.Name,
+		Database:   a.config.Database,
+		Started:    a.started,
+		Queries:    a.transcript,
+	}
+	if a.aiManager != nil {
+		for _, entry := range a.aiManager.GetPromptHistory() {
+			if entry.Timestamp.Before(a.started) {
+				continue
+			}
+			report.Exchanges = append(report.Exchanges, core.ReportExchange{
+				Timestamp: entry.Timestamp,
+				Question:  entry.UserMessage,
+				Answer:    entry.AIResponse,
+				Model:     fmt.Sprintf("%s/%s", entry.Provider, entry.Model),
+				Latency:   entry.Latency,
+			})
+		}
+	}
+	return report
+}
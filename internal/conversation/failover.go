@@ -0,0 +1,169 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/config"
+)
+
+// handleAIConfigFallback handles /config ai fallback [provider[:model] ...|none]
+func (a *App) handleAIConfigFallback(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+	if len(args) == 0 {
+		a.printFallbacks()
+		return nil
+	}
+
+	var fallbacks []config.FallbackConfig
+	if !(len(args) == 1 && args[0] == "none") {
+		for _, arg := range args {
+			fallback, ok := parseFallback(arg)
+			if !ok {
+				fmt.Printf(a.i18nMgr.Get("unknown_fallback_provider"), arg)
+				fmt.Println(a.i18nMgr.Get("usage_config_ai_fallback"))
+				return nil
+			}
+			fallbacks = append(fallbacks, fallback)
+		}
+	}
+
+	if err := a.aiManager.SetFallbacks(fallbacks); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_ai_config"), err)
+	}
+	a.printFallbacks()
+	return nil
+}
+
+// parseFallback reads provider or provider:model. Ollama model names contain a colon
+// of their own, so only the first one separates the provider.
+func parseFallback(arg string) (config.FallbackConfig, bool) {
+	name, model, _ := strings.Cut(arg, ":")
+	provider := config.Provider(strings.ToLower(name))
+	if !provider.IsKnown() {
+		return config.FallbackConfig{}, false
+	}
+	return config.FallbackConfig{Provider: provider, Model: model}, true
+}
+
+// printFallbacks shows the provider chain tried for each AI request
+func (a *App) printFallbacks() {
+	cfg := a.aiManager.GetConfig()
+	if len(cfg.AI.Fallbacks) == 0 {
+		fmt.Println(a.i18nMgr.Get("ai_no_fallbacks"))
+		return
+	}
+	chain := []string{cfg.FormatProviderInfo()}
+	for _, fallback := range cfg.AI.Fallbacks {
+		chain = append(chain, fmt.Sprintf("%s/%s", fallback.Provider, cfg.FallbackModel(fallback)))
+	}
+	fmt.Printf(a.i18nMgr.Get("ai_fallback_chain"), strings.Join(chain, " → "))
+}
+
+// handleAIConfigBudget handles /config ai budget [usd|off]
+func (a *App) handleAIConfigBudget(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+	if len(args) == 0 {
+		a.printDailyBudget()
+		return nil
+	}
+
+	budget := 0.0
+	if args[0] != "off" {
+		value, err := strconv.ParseFloat(strings.TrimPrefix(args[0], "$"), 64)
+		if err != nil || value <= 0 {
+			fmt.Println(a.i18nMgr.Get("usage_config_ai_budget"))
+			return nil
+		}
+		budget = value
+	}
+
+	if err := a.aiManager.SetDailyBudget(budget); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_ai_config"), err)
+	}
+	a.printDailyBudget()
+	return nil
+}
+
+func (a *App) printDailyBudget() {
+	budget := a.aiManager.GetConfig().AI.DailyBudget
+	if budget <= 0 {
+		fmt.Println(a.i18nMgr.Get("ai_budget_unlimited"))
+		return
+	}
+	fmt.Printf(a.i18nMgr.Get("ai_budget_status"), budget)
+}
+
+// handleAIConfigConfirm handles /config ai confirm [on|off [provider]]
+func (a *App) handleAIConfigConfirm(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+	if len(args) == 0 {
+		a.printSendConfirmation()
+		return nil
+	}
+	if (args[0] != "on" && args[0] != "off") || len(args) > 2 {
+		fmt.Println(a.i18nMgr.Get("usage_config_ai_confirm"))
+		return nil
+	}
+
+	provider := a.aiManager.GetConfig().AI.Provider
+	if len(args) == 2 {
+		provider = config.Provider(strings.ToLower(args[1]))
+		if !provider.IsKnown() {
+			fmt.Printf(a.i18nMgr.Get("unknown_fallback_provider"), args[1])
+			fmt.Println(a.i18nMgr.Get("usage_config_ai_confirm"))
+			return nil
+		}
+	}
+	if !provider.IsPaid() {
+		fmt.Printf(a.i18nMgr.Get("ai_confirm_free_provider"), provider)
+		return nil
+	}
+
+	if err := a.aiManager.SetSendConfirmation(provider, args[0] == "on"); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_ai_config"), err)
+	}
+	a.printSendConfirmation()
+	return nil
+}
+
+// printSendConfirmation shows which providers ask before a chat message is sent
+func (a *App) printSendConfirmation() {
+	var providers []string
+	for _, provider := range a.aiManager.GetConfig().AI.ConfirmSend {
+		providers = append(providers, string(provider))
+	}
+	if len(providers) == 0 {
+		fmt.Println(a.i18nMgr.Get("ai_confirm_off"))
+		return
+	}
+	fmt.Printf(a.i18nMgr.Get("ai_confirm_status"), strings.Join(providers, ", "))
+}
+
+// confirmAISend asks before a chat message goes to a paid provider that has
+// confirmation turned on, showing the estimated tokens and cost
+func (a *App) confirmAISend(message string, tables []string) bool {
+	if !a.aiManager.NeedsSendConfirmation() {
+		return true
+	}
+	estimate := a.aiManager.EstimateChat(message, tables)
+	return a.confirm(a.i18nMgr.GetWithArgs("ai_confirm_send", estimate.Model,
+		estimate.InputTokens+estimate.OutputTokens, formatEstimatedCost(estimate.Cost)))
+}
+
+// formatEstimatedCost rounds an estimate to cents, since more digits would suggest a
+// precision the estimate does not have
+func formatEstimatedCost(cost float64) string {
+	if cost < 0.01 {
+		return "<$0.01"
+	}
+	return fmt.Sprintf("$%.2f", cost)
+}
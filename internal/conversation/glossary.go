@@ -0,0 +1,55 @@
+package conversation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sqlterm/internal/ai"
+)
+
+// glossaryPath returns the glossary file of the active connection
+func (a *App) glossaryPath() string {
+	return filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), ai.GlossaryFile)
+}
+
+// loadGlossary reads the active connection's glossary into the AI manager
+func (a *App) loadGlossary() (*ai.Glossary, error) {
+	glossary, err := ai.LoadGlossary(a.glossaryPath())
+	if err != nil {
+		return nil, err
+	}
+	a.aiManager.SetGlossary(glossary)
+	return glossary, nil
+}
+
+// handleGlossary reloads and lists the business terms defined for the connection
+func (a *App) handleGlossary() error {
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+	if a.config == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	glossary, err := a.loadGlossary()
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_glossary"), a.glossaryPath(), err)
+	}
+	if len(glossary.Terms) == 0 {
+		fmt.Printf(a.i18nMgr.Get("glossary_empty"), a.glossaryPath())
+		return nil
+	}
+
+	fmt.Printf(a.i18nMgr.Get("glossary_header"), len(glossary.Terms), a.glossaryPath())
+	for _, term := range glossary.Terms {
+		name := term.Term
+		if len(term.Aliases) > 0 {
+			name += " (" + strings.Join(term.Aliases, ", ") + ")"
+		}
+		fmt.Printf("  %s = %s\n", name, strings.TrimSpace(term.Definition))
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package conversation
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/config"
+)
+
+// printProviderHealth probes a local provider for /config ai status and explains
+// what to fix when it is not ready to answer
+func (a *App) printProviderHealth(model string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	health, ok := a.aiManager.CheckProviderHealth(ctx)
+	if !ok {
+		return
+	}
+
+	if !health.Reachable {
+		fmt.Printf(a.i18nMgr.Get("health_unreachable"), health.Provider, health.BaseURL)
+		fmt.Printf("   💡 %s\n", a.unreachableHint(health))
+		return
+	}
+
+	version := health.Version
+	if version == "" {
+		version = "-"
+	}
+	fmt.Printf(a.i18nMgr.Get("health_reachable"), health.Provider, health.BaseURL, version, health.Latency.Round(time.Millisecond))
+	loaded := strings.Join(health.LoadedModels, ", ")
+	if loaded == "" {
+		loaded = "-"
+	}
+	fmt.Printf(a.i18nMgr.Get("health_loaded_models"), loaded)
+
+	switch {
+	case health.IsLoaded(model):
+	case health.HasModel(model):
+		fmt.Printf(a.i18nMgr.Get("health_model_not_loaded"), model)
+	case health.Provider == config.ProviderOllama:
+		fmt.Printf(a.i18nMgr.Get("health_hint_ollama_pull"), model, model)
+	default:
+		fmt.Printf(a.i18nMgr.Get("health_hint_lmstudio_load"), model)
+	}
+}
+
+// unreachableHint turns a failed probe into the step most likely to fix it
+func (a *App) unreachableHint(health *ai.ProviderHealth) string {
+	host := health.BaseURL
+	if u, err := url.Parse(health.BaseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	msg := strings.ToLower(health.Err.Error())
+	switch {
+	case strings.Contains(msg, "connection refused"):
+		if health.Provider == config.ProviderOllama {
+			return a.i18nMgr.GetWithArgs("health_hint_ollama_not_running", host)
+		}
+		return a.i18nMgr.GetWithArgs("health_hint_lmstudio_not_running", host)
+	case strings.Contains(msg, "no such host"):
+		return a.i18nMgr.GetWithArgs("health_hint_bad_host", host, health.Provider)
+	case strings.Contains(msg, "timeout") || strings.Contains(msg, "deadline exceeded"):
+		return a.i18nMgr.GetWithArgs("health_hint_timeout", host)
+	case strings.Contains(msg, "status 404"):
+		return a.i18nMgr.GetWithArgs("health_hint_wrong_endpoint", health.BaseURL, health.Provider, health.Provider)
+	default:
+		return health.Err.Error()
+	}
+}
@@ -0,0 +1,63 @@
+package conversation
+
+import (
+	"fmt"
+	"strconv"
+
+	"sqlterm/internal/core"
+)
+
+// histogramBarWidth is the length of the longest bar /hist draws
+const histogramBarWidth = 40
+
+// handleHistogram draws the distribution of a numeric column:
+// /hist orders.total [--buckets 20]
+func (a *App) handleHistogram(args []string) error {
+	buckets := core.DefaultHistogramBuckets
+	var target string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--buckets" && i+1 < len(args):
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 || n > core.MaxHistogramBuckets {
+				fmt.Println(a.i18nMgr.Get("usage_hist"))
+				return nil
+			}
+			buckets = n
+			i++
+		case target == "":
+			target = args[i]
+		default:
+			fmt.Println(a.i18nMgr.Get("usage_hist"))
+			return nil
+		}
+	}
+	if target == "" {
+		fmt.Println(a.i18nMgr.Get("usage_hist"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	table, columns := a.profileTarget(target)
+	if len(columns) != 1 {
+		fmt.Println(a.i18nMgr.Get("usage_hist"))
+		return nil
+	}
+	h, err := core.ColumnHistogram(a.connection, a.config.DatabaseType, table, columns[0], buckets)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_build_histogram"), target, err)
+	}
+
+	fmt.Printf(a.i18nMgr.Get("hist_summary"), h.Table, h.Column, h.Rows, h.Profile.Nulls)
+	if len(h.Counts) == 0 {
+		fmt.Println(a.i18nMgr.Get("hist_all_null"))
+		return nil
+	}
+	fmt.Printf(a.i18nMgr.Get("hist_range"), h.Profile.Min, h.Profile.Max, h.Profile.Distribution)
+	fmt.Println()
+	fmt.Print(h.Render(histogramBarWidth))
+	return nil
+}
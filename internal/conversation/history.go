@@ -0,0 +1,103 @@
+package conversation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/session"
+)
+
+// maxHistoryMatches is how many of the most recent matches /history search shows
+const maxHistoryMatches = 50
+
+// handleHistory handles /history [--all] search <term>: lines of the current history,
+// or with --all of every connection's and the global one, that contain the term. A
+// match can then be copied into the input line to edit and run.
+func (a *App) handleHistory(args []string) error {
+	all := len(args) > 0 && args[0] == "--all"
+	if all {
+		args = args[1:]
+	}
+	if len(args) < 2 || args[0] != "search" {
+		fmt.Println(a.i18nMgr.Get("usage_history"))
+		return nil
+	}
+	term := strings.Trim(strings.Join(args[1:], " "), `"'`)
+	if term == "" {
+		fmt.Println(a.i18nMgr.Get("usage_history"))
+		return nil
+	}
+
+	names, err := a.historyNames(all)
+	if err != nil {
+		return err
+	}
+	matches, err := a.sessionMgr.SearchHistory(term, names)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		fmt.Println(a.i18nMgr.GetWithArgs("history_no_matches", term))
+		return nil
+	}
+	if len(matches) > maxHistoryMatches {
+		fmt.Println(a.i18nMgr.GetWithArgs("history_matches_truncated", len(matches), maxHistoryMatches))
+		matches = matches[len(matches)-maxHistoryMatches:]
+	}
+
+	for i, match := range matches {
+		fmt.Printf("%3d. %s\n", i+1, match.Line)
+		fmt.Printf("     %s\n", a.formatHistorySource(match))
+	}
+	a.copyHistoryMatch(matches)
+	return nil
+}
+
+// historyNames returns the histories to search: every connection's and the global one,
+// or the one in use
+func (a *App) historyNames(all bool) ([]string, error) {
+	switch {
+	case all:
+		connections, err := a.sessionMgr.HistoryConnections()
+		if err != nil {
+			return nil, err
+		}
+		return append(connections, ""), nil
+	case a.config != nil:
+		return []string{a.config.Name}, nil
+	default:
+		return []string{""}, nil
+	}
+}
+
+// formatHistorySource says which history a match is from and when it last ran
+func (a *App) formatHistorySource(match session.HistoryMatch) string {
+	source := match.Connection
+	if source == "" {
+		source = a.i18nMgr.Get("history_global")
+	}
+	if match.LastRun.IsZero() {
+		return source
+	}
+	return source + " · " + match.LastRun.Local().Format("2006-01-02 15:04")
+}
+
+// copyHistoryMatch offers to put one of the matches into the next input line
+func (a *App) copyHistoryMatch(matches []session.HistoryMatch) {
+	if a.rl == nil {
+		return
+	}
+	answer, err := a.readInput(a.i18nMgr.Get("history_copy_prompt"))
+	if err != nil || answer == "" {
+		return
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(matches) {
+		fmt.Println(a.i18nMgr.GetWithArgs("history_invalid_choice", answer))
+		return
+	}
+	if _, err := a.rl.WriteStdin([]byte(matches[n-1].Line)); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	}
+}
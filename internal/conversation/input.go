@@ -0,0 +1,65 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+// readInput prompts on the readline instance and restores the REPL prompt afterwards
+func (a *App) readInput(prompt string) (string, error) {
+	if a.rl == nil {
+		return "", errors.New(a.i18nMgr.Get("interactive_input_unavailable"))
+	}
+	defer a.updatePrompt()
+
+	a.rl.SetPrompt(prompt)
+	// A block pasted into the answer is part of it
+	line, _, err := a.readLine()
+	if err != nil {
+		return "", fmt.Errorf(a.i18nMgr.Get("failed_to_read_input"), err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// readSecret prompts for a password or key without echoing what is typed
+func (a *App) readSecret(prompt string) (string, error) {
+	if a.rl == nil {
+		return "", errors.New(a.i18nMgr.Get("interactive_input_unavailable"))
+	}
+	defer a.updatePrompt()
+
+	secret, err := a.rl.ReadPassword(prompt)
+	if err != nil {
+		return "", fmt.Errorf(a.i18nMgr.Get("failed_to_read_input"), err)
+	}
+	return string(secret), nil
+}
+
+// resolvePassword fills in the password of a saved connection for opening it, running
+// its password_command or asking for it without echo
+func (a *App) resolvePassword(cfg *core.ConnectionConfig) (*core.ConnectionConfig, error) {
+	resolved, err := config.ResolvePassword(cfg, func(c *core.ConnectionConfig) (string, error) {
+		return a.readSecret(a.i18nMgr.GetWithArgs("enter_password_for", c.Username, c.Host))
+	})
+	if err != nil {
+		return nil, fmt.Errorf(a.i18nMgr.Get("failed_to_get_password"), err)
+	}
+	return resolved, nil
+}
+
+// confirm asks a yes/no question and defaults to no
+func (a *App) confirm(question string) bool {
+	answer, err := a.readInput(question + " [y/N]: ")
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
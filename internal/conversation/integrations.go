@@ -0,0 +1,197 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+func (a *App) integrationsConfig() config.IntegrationsConfig {
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.Integrations
+		}
+	}
+	return config.IntegrationsConfig{}
+}
+
+// handleConfigIntegrations shows or sets up the services results can be exported to:
+// /config integrations sheets signs in to Google for > sheets:// exports,
+// /config integrations smtp sets the mail server for --email, and remove after either
+// forgets its settings
+func (a *App) handleConfigIntegrations(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+
+	if len(args) == 0 || args[0] == "status" {
+		fmt.Println(a.i18nMgr.Get("integrations_config_title"))
+		if sheets := a.integrationsConfig().Sheets; sheets.RefreshToken != "" {
+			fmt.Printf(a.i18nMgr.Get("sheets_status_authorized"), sheets.ClientID)
+		} else {
+			fmt.Println(a.i18nMgr.Get("sheets_status_not_authorized"))
+		}
+		if smtp := a.integrationsConfig().SMTP; smtp.Host != "" {
+			fmt.Printf(a.i18nMgr.Get("smtp_status_configured"), smtp.Host, smtp.Port, smtp.From)
+		} else {
+			fmt.Println(a.i18nMgr.Get("smtp_status_not_configured"))
+		}
+		return nil
+	}
+
+	switch {
+	case args[0] == "sheets" && len(args) == 1:
+		return a.authorizeSheets()
+	case args[0] == "sheets" && len(args) == 2 && args[1] == "remove":
+		integrations := a.integrationsConfig()
+		integrations.Sheets = config.SheetsConfig{}
+		if err := a.aiManager.SetIntegrationsConfig(integrations); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_update_integrations_config"), err)
+		}
+		fmt.Println(a.i18nMgr.Get("sheets_removed"))
+		return nil
+	case args[0] == "smtp" && len(args) == 1:
+		return a.configureSMTP()
+	case args[0] == "smtp" && len(args) == 2 && args[1] == "remove":
+		integrations := a.integrationsConfig()
+		integrations.SMTP = config.SMTPConfig{}
+		if err := a.aiManager.SetIntegrationsConfig(integrations); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_update_integrations_config"), err)
+		}
+		fmt.Println(a.i18nMgr.Get("smtp_removed"))
+		return nil
+	default:
+		return a.printConfigIntegrationsHelp()
+	}
+}
+
+// authorizeSheets asks for a Google OAuth client of the Desktop app type and signs in
+// with it in the browser, saving the refresh token exports use
+func (a *App) authorizeSheets() error {
+	fmt.Println(a.i18nMgr.Get("sheets_setup_intro"))
+	sheets := a.integrationsConfig().Sheets
+	clientID, err := a.readInput(a.i18nMgr.Get("sheets_client_id_prompt"))
+	if err != nil {
+		return err
+	}
+	if clientID != "" {
+		sheets.ClientID = clientID
+	}
+	clientSecret, err := a.readSecret(a.i18nMgr.Get("sheets_client_secret_prompt"))
+	if err != nil {
+		return err
+	}
+	if clientSecret != "" {
+		sheets.ClientSecret = clientSecret
+	}
+	if sheets.ClientID == "" || sheets.ClientSecret == "" {
+		return errors.New(a.i18nMgr.Get("sheets_client_required"))
+	}
+
+	refreshToken, err := core.AuthorizeSheets(sheets.ClientID, sheets.ClientSecret, func(url string) {
+		fmt.Printf(a.i18nMgr.Get("sheets_open_url"), url)
+	})
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_authorize_sheets"), err)
+	}
+	sheets.RefreshToken = refreshToken
+
+	integrations := a.integrationsConfig()
+	integrations.Sheets = sheets
+	if err := a.aiManager.SetIntegrationsConfig(integrations); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_integrations_config"), err)
+	}
+	fmt.Println(a.i18nMgr.Get("sheets_authorized"))
+	return nil
+}
+
+// configureSMTP asks for the mail server exported files are emailed through. Enter keeps
+// a saved value.
+func (a *App) configureSMTP() error {
+	smtp := a.integrationsConfig().SMTP
+	if smtp.Port == 0 {
+		smtp.Port = 587
+	}
+	ask := func(key string, value *string) error {
+		answer, err := a.readInput(a.i18nMgr.GetWithArgs(key, *value))
+		if err != nil {
+			return err
+		}
+		if answer != "" {
+			*value = answer
+		}
+		return nil
+	}
+
+	port := strconv.Itoa(smtp.Port)
+	if err := ask("smtp_host_prompt", &smtp.Host); err != nil {
+		return err
+	}
+	if err := ask("smtp_port_prompt", &port); err != nil {
+		return err
+	}
+	if err := ask("smtp_username_prompt", &smtp.Username); err != nil {
+		return err
+	}
+	if smtp.Username != "" && smtp.PasswordCommand == "" {
+		password, err := a.readSecret(a.i18nMgr.Get("smtp_password_prompt"))
+		if err != nil {
+			return err
+		}
+		if password != "" {
+			smtp.Password = password
+		}
+	}
+	if smtp.From == "" {
+		smtp.From = smtp.Username
+	}
+	if err := ask("smtp_from_prompt", &smtp.From); err != nil {
+		return err
+	}
+
+	var err error
+	if smtp.Port, err = strconv.Atoi(port); err != nil || smtp.Port <= 0 {
+		return fmt.Errorf(a.i18nMgr.Get("invalid_smtp_port"), port)
+	}
+	if smtp.Host == "" || smtp.From == "" {
+		return errors.New(a.i18nMgr.Get("smtp_host_required"))
+	}
+
+	integrations := a.integrationsConfig()
+	integrations.SMTP = smtp
+	if err := a.aiManager.SetIntegrationsConfig(integrations); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_integrations_config"), err)
+	}
+	fmt.Println(a.i18nMgr.Get("smtp_configured"))
+	return nil
+}
+
+// exportToSheet replaces a Google Sheets tab with a result, for > sheets://<id>/<tab>
+func (a *App) exportToSheet(result *core.QueryResult, target string) (int, error) {
+	sheets := a.integrationsConfig().Sheets
+	if sheets.RefreshToken == "" {
+		result.Close()
+		return 0, errors.New(a.i18nMgr.Get("sheets_not_configured"))
+	}
+	rows, err := core.ExportToSheet(result, target, core.SheetsCredentials{
+		ClientID:     sheets.ClientID,
+		ClientSecret: sheets.ClientSecret,
+		RefreshToken: sheets.RefreshToken,
+	})
+	if err != nil {
+		return rows, err
+	}
+	if id, _, err := core.ParseSheetsTarget(target); err == nil {
+		fmt.Printf("📍 %s: %s\n", a.i18nMgr.Get("file_location"), core.SheetURL(id))
+	}
+	return rows, nil
+}
+
+func (a *App) printConfigIntegrationsHelp() error {
+	fmt.Print(a.i18nMgr.Get("help_config_integrations_title"))
+	fmt.Print(a.i18nMgr.Get("help_config_integrations_commands"))
+	return nil
+}
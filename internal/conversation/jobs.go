@@ -0,0 +1,226 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+	"sqlterm/internal/session"
+)
+
+// startJob runs a statement ending in & on a connection of its own and returns to the
+// prompt at once. Policy checks and password prompts happen before it leaves.
+func (a *App) startJob(query string) error {
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	if strings.Contains(query, " > ") {
+		return errors.New(a.i18nMgr.Get("job_export_unsupported"))
+	}
+
+	unmask := a.unmaskNext
+	a.unmaskNext = false
+	query, steps := core.SplitPipeline(query)
+	query, err := core.ExpandVariables(query, a.vars)
+	if err != nil {
+		return err
+	}
+	if err := a.enforceConnectionPolicy(query); err != nil {
+		return err
+	}
+	approved := a.approved
+	a.approved = nil
+	if a.inTransaction {
+		fmt.Println(a.i18nMgr.Get("job_transaction_warning"))
+	}
+
+	cfg := a.config
+	resolved, err := a.resolvePassword(cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := core.NewConnection(resolved)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_connect"), core.ClassifyError(err, resolved.DatabaseType))
+	}
+	if err := a.sessionMgr.EnsureSessionDir(cfg.Name); err != nil {
+		conn.Close()
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_session_dir"), err)
+	}
+
+	job := &backgroundJob{
+		conn:       conn,
+		config:     cfg,
+		approved:   approved,
+		query:      query,
+		steps:      steps,
+		formatters: a.valueFormatters(),
+		sensitive:  a.sensitive,
+		unmask:     unmask,
+		resultsDir: filepath.Join(a.sessionMgr.GetSessionDir(cfg.Name), "results"),
+		queryLog:   a.queryLog,
+	}
+	started := a.jobs.Start(query, cfg.Name, func(ctx context.Context, id int) (int, string, error) {
+		defer job.conn.Close()
+		start := time.Now()
+		rows, path, err := a.runJob(ctx, job, id)
+		// Session stats belong to the REPL, so jobs are left out of them
+		a.auditOn(job.config, job.approved, job.query, start, rows, err)
+		if err := recordQueryLog(job.queryLog, job.query, start, rows, err); err != nil {
+			fmt.Fprintf(a.notifyWriter(), a.i18nMgr.Get("query_log_warning"), err)
+		}
+		return rows, path, err
+	}, a.reportJob)
+	fmt.Printf(a.i18nMgr.Get("job_started"), started.ID, a.truncateQuery(oneLine(query)), started.ID)
+	return nil
+}
+
+// backgroundJob is what a job takes from the session that started it, which may have
+// moved to another connection by the time the job ends
+type backgroundJob struct {
+	conn       core.Connection
+	config     *core.ConnectionConfig
+	approved   *approvedStatement
+	query      string
+	steps      []core.PipeStep
+	formatters []core.ValueFormatter
+	sensitive  *core.SensitiveColumns
+	unmask     bool
+	resultsDir string
+	queryLog   *session.QueryLog
+}
+
+// runJob executes a background statement and saves its result as markdown in the
+// session's results directory
+func (a *App) runJob(ctx context.Context, job *backgroundJob, id int) (int, string, error) {
+	result, err := core.ExecuteContext(ctx, job.conn, job.query)
+	if err != nil {
+		return 0, "", err
+	}
+	formatters := job.formatters
+	if mask := job.sensitive.Mask(job.query, result.Columns); mask != nil && !job.unmask {
+		formatters = append([]core.ValueFormatter{mask}, formatters...)
+	}
+	result.SetFormatters(formatters...)
+	if err := result.Pipe(job.steps...); err != nil {
+		result.Close()
+		return 0, "", err
+	}
+
+	if err := os.MkdirAll(job.resultsDir, 0755); err != nil {
+		result.Close()
+		return 0, "", fmt.Errorf(a.i18nMgr.Get("failed_to_create_results_dir"), job.resultsDir, err)
+	}
+	path := filepath.Join(job.resultsDir, fmt.Sprintf("job_%d_%s.md", id, time.Now().Format("20060102_150405")))
+	file, err := os.Create(path)
+	if err != nil {
+		result.Close()
+		return 0, "", err
+	}
+	defer file.Close()
+
+	var header strings.Builder
+	header.WriteString(fmt.Sprintf("# %s - %s\n\n", a.i18nMgr.GetWithArgs("job_results_header", id), time.Now().Format("2006-01-02 15:04:05")))
+	header.WriteString(fmt.Sprintf("**%s:** %s\n\n", a.i18nMgr.Get("connection_header"), job.config.Name))
+	file.WriteString(header.String())
+	// Writing the markdown reads the rows and closes the result
+	err = core.SaveQueryResultAsMarkdown(result, job.query, job.config.Name, file, a.i18nMgr)
+	if err == nil {
+		err = result.Error()
+	}
+	if err != nil {
+		return result.RowCount(), "", err
+	}
+	return result.RowCount(), path, nil
+}
+
+// reportJob tells the user a background statement has ended, whatever they are doing
+func (a *App) reportJob(job core.Job) {
+	query := a.truncateQuery(oneLine(job.Query))
+	elapsed := job.Elapsed().Round(time.Millisecond)
+	switch job.Status {
+	case core.JobDone:
+		fmt.Fprintf(a.notifyWriter(), a.i18nMgr.Get("job_done"), job.ID, query, formatRowCount(float64(job.Rows)), elapsed, job.ID)
+	case core.JobCancelled:
+		fmt.Fprintf(a.notifyWriter(), a.i18nMgr.Get("job_cancelled"), job.ID, query)
+	default:
+		fmt.Fprintf(a.notifyWriter(), a.i18nMgr.Get("job_failed"), job.ID, query, job.Error)
+	}
+}
+
+// handleJobs handles /jobs, /jobs result <id> and /jobs cancel <id>
+func (a *App) handleJobs(args []string) error {
+	if len(args) == 0 {
+		a.showJobs()
+		return nil
+	}
+	if len(args) != 2 || (args[0] != "result" && args[0] != "cancel") {
+		fmt.Println(a.i18nMgr.Get("usage_jobs"))
+		return nil
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err != nil {
+		fmt.Println(a.i18nMgr.Get("usage_jobs"))
+		return nil
+	}
+	job, ok := a.jobs.Get(id)
+	if !ok {
+		fmt.Printf(a.i18nMgr.Get("job_not_found"), id)
+		return nil
+	}
+
+	if args[0] == "cancel" {
+		if a.jobs.Cancel(id) {
+			fmt.Printf(a.i18nMgr.Get("job_cancelling"), id)
+		} else {
+			fmt.Printf(a.i18nMgr.Get("job_not_running"), id)
+		}
+		return nil
+	}
+
+	switch job.Status {
+	case core.JobRunning:
+		fmt.Printf(a.i18nMgr.Get("job_still_running"), id, job.Elapsed().Round(time.Second))
+	case core.JobDone:
+		if err := a.sessionMgr.ViewMarkdown(job.ResultPath); err != nil {
+			fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+		}
+		fmt.Printf("📍 %s: %s\n", a.i18nMgr.Get("file_location"), job.ResultPath)
+	case core.JobCancelled:
+		fmt.Printf(a.i18nMgr.Get("job_cancelled"), id, a.truncateQuery(oneLine(job.Query)))
+	default:
+		fmt.Printf(a.i18nMgr.Get("job_failed"), id, a.truncateQuery(oneLine(job.Query)), job.Error)
+	}
+	return nil
+}
+
+// showJobs lists the background statements of this session with their state
+func (a *App) showJobs() {
+	jobs := a.jobs.Items()
+	if len(jobs) == 0 {
+		fmt.Println(a.i18nMgr.Get("jobs_empty"))
+		return
+	}
+
+	fmt.Println(a.i18nMgr.Get("jobs_header"))
+	for _, job := range jobs {
+		status := a.i18nMgr.Get("job_status_running")
+		switch job.Status {
+		case core.JobDone:
+			status = a.i18nMgr.GetWithArgs("job_status_done", formatRowCount(float64(job.Rows)))
+		case core.JobFailed:
+			status = a.i18nMgr.Get("job_status_failed")
+		case core.JobCancelled:
+			status = a.i18nMgr.Get("job_status_cancelled")
+		}
+		fmt.Printf("  #%d  %s  %-8s  %s  %s  %s\n", job.ID, job.Started.Format("15:04:05"), job.Elapsed().Round(time.Second),
+			job.Connection, status, a.truncateQuery(oneLine(job.Query)))
+	}
+}
@@ -0,0 +1,101 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// queriesDir is the query library: repl.queries_dir from the config, or ./queries
+func (a *App) queriesDir() string {
+	if dir := a.replConfig().QueriesDir; dir != "" {
+		return dir
+	}
+	return core.DefaultQueriesDir
+}
+
+// resolveQueryFile finds the file an @ reference names: a path as typed, or a file or
+// query name in the library, where the .sql extension may be left off
+func (a *App) resolveQueryFile(name string) (string, bool) {
+	candidates := []string{name, filepath.Join(a.queriesDir(), name)}
+	if filepath.Ext(name) == "" {
+		candidates = append(candidates, filepath.Join(a.queriesDir(), name+".sql"))
+	}
+	for _, path := range candidates {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// handleQueries browses the query library: /queries [list] shows every query with its
+// front matter and /queries search <term> finds queries by name, description or SQL.
+// Library queries run with @name.
+func (a *App) handleQueries(args []string) error {
+	var term string
+	switch {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "list"):
+	case len(args) > 1 && args[0] == "search":
+		term = strings.Join(args[1:], " ")
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_queries"))
+		return nil
+	}
+
+	dir := a.queriesDir()
+	queries, err := core.LoadLibrary(dir)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_library"), dir, err)
+	}
+	if len(queries) == 0 {
+		fmt.Printf(a.i18nMgr.Get("library_empty"), dir)
+		return nil
+	}
+	if term != "" {
+		if queries = core.SearchLibrary(queries, term); len(queries) == 0 {
+			fmt.Printf(a.i18nMgr.Get("library_no_matches"), term)
+			return nil
+		}
+	}
+	return a.displayMarkdown(a.formatLibrary(dir, queries))
+}
+
+func (a *App) formatLibrary(dir string, queries []core.LibraryQuery) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# 📚 %s - %s\n\n", a.i18nMgr.Get("library_header"), dir)
+	sb.WriteString(a.i18nMgr.Get("library_table_header"))
+	sb.WriteString("|---|---|---|---|\n")
+	for _, query := range queries {
+		params := make([]string, len(query.Params))
+		for i, param := range query.Params {
+			params[i] = "`" + param + "`"
+		}
+		fmt.Fprintf(&sb, "| `@%s` | %s | %s | %s |\n", query.Name, libraryCell(query.Description),
+			strings.Join(params, ", "), libraryCell(query.Owner))
+	}
+	return sb.String()
+}
+
+// libraryCell keeps front matter text on one line of a markdown table
+func libraryCell(value string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(value), " "), "|", `\|`)
+}
+
+// addLibraryCandidates completes @ references to library queries by name, such as
+// finance/revenue for queries/finance/revenue.sql
+func (ac *AutoCompleter) addLibraryCandidates(candidates *[]string, typed string) {
+	queries, err := core.LoadLibrary(ac.app.queriesDir())
+	if err != nil {
+		return
+	}
+	for _, query := range queries {
+		if strings.HasPrefix(query.Name, typed) && !slices.Contains(*candidates, query.Name[len(typed):]) {
+			*candidates = append(*candidates, query.Name[len(typed):])
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/core"
+)
+
+// sessionLineage builds the column lineage of the statements that succeeded this session,
+// or in every session with allSessions
+func (a *App) sessionLineage(allSessions bool) (*core.Lineage, error) {
+	if a.queryLog == nil {
+		return &core.Lineage{}, nil
+	}
+	queries, err := a.queryLog.Succeeded(allSessions)
+	if err != nil {
+		return nil, fmt.Errorf(a.i18nMgr.Get("failed_to_read_query_log"), err)
+	}
+	return core.BuildLineage(queries), nil
+}
+
+// handleLineage shows which columns feed table.column and which it feeds, from the
+// SELECT, INSERT ... SELECT and CREATE ... AS SELECT statements run this session
+func (a *App) handleLineage(args []string) error {
+	column := ""
+	allSessions := false
+	for _, arg := range args {
+		if arg == "--all" {
+			allSessions = true
+			continue
+		}
+		if column != "" {
+			column = ""
+			break
+		}
+		column = arg
+	}
+	if column == "" {
+		fmt.Println(a.i18nMgr.Get("usage_lineage"))
+		return nil
+	}
+	if a.queryLog == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	lineage, err := a.sessionLineage(allSessions)
+	if err != nil {
+		return err
+	}
+	upstream, downstream := lineage.Upstream(column), lineage.Downstream(column)
+	if len(upstream) == 0 && len(downstream) == 0 {
+		fmt.Printf(a.i18nMgr.Get("no_lineage_found"), column)
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🔗 %s\n", a.i18nMgr.GetWithArgs("lineage_header", column)))
+	if len(upstream) > 0 {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", a.i18nMgr.Get("lineage_fed_by")))
+		a.formatLineageSteps(&sb, upstream)
+	}
+	if len(downstream) > 0 {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", a.i18nMgr.Get("lineage_feeds")))
+		a.formatLineageSteps(&sb, downstream)
+	}
+	return a.displayMarkdown(sb.String())
+}
+
+func (a *App) formatLineageSteps(sb *strings.Builder, steps []core.LineageStep) {
+	for _, step := range steps {
+		target := "`" + step.Target.String() + "`"
+		if step.Target.Table == "" {
+			target = a.i18nMgr.GetWithArgs("lineage_result_column", step.Target.Column)
+		}
+		sources := make([]string, len(step.Sources))
+		for i, source := range step.Sources {
+			sources[i] = "`" + source.String() + "`"
+		}
+		query := strings.ReplaceAll(a.truncateQuery(strings.Join(strings.Fields(step.Query), " ")), "`", "'")
+		sb.WriteString(fmt.Sprintf("%s- %s ← %s  \n%s  `%s`\n",
+			strings.Repeat("  ", step.Depth), target, strings.Join(sources, ", "), strings.Repeat("  ", step.Depth), query))
+	}
+}
+
+// attachLineage adds the lineage of the columns a chat message names when it asks where
+// a value comes from, so the answer can point at the queries that produced it
+func (a *App) attachLineage(message string) string {
+	if a.queryLog == nil {
+		return message
+	}
+	lineage, err := a.sessionLineage(false)
+	if err != nil {
+		return message
+	}
+	context := lineage.LineageContext(message)
+	if context == "" {
+		return message
+	}
+	fmt.Println(a.i18nMgr.Get("lineage_attached"))
+	return ai.AttachToMessage(message, context)
+}
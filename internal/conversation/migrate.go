@@ -0,0 +1,144 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// handleMigrate runs /migrate status|up [n]|down [n] against the migrations directory
+func (a *App) handleMigrate(args []string) error {
+	if len(args) == 0 {
+		args = []string{"status"}
+	}
+	action := args[0]
+
+	count := 0 // 0 means all pending for up, and is replaced by 1 for down
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 || len(args) > 2 {
+			fmt.Println(a.i18nMgr.Get("usage_migrate"))
+			return nil
+		}
+		count = n
+	}
+
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	if !core.MigrationsDirExists(core.DefaultMigrationsDir) {
+		return errors.New(a.i18nMgr.GetWithArgs("migrations_dir_not_found", core.DefaultMigrationsDir))
+	}
+
+	migrations, err := core.MigrationStatus(a.connection, core.DefaultMigrationsDir)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_migrations"), err)
+	}
+
+	switch action {
+	case "status":
+		return a.displayMarkdown(a.formatMigrationStatus(migrations))
+	case "up":
+		var pending []core.Migration
+		for _, m := range migrations {
+			if !m.Applied() {
+				pending = append(pending, m)
+			}
+		}
+		if count > 0 && count < len(pending) {
+			pending = pending[:count]
+		}
+		return a.runMigrations(pending, "up")
+	case "down":
+		if count == 0 {
+			count = 1
+		}
+		var applied []core.Migration
+		for i := len(migrations) - 1; i >= 0 && len(applied) < count; i-- {
+			if migrations[i].Applied() {
+				applied = append(applied, migrations[i])
+			}
+		}
+		return a.runMigrations(applied, "down")
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_migrate"))
+		return nil
+	}
+}
+
+// runMigrations applies or reverts migrations one at a time, each in its own transaction,
+// stopping at the first failure. Every attempt is recorded in the session query log.
+func (a *App) runMigrations(migrations []core.Migration, direction string) error {
+	if len(migrations) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_migrations_to_run"))
+		return nil
+	}
+
+	labels := make([]string, len(migrations))
+	for i, m := range migrations {
+		labels[i] = fmt.Sprintf("%d_%s", m.Version, m.Name)
+	}
+	if err := a.guardChange(changeGuard{
+		conn:     a.config,
+		approval: fmt.Sprintf("/migrate %s %s", direction, strings.Join(labels, " ")),
+		question: a.i18nMgr.GetWithArgs("migrate_confirm_question", len(migrations), direction),
+	}); err != nil {
+		return err
+	}
+
+	// One approval covers every migration of the run
+	approved := a.approved
+	for _, m := range migrations {
+		label := fmt.Sprintf("%d_%s", m.Version, m.Name)
+		start := time.Now()
+		a.approved = approved
+		var err error
+		if direction == "up" {
+			err = core.ApplyMigration(a.connection, m)
+		} else {
+			err = core.RevertMigration(a.connection, m)
+		}
+		a.logQuery(fmt.Sprintf("/migrate %s %s", direction, label), start, 0, err)
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("migration_failed"), label, err)
+		}
+		fmt.Printf(a.i18nMgr.Get("migration_done"), direction, label, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func (a *App) formatMigrationStatus(migrations []core.Migration) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🧱 %s\n\n", a.i18nMgr.Get("migration_status_header")))
+	if len(migrations) == 0 {
+		sb.WriteString(a.i18nMgr.GetWithArgs("no_migrations_found", core.DefaultMigrationsDir))
+		sb.WriteString("\n")
+		return sb.String()
+	}
+
+	sb.WriteString(a.i18nMgr.Get("migration_status_table_header"))
+	sb.WriteString("|---|---|---|---|\n")
+	pending := 0
+	for _, m := range migrations {
+		status := "⏳ " + a.i18nMgr.Get("migration_pending")
+		if m.Applied() {
+			status = "✅ " + m.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		down := "✅"
+		if m.DownPath == "" {
+			down = "—"
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s | %s |\n", m.Version, m.Name, status, down))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(a.i18nMgr.GetWithArgs("migration_pending_count", pending, len(migrations)))
+	sb.WriteString("\n")
+	return sb.String()
+}
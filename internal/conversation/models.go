@@ -0,0 +1,52 @@
+package conversation
+
+import (
+	"strings"
+
+	"sqlterm/internal/ai"
+)
+
+// parseModelFilter reads the list-models options: --min-context <tokens>, --tools and --json
+func parseModelFilter(args []string) (ai.ModelFilter, bool) {
+	var filter ai.ModelFilter
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--min-context":
+			if i+1 >= len(args) {
+				return filter, false
+			}
+			i++
+			tokens, err := ai.ParseTokenCount(args[i])
+			if err != nil {
+				return filter, false
+			}
+			filter.MinContext = tokens
+		case "--tools":
+			filter.Tools = true
+		case "--json":
+			filter.JSONMode = true
+		default:
+			return filter, false
+		}
+	}
+	return filter, true
+}
+
+// formatModelCapabilities summarises what the provider reports about a model, e.g.
+// "Context: 128k · tools · JSON mode", or "" when it reports nothing
+func (a *App) formatModelCapabilities(model ai.ModelInfo) string {
+	var parts []string
+	if model.ContextLength > 0 {
+		parts = append(parts, a.i18nMgr.GetWithArgs("model_context_length", ai.FormatTokenCount(model.ContextLength)))
+	}
+	switch model.Tools {
+	case ai.CapabilityYes:
+		parts = append(parts, a.i18nMgr.Get("model_supports_tools"))
+	case ai.CapabilityNo:
+		parts = append(parts, a.i18nMgr.Get("model_no_tools"))
+	}
+	if model.JSONMode == ai.CapabilityYes {
+		parts = append(parts, a.i18nMgr.Get("model_supports_json"))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,60 @@
+package conversation
+
+import (
+	"fmt"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// notifyAfter returns how long a query runs before its end is announced, or 0 when
+// notices are off
+func (a *App) notifyAfter() time.Duration {
+	seconds := a.displayConfig().NotifyAfter
+	switch {
+	case seconds < 0:
+		return 0
+	case seconds == 0:
+		return core.DefaultNotifyAfter
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// notifyMethod returns how the end of a long query is announced
+func (a *App) notifyMethod() string {
+	if method := a.displayConfig().Notify; method != "" {
+		return method
+	}
+	return core.NotifyBell
+}
+
+// notifyQueryDone rings the bell or shows a desktop notification when a query that ran
+// past the notify threshold finishes or fails, so it can run while the user works elsewhere
+func (a *App) notifyQueryDone(query string, start time.Time, rows int, err error) {
+	after := a.notifyAfter()
+	elapsed := time.Since(start)
+	if after <= 0 || elapsed < after {
+		return
+	}
+	elapsed = elapsed.Round(time.Second)
+
+	method := a.notifyMethod()
+	if method == core.NotifyDesktop || method == core.NotifyBoth {
+		title := a.i18nMgr.Get("notify_query_finished_title")
+		message := a.i18nMgr.GetWithArgs("notify_query_finished", a.truncateQuery(query), formatRowCount(float64(rows)), elapsed)
+		if err != nil {
+			title = a.i18nMgr.Get("notify_query_failed_title")
+			message = a.i18nMgr.GetWithArgs("notify_query_failed", a.truncateQuery(query), elapsed, err)
+		}
+		if a.config != nil {
+			title += " · " + a.config.Name
+		}
+		if notifyErr := core.DesktopNotify(title, message); notifyErr != nil {
+			fmt.Printf(a.i18nMgr.Get("notify_desktop_warning"), notifyErr)
+			method = core.NotifyBell
+		}
+	}
+	if method == core.NotifyBell || method == core.NotifyBoth {
+		fmt.Print("\a")
+	}
+}
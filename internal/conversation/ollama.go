@@ -0,0 +1,113 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"sqlterm/internal/ai"
+)
+
+// handleAIConfigModels handles /config ai models [--installed]
+func (a *App) handleAIConfigModels(args []string) error {
+	if len(args) == 0 || args[0] != "--installed" {
+		return a.handleAIConfigListModels(args)
+	}
+	if len(args) > 1 {
+		fmt.Println(a.i18nMgr.Get("usage_config_ai_models"))
+		return nil
+	}
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+
+	models, err := a.aiManager.InstalledOllamaModels(context.Background())
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_list_models"), err)
+	}
+	if len(models) == 0 {
+		fmt.Println(a.i18nMgr.Get("ollama_no_models"))
+		return nil
+	}
+
+	current := a.aiManager.GetConfig().AI.Model
+	fmt.Printf(a.i18nMgr.Get("ollama_installed_header"), len(models))
+	for _, model := range models {
+		marker := " "
+		if model.Name == current {
+			marker = "*"
+		}
+		details := strings.Join(nonEmpty(model.Family, model.ParameterSize, model.Quantization), ", ")
+		fmt.Printf(" %s %-32s %8s  %s  %s\n", marker, model.Name, formatBytes(model.Size),
+			model.ModifiedAt.Format("2006-01-02"), details)
+	}
+	return nil
+}
+
+// handleAIConfigPull handles /config ai pull <model>, showing download progress until
+// Ollama finishes or Ctrl+C cancels
+func (a *App) handleAIConfigPull(args []string) error {
+	if len(args) != 1 {
+		fmt.Println(a.i18nMgr.Get("usage_config_ai_pull"))
+		return nil
+	}
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+	model := args[0]
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf(a.i18nMgr.Get("ollama_pulling"), model)
+	lastStatus := ""
+	err := a.aiManager.PullOllamaModel(ctx, model, func(p ai.PullProgress) {
+		if p.Status != lastStatus && lastStatus != "" {
+			fmt.Println()
+		}
+		lastStatus = p.Status
+		if percent := p.Percent(); percent >= 0 {
+			fmt.Printf("\r   %s %5.1f%% (%s / %s)", p.Status, percent, formatBytes(p.Completed), formatBytes(p.Total))
+		} else {
+			fmt.Printf("\r   %s", p.Status)
+		}
+	})
+	fmt.Println()
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Println(a.i18nMgr.Get("ollama_pull_cancelled"))
+			return nil
+		}
+		return fmt.Errorf(a.i18nMgr.Get("ollama_pull_failed"), model, err)
+	}
+
+	fmt.Printf(a.i18nMgr.Get("ollama_pulled"), model, model)
+	return nil
+}
+
+// formatBytes shows a size in the largest unit that keeps it at or above 1
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMGT"[exp])
+}
+
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, value := range values {
+		if value != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
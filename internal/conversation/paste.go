@@ -0,0 +1,182 @@
+package conversation
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"sqlterm/internal/core"
+
+	"github.com/chzyer/readline"
+	"golang.org/x/term"
+)
+
+// With bracketed paste on, terminals wrap pasted text in pasteStart and pasteEnd, so a
+// pasted block can be told apart from lines typed one by one
+const (
+	bracketedPasteOn  = "\x1b[?2004h"
+	bracketedPasteOff = "\x1b[?2004l"
+	pasteStart        = "\x1b[200~"
+	pasteEnd          = "\x1b[201~"
+)
+
+// pasteReader sits between the terminal and readline. Typed input and single-line pastes
+// pass through; a multi-line paste is held back for the REPL, and readline only sees the
+// Enter that ends it, so the block arrives as one input instead of a line per Enter.
+type pasteReader struct {
+	in      io.ReadCloser
+	raw     []byte // Read from the terminal, not yet sorted into out or block
+	out     []byte // Ready for readline
+	block   []byte // The paste being read
+	inPaste bool
+
+	mu     sync.Mutex
+	pasted string // The last multi-line paste, until take
+}
+
+func newPasteReader(in io.ReadCloser) *pasteReader {
+	return &pasteReader{in: in}
+}
+
+func (r *pasteReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		buf := make([]byte, 1024)
+		n, err := r.in.Read(buf)
+		r.raw = append(r.raw, buf[:n]...)
+		r.sort()
+		if err != nil {
+			if len(r.out) == 0 {
+				return 0, err
+			}
+			break
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// sort moves what has been read into out or the paste block. A marker split between
+// reads stays in raw until the rest of it arrives.
+func (r *pasteReader) sort() {
+	for {
+		marker, dst := pasteStart, &r.out
+		if r.inPaste {
+			marker, dst = pasteEnd, &r.block
+		}
+		i := bytes.Index(r.raw, []byte(marker))
+		if i < 0 {
+			keep := partialMarker(r.raw, marker)
+			*dst = append(*dst, r.raw[:len(r.raw)-keep]...)
+			r.raw = r.raw[len(r.raw)-keep:]
+			return
+		}
+		*dst = append(*dst, r.raw[:i]...)
+		r.raw = r.raw[i+len(marker):]
+		if r.inPaste {
+			r.endPaste()
+		}
+		r.inPaste = !r.inPaste
+	}
+}
+
+// endPaste hands a multi-line paste to the REPL and a single line to readline
+func (r *pasteReader) endPaste() {
+	block := strings.ReplaceAll(string(r.block), "\r\n", "\n")
+	block = strings.ReplaceAll(block, "\r", "\n")
+	r.block = nil
+	if !strings.Contains(strings.TrimRight(block, "\n"), "\n") {
+		r.out = append(r.out, strings.TrimRight(block, "\n")...)
+		return
+	}
+	r.mu.Lock()
+	r.pasted = block
+	r.mu.Unlock()
+	r.out = append(r.out, '\r')
+}
+
+// take returns the last multi-line paste once, or ""
+func (r *pasteReader) take() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pasted := r.pasted
+	r.pasted = ""
+	return pasted
+}
+
+func (r *pasteReader) Close() error {
+	return r.in.Close()
+}
+
+// partialMarker returns how many bytes at the end of data could begin marker
+func partialMarker(data []byte, marker string) int {
+	for n := min(len(data), len(marker)-1); n > 0; n-- {
+		if bytes.HasSuffix(data, []byte(marker[:n])) {
+			return n
+		}
+	}
+	return 0
+}
+
+// newReadline opens a line editor reading through its own paste reader, with the draft
+// keys bound. Each instance needs a fresh reader, since closing readline closes its input.
+func (a *App) newReadline(cfg *readline.Config) (*readline.Instance, error) {
+	a.paste = newPasteReader(readline.NewCancelableStdin(os.Stdin))
+	cfg.Stdin = a.paste
+	cfg.FuncFilterInputRune = draftKeys
+	cfg.SetListener(a.onDraftKey)
+	return readline.NewEx(cfg)
+}
+
+// enableBracketedPaste asks the terminal to mark pastes and returns the function that
+// stops it again
+func enableBracketedPaste() func() {
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return func() {}
+	}
+	fmt.Print(bracketedPasteOn)
+	return func() { fmt.Print(bracketedPasteOff) }
+}
+
+// readLine reads a line with readline. When a multi-line block was pasted into it, the
+// block follows what was typed before it and pasted is true.
+func (a *App) readLine() (line string, pasted bool, err error) {
+	line, err = a.rl.Readline()
+	if a.paste != nil {
+		if block := a.paste.take(); block != "" && err == nil {
+			return line + block, true, nil
+		}
+	}
+	return line, false, err
+}
+
+// confirmPaste shows a pasted block and asks whether to run it
+func (a *App) confirmPaste(block string) bool {
+	lang := "text"
+	if core.IsSQLStatement(block) {
+		lang = "sql"
+	}
+	lines := strings.Count(block, "\n") + 1
+	markdown := fmt.Sprintf("%s\n\n```%s\n%s\n```\n", a.i18nMgr.GetWithArgs("paste_received", lines), lang, block)
+	if err := a.displayMarkdown(markdown); err != nil {
+		fmt.Println(block)
+	}
+	return a.confirm(a.i18nMgr.Get("paste_run_question"))
+}
+
+// processPaste runs a confirmed multi-line paste as one input. SQL runs as a single query
+// even with bare SQL off, since the paste was confirmed as it is.
+func (a *App) processPaste(block string) error {
+	block = strings.TrimSpace(block)
+	if !a.confirmPaste(block) {
+		fmt.Println(a.i18nMgr.Get("paste_discarded"))
+		return nil
+	}
+	if !strings.HasPrefix(block, "/") && !strings.HasPrefix(block, "@") && core.IsSQLStatement(block) {
+		return a.runQueryLine(block)
+	}
+	return a.processLine(block)
+}
@@ -0,0 +1,78 @@
+package conversation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sqlterm/internal/ai"
+)
+
+// handlePhase shows the AI conversation phase, or moves it with /phase <discovery|schema|sql>
+func (a *App) handlePhase(args []string) error {
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+
+	if len(args) > 0 {
+		phase, ok := ai.ParseConversationPhase(args[0])
+		if !ok || len(args) > 1 {
+			fmt.Println(a.i18nMgr.Get("usage_phase"))
+			return nil
+		}
+		a.aiManager.SetPhase(phase)
+		fmt.Printf(a.i18nMgr.Get("phase_set"), phase.String())
+		return nil
+	}
+
+	conversation := a.aiManager.GetCurrentConversation()
+	if conversation == nil {
+		fmt.Println(a.i18nMgr.Get("no_ai_conversation"))
+		return nil
+	}
+
+	loaded := make([]string, 0, len(conversation.LoadedTables))
+	for name := range conversation.LoadedTables {
+		loaded = append(loaded, name)
+	}
+	sort.Strings(loaded)
+
+	fmt.Printf(a.i18nMgr.Get("phase_current"), conversation.CurrentPhase.String(), len(conversation.ConversationHistory))
+	fmt.Printf(a.i18nMgr.Get("phase_loaded_tables"), formatTableList(loaded))
+	fmt.Printf(a.i18nMgr.Get("phase_related_tables"), formatTableList(conversation.RelatedTables))
+	return nil
+}
+
+// handleLoadSchema loads table schemas into the AI conversation by hand, for when the
+// model does not ask for them itself
+func (a *App) handleLoadSchema(args []string) error {
+	tables := splitList(strings.Join(args, ","))
+	if len(tables) == 0 {
+		fmt.Println(a.i18nMgr.Get("usage_load_schema"))
+		return nil
+	}
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	loaded, err := a.aiManager.LoadSchemas(a.connection, tables)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_schema"), err)
+	}
+	fmt.Printf(a.i18nMgr.Get("schemas_loaded"), formatTableList(loaded),
+		a.aiManager.GetCurrentConversation().CurrentPhase.String())
+	return nil
+}
+
+func formatTableList(tables []string) string {
+	if len(tables) == 0 {
+		return "-"
+	}
+	return strings.Join(tables, ", ")
+}
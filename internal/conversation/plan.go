@@ -0,0 +1,65 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// handlePlan renders the EXPLAIN plan of a query as a tree
+func (a *App) handlePlan(args []string) error {
+	analyze := len(args) > 0 && args[0] == "--analyze"
+	if analyze {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Println(a.i18nMgr.Get("usage_plan"))
+		return nil
+	}
+
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	query := strings.Join(args, " ")
+	if analyze && !core.IsReadOnlyQuery(query) {
+		// ANALYZE executes the statement, so keep it away from writes
+		return errors.New(a.i18nMgr.Get("plan_analyze_read_only"))
+	}
+
+	root, err := core.ExplainQuery(a.connection, a.config.DatabaseType, query, analyze)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_explain_query"), err)
+	}
+
+	return a.displayMarkdown(a.formatPlan(root))
+}
+
+func (a *App) formatPlan(root *core.PlanNode) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🗺️ %s\n\n", a.i18nMgr.Get("query_plan_header")))
+	sb.WriteString("```text\n")
+	sb.WriteString(core.RenderPlan(root))
+	sb.WriteString("```\n")
+
+	var warnings []string
+	root.Walk(func(node *core.PlanNode) {
+		if node.SeqScan {
+			warnings = append(warnings, a.i18nMgr.GetWithArgs("plan_seq_scan_warning", node.Relation, node.PlanRows))
+		}
+		if node.Misestimated() {
+			warnings = append(warnings, a.i18nMgr.GetWithArgs("plan_misestimate_warning", node.Operation, node.PlanRows, node.ActualRows))
+		}
+	})
+
+	if len(warnings) > 0 {
+		sb.WriteString(fmt.Sprintf("\n## %s\n\n", a.i18nMgr.Get("plan_warnings_header")))
+		for _, warning := range warnings {
+			sb.WriteString("- " + warning + "\n")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+// loadPolicy reads the policy file of a profile, with the commands it denies resolved
+// to their names so aliases are denied too. A command sqlterm does not have is an error
+// rather than a rule that silently never applies.
+func loadPolicy(aiManager *ai.Manager, configDir string) (*config.Policy, error) {
+	settings := config.PolicyConfig{}
+	if aiManager != nil && aiManager.GetConfig() != nil {
+		settings = aiManager.GetConfig().Policy
+	}
+	path := settings.FilePath(configDir)
+	policy, err := config.LoadPolicy(path)
+	if err != nil || policy == nil {
+		return nil, err
+	}
+	for i := range policy.Rules {
+		denied := policy.Rules[i].DenyCommands
+		for j, name := range denied {
+			cmd, ok := lookupCommand(name)
+			if !ok {
+				return nil, fmt.Errorf("%s: rules[%d].deny_commands: unknown command %s", path, i, name)
+			}
+			denied[j] = cmd.name
+		}
+	}
+	return policy, nil
+}
+
+// checkCommandPolicy rejects a command the policy file denies on the active connection
+func (a *App) checkCommandPolicy(cmd *command) error {
+	rule := a.policy.DeniedCommand(a.config, cmd.name)
+	if rule == nil {
+		return nil
+	}
+	return policyDenial(a.i18nMgr.GetWithArgs("policy_command_denied", cmd.name), rule)
+}
+
+// checkStatementPolicy rejects a statement the policy file denies on the active connection
+func (a *App) checkStatementPolicy(query string) error {
+	rule := a.policy.DeniedStatement(a.config, query)
+	if rule == nil {
+		return nil
+	}
+	return policyDenial(a.i18nMgr.GetWithArgs("policy_statement_denied", core.LeadingKeyword(query)), rule)
+}
+
+// policyDenial adds the rule's own message, when it has one, to the reason
+func policyDenial(reason string, rule *config.PolicyRule) error {
+	if rule.Message != "" {
+		reason += ": " + rule.Message
+	}
+	return errors.New(reason)
+}
@@ -0,0 +1,68 @@
+package conversation
+
+import (
+	"fmt"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// previewThreshold returns the estimated table size above which SELECT * of a whole
+// table is previewed first, or 0 when previews are off
+func (a *App) previewThreshold() int64 {
+	threshold := a.displayConfig().PreviewThreshold
+	switch {
+	case threshold < 0:
+		return 0
+	case threshold == 0:
+		return core.DefaultPreviewThreshold
+	}
+	return threshold
+}
+
+// previewLargeTable shows the first rows of a SELECT * over a table whose statistics put
+// it above the preview threshold, then asks whether to fetch all of it or export it to a
+// file instead, so a huge table does not flood the terminal by accident. handled is false
+// when the query runs as usual.
+func (a *App) previewLargeTable(query string) (handled bool, err error) {
+	// Without a terminal to ask on, queries run as typed
+	if a.rl == nil || a.connection == nil || a.config == nil {
+		return false, nil
+	}
+	threshold := a.previewThreshold()
+	if threshold <= 0 {
+		return false, nil
+	}
+	table, name, ok := core.BareTableScan(query)
+	if !ok {
+		return false, nil
+	}
+	rows := core.EstimateTableRows(a.connection, a.config.DatabaseType, name)
+	if rows <= threshold {
+		return false, nil
+	}
+
+	fmt.Println(a.i18nMgr.GetWithArgs("preview_large_table", table, formatRowCount(float64(rows)), core.PreviewRows))
+	if err := a.showQuery(core.PreviewQuery(table)); err != nil {
+		return true, err
+	}
+
+	answer, err := a.readInput(a.i18nMgr.GetWithArgs("preview_fetch_question", formatRowCount(float64(rows))))
+	if err != nil {
+		return true, nil
+	}
+	switch strings.ToLower(answer) {
+	case "a", "all":
+		return true, a.showQuery(query)
+	case "e", "export":
+		filename := checkpointNameChars.ReplaceAllString(name[strings.LastIndex(name, ".")+1:], "_") + ".csv"
+		if answer, err := a.readInput(a.i18nMgr.GetWithArgs("preview_export_question", filename)); err != nil {
+			return true, nil
+		} else if answer != "" {
+			filename = answer
+		}
+		return true, a.processQueryWithCSVExport(strings.TrimSuffix(strings.TrimSpace(query), ";") + " > " + filename)
+	}
+	fmt.Println(a.i18nMgr.Get("preview_kept"))
+	return true, nil
+}
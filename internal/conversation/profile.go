@@ -0,0 +1,74 @@
+package conversation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/core"
+)
+
+// handleProfile profiles a table or one column: /profile users or /profile users.email.
+// With --remember the column summaries are kept as context for the AI.
+func (a *App) handleProfile(args []string) error {
+	remember := false
+	var target string
+	for _, arg := range args {
+		switch {
+		case arg == "--remember":
+			remember = true
+		case target == "":
+			target = arg
+		default:
+			fmt.Println(a.i18nMgr.Get("usage_profile"))
+			return nil
+		}
+	}
+	if target == "" {
+		fmt.Println(a.i18nMgr.Get("usage_profile"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	if remember && a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+
+	table, columns := a.profileTarget(target)
+	profile, err := core.ProfileTable(a.connection, a.config.DatabaseType, table, columns)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_profile"), target, err)
+	}
+	if err := a.showReport("profile", core.FormatProfile(profile, a.i18nMgr)); err != nil {
+		return err
+	}
+
+	if remember {
+		summaries := make([]ai.ColumnProfile, len(profile.Columns))
+		for i, cp := range profile.Columns {
+			summaries[i] = ai.ColumnProfile{Column: cp.Column, Summary: cp.Summary(profile.Rows)}
+		}
+		if err := a.aiManager.RememberProfiles(table, summaries); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_remember_profile"), err)
+		}
+		fmt.Printf(a.i18nMgr.Get("profile_remembered"), len(summaries), table)
+	}
+	return nil
+}
+
+// profileTarget splits table.column, unless the whole name is a table, as a
+// schema-qualified one may be
+func (a *App) profileTarget(target string) (string, []string) {
+	i := strings.LastIndex(target, ".")
+	if i <= 0 || i == len(target)-1 {
+		return target, nil
+	}
+	if tables, err := a.connection.ListTables(); err == nil && slices.Contains(tables, target) {
+		return target, nil
+	}
+	return target[:i], []string{target[i+1:]}
+}
@@ -0,0 +1,160 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/config"
+	"sqlterm/internal/i18n"
+	"sqlterm/internal/session"
+)
+
+// handleUseProfile lists the config profiles, or switches to one: /use-profile personal
+func (a *App) handleUseProfile(args []string) error {
+	switch len(args) {
+	case 0:
+		return a.listProfiles()
+	case 1:
+		return a.switchProfile(args[0])
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_use_profile"))
+		return nil
+	}
+}
+
+// listProfiles prints the profiles, marking the active one
+func (a *App) listProfiles() error {
+	profiles, err := config.ListProfiles()
+	if err != nil {
+		return err
+	}
+	fmt.Println(a.i18nMgr.Get("profiles_header"))
+	for _, name := range profiles {
+		marker := " "
+		if name == a.configMgr.GetProfile() {
+			marker = "*"
+		}
+		fmt.Printf("  %s %s\n", marker, name)
+	}
+	return nil
+}
+
+// switchProfile closes the connection and reopens the config, AI settings and session
+// folders of another profile, and forgets the results, variables and AI attachments of
+// the session, so nothing from one profile shows in another. A profile that does not
+// exist yet is created after confirmation.
+func (a *App) switchProfile(name string) error {
+	if err := config.ValidateProfileName(name); err != nil {
+		return err
+	}
+	if name == a.configMgr.GetProfile() {
+		fmt.Printf(a.i18nMgr.Get("profile_already_active"), name)
+		return nil
+	}
+	if !config.ProfileExists(name) && !a.confirm(a.i18nMgr.GetWithArgs("profile_create_confirm", name)) {
+		return nil
+	}
+
+	previous := config.ActiveProfile()
+	config.SetProfile(name)
+	configMgr, err := config.OpenManager()
+	if err == nil {
+		err = os.MkdirAll(filepath.Join(configMgr.GetDataDir(), "sessions"), 0755)
+	}
+	if err != nil {
+		config.SetProfile(previous)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_switch_profile"), name, err)
+	}
+	aiManager, aiErr := ai.NewManager(configMgr.GetConfigDir())
+	if aiErr == nil {
+		aiManager.SetDataDir(configMgr.GetDataDir())
+	}
+	policy, err := loadPolicy(aiManager, configMgr.GetConfigDir())
+	if err != nil {
+		config.SetProfile(previous)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_policy"), err)
+	}
+	auditLog, err := openAuditLog(aiManager, configMgr.GetConfigDir())
+	if err != nil {
+		config.SetProfile(previous)
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_open_audit_log"), err)
+	}
+
+	// Nothing of the old profile's connection may outlive the switch
+	a.jobs.CancelAll()
+	if a.connection != nil {
+		a.closeConnection()
+	}
+	if a.queryLog != nil {
+		a.queryLog.Close()
+		a.queryLog = nil
+	}
+	if a.aiManager != nil {
+		a.aiManager.CloseVectorStore()
+	}
+	if a.auditLog != nil {
+		a.auditLog.Close()
+	}
+	a.auditLog = auditLog
+	a.policy = policy
+
+	language := "en_au"
+	if aiManager != nil {
+		if aiConfig := aiManager.GetConfig(); aiConfig != nil {
+			language = aiConfig.Language
+		}
+	}
+	if i18nMgr, err := i18n.NewManager(language); err == nil {
+		a.i18nMgr = i18nMgr
+	}
+
+	a.configMgr = configMgr
+	a.sessionMgr = session.NewManager(configMgr.GetDataDir(), a.i18nMgr)
+	a.aiManager = aiManager
+	a.connection = nil
+	a.config = nil
+	a.lastResult = nil
+	a.lastColumns = nil
+	a.attachRows = 0
+	a.transcript = nil
+	a.vars = nil
+	a.approved = nil
+	a.unmaskNext = false
+	a.inTransaction = false
+	a.updatePrompt()
+	if err := a.switchToGlobalHistory(); err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	}
+
+	if aiErr != nil {
+		fmt.Printf(a.i18nMgr.Get("ai_config_invalid_warning"), aiErr)
+	}
+	fmt.Printf(a.i18nMgr.Get("profile_switched"), name, configMgr.GetConfigDir())
+	return nil
+}
+
+// completeProfileNames completes the profile name after /use-profile
+func completeProfileNames(ac *AutoCompleter, words []string, line string) []string {
+	currentWord := ""
+	if !strings.HasSuffix(line, " ") {
+		currentWord = words[len(words)-1]
+	}
+	if len(words) > 2 || (len(words) == 2 && currentWord == "") {
+		return nil
+	}
+
+	profiles, err := config.ListProfiles()
+	if err != nil {
+		return nil
+	}
+	var candidates []string
+	for _, name := range profiles {
+		if strings.HasPrefix(name, currentWord) {
+			candidates = append(candidates, name[len(currentWord):])
+		}
+	}
+	return candidates
+}
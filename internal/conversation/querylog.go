@@ -0,0 +1,75 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/session"
+)
+
+// handleSlowQueries lists the slowest logged statements and offers to explain one
+func (a *App) handleSlowQueries(args []string) error {
+	limit := 10
+	allSessions := false
+	for _, arg := range args {
+		if arg == "--all" {
+			allSessions = true
+			continue
+		}
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			fmt.Println(a.i18nMgr.Get("usage_slow"))
+			return nil
+		}
+		limit = n
+	}
+
+	if a.queryLog == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	entries, err := a.queryLog.Slowest(limit, allSessions)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_query_log"), err)
+	}
+	if len(entries) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_queries_logged"))
+		return nil
+	}
+
+	if err := a.displayMarkdown(a.formatSlowQueries(entries)); err != nil {
+		return err
+	}
+
+	choice, err := a.readInput(a.i18nMgr.GetWithArgs("slow_explain_prompt", len(entries)))
+	if err != nil || choice == "" {
+		return nil
+	}
+	index, err := strconv.Atoi(choice)
+	if err != nil || index < 1 || index > len(entries) {
+		return errors.New(a.i18nMgr.GetWithArgs("invalid_choice", choice))
+	}
+	return a.handlePlan([]string{entries[index-1].Query})
+}
+
+func (a *App) formatSlowQueries(entries []session.QueryLogEntry) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🐌 %s\n\n", a.i18nMgr.Get("slow_queries_header")))
+	sb.WriteString(a.i18nMgr.Get("slow_queries_table_header"))
+	sb.WriteString("|---|---|---|---|---|\n")
+	for i, entry := range entries {
+		query := strings.Join(strings.Fields(entry.Query), " ")
+		query = strings.ReplaceAll(a.truncateQuery(query), "|", "\\|")
+		status := "✅"
+		if entry.Error != "" {
+			status = "❌"
+		}
+		sb.WriteString(fmt.Sprintf("| %d | %v | %d | %s | `%s` |\n",
+			i+1, entry.Duration.Round(time.Millisecond), entry.Rows, status, query))
+	}
+	return sb.String()
+}
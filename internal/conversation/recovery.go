@@ -0,0 +1,74 @@
+package conversation
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/session"
+)
+
+// recoverPanic saves what the REPL was in the middle of when a command panicked, so
+// the next launch can offer it back, and ends the session with an error instead of a
+// crash. It must be deferred directly.
+func (a *App) recoverPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	state := &session.RecoveryState{
+		Input:         a.draft,
+		InTransaction: a.inTransaction,
+		Panic:         fmt.Sprint(r),
+		Stack:         string(debug.Stack()),
+		SavedAt:       time.Now(),
+	}
+	if a.config != nil {
+		state.Connection = a.config.Name
+	}
+	if a.aiManager != nil {
+		if convCtx := a.aiManager.GetCurrentConversation(); convCtx != nil {
+			state.Conversation, _ = json.Marshal(convCtx)
+		}
+	}
+	if saveErr := a.sessionMgr.SaveRecovery(state); saveErr != nil {
+		*err = fmt.Errorf(a.i18nMgr.Get("crash_state_not_saved"), r, saveErr)
+		return
+	}
+	*err = fmt.Errorf(a.i18nMgr.Get("crash_state_saved"), r, a.sessionMgr.RecoveryPath())
+}
+
+// offerRecovery tells the user about a crash in the last run and offers back what it
+// saved: the unfinished input goes back on the prompt and the AI conversation can be
+// resumed. The state is only offered once.
+func (a *App) offerRecovery() {
+	state, err := a.sessionMgr.LoadRecovery()
+	if err != nil {
+		fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+	}
+	if state == nil {
+		return
+	}
+	defer a.sessionMgr.ClearRecovery()
+
+	fmt.Printf(a.i18nMgr.Get("crash_recovered"), state.SavedAt.Format("2006-01-02 15:04:05"), state.Panic)
+	if state.InTransaction {
+		fmt.Printf(a.i18nMgr.Get("crash_transaction_lost"), state.Connection)
+	}
+
+	if len(state.Conversation) > 0 && a.aiManager != nil {
+		var convCtx ai.ConversationContext
+		if json.Unmarshal(state.Conversation, &convCtx) == nil &&
+			a.confirm(a.i18nMgr.GetWithArgs("crash_restore_conversation", convCtx.OriginalQuery)) {
+			a.aiManager.RestoreConversation(&convCtx)
+		}
+	}
+
+	if state.Input != "" {
+		fmt.Println(a.i18nMgr.Get("crash_input_restored"))
+		a.rl.WriteStdin([]byte(state.Input))
+	}
+}
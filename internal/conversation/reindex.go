@@ -0,0 +1,96 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"sqlterm/internal/ai"
+)
+
+// handleReindex handles /reindex [status|cancel]. Without arguments it embeds the
+// connection's tables again, e.g. after schema changes.
+func (a *App) handleReindex(args []string) error {
+	if len(args) > 1 {
+		fmt.Println(a.i18nMgr.Get("usage_reindex"))
+		return nil
+	}
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	subcommand := ""
+	if len(args) == 1 {
+		subcommand = args[0]
+	}
+	switch subcommand {
+	case "":
+		if err := a.aiManager.StartReindex(); err != nil {
+			if errors.Is(err, ai.ErrNoVectorStore) {
+				fmt.Println(a.i18nMgr.Get("usage_not_available_no_db"))
+				return nil
+			}
+			return err
+		}
+		fmt.Println(a.i18nMgr.Get("reindex_started"))
+	case "status":
+		a.showReindexStatus()
+	case "cancel":
+		if a.aiManager.CancelReindex() {
+			fmt.Println(a.i18nMgr.Get("reindex_cancelled"))
+		} else {
+			fmt.Println(a.i18nMgr.Get("reindex_not_running"))
+		}
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_reindex"))
+	}
+	return nil
+}
+
+func (a *App) showReindexStatus() {
+	status, ok := a.aiManager.ReindexStatus()
+	if !ok {
+		fmt.Println(a.i18nMgr.Get("reindex_never_run"))
+		return
+	}
+
+	switch {
+	case status.Running:
+		current := status.Current
+		if current == "" {
+			current = "-"
+		}
+		fmt.Printf(a.i18nMgr.Get("reindex_status_running"), status.Done, status.Total, current,
+			time.Since(status.StartedAt).Round(time.Second))
+	case status.Err != nil:
+		fmt.Printf(a.i18nMgr.Get("reindex_status_error"), status.Err)
+		return
+	case status.Cancelled:
+		fmt.Printf(a.i18nMgr.Get("reindex_status_cancelled"), status.Done, status.Total)
+	default:
+		fmt.Printf(a.i18nMgr.Get("reindex_status_done"), status.Done, status.Total,
+			status.FinishedAt.Sub(status.StartedAt).Round(time.Millisecond), status.FinishedAt.Format("15:04:05"))
+	}
+
+	if len(status.Failures) > 0 {
+		fmt.Printf(a.i18nMgr.Get("reindex_failures"), len(status.Failures))
+		for _, failure := range status.Failures {
+			fmt.Printf("   %s: %v\n", failure.Table, failure.Err)
+		}
+	}
+}
+
+// closeConnection stops background work reading the current connection, drops its
+// scratch tables, then closes it
+func (a *App) closeConnection() {
+	if a.aiManager != nil {
+		a.aiManager.CancelReindex()
+	}
+	a.dropScratchTables()
+	a.connection.Close()
+}
@@ -0,0 +1,78 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+
+	"sqlterm/internal/config"
+)
+
+// replConfig returns the configured line handling preferences, or defaults when unavailable
+func (a *App) replConfig() config.REPLConfig {
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.REPL
+		}
+	}
+	return config.REPLConfig{}
+}
+
+// processAIMessage sends an explicit AI message from /ai or a ? prefix
+func (a *App) processAIMessage(message string) error {
+	if message == "" {
+		fmt.Println(a.i18nMgr.Get("usage_ai"))
+		return nil
+	}
+	return a.processAIChat(message)
+}
+
+func (a *App) handleConfigREPL(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+
+	if len(args) == 0 || args[0] == "status" {
+		fmt.Println(a.i18nMgr.Get("repl_config_title"))
+		fmt.Printf(a.i18nMgr.Get("repl_bare_sql_status"), a.replConfig().BareSQL)
+		fmt.Printf(a.i18nMgr.Get("repl_queries_dir_status"), a.queriesDir())
+		return nil
+	}
+
+	repl := a.replConfig()
+	switch args[0] {
+	case "bare-sql":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			fmt.Println(a.i18nMgr.Get("usage_config_repl_bare_sql"))
+			return nil
+		}
+		repl.BareSQL = args[1] == "on"
+	case "queries-dir":
+		if len(args) != 2 {
+			fmt.Println(a.i18nMgr.Get("usage_config_repl_queries_dir"))
+			return nil
+		}
+		repl.QueriesDir = args[1]
+	default:
+		fmt.Printf(a.i18nMgr.Get("unknown_repl_setting"), args[0])
+		return a.printConfigREPLHelp()
+	}
+
+	if err := a.aiManager.SetREPLConfig(repl); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_repl_config"), err)
+	}
+
+	if args[0] == "queries-dir" {
+		fmt.Printf(a.i18nMgr.Get("repl_queries_dir_set"), repl.QueriesDir)
+	} else if repl.BareSQL {
+		fmt.Print(a.i18nMgr.Get("repl_bare_sql_enabled"))
+	} else {
+		fmt.Print(a.i18nMgr.Get("repl_bare_sql_disabled"))
+	}
+	return nil
+}
+
+func (a *App) printConfigREPLHelp() error {
+	fmt.Print(a.i18nMgr.Get("help_config_repl_title"))
+	fmt.Print(a.i18nMgr.Get("help_config_repl_commands"))
+	return nil
+}
@@ -0,0 +1,54 @@
+package conversation
+
+import (
+	"fmt"
+	"strconv"
+
+	"sqlterm/internal/core"
+)
+
+// defaultSampleRows is how many rows /sample takes when no count is given
+const defaultSampleRows = 100
+
+// handleSample runs a random sample of a table: /sample orders [100] [--stratify status].
+// The generated query is printed first so it can be reused.
+func (a *App) handleSample(args []string) error {
+	opts := core.SampleOptions{Rows: defaultSampleRows}
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--stratify" && i+1 < len(args):
+			opts.Stratify = args[i+1]
+			i++
+		case opts.Table == "":
+			opts.Table = args[i]
+		default:
+			rows, err := strconv.Atoi(args[i])
+			if err != nil || rows <= 0 {
+				fmt.Println(a.i18nMgr.Get("usage_sample"))
+				return nil
+			}
+			opts.Rows = rows
+		}
+	}
+	if opts.Table == "" {
+		fmt.Println(a.i18nMgr.Get("usage_sample"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	for _, name := range []string{opts.Table, opts.Stratify} {
+		if name == "" {
+			continue
+		}
+		if err := core.ValidateIdentifier(name); err != nil {
+			return err
+		}
+	}
+
+	opts.Estimated = core.EstimateTableRows(a.connection, a.config.DatabaseType, opts.Table)
+	query := core.SampleQuery(a.config.DatabaseType, opts)
+	fmt.Printf(a.i18nMgr.Get("sample_query"), query)
+	return a.runQueryLine(query)
+}
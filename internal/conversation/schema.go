@@ -0,0 +1,151 @@
+package conversation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/core"
+	"sqlterm/internal/session"
+)
+
+// handleSchema handles /schema snapshot, /schema drift [version] and /schema list
+func (a *App) handleSchema(args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		fmt.Println(a.i18nMgr.Get("usage_schema"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	switch args[0] {
+	case "snapshot":
+		return a.handleSchemaSnapshot()
+	case "drift":
+		return a.handleSchemaDrift(args[1:])
+	case "list":
+		return a.handleSchemaList()
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_schema"))
+		return nil
+	}
+}
+
+// handleSchemaSnapshot saves the live schema as the connection's next snapshot version
+func (a *App) handleSchemaSnapshot() error {
+	snapshot, err := core.TakeSchemaSnapshot(a.connection, a.config.DatabaseType)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_schema"), err)
+	}
+	snapshot.Connection = a.config.Name
+	path, err := a.sessionMgr.SaveSchemaSnapshot(snapshot)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_save_schema_snapshot"), err)
+	}
+
+	indexes := 0
+	for _, list := range snapshot.Indexes {
+		indexes += len(list)
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("schema_snapshot_saved", snapshot.Version, len(snapshot.Tables), indexes, path))
+	if snapshot.Indexes == nil {
+		fmt.Println(a.i18nMgr.Get("schema_snapshot_no_indexes"))
+	}
+	return nil
+}
+
+// handleSchemaDrift compares the live schema with the latest snapshot, or the given version
+func (a *App) handleSchemaDrift(args []string) error {
+	files, err := a.sessionMgr.ListSchemaSnapshots(a.config.Name)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println(a.i18nMgr.Get("schema_no_snapshots"))
+		return nil
+	}
+	file := files[len(files)-1]
+	if len(args) > 0 {
+		version, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+		if err != nil {
+			fmt.Println(a.i18nMgr.Get("usage_schema"))
+			return nil
+		}
+		found := false
+		for _, f := range files {
+			if f.Version == version {
+				file, found = f, true
+			}
+		}
+		if !found {
+			fmt.Println(a.i18nMgr.GetWithArgs("schema_snapshot_not_found", version))
+			return nil
+		}
+	}
+
+	before, err := session.LoadSchemaSnapshot(file.Path)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_schema_snapshot"), err)
+	}
+	live, err := core.TakeSchemaSnapshot(a.connection, a.config.DatabaseType)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_schema"), err)
+	}
+	taken := file.Taken.Format("2006-01-02 15:04")
+	changes := core.DiffSchemas(before, live)
+	if len(changes) == 0 {
+		fmt.Println(a.i18nMgr.GetWithArgs("schema_no_drift", file.Version, taken))
+		return nil
+	}
+
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 🧭 %s\n\n", a.i18nMgr.GetWithArgs("schema_drift_title", len(changes), file.Version, taken)))
+	sb.WriteString(a.i18nMgr.Get("schema_drift_header") + "\n")
+	sb.WriteString("|---|---|---|---|---|\n")
+	for _, change := range changes {
+		object := change.Object
+		if change.Name != "" {
+			object += " " + change.Name
+		}
+		sb.WriteString(fmt.Sprintf("| %s %s | %s | %s | %s | %s |\n", schemaChangeSymbol(change.Kind), a.i18nMgr.Get("schema_change_"+change.Kind),
+			escape.Replace(change.Table), escape.Replace(object), escape.Replace(change.Old), escape.Replace(change.New)))
+	}
+	return a.displayMarkdown(sb.String())
+}
+
+// schemaChangeSymbol marks a change the way a diff does
+func schemaChangeSymbol(kind string) string {
+	switch kind {
+	case core.SchemaAdded:
+		return "+"
+	case core.SchemaRemoved:
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+// handleSchemaList lists the connection's schema snapshots
+func (a *App) handleSchemaList() error {
+	files, err := a.sessionMgr.ListSchemaSnapshots(a.config.Name)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Println(a.i18nMgr.Get("schema_no_snapshots"))
+		return nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 📸 %s\n\n", a.i18nMgr.GetWithArgs("schema_list_title", a.config.Name)))
+	sb.WriteString(a.i18nMgr.Get("schema_list_header") + "\n")
+	sb.WriteString("|---:|---|---|\n")
+	for _, file := range files {
+		sb.WriteString(fmt.Sprintf("| %d | %s | %s |\n", file.Version, file.Taken.Format("2006-01-02 15:04"), filepath.Base(file.Path)))
+	}
+	return a.displayMarkdown(sb.String())
+}
@@ -0,0 +1,164 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// handleScratch keeps a query's rows in a temporary table for the rest of the session:
+// /scratch create [name] FROM SELECT ... makes one, named scratch_<n> when no name is
+// given, /scratch lists them and /scratch drop [name] drops one or all. Whatever is
+// left is dropped on disconnect.
+func (a *App) handleScratch(args []string) error {
+	if len(args) == 0 || args[0] == "list" {
+		a.listScratchTables()
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	switch args[0] {
+	case "create":
+		from := slices.IndexFunc(args, func(arg string) bool { return strings.EqualFold(arg, "FROM") })
+		if from < 1 || from > 2 || from == len(args)-1 {
+			fmt.Println(a.i18nMgr.Get("usage_scratch"))
+			return nil
+		}
+		var name string
+		if from == 2 {
+			name = args[1]
+		}
+		return a.createScratchTable(name, strings.Join(args[from+1:], " "))
+	case "drop":
+		switch len(args) {
+		case 1:
+			a.dropScratchTables()
+		case 2:
+			if !slices.Contains(a.scratch, args[1]) {
+				return fmt.Errorf(a.i18nMgr.Get("scratch_not_found"), args[1])
+			}
+			a.dropScratchTable(args[1])
+		default:
+			fmt.Println(a.i18nMgr.Get("usage_scratch"))
+		}
+		return nil
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_scratch"))
+		return nil
+	}
+}
+
+// createScratchTable materializes query into a temporary table. The connection is
+// pinned to one session while scratch tables exist, since they are only visible there.
+func (a *App) createScratchTable(name, query string) error {
+	if name == "" {
+		var err error
+		if name, err = a.nextScratchName(); err != nil {
+			return err
+		}
+	} else if slices.Contains(a.scratch, name) {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_scratch"), name,
+			fmt.Errorf(a.i18nMgr.Get("scratch_exists"), name))
+	}
+
+	statement, err := core.ScratchTableQuery(a.config.DatabaseType, name, query)
+	if errors.Is(err, core.ErrUnsupportedDatabase) {
+		return fmt.Errorf("%s", a.i18nMgr.Get("scratch_unsupported"))
+	}
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_scratch"), name, err)
+	}
+
+	if len(a.scratch) == 0 {
+		if err := core.PinSession(a.connection, true); err != nil {
+			if errors.Is(err, core.ErrUnsupportedDatabase) {
+				return fmt.Errorf("%s", a.i18nMgr.Get("scratch_unsupported"))
+			}
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_create_scratch"), name, err)
+		}
+	}
+	result, err := a.executeQuery(statement)
+	if err == nil {
+		err = drainResult(result)
+	}
+	if err != nil {
+		if len(a.scratch) == 0 {
+			core.PinSession(a.connection, false)
+		}
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_create_scratch"), name, err)
+	}
+	a.scratch = append(a.scratch, name)
+
+	count, err := a.scalarQuery("SELECT COUNT(*) FROM " + core.QuoteIdentifier(a.config.DatabaseType, name))
+	if err != nil {
+		count = "?"
+	}
+	fmt.Printf(a.i18nMgr.Get("scratch_created"), name, count)
+	return nil
+}
+
+// nextScratchName picks the first scratch_<n> not taken by this session or a table
+func (a *App) nextScratchName() (string, error) {
+	tables, err := a.connection.ListTables()
+	if err != nil {
+		return "", err
+	}
+	for n := 1; ; n++ {
+		name := "scratch_" + strconv.Itoa(n)
+		if !slices.Contains(a.scratch, name) && !slices.Contains(tables, name) {
+			return name, nil
+		}
+	}
+}
+
+// dropScratchTables drops every scratch table of the session; it is called before the
+// connection is closed as well as by /scratch drop
+func (a *App) dropScratchTables() {
+	for len(a.scratch) > 0 {
+		a.dropScratchTable(a.scratch[len(a.scratch)-1])
+	}
+}
+
+// dropScratchTable drops one scratch table and stops tracking it even when the drop
+// fails, as the table goes with the session anyway
+func (a *App) dropScratchTable(name string) {
+	result, err := a.connection.Execute(core.DropScratchTableQuery(a.config.DatabaseType, name))
+	if err == nil {
+		err = drainResult(result)
+	}
+	if err != nil {
+		fmt.Printf(a.i18nMgr.Get("failed_to_drop_scratch"), name, err)
+	} else {
+		fmt.Printf(a.i18nMgr.Get("scratch_dropped"), name)
+	}
+
+	a.scratch = slices.DeleteFunc(a.scratch, func(s string) bool { return s == name })
+	if len(a.scratch) == 0 {
+		core.PinSession(a.connection, false)
+	}
+}
+
+// drainResult reads a statement's result to the end, which SQLite needs to run it
+func drainResult(result *core.QueryResult) error {
+	for range result.Itor() {
+	}
+	result.Close()
+	return result.Error()
+}
+
+func (a *App) listScratchTables() {
+	if len(a.scratch) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_scratch_tables"))
+		return
+	}
+	for _, name := range a.scratch {
+		fmt.Printf("  %s\n", name)
+	}
+}
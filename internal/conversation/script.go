@@ -0,0 +1,69 @@
+package conversation
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// RunScript runs a file of prompt lines without the REPL, for sqlterm run. SQL lines run
+// directly, as with bare SQL on; blank lines and lines starting with -- or # are
+// skipped. Every line runs, and the script fails if any of them did, so a CI job
+// catches failing /assert checks. Like Run, it closes the app when done.
+func (a *App) RunScript(path string) error {
+	defer a.rl.Close()
+	defer func() {
+		if a.aiManager != nil {
+			a.aiManager.CloseVectorStore()
+		}
+		if a.connection != nil {
+			a.dropScratchTables()
+		}
+	}()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Printf(a.i18nMgr.Get("script_line"), i+1, line)
+
+		if core.IsSQLStatement(line) {
+			err = a.runScriptQuery(line)
+		} else {
+			err = a.processLine(line)
+		}
+		if err != nil {
+			fmt.Printf(a.i18nMgr.Get("generic_error"), err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf(a.i18nMgr.Get("script_failed"), failed, path)
+	}
+	return nil
+}
+
+// runScriptQuery runs a statement and reports its row count rather than rendering the
+// result, and unlike /exec returns the error
+func (a *App) runScriptQuery(query string) error {
+	result, err := a.executeQuery(strings.TrimSuffix(query, ";"))
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+	if err := result.ForEachRow(func([]core.Value) error { return nil }); err != nil {
+		return err
+	}
+	fmt.Printf(a.i18nMgr.Get("script_query_done"), result.RowCount())
+	return nil
+}
@@ -0,0 +1,68 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+)
+
+// handleConfigSecrets handles /config secrets [status|encrypt|decrypt]. encrypt asks for a
+// new passphrase, so it also changes the passphrase of secrets already encrypted.
+func (a *App) handleConfigSecrets(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+	secrets := a.aiManager.GetConfig().Secrets
+
+	if len(args) == 0 || args[0] == "status" {
+		switch {
+		case !secrets.Encrypt:
+			fmt.Print(a.i18nMgr.Get("secrets_status_plaintext"))
+		case secrets.KeyCommand != "":
+			fmt.Printf(a.i18nMgr.Get("secrets_status_key_command"), secrets.KeyCommand)
+		default:
+			fmt.Print(a.i18nMgr.Get("secrets_status_encrypted"))
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "encrypt":
+		passphrase, err := a.readSecret(a.i18nMgr.Get("secrets_new_passphrase"))
+		if err != nil {
+			return err
+		}
+		if passphrase == "" {
+			return errors.New(a.i18nMgr.Get("secrets_passphrase_empty"))
+		}
+		again, err := a.readSecret(a.i18nMgr.Get("secrets_repeat_passphrase"))
+		if err != nil {
+			return err
+		}
+		if again != passphrase {
+			return errors.New(a.i18nMgr.Get("secrets_passphrase_mismatch"))
+		}
+		if err := a.aiManager.EncryptSecrets(passphrase); err != nil {
+			return err
+		}
+		if secrets.Encrypt {
+			fmt.Print(a.i18nMgr.Get("secrets_passphrase_changed"))
+		} else {
+			fmt.Print(a.i18nMgr.Get("secrets_encrypted"))
+		}
+	case "decrypt":
+		if !secrets.Encrypt {
+			fmt.Print(a.i18nMgr.Get("secrets_status_plaintext"))
+			return nil
+		}
+		if !a.confirm(a.i18nMgr.Get("secrets_decrypt_confirm")) {
+			return nil
+		}
+		if err := a.aiManager.EncryptSecrets(""); err != nil {
+			return err
+		}
+		fmt.Print(a.i18nMgr.Get("secrets_decrypted"))
+	default:
+		fmt.Println(a.i18nMgr.Get("usage_config_secrets"))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package conversation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// shouldSelfCorrect reports whether a failed query is one the AI wrote and may fix.
+// Only read-only queries qualify, so a rejected or failed write is never retried.
+func (a *App) shouldSelfCorrect(query string) bool {
+	if a.aiManager == nil || !a.aiManager.IsConfigured() || !a.aiManager.GetConfig().AI.SelfCorrect {
+		return false
+	}
+	return core.IsReadOnlyQuery(query) && a.aiManager.IsGeneratedQuery(query)
+}
+
+// selfCorrect asks the AI to fix a failing query and runs each correction until one
+// succeeds or the attempts run out. It returns the last query tried with its result or error.
+func (a *App) selfCorrect(query string, execErr error) (string, *core.QueryResult, error) {
+	attempts := a.aiManager.GetConfig().SelfCorrectAttempts()
+	fmt.Printf(a.i18nMgr.Get("self_correct_started"), execErr)
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		fmt.Printf(a.i18nMgr.Get("self_correct_attempt"), attempt, attempts)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		corrected, err := a.aiManager.CorrectQuery(ctx, query, execErr.Error())
+		cancel()
+		if err != nil {
+			fmt.Printf(a.i18nMgr.Get("self_correct_request_failed"), err)
+			return query, nil, execErr
+		}
+
+		renderer := core.NewMarkdownRenderer(a.i18nMgr)
+		if err := renderer.RenderAndDisplay(core.FormatSQLInMarkdown("```sql\n" + corrected + "\n```")); err != nil {
+			fmt.Println(corrected)
+		}
+		if !core.IsReadOnlyQuery(corrected) {
+			fmt.Println(a.i18nMgr.Get("self_correct_not_read_only"))
+			return query, nil, execErr
+		}
+
+		result, err := a.executeQuery(corrected)
+		if err == nil {
+			fmt.Printf(a.i18nMgr.Get("self_correct_succeeded"), attempt)
+			return corrected, result, nil
+		}
+		fmt.Printf(a.i18nMgr.Get("self_correct_attempt_failed"), attempt, err)
+		query, execErr = corrected, err
+	}
+
+	fmt.Printf(a.i18nMgr.Get("self_correct_gave_up"), attempts)
+	return query, nil, execErr
+}
+
+// handleAIConfigSelfCorrect handles /config ai self-correct on|off [attempts]
+func (a *App) handleAIConfigSelfCorrect(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+	if len(args) == 0 {
+		cfg := a.aiManager.GetConfig()
+		fmt.Printf(a.i18nMgr.Get("self_correct_status"), cfg.AI.SelfCorrect, cfg.SelfCorrectAttempts())
+		return nil
+	}
+	if args[0] != "on" && args[0] != "off" {
+		fmt.Println(a.i18nMgr.Get("usage_config_ai_self_correct"))
+		return nil
+	}
+
+	attempts := 0
+	if len(args) > 1 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			fmt.Println(a.i18nMgr.Get("usage_config_ai_self_correct"))
+			return nil
+		}
+		attempts = n
+	}
+
+	if err := a.aiManager.SetSelfCorrect(args[0] == "on", attempts); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_ai_config"), err)
+	}
+	cfg := a.aiManager.GetConfig()
+	fmt.Printf(a.i18nMgr.Get("self_correct_status"), cfg.AI.SelfCorrect, cfg.SelfCorrectAttempts())
+	return nil
+}
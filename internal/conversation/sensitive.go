@@ -0,0 +1,102 @@
+package conversation
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// sensitivePath returns the sensitive column file of the active connection
+func (a *App) sensitivePath() string {
+	return filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), core.SensitiveFile)
+}
+
+// loadSensitiveColumns reads the active connection's sensitive columns, which are then
+// masked in results and left out of AI sample data
+func (a *App) loadSensitiveColumns() error {
+	sensitive, err := core.LoadSensitiveColumns(a.sensitivePath())
+	if err != nil {
+		// Tags of the previous connection say nothing about this one
+		sensitive = nil
+	}
+	a.sensitive = sensitive
+	if a.aiManager != nil {
+		a.aiManager.SetSensitiveColumns(sensitive)
+	}
+	return err
+}
+
+// handleSensitive lists, adds or removes the sensitive columns of the connection:
+// /sensitive [add|remove <table.column|column>...]
+func (a *App) handleSensitive(args []string) error {
+	if a.config == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+	if err := a.loadSensitiveColumns(); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_load_sensitive"), a.sensitivePath(), err)
+	}
+
+	if len(args) == 0 {
+		if len(a.sensitive.Columns) == 0 {
+			fmt.Printf(a.i18nMgr.Get("sensitive_empty"), a.sensitivePath())
+			return nil
+		}
+		fmt.Printf(a.i18nMgr.Get("sensitive_header"), len(a.sensitive.Columns), a.sensitivePath())
+		for _, column := range a.sensitive.Columns {
+			fmt.Printf("  %s\n", column)
+		}
+		return nil
+	}
+	if len(args) < 2 || (args[0] != "add" && args[0] != "remove") {
+		fmt.Println(a.i18nMgr.Get("usage_sensitive"))
+		return nil
+	}
+
+	var changed []string
+	for _, column := range args[1:] {
+		if args[0] == "add" && a.sensitive.Add(column) || args[0] == "remove" && a.sensitive.Remove(column) {
+			changed = append(changed, column)
+		}
+	}
+	if len(changed) == 0 {
+		fmt.Println(a.i18nMgr.Get("sensitive_unchanged"))
+		return nil
+	}
+	if err := core.SaveSensitiveColumns(a.sensitivePath(), a.sensitive); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_save_sensitive"), a.sensitivePath(), err)
+	}
+	if args[0] == "remove" {
+		fmt.Printf(a.i18nMgr.Get("sensitive_removed"), strings.Join(changed, ", "))
+		return nil
+	}
+
+	fmt.Printf(a.i18nMgr.Get("sensitive_added"), strings.Join(changed, ", "))
+	if a.aiManager != nil {
+		// Sample rows stored before the tag may hold the column's values
+		for _, column := range changed {
+			table := ""
+			if i := strings.LastIndex(column, "."); i > 0 {
+				table = column[:i]
+			}
+			if err := a.aiManager.ForgetSampleData(table); err != nil {
+				fmt.Printf(a.i18nMgr.Get("generic_warning"), err)
+			}
+		}
+	}
+	return nil
+}
+
+// handleUnmask shows the sensitive columns of the next query's result. AI attachments
+// keep them masked.
+func (a *App) handleUnmask() error {
+	if a.sensitive == nil || len(a.sensitive.Columns) == 0 {
+		fmt.Println(a.i18nMgr.Get("unmask_nothing"))
+		return nil
+	}
+	a.unmaskNext = true
+	fmt.Println(a.i18nMgr.Get("unmask_next_query"))
+	return nil
+}
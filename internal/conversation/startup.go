@@ -0,0 +1,53 @@
+package conversation
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"sqlterm/internal/i18n"
+)
+
+// StartupProfile records how long each startup step took, for --profile-startup.
+// A nil profile records nothing, so callers can mark steps unconditionally.
+type StartupProfile struct {
+	start time.Time
+	last  time.Time
+	steps []startupStep
+}
+
+type startupStep struct {
+	name     string
+	duration time.Duration
+}
+
+// NewStartupProfile starts a profile whose first step is measured from start
+func NewStartupProfile(start time.Time) *StartupProfile {
+	return &StartupProfile{start: start, last: start}
+}
+
+// Mark ends a step, timing it from the end of the previous one
+func (p *StartupProfile) Mark(step string) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	p.steps = append(p.steps, startupStep{name: step, duration: now.Sub(p.last)})
+	p.last = now
+}
+
+// Report writes each step's time and the total, slowest steps marked
+func (p *StartupProfile) Report(w io.Writer, i18nMgr *i18n.Manager) {
+	if p == nil {
+		return
+	}
+	total := p.last.Sub(p.start)
+	fmt.Fprintf(w, i18nMgr.Get("startup_profile_header"), total.Round(time.Microsecond))
+	for _, step := range p.steps {
+		marker := " "
+		if total > 0 && step.duration*4 >= total {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %-12s %10s\n", marker, step.name, step.duration.Round(time.Microsecond))
+	}
+}
@@ -0,0 +1,55 @@
+package conversation
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// finishSessionStats writes the summary of the connection's session, when anything ran,
+// and stops counting
+func (a *App) finishSessionStats() {
+	stats := a.stats
+	a.stats = nil
+	if stats == nil || (stats.Total() == 0 && stats.Failed == 0) {
+		return
+	}
+	stats.Ended = time.Now()
+	if err := a.sessionMgr.WriteSessionSummary(stats); err != nil {
+		fmt.Printf(a.i18nMgr.Get("session_summary_warning"), err)
+	}
+}
+
+// printSessionStats shows what the session has run so far, for /status
+func (a *App) printSessionStats() {
+	stats := a.stats
+	if stats == nil {
+		return
+	}
+	counts := formatStatementCounts(stats.Statements)
+	if counts == "" {
+		counts = "-"
+	}
+	fmt.Println(a.i18nMgr.GetWithArgs("session_stats_statements", stats.Total(), counts, stats.Failed))
+	fmt.Println(a.i18nMgr.GetWithArgs("session_stats_rows", formatRowCount(float64(stats.RowsFetched))))
+	if stats.Exports > 0 {
+		fmt.Println(a.i18nMgr.GetWithArgs("session_stats_exports", stats.Exports,
+			formatRowCount(float64(stats.ExportedRows)), formatBytes(stats.ExportedBytes)))
+	}
+}
+
+// formatStatementCounts writes counts by statement type as SELECT 12 · INSERT 3, most
+// frequent first
+func formatStatementCounts(counts map[string]int) string {
+	types := slices.SortedFunc(maps.Keys(counts), func(x, y string) int {
+		return cmp.Or(cmp.Compare(counts[y], counts[x]), cmp.Compare(x, y))
+	})
+	parts := make([]string, len(types))
+	for i, t := range types {
+		parts[i] = fmt.Sprintf("%s %d", t, counts[t])
+	}
+	return strings.Join(parts, " · ")
+}
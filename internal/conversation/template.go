@@ -0,0 +1,42 @@
+package conversation
+
+import (
+	"fmt"
+	"maps"
+
+	"sqlterm/internal/core"
+)
+
+// renderQueryFile runs an @ file that is a Go template before its statements are split,
+// asking for each value not given with --var. Files without template actions are
+// returned as they are.
+func (a *App) renderQueryFile(content string, vars map[string]string) (string, error) {
+	if !core.IsSQLTemplate(content) {
+		return content, nil
+	}
+	fields, err := core.SQLTemplateFields(content)
+	if err != nil {
+		return "", fmt.Errorf(a.i18nMgr.Get("failed_to_render_template"), err)
+	}
+
+	values := maps.Clone(vars)
+	if values == nil {
+		values = map[string]string{}
+	}
+	for _, field := range fields {
+		if _, ok := values[field]; ok {
+			continue
+		}
+		value, err := a.readInput(a.i18nMgr.GetWithArgs("template_value_prompt", field))
+		if err != nil {
+			return "", err
+		}
+		values[field] = value
+	}
+
+	rendered, err := core.RenderSQLTemplate(content, a.config.DatabaseType, values)
+	if err != nil {
+		return "", fmt.Errorf(a.i18nMgr.Get("failed_to_render_template"), err)
+	}
+	return rendered, nil
+}
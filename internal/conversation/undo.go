@@ -0,0 +1,281 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+// safetyConfig returns the configured safeguards, or defaults when unavailable
+func (a *App) safetyConfig() config.SafetyConfig {
+	if a.aiManager != nil {
+		if cfg := a.aiManager.GetConfig(); cfg != nil {
+			return cfg.Safety
+		}
+	}
+	return config.SafetyConfig{}
+}
+
+func (a *App) handleConfigSafety(args []string) error {
+	if a.aiManager == nil {
+		return errors.New(a.i18nMgr.Get("ai_manager_not_initialized"))
+	}
+
+	safety := a.safetyConfig()
+	if len(args) == 0 || args[0] == "status" {
+		fmt.Println(a.i18nMgr.Get("safety_config_title"))
+		if safety.PreImage {
+			fmt.Printf(a.i18nMgr.Get("safety_pre_image_status_on"), preImageTarget(safety))
+		} else {
+			fmt.Println(a.i18nMgr.Get("safety_pre_image_status_off"))
+		}
+		return nil
+	}
+
+	switch args[0] {
+	case "pre-image":
+		if len(args) < 2 || len(args) > 3 || (args[1] != "on" && args[1] != "off") ||
+			(len(args) == 3 && (args[1] != "on" || (args[2] != config.PreImageCSV && args[2] != config.PreImageTable))) {
+			fmt.Println(a.i18nMgr.Get("usage_config_safety_pre_image"))
+			return nil
+		}
+		safety.PreImage = args[1] == "on"
+		if len(args) == 3 {
+			safety.PreImageTarget = args[2]
+		}
+	default:
+		fmt.Printf(a.i18nMgr.Get("unknown_safety_setting"), args[0])
+		return a.printConfigSafetyHelp()
+	}
+
+	if err := a.aiManager.SetSafetyConfig(safety); err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_update_safety_config"), err)
+	}
+	if safety.PreImage {
+		fmt.Printf(a.i18nMgr.Get("safety_pre_image_enabled"), preImageTarget(safety))
+	} else {
+		fmt.Print(a.i18nMgr.Get("safety_pre_image_disabled"))
+	}
+	return nil
+}
+
+func (a *App) printConfigSafetyHelp() error {
+	fmt.Print(a.i18nMgr.Get("help_config_safety_title"))
+	fmt.Print(a.i18nMgr.Get("help_config_safety_commands"))
+	return nil
+}
+
+// preImageTarget returns where pre-images are kept, csv unless set otherwise
+func preImageTarget(safety config.SafetyConfig) string {
+	if safety.PreImageTarget == "" {
+		return config.PreImageCSV
+	}
+	return safety.PreImageTarget
+}
+
+// preImageDir is where the connection's pre-images are described, and their CSVs kept
+func (a *App) preImageDir() string {
+	return filepath.Join(a.sessionMgr.GetSessionDir(a.config.Name), "preimages")
+}
+
+// capturePreImage saves the rows an UPDATE or DELETE is about to change, when
+// /config safety pre-image is on. A statement whose rows cannot be selected runs only
+// after the user agrees to go without a pre-image.
+func (a *App) capturePreImage(query string) error {
+	if a.undoing || a.connection == nil || !a.safetyConfig().PreImage {
+		return nil
+	}
+	keyword := core.LeadingKeyword(query)
+	if keyword != "UPDATE" && keyword != "DELETE" {
+		return nil
+	}
+	m, ok := core.ParseModification(query)
+	if !ok {
+		if !a.confirm(a.i18nMgr.Get("pre_image_unsupported_question")) {
+			return errors.New(a.i18nMgr.Get("statement_cancelled"))
+		}
+		return nil
+	}
+
+	p := &core.PreImage{Created: time.Now(), Statement: query, Kind: m.Kind, Table: m.Table}
+	if info, err := a.connection.DescribeTable(m.Name); err == nil {
+		p.Key = info.PrimaryKeys
+	}
+	name := core.PreImageName(p.Created)
+	var err error
+	if preImageTarget(a.safetyConfig()) == config.PreImageTable {
+		p.BackupTable = name
+		err = a.copyPreImageTable(m, p)
+	} else {
+		p.File = filepath.Join(a.preImageDir(), name+".csv")
+		err = a.writePreImageFile(m, p)
+	}
+	if err == nil {
+		err = core.SavePreImage(a.preImageDir(), p)
+	}
+	if err != nil {
+		fmt.Printf(a.i18nMgr.Get("pre_image_failed"), err)
+		if !a.confirm(a.i18nMgr.Get("pre_image_failed_question")) {
+			return errors.New(a.i18nMgr.Get("statement_cancelled"))
+		}
+		return nil
+	}
+
+	where := p.File
+	if p.BackupTable != "" {
+		where = p.BackupTable
+	}
+	fmt.Printf(a.i18nMgr.Get("pre_image_saved"), p.Rows, where)
+	return nil
+}
+
+// copyPreImageTable copies the rows into a backup table, logged like any other statement
+func (a *App) copyPreImageTable(m core.Modification, p *core.PreImage) error {
+	statement, err := core.PreImageTableQuery(a.config.DatabaseType, m, p.BackupTable)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	result, err := a.connection.Execute(statement)
+	if err == nil {
+		err = drainResult(result)
+	}
+	a.logQuery(statement, start, 0, err)
+	if err != nil {
+		return err
+	}
+	rows, err := a.countRows("SELECT count(*) FROM " + core.QuoteIdentifier(a.config.DatabaseType, p.BackupTable))
+	if err != nil {
+		return err
+	}
+	p.Rows = int(rows)
+	return nil
+}
+
+// writePreImageFile selects the rows into a CSV in the pre-image directory
+func (a *App) writePreImageFile(m core.Modification, p *core.PreImage) error {
+	result, err := a.connection.Execute(m.Select("*"))
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+	for _, column := range result.Columns {
+		p.Types = append(p.Types, column.Type)
+	}
+	p.Rows, err = core.WritePreImageCSV(result, p.File)
+	return err
+}
+
+// handleUndo handles /undo last: the statements putting back the rows the last UPDATE
+// or DELETE with a pre-image changed are shown, and run once confirmed
+func (a *App) handleUndo(args []string) error {
+	if len(args) != 1 || args[0] != "last" {
+		fmt.Println(a.i18nMgr.Get("usage_undo"))
+		return nil
+	}
+	if a.connection == nil {
+		fmt.Println(a.i18nMgr.Get("no_database_connection"))
+		return nil
+	}
+
+	p, err := core.LastPreImage(a.preImageDir())
+	if errors.Is(err, core.ErrNoPreImage) {
+		fmt.Println(a.i18nMgr.Get("no_pre_image"))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	columns, rows, err := a.readPreImage(p)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_pre_image"), err)
+	}
+	statements, err := p.UndoStatements(a.config.DatabaseType, columns, rows)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("undo_not_possible"), err)
+	}
+	if len(statements) == 0 {
+		fmt.Println(a.i18nMgr.Get("undo_nothing_to_do"))
+		return nil
+	}
+
+	if err := a.displayMarkdown(a.formatUndo(p, statements)); err != nil {
+		return err
+	}
+	if err := a.guardUndo(statements); err != nil {
+		return err
+	}
+
+	// The undo statements change the rows back themselves, so they take no pre-image
+	a.undoing = true
+	defer func() { a.undoing = false }()
+	for i, statement := range statements {
+		result, err := a.executeQuery(statement)
+		if err == nil {
+			err = drainResult(result)
+		}
+		if err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("undo_failed"), i, len(statements), err)
+		}
+	}
+
+	p.Undone = true
+	if err := core.SavePreImage(a.preImageDir(), p); err != nil {
+		return err
+	}
+	fmt.Printf(a.i18nMgr.Get("undo_done"), len(statements), p.Table)
+	return nil
+}
+
+// guardUndo asks once for the whole undo instead of once per statement: the policy file
+// still applies to each statement, and production connections confirm or approve the lot
+func (a *App) guardUndo(statements []string) error {
+	for _, statement := range statements {
+		if err := a.checkStatementPolicy(statement); err != nil {
+			return err
+		}
+	}
+	return a.guardChange(changeGuard{
+		conn:     a.config,
+		approval: strings.Join(statements, ";\n"),
+		question: a.i18nMgr.GetWithArgs("undo_question", len(statements)),
+		ask:      true,
+	})
+}
+
+// readPreImage reads the rows of a pre-image from its CSV or backup table
+func (a *App) readPreImage(p *core.PreImage) ([]core.Column, [][]*string, error) {
+	if p.File != "" {
+		return core.ReadPreImageCSV(p.File, p.Types)
+	}
+	result, err := a.connection.Execute("SELECT * FROM " + core.QuoteIdentifier(a.config.DatabaseType, p.BackupTable))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer result.Close()
+	return core.ReadPreImageRows(result)
+}
+
+// maxUndoShown is how many undo statements are shown before the rest are counted
+const maxUndoShown = 20
+
+func (a *App) formatUndo(p *core.PreImage, statements []string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# ↩️ %s\n\n", a.i18nMgr.GetWithArgs("undo_header", p.Kind, p.Table, p.Created.Format("2006-01-02 15:04:05"))))
+	sb.WriteString(fmt.Sprintf("```sql\n%s\n```\n\n", strings.TrimSpace(p.Statement)))
+	sb.WriteString(a.i18nMgr.GetWithArgs("undo_statements", len(statements)) + "\n\n")
+	shown := statements
+	if len(shown) > maxUndoShown {
+		shown = shown[:maxUndoShown]
+	}
+	sb.WriteString("```sql\n" + strings.Join(shown, ";\n") + ";\n```\n")
+	if more := len(statements) - len(shown); more > 0 {
+		sb.WriteString("\n" + a.i18nMgr.GetWithArgs("undo_more_statements", more) + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,201 @@
+package conversation
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/ai"
+	"sqlterm/internal/config"
+	"sqlterm/internal/core"
+)
+
+// handleUsage shows AI requests, tokens and cost per provider and model with their
+// median and 95th percentile response times
+func (a *App) handleUsage(args []string) error {
+	if len(args) > 0 && args[0] == "detail" {
+		return a.handleUsageDetail(args[1:])
+	}
+
+	days := 7
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 || len(args) > 1 {
+			fmt.Println(a.i18nMgr.Get("usage_usage"))
+			return nil
+		}
+		days = n
+	}
+
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+	store := a.aiManager.GetUsageStore()
+	if store == nil {
+		fmt.Println(a.i18nMgr.Get("usage_not_available_no_db"))
+		return nil
+	}
+
+	totals, err := store.GetProviderModelStats(days)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_usage"), err)
+	}
+	latencies, err := store.GetLatencyStats(days)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_usage"), err)
+	}
+	summary, err := store.GetUsageSummary()
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_usage"), err)
+	}
+	if len(totals) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_ai_usage"))
+		return nil
+	}
+
+	return a.displayMarkdown(a.formatUsage(days, summary, totals, latencies))
+}
+
+func (a *App) formatUsage(days int, summary *ai.UsageSummary, totals map[string]map[string]ai.UsageTotals, latencies []ai.LatencyStats) string {
+	latencyByModel := make(map[string]ai.LatencyStats, len(latencies))
+	for _, stats := range latencies {
+		latencyByModel[string(stats.Provider)+"\x00"+stats.Model] = stats
+	}
+
+	providers := make([]string, 0, len(totals))
+	for provider := range totals {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 📊 %s\n\n", a.i18nMgr.Get("usage_ai_header")))
+	sb.WriteString(a.i18nMgr.GetWithArgs("todays_usage_display", summary.Today.Requests, summary.Today.Cost) + "  \n")
+	sb.WriteString(a.i18nMgr.GetWithArgs("last_7_days_display", summary.Last7Days.Requests, summary.Last7Days.Cost) + "\n\n")
+	sb.WriteString(fmt.Sprintf(a.i18nMgr.Get("usage_ai_table_header"), days))
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	for _, provider := range providers {
+		models := make([]string, 0, len(totals[provider]))
+		for model := range totals[provider] {
+			models = append(models, model)
+		}
+		sort.Strings(models)
+
+		for _, model := range models {
+			stats := totals[provider][model]
+			p50, p95 := "-", "-"
+			if latency, ok := latencyByModel[provider+"\x00"+model]; ok {
+				p50 = latency.P50.Round(10 * time.Millisecond).String()
+				p95 = latency.P95.Round(10 * time.Millisecond).String()
+			}
+			sb.WriteString(fmt.Sprintf("| %s | %s | %d | %d | %d | %s | %s | %s |\n",
+				provider, strings.ReplaceAll(model, "|", "\\|"), stats.Requests, stats.InputTokens,
+				stats.OutputTokens, a.formatCost(stats.Cost), p50, p95))
+		}
+	}
+	sb.WriteString(a.i18nMgr.Get("usage_latency_note"))
+	return sb.String()
+}
+
+// handleUsageDetail lists the requests recorded on one day, while its details are retained
+func (a *App) handleUsageDetail(args []string) error {
+	if len(args) != 1 {
+		fmt.Println(a.i18nMgr.Get("usage_usage"))
+		return nil
+	}
+	date, ok := parseUsageDate(args[0])
+	if !ok {
+		fmt.Println(a.i18nMgr.Get("usage_usage"))
+		return nil
+	}
+
+	if a.aiManager == nil {
+		fmt.Println(a.i18nMgr.Get("ai_not_configured_short"))
+		return nil
+	}
+	store := a.aiManager.GetUsageStore()
+	if store == nil {
+		fmt.Println(a.i18nMgr.Get("usage_not_available_no_db"))
+		return nil
+	}
+
+	details, err := store.GetUsageDetails(date)
+	if err != nil {
+		return fmt.Errorf(a.i18nMgr.Get("failed_to_read_usage"), err)
+	}
+	if len(details) == 0 {
+		if days := store.RetentionDays(); days > 0 {
+			fmt.Println(a.i18nMgr.GetWithArgs("no_usage_detail_retained", date, days))
+		} else {
+			fmt.Println(a.i18nMgr.GetWithArgs("no_usage_detail", date))
+		}
+		return nil
+	}
+	return a.displayMarkdown(a.formatUsageDetail(date, details))
+}
+
+// parseUsageDate accepts YYYY-MM-DD, today or yesterday
+func parseUsageDate(input string) (string, bool) {
+	switch input {
+	case "today":
+		return time.Now().Format("2006-01-02"), true
+	case "yesterday":
+		return time.Now().AddDate(0, 0, -1).Format("2006-01-02"), true
+	}
+	if _, err := time.Parse("2006-01-02", input); err != nil {
+		return "", false
+	}
+	return input, true
+}
+
+func (a *App) formatUsageDetail(date string, details []ai.UsageDetails) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# 📊 %s\n\n", a.i18nMgr.GetWithArgs("usage_detail_header", date, len(details))))
+	sb.WriteString(a.i18nMgr.Get("usage_detail_table_header"))
+	sb.WriteString("|---|---|---|---|---|---|---|---|\n")
+	// Oldest first reads as the day's audit trail
+	for i := len(details) - 1; i >= 0; i-- {
+		detail := details[i]
+		latency := "-"
+		if detail.Latency > 0 {
+			latency = detail.Latency.Round(10 * time.Millisecond).String()
+		}
+		question := strings.Join(strings.Fields(detail.UserMessage), " ")
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %d | %d | %s | %s | %s |\n",
+			detail.RequestTime.Local().Format("15:04:05"), detail.Provider, strings.ReplaceAll(detail.Model, "|", "\\|"),
+			detail.InputTokens, detail.OutputTokens, a.formatCost(detail.Cost), latency,
+			strings.ReplaceAll(core.TruncateWidth(question, 60), "|", "\\|")))
+	}
+	return sb.String()
+}
+
+// formatCost shows the cost of AI requests, or free for local models
+func (a *App) formatCost(cost float64) string {
+	if cost <= 0 {
+		return a.i18nMgr.Get("ai_call_free")
+	}
+	return fmt.Sprintf("$%.6f", cost)
+}
+
+// formatLastAICall summarises the tokens, response time and cost of the last AI call
+func (a *App) formatLastAICall() string {
+	entry, ok := a.aiManager.LastPromptEntry()
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(a.i18nMgr.Get("ai_call_summary"), entry.InputTokens, entry.OutputTokens,
+		entry.Latency.Round(10*time.Millisecond), a.formatCost(entry.Cost))
+}
+
+// answeredBy names the provider and model of the last AI call, marking a fallback
+// that answered in place of the configured one
+func (a *App) answeredBy(cfg *config.Config) string {
+	entry, ok := a.aiManager.LastPromptEntry()
+	if !ok || (entry.Provider == cfg.AI.Provider && entry.Model == cfg.AI.Model) {
+		return cfg.FormatProviderInfo()
+	}
+	return fmt.Sprintf(a.i18nMgr.Get("ai_answered_by_fallback"), entry.Provider, entry.Model, cfg.FormatProviderInfo())
+}
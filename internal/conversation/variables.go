@@ -0,0 +1,107 @@
+package conversation
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"sqlterm/internal/core"
+)
+
+// handleSet sets a session variable for {{name}} references in later queries:
+// /set last_id = SELECT max(id) FROM orders stores the query's single value, and
+// /set region = 'APAC' stores the text as is. /set lists the variables and
+// /set --unset name removes one.
+func (a *App) handleSet(args []string) error {
+	if len(args) == 0 {
+		a.listVariables()
+		return nil
+	}
+	if args[0] == "--unset" {
+		if len(args) != 2 {
+			fmt.Println(a.i18nMgr.Get("usage_set"))
+			return nil
+		}
+		delete(a.vars, args[1])
+		fmt.Printf(a.i18nMgr.Get("variable_unset"), args[1])
+		return nil
+	}
+
+	name, expr, ok := strings.Cut(strings.Join(args, " "), "=")
+	name, expr = strings.TrimSpace(name), strings.TrimSpace(expr)
+	if !ok || expr == "" {
+		fmt.Println(a.i18nMgr.Get("usage_set"))
+		return nil
+	}
+	if !core.ValidVariableName(name) {
+		return fmt.Errorf(a.i18nMgr.Get("invalid_variable_name"), name)
+	}
+
+	value := expr
+	if core.IsSQLStatement(expr) {
+		if a.connection == nil {
+			fmt.Println(a.i18nMgr.Get("no_database_connection"))
+			return nil
+		}
+		var err error
+		if value, err = a.scalarQuery(strings.TrimSuffix(expr, ";")); err != nil {
+			return fmt.Errorf(a.i18nMgr.Get("failed_to_set_variable"), name, err)
+		}
+	}
+
+	if a.vars == nil {
+		a.vars = map[string]string{}
+	}
+	a.vars[name] = value
+	fmt.Printf(a.i18nMgr.Get("variable_set"), name, value)
+	return nil
+}
+
+// scalarQuery runs a query that must return one row of one column, giving NULL as the
+// SQL keyword so the value can be used in a later query
+func (a *App) scalarQuery(query string) (string, error) {
+	result, err := a.executeQuery(query)
+	if err != nil {
+		return "", err
+	}
+	defer result.Close()
+	if len(result.Columns) != 1 {
+		return "", fmt.Errorf(a.i18nMgr.Get("scalar_query_columns"), len(result.Columns))
+	}
+
+	var values []core.Value
+	err = result.ForEachRow(func(row []core.Value) error {
+		values = append(values, row[0])
+		if len(values) > 1 {
+			return core.ErrStopRows
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case len(values) == 0:
+		return "", fmt.Errorf("%s", a.i18nMgr.Get("scalar_query_no_rows"))
+	case len(values) > 1:
+		return "", fmt.Errorf("%s", a.i18nMgr.Get("scalar_query_many_rows"))
+	case values[0].IsNull():
+		return "NULL", nil
+	}
+	return values[0].String(), nil
+}
+
+func (a *App) listVariables() {
+	if len(a.vars) == 0 {
+		fmt.Println(a.i18nMgr.Get("no_variables"))
+		return
+	}
+	names := make([]string, 0, len(a.vars))
+	for name := range a.vars {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		fmt.Printf("  {{%s}} = %s\n", name, a.vars[name])
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedDatabase is returned by features that need server-side system views
+var ErrUnsupportedDatabase = errors.New("not supported for this database type")
+
+// SessionActivity is one server session as reported by pg_stat_activity or SHOW PROCESSLIST
+type SessionActivity struct {
+	PID      string
+	User     string
+	Database string
+	Client   string
+	State    string
+	Wait     string
+	Duration time.Duration
+	Query    string
+}
+
+// Idle reports whether the session is not running a statement
+func (s SessionActivity) Idle() bool {
+	state := strings.ToLower(s.State)
+	return state == "idle" || state == "sleep"
+}
+
+const postgresActivityQuery = `SELECT pid, COALESCE(usename, ''), COALESCE(datname, ''),
+	COALESCE(client_addr::text, ''), COALESCE(state, ''),
+	COALESCE(wait_event_type || ':' || wait_event, ''),
+	COALESCE(EXTRACT(EPOCH FROM (now() - query_start)), 0), COALESCE(query, '')
+FROM pg_stat_activity
+WHERE pid <> pg_backend_pid() AND backend_type = 'client backend'
+ORDER BY query_start NULLS LAST`
+
+const mysqlActivityQuery = `SELECT ID, USER, HOST, COALESCE(DB, ''), COMMAND, COALESCE(STATE, ''), TIME, COALESCE(INFO, '')
+FROM information_schema.PROCESSLIST
+WHERE ID <> CONNECTION_ID()
+ORDER BY TIME DESC`
+
+// ListActivity returns the sessions connected to the server, excluding our own.
+// Idle sessions are only included when includeIdle is set.
+func ListActivity(conn Connection, dbType DatabaseType, includeIdle bool) ([]SessionActivity, error) {
+	var query string
+	switch dbType {
+	case PostgreSQL:
+		query = postgresActivityQuery
+	case MySQL:
+		query = mysqlActivityQuery
+	default:
+		return nil, ErrUnsupportedDatabase
+	}
+
+	result, err := conn.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var sessions []SessionActivity
+	for row := range result.Itor() {
+		var session SessionActivity
+		switch dbType {
+		case PostgreSQL:
+			seconds, _ := strconv.ParseFloat(row[6].String(), 64)
+			session = SessionActivity{
+				PID:      row[0].String(),
+				User:     row[1].String(),
+				Database: row[2].String(),
+				Client:   row[3].String(),
+				State:    row[4].String(),
+				Wait:     row[5].String(),
+				Duration: time.Duration(seconds * float64(time.Second)),
+				Query:    row[7].String(),
+			}
+		case MySQL:
+			seconds, _ := strconv.Atoi(row[6].String())
+			session = SessionActivity{
+				PID:      row[0].String(),
+				User:     row[1].String(),
+				Client:   row[2].String(),
+				Database: row[3].String(),
+				State:    row[4].String(),
+				Wait:     row[5].String(),
+				Duration: time.Duration(seconds) * time.Second,
+				Query:    row[7].String(),
+			}
+		}
+		if includeIdle || !session.Idle() {
+			sessions = append(sessions, session)
+		}
+	}
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	return sessions, nil
+}
+
+// KillStatement returns the statement that terminates a session, or only cancels its
+// running query when queryOnly is set
+func KillStatement(dbType DatabaseType, pid string, queryOnly bool) (string, error) {
+	id, err := strconv.ParseUint(pid, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid process id: %s", pid)
+	}
+
+	switch dbType {
+	case PostgreSQL:
+		if queryOnly {
+			return fmt.Sprintf("SELECT pg_cancel_backend(%d)", id), nil
+		}
+		return fmt.Sprintf("SELECT pg_terminate_backend(%d)", id), nil
+	case MySQL:
+		if queryOnly {
+			return fmt.Sprintf("KILL QUERY %d", id), nil
+		}
+		return fmt.Sprintf("KILL %d", id), nil
+	default:
+		return "", ErrUnsupportedDatabase
+	}
+}
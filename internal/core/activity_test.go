@@ -0,0 +1,57 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKillStatement(t *testing.T) {
+	testCases := []struct {
+		name      string
+		dbType    DatabaseType
+		pid       string
+		queryOnly bool
+		expected  string
+		hasError  bool
+	}{
+		{name: "Postgres terminate", dbType: PostgreSQL, pid: "42", expected: "SELECT pg_terminate_backend(42)"},
+		{name: "Postgres cancel", dbType: PostgreSQL, pid: "42", queryOnly: true, expected: "SELECT pg_cancel_backend(42)"},
+		{name: "MySQL kill", dbType: MySQL, pid: "7", expected: "KILL 7"},
+		{name: "MySQL kill query", dbType: MySQL, pid: "7", queryOnly: true, expected: "KILL QUERY 7"},
+		{name: "Rejects non-numeric pid", dbType: MySQL, pid: "7; DROP TABLE users", hasError: true},
+		{name: "SQLite unsupported", dbType: SQLite, pid: "1", hasError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			statement, err := KillStatement(tc.dbType, tc.pid, tc.queryOnly)
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("Expected error, got %q", statement)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if statement != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, statement)
+			}
+		})
+	}
+}
+
+func TestListActivityUnsupported(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	if _, err := ListActivity(conn, SQLite, false); !errors.Is(err, ErrUnsupportedDatabase) {
+		t.Errorf("Expected ErrUnsupportedDatabase, got %v", err)
+	}
+}
+
+func TestSessionActivityIdle(t *testing.T) {
+	for state, idle := range map[string]bool{"idle": true, "Sleep": true, "active": false, "Query": false} {
+		if got := (SessionActivity{State: state}).Idle(); got != idle {
+			t.Errorf("State %q: expected idle=%v, got %v", state, idle, got)
+		}
+	}
+}
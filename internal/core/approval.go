@@ -0,0 +1,190 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ApprovalBundle is a statement waiting for a second person's approval before it runs
+// on a guarded connection. It is saved as JSON for the approver to review.
+type ApprovalBundle struct {
+	ID            string    `json:"id"`
+	Created       time.Time `json:"created"`
+	Requester     string    `json:"requester"`
+	Connection    string    `json:"connection"`
+	Database      string    `json:"database,omitempty"`
+	Statement     string    `json:"statement"`
+	Plan          string    `json:"plan,omitempty"`           // Rendered EXPLAIN plan, empty when there is none
+	EstimatedRows float64   `json:"estimated_rows,omitempty"` // Planner's estimate of the rows affected
+}
+
+// NewApprovalBundle starts a bundle for a statement on a connection, under a random ID
+func NewApprovalBundle(requester string, conn *ConnectionConfig, statement string) (*ApprovalBundle, error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	return &ApprovalBundle{
+		ID:         hex.EncodeToString(id),
+		Created:    time.Now().UTC(),
+		Requester:  requester,
+		Connection: conn.Name,
+		Database:   conn.Database,
+		Statement:  statement,
+	}, nil
+}
+
+// AddPlan explains the statement and records the plan and, unless one is already set,
+// the planner's row estimate
+func (b *ApprovalBundle) AddPlan(conn Connection, dbType DatabaseType) error {
+	root, err := ExplainQuery(conn, dbType, b.Statement, false)
+	if err != nil {
+		return err
+	}
+	b.Plan = RenderPlan(root)
+	if b.EstimatedRows == 0 {
+		b.EstimatedRows = root.EstimatedRows()
+	}
+	return nil
+}
+
+// ApprovalToken returns the token an approver hands back to the requester: the
+// bundle signed with the approver's private key
+func (b *ApprovalBundle) ApprovalToken(key ed25519.PrivateKey) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, b.message()))
+}
+
+// VerifyApproval checks a token against the public keys of the approvers, by name, and
+// returns the name of the one who signed it. The requester cannot approve their own
+// statement.
+func (b *ApprovalBundle) VerifyApproval(approvers map[string]string, token string) (string, error) {
+	if len(approvers) == 0 {
+		return "", errors.New("the policy file lists no approvers")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(token))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return "", errors.New("not an approval token")
+	}
+	for _, name := range slices.Sorted(maps.Keys(approvers)) {
+		public, err := ParseApprovalPublicKey(approvers[name])
+		if err != nil || !ed25519.Verify(public, b.message(), signature) {
+			continue
+		}
+		if strings.EqualFold(name, b.Requester) {
+			return "", errors.New("a statement cannot be approved by the person who asked for it")
+		}
+		return name, nil
+	}
+	return "", errors.New("the approval token does not match this statement or any approver's key")
+}
+
+// message covers everything the approver reviewed, so no part of the request can be
+// changed after approval
+func (b *ApprovalBundle) message() []byte {
+	return []byte(strings.Join([]string{b.ID, b.Requester, b.Connection, b.Database, b.Statement}, "\x00"))
+}
+
+// GenerateApprovalKey writes a new private key for signing approvals to path, which
+// must not exist yet, and returns its public key as the policy file lists it
+func GenerateApprovalKey(path string) (string, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", fmt.Errorf("failed to create approval key: %w", err)
+	}
+	defer file.Close()
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(public), nil
+}
+
+// LoadApprovalKey reads a private key written by GenerateApprovalKey
+func LoadApprovalKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM private key", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read approval key %s: %w", path, err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 key", path)
+	}
+	return key, nil
+}
+
+// ApprovalPublicKey returns the public half of a private key as the policy file lists it
+func ApprovalPublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// ParseApprovalPublicKey decodes a public key as the policy file lists it
+func ParseApprovalPublicKey(value string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("expected a base64 Ed25519 public key, as printed by sqlterm approve --new-key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// SaveApprovalBundle writes a bundle to dir as <id>.json and returns its path
+func SaveApprovalBundle(dir string, b *ApprovalBundle) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create approvals directory: %w", err)
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, b.ID+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return "", fmt.Errorf("failed to write approval bundle: %w", err)
+	}
+	return path, nil
+}
+
+// LoadApprovalBundle reads a bundle written by SaveApprovalBundle
+func LoadApprovalBundle(path string) (*ApprovalBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b ApprovalBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("not an approval bundle: %w", err)
+	}
+	if b.ID == "" || b.Statement == "" {
+		return nil, errors.New("not an approval bundle: id or statement is missing")
+	}
+	return &b, nil
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"crypto/ed25519"
+	"path/filepath"
+	"testing"
+)
+
+func TestApprovalBundle(t *testing.T) {
+	dir := t.TempDir()
+	conn := &ConnectionConfig{Name: "prod", DatabaseType: SQLite, Database: filepath.Join(dir, "prod.db")}
+	db, err := NewConnection(conn)
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer db.Close()
+	mustExec(t, db, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+
+	bundle, err := NewApprovalBundle("alice", conn, "DELETE FROM users WHERE name = 'x'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bundle.AddPlan(db, SQLite); err != nil || bundle.Plan == "" {
+		t.Errorf("Expected a plan, got %q, %v", bundle.Plan, err)
+	}
+	path, err := SaveApprovalBundle(filepath.Join(dir, "approvals"), bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadApprovalBundle(path)
+	if err != nil {
+		t.Fatalf("LoadApprovalBundle failed: %v", err)
+	}
+
+	bobKey := generateApprovalKey(t, filepath.Join(dir, "bob.key"))
+	aliceKey := generateApprovalKey(t, filepath.Join(dir, "alice.key"))
+	approvers := map[string]string{"bob": ApprovalPublicKey(bobKey), "alice": ApprovalPublicKey(aliceKey)}
+
+	token := loaded.ApprovalToken(bobKey)
+	if approver, err := bundle.VerifyApproval(approvers, token); err != nil || approver != "bob" {
+		t.Errorf("Expected bob's token to verify, got %q, %v", approver, err)
+	}
+	if _, err := bundle.VerifyApproval(map[string]string{"carol": approvers["alice"]}, token); err == nil {
+		t.Error("Expected a token signed by a key the policy does not list to be rejected")
+	}
+	if _, err := bundle.VerifyApproval(nil, token); err == nil {
+		t.Error("Expected approvals to be refused when the policy lists no approvers")
+	}
+	if _, err := bundle.VerifyApproval(approvers, bundle.ApprovalToken(aliceKey)); err == nil {
+		t.Error("Expected the requester's own approval to be rejected")
+	}
+	if _, err := GenerateApprovalKey(filepath.Join(dir, "bob.key")); err == nil {
+		t.Error("Expected an existing key to be kept")
+	}
+
+	changed := *bundle
+	changed.Statement = "DELETE FROM users"
+	if _, err := changed.VerifyApproval(approvers, token); err == nil {
+		t.Error("Expected a token for another statement to be rejected")
+	}
+}
+
+func generateApprovalKey(t *testing.T, path string) ed25519.PrivateKey {
+	t.Helper()
+	public, err := GenerateApprovalKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := LoadApprovalKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ApprovalPublicKey(key) != public {
+		t.Fatalf("Expected the loaded key to match public key %s", public)
+	}
+	return key
+}
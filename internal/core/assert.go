@@ -0,0 +1,231 @@
+package core
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MaxAssertMismatches is how many mismatched cells an assertion report lists; the rest
+// are only counted
+const MaxAssertMismatches = 20
+
+// Snapshot is a query result as text, saved as the expected result of an assertion.
+// NULL is the empty string, as in CSV exports.
+type Snapshot struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// AssertMismatch is a cell whose value differs from the snapshot. Row counts from 1.
+type AssertMismatch struct {
+	Row      int
+	Column   string
+	Expected string
+	Actual   string
+}
+
+// AssertReport is the outcome of comparing a result with its snapshot
+type AssertReport struct {
+	ExpectedColumns []string
+	ActualColumns   []string
+	ExpectedRows    int
+	ActualRows      int
+	Mismatches      []AssertMismatch // The first MaxAssertMismatches
+	MismatchCount   int
+}
+
+// Passed reports whether the result matched the snapshot exactly
+func (r *AssertReport) Passed() bool {
+	return r.ColumnsMatch() && r.ExpectedRows == r.ActualRows && r.MismatchCount == 0
+}
+
+// ColumnsMatch reports whether the result has the snapshot's columns in the same order.
+// An empty JSON snapshot names no columns, so it only expects no rows.
+func (r *AssertReport) ColumnsMatch() bool {
+	if len(r.ExpectedColumns) == 0 && r.ExpectedRows == 0 {
+		return true
+	}
+	return slices.Equal(r.ExpectedColumns, r.ActualColumns)
+}
+
+// SnapshotFromResult reads every row of a result and closes it
+func SnapshotFromResult(result *QueryResult) (*Snapshot, error) {
+	defer result.Close()
+	snapshot := &Snapshot{Columns: result.DisplayColumnNames()}
+	err := result.ForEachRow(func(row []Value) error {
+		cells := make([]string, len(row))
+		for i, val := range row {
+			cells[i] = val.String()
+		}
+		snapshot.Rows = append(snapshot.Rows, cells)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// CompareSnapshot compares a result with the expected snapshot row by row, so queries
+// should have an ORDER BY. Cells that are both numbers match when equal in value, so
+// 1.50 matches 1.5.
+func CompareSnapshot(expected, actual *Snapshot) *AssertReport {
+	report := &AssertReport{
+		ExpectedColumns: expected.Columns,
+		ActualColumns:   actual.Columns,
+		ExpectedRows:    len(expected.Rows),
+		ActualRows:      len(actual.Rows),
+	}
+	if !report.ColumnsMatch() {
+		return report
+	}
+
+	for i := range min(len(expected.Rows), len(actual.Rows)) {
+		for j, column := range expected.Columns {
+			want, got := cell(expected.Rows[i], j), cell(actual.Rows[i], j)
+			if cellsEqual(want, got) {
+				continue
+			}
+			report.MismatchCount++
+			if len(report.Mismatches) < MaxAssertMismatches {
+				report.Mismatches = append(report.Mismatches, AssertMismatch{Row: i + 1, Column: column, Expected: want, Actual: got})
+			}
+		}
+	}
+	return report
+}
+
+func cell(row []string, index int) string {
+	if index < len(row) {
+		return row[index]
+	}
+	return ""
+}
+
+func cellsEqual(want, got string) bool {
+	if want == got {
+		return true
+	}
+	a, errA := strconv.ParseFloat(strings.TrimSpace(want), 64)
+	b, errB := strconv.ParseFloat(strings.TrimSpace(got), 64)
+	return errA == nil && errB == nil && a == b
+}
+
+// LoadSnapshot reads a snapshot from a CSV file with a header row, or a JSON array of
+// objects with one key per column
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return parseJSONSnapshot(data)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %w", path, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("snapshot %s has no header row", path)
+	}
+	return &Snapshot{Columns: records[0], Rows: records[1:]}, nil
+}
+
+// parseJSONSnapshot reads objects in key order, since the keys are the columns
+func parseJSONSnapshot(data []byte) (*Snapshot, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("a JSON snapshot must be an array of objects")
+	}
+
+	snapshot := &Snapshot{Columns: []string{}}
+	for dec.More() {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("a JSON snapshot must be an array of objects")
+		}
+		values := map[string]string{}
+		var keys []string
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read JSON snapshot: %w", err)
+			}
+			var value any
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("failed to read JSON snapshot: %w", err)
+			}
+			keys = append(keys, key.(string))
+			values[key.(string)] = snapshotCell(value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read JSON snapshot: %w", err)
+		}
+		if len(snapshot.Rows) == 0 {
+			snapshot.Columns = keys
+		}
+		row := make([]string, len(snapshot.Columns))
+		for i, column := range snapshot.Columns {
+			row[i] = values[column]
+		}
+		snapshot.Rows = append(snapshot.Rows, row)
+	}
+	return snapshot, nil
+}
+
+func snapshotCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// SaveSnapshot writes a snapshot as CSV, or as JSON when the path ends in .json
+func SaveSnapshot(path string, snapshot *Snapshot) error {
+	var buf bytes.Buffer
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		buf.WriteString("[\n")
+		for i, row := range snapshot.Rows {
+			buf.WriteString("  {")
+			for j, column := range snapshot.Columns {
+				if j > 0 {
+					buf.WriteString(", ")
+				}
+				key, _ := json.Marshal(column)
+				value, _ := json.Marshal(cell(row, j))
+				fmt.Fprintf(&buf, "%s: %s", key, value)
+			}
+			buf.WriteString("}")
+			if i < len(snapshot.Rows)-1 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n")
+		}
+		buf.WriteString("]\n")
+	} else {
+		writer := csv.NewWriter(&buf)
+		writer.Write(snapshot.Columns)
+		writer.WriteAll(snapshot.Rows)
+		if err := writer.Error(); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompareSnapshot(t *testing.T) {
+	expected := &Snapshot{
+		Columns: []string{"id", "name", "total"},
+		Rows:    [][]string{{"1", "alice", "10.50"}, {"2", "bob", "3"}},
+	}
+
+	testCases := []struct {
+		name       string
+		actual     *Snapshot
+		passed     bool
+		mismatches []AssertMismatch
+	}{
+		{
+			name:   "equal numbers in another format match",
+			actual: &Snapshot{Columns: []string{"id", "name", "total"}, Rows: [][]string{{"1", "alice", "10.5"}, {"2", "bob", "3.0"}}},
+			passed: true,
+		},
+		{
+			name:       "changed cell",
+			actual:     &Snapshot{Columns: []string{"id", "name", "total"}, Rows: [][]string{{"1", "alice", "10.5"}, {"2", "bobby", "3"}}},
+			mismatches: []AssertMismatch{{Row: 2, Column: "name", Expected: "bob", Actual: "bobby"}},
+		},
+		{
+			name:   "missing row",
+			actual: &Snapshot{Columns: []string{"id", "name", "total"}, Rows: [][]string{{"1", "alice", "10.5"}}},
+		},
+		{
+			name:   "different columns",
+			actual: &Snapshot{Columns: []string{"id", "name"}, Rows: [][]string{{"1", "alice"}, {"2", "bob"}}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			report := CompareSnapshot(expected, tc.actual)
+			if report.Passed() != tc.passed {
+				t.Errorf("Expected passed=%v, got %+v", tc.passed, report)
+			}
+			if !reflect.DeepEqual(report.Mismatches, tc.mismatches) {
+				t.Errorf("Expected mismatches %v, got %v", tc.mismatches, report.Mismatches)
+			}
+		})
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	snapshot := &Snapshot{
+		Columns: []string{"id", "note"},
+		Rows:    [][]string{{"1", "has, comma"}, {"2", ""}},
+	}
+
+	for _, name := range []string{"expected.csv", "expected.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SaveSnapshot(path, snapshot); err != nil {
+			t.Fatalf("SaveSnapshot(%s) failed: %v", name, err)
+		}
+		loaded, err := LoadSnapshot(path)
+		if err != nil {
+			t.Fatalf("LoadSnapshot(%s) failed: %v", name, err)
+		}
+		if !reflect.DeepEqual(loaded, snapshot) {
+			t.Errorf("%s: expected %+v, got %+v", name, snapshot, loaded)
+		}
+	}
+}
+
+func TestParseJSONSnapshot(t *testing.T) {
+	snapshot, err := parseJSONSnapshot([]byte(`[{"id": 1, "active": true, "tags": ["a"], "note": null}]`))
+	if err != nil {
+		t.Fatalf("parseJSONSnapshot failed: %v", err)
+	}
+	expected := &Snapshot{
+		Columns: []string{"id", "active", "tags", "note"},
+		Rows:    [][]string{{"1", "true", `["a"]`, ""}},
+	}
+	if !reflect.DeepEqual(snapshot, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, snapshot)
+	}
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Authentication methods for a connection's auth block
+const (
+	AuthIAM    = "iam"    // Tokens issued by the cloud provider; see iam.go
+	AuthGSSAPI = "gssapi" // Kerberos ticket from kinit, no password
+	AuthLDAP   = "ldap"   // Password checked by the server against LDAP, so it is only sent over TLS
+)
+
+// AuthMethods lists the supported auth block methods
+var AuthMethods = []string{AuthIAM, AuthGSSAPI, AuthLDAP}
+
+// gssapiAvailable is set when a Kerberos provider is registered with the PostgreSQL
+// driver, which only builds tagged kerberos do; see gssapi_kerberos.go
+var gssapiAvailable bool
+
+// AuthConfig replaces plain password authentication
+type AuthConfig struct {
+	Method string `yaml:"method"` // iam, gssapi or ldap
+
+	// IAM
+	Provider     string `yaml:"provider,omitempty"`      // aws or gcp
+	Region       string `yaml:"region,omitempty"`        // AWS region; defaults to AWS_REGION
+	Profile      string `yaml:"profile,omitempty"`       // AWS credentials profile; defaults to AWS_PROFILE, then default
+	TokenCommand string `yaml:"token_command,omitempty"` // GCP: prints an access token; defaults to gcloud auth print-access-token
+	SSLRootCert  string `yaml:"sslrootcert,omitempty"`   // CA bundle that signs the server certificate, such as RDS's global-bundle.pem; defaults to the system roots
+
+	// GSSAPI, named after the libpq settings
+	KrbSrvName string `yaml:"krbsrvname,omitempty"` // Kerberos service name; defaults to postgres
+	KrbSPN     string `yaml:"krbspn,omitempty"`     // Full service principal, overriding krbsrvname
+	GSSEncMode string `yaml:"gssencmode,omitempty"` // disable or prefer; the driver encrypts with TLS only
+}
+
+// UsesPassword reports whether the connection signs in with a password, rather than
+// with IAM tokens or a Kerberos ticket
+func (c *ConnectionConfig) UsesPassword() bool {
+	return c.Auth == nil || c.Auth.Method == AuthLDAP
+}
+
+// validateAuth rejects auth blocks the connection's database cannot use
+func validateAuth(config *ConnectionConfig) error {
+	auth := config.Auth
+	switch auth.Method {
+	case AuthIAM:
+		if config.DatabaseType != MySQL && config.DatabaseType != PostgreSQL {
+			return fmt.Errorf("IAM authentication needs MySQL or PostgreSQL, not %s", config.DatabaseType)
+		}
+	case AuthGSSAPI, AuthLDAP:
+		if config.DatabaseType != PostgreSQL {
+			return fmt.Errorf("%s authentication is only supported for PostgreSQL, not %s", auth.Method, config.DatabaseType)
+		}
+		if auth.Method == AuthGSSAPI && !gssapiAvailable {
+			return fmt.Errorf("gssapi authentication needs a build of sqlterm with Kerberos support: make build-kerberos")
+		}
+	default:
+		return fmt.Errorf("unsupported auth method %q; use %s", auth.Method, strings.Join(AuthMethods, ", "))
+	}
+
+	switch auth.GSSEncMode {
+	case "", "disable", "prefer":
+	case "require":
+		return fmt.Errorf("gssencmode=require is not supported: the PostgreSQL driver encrypts with TLS only")
+	default:
+		return fmt.Errorf("invalid gssencmode %q; use disable or prefer", auth.GSSEncMode)
+	}
+	return nil
+}
+
+// postgresAuthParams returns the sslmode and extra DSN settings for an auth block
+func postgresAuthParams(auth *AuthConfig) (string, []string) {
+	if auth == nil {
+		return "disable", nil
+	}
+	switch auth.Method {
+	case AuthIAM:
+		// RDS and Cloud SQL only accept IAM tokens over TLS, and a token is as good as a
+		// password until it expires, so only a server with a verified certificate gets it
+		return "verify-full", sslRootCertParams(auth)
+	case AuthLDAP:
+		// LDAP passwords are sent to the server in the clear
+		if auth.SSLRootCert != "" {
+			return "verify-full", sslRootCertParams(auth)
+		}
+		return "require", nil
+	case AuthGSSAPI:
+		var params []string
+		if auth.KrbSrvName != "" {
+			params = append(params, "krbsrvname="+quotePQValue(auth.KrbSrvName))
+		}
+		if auth.KrbSPN != "" {
+			params = append(params, "krbspn="+quotePQValue(auth.KrbSPN))
+		}
+		return "disable", params
+	}
+	return "disable", nil
+}
+
+func sslRootCertParams(auth *AuthConfig) []string {
+	if auth.SSLRootCert == "" {
+		return nil
+	}
+	return []string{"sslrootcert=" + quotePQValue(auth.SSLRootCert)}
+}
+
+// mysqlVerifiedTLS registers a TLS config for the MySQL driver that checks the server's
+// certificate and host name against the auth block's CA bundle, or the system roots,
+// and returns the name a DSN refers to it by
+func mysqlVerifiedTLS(config *ConnectionConfig) (string, error) {
+	tlsConfig := &tls.Config{ServerName: config.Host, MinVersion: tls.VersionTLS12}
+	if path := config.Auth.SSLRootCert; path != "" {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read sslrootcert: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("no certificates found in sslrootcert %s", path)
+		}
+	}
+	// Connections to the same server with the same CA share a config
+	name := fmt.Sprintf("sqlterm-%x", sha256.Sum256([]byte(config.Host+"\x00"+config.Auth.SSLRootCert)))[:24]
+	if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+		return "", err
+	}
+	return name, nil
+}
@@ -0,0 +1,83 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAuth(t *testing.T) {
+	// Without a Kerberos provider every GSSAPI sign-in would fail, so it is refused
+	gssapi := &ConnectionConfig{DatabaseType: PostgreSQL, Auth: &AuthConfig{Method: AuthGSSAPI}}
+	if err := validateAuth(gssapi); gssapiAvailable != (err == nil) {
+		t.Errorf("validateAuth() error = %v with a provider registered: %v", err, gssapiAvailable)
+	}
+	defer func(available bool) { gssapiAvailable = available }(gssapiAvailable)
+	gssapiAvailable = true
+
+	testCases := []struct {
+		name    string
+		dbType  DatabaseType
+		auth    AuthConfig
+		wantErr bool
+	}{
+		{name: "GSSAPI on PostgreSQL", dbType: PostgreSQL, auth: AuthConfig{Method: AuthGSSAPI, GSSEncMode: "prefer"}},
+		{name: "LDAP on PostgreSQL", dbType: PostgreSQL, auth: AuthConfig{Method: AuthLDAP}},
+		{name: "IAM on MySQL", dbType: MySQL, auth: AuthConfig{Method: AuthIAM, Provider: IAMProviderAWS}},
+		{name: "GSSAPI on MySQL", dbType: MySQL, auth: AuthConfig{Method: AuthGSSAPI}, wantErr: true},
+		{name: "LDAP on SQLite", dbType: SQLite, auth: AuthConfig{Method: AuthLDAP}, wantErr: true},
+		{name: "GSS encryption required", dbType: PostgreSQL, auth: AuthConfig{Method: AuthGSSAPI, GSSEncMode: "require"}, wantErr: true},
+		{name: "Unknown gssencmode", dbType: PostgreSQL, auth: AuthConfig{Method: AuthGSSAPI, GSSEncMode: "allow"}, wantErr: true},
+		{name: "Unknown method", dbType: PostgreSQL, auth: AuthConfig{Method: "radius"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAuth(&ConnectionConfig{DatabaseType: tc.dbType, Auth: &tc.auth})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validateAuth() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestDataSource_PostgresAuth(t *testing.T) {
+	config := &ConnectionConfig{DatabaseType: PostgreSQL, Host: "pg.corp", Port: 5432, Username: "alice", Database: "dw",
+		Auth: &AuthConfig{Method: AuthGSSAPI, KrbSrvName: "pgsql", KrbSPN: "postgres/pg.corp@CORP.EXAMPLE"}}
+
+	_, dsn, err := dataSource(config, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"krbsrvname='pgsql'", "krbspn='postgres/pg.corp@CORP.EXAMPLE'", "sslmode=disable"} {
+		if !strings.Contains(dsn, want) {
+			t.Errorf("DSN should contain %q, got %q", want, dsn)
+		}
+	}
+	if strings.Contains(dsn, "gssencmode") {
+		t.Errorf("gssencmode is not a lib/pq setting and would be sent to the server, got %q", dsn)
+	}
+
+	config.Auth = &AuthConfig{Method: AuthLDAP}
+	if _, dsn, _ = dataSource(config, "secret"); !strings.Contains(dsn, "sslmode=require") {
+		t.Errorf("LDAP passwords should only be sent over TLS, got %q", dsn)
+	}
+}
+
+func TestUsesPassword(t *testing.T) {
+	testCases := []struct {
+		auth *AuthConfig
+		want bool
+	}{
+		{auth: nil, want: true},
+		{auth: &AuthConfig{Method: AuthLDAP}, want: true},
+		{auth: &AuthConfig{Method: AuthGSSAPI}, want: false},
+		{auth: &AuthConfig{Method: AuthIAM}, want: false},
+	}
+
+	for _, tc := range testCases {
+		cfg := &ConnectionConfig{DatabaseType: PostgreSQL, Auth: tc.auth}
+		if got := cfg.UsesPassword(); got != tc.want {
+			t.Errorf("UsesPassword() with %+v = %v, want %v", tc.auth, got, tc.want)
+		}
+	}
+}
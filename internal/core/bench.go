@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// BenchOptions controls how often and how widely a benchmark runs its query
+type BenchOptions struct {
+	Runs        int // Timed runs
+	Warmup      int // Untimed runs first, to fill caches and plans
+	Concurrency int // Runs in flight at once
+}
+
+// BenchResult is what a benchmark measured. Latencies cover executing the query and
+// reading all of its rows.
+type BenchResult struct {
+	Query         string        `json:"query"`
+	Connection    string        `json:"connection"`
+	Started       time.Time     `json:"started"`
+	Runs          int           `json:"runs"`
+	Warmup        int           `json:"warmup,omitempty"`
+	Concurrency   int           `json:"concurrency"`
+	Rows          int64         `json:"rows"` // Rows of one run
+	Min           time.Duration `json:"min"`
+	Avg           time.Duration `json:"avg"`
+	P50           time.Duration `json:"p50"`
+	P95           time.Duration `json:"p95"`
+	Max           time.Duration `json:"max"`
+	Elapsed       time.Duration `json:"elapsed"` // Wall time of the timed runs
+	RowsPerSecond float64       `json:"rows_per_second"`
+}
+
+// RunBenchmark runs a query repeatedly and summarises its latencies. progress, when not
+// nil, is called after each timed run with the number finished. The first failing run
+// stops the benchmark.
+func RunBenchmark(conn Connection, query string, opts BenchOptions, progress func(done int)) (*BenchResult, error) {
+	if opts.Runs <= 0 {
+		return nil, errors.New("a benchmark needs at least one run")
+	}
+	opts.Concurrency = min(max(opts.Concurrency, 1), opts.Runs)
+
+	for range opts.Warmup {
+		if _, _, err := timeQuery(conn, query); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &BenchResult{Query: query, Started: time.Now(), Runs: opts.Runs, Warmup: opts.Warmup, Concurrency: opts.Concurrency}
+	latencies := make([]time.Duration, 0, opts.Runs)
+	var totalRows int64
+	var firstErr error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	runs := make(chan struct{})
+	for range opts.Concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range runs {
+				latency, rows, err := timeQuery(conn, query)
+				mu.Lock()
+				switch {
+				case err != nil && firstErr == nil:
+					firstErr = err
+				case err == nil:
+					latencies = append(latencies, latency)
+					totalRows += rows
+					result.Rows = rows
+					if progress != nil {
+						progress(len(latencies))
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	started := time.Now()
+	for range opts.Runs {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		runs <- struct{}{}
+	}
+	close(runs)
+	wg.Wait()
+	result.Elapsed = time.Since(started)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	slices.Sort(latencies)
+	var sum time.Duration
+	for _, latency := range latencies {
+		sum += latency
+	}
+	result.Min, result.Max = latencies[0], latencies[len(latencies)-1]
+	result.Avg = sum / time.Duration(len(latencies))
+	result.P50 = DurationPercentile(latencies, 0.50)
+	result.P95 = DurationPercentile(latencies, 0.95)
+	if seconds := result.Elapsed.Seconds(); seconds > 0 {
+		result.RowsPerSecond = float64(totalRows) / seconds
+	}
+	return result, nil
+}
+
+// timeQuery runs a query and reads all of its rows, returning how long that took
+func timeQuery(conn Connection, query string) (time.Duration, int64, error) {
+	start := time.Now()
+	result, err := conn.Execute(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer result.Close()
+	var rows int64
+	err = result.ForEachRow(func([]Value) error {
+		rows++
+		return nil
+	})
+	return time.Since(start), rows, err
+}
+
+// DurationPercentile returns the nearest-rank percentile p (0-1] of sorted durations
+func DurationPercentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
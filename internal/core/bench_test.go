@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE items (id INTEGER PRIMARY KEY)",
+		"INSERT INTO items VALUES (1), (2), (3)",
+	)
+
+	var done []int
+	result, err := RunBenchmark(conn, "SELECT * FROM items", BenchOptions{Runs: 8, Warmup: 2, Concurrency: 3}, func(n int) {
+		done = append(done, n)
+	})
+	if err != nil {
+		t.Fatalf("Benchmark failed: %v", err)
+	}
+	if result.Runs != 8 || result.Concurrency != 3 || result.Rows != 3 || len(done) != 8 || done[7] != 8 {
+		t.Errorf("Unexpected benchmark %+v, progress %v", result, done)
+	}
+	if result.Min > result.P50 || result.P50 > result.P95 || result.P95 > result.Max || result.Avg < result.Min || result.Avg > result.Max {
+		t.Errorf("Latencies out of order: %+v", result)
+	}
+	if result.RowsPerSecond <= 0 {
+		t.Errorf("Expected a row rate, got %v", result.RowsPerSecond)
+	}
+
+	if _, err := RunBenchmark(conn, "SELECT * FROM missing", BenchOptions{Runs: 3, Concurrency: 2}, nil); err == nil {
+		t.Error("Expected a failing query to stop the benchmark")
+	}
+}
+
+func TestDurationPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 20)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	if p := DurationPercentile(sorted, 0.95); p != 19*time.Millisecond {
+		t.Errorf("Expected p95 of 19ms, got %v", p)
+	}
+	if p := DurationPercentile(sorted[:1], 0.5); p != time.Millisecond {
+		t.Errorf("Expected the only value, got %v", p)
+	}
+}
@@ -0,0 +1,35 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// chartBlocks draw the fractional end of a bar in eighths
+var chartBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+// RenderBarChart draws one horizontal unicode bar per label, the largest value barWidth
+// columns long, followed by the value. Values at or below zero get no bar.
+func RenderBarChart(labels []string, values []float64, barWidth int) string {
+	labelWidth, peak := 0, 0.0
+	for i, label := range labels {
+		labelWidth = max(labelWidth, DisplayWidth(label))
+		peak = max(peak, values[i])
+	}
+
+	var sb strings.Builder
+	for i, label := range labels {
+		bar := ""
+		if peak > 0 && values[i] > 0 {
+			eighths := int(values[i] / peak * float64(barWidth*8))
+			bar = strings.Repeat("█", eighths/8) + chartBlocks[eighths%8]
+		}
+		fmt.Fprintf(&sb, "%s  %s %s\n", PadRight(label, labelWidth), PadRight(bar, barWidth), formatChartValue(values[i]))
+	}
+	return sb.String()
+}
+
+func formatChartValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
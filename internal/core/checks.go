@@ -0,0 +1,291 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/i18n"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChecksFile is the data quality checks file kept per connection
+const ChecksFile = "checks.yaml"
+
+// CheckSuite is a set of declarative data quality checks, read from a checks file:
+//
+//	checks:
+//	  - table: orders
+//	    not_null: [customer_id, total]
+//	    unique: [id, "customer_id, order_no"]
+//	    references: {customer_id: customers.id}
+//	    freshness: {column: created_at, max_age: 24h}
+type CheckSuite struct {
+	Checks []TableChecks `yaml:"checks"`
+}
+
+// TableChecks are the checks on one table
+type TableChecks struct {
+	Table      string            `yaml:"table"`
+	NotNull    []string          `yaml:"not_null,omitempty"`
+	Unique     []string          `yaml:"unique,omitempty"`     // A column, or comma-separated columns unique together
+	References map[string]string `yaml:"references,omitempty"` // Column to table.column it must match
+	Freshness  *FreshnessCheck   `yaml:"freshness,omitempty"`
+}
+
+// FreshnessCheck requires the newest value of a timestamp column to be recent
+type FreshnessCheck struct {
+	Column string `yaml:"column"`
+	MaxAge string `yaml:"max_age"` // A Go duration such as 90m, or days such as 2d
+}
+
+// CheckResult is the outcome of one check. Failing counts the offending rows, or
+// duplicate groups for uniqueness; freshness checks report the age in Detail.
+type CheckResult struct {
+	Table   string
+	Check   string
+	Passed  bool
+	Failing int64
+	Detail  string
+	Err     error // The check could not run
+}
+
+// LoadChecks reads a checks file. A missing file gives an empty suite.
+func LoadChecks(path string) (*CheckSuite, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &CheckSuite{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var suite CheckSuite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, err
+	}
+	for i, checks := range suite.Checks {
+		if strings.TrimSpace(checks.Table) == "" {
+			return nil, fmt.Errorf("entry %d needs a table", i+1)
+		}
+		if checks.Freshness != nil {
+			if checks.Freshness.Column == "" {
+				return nil, fmt.Errorf("freshness check on %s needs a column", checks.Table)
+			}
+			if _, err := parseMaxAge(checks.Freshness.MaxAge); err != nil {
+				return nil, fmt.Errorf("freshness check on %s: %w", checks.Table, err)
+			}
+		}
+		for column, target := range checks.References {
+			if _, _, ok := splitReference(target); !ok {
+				return nil, fmt.Errorf("reference of %s.%s must be table.column, got %q", checks.Table, column, target)
+			}
+		}
+	}
+	return &suite, nil
+}
+
+// parseMaxAge reads a duration, allowing a d suffix for days
+func parseMaxAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid max_age %q", value)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max_age %q", value)
+	}
+	return age, nil
+}
+
+// splitReference splits table.column at the last dot, so the table may be schema-qualified
+func splitReference(target string) (table, column string, ok bool) {
+	i := strings.LastIndex(target, ".")
+	if i <= 0 || i == len(target)-1 {
+		return "", "", false
+	}
+	return target[:i], target[i+1:], true
+}
+
+// RunChecks runs every check in the suite. A check that cannot run is reported with its
+// error rather than stopping the others.
+func RunChecks(conn Connection, dbType DatabaseType, suite *CheckSuite) []CheckResult {
+	var results []CheckResult
+	for _, checks := range suite.Checks {
+		table := QuoteQualifiedName(dbType, checks.Table)
+		for _, column := range checks.NotNull {
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NULL", table, QuoteIdentifier(dbType, column))
+			results = append(results, countCheck(conn, checks.Table, "not_null("+column+")", query))
+		}
+		for _, spec := range checks.Unique {
+			columns := splitColumns(spec)
+			quoted := make([]string, len(columns))
+			notNull := make([]string, len(columns))
+			for i, column := range columns {
+				quoted[i] = QuoteIdentifier(dbType, column)
+				notNull[i] = quoted[i] + " IS NOT NULL"
+			}
+			// NULLs never collide in a unique index, so they are left out here too
+			query := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT %s FROM %s WHERE %s GROUP BY %s HAVING COUNT(*) > 1) duplicates",
+				strings.Join(quoted, ", "), table, strings.Join(notNull, " AND "), strings.Join(quoted, ", "))
+			results = append(results, countCheck(conn, checks.Table, "unique("+strings.Join(columns, ", ")+")", query))
+		}
+		columns := make([]string, 0, len(checks.References))
+		for column := range checks.References {
+			columns = append(columns, column)
+		}
+		slices.Sort(columns)
+		for _, column := range columns {
+			target := checks.References[column]
+			parent, parentColumn, _ := splitReference(target)
+			child := QuoteIdentifier(dbType, column)
+			key := QuoteIdentifier(dbType, parentColumn)
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s c LEFT JOIN %s p ON c.%s = p.%s WHERE c.%s IS NOT NULL AND p.%s IS NULL",
+				table, QuoteQualifiedName(dbType, parent), child, key, child, key)
+			results = append(results, countCheck(conn, checks.Table, "references("+column+" → "+target+")", query))
+		}
+		if checks.Freshness != nil {
+			results = append(results, freshnessCheck(conn, dbType, checks.Table, checks.Freshness))
+		}
+	}
+	return results
+}
+
+func splitColumns(spec string) []string {
+	var columns []string
+	for _, column := range strings.Split(spec, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// countCheck passes when the query counts no offending rows
+func countCheck(conn Connection, table, check, query string) CheckResult {
+	result := CheckResult{Table: table, Check: check}
+	rows, err := queryStrings(conn, query)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		result.Err = fmt.Errorf("no count returned")
+		return result
+	}
+	if result.Failing, err = strconv.ParseInt(rows[0][0], 10, 64); err != nil {
+		result.Err = err
+		return result
+	}
+	result.Passed = result.Failing == 0
+	return result
+}
+
+// checkTimeLayouts are the formats MAX() of a timestamp column comes back in when the
+// driver returns text
+var checkTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-0700",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func freshnessCheck(conn Connection, dbType DatabaseType, table string, freshness *FreshnessCheck) CheckResult {
+	result := CheckResult{Table: table, Check: "freshness(" + freshness.Column + " ≤ " + freshness.MaxAge + ")"}
+	maxAge, _ := parseMaxAge(freshness.MaxAge)
+
+	query := fmt.Sprintf("SELECT MAX(%s) FROM %s", QuoteIdentifier(dbType, freshness.Column), QuoteQualifiedName(dbType, table))
+	res, err := conn.Execute(query)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer res.Close()
+
+	var newest time.Time
+	var found bool
+	err = res.ForEachRow(func(row []Value) error {
+		if len(row) == 0 || row[0].IsNull() {
+			return nil
+		}
+		if t, ok := row[0].(TimeValue); ok {
+			newest, found = t.Value, true
+			return nil
+		}
+		text := strings.TrimSpace(row[0].String())
+		for _, layout := range checkTimeLayouts {
+			// Timestamps without a zone are taken as UTC, which is how most are stored
+			if t, err := time.Parse(layout, text); err == nil {
+				newest, found = t, true
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot read %q as a timestamp", text)
+	})
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if !found {
+		result.Detail = "no rows"
+		return result
+	}
+
+	age := time.Since(newest).Round(time.Second)
+	result.Detail = fmt.Sprintf("newest %s, %s old", newest.Format("2006-01-02 15:04:05"), age)
+	result.Passed = age <= maxAge
+	return result
+}
+
+// FormatChecksReport renders check results as a markdown report with a summary line
+// and a row per check
+func FormatChecksReport(results []CheckResult, connection string, i18nMgr *i18n.Manager) string {
+	passed, failed, errored := 0, 0, 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			errored++
+		case result.Passed:
+			passed++
+		default:
+			failed++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s - %s\n\n", i18nMgr.Get("checks_report_title"), time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&sb, "**%s:** %s\n\n", i18nMgr.Get("connection_header"), connection)
+	fmt.Fprintf(&sb, "%s\n\n", i18nMgr.GetWithArgs("checks_summary", passed, failed, errored))
+	fmt.Fprintf(&sb, "| | %s | %s | %s | %s |\n|---|---|---|---|---|\n", i18nMgr.Get("checks_column_table"),
+		i18nMgr.Get("checks_column_check"), i18nMgr.Get("checks_column_failing"), i18nMgr.Get("checks_column_detail"))
+	for _, result := range results {
+		status, failing, detail := "✅", strconv.FormatInt(result.Failing, 10), result.Detail
+		switch {
+		case result.Err != nil:
+			status, failing, detail = "⚠️", "", result.Err.Error()
+		case !result.Passed:
+			status = "❌"
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", status, markdownCell(result.Table), markdownCell(result.Check),
+			failing, markdownCell(detail))
+	}
+	return sb.String()
+}
+
+// markdownCell keeps a value on one line of a markdown table
+func markdownCell(value string) string {
+	value = strings.ReplaceAll(value, "|", `\|`)
+	return strings.Join(strings.Fields(value), " ")
+}
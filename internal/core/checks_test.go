@@ -0,0 +1,110 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunChecks(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	recent := time.Now().UTC().Add(-time.Hour).Format("2006-01-02 15:04:05")
+	mustExec(t, conn,
+		"CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT)",
+		"CREATE TABLE orders (id INTEGER, customer_id INTEGER, created_at TEXT)",
+		"INSERT INTO customers VALUES (1, 'a@example.com'), (2, NULL), (3, 'a@example.com')",
+		"INSERT INTO orders VALUES (1, 1, '2020-01-01 00:00:00'), (2, 9, '"+recent+"'), (2, NULL, NULL)",
+	)
+
+	suite := &CheckSuite{Checks: []TableChecks{
+		{Table: "customers", NotNull: []string{"id", "email"}, Unique: []string{"email"}},
+		{
+			Table:      "orders",
+			Unique:     []string{"id", "id, customer_id"},
+			References: map[string]string{"customer_id": "customers.id"},
+			Freshness:  &FreshnessCheck{Column: "created_at", MaxAge: "2h"},
+		},
+		{Table: "missing", NotNull: []string{"id"}},
+	}}
+
+	want := []struct {
+		check   string
+		passed  bool
+		failing int64
+		err     bool
+	}{
+		{check: "not_null(id)", passed: true},
+		{check: "not_null(email)", failing: 1},
+		{check: "unique(email)", failing: 1},
+		{check: "unique(id)", failing: 1},
+		{check: "unique(id, customer_id)", passed: true},
+		{check: "references(customer_id → customers.id)", failing: 1},
+		{check: "freshness(created_at ≤ 2h)", passed: true},
+		{check: "not_null(id)", err: true},
+	}
+
+	results := RunChecks(conn, SQLite, suite)
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d results, got %d: %+v", len(want), len(results), results)
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.Check != w.check || (r.Err != nil) != w.err {
+			t.Errorf("Result %d: got %s (err %v), want %s", i, r.Check, r.Err, w.check)
+			continue
+		}
+		if !w.err && (r.Passed != w.passed || r.Failing != w.failing) {
+			t.Errorf("%s on %s: passed=%v failing=%d, want passed=%v failing=%d", r.Check, r.Table, r.Passed, r.Failing, w.passed, w.failing)
+		}
+	}
+
+	stale := RunChecks(conn, SQLite, &CheckSuite{Checks: []TableChecks{
+		{Table: "orders", Freshness: &FreshnessCheck{Column: "created_at", MaxAge: "1m"}},
+	}})
+	if stale[0].Passed || stale[0].Err != nil {
+		t.Errorf("Expected a stale freshness failure, got %+v", stale[0])
+	}
+}
+
+func TestLoadChecks(t *testing.T) {
+	dir := t.TempDir()
+
+	suite, err := LoadChecks(filepath.Join(dir, ChecksFile))
+	if err != nil || len(suite.Checks) != 0 {
+		t.Fatalf("Expected an empty suite for a missing file, got %+v, %v", suite, err)
+	}
+
+	testCases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "valid",
+			content: "checks:\n  - table: orders\n    not_null: [id]\n    references: {customer_id: app.customers.id}\n    freshness: {column: created_at, max_age: 2d}\n",
+		},
+		{name: "missing table", content: "checks:\n  - not_null: [id]\n", wantErr: "needs a table"},
+		{name: "bad max age", content: "checks:\n  - table: t\n    freshness: {column: c, max_age: soon}\n", wantErr: "invalid max_age"},
+		{name: "bad reference", content: "checks:\n  - table: t\n    references: {c: customers}\n", wantErr: "table.column"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(tc.name, " ", "_")+".yaml")
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadChecks(path)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
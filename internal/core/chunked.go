@@ -0,0 +1,174 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChunkSize is the number of rows each statement of a chunked UPDATE or DELETE changes
+const DefaultChunkSize = 1000
+
+// ErrNotChunkable is returned for statements RunChunked cannot split into key ranges
+var ErrNotChunkable = errors.New("only a single-table UPDATE or DELETE without LIMIT, USING or joins can run in chunks")
+
+// ChunkOptions controls how a statement is split into key ranges
+type ChunkOptions struct {
+	Key       string // A unique, indexed column the ranges are taken over
+	BatchSize int
+	Sleep     time.Duration // Pause between chunks, so replicas and other sessions keep up
+	After     string        // Key of the last row already done, as an SQL literal, see ChunkCheckpoint
+}
+
+// ChunkProgress is reported after each chunk runs
+type ChunkProgress struct {
+	Chunks  int
+	Rows    int64  // Rows the chunks run so far matched
+	LastKey string // Upper bound of the last chunk, as an SQL literal; empty after the final one
+}
+
+// ChunkCheckpoint records how far a chunked statement got, so an interrupted run can go on
+// after the last chunk it finished instead of starting over
+type ChunkCheckpoint struct {
+	Statement string    `json:"statement"`
+	Key       string    `json:"key"`
+	LastKey   string    `json:"last_key"`
+	Rows      int64     `json:"rows"`
+	Chunks    int       `json:"chunks"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Matches reports whether the checkpoint is of the same statement over the same key
+func (c *ChunkCheckpoint) Matches(statement, key string) bool {
+	return c.Statement == statement && strings.EqualFold(c.Key, key)
+}
+
+// LoadChunkCheckpoint reads a checkpoint, returning nil when there is none
+func LoadChunkCheckpoint(path string) (*ChunkCheckpoint, error) {
+	return loadCheckpoint[ChunkCheckpoint](path)
+}
+
+// SaveChunkCheckpoint writes a checkpoint, replacing the previous one in one step
+func SaveChunkCheckpoint(path string, checkpoint *ChunkCheckpoint) error {
+	return saveCheckpoint(path, checkpoint)
+}
+
+// chunkRange returns the condition selecting keys after the last chunk, up to and
+// including upper. Either bound may be empty.
+func chunkRange(dbType DatabaseType, key, after, upper string) string {
+	quoted := QuoteIdentifier(dbType, key)
+	var conditions []string
+	if after != "" {
+		conditions = append(conditions, quoted+" > "+after)
+	}
+	if upper != "" {
+		conditions = append(conditions, quoted+" <= "+upper)
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// chunkUpperBound returns the key of the last row of the next chunk, the batch-th row the
+// statement matches after the previous chunk. found is false when fewer rows are left.
+func chunkUpperBound(conn Connection, m Modification, opts ChunkOptions, after string) (upper string, found bool, err error) {
+	dbType := quotingDialect(conn)
+	quoted := QuoteIdentifier(dbType, opts.Key)
+	query := m.restrict(chunkRange(dbType, opts.Key, after, "")).Select(quoted) +
+		fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET %d", quoted, opts.BatchSize-1)
+
+	result, err := conn.Execute(query)
+	if err != nil {
+		return "", false, err
+	}
+	defer result.Close()
+	// The bound is compared with the column, so it keeps the driver's value
+	result.SetFormatters()
+	err = result.ForEachRow(func(row []Value) error {
+		if !found && len(row) > 0 {
+			upper, found = SQLLiteral(dbType, row[0], result.Columns[0]), true
+		}
+		return nil
+	})
+	return upper, found, err
+}
+
+// CountChunkedRows returns how many rows a chunked statement has left to change
+func CountChunkedRows(conn Connection, statement string, opts ChunkOptions) (int64, error) {
+	m, ok := ParseModification(statement)
+	if !ok {
+		return 0, ErrNotChunkable
+	}
+	return countChunkRows(conn, m, chunkRange(quotingDialect(conn), opts.Key, opts.After, ""))
+}
+
+func countChunkRows(conn Connection, m Modification, condition string) (int64, error) {
+	rows, err := queryStrings(conn, m.restrict(condition).Select("COUNT(*)"))
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return 0, fmt.Errorf("no row count returned")
+	}
+	return strconv.ParseInt(rows[0][0], 10, 64)
+}
+
+// RunChunked runs an UPDATE or DELETE as a series of statements, each over the next range
+// of at most BatchSize matching rows in key order, so no single statement holds its locks
+// for long. Progress is reported after every chunk; its LastKey is where an interrupted
+// run can resume.
+func RunChunked(conn Connection, statement string, opts ChunkOptions, progress func(ChunkProgress) error) (int64, error) {
+	m, ok := ParseModification(statement)
+	if !ok {
+		return 0, ErrNotChunkable
+	}
+	if opts.Key == "" {
+		return 0, errors.New("a key column is needed to split the statement into chunks")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultChunkSize
+	}
+	dbType := quotingDialect(conn)
+
+	var done ChunkProgress
+	after := opts.After
+	for {
+		upper, found, err := chunkUpperBound(conn, m, opts, after)
+		if err != nil {
+			return done.Rows, err
+		}
+		condition := chunkRange(dbType, opts.Key, after, upper)
+		rows := int64(opts.BatchSize)
+		if !found {
+			if rows, err = countChunkRows(conn, m, condition); err != nil {
+				return done.Rows, err
+			}
+			if rows == 0 {
+				return done.Rows, nil
+			}
+		}
+
+		chunk := statement
+		if condition != "" {
+			chunk, _ = RestrictModification(statement, condition)
+		}
+		if err := execStatement(conn, chunk); err != nil {
+			return done.Rows, err
+		}
+		done.Chunks++
+		done.Rows += rows
+		done.LastKey = upper
+		if progress != nil {
+			if err := progress(done); err != nil {
+				return done.Rows, err
+			}
+		}
+		if !found {
+			return done.Rows, nil
+		}
+		after = upper
+		if opts.Sleep > 0 {
+			time.Sleep(opts.Sleep)
+		}
+	}
+}
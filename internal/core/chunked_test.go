@@ -0,0 +1,63 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunChunked(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, created_at TEXT, done BOOLEAN DEFAULT 0)",
+		"INSERT INTO events (id, created_at) VALUES (1, '2022-01-01'), (2, '2024-01-01'), (3, '2022-02-01'), (5, '2022-03-01'), (8, '2022-04-01'), (9, '2022-05-01'), (10, '2025-01-01')",
+	)
+
+	statement := "UPDATE events SET done = 1 WHERE created_at < '2023-01-01'"
+	opts := ChunkOptions{Key: "id", BatchSize: 2}
+	if total, err := CountChunkedRows(conn, statement, opts); err != nil || total != 5 {
+		t.Fatalf("Expected 5 rows to change, got %d, %v", total, err)
+	}
+	var reported []ChunkProgress
+	changed, err := RunChunked(conn, statement, opts, func(p ChunkProgress) error {
+		reported = append(reported, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Chunked update failed: %v", err)
+	}
+	if changed != 5 || len(reported) != 3 || reported[0].LastKey != "3" || reported[1].LastKey != "8" || reported[2].LastKey != "" {
+		t.Fatalf("Unexpected run of %d rows, progress %+v", changed, reported)
+	}
+	if rows, _ := queryStrings(conn, "SELECT COUNT(*) FROM events WHERE done"); rows[0][0] != "5" {
+		t.Errorf("Expected 5 updated rows, got %s", rows[0][0])
+	}
+
+	// Resuming after a chunk only changes the rows past it
+	opts.After = reported[0].LastKey
+	if changed, err := RunChunked(conn, "DELETE FROM events WHERE done", opts, nil); err != nil || changed != 3 {
+		t.Fatalf("Expected the resumed delete to remove 3 rows, got %d, %v", changed, err)
+	}
+	if rows, _ := queryStrings(conn, "SELECT id FROM events ORDER BY id"); len(rows) != 4 || rows[1][0] != "2" || rows[2][0] != "3" {
+		t.Errorf("Unexpected remaining rows %q", rows)
+	}
+
+	if _, err := RunChunked(conn, "DELETE FROM events LIMIT 10", opts, nil); err != ErrNotChunkable {
+		t.Errorf("Expected ErrNotChunkable, got %v", err)
+	}
+}
+
+func TestChunkCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chunked", "events.json")
+	saved := &ChunkCheckpoint{Statement: "DELETE FROM events", Key: "id", LastKey: "10000", Rows: 10000, Chunks: 1, Updated: time.Now()}
+	if err := SaveChunkCheckpoint(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadChunkCheckpoint(path)
+	if err != nil || loaded == nil || loaded.LastKey != "10000" || loaded.Chunks != 1 {
+		t.Fatalf("Unexpected checkpoint %+v, %v", loaded, err)
+	}
+	if !loaded.Matches("DELETE FROM events", "ID") || loaded.Matches("DELETE FROM events WHERE id > 5", "id") {
+		t.Error("Expected the checkpoint to match only the same statement")
+	}
+}
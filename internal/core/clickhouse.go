@@ -0,0 +1,269 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ClickHouseMaxResultRows caps the rows a displayed query sends back. ClickHouse tables
+// are often billions of rows, so a SELECT without LIMIT is cut off here instead of
+// streaming them all into the terminal.
+const ClickHouseMaxResultRows = 100000
+
+// clickhouseRowCapKey carries the row cap of one query in its context
+type clickhouseRowCapKey struct{}
+
+// clickhouseFormat prints column names and types ahead of the rows, so columns are
+// known even for empty results
+const clickhouseFormat = "JSONCompactEachRowWithNamesAndTypes"
+
+func init() {
+	sql.Register("clickhouse", &clickhouseDriver{})
+}
+
+// clickhouseDSN is the HTTP interface URL for a connection. Credentials are part of the
+// URL and sent as headers, never as query parameters.
+func clickhouseDSN(config *ConnectionConfig, password string) string {
+	scheme := "http"
+	if config.SSL {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword(config.Username, password),
+		Host:   net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		Path:   "/",
+	}
+	query := url.Values{}
+	if config.Database != "" {
+		query.Set("database", config.Database)
+	}
+	if config.ReadOnly {
+		// 2 still lets the driver's own settings through, unlike 1
+		query.Set("readonly", "2")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// clickhouseDriver talks to the ClickHouse HTTP interface, which needs no client
+// library and is open on every server
+type clickhouseDriver struct{}
+
+func (d *clickhouseDriver) Open(name string) (driver.Conn, error) {
+	u, err := url.Parse(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ClickHouse address: %w", err)
+	}
+	user := u.User
+	u.User = nil
+
+	params := u.Query()
+	params.Set("default_format", clickhouseFormat)
+	params.Set("output_format_json_quote_64bit_integers", "0")
+	u.RawQuery = params.Encode()
+
+	conn := &clickhouseConn{endpoint: u.String(), client: http.DefaultClient}
+	if user != nil {
+		conn.user = user.Username()
+		conn.password, _ = user.Password()
+	}
+	return conn, nil
+}
+
+type clickhouseConn struct {
+	endpoint string
+	user     string
+	password string
+	client   *http.Client
+}
+
+// post sends a statement and returns the response body, which the caller closes
+func (c *clickhouseConn) post(ctx context.Context, query string) (io.ReadCloser, error) {
+	endpoint := c.endpoint
+	if rows, ok := ctx.Value(clickhouseRowCapKey{}).(int); ok {
+		endpoint += "&" + url.Values{"max_result_rows": {strconv.Itoa(rows)}, "result_overflow_mode": {"break"}}.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	if c.user != "" {
+		req.Header.Set("X-ClickHouse-User", c.user)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if msg := strings.TrimSpace(string(body)); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, fmt.Errorf("ClickHouse returned %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+func (c *clickhouseConn) Prepare(query string) (driver.Stmt, error) {
+	return &clickhouseStmt{conn: c, query: query}, nil
+}
+
+func (c *clickhouseConn) Close() error {
+	return nil
+}
+
+func (c *clickhouseConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("ClickHouse does not support transactions")
+}
+
+// Ping runs a query rather than calling /ping, which answers without checking credentials
+func (c *clickhouseConn) Ping(ctx context.Context) error {
+	body, err := c.post(ctx, "SELECT 1")
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+type clickhouseStmt struct {
+	conn  *clickhouseConn
+	query string
+}
+
+func (s *clickhouseStmt) Close() error {
+	return nil
+}
+
+// NumInput is zero because statements are sent as plain text
+func (s *clickhouseStmt) NumInput() int {
+	return 0
+}
+
+func (s *clickhouseStmt) Exec(args []driver.Value) (driver.Result, error) {
+	body, err := s.conn.post(context.Background(), s.query)
+	if err != nil {
+		return nil, err
+	}
+	body.Close()
+	return driver.RowsAffected(0), nil
+}
+
+func (s *clickhouseStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), nil)
+}
+
+// QueryContext lets cancellation and the row cap of CapDisplayRows reach the request
+func (s *clickhouseStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	body, err := s.conn.post(ctx, strings.TrimSuffix(strings.TrimSpace(s.query), ";"))
+	if err != nil {
+		return nil, err
+	}
+	return newClickHouseRows(body)
+}
+
+// CapDisplayRows returns a connection whose queries stop after ClickHouseMaxResultRows
+// rows on ClickHouse, for results that are only displayed. Exports and copies run on
+// conn itself and get every row.
+func CapDisplayRows(conn Connection) Connection {
+	c, ok := conn.(*connection)
+	if !ok || c.config.DatabaseType != ClickHouse {
+		return conn
+	}
+	return cappedConnection{c}
+}
+
+type cappedConnection struct {
+	*connection
+}
+
+// Execute bypasses the statement cache, which has nothing to prepare on ClickHouse
+func (c cappedConnection) Execute(query string) (*QueryResult, error) {
+	if c.config.ReadOnly && !IsReadOnlyQuery(query) {
+		return nil, ErrReadOnlyConnection
+	}
+	ctx := context.WithValue(context.Background(), clickhouseRowCapKey{}, ClickHouseMaxResultRows)
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, ClassifyError(fmt.Errorf("failed to execute query: %w", err), c.config.DatabaseType)
+	}
+	return NewQueryResult(rows)
+}
+
+// clickhouseRows streams rows in the default format: a JSON array of column names, one
+// of types, then one array per row. Statements without a result send nothing.
+type clickhouseRows struct {
+	body    io.ReadCloser
+	dec     *json.Decoder
+	columns []string
+	types   []string
+}
+
+func newClickHouseRows(body io.ReadCloser) (*clickhouseRows, error) {
+	dec := json.NewDecoder(body)
+	dec.UseNumber()
+	rows := &clickhouseRows{body: body, dec: dec}
+
+	if err := dec.Decode(&rows.columns); err != nil {
+		if err == io.EOF {
+			return rows, nil
+		}
+		body.Close()
+		return nil, fmt.Errorf("failed to read ClickHouse columns: %w", err)
+	}
+	if err := dec.Decode(&rows.types); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to read ClickHouse column types: %w", err)
+	}
+	return rows, nil
+}
+
+func (r *clickhouseRows) Columns() []string {
+	return r.columns
+}
+
+func (r *clickhouseRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index]
+}
+
+func (r *clickhouseRows) Close() error {
+	return r.body.Close()
+}
+
+func (r *clickhouseRows) Next(dest []driver.Value) error {
+	if len(r.columns) == 0 {
+		return io.EOF
+	}
+	var values []any
+	if err := r.dec.Decode(&values); err != nil {
+		if err == io.EOF {
+			return io.EOF
+		}
+		// Errors after the first rows are appended to the body as text
+		rest, _ := io.ReadAll(io.MultiReader(r.dec.Buffered(), io.LimitReader(r.body, 64*1024)))
+		if msg := strings.TrimSpace(string(rest)); strings.Contains(msg, "Exception") {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("failed to read ClickHouse row: %w", err)
+	}
+	if len(values) != len(dest) {
+		return fmt.Errorf("ClickHouse row has %d values, expected %d", len(values), len(dest))
+	}
+	for i, value := range values {
+		dest[i] = jsonDriverValue(value)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// clickhouseServer answers the HTTP interface with a canned body, or with an error status
+// for queries containing "missing_table"
+func clickhouseServer(t *testing.T, body string) (*ConnectionConfig, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(query)))
+		requests = append(requests, r)
+		if strings.Contains(string(query), "missing_table") {
+			http.Error(w, "Code: 60. DB::Exception: Table default.missing_table does not exist.", http.StatusNotFound)
+			return
+		}
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	portNumber, _ := strconv.Atoi(port)
+	return &ConnectionConfig{
+		DatabaseType: ClickHouse, Host: host, Port: portNumber,
+		Username: "analyst", Password: "s3cret", Database: "events", StatementCacheSize: -1,
+	}, &requests
+}
+
+func TestClickHouseConnection_Execute(t *testing.T) {
+	config, requests := clickhouseServer(t, `["day","hits","ratio"]
+["Date","UInt64","Nullable(Float64)"]
+["2024-03-01",18446744073709551615,0.5]
+["2024-03-02",42,null]
+`)
+	conn, err := NewConnection(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Execute("SELECT day, count() AS hits, avg(x) AS ratio FROM visits GROUP BY day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	if len(result.Columns) != 3 || result.Columns[1].Type != "UInt64" {
+		t.Errorf("Expected columns with ClickHouse types, got %+v", result.Columns)
+	}
+	var rows [][]Value
+	if err := result.ForEachRow(func(row []Value) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0][1].String() != "18446744073709551615" || rows[1][1].String() != "42" || !rows[1][2].IsNull() {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	req := (*requests)[len(*requests)-1]
+	if req.Header.Get("X-ClickHouse-User") != "analyst" || req.Header.Get("X-ClickHouse-Key") != "s3cret" {
+		t.Error("Expected credentials in the ClickHouse headers")
+	}
+	params := req.URL.Query()
+	if params.Get("database") != "events" || params.Get("default_format") != clickhouseFormat || params.Has("max_result_rows") {
+		t.Errorf("Unexpected query parameters %v", params)
+	}
+	if strings.Contains(req.URL.String(), "s3cret") {
+		t.Error("The password must not appear in the URL")
+	}
+
+	// Only results that are displayed stop at the cap; exports need every row
+	capped, err := CapDisplayRows(conn).Execute("SELECT * FROM visits")
+	if err != nil {
+		t.Fatal(err)
+	}
+	capped.Close()
+	params = (*requests)[len(*requests)-1].URL.Query()
+	if params.Get("max_result_rows") != strconv.Itoa(ClickHouseMaxResultRows) || params.Get("result_overflow_mode") != "break" ||
+		params.Get("database") != "events" {
+		t.Errorf("Unexpected query parameters for a displayed query %v", params)
+	}
+}
+
+func TestClickHouseConnection_Errors(t *testing.T) {
+	config, _ := clickhouseServer(t, "")
+	conn, err := NewConnection(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Execute("SELECT * FROM missing_table"); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("Expected the server's exception, got %v", err)
+	}
+
+	// Statements without a result, such as CREATE TABLE, send an empty body
+	result, err := conn.Execute("CREATE TABLE t (x UInt8) ENGINE = Memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	if len(result.Columns) != 0 {
+		t.Errorf("Expected no columns, got %+v", result.Columns)
+	}
+}
+
+func TestClickHouseDSN_ReadOnly(t *testing.T) {
+	config := &ConnectionConfig{DatabaseType: ClickHouse, Host: "ch.internal", Port: 8443, Username: "u", SSL: true, ReadOnly: true}
+	dsn := clickhouseDSN(config, "p")
+	if !strings.HasPrefix(dsn, "https://u:p@ch.internal:8443/") || !strings.Contains(dsn, "readonly=2") {
+		t.Errorf("Unexpected DSN %q", dsn)
+	}
+}
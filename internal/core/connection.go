@@ -2,10 +2,16 @@ package core
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
-
-	_ "github.com/go-sql-driver/mysql"
-	_ "github.com/lib/pq"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -20,39 +26,142 @@ type Connection interface {
 type connection struct {
 	db     *sql.DB
 	config *ConnectionConfig
+	stmts  *stmtCache       // Nil when statement caching is disabled
+	sqlite *sqliteConnector // Nil for other database types
+
+	replicas    []*connection // Read replicas, see RouteQuery
+	nextReplica atomic.Uint32
+	pinned      atomic.Bool // Set by PinSession while temporary tables are in use
+
+	flavorOnce sync.Once
+	flavor     string // See ServerFlavor, set on first use
 }
 
 func NewConnection(config *ConnectionConfig) (Connection, error) {
-	var dsn string
-	var driverName string
-
-	switch config.DatabaseType {
-	case MySQL:
-		driverName = "mysql"
-		dsn = fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
-			config.Username, config.Password, config.Host, config.Port, config.Database)
-	case PostgreSQL:
-		driverName = "postgres"
-		dsn = fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-			config.Host, config.Port, config.Username, config.Password, config.Database)
-	case SQLite:
-		driverName = "sqlite3"
-		dsn = config.Database
-	default:
-		return nil, fmt.Errorf("unsupported database type: %v", config.DatabaseType)
+	if config.Auth != nil {
+		if err := validateAuth(config); err != nil {
+			return nil, err
+		}
 	}
 
-	db, err := sql.Open(driverName, dsn)
+	db, sqliteConn, err := openDB(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+		return nil, err
 	}
 
+	conn := newConnection(db, config)
+	conn.sqlite = sqliteConn
+	if len(config.Replicas) > 0 {
+		if err := conn.openReplicas(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func newConnection(db *sql.DB, config *ConnectionConfig) *connection {
 	conn := &connection{
 		db:     db,
 		config: config,
 	}
+	switch {
+	case config.StatementCacheSize > 0:
+		conn.stmts = newStmtCache(config.StatementCacheSize)
+	case config.StatementCacheSize == 0:
+		conn.stmts = newStmtCache(defaultStatementCacheSize)
+	}
+	return conn
+}
 
-	return conn, nil
+// openDB opens the connection pool for a configuration. SQLite pools come with their
+// connector, which /attach adds to.
+func openDB(config *ConnectionConfig) (*sql.DB, *sqliteConnector, error) {
+	if config.Auth != nil && config.Auth.Method == AuthIAM {
+		var drv driver.Driver
+		switch config.DatabaseType {
+		case MySQL:
+			drv = &mysql.MySQLDriver{}
+		case PostgreSQL:
+			drv = &pq.Driver{}
+		}
+		connector, err := newTokenConnector(config, drv)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sql.OpenDB(connector), nil, nil
+	}
+
+	driverName, dsn, err := dataSource(config, config.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if config.DatabaseType == SQLite {
+		connector := newSQLiteConnector(config, dsn)
+		return sql.OpenDB(connector), connector, nil
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return db, nil, nil
+}
+
+// dataSource returns the driver and DSN for a connection, with the password passed
+// separately so IAM tokens can replace it on each connect
+func dataSource(config *ConnectionConfig, password string) (string, string, error) {
+	switch config.DatabaseType {
+	case MySQL:
+		cfg := mysql.NewConfig()
+		cfg.User = config.Username
+		cfg.Passwd = password
+		cfg.Net = "tcp"
+		cfg.Addr = net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+		cfg.DBName = config.Database
+		cfg.ParseTime = true
+		if config.Auth != nil && config.Auth.Method == AuthIAM {
+			// IAM tokens are sent with the cleartext plugin, so only over TLS to a
+			// server whose certificate checks out
+			name, err := mysqlVerifiedTLS(config)
+			if err != nil {
+				return "", "", err
+			}
+			cfg.TLSConfig = name
+			cfg.AllowCleartextPasswords = true
+		}
+		return "mysql", cfg.FormatDSN(), nil
+	case PostgreSQL:
+		sslMode, authParams := postgresAuthParams(config.Auth)
+		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+			config.Host, config.Port, config.Username, quotePQValue(password), config.Database, sslMode)
+		for _, param := range authParams {
+			dsn += " " + param
+		}
+		if config.ReadOnly {
+			// Let the server reject writes as well
+			dsn += " default_transaction_read_only=on"
+		}
+		return "postgres", dsn, nil
+	case SQLite:
+		if config.ReadOnly {
+			return "sqlite3", fmt.Sprintf("file:%s?mode=ro", config.Database), nil
+		}
+		return "sqlite3", config.Database, nil
+	case DuckDB:
+		return "duckdb", duckdbDSN(config), nil
+	case ClickHouse:
+		return "clickhouse", clickhouseDSN(config, password), nil
+	default:
+		return "", "", fmt.Errorf("unsupported database type: %v", config.DatabaseType)
+	}
+}
+
+// quotePQValue quotes a connection string value for lib/pq, which passwords and tokens
+// with spaces or quotes need
+func quotePQValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
 }
 
 func (c *connection) Ping() error {
@@ -60,27 +169,80 @@ func (c *connection) Ping() error {
 }
 
 func (c *connection) Execute(query string) (*QueryResult, error) {
-	rows, err := c.db.Query(query)
+	if c.config.ReadOnly && !IsReadOnlyQuery(query) {
+		return nil, ErrReadOnlyConnection
+	}
+	if changesOnly(query) {
+		return c.exec(query)
+	}
+
+	var rows *sql.Rows
+	var err error
+	if c.stmts != nil {
+		rows, err = c.stmts.query(c.db, query)
+	} else {
+		rows, err = c.db.Query(query)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, ClassifyError(fmt.Errorf("failed to execute query: %w", err), c.config.DatabaseType)
 	}
 
 	return NewQueryResult(rows)
 }
 
+// exec runs a change that returns no rows, so the driver reports how many it affected
+func (c *connection) exec(query string) (*QueryResult, error) {
+	var res sql.Result
+	var err error
+	if c.stmts != nil {
+		res, err = c.stmts.exec(c.db, query)
+	} else {
+		res, err = c.db.Exec(query)
+	}
+	if err != nil {
+		return nil, ClassifyError(fmt.Errorf("failed to execute query: %w", err), c.config.DatabaseType)
+	}
+	return newExecResult(res), nil
+}
+
 func (c *connection) ListTables() ([]string, error) {
 	var query string
 	switch c.config.DatabaseType {
 	case MySQL:
-		query = "SHOW TABLES"
+		// MariaDB and TiDB list sequences as tables
+		query = "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE <> 'SEQUENCE' ORDER BY TABLE_NAME"
 	case PostgreSQL:
 		query = "SELECT tablename FROM pg_tables WHERE schemaname = 'public'"
 	case SQLite:
 		query = "SELECT name FROM sqlite_master WHERE type='table'"
+	case DuckDB:
+		// Views included, so data files opened directly are listed
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = 'main' ORDER BY table_name"
+	case ClickHouse:
+		query = "SELECT name FROM system.tables WHERE database = currentDatabase() ORDER BY name"
 	default:
 		return nil, fmt.Errorf("unsupported database type: %v", c.config.DatabaseType)
 	}
 
+	tables, err := c.listTables(query, "")
+	if err != nil {
+		return nil, err
+	}
+	if c.sqlite != nil {
+		// Tables of attached databases follow, qualified with their schema
+		for _, schema := range c.sqlite.schemas() {
+			attached, err := c.listTables(fmt.Sprintf("SELECT name FROM %s.sqlite_master WHERE type='table' ORDER BY name",
+				QuoteIdentifier(SQLite, schema)), schema+".")
+			if err != nil {
+				return nil, err
+			}
+			tables = append(tables, attached...)
+		}
+	}
+	return tables, nil
+}
+
+func (c *connection) listTables(query, prefix string) ([]string, error) {
 	rows, err := c.db.Query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tables: %w", err)
@@ -93,30 +255,60 @@ func (c *connection) ListTables() ([]string, error) {
 		if err := rows.Scan(&tableName); err != nil {
 			return nil, fmt.Errorf("failed to scan table name: %w", err)
 		}
-		tables = append(tables, tableName)
+		tables = append(tables, prefix+tableName)
 	}
 
 	return tables, nil
 }
 
+// sqliteTableInfoQuery is PRAGMA table_info as a table-valued function, which unlike the
+// pragma statement takes the table name as a parameter
+const sqliteTableInfoQuery = `SELECT cid, name, type, "notnull", dflt_value, pk FROM pragma_table_info(?, ?)`
+
+// DescribeTable binds the table name as a parameter wherever the dialect allows, since
+// names can come from AI output; DESCRIBE and the DuckDB shell cannot take one, so the
+// name is quoted there
 func (c *connection) DescribeTable(tableName string) (*TableInfo, error) {
+	if err := ValidateIdentifier(tableName); err != nil {
+		return nil, err
+	}
+
 	var query string
+	var args []any
 	switch c.config.DatabaseType {
 	case MySQL:
-		query = fmt.Sprintf("DESCRIBE %s", tableName)
+		query = "DESCRIBE " + QuoteIdentifier(MySQL, tableName)
 	case PostgreSQL:
-		query = fmt.Sprintf(`
+		query = `
 			SELECT column_name, data_type, is_nullable, column_default, ''
 			FROM information_schema.columns
-			WHERE table_name = '%s'
-			ORDER BY ordinal_position`, tableName)
+			WHERE table_name = $1
+			ORDER BY ordinal_position`
+		args = []any{tableName}
 	case SQLite:
-		query = fmt.Sprintf("PRAGMA table_info(%s)", tableName)
+		table, schema := c.sqliteTableName(tableName)
+		query = sqliteTableInfoQuery
+		args = []any{table, schema}
+	case DuckDB:
+		query = `
+			SELECT column_name, data_type, is_nullable, column_default, '' AS extra
+			FROM information_schema.columns
+			WHERE table_schema = 'main' AND table_name = ` + QuoteLiteral(DuckDB, tableName) + `
+			ORDER BY ordinal_position`
+	case ClickHouse:
+		// Shaped like MySQL's DESCRIBE; the sorting key stands in for a primary key
+		query = `
+			SELECT name, type, if(startsWith(type, 'Nullable('), 'YES', 'NO') AS nullable,
+			       if(is_in_primary_key, 'PRI', '') AS key, nullIf(default_expression, '') AS default,
+			       default_kind AS extra
+			FROM system.columns
+			WHERE database = currentDatabase() AND table = ` + QuoteLiteral(ClickHouse, tableName) + `
+			ORDER BY position`
 	default:
 		return nil, fmt.Errorf("unsupported database type: %v", c.config.DatabaseType)
 	}
 
-	rows, err := c.db.Query(query)
+	rows, err := c.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to describe table: %w", err)
 	}
@@ -133,20 +325,25 @@ func (c *connection) DescribeTable(tableName string) (*TableInfo, error) {
 		var defaultVal interface{}
 
 		switch c.config.DatabaseType {
-		case MySQL:
+		case MySQL, ClickHouse:
 			var key, extra string
 			err = rows.Scan(&column.Name, &column.Type, &nullable, &key, &defaultVal, &extra)
 			column.Key = key
 			column.Extra = extra
-		case PostgreSQL:
+		case PostgreSQL, DuckDB:
 			var extra string
 			err = rows.Scan(&column.Name, &column.Type, &nullable, &defaultVal, &extra)
 			column.Extra = extra
 		case SQLite:
 			var cid int
 			var pk int
-			err = rows.Scan(&cid, &column.Name, &column.Type, &nullable, &defaultVal, &pk)
-			if pk == 1 {
+			var notNull int
+			err = rows.Scan(&cid, &column.Name, &column.Type, &notNull, &defaultVal, &pk)
+			if notNull == 0 {
+				nullable = "YES"
+			}
+			// pk is the column's position in the primary key
+			if pk > 0 {
 				column.Key = "PRI"
 			}
 		}
@@ -198,29 +395,48 @@ func (c *connection) DescribeTable(tableName string) (*TableInfo, error) {
 
 func (c *connection) getPrimaryKeys(tableName string) ([]string, error) {
 	var query string
+	args := []any{tableName}
 	switch c.config.DatabaseType {
 	case MySQL:
-		query = fmt.Sprintf(`
+		query = `
 			SELECT COLUMN_NAME 
 			FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE 
 			WHERE TABLE_SCHEMA = DATABASE() 
-			AND TABLE_NAME = '%s' 
+			AND TABLE_NAME = ? 
 			AND CONSTRAINT_NAME = 'PRIMARY'
-			ORDER BY ORDINAL_POSITION`, tableName)
+			ORDER BY ORDINAL_POSITION`
 	case PostgreSQL:
-		query = fmt.Sprintf(`
+		query = `
 			SELECT a.attname
 			FROM pg_index i
 			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
-			WHERE i.indrelid = '%s'::regclass AND i.indisprimary
-			ORDER BY a.attnum`, tableName)
+			WHERE i.indrelid = $1::regclass AND i.indisprimary
+			ORDER BY a.attnum`
+		// regclass parses its input as SQL, so the name keeps its case only when quoted
+		args = []any{QuoteIdentifier(PostgreSQL, tableName)}
 	case SQLite:
-		query = fmt.Sprintf("PRAGMA table_info(%s)", tableName)
+		table, schema := c.sqliteTableName(tableName)
+		query = sqliteTableInfoQuery
+		args = []any{table, schema}
+	case DuckDB:
+		query = `
+			SELECT unnest(constraint_column_names) AS column_name
+			FROM duckdb_constraints()
+			WHERE schema_name = 'main' AND table_name = ` + QuoteLiteral(DuckDB, tableName) + `
+			AND constraint_type = 'PRIMARY KEY'`
+		args = nil
+	case ClickHouse:
+		query = `
+			SELECT name FROM system.columns
+			WHERE database = currentDatabase() AND table = ` + QuoteLiteral(ClickHouse, tableName) + `
+			AND is_in_primary_key
+			ORDER BY position`
+		args = nil
 	default:
 		return nil, fmt.Errorf("unsupported database type: %v", c.config.DatabaseType)
 	}
 
-	rows, err := c.db.Query(query)
+	rows, err := c.db.Query(query, args...)
 	if err != nil {
 		return []string{}, nil // Return empty slice if query fails
 	}
@@ -238,7 +454,7 @@ func (c *connection) getPrimaryKeys(tableName string) ([]string, error) {
 			if err != nil {
 				continue
 			}
-			if pk == 1 {
+			if pk > 0 {
 				primaryKeys = append(primaryKeys, name)
 			}
 		} else {
@@ -254,32 +470,49 @@ func (c *connection) getPrimaryKeys(tableName string) ([]string, error) {
 	return primaryKeys, nil
 }
 
+// tidbConstraintsQuery leaves out CHECK constraints, which TiDB ignores by default and
+// before 7.2 has no CHECK_CONSTRAINTS table for
+const tidbConstraintsQuery = `
+	SELECT tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE, kcu.COLUMN_NAME, NULL
+	FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+	JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+		ON tc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND tc.TABLE_NAME = kcu.TABLE_NAME
+		AND tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+	WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.TABLE_NAME = ? AND tc.CONSTRAINT_TYPE = 'UNIQUE'`
+
 func (c *connection) getConstraints(tableName string) ([]ConstraintInfo, error) {
 	var query string
 	switch c.config.DatabaseType {
 	case MySQL:
-		query = fmt.Sprintf(`
-			SELECT CONSTRAINT_NAME, CONSTRAINT_TYPE, COLUMN_NAME, CHECK_CLAUSE
+		// CHECK constraints have no key columns
+		query = `
+			SELECT tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE, COALESCE(kcu.COLUMN_NAME, ''), cc.CHECK_CLAUSE
 			FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
-			LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu ON tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
-			LEFT JOIN INFORMATION_SCHEMA.CHECK_CONSTRAINTS cc ON tc.CONSTRAINT_NAME = cc.CONSTRAINT_NAME
-			WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.TABLE_NAME = '%s'
-			AND tc.CONSTRAINT_TYPE IN ('UNIQUE', 'CHECK')`, tableName)
+			LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+				ON tc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND tc.TABLE_NAME = kcu.TABLE_NAME
+				AND tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+			LEFT JOIN INFORMATION_SCHEMA.CHECK_CONSTRAINTS cc
+				ON tc.CONSTRAINT_SCHEMA = cc.CONSTRAINT_SCHEMA AND tc.CONSTRAINT_NAME = cc.CONSTRAINT_NAME
+			WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.TABLE_NAME = ?
+			AND tc.CONSTRAINT_TYPE IN ('UNIQUE', 'CHECK')`
+		if c.serverFlavor() == FlavorTiDB {
+			query = tidbConstraintsQuery
+		}
 	case PostgreSQL:
-		query = fmt.Sprintf(`
+		query = `
 			SELECT tc.constraint_name, tc.constraint_type, kcu.column_name, cc.check_clause
 			FROM information_schema.table_constraints tc
 			LEFT JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name
 			LEFT JOIN information_schema.check_constraints cc ON tc.constraint_name = cc.constraint_name
-			WHERE tc.table_name = '%s'
-			AND tc.constraint_type IN ('UNIQUE', 'CHECK')`, tableName)
-	case SQLite:
-		return []ConstraintInfo{}, nil // SQLite constraint info is limited
+			WHERE tc.table_name = $1
+			AND tc.constraint_type IN ('UNIQUE', 'CHECK')`
+	case SQLite, DuckDB, ClickHouse:
+		return []ConstraintInfo{}, nil // Constraint info is limited in these databases
 	default:
 		return nil, fmt.Errorf("unsupported database type: %v", c.config.DatabaseType)
 	}
 
-	rows, err := c.db.Query(query)
+	rows, err := c.db.Query(query, tableName)
 	if err != nil {
 		return []ConstraintInfo{}, nil // Return empty slice if query fails
 	}
@@ -304,31 +537,38 @@ func (c *connection) getConstraints(tableName string) ([]ConstraintInfo, error)
 
 func (c *connection) getForeignKeys(tableName string) ([]ForeignKeyInfo, error) {
 	var query string
+	args := []any{tableName}
 	switch c.config.DatabaseType {
 	case MySQL:
-		query = fmt.Sprintf(`
+		query = `
 			SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME,
 			       DELETE_RULE, UPDATE_RULE
 			FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
 			JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc ON kcu.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
-			WHERE kcu.TABLE_SCHEMA = DATABASE() AND kcu.TABLE_NAME = '%s'
-			AND kcu.REFERENCED_TABLE_NAME IS NOT NULL`, tableName)
+			WHERE kcu.TABLE_SCHEMA = DATABASE() AND kcu.TABLE_NAME = ?
+			AND kcu.REFERENCED_TABLE_NAME IS NOT NULL`
 	case PostgreSQL:
-		query = fmt.Sprintf(`
+		query = `
 			SELECT tc.constraint_name, kcu.column_name, ccu.table_name, ccu.column_name,
 			       rc.delete_rule, rc.update_rule
 			FROM information_schema.table_constraints tc
 			JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name
 			JOIN information_schema.constraint_column_usage ccu ON ccu.constraint_name = tc.constraint_name
 			JOIN information_schema.referential_constraints rc ON tc.constraint_name = rc.constraint_name
-			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_name = '%s'`, tableName)
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_name = $1`
 	case SQLite:
-		query = fmt.Sprintf("PRAGMA foreign_key_list(%s)", tableName)
+		table, schema := c.sqliteTableName(tableName)
+		query = `SELECT id, seq, "table", "from", "to", on_update, on_delete, match FROM pragma_foreign_key_list(?, ?)`
+		args = []any{table, schema}
+	case DuckDB:
+		return []ForeignKeyInfo{}, nil // Not reported by every DuckDB version
+	case ClickHouse:
+		return []ForeignKeyInfo{}, nil // ClickHouse has no foreign keys
 	default:
 		return nil, fmt.Errorf("unsupported database type: %v", c.config.DatabaseType)
 	}
 
-	rows, err := c.db.Query(query)
+	rows, err := c.db.Query(query, args...)
 	if err != nil {
 		return []ForeignKeyInfo{}, nil // Return empty slice if query fails
 	}
@@ -338,15 +578,15 @@ func (c *connection) getForeignKeys(tableName string) ([]ForeignKeyInfo, error)
 	for rows.Next() {
 		var fk ForeignKeyInfo
 		if c.config.DatabaseType == SQLite {
+			// SQLite foreign keys are unnamed; the last column is the MATCH clause
 			var id int
 			var seq int
-			err = rows.Scan(&id, &seq, &fk.ReferencedTable, &fk.Column, &fk.ReferencedColumn, &fk.OnUpdate, &fk.OnDelete, &fk.Name)
+			var match string
+			err = rows.Scan(&id, &seq, &fk.ReferencedTable, &fk.Column, &fk.ReferencedColumn, &fk.OnUpdate, &fk.OnDelete, &match)
 			if err != nil {
 				continue
 			}
-			if fk.Name == "" {
-				fk.Name = fmt.Sprintf("fk_%d", id)
-			}
+			fk.Name = fmt.Sprintf("fk_%d", id)
 		} else {
 			err = rows.Scan(&fk.Name, &fk.Column, &fk.ReferencedTable, &fk.ReferencedColumn, &fk.OnDelete, &fk.OnUpdate)
 			if err != nil {
@@ -360,5 +600,11 @@ func (c *connection) getForeignKeys(tableName string) ([]ForeignKeyInfo, error)
 }
 
 func (c *connection) Close() error {
+	for _, replica := range c.replicas {
+		replica.Close()
+	}
+	if c.stmts != nil {
+		c.stmts.close()
+	}
 	return c.db.Close()
 }
@@ -0,0 +1,473 @@
+package core
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCopyBatchSize is the number of rows per INSERT when copying a table
+const DefaultCopyBatchSize = 1000
+
+// What a copy does with a row whose key is already in the target table
+const (
+	CopyConflictError  = "error"  // The INSERT fails and the copy stops
+	CopyConflictSkip   = "skip"   // The target's row is kept
+	CopyConflictUpsert = "upsert" // The target's row is overwritten
+)
+
+// ErrCopyNeedsKey is returned for skip and upsert without a key to detect conflicts on
+var ErrCopyNeedsKey = errors.New("skipping or overwriting existing rows needs a primary key on the target table")
+
+// CopyOptions selects the rows a copy reads and how it writes them
+type CopyOptions struct {
+	SourceTable  string
+	TargetTable  string
+	Where        string   // Condition on the source rows, as SQL
+	Keys         []string // Source columns rows are read in order of; without them a copy cannot resume
+	ConflictKeys []string // Target columns a conflict is detected on, for skip and upsert
+	BatchSize    int
+	OnConflict   string
+	After        []string // Key of the last row already copied, as SQL literals, see CopyCheckpoint
+	Create       bool     // Create the target table from the source columns first
+}
+
+// CopyProgress is reported after each batch is written
+type CopyProgress struct {
+	Rows    int64    // Rows written by this copy so far
+	LastKey []string // Key of the last row written, as SQL literals; nil without keys
+}
+
+// CopyCheckpoint records how far a copy got, so an interrupted copy can go on after the
+// last row it wrote instead of starting over
+type CopyCheckpoint struct {
+	Source  string    `json:"source"` // connection.table
+	Target  string    `json:"target"`
+	Where   string    `json:"where,omitempty"`
+	Keys    []string  `json:"keys"`
+	LastKey []string  `json:"last_key"`
+	Rows    int64     `json:"rows"`
+	Updated time.Time `json:"updated"`
+}
+
+// Matches reports whether the checkpoint is of a copy of the same rows
+func (c *CopyCheckpoint) Matches(source, target, where string, keys []string) bool {
+	return c.Source == source && c.Target == target && c.Where == where && strings.Join(c.Keys, ",") == strings.Join(keys, ",")
+}
+
+// LoadCopyCheckpoint reads a checkpoint, returning nil when there is none
+func LoadCopyCheckpoint(path string) (*CopyCheckpoint, error) {
+	return loadCheckpoint[CopyCheckpoint](path)
+}
+
+// SaveCopyCheckpoint writes a checkpoint, replacing the previous one in one step so an
+// interruption never leaves half a file
+func SaveCopyCheckpoint(path string, checkpoint *CopyCheckpoint) error {
+	return saveCheckpoint(path, checkpoint)
+}
+
+func loadCheckpoint[T any](path string) (*T, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint T
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &checkpoint, nil
+}
+
+func saveCheckpoint(path string, checkpoint any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CopyColumnType maps a source column to a type of the target dialect, by the kind of
+// values it holds. Keys get types the dialect can index.
+func CopyColumnType(col Column, dbType DatabaseType, key bool) string {
+	upper := strings.ToUpper(col.Type)
+	switch JSONLColumnType(col) {
+	case IntegerJSONLType:
+		switch dbType {
+		case SQLite:
+			return "INTEGER"
+		case ClickHouse:
+			return "Int64"
+		}
+		return "BIGINT"
+	case NumberJSONLType:
+		if col.Precision > 0 {
+			if dbType == ClickHouse {
+				return fmt.Sprintf("Decimal(%d, %d)", col.Precision, col.Scale)
+			}
+			return fmt.Sprintf("DECIMAL(%d,%d)", col.Precision, col.Scale)
+		}
+		switch dbType {
+		case MySQL:
+			return "DOUBLE"
+		case SQLite:
+			return "REAL"
+		case ClickHouse:
+			return "Float64"
+		}
+		return "DOUBLE PRECISION"
+	case BooleanJSONLType:
+		if dbType == ClickHouse {
+			return "Bool"
+		}
+		return "BOOLEAN"
+	case TimestampJSONLType:
+		switch {
+		case isDateType(col):
+			if dbType == ClickHouse {
+				return "Date32"
+			}
+			return "DATE"
+		case dbType == MySQL:
+			return "DATETIME(6)"
+		case dbType == ClickHouse:
+			return "DateTime64(6)"
+		case (dbType == PostgreSQL || dbType == DuckDB) && (strings.Contains(upper, "TZ") || strings.Contains(upper, "TIME ZONE")):
+			return "TIMESTAMPTZ"
+		}
+		return "TIMESTAMP"
+	case JSONJSONLType:
+		switch dbType {
+		case PostgreSQL:
+			return "JSONB"
+		case MySQL, DuckDB:
+			return "JSON"
+		case ClickHouse:
+			return "String"
+		}
+		return "TEXT"
+	}
+
+	switch {
+	case dbType == ClickHouse:
+		return "String"
+	case isBinaryType(col):
+		switch {
+		case dbType == PostgreSQL:
+			return "BYTEA"
+		case dbType == MySQL && key:
+			return "VARBINARY(255)"
+		case dbType == MySQL:
+			return "LONGBLOB"
+		}
+		return "BLOB"
+	case col.Length > 0 && col.Length <= 65535:
+		return fmt.Sprintf("VARCHAR(%d)", col.Length)
+	case dbType == MySQL && key:
+		// MySQL cannot index TEXT without a prefix length
+		return "VARCHAR(255)"
+	case dbType == MySQL:
+		return "LONGTEXT"
+	}
+	return "TEXT"
+}
+
+// isDateType reports whether a column holds dates without a time of day
+func isDateType(col Column) bool {
+	base, _, _ := strings.Cut(strings.ToUpper(col.Type), "(")
+	return base == "DATE" || base == "DATE32"
+}
+
+// isBinaryType reports whether a column holds bytes rather than text. Drivers return
+// both as []byte, so only the column type tells them apart.
+func isBinaryType(col Column) bool {
+	base, _, _ := strings.Cut(strings.ToUpper(col.Type), "(")
+	switch base {
+	case "BYTEA", "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY":
+		return true
+	}
+	return false
+}
+
+// CopyTableDDL returns the CREATE TABLE for a copy's target, with the source columns
+// mapped to the target dialect and the keys as its primary key
+func CopyTableDDL(dbType DatabaseType, table string, columns []Column, keys []string) string {
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[strings.ToLower(key)] = true
+	}
+	definitions := make([]string, len(columns))
+	for i, col := range columns {
+		key := isKey[strings.ToLower(col.Name)]
+		columnType := CopyColumnType(col, dbType, key)
+		switch {
+		case dbType == ClickHouse && !key && (col.Nullable == nil || *col.Nullable):
+			columnType = "Nullable(" + columnType + ")"
+		case dbType != ClickHouse && col.Nullable != nil && !*col.Nullable:
+			columnType += " NOT NULL"
+		}
+		definitions[i] = QuoteIdentifier(dbType, col.Name) + " " + columnType
+	}
+
+	quotedKeys := make([]string, len(keys))
+	for i, key := range keys {
+		quotedKeys[i] = QuoteIdentifier(dbType, key)
+	}
+	table = QuoteQualifiedName(dbType, table)
+	if dbType == ClickHouse {
+		order := "tuple()"
+		if len(keys) > 0 {
+			order = "(" + strings.Join(quotedKeys, ", ") + ")"
+		}
+		return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = MergeTree ORDER BY %s", table, strings.Join(definitions, ", "), order)
+	}
+	if len(keys) > 0 {
+		definitions = append(definitions, "PRIMARY KEY ("+strings.Join(quotedKeys, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(definitions, ", "))
+}
+
+// SQLLiteral writes a value of a column as a literal of the dialect. Times keep their
+// offset where the dialect reads one, DATE columns drop the time of day, and binary
+// columns are written in hex so no byte is read as text.
+func SQLLiteral(dbType DatabaseType, val Value, col Column) string {
+	if val.IsNull() {
+		return "NULL"
+	}
+	if isBinaryType(col) {
+		return binaryLiteral(dbType, []byte(val.String()))
+	}
+	switch v := val.(type) {
+	case IntValue:
+		return strconv.FormatInt(v.Value, 10)
+	case FloatValue:
+		return strconv.FormatFloat(v.Value, 'g', -1, 64)
+	case BoolValue:
+		if v.Value {
+			return "TRUE"
+		}
+		return "FALSE"
+	case TimeValue:
+		switch {
+		case isDateType(col):
+			return QuoteLiteral(dbType, v.Value.Format("2006-01-02"))
+		case dbType == MySQL || dbType == ClickHouse:
+			return QuoteLiteral(dbType, v.Value.Format("2006-01-02 15:04:05.999999"))
+		}
+		return QuoteLiteral(dbType, v.Value.Format("2006-01-02 15:04:05.999999-07:00"))
+	}
+	return QuoteLiteral(dbType, val.String())
+}
+
+// binaryLiteral writes bytes as a hex literal of the dialect
+func binaryLiteral(dbType DatabaseType, data []byte) string {
+	encoded := hex.EncodeToString(data)
+	switch dbType {
+	case PostgreSQL:
+		return `'\x` + encoded + `'::bytea`
+	case DuckDB:
+		var sb strings.Builder
+		for i := 0; i < len(encoded); i += 2 {
+			sb.WriteString(`\x` + encoded[i:i+2])
+		}
+		return "'" + sb.String() + "'::BLOB"
+	case ClickHouse:
+		return "unhex('" + encoded + "')"
+	}
+	return "X'" + encoded + "'"
+}
+
+// copySelect returns the query reading the rows a copy has left, in key order
+func copySelect(dbType DatabaseType, opts CopyOptions, selected string) string {
+	var conditions []string
+	if strings.TrimSpace(opts.Where) != "" {
+		conditions = append(conditions, "("+opts.Where+")")
+	}
+	keys := make([]string, len(opts.Keys))
+	for i, key := range opts.Keys {
+		keys[i] = QuoteIdentifier(dbType, key)
+	}
+	if len(opts.After) > 0 && len(opts.After) == len(keys) {
+		conditions = append(conditions, fmt.Sprintf("(%s) > (%s)", strings.Join(keys, ", "), strings.Join(opts.After, ", ")))
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", selected, QuoteQualifiedName(dbType, opts.SourceTable))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if len(keys) > 0 && selected == "*" {
+		query += " ORDER BY " + strings.Join(keys, ", ")
+	}
+	return query
+}
+
+// CountCopyRows returns how many rows a copy has left to write
+func CountCopyRows(source Connection, opts CopyOptions) (int64, error) {
+	rows, err := queryStrings(source, copySelect(quotingDialect(source), opts, "COUNT(*)"))
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return 0, fmt.Errorf("no row count returned")
+	}
+	return strconv.ParseInt(rows[0][0], 10, 64)
+}
+
+// insertStatement returns the INSERT of a batch of rows, with the conflict policy
+func insertStatement(dbType DatabaseType, table string, columns []string, conflictKeys []string, onConflict string, values []string) (string, error) {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = QuoteIdentifier(dbType, col)
+	}
+	isKey := make(map[string]bool, len(conflictKeys))
+	quotedKeys := make([]string, len(conflictKeys))
+	for i, key := range conflictKeys {
+		isKey[strings.ToLower(key)] = true
+		quotedKeys[i] = QuoteIdentifier(dbType, key)
+	}
+
+	verb, suffix := "INSERT INTO", ""
+	switch onConflict {
+	case "", CopyConflictError:
+	case CopyConflictSkip, CopyConflictUpsert:
+		if len(conflictKeys) == 0 {
+			return "", ErrCopyNeedsKey
+		}
+		var updates []string
+		for i, col := range columns {
+			if isKey[strings.ToLower(col)] || onConflict == CopyConflictSkip {
+				continue
+			}
+			switch dbType {
+			case MySQL:
+				updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", quoted[i], quoted[i]))
+			default:
+				updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoted[i], quoted[i]))
+			}
+		}
+		switch dbType {
+		case MySQL:
+			if len(updates) == 0 {
+				verb = "INSERT IGNORE INTO"
+			} else {
+				suffix = " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+			}
+		case PostgreSQL, SQLite, DuckDB:
+			suffix = " ON CONFLICT (" + strings.Join(quotedKeys, ", ") + ") DO NOTHING"
+			if len(updates) > 0 {
+				suffix = " ON CONFLICT (" + strings.Join(quotedKeys, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+			}
+		default:
+			return "", ErrUnsupportedDatabase
+		}
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q", onConflict)
+	}
+	return fmt.Sprintf("%s %s (%s) VALUES %s%s", verb, QuoteQualifiedName(dbType, table),
+		strings.Join(quoted, ", "), strings.Join(values, ", "), suffix), nil
+}
+
+// CopyTable streams the rows of a source table into a table of another connection, in
+// batches of one INSERT each, reporting progress after every batch. Rows are read in key
+// order, so the last key reported is where an interrupted copy can resume.
+func CopyTable(source, target Connection, opts CopyOptions, progress func(CopyProgress) error) (int64, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultCopyBatchSize
+	}
+	sourceType, targetType := quotingDialect(source), quotingDialect(target)
+
+	result, err := source.Execute(copySelect(sourceType, opts, "*"))
+	if err != nil {
+		return 0, err
+	}
+	defer result.Close()
+	// Values are copied as the driver returned them, not as they are displayed
+	result.SetFormatters()
+
+	columns := result.ColumnNames()
+	keyIdx := make([]int, len(opts.Keys))
+	for i, key := range opts.Keys {
+		if keyIdx[i] = columnIndex(result.Columns, key); keyIdx[i] < 0 {
+			return 0, fmt.Errorf("key column %s not found in %s", key, opts.SourceTable)
+		}
+	}
+
+	if opts.Create {
+		keys := opts.ConflictKeys
+		if len(keys) == 0 {
+			keys = opts.Keys
+		}
+		if err := execStatement(target, CopyTableDDL(targetType, opts.TargetTable, result.Columns, keys)); err != nil {
+			return 0, fmt.Errorf("failed to create %s: %w", opts.TargetTable, err)
+		}
+	}
+
+	var copied int64
+	var batch []string
+	var lastKey []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		statement, err := insertStatement(targetType, opts.TargetTable, columns, opts.ConflictKeys, opts.OnConflict, batch)
+		if err != nil {
+			return err
+		}
+		if err := execStatement(target, statement); err != nil {
+			return err
+		}
+		copied += int64(len(batch))
+		batch = batch[:0]
+		if progress != nil {
+			return progress(CopyProgress{Rows: copied, LastKey: lastKey})
+		}
+		return nil
+	}
+
+	err = result.ForEachRow(func(row []Value) error {
+		values := make([]string, len(row))
+		for i, val := range row {
+			values[i] = SQLLiteral(targetType, val, result.Columns[i])
+		}
+		batch = append(batch, "("+strings.Join(values, ", ")+")")
+		if len(keyIdx) > 0 {
+			lastKey = make([]string, len(keyIdx))
+			for i, idx := range keyIdx {
+				lastKey[i] = SQLLiteral(sourceType, row[idx], result.Columns[idx])
+			}
+		}
+		if len(batch) >= opts.BatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	return copied, err
+}
+
+// execStatement runs a statement that returns no rows, reading the result to its end so
+// drivers that run statements lazily, like SQLite's, have run it
+func execStatement(conn Connection, statement string) error {
+	result, err := conn.Execute(statement)
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+	return result.ForEachRow(func([]Value) error { return nil })
+}
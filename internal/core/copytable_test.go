@@ -0,0 +1,160 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCopyTable(t *testing.T) {
+	source := newTestSQLiteConnection(t)
+	target := newTestSQLiteConnection(t)
+	mustExec(t, source,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name VARCHAR(20) NOT NULL, score REAL, active BOOLEAN, created DATETIME, avatar BLOB)",
+		"INSERT INTO users VALUES (1, 'ann', 1.5, 1, '2024-03-01 09:30:00', X'00ff27'), (2, 'bob''s', NULL, 0, NULL, NULL), (3, 'cy', 3, 1, '2024-03-02 10:00:00', NULL), (4, 'di', 4, 0, NULL, NULL)",
+	)
+
+	var reported []CopyProgress
+	opts := CopyOptions{SourceTable: "users", TargetTable: "users_copy", Where: "id < 4", Keys: []string{"id"}, BatchSize: 2, Create: true}
+	if total, err := CountCopyRows(source, opts); err != nil || total != 3 {
+		t.Fatalf("Expected 3 rows to copy, got %d, %v", total, err)
+	}
+	copied, err := CopyTable(source, target, opts, func(p CopyProgress) error {
+		reported = append(reported, p)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if copied != 3 || len(reported) != 2 || reported[1].Rows != 3 || !slices.Equal(reported[0].LastKey, []string{"2"}) {
+		t.Fatalf("Unexpected copy of %d rows, progress %+v", copied, reported)
+	}
+	rows, err := queryStrings(target, "SELECT id, name, score, active, created FROM users_copy ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[1][1] != "bob's" || rows[1][2] != "" || rows[0][3] != "true" || !strings.HasPrefix(rows[2][4], "2024-03-02 10:00:00") {
+		t.Errorf("Unexpected copied rows %q", rows)
+	}
+	if rows, _ := queryStrings(target, "SELECT hex(avatar), typeof(avatar) FROM users_copy WHERE id = 1"); rows[0][0] != "00FF27" || rows[0][1] != "blob" {
+		t.Errorf("Expected the bytes copied as a blob, got %q", rows)
+	}
+
+	// Resuming after the last key copies only the rest
+	opts.Where, opts.After, opts.Create = "", reported[1].LastKey, false
+	if copied, err = CopyTable(source, target, opts, nil); err != nil || copied != 1 {
+		t.Fatalf("Expected the resumed copy to add 1 row, got %d, %v", copied, err)
+	}
+
+	mustExec(t, source, "UPDATE users SET name = 'ann2' WHERE id = 1")
+	opts.After = nil
+	if _, err := CopyTable(source, target, opts, nil); err == nil {
+		t.Error("Expected copying existing rows to fail without a conflict policy")
+	}
+	opts.ConflictKeys = []string{"id"}
+	opts.OnConflict = CopyConflictSkip
+	if _, err := CopyTable(source, target, opts, nil); err != nil {
+		t.Fatalf("Copy with skip failed: %v", err)
+	}
+	if rows, _ := queryStrings(target, "SELECT name FROM users_copy WHERE id = 1"); rows[0][0] != "ann" {
+		t.Errorf("Expected skip to keep the target row, got %q", rows[0][0])
+	}
+	opts.OnConflict = CopyConflictUpsert
+	if _, err := CopyTable(source, target, opts, nil); err != nil {
+		t.Fatalf("Copy with upsert failed: %v", err)
+	}
+	if rows, _ := queryStrings(target, "SELECT name FROM users_copy WHERE id = 1"); rows[0][0] != "ann2" {
+		t.Errorf("Expected upsert to overwrite the target row, got %q", rows[0][0])
+	}
+}
+
+func TestCopyTableDDL(t *testing.T) {
+	notNull := false
+	columns := []Column{
+		{Name: "id", Type: "INT4", ScanType: "int32", Nullable: &notNull},
+		{Name: "email", Type: "VARCHAR", ScanType: "string", Length: 120},
+		{Name: "total", Type: "NUMERIC", Precision: 10, Scale: 2},
+		{Name: "created", Type: "TIMESTAMPTZ", ScanType: "time.Time"},
+		{Name: "born", Type: "DATE", ScanType: "time.Time"},
+		{Name: "meta", Type: "JSONB"},
+	}
+	testCases := []struct {
+		dbType   DatabaseType
+		expected string
+	}{
+		{PostgreSQL, `CREATE TABLE IF NOT EXISTS "public"."users" ("id" BIGINT NOT NULL, "email" VARCHAR(120), "total" DECIMAL(10,2), "created" TIMESTAMPTZ, "born" DATE, "meta" JSONB, PRIMARY KEY ("id"))`},
+		{MySQL, "CREATE TABLE IF NOT EXISTS `public`.`users` (`id` BIGINT NOT NULL, `email` VARCHAR(120), `total` DECIMAL(10,2), `created` DATETIME(6), `born` DATE, `meta` JSON, PRIMARY KEY (`id`))"},
+		{ClickHouse, `CREATE TABLE IF NOT EXISTS "public"."users" ("id" Int64, "email" Nullable(String), "total" Nullable(Decimal(10, 2)), "created" Nullable(DateTime64(6)), "born" Nullable(Date32), "meta" Nullable(String)) ENGINE = MergeTree ORDER BY ("id")`},
+	}
+	for _, tc := range testCases {
+		if got := CopyTableDDL(tc.dbType, "public.users", columns, []string{"id"}); got != tc.expected {
+			t.Errorf("%v:\nexpected %s\ngot      %s", tc.dbType, tc.expected, got)
+		}
+	}
+	if got := CopyColumnType(Column{Type: "TEXT"}, MySQL, true); got != "VARCHAR(255)" {
+		t.Errorf("Expected a MySQL key column to be indexable, got %s", got)
+	}
+}
+
+func TestSQLLiteral(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 30, 0, 500000000, time.FixedZone("AEDT", 11*3600))
+	date, text, bytea, blob := Column{Type: "DATE"}, Column{Type: "TEXT"}, Column{Type: "BYTEA"}, Column{Type: "BLOB"}
+	binary := StringValue{Value: "\x00'\\\xff"}
+	testCases := []struct {
+		dbType   DatabaseType
+		value    Value
+		column   Column
+		expected string
+	}{
+		{PostgreSQL, NullValue{}, text, "NULL"},
+		{PostgreSQL, IntValue{Value: 42}, text, "42"},
+		{PostgreSQL, FloatValue{Value: 0.1}, text, "0.1"},
+		{SQLite, BoolValue{Value: true}, text, "TRUE"},
+		{PostgreSQL, TimeValue{Value: at}, text, "'2024-03-01 09:30:00.5+11:00'"},
+		{MySQL, TimeValue{Value: at}, text, "'2024-03-01 09:30:00.5'"},
+		{PostgreSQL, TimeValue{Value: at}, date, "'2024-03-01'"},
+		{MySQL, StringValue{Value: `it's a \ test`}, text, `'it''s a \\ test'`},
+		// Bytes are written in hex whatever they hold
+		{PostgreSQL, binary, bytea, `'\x00275cff'::bytea`},
+		{MySQL, binary, blob, "X'00275cff'"},
+		{SQLite, binary, blob, "X'00275cff'"},
+		{DuckDB, binary, blob, `'\x00\x27\x5c\xff'::BLOB`},
+		{PostgreSQL, NullValue{}, bytea, "NULL"},
+	}
+	for _, tc := range testCases {
+		if got := SQLLiteral(tc.dbType, tc.value, tc.column); got != tc.expected {
+			t.Errorf("%v %#v: expected %s, got %s", tc.dbType, tc.value, tc.expected, got)
+		}
+	}
+}
+
+func TestCopySelect(t *testing.T) {
+	opts := CopyOptions{SourceTable: "sales.order items", Keys: []string{"order id"}, After: []string{"42"}, Where: "total > 0"}
+	expected := `SELECT * FROM "sales"."order items" WHERE (total > 0) AND ("order id") > (42) ORDER BY "order id"`
+	if got := copySelect(PostgreSQL, opts, "*"); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if got := copySelect(MySQL, CopyOptions{SourceTable: "orders", Keys: []string{"id"}}, "COUNT(*)"); got != "SELECT COUNT(*) FROM `orders`" {
+		t.Errorf("Unexpected count query %s", got)
+	}
+}
+
+func TestCopyCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "copies", "prod.users__staging.users.json")
+	if checkpoint, err := LoadCopyCheckpoint(path); err != nil || checkpoint != nil {
+		t.Fatalf("Expected no checkpoint, got %+v, %v", checkpoint, err)
+	}
+	saved := &CopyCheckpoint{Source: "prod.users", Target: "staging.users", Keys: []string{"id"}, LastKey: []string{"5000"}, Rows: 5000, Updated: time.Now()}
+	if err := SaveCopyCheckpoint(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCopyCheckpoint(path)
+	if err != nil || loaded == nil || loaded.Rows != 5000 || loaded.LastKey[0] != "5000" {
+		t.Fatalf("Unexpected checkpoint %+v, %v", loaded, err)
+	}
+	if !loaded.Matches("prod.users", "staging.users", "", []string{"id"}) || loaded.Matches("prod.users", "staging.users", "id > 10", []string{"id"}) {
+		t.Error("Expected the checkpoint to match only the same copy")
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/i18n"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Dashboard panel display types
+const (
+	DisplayTable       = "table"
+	DisplayChart       = "chart"
+	DisplaySingleValue = "single-value"
+)
+
+// Dashboard limits: rows shown in a table panel, and bars in a chart panel
+const (
+	DashboardTableRows = 20
+	DashboardChartBars = 30
+)
+
+// dashboardBarWidth is the length of the longest bar in a chart panel
+const dashboardBarWidth = 40
+
+// Dashboard is a set of named queries shown together, read from a YAML file:
+//
+//	title: Operations
+//	queries:
+//	  - name: Signups today
+//	    sql: SELECT COUNT(*) FROM users WHERE created_at >= CURRENT_DATE
+//	    display: single-value
+//	  - name: Orders by status
+//	    sql: SELECT status, COUNT(*) FROM orders GROUP BY status
+//	    display: chart
+type Dashboard struct {
+	Title   string           `yaml:"title"`
+	Queries []DashboardQuery `yaml:"queries"`
+}
+
+// DashboardQuery is one panel of a dashboard. A chart takes its labels from the first
+// column and its values from the second; a single value is the first column of the
+// first row.
+type DashboardQuery struct {
+	Name    string `yaml:"name"`
+	SQL     string `yaml:"sql"`
+	Display string `yaml:"display,omitempty"` // table (default), chart or single-value
+}
+
+// LoadDashboard reads and checks a dashboard file
+func LoadDashboard(path string) (*Dashboard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var dashboard Dashboard
+	if err := yaml.Unmarshal(data, &dashboard); err != nil {
+		return nil, err
+	}
+	if len(dashboard.Queries) == 0 {
+		return nil, errors.New("dashboard has no queries")
+	}
+	for i := range dashboard.Queries {
+		q := &dashboard.Queries[i]
+		if strings.TrimSpace(q.SQL) == "" {
+			return nil, fmt.Errorf("query %d (%s) has no sql", i+1, q.Name)
+		}
+		if q.Name == "" {
+			q.Name = fmt.Sprintf("Query %d", i+1)
+		}
+		switch q.Display {
+		case "":
+			q.Display = DisplayTable
+		case DisplayTable, DisplayChart, DisplaySingleValue:
+		default:
+			return nil, fmt.Errorf("query %s: unknown display %q, expected table, chart or single-value", q.Name, q.Display)
+		}
+	}
+	return &dashboard, nil
+}
+
+// RenderDashboard runs every query with run and renders the panels as one markdown
+// document. A query that fails shows its error in place of its panel.
+func RenderDashboard(dashboard *Dashboard, run func(query string) (*QueryResult, error), i18nMgr *i18n.Manager) string {
+	var sb strings.Builder
+	title := dashboard.Title
+	if title == "" {
+		title = i18nMgr.Get("dashboard_default_title")
+	}
+	fmt.Fprintf(&sb, "# %s - %s\n\n", title, time.Now().Format("2006-01-02 15:04:05"))
+
+	for _, q := range dashboard.Queries {
+		fmt.Fprintf(&sb, "## %s\n\n", q.Name)
+		start := time.Now()
+		result, err := run(strings.TrimSuffix(strings.TrimSpace(q.SQL), ";"))
+		if err == nil {
+			err = renderPanel(&sb, q.Display, result, i18nMgr)
+		}
+		if err != nil {
+			fmt.Fprintf(&sb, "⚠️ %s\n\n", err)
+			continue
+		}
+		fmt.Fprintf(&sb, "*⏱ %v*\n\n", time.Since(start).Round(time.Millisecond))
+	}
+	return sb.String()
+}
+
+// renderPanel writes a result in a panel's display type and closes it
+func renderPanel(sb *strings.Builder, display string, result *QueryResult, i18nMgr *i18n.Manager) error {
+	if display == DisplayTable {
+		sb.WriteString(ToMarkdown(result, DashboardTableRows, i18nMgr))
+		sb.WriteString("\n")
+		return nil
+	}
+	defer result.Close()
+
+	var rows [][]string
+	err := result.ForEachRow(func(row []Value) error {
+		cells := make([]string, len(row))
+		for i, val := range row {
+			cells[i] = val.String()
+		}
+		rows = append(rows, cells)
+		if display == DisplaySingleValue || len(rows) >= DashboardChartBars {
+			return ErrStopRows
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return errors.New(i18nMgr.Get("dashboard_no_rows"))
+	}
+	if display == DisplaySingleValue {
+		fmt.Fprintf(sb, "> **%s**\n\n", rows[0][0])
+		return nil
+	}
+
+	labels := make([]string, len(rows))
+	values := make([]float64, len(rows))
+	for i, row := range rows {
+		if len(row) < 2 {
+			return errors.New(i18nMgr.Get("dashboard_chart_columns"))
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		if err != nil {
+			return fmt.Errorf(i18nMgr.Get("dashboard_chart_not_number"), row[1])
+		}
+		labels[i], values[i] = row[0], value
+	}
+	fmt.Fprintf(sb, "```\n%s```\n\n", RenderBarChart(labels, values, dashboardBarWidth))
+	return nil
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/i18n"
+)
+
+func TestLoadDashboard(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "dashboard.yaml")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	dashboard, err := LoadDashboard(write("title: Ops\nqueries:\n  - sql: SELECT 1\n  - name: Chart\n    sql: SELECT 'a', 1\n    display: chart\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := dashboard.Queries[0]; q.Name != "Query 1" || q.Display != DisplayTable {
+		t.Errorf("Expected defaults for the first query, got %+v", q)
+	}
+
+	for content, wantErr := range map[string]string{
+		"title: Empty\n":                                  "no queries",
+		"queries:\n  - name: A\n":                         "no sql",
+		"queries:\n  - sql: SELECT 1\n    display: pie\n": "unknown display",
+	} {
+		if _, err := LoadDashboard(write(content)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Expected error containing %q for %q, got %v", wantErr, content, err)
+		}
+	}
+}
+
+func TestRenderDashboard(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER, status TEXT)",
+		"INSERT INTO orders VALUES (1, 'new'), (2, 'new'), (3, 'paid')",
+	)
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dashboard := &Dashboard{Title: "Ops", Queries: []DashboardQuery{
+		{Name: "Orders", SQL: "SELECT COUNT(*) FROM orders;", Display: DisplaySingleValue},
+		{Name: "By status", SQL: "SELECT status, COUNT(*) FROM orders GROUP BY status ORDER BY status", Display: DisplayChart},
+		{Name: "Latest", SQL: "SELECT id, status FROM orders ORDER BY id DESC", Display: DisplayTable},
+		{Name: "Broken", SQL: "SELECT status FROM orders", Display: DisplayChart},
+		{Name: "Missing", SQL: "SELECT * FROM missing", Display: DisplayTable},
+	}}
+	md := RenderDashboard(dashboard, conn.Execute, i18nMgr)
+
+	for _, want := range []string{
+		"# Ops - ",
+		"## Orders\n\n> **3**\n",
+		"new   " + strings.Repeat("█", 40) + " 2\n",
+		"paid  " + strings.Repeat("█", 20) + strings.Repeat(" ", 20) + " 1\n",
+		"| 3  | paid   |",
+		"## Broken\n\n⚠️ A chart needs a label column and a value column\n",
+		"## Missing\n\n⚠️ failed to execute query: no such table: missing",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected dashboard to contain %q, got\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package core
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUnorderedKeys is returned when a side does not stream rows in the order the
+// comparator expects, which would make a merge join report bogus differences
+var ErrUnorderedKeys = errors.New("rows are not sorted consistently by key; use numeric or binary-collated key columns")
+
+// RowChangeKind describes how a row differs between two tables
+type RowChangeKind string
+
+const (
+	RowAdded   RowChangeKind = "added"   // Only in the right table
+	RowRemoved RowChangeKind = "removed" // Only in the left table
+	RowChanged RowChangeKind = "changed" // Same key, different values
+)
+
+// RowDiff is one differing row. Left or Right is nil when the row exists on one side only.
+type RowDiff struct {
+	Kind    RowChangeKind
+	Key     []string
+	Left    []Value
+	Right   []Value
+	Changed []string // Names of the columns that differ, for RowChanged
+}
+
+// DataDiffOptions selects what to compare
+type DataDiffOptions struct {
+	Table   string
+	Keys    []string
+	Columns []string // Compared columns; empty compares every non-key column present on both sides
+}
+
+// DataDiffSummary counts rows by outcome
+type DataDiffSummary struct {
+	Same    int
+	Added   int
+	Removed int
+	Changed int
+}
+
+// Total returns the number of differing rows
+func (s DataDiffSummary) Total() int {
+	return s.Added + s.Removed + s.Changed
+}
+
+// dataDiffSide streams one table ordered by key
+type dataDiffSide struct {
+	next    func() ([]Value, bool)
+	stop    func()
+	result  *QueryResult
+	keyIdx  []int
+	lastKey []string
+}
+
+// openDataDiffSide streams the rows of a table matching condition, or every row
+// without one, ordered by key
+func openDataDiffSide(conn Connection, table string, keys, columns []string, condition string) (*dataDiffSide, error) {
+	dbType := quotingDialect(conn)
+	quote := func(names []string) string {
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = QuoteIdentifier(dbType, name)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	selected := "*"
+	if len(columns) > 0 {
+		selected = quote(append(append([]string{}, keys...), columns...))
+	}
+	query := "SELECT " + selected + " FROM " + QuoteQualifiedName(dbType, table)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	query += " ORDER BY " + quote(keys)
+
+	result, err := conn.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+
+	side := &dataDiffSide{result: result}
+	for _, key := range keys {
+		idx := columnIndex(result.Columns, key)
+		if idx < 0 {
+			result.Close()
+			return nil, fmt.Errorf("key column %s not found in %s", key, table)
+		}
+		side.keyIdx = append(side.keyIdx, idx)
+	}
+	side.next, side.stop = iter.Pull(result.Itor())
+	return side, nil
+}
+
+// read returns the next row and its key, checking that keys ascend
+func (s *dataDiffSide) read() ([]Value, []string, bool, error) {
+	row, ok := s.next()
+	if !ok {
+		return nil, nil, false, s.result.Error()
+	}
+	key := make([]string, len(s.keyIdx))
+	for i, idx := range s.keyIdx {
+		key[i] = row[idx].String()
+	}
+	if s.lastKey != nil && compareKeys(s.lastKey, key) > 0 {
+		return nil, nil, false, ErrUnorderedKeys
+	}
+	s.lastKey = key
+	return row, key, true, nil
+}
+
+func (s *dataDiffSide) close() {
+	s.stop()
+	s.result.Close()
+}
+
+func columnIndex(columns []Column, name string) int {
+	for i, col := range columns {
+		if strings.EqualFold(col.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// compareKeys orders composite keys, comparing numerically when both parts are numbers
+func compareKeys(a, b []string) int {
+	for i := range a {
+		x, errX := strconv.ParseFloat(a[i], 64)
+		y, errY := strconv.ParseFloat(b[i], 64)
+		switch {
+		case errX == nil && errY == nil:
+			if x < y {
+				return -1
+			}
+			if x > y {
+				return 1
+			}
+		default:
+			if c := strings.Compare(a[i], b[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// DataDiff compares a table across two connections with a merge join over rows
+// streamed in key order, so neither side is held in memory
+type DataDiff struct {
+	Columns  []string // Compared column names, in RowDiff value order
+	left     *dataDiffSide
+	right    *dataDiffSide
+	leftIdx  []int
+	rightIdx []int
+}
+
+// NewDataDiff starts streaming both sides of the comparison
+func NewDataDiff(left, right Connection, opts DataDiffOptions) (*DataDiff, error) {
+	if len(opts.Keys) == 0 {
+		return nil, fmt.Errorf("at least one key column is required")
+	}
+
+	l, err := openDataDiffSide(left, opts.Table, opts.Keys, opts.Columns, "")
+	if err != nil {
+		return nil, err
+	}
+	r, err := openDataDiffSide(right, opts.Table, opts.Keys, opts.Columns, "")
+	if err != nil {
+		l.close()
+		return nil, err
+	}
+
+	d := &DataDiff{left: l, right: r}
+	d.Columns, d.leftIdx, d.rightIdx = matchColumns(l.result.Columns, r.result.Columns, opts.Keys)
+	return d, nil
+}
+
+// matchColumns pairs up non-key columns by name so column order may differ between sides
+func matchColumns(left, right []Column, keys []string) (names []string, leftIdx, rightIdx []int) {
+	for i, col := range left {
+		if slices.ContainsFunc(keys, func(key string) bool { return strings.EqualFold(key, col.Name) }) {
+			continue
+		}
+		if j := columnIndex(right, col.Name); j >= 0 {
+			names = append(names, col.Name)
+			leftIdx = append(leftIdx, i)
+			rightIdx = append(rightIdx, j)
+		}
+	}
+	return names, leftIdx, rightIdx
+}
+
+// pickValues returns the values at the given column positions
+func pickValues(row []Value, idx []int) []Value {
+	values := make([]Value, len(idx))
+	for i, j := range idx {
+		values[i] = row[j]
+	}
+	return values
+}
+
+// Close stops streaming both sides
+func (d *DataDiff) Close() {
+	d.left.close()
+	d.right.close()
+}
+
+// Run walks both sides, calling fn for each differing row; returning an error from fn
+// stops the comparison
+func (d *DataDiff) Run(fn func(RowDiff) error) (DataDiffSummary, error) {
+	var summary DataDiffSummary
+	l, r := d.left, d.right
+	lRow, lKey, lOK, err := l.read()
+	if err != nil {
+		return summary, err
+	}
+	rRow, rKey, rOK, err := r.read()
+	if err != nil {
+		return summary, err
+	}
+
+	for lOK || rOK {
+		var diff *RowDiff
+		cmp := 0
+		switch {
+		case !rOK:
+			cmp = -1
+		case !lOK:
+			cmp = 1
+		default:
+			cmp = compareKeys(lKey, rKey)
+		}
+
+		switch {
+		case cmp < 0:
+			summary.Removed++
+			diff = &RowDiff{Kind: RowRemoved, Key: lKey, Left: pickValues(lRow, d.leftIdx)}
+		case cmp > 0:
+			summary.Added++
+			diff = &RowDiff{Kind: RowAdded, Key: rKey, Right: pickValues(rRow, d.rightIdx)}
+		default:
+			lValues, rValues := pickValues(lRow, d.leftIdx), pickValues(rRow, d.rightIdx)
+			var changed []string
+			for i := range lValues {
+				if lValues[i].String() != rValues[i].String() || lValues[i].IsNull() != rValues[i].IsNull() {
+					changed = append(changed, d.Columns[i])
+				}
+			}
+			if len(changed) > 0 {
+				summary.Changed++
+				diff = &RowDiff{Kind: RowChanged, Key: lKey, Left: lValues, Right: rValues, Changed: changed}
+			} else {
+				summary.Same++
+			}
+		}
+
+		if diff != nil {
+			if err := fn(*diff); err != nil {
+				return summary, err
+			}
+		}
+
+		if cmp <= 0 {
+			if lRow, lKey, lOK, err = l.read(); err != nil {
+				return summary, err
+			}
+		}
+		if cmp >= 0 {
+			if rRow, rKey, rOK, err = r.read(); err != nil {
+				return summary, err
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// DataDiffCSVWriter writes row differences as CSV: change, key columns, then
+// left/right value pairs for each compared column
+type DataDiffCSVWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func NewDataDiffCSVWriter(filePath string, keys, columns []string) (*DataDiffCSVWriter, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV file: %w", err)
+	}
+
+	w := &DataDiffCSVWriter{file: file, writer: csv.NewWriter(file)}
+	header := append([]string{"change"}, keys...)
+	for _, col := range columns {
+		header = append(header, "left_"+col, "right_"+col)
+	}
+	if err := w.writer.Write(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *DataDiffCSVWriter) Write(diff RowDiff) error {
+	record := append([]string{string(diff.Kind)}, diff.Key...)
+	width := max(len(diff.Left), len(diff.Right))
+	for i := 0; i < width; i++ {
+		record = append(record, valueAt(diff.Left, i), valueAt(diff.Right, i))
+	}
+	return w.writer.Write(record)
+}
+
+func (w *DataDiffCSVWriter) Close() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		w.file.Close()
+		return fmt.Errorf("CSV writer error: %w", err)
+	}
+	return w.file.Close()
+}
+
+func valueAt(values []Value, i int) string {
+	if i >= len(values) {
+		return ""
+	}
+	return values[i].String()
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDataDiff(t *testing.T) {
+	left := newTestSQLiteConnection(t)
+	right := newTestSQLiteConnection(t)
+	mustExec(t, left,
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT, total REAL)",
+		"INSERT INTO orders VALUES (1, 'paid', 10), (2, 'open', 20), (3, 'open', 30), (10, 'paid', 100)",
+	)
+	mustExec(t, right,
+		"CREATE TABLE orders (total REAL, status TEXT, id INTEGER PRIMARY KEY)",
+		"INSERT INTO orders VALUES (10, 'paid', 1), (25, 'open', 2), (100, 'paid', 10), (40, 'new', 4)",
+	)
+
+	diff, err := NewDataDiff(left, right, DataDiffOptions{Table: "orders", Keys: []string{"id"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer diff.Close()
+
+	if strings.Join(diff.Columns, ",") != "status,total" {
+		t.Errorf("Expected columns matched by name, got %v", diff.Columns)
+	}
+
+	var diffs []RowDiff
+	summary, err := diff.Run(func(row RowDiff) error {
+		diffs = append(diffs, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if summary.Same != 2 || summary.Changed != 1 || summary.Removed != 1 || summary.Added != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+
+	expected := []struct {
+		kind RowChangeKind
+		key  string
+	}{
+		{RowChanged, "2"}, {RowRemoved, "3"}, {RowAdded, "4"},
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("Expected %d diffs, got %+v", len(expected), diffs)
+	}
+	for i, want := range expected {
+		if diffs[i].Kind != want.kind || diffs[i].Key[0] != want.key {
+			t.Errorf("Diff %d: expected %s %s, got %s %v", i, want.kind, want.key, diffs[i].Kind, diffs[i].Key)
+		}
+	}
+	if strings.Join(diffs[0].Changed, ",") != "total" {
+		t.Errorf("Expected only total to change, got %v", diffs[0].Changed)
+	}
+}
+
+func TestDataDiffColumnsAndCSV(t *testing.T) {
+	left := newTestSQLiteConnection(t)
+	right := newTestSQLiteConnection(t)
+	for _, conn := range []Connection{left, right} {
+		mustExec(t, conn, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, updated_at TEXT)")
+	}
+	mustExec(t, left, "INSERT INTO items VALUES (1, 'a', '2024-01-01')")
+	mustExec(t, right, "INSERT INTO items VALUES (1, 'b', '2024-02-02')")
+
+	diff, err := NewDataDiff(left, right, DataDiffOptions{Table: "items", Keys: []string{"id"}, Columns: []string{"name"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer diff.Close()
+
+	csvPath := filepath.Join(t.TempDir(), "diff.csv")
+	writer, err := NewDataDiffCSVWriter(csvPath, []string{"id"}, diff.Columns)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	if _, err := diff.Run(writer.Write); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if string(data) != "change,id,left_name,right_name\nchanged,1,a,b\n" {
+		t.Errorf("Unexpected CSV:\n%s", data)
+	}
+}
+
+func TestDataDiffUnorderedKeys(t *testing.T) {
+	left := newTestSQLiteConnection(t)
+	right := newTestSQLiteConnection(t)
+	for _, conn := range []Connection{left, right} {
+		// Mixed-type keys sort differently in SQLite than in the comparator
+		mustExec(t, conn,
+			"CREATE TABLE t (k, v)",
+			"INSERT INTO t VALUES (30, 'x'), ('20x', 'y')",
+		)
+	}
+
+	diff, err := NewDataDiff(left, right, DataDiffOptions{Table: "t", Keys: []string{"k"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer diff.Close()
+
+	if _, err := diff.Run(func(RowDiff) error { return nil }); !errors.Is(err, ErrUnorderedKeys) {
+		t.Errorf("Expected ErrUnorderedKeys, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TableDDL is the CREATE TABLE statement for a table, followed by its index statements
+type TableDDL struct {
+	Table   string
+	Create  string
+	Indexes []string
+}
+
+// String joins the statements into a script, each terminated by a semicolon
+func (d *TableDDL) String() string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSuffix(strings.TrimSpace(d.Create), ";") + ";\n")
+	for _, index := range d.Indexes {
+		sb.WriteString(strings.TrimSuffix(strings.TrimSpace(index), ";") + ";\n")
+	}
+	return sb.String()
+}
+
+// ddlColumn is a column definition in dialect-ready form
+type ddlColumn struct {
+	Name    string
+	Type    string
+	NotNull bool
+	Default string
+}
+
+// GenerateDDL returns the CREATE TABLE statement for a table. MySQL and SQLite report the
+// statement as stored by the server; PostgreSQL has no SHOW CREATE TABLE, so it is
+// rebuilt from the system catalogs the way pg_dump does.
+func GenerateDDL(conn Connection, dbType DatabaseType, table string) (*TableDDL, error) {
+	if err := ValidateIdentifier(table); err != nil {
+		return nil, err
+	}
+	switch dbType {
+	case MySQL:
+		return mysqlDDL(conn, table)
+	case PostgreSQL:
+		return postgresDDL(conn, table)
+	case SQLite:
+		return sqliteDDL(conn, table)
+	case DuckDB:
+		return duckdbDDL(conn, table)
+	case ClickHouse:
+		return clickhouseDDL(conn, table)
+	default:
+		return nil, fmt.Errorf("unsupported database type: %v", dbType)
+	}
+}
+
+func mysqlDDL(conn Connection, table string) (*TableDDL, error) {
+	rows, err := queryStrings(conn, "SHOW CREATE TABLE "+QuoteQualifiedName(MySQL, table))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	// Indexes are part of the MySQL statement
+	return &TableDDL{Table: table, Create: rows[0][1]}, nil
+}
+
+func sqliteDDL(conn Connection, table string) (*TableDDL, error) {
+	rows, err := queryStrings(conn, fmt.Sprintf(
+		"SELECT type, sql FROM sqlite_master WHERE tbl_name = %s AND type IN ('table', 'index') AND sql IS NOT NULL ORDER BY type = 'index', name",
+		QuoteLiteral(SQLite, table)))
+	if err != nil {
+		return nil, err
+	}
+
+	ddl := &TableDDL{Table: table}
+	for _, row := range rows {
+		if row[0] == "table" {
+			ddl.Create = row[1]
+		} else {
+			ddl.Indexes = append(ddl.Indexes, row[1])
+		}
+	}
+	if ddl.Create == "" {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return ddl, nil
+}
+
+// clickhouseDDL returns SHOW CREATE TABLE, which includes the engine and sorting key
+func clickhouseDDL(conn Connection, table string) (*TableDDL, error) {
+	rows, err := queryStrings(conn, "SHOW CREATE TABLE "+QuoteQualifiedName(ClickHouse, table))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return &TableDDL{Table: table, Create: rows[0][0]}, nil
+}
+
+// duckdbDDL reads the statements DuckDB keeps for a table, or for a view such as a data
+// file opened directly
+func duckdbDDL(conn Connection, table string) (*TableDDL, error) {
+	name := QuoteLiteral(DuckDB, table)
+	rows, err := queryStrings(conn, fmt.Sprintf(`
+		SELECT 'table' AS kind, sql FROM duckdb_tables() WHERE schema_name = 'main' AND table_name = %[1]s
+		UNION ALL
+		SELECT 'view', sql FROM duckdb_views() WHERE schema_name = 'main' AND view_name = %[1]s
+		UNION ALL
+		SELECT 'index', sql FROM duckdb_indexes() WHERE schema_name = 'main' AND table_name = %[1]s AND sql IS NOT NULL`,
+		name))
+	if err != nil {
+		return nil, err
+	}
+
+	ddl := &TableDDL{Table: table}
+	for _, row := range rows {
+		if row[0] == "index" {
+			ddl.Indexes = append(ddl.Indexes, row[1])
+		} else {
+			ddl.Create = row[1]
+		}
+	}
+	if ddl.Create == "" {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+	return ddl, nil
+}
+
+const postgresColumnsQuery = `SELECT quote_ident(a.attname), format_type(a.atttypid, a.atttypmod),
+	a.attnotnull, COALESCE(pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_attribute a
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE a.attrelid = %s::regclass AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`
+
+// Primary key first, then the rest by name so the output is stable
+const postgresConstraintsQuery = `SELECT 'CONSTRAINT ' || quote_ident(conname) || ' ' || pg_get_constraintdef(oid)
+FROM pg_constraint
+WHERE conrelid = %s::regclass AND contype IN ('p', 'u', 'f', 'c', 'x')
+ORDER BY contype <> 'p', contype, conname`
+
+// Indexes backing constraints are created by the constraints themselves
+const postgresIndexesQuery = `SELECT pg_get_indexdef(i.indexrelid)
+FROM pg_index i
+WHERE i.indrelid = %s::regclass
+AND NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid)
+ORDER BY i.indexrelid::regclass::text`
+
+func postgresDDL(conn Connection, table string) (*TableDDL, error) {
+	// regclass parses the literal as a possibly schema-qualified name, as psql does
+	name := QuoteLiteral(PostgreSQL, table)
+
+	rows, err := queryStrings(conn, fmt.Sprintf(postgresColumnsQuery, name))
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]ddlColumn, 0, len(rows))
+	for _, row := range rows {
+		columns = append(columns, ddlColumn{Name: row[0], Type: row[1], NotNull: row[2] == "true", Default: row[3]})
+	}
+
+	rows, err = queryStrings(conn, fmt.Sprintf(postgresConstraintsQuery, name))
+	if err != nil {
+		return nil, err
+	}
+	var constraints []string
+	for _, row := range rows {
+		constraints = append(constraints, row[0])
+	}
+
+	rows, err = queryStrings(conn, fmt.Sprintf(postgresIndexesQuery, name))
+	if err != nil {
+		return nil, err
+	}
+	ddl := &TableDDL{Table: table, Create: buildCreateTable(table, columns, constraints)}
+	for _, row := range rows {
+		ddl.Indexes = append(ddl.Indexes, row[0])
+	}
+	return ddl, nil
+}
+
+// buildCreateTable lays out a CREATE TABLE statement with one column or constraint per line
+func buildCreateTable(table string, columns []ddlColumn, constraints []string) string {
+	lines := make([]string, 0, len(columns)+len(constraints))
+	for _, col := range columns {
+		line := col.Name + " " + col.Type
+		if col.Default != "" {
+			line += " DEFAULT " + col.Default
+		}
+		if col.NotNull {
+			line += " NOT NULL"
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, constraints...)
+	return fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", table, strings.Join(lines, ",\n    "))
+}
+
+// queryStrings runs a metadata query and returns every row as strings
+func queryStrings(conn Connection, query string) ([][]string, error) {
+	result, err := conn.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var rows [][]string
+	err = result.ForEachRow(func(row []Value) error {
+		values := make([]string, len(row))
+		for i, v := range row {
+			values[i] = v.String()
+		}
+		rows = append(rows, values)
+		return nil
+	})
+	return rows, err
+}
@@ -0,0 +1,43 @@
+package core
+
+import "testing"
+
+func TestGenerateDDLSQLite(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL DEFAULT '')",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), total REAL)",
+		"CREATE INDEX idx_orders_user ON orders (user_id)",
+	)
+
+	ddl, err := GenerateDDL(conn, SQLite, "orders")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), total REAL);\n" +
+		"CREATE INDEX idx_orders_user ON orders (user_id);\n"
+	if got := ddl.String(); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	if _, err := GenerateDDL(conn, SQLite, "missing"); err == nil {
+		t.Error("Expected error for a missing table")
+	}
+}
+
+func TestBuildCreateTable(t *testing.T) {
+	columns := []ddlColumn{
+		{Name: "id", Type: "integer", NotNull: true, Default: "nextval('users_id_seq'::regclass)"},
+		{Name: "email", Type: "character varying(255)"},
+	}
+	constraints := []string{"CONSTRAINT users_pkey PRIMARY KEY (id)"}
+
+	expected := "CREATE TABLE users (\n" +
+		"    id integer DEFAULT nextval('users_id_seq'::regclass) NOT NULL,\n" +
+		"    email character varying(255),\n" +
+		"    CONSTRAINT users_pkey PRIMARY KEY (id)\n" +
+		")"
+	if got := buildCreateTable("users", columns, constraints); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
@@ -0,0 +1,237 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"sqlterm/internal/i18n"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// DefaultTestTimeout bounds each step of a connection test
+const DefaultTestTimeout = 10 * time.Second
+
+// FailureKind classifies why a connection attempt failed
+type FailureKind string
+
+const (
+	FailureNone       FailureKind = ""
+	FailureDNS        FailureKind = "dns"
+	FailureNetwork    FailureKind = "network"
+	FailureTimeout    FailureKind = "timeout"
+	FailureAuth       FailureKind = "auth"
+	FailureTLS        FailureKind = "tls"
+	FailurePermission FailureKind = "permission"
+	FailureDatabase   FailureKind = "database"
+	FailureUnknown    FailureKind = "unknown"
+)
+
+// Connection test steps
+const (
+	StepResolve = "resolve"
+	StepConnect = "connect"
+	StepQuery   = "query"
+)
+
+// TestStep records the outcome of one stage of a connection test
+type TestStep struct {
+	Name     string
+	Detail   string
+	Duration time.Duration
+	Err      error
+}
+
+// ConnectionTestReport is the result of TestConnection
+type ConnectionTestReport struct {
+	Steps   []TestStep
+	Failure FailureKind
+}
+
+// OK reports whether every step succeeded
+func (r *ConnectionTestReport) OK() bool {
+	return r.Failure == FailureNone
+}
+
+// Err returns the error of the failed step, if any
+func (r *ConnectionTestReport) Err() error {
+	for _, step := range r.Steps {
+		if step.Err != nil {
+			return step.Err
+		}
+	}
+	return nil
+}
+
+// TestConnection resolves the host, connects, pings and runs SELECT 1 against a
+// throwaway connection, stopping at the first failing step. Each step is bounded by timeout.
+func TestConnection(config *ConnectionConfig, timeout time.Duration) *ConnectionTestReport {
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+	report := &ConnectionTestReport{}
+
+	record := func(name, detail string, start time.Time, err error) bool {
+		report.Steps = append(report.Steps, TestStep{Name: name, Detail: detail, Duration: time.Since(start), Err: err})
+		if err != nil {
+			report.Failure = ClassifyConnectionError(err)
+			return false
+		}
+		return true
+	}
+
+	if !config.DatabaseType.IsFileBased() && config.Host != "" && net.ParseIP(config.Host) == nil {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		addrs, err := net.DefaultResolver.LookupHost(ctx, config.Host)
+		cancel()
+		if !record(StepResolve, strings.Join(addrs, ", "), start, err) {
+			return report
+		}
+	}
+
+	detail := fmt.Sprintf("%s:%d", config.Host, config.Port)
+	start := time.Now()
+	if config.DatabaseType.IsFileBased() {
+		// Pinging a missing database file silently creates it, so check it exists first
+		detail = config.Database
+		if _, err := os.Stat(config.Database); err != nil {
+			record(StepConnect, detail, start, err)
+			return report
+		}
+	}
+
+	conn, err := NewConnection(config)
+	if err != nil {
+		record(StepConnect, detail, start, err)
+		return report
+	}
+	defer conn.Close()
+	db := conn.(*connection).db
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	err = db.PingContext(ctx)
+	cancel()
+	if !record(StepConnect, detail, start, err) {
+		return report
+	}
+
+	start = time.Now()
+	ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	var one int
+	err = db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	cancel()
+	record(StepQuery, "SELECT 1", start, err)
+
+	return report
+}
+
+// ClassifyConnectionError maps driver and network errors to a FailureKind
+func ClassifyConnectionError(err error) FailureKind {
+	if err == nil {
+		return FailureNone
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return FailureDNS
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return FailureTimeout
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "28P01", "28000":
+			return FailureAuth
+		case "42501":
+			return FailurePermission
+		case "3D000":
+			return FailureDatabase
+		}
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1045:
+			return FailureAuth
+		case 1044, 1142, 1227:
+			return FailurePermission
+		case 1049:
+			return FailureDatabase
+		}
+	}
+
+	var recordErr tls.RecordHeaderError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certErr x509.CertificateInvalidError
+	if errors.As(err, &recordErr) || errors.As(err, &unknownAuthority) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &certErr) {
+		return FailureTLS
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		return FailureDatabase
+	}
+	if errors.Is(err, os.ErrPermission) {
+		return FailurePermission
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return FailureTimeout
+		}
+		return FailureNetwork
+	}
+
+	// Fall back to message matching for drivers that return plain errors
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "ssl") || strings.Contains(msg, "tls") || strings.Contains(msg, "certificate"):
+		return FailureTLS
+	case strings.Contains(msg, "password authentication failed") || strings.Contains(msg, "access denied"):
+		return FailureAuth
+	case strings.Contains(msg, "permission denied") || strings.Contains(msg, "readonly database"):
+		return FailurePermission
+	case strings.Contains(msg, "unable to open database file"):
+		return FailureDatabase
+	case strings.Contains(msg, "connection refused") || strings.Contains(msg, "no route to host"):
+		return FailureNetwork
+	}
+
+	return FailureUnknown
+}
+
+// FormatConnectionTestReport renders a report as one line per step, followed by a
+// hint for the failure category
+func FormatConnectionTestReport(report *ConnectionTestReport, i18nMgr *i18n.Manager) string {
+	var sb strings.Builder
+	for _, step := range report.Steps {
+		label := i18nMgr.Get("test_step_" + step.Name)
+		elapsed := step.Duration.Round(time.Millisecond)
+		if step.Err != nil {
+			sb.WriteString(i18nMgr.GetWithArgs("test_step_failed", label, elapsed, step.Err))
+			continue
+		}
+		sb.WriteString(i18nMgr.GetWithArgs("test_step_passed", label, step.Detail, elapsed))
+	}
+
+	if report.OK() {
+		sb.WriteString(i18nMgr.Get("connection_test_passed"))
+	} else {
+		sb.WriteString(i18nMgr.Get("test_hint_" + string(report.Failure)))
+	}
+	return sb.String()
+}
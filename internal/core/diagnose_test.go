@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestClassifyConnectionError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected FailureKind
+	}{
+		{name: "No error", err: nil, expected: FailureNone},
+		{name: "DNS", err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "db.invalid"}}, expected: FailureDNS},
+		{name: "Timeout", err: fmt.Errorf("ping: %w", context.DeadlineExceeded), expected: FailureTimeout},
+		{name: "Postgres auth", err: &pq.Error{Code: "28P01"}, expected: FailureAuth},
+		{name: "Postgres missing database", err: &pq.Error{Code: "3D000"}, expected: FailureDatabase},
+		{name: "MySQL access denied", err: &mysql.MySQLError{Number: 1045}, expected: FailureAuth},
+		{name: "MySQL database privilege", err: &mysql.MySQLError{Number: 1044}, expected: FailurePermission},
+		{name: "Connection refused", err: &net.OpError{Op: "dial", Err: errors.New("connect: connection refused")}, expected: FailureNetwork},
+		{name: "SSL message", err: errors.New("pq: SSL is not enabled on the server"), expected: FailureTLS},
+		{name: "Other", err: errors.New("boom"), expected: FailureUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ClassifyConnectionError(tc.err); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTestConnection(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	conn, err := NewConnection(&ConnectionConfig{Name: "setup", DatabaseType: SQLite, Database: dbPath})
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	if err := conn.Ping(); err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	conn.Close()
+
+	report := TestConnection(&ConnectionConfig{Name: "ok", DatabaseType: SQLite, Database: dbPath}, time.Second)
+	if !report.OK() {
+		t.Fatalf("Expected test to pass, got %v", report.Err())
+	}
+	if len(report.Steps) != 2 || report.Steps[1].Name != StepQuery {
+		t.Errorf("Expected connect and query steps, got %+v", report.Steps)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.db")
+	report = TestConnection(&ConnectionConfig{Name: "missing", DatabaseType: SQLite, Database: missing}, time.Second)
+	if report.OK() || report.Failure != FailureDatabase {
+		t.Errorf("Expected database failure, got %q", report.Failure)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Error("Testing a missing SQLite file should not create it")
+	}
+}
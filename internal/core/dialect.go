@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DialectIssue is a construct in a query the target database does not support
+type DialectIssue struct {
+	Construct   string // What was found, e.g. ILIKE
+	Alternative string // What the dialect offers instead
+}
+
+// dialectRule flags a construct in the dialects that have an alternative listed for it.
+// Server flavors override their database type's entry; an empty alternative there means
+// the flavor supports the construct.
+type dialectRule struct {
+	pattern      *regexp.Regexp
+	construct    string
+	alternatives map[DatabaseType]string
+	flavors      map[string]string
+}
+
+var dialectRules = []dialectRule{
+	{regexp.MustCompile(`(?i)\bILIKE\b`), "ILIKE", map[DatabaseType]string{
+		MySQL: "LIKE (case-insensitive with the default collation)", SQLite: "LIKE (case-insensitive for ASCII)"}, nil},
+	{regexp.MustCompile(`::\s*[A-Za-z]`), ":: casts", map[DatabaseType]string{
+		MySQL: "CAST(expr AS type)", SQLite: "CAST(expr AS type)"}, nil},
+	{regexp.MustCompile(`(?i)\bSELECT\s+(?:DISTINCT\s+)?TOP\s*\(?\s*\d+`), "SELECT TOP n", map[DatabaseType]string{
+		MySQL: "LIMIT n", PostgreSQL: "LIMIT n", SQLite: "LIMIT n", DuckDB: "LIMIT n", ClickHouse: "LIMIT n"}, nil},
+	{regexp.MustCompile("`"), "backtick quoting", map[DatabaseType]string{
+		PostgreSQL: `double-quoted identifiers`, DuckDB: `double-quoted identifiers`}, nil},
+	{regexp.MustCompile(`(?i)\bLIMIT\s+\d+\s*,\s*\d+`), "LIMIT offset, count", map[DatabaseType]string{
+		PostgreSQL: "LIMIT count OFFSET offset"}, nil},
+	{regexp.MustCompile(`(?i)\bIFNULL\s*\(`), "IFNULL()", map[DatabaseType]string{
+		PostgreSQL: "COALESCE()"}, nil},
+	{regexp.MustCompile(`(?i)\bGROUP_CONCAT\s*\(`), "GROUP_CONCAT()", map[DatabaseType]string{
+		PostgreSQL: "STRING_AGG()"}, nil},
+	{regexp.MustCompile(`(?i)\bSTRING_AGG\s*\(`), "STRING_AGG()", map[DatabaseType]string{
+		MySQL: "GROUP_CONCAT()"}, nil},
+	{regexp.MustCompile(`(?i)\bDATE_FORMAT\s*\(`), "DATE_FORMAT()", map[DatabaseType]string{
+		PostgreSQL: "TO_CHAR()", SQLite: "strftime()", DuckDB: "strftime()"}, nil},
+	{regexp.MustCompile(`(?i)\bDATE_TRUNC\s*\(`), "DATE_TRUNC()", map[DatabaseType]string{
+		MySQL: "DATE_FORMAT()", SQLite: "strftime() or date()"}, nil},
+	{regexp.MustCompile(`(?i)\bNOW\s*\(\s*\)`), "NOW()", map[DatabaseType]string{
+		SQLite: "datetime('now')"}, nil},
+	{regexp.MustCompile(`(?i)\bFULL\s+(?:OUTER\s+)?JOIN\b`), "FULL OUTER JOIN", map[DatabaseType]string{
+		MySQL: "a UNION of LEFT and RIGHT JOIN"}, nil},
+	{regexp.MustCompile(`(?i)\bNULLS\s+(?:FIRST|LAST)\b`), "NULLS FIRST/LAST", map[DatabaseType]string{
+		MySQL: "ORDER BY col IS NULL, col"}, nil},
+	{regexp.MustCompile(`(?i)\bRETURNING\b`), "RETURNING", map[DatabaseType]string{
+		MySQL: "a separate SELECT after the statement", ClickHouse: "a separate SELECT after the statement"},
+		map[string]string{FlavorMariaDB: ""}},
+	{regexp.MustCompile(`->>?`), "-> JSON operators", nil, map[string]string{
+		FlavorMariaDB: "JSON_VALUE() or JSON_EXTRACT()"}},
+	{regexp.MustCompile(`(?i)\bAUTO_INCREMENT\b`), "AUTO_INCREMENT", map[DatabaseType]string{
+		PostgreSQL: "GENERATED ALWAYS AS IDENTITY", SQLite: "INTEGER PRIMARY KEY",
+		DuckDB: "a SEQUENCE with DEFAULT nextval()"}, nil},
+}
+
+// literalPattern matches string literals and comments, whose content is not SQL syntax
+var literalPattern = regexp.MustCompile(`(?s)'(?:[^']|'')*'|--[^\n]*|/\*.*?\*/`)
+
+// ValidateDialect flags constructs in a query that the database type does not support,
+// such as ILIKE on MySQL or backtick quoting on PostgreSQL. The flavor, from
+// ServerFlavor, refines the check for MySQL-compatible servers. It is a heuristic meant
+// for generated SQL; an empty result does not mean the query is valid.
+func ValidateDialect(query string, dbType DatabaseType, flavor string) []DialectIssue {
+	code := literalPattern.ReplaceAllString(query, "''")
+
+	var issues []DialectIssue
+	for _, rule := range dialectRules {
+		alternative, ok := rule.alternatives[dbType]
+		if flavorAlternative, found := rule.flavors[flavor]; found && flavor != "" {
+			alternative, ok = flavorAlternative, flavorAlternative != ""
+		}
+		if ok && rule.pattern.MatchString(code) {
+			issues = append(issues, DialectIssue{Construct: rule.construct, Alternative: alternative})
+		}
+	}
+	return issues
+}
+
+// ServerVersion returns the version string the database server reports
+func ServerVersion(conn Connection, dbType DatabaseType) (string, error) {
+	var query string
+	switch dbType {
+	case MySQL:
+		query = "SELECT VERSION()"
+	case PostgreSQL:
+		query = "SHOW server_version"
+	case SQLite:
+		query = "SELECT sqlite_version()"
+	case DuckDB, ClickHouse:
+		query = "SELECT version()"
+	default:
+		return "", fmt.Errorf("unsupported database type: %v", dbType)
+	}
+
+	rows, err := queryStrings(conn, query)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return "", fmt.Errorf("no version returned")
+	}
+	return rows[0][0], nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateDialect(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		dbType   DatabaseType
+		flavor   string
+		expected []string
+	}{
+		{
+			name:     "ILIKE on MySQL",
+			query:    "SELECT * FROM users WHERE name ILIKE 'a%'",
+			dbType:   MySQL,
+			expected: []string{"ILIKE"},
+		},
+		{
+			name:     "ILIKE on PostgreSQL is fine",
+			query:    "SELECT * FROM users WHERE name ILIKE 'a%'",
+			dbType:   PostgreSQL,
+			expected: nil,
+		},
+		{
+			name:     "MySQL style on PostgreSQL",
+			query:    "SELECT `name`, IFNULL(age, 0) FROM users LIMIT 10, 20",
+			dbType:   PostgreSQL,
+			expected: []string{"backtick quoting", "LIMIT offset, count", "IFNULL()"},
+		},
+		{
+			name:     "PostgreSQL functions on SQLite",
+			query:    "SELECT date_trunc('day', created_at)::date, now() FROM orders",
+			dbType:   SQLite,
+			expected: []string{":: casts", "DATE_TRUNC()", "NOW()"},
+		},
+		{
+			name:     "TOP anywhere",
+			query:    "SELECT TOP 5 * FROM users",
+			dbType:   SQLite,
+			expected: []string{"SELECT TOP n"},
+		},
+		{
+			name:     "RETURNING on MySQL",
+			query:    "DELETE FROM users WHERE id = 1 RETURNING id",
+			dbType:   MySQL,
+			expected: []string{"RETURNING"},
+		},
+		{
+			name:     "RETURNING on MariaDB is fine",
+			query:    "DELETE FROM users WHERE id = 1 RETURNING id",
+			dbType:   MySQL,
+			flavor:   FlavorMariaDB,
+			expected: nil,
+		},
+		{
+			name:     "JSON operators on MariaDB",
+			query:    "SELECT doc->>'$.name' FROM users",
+			dbType:   MySQL,
+			flavor:   FlavorMariaDB,
+			expected: []string{"-> JSON operators"},
+		},
+		{
+			name:     "JSON operators on MySQL are fine",
+			query:    "SELECT doc->>'$.name' FROM users",
+			dbType:   MySQL,
+			expected: nil,
+		},
+		{
+			name:     "constructs inside literals and comments are ignored",
+			query:    "SELECT 'it''s `quoted` ILIKE' AS note -- uses ILIKE\nFROM users /* FULL JOIN */",
+			dbType:   MySQL,
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var constructs []string
+			for _, issue := range ValidateDialect(tc.query, tc.dbType, tc.flavor) {
+				constructs = append(constructs, issue.Construct)
+			}
+			if !reflect.DeepEqual(constructs, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, constructs)
+			}
+		})
+	}
+}
+
+func TestServerFlavor(t *testing.T) {
+	testCases := []struct {
+		dbType   DatabaseType
+		version  string
+		expected string
+	}{
+		{MySQL, "8.0.36", ""},
+		{MySQL, "10.11.6-MariaDB-0+deb12u1", FlavorMariaDB},
+		{MySQL, "5.5.5-10.6.12-MariaDB-log", FlavorMariaDB},
+		{MySQL, "8.0.11-TiDB-v7.5.0", FlavorTiDB},
+		{PostgreSQL, "16.2", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := ServerFlavor(tc.dbType, tc.version); got != tc.expected {
+			t.Errorf("ServerFlavor(%v, %q) = %q, expected %q", tc.dbType, tc.version, got, tc.expected)
+		}
+	}
+}
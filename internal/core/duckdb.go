@@ -0,0 +1,289 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// duckdbCommand is the DuckDB command line shell. DuckDB is driven through it rather
+// than cgo bindings, so sqlterm builds without the DuckDB library and any installed
+// DuckDB version works.
+const duckdbCommand = "duckdb"
+
+// duckdbSourceExtensions are files DuckDB can query directly. Opening one starts an
+// in-memory database with the file as a view named after it.
+var duckdbSourceExtensions = []string{".csv", ".tsv", ".parquet", ".json", ".ndjson", ".jsonl"}
+
+// duckdbDescribeKeywords start queries DESCRIBE accepts, which is how result columns and
+// their types are found even when no rows come back
+var duckdbDescribeKeywords = []string{"SELECT", "WITH", "VALUES", "TABLE", "FROM"}
+
+func init() {
+	sql.Register("duckdb", &duckdbDriver{})
+}
+
+// duckdbDSN is the data source name for a DuckDB connection: the database or data file,
+// with a -readonly flag prefix for read-only connections
+func duckdbDSN(config *ConnectionConfig) string {
+	if config.ReadOnly {
+		return "-readonly " + config.Database
+	}
+	return config.Database
+}
+
+// duckdbDriver runs each statement in a new duckdb process with JSON output. Nothing
+// but the database file is kept between statements, so temporary tables, SET options
+// and transactions do not carry over.
+type duckdbDriver struct{}
+
+func (d *duckdbDriver) Open(name string) (driver.Conn, error) {
+	path, readOnly := strings.CutPrefix(name, "-readonly ")
+	return &duckdbConn{path: path, readOnly: readOnly}, nil
+}
+
+type duckdbConn struct {
+	path     string
+	readOnly bool
+}
+
+// isSource reports whether the connection opens a data file rather than a database
+func (c *duckdbConn) isSource() bool {
+	return slices.Contains(duckdbSourceExtensions, strings.ToLower(filepath.Ext(c.path)))
+}
+
+// run executes statements and decodes each result set the shell prints
+func (c *duckdbConn) run(ctx context.Context, statements string) ([]duckdbResult, error) {
+	binary, err := exec.LookPath(duckdbCommand)
+	if err != nil {
+		return nil, fmt.Errorf("the duckdb command line shell is needed for DuckDB connections; install it from https://duckdb.org: %w", err)
+	}
+
+	args := []string{"-json", "-bail"}
+	if c.isSource() {
+		view := strings.TrimSuffix(filepath.Base(c.path), filepath.Ext(c.path))
+		statements = fmt.Sprintf("CREATE VIEW %s AS SELECT * FROM %s;\n%s",
+			QuoteIdentifier(DuckDB, view), QuoteLiteral(DuckDB, c.path), statements)
+	} else {
+		if c.readOnly {
+			args = append(args, "-readonly")
+		}
+		args = append(args, c.path)
+	}
+	args = append(args, "-c", statements)
+
+	cmd := exec.CommandContext(ctx, binary, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return decodeDuckDBOutput(&stdout)
+}
+
+func (c *duckdbConn) Prepare(query string) (driver.Stmt, error) {
+	return &duckdbStmt{conn: c, query: query}, nil
+}
+
+func (c *duckdbConn) Close() error {
+	return nil
+}
+
+func (c *duckdbConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported on DuckDB connections: each statement runs in its own duckdb process")
+}
+
+// Ping checks that the shell is installed and can open the database
+func (c *duckdbConn) Ping(ctx context.Context) error {
+	_, err := c.run(ctx, "SELECT 1;")
+	return err
+}
+
+type duckdbStmt struct {
+	conn  *duckdbConn
+	query string
+}
+
+func (s *duckdbStmt) Close() error {
+	return nil
+}
+
+// NumInput is zero because the shell cannot bind parameters
+func (s *duckdbStmt) NumInput() int {
+	return 0
+}
+
+func (s *duckdbStmt) Exec(args []driver.Value) (driver.Result, error) {
+	results, err := s.conn.run(context.Background(), strings.TrimSpace(s.query))
+	if err != nil {
+		return nil, err
+	}
+	// Writes print their row count as a single Count column
+	for _, result := range results {
+		if len(result.columns) == 1 && result.columns[0] == "Count" && len(result.rows) == 1 {
+			if count, ok := result.rows[0][0].(int64); ok {
+				return driver.RowsAffected(count), nil
+			}
+		}
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s *duckdbStmt) Query(args []driver.Value) (driver.Rows, error) {
+	query := strings.TrimSuffix(strings.TrimSpace(s.query), ";")
+	describe := slices.Contains(duckdbDescribeKeywords, leadingKeyword(query))
+	statements := query + ";"
+	if describe {
+		statements = "DESCRIBE " + query + ";\n" + statements
+	}
+
+	results, err := s.conn.run(context.Background(), statements)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := &duckdbRows{}
+	if describe && len(results) > 0 {
+		for _, column := range results[0].rows {
+			name, _ := column[0].(string)
+			typeName, _ := column[1].(string)
+			rows.columns = append(rows.columns, name)
+			rows.types = append(rows.types, typeName)
+		}
+		results = results[1:]
+	}
+	if len(results) > 0 {
+		// Statements DESCRIBE cannot take name their columns in the output
+		last := results[len(results)-1]
+		if rows.columns == nil {
+			rows.columns = last.columns
+			rows.types = make([]string, len(last.columns))
+		}
+		rows.rows = last.rows
+	}
+	return rows, nil
+}
+
+// duckdbResult is one result set printed by the shell
+type duckdbResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// decodeDuckDBOutput reads the JSON arrays the shell prints, one per result set. Object
+// keys are read in order, since they are the result's columns.
+func decodeDuckDBOutput(r io.Reader) ([]duckdbResult, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	var results []duckdbResult
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return results, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read duckdb output: %w", err)
+		}
+		if tok != json.Delim('[') {
+			return nil, fmt.Errorf("unexpected duckdb output %v", tok)
+		}
+
+		var result duckdbResult
+		for dec.More() {
+			if tok, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("failed to read duckdb row: %w", err)
+			} else if tok != json.Delim('{') {
+				return nil, fmt.Errorf("unexpected duckdb row %v", tok)
+			}
+			var row []driver.Value
+			var columns []string
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, fmt.Errorf("failed to read duckdb column: %w", err)
+				}
+				var value any
+				if err := dec.Decode(&value); err != nil {
+					return nil, fmt.Errorf("failed to read duckdb value: %w", err)
+				}
+				columns = append(columns, key.(string))
+				row = append(row, jsonDriverValue(value))
+			}
+			if _, err := dec.Token(); err != nil {
+				return nil, fmt.Errorf("failed to read duckdb row: %w", err)
+			}
+			if result.columns == nil {
+				result.columns = columns
+			}
+			result.rows = append(result.rows, row)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to read duckdb output: %w", err)
+		}
+		results = append(results, result)
+	}
+}
+
+// jsonDriverValue converts a decoded JSON value to a driver value. Lists and structs
+// are kept as their JSON text.
+func jsonDriverValue(value any) driver.Value {
+	switch v := value.(type) {
+	case nil, string, bool:
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if !strings.ContainsAny(v.String(), ".eE") {
+			// Integers beyond int64, such as large UInt64 values, keep every digit
+			return v.String()
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+type duckdbRows struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+	next    int
+}
+
+func (r *duckdbRows) Columns() []string {
+	return r.columns
+}
+
+func (r *duckdbRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index]
+}
+
+func (r *duckdbRows) Close() error {
+	return nil
+}
+
+func (r *duckdbRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
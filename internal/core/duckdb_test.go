@@ -0,0 +1,110 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDecodeDuckDBOutput(t *testing.T) {
+	output := `[{"id":1,"name":"a","score":1.5,"tags":["x","y"],"note":null},
+{"id":2,"name":"b","score":2,"tags":[],"note":"ok"}]
+[{"Count":3}]
+`
+	results, err := decodeDuckDBOutput(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 result sets, got %d", len(results))
+	}
+
+	first := results[0]
+	if strings.Join(first.columns, ",") != "id,name,score,tags,note" {
+		t.Errorf("Expected columns in output order, got %v", first.columns)
+	}
+	if len(first.rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(first.rows))
+	}
+	row := first.rows[0]
+	if row[0] != int64(1) || row[1] != "a" || row[2] != 1.5 || row[3] != `["x","y"]` || row[4] != nil {
+		t.Errorf("Unexpected row values %#v", row)
+	}
+
+	if _, err := decodeDuckDBOutput(strings.NewReader("Error: not json")); err == nil {
+		t.Error("Expected an error for output that is not JSON")
+	}
+}
+
+// fakeDuckDB puts a duckdb script first on PATH that prints output and records its arguments
+func fakeDuckDB(t *testing.T, output string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake duckdb shell is a sh script")
+	}
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + argsFile + "\ncat <<'OUT'\n" + output + "\nOUT\n"
+	if err := os.WriteFile(filepath.Join(dir, "duckdb"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return argsFile
+}
+
+func TestDuckDBConnection_Execute(t *testing.T) {
+	argsFile := fakeDuckDB(t, `[{"column_name":"region","column_type":"VARCHAR","null":"YES","key":null,"default":null,"extra":null},
+{"column_name":"total","column_type":"BIGINT","null":"YES","key":null,"default":null,"extra":null}]
+[{"region":"north","total":42}]`)
+
+	conn, err := NewConnection(&ConnectionConfig{DatabaseType: DuckDB, Database: "sales.csv", StatementCacheSize: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	result, err := conn.Execute("SELECT region, sum(amount) AS total FROM sales GROUP BY region")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	if len(result.Columns) != 2 || result.Columns[1].Name != "total" || result.Columns[1].Type != "BIGINT" {
+		t.Errorf("Expected columns from DESCRIBE, got %+v", result.Columns)
+	}
+	var rows [][]Value
+	if err := result.ForEachRow(func(row []Value) error {
+		rows = append(rows, row)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0][0].String() != "north" || rows[0][1].String() != "42" {
+		t.Errorf("Unexpected rows %v", rows)
+	}
+
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), `CREATE VIEW "sales" AS SELECT * FROM 'sales.csv';`) {
+		t.Errorf("Expected the CSV file to be opened as a view, got args:\n%s", args)
+	}
+	if !strings.Contains(string(args), "DESCRIBE SELECT region") {
+		t.Errorf("Expected the query to be described for its columns, got args:\n%s", args)
+	}
+}
+
+func TestDuckDBConnection_MissingShell(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	conn, err := NewConnection(&ConnectionConfig{DatabaseType: DuckDB, Database: "analytics.duckdb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.Ping(); err == nil || !strings.Contains(err.Error(), "duckdb") {
+		t.Errorf("Expected an error naming the duckdb shell, got %v", err)
+	}
+}
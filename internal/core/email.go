@@ -0,0 +1,189 @@
+package core
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default templates for emailed results
+const (
+	DefaultEmailSubject = `sqlterm: {{ .File }} ({{ .Rows }} rows)`
+	DefaultEmailBody    = `{{ .Rows }} rows from {{ .Connection }} in {{ .Duration }}, attached as {{ .File }}.
+
+{{ .Query }}
+`
+)
+
+// smtpImplicitTLSPort is the submission port that starts with TLS rather than STARTTLS
+const smtpImplicitTLSPort = 465
+
+// SMTPSettings is the mail server results are emailed through
+type SMTPSettings struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// EmailReport is what the subject and body templates of an emailed result refer to
+type EmailReport struct {
+	Query      string
+	Rows       int
+	Duration   time.Duration
+	File       string // Attached file names, comma separated
+	Connection string
+}
+
+// RenderEmail fills in the subject and body templates, using the defaults for empty ones
+func RenderEmail(subjectTemplate, bodyTemplate string, report EmailReport) (string, string, error) {
+	report.Duration = report.Duration.Round(time.Millisecond)
+	render := func(text, fallback string) (string, error) {
+		if text == "" {
+			text = fallback
+		}
+		tmpl, err := template.New("email").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return "", err
+		}
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, report); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+	subject, err := render(subjectTemplate, DefaultEmailSubject)
+	if err != nil {
+		return "", "", fmt.Errorf("subject template: %w", err)
+	}
+	body, err := render(bodyTemplate, DefaultEmailBody)
+	if err != nil {
+		return "", "", fmt.Errorf("body template: %w", err)
+	}
+	// Header values cannot span lines
+	return strings.Join(strings.Fields(subject), " "), body, nil
+}
+
+// ComposeEmail builds a MIME message with a plain text body and the files attached
+func ComposeEmail(from string, to []string, subject, body string, attachments []string) ([]byte, error) {
+	var buf bytes.Buffer
+	message := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", message.Boundary())
+
+	text, err := message.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(text)
+	qp.Write([]byte(body))
+	qp.Close()
+
+	for _, path := range attachments {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(path)
+		contentType := mime.TypeByExtension(filepath.Ext(name))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := message.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(data)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	if err := message.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SendEmail delivers a message through an SMTP server, with TLS from the start on port
+// 465 and STARTTLS when the server offers it on other ports
+func SendEmail(settings SMTPSettings, to []string, message []byte) error {
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(settings.Port))
+	tlsConfig := &tls.Config{ServerName: settings.Host}
+
+	var client *smtp.Client
+	if settings.Port == smtpImplicitTLSPort {
+		conn, err := tls.Dial("tcp", addr, tlsConfig)
+		if err != nil {
+			return err
+		}
+		if client, err = smtp.NewClient(conn, settings.Host); err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		var err error
+		if client, err = smtp.Dial(addr); err != nil {
+			return err
+		}
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return err
+			}
+		}
+	}
+	defer client.Close()
+
+	if settings.Username != "" {
+		// PlainAuth refuses to send the password without TLS, except to localhost
+		if err := client.Auth(smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(settings.From); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("%s: %w", recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
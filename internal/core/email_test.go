@@ -0,0 +1,131 @@
+package core
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderEmail(t *testing.T) {
+	report := EmailReport{Query: "SELECT * FROM orders", Rows: 42, Duration: 1234567 * time.Microsecond, File: "orders.xlsx", Connection: "prod"}
+	subject, body, err := RenderEmail("", "", report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "sqlterm: orders.xlsx (42 rows)" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.HasPrefix(body, "42 rows from prod in 1.235s, attached as orders.xlsx.") || !strings.Contains(body, "SELECT * FROM orders") {
+		t.Errorf("Unexpected body %q", body)
+	}
+
+	subject, _, err = RenderEmail("Weekly\n{{ .Rows }} orders", "", report)
+	if err != nil || subject != "Weekly 42 orders" {
+		t.Errorf("Expected a one line subject, got %q, %v", subject, err)
+	}
+	if _, _, err := RenderEmail("{{ .Missing }}", "", report); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
+
+func TestComposeAndSendEmail(t *testing.T) {
+	attachment := filepath.Join(t.TempDir(), "orders.csv")
+	if err := os.WriteFile(attachment, []byte("id,total\n1,9.5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	message, err := ComposeEmail("sqlterm@example.com", []string{"team@example.com"}, "Orders – daily", "Rows: 1\n", []string{attachment})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(message)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); subject != "Orders – daily" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	_, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	text, _ := parts.NextPart()
+	if body, _ := io.ReadAll(text); string(body) != "Rows: 1\r\n" {
+		t.Errorf("Unexpected body %q", body)
+	}
+	file, _ := parts.NextPart()
+	if data, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, file)); file.FileName() != "orders.csv" || string(data) != "id,total\n1,9.5\n" {
+		t.Errorf("Unexpected attachment %q: %q", file.FileName(), data)
+	}
+
+	// A minimal SMTP server that records the envelope and message
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		var lines []string
+		reply("220 localhost ready")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				received <- lines
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			command := strings.ToUpper(strings.Fields(line + " x")[0])
+			switch command {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 go ahead")
+				for {
+					data, _ := r.ReadString('\n')
+					if data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	settings := SMTPSettings{Host: "127.0.0.1", Port: addr.Port, From: "sqlterm@example.com"}
+	if err := SendEmail(settings, []string{"team@example.com"}, message); err != nil {
+		t.Fatal(err)
+	}
+	lines := <-received
+	if len(lines) < 3 || lines[0] != "MAIL FROM:<sqlterm@example.com>" || lines[1] != "RCPT TO:<team@example.com>" {
+		t.Errorf("Unexpected envelope %v", lines)
+	}
+	if !strings.Contains(strings.Join(lines, "\n"), `filename=orders.csv`) {
+		t.Errorf("Expected the attachment to be sent, got %v", lines)
+	}
+}
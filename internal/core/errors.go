@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"sqlterm/internal/i18n"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// ErrorKind classifies why a statement or connection failed, independent of the driver
+type ErrorKind string
+
+const (
+	ErrorAuth          ErrorKind = "auth"
+	ErrorUnknownColumn ErrorKind = "unknown_column"
+	ErrorUnknownTable  ErrorKind = "unknown_table"
+	ErrorSyntax        ErrorKind = "syntax"
+	ErrorTimeout       ErrorKind = "timeout"
+	ErrorPermission    ErrorKind = "permission"
+	ErrorConnection    ErrorKind = "connection"
+	ErrorOther         ErrorKind = "other"
+)
+
+// QueryError is a driver error with its kind and the dialect that raised it, so it can
+// be explained with a hint for that database
+type QueryError struct {
+	Kind    ErrorKind
+	Dialect DatabaseType
+	Err     error
+}
+
+func (e *QueryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Fixable reports whether the error is a mistake in the statement itself, which
+// rewriting it can fix
+func (e *QueryError) Fixable() bool {
+	switch e.Kind {
+	case ErrorSyntax, ErrorUnknownColumn, ErrorUnknownTable:
+		return true
+	}
+	return false
+}
+
+// Hint returns advice for the error, preferring a hint written for the dialect
+// (error_hint_<kind>_<dialect>) over the general one (error_hint_<kind>). It returns ""
+// when there is none.
+func (e *QueryError) Hint(i18nMgr *i18n.Manager) string {
+	for _, key := range []string{"error_hint_" + string(e.Kind) + "_" + e.Dialect.String(), "error_hint_" + string(e.Kind)} {
+		if i18nMgr.Has(key) {
+			return i18nMgr.Get(key)
+		}
+	}
+	return ""
+}
+
+// ClassifyError wraps a driver error in a QueryError. It returns nil for nil, and an
+// error that is already a QueryError as it is.
+func ClassifyError(err error, dialect DatabaseType) error {
+	if err == nil {
+		return nil
+	}
+	var queryErr *QueryError
+	if errors.As(err, &queryErr) {
+		return err
+	}
+	return &QueryError{Kind: errorKind(err), Dialect: dialect, Err: err}
+}
+
+func errorKind(err error) ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrorTimeout
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == "42703":
+			return ErrorUnknownColumn
+		case pqErr.Code == "42P01":
+			return ErrorUnknownTable
+		case pqErr.Code == "42601":
+			return ErrorSyntax
+		case pqErr.Code == "57014":
+			// query_canceled, which statement_timeout raises
+			return ErrorTimeout
+		case pqErr.Code == "42501":
+			return ErrorPermission
+		case pqErr.Code.Class() == "28":
+			return ErrorAuth
+		case pqErr.Code.Class() == "08":
+			return ErrorConnection
+		}
+		return ErrorOther
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1054:
+			return ErrorUnknownColumn
+		case 1146:
+			return ErrorUnknownTable
+		case 1064, 1149:
+			return ErrorSyntax
+		case 3024, 1969:
+			// max_execution_time (MySQL) and max_statement_time (MariaDB) exceeded
+			return ErrorTimeout
+		case 1044, 1142, 1143, 1227:
+			return ErrorPermission
+		case 1045:
+			return ErrorAuth
+		}
+		return ErrorOther
+	}
+
+	// SQLite, DuckDB and ClickHouse errors are only told apart by their message
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "no such column") || strings.Contains(msg, "referenced column") ||
+		strings.Contains(msg, "missing columns") || strings.Contains(msg, "unknown_identifier"):
+		return ErrorUnknownColumn
+	case strings.Contains(msg, "no such table") || strings.Contains(msg, "unknown_table") ||
+		strings.Contains(msg, "catalog error: table with name"):
+		return ErrorUnknownTable
+	case strings.Contains(msg, "syntax error") || strings.Contains(msg, "parser error"):
+		return ErrorSyntax
+	case strings.Contains(msg, "timeout_exceeded") || strings.Contains(msg, "interrupted"):
+		return ErrorTimeout
+	case strings.Contains(msg, "authentication_failed"):
+		return ErrorAuth
+	case strings.Contains(msg, "access_denied") || strings.Contains(msg, "not authorized") ||
+		strings.Contains(msg, "readonly database"):
+		return ErrorPermission
+	}
+
+	switch ClassifyConnectionError(err) {
+	case FailureAuth:
+		return ErrorAuth
+	case FailurePermission:
+		return ErrorPermission
+	case FailureTimeout:
+		return ErrorTimeout
+	case FailureDNS, FailureNetwork, FailureTLS:
+		return ErrorConnection
+	}
+	return ErrorOther
+}
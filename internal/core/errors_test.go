@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/i18n"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestClassifyError(t *testing.T) {
+	testCases := []struct {
+		err     error
+		dialect DatabaseType
+		kind    ErrorKind
+		fixable bool
+	}{
+		{&pq.Error{Code: "42703", Message: `column "OrderID" does not exist`}, PostgreSQL, ErrorUnknownColumn, true},
+		{&pq.Error{Code: "42P01"}, PostgreSQL, ErrorUnknownTable, true},
+		{&pq.Error{Code: "57014"}, PostgreSQL, ErrorTimeout, false},
+		{&pq.Error{Code: "28P01"}, PostgreSQL, ErrorAuth, false},
+		{&mysql.MySQLError{Number: 1064}, MySQL, ErrorSyntax, true},
+		{&mysql.MySQLError{Number: 1142}, MySQL, ErrorPermission, false},
+		{errors.New("Code: 47. DB::Exception: Missing columns: 'totl'"), ClickHouse, ErrorUnknownColumn, true},
+		{fmt.Errorf("failed to execute query: %w", context.DeadlineExceeded), SQLite, ErrorTimeout, false},
+		{errors.New("disk I/O error"), SQLite, ErrorOther, false},
+	}
+	for _, tc := range testCases {
+		var queryErr *QueryError
+		if !errors.As(ClassifyError(tc.err, tc.dialect), &queryErr) {
+			t.Fatalf("ClassifyError(%v) is not a QueryError", tc.err)
+		}
+		if queryErr.Kind != tc.kind || queryErr.Fixable() != tc.fixable {
+			t.Errorf("ClassifyError(%v) = %s (fixable %v), want %s (fixable %v)", tc.err, queryErr.Kind, queryErr.Fixable(), tc.kind, tc.fixable)
+		}
+		if !errors.Is(queryErr, tc.err) || queryErr.Error() != tc.err.Error() {
+			t.Errorf("ClassifyError(%v) should keep the driver error and its message", tc.err)
+		}
+	}
+	if ClassifyError(nil, SQLite) != nil {
+		t.Error("ClassifyError(nil) should be nil")
+	}
+}
+
+func TestQueryErrorHint(t *testing.T) {
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn, "CREATE TABLE orders (id INTEGER)")
+	_, err = conn.Execute("SELECT totl FROM orders")
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Kind != ErrorUnknownColumn || queryErr.Dialect != SQLite {
+		t.Fatalf("Expected an unknown column error from SQLite, got %#v", err)
+	}
+	if hint := queryErr.Hint(i18nMgr); !strings.Contains(hint, "single quotes") {
+		t.Errorf("Expected the SQLite hint, got %q", hint)
+	}
+
+	// Dialects without their own hint get the general one
+	queryErr = &QueryError{Kind: ErrorUnknownColumn, Dialect: DuckDB}
+	if hint := queryErr.Hint(i18nMgr); !strings.Contains(hint, "case-sensitive in quoted identifiers") {
+		t.Errorf("Expected the general hint, got %q", hint)
+	}
+	if hint := (&QueryError{Kind: ErrorOther}).Hint(i18nMgr); hint != "" {
+		t.Errorf("Expected no hint for other errors, got %q", hint)
+	}
+}
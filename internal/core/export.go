@@ -20,34 +20,42 @@ func ToMarkdown(result *QueryResult, limit int, i18nMgr *i18n.Manager) string {
 	// Calculate column widths
 	widths := make([]int, len(result.Columns))
 	rowsToProcess := make([][]string, 0)
+	headers := result.DisplayColumnNames()
+	jsonColumns := make([]bool, len(result.Columns))
 	for i, col := range result.Columns {
-		widths[i] = len(col.Name)
+		widths[i] = DisplayWidth(headers[i])
+		jsonColumns[i] = IsJSONType(col.Type)
 	}
 
-	for row := range result.Itor() {
+	// Only the rows shown are kept; column widths need them all before the table is written
+	err := result.ForEachRow(func(row []Value) error {
 		line := make([]string, len(result.Columns))
 		rowsToProcess = append(rowsToProcess, line)
 		for i, val := range row {
-			if i < len(widths) && len(val.String()) > widths[i] {
-				widths[i] = len(val.String())
+			cell := val.String()
+			if i < len(jsonColumns) && jsonColumns[i] && !val.IsNull() {
+				cell = CompactJSON(cell)
 			}
-			line[i] = val.String()
+			if i < len(widths) {
+				widths[i] = max(widths[i], DisplayWidth(cell))
+			}
+			line[i] = cell
 		}
 		count++
 		if count >= limit {
-			break
+			return ErrStopRows
 		}
-	}
-
-	if result.Error() != nil {
-		sb.WriteString(fmt.Sprint(i18nMgr.Get("query_error"), result.Error()))
+		return nil
+	})
+	if err != nil {
+		sb.WriteString(fmt.Sprint(i18nMgr.Get("query_error"), err))
 		return sb.String()
 	}
 
 	// Write header
 	sb.WriteString("| ")
-	for i, col := range result.Columns {
-		sb.WriteString(fmt.Sprintf("%-*s", widths[i], col.Name))
+	for i, header := range headers {
+		sb.WriteString(PadRight(header, widths[i]))
 		if i < len(result.Columns)-1 {
 			sb.WriteString(" | ")
 		}
@@ -69,7 +77,7 @@ func ToMarkdown(result *QueryResult, limit int, i18nMgr *i18n.Manager) string {
 		sb.WriteString("| ")
 		for i, val := range row {
 			if i < len(widths) {
-				sb.WriteString(fmt.Sprintf("%-*s", widths[i], val))
+				sb.WriteString(PadRight(val, widths[i]))
 			}
 			if i < len(result.Columns)-1 {
 				sb.WriteString(" | ")
@@ -83,9 +91,38 @@ func ToMarkdown(result *QueryResult, limit int, i18nMgr *i18n.Manager) string {
 		sb.WriteString(fmt.Sprintf("\n%s\n", i18nMgr.GetWithArgs("markdown_truncation_note", limit)))
 	}
 
+	writeJSONDetails(&sb, result.Columns, jsonColumns, rowsToProcess, i18nMgr)
+
 	return sb.String()
 }
 
+// writeJSONDetails pretty-prints the leading values of JSON columns below the table,
+// since markdown table cells cannot hold multi-line documents
+func writeJSONDetails(sb *strings.Builder, columns []Column, jsonColumns []bool, rows [][]string, i18nMgr *i18n.Manager) {
+	const maxJSONRows = 3
+
+	for i, col := range columns {
+		if !jsonColumns[i] {
+			continue
+		}
+		shown := 0
+		for rowIdx, row := range rows {
+			if shown >= maxJSONRows {
+				break
+			}
+			pretty, ok := PrettyJSON(row[i])
+			if !ok {
+				continue
+			}
+			if shown == 0 {
+				sb.WriteString(fmt.Sprintf("\n#### %s\n", i18nMgr.GetWithArgs("markdown_json_column_header", col.Name)))
+			}
+			sb.WriteString(fmt.Sprintf("\n%s\n```json\n%s\n```\n", i18nMgr.GetWithArgs("markdown_json_row_label", rowIdx+1), pretty))
+			shown++
+		}
+	}
+}
+
 func SaveQueryResultAsMarkdown(result *QueryResult, query string, connection string, resultWriter io.Writer, i18nMgr *i18n.Manager) error {
 	// Format the SQL query for better readability
 	formatter := NewSQLFormatter()
@@ -157,25 +194,45 @@ func SaveQueryResultAsStreamingCSV(result *QueryResult, filePath string) (int, e
 	defer writer.Close()
 
 	// Write headers
-	if err := writer.WriteHeaders(result.ColumnNames()); err != nil {
+	if err := writer.WriteHeaders(result.DisplayColumnNames()); err != nil {
 		return count, fmt.Errorf("failed to write CSV headers: %w", err)
 	}
 
 	// Write rows one by one
-	for row := range result.Itor() {
+	err = result.ForEachRow(func(row []Value) error {
 		if err := writer.WriteRow(row); err != nil {
-			return count, fmt.Errorf("failed to write CSV row: %w", err)
+			return fmt.Errorf("failed to write CSV row: %w", err)
 		}
 		count++
-	}
-
-	if result.Error() != nil {
-		return count, fmt.Errorf("failed to fetch data: %w", err)
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
 	}
 
 	return count, nil
 }
 
+// SaveQueryResultToFile exports a result in the format implied by the file extension,
+// falling back to CSV
+func SaveQueryResultToFile(result *QueryResult, filePath string) (int, error) {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".geojson":
+		return SaveQueryResultAsGeoJSON(result, filePath)
+	case ".xlsx":
+		return SaveQueryResultAsXLSX(result, filePath)
+	case ".jsonl", ".ndjson":
+		return SaveQueryResultAsJSONL(result, filePath, false)
+	case ".md":
+		return SaveQueryResultAsMarkdownTable(result, filePath)
+	default:
+		return SaveQueryResultAsStreamingCSV(result, filePath)
+	}
+}
+
 // GenerateNumberedCSVPath creates a numbered CSV filename for multiple queries
 func GenerateNumberedCSVPath(baseFilePath string, queryIndex int) string {
 	dir := filepath.Dir(baseFilePath)
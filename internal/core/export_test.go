@@ -0,0 +1,102 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sqlterm/internal/i18n"
+	"strings"
+	"testing"
+)
+
+func TestSaveQueryResultToFileGeoJSON(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+
+	result, err := conn.Execute("SELECT 'Melbourne' AS name, 7 AS zone, '0101000020E6100000000000000000F03F0000000000000040' AS geom")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.geojson")
+	count, err := SaveQueryResultToFile(result, path)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 feature, got %d", count)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+
+	var collection struct {
+		Type     string `json:"type"`
+		Features []struct {
+			Geometry struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+			Properties map[string]any `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("Export is not valid JSON: %v\n%s", err, data)
+	}
+
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 1 {
+		t.Fatalf("Unexpected collection: %s", data)
+	}
+	feature := collection.Features[0]
+	if feature.Geometry.Type != "Point" || len(feature.Geometry.Coordinates) != 2 {
+		t.Errorf("Unexpected geometry: %+v", feature.Geometry)
+	}
+	if feature.Properties["name"] != "Melbourne" || feature.Properties["zone"] != float64(7) {
+		t.Errorf("Unexpected properties: %v", feature.Properties)
+	}
+	if _, ok := feature.Properties["geom"]; ok {
+		t.Error("Geometry column should not be repeated in properties")
+	}
+}
+
+func TestSaveQueryResultToFileGeoJSONWithoutGeometry(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+
+	result, err := conn.Execute("SELECT 1 AS id")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "out.geojson")
+	if _, err := SaveQueryResultToFile(result, path); !errors.Is(err, ErrNoGeometryColumn) {
+		t.Errorf("Expected ErrNoGeometryColumn, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected incomplete export file to be removed")
+	}
+}
+
+func TestToMarkdownAlignsWideCharacters(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	i18nMgr, err := i18n.NewManager("zh_cn")
+	if err != nil {
+		t.Fatalf("Failed to create i18n manager: %v", err)
+	}
+
+	result, err := conn.Execute("SELECT '北京' AS 城市, 'Beijing' AS name UNION ALL SELECT 'Sydney', '悉尼'")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(ToMarkdown(result, 10, i18nMgr)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, separator and 2 rows, got:\n%s", strings.Join(lines, "\n"))
+	}
+	for _, line := range lines {
+		if DisplayWidth(line) != DisplayWidth(lines[0]) {
+			t.Errorf("Misaligned line %q: width %d, header width %d", line, DisplayWidth(line), DisplayWidth(lines[0]))
+		}
+	}
+}
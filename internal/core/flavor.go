@@ -0,0 +1,60 @@
+package core
+
+import "strings"
+
+// Servers that speak the MySQL protocol but differ from MySQL in their SQL and catalog
+const (
+	FlavorMariaDB = "mariadb"
+	FlavorTiDB    = "tidb"
+)
+
+// ServerFlavor identifies a MySQL-compatible server from the version it reports, such as
+// 10.11.6-MariaDB or 8.0.11-TiDB-v7.5.0. It is empty for MySQL itself and for every other
+// database type.
+func ServerFlavor(dbType DatabaseType, version string) string {
+	if dbType != MySQL {
+		return ""
+	}
+	lower := strings.ToLower(version)
+	switch {
+	case strings.Contains(lower, "-mariadb"):
+		return FlavorMariaDB
+	case strings.Contains(lower, "-tidb-"):
+		return FlavorTiDB
+	default:
+		return ""
+	}
+}
+
+// FlavorOf returns the server flavor of a connection opened by NewConnection. The
+// version is asked for once per connection; an empty result means vanilla, unknown or
+// not a MySQL connection.
+func FlavorOf(conn Connection) string {
+	c, ok := conn.(*connection)
+	if !ok {
+		return ""
+	}
+	return c.serverFlavor()
+}
+
+func (c *connection) serverFlavor() string {
+	if c.config.DatabaseType != MySQL {
+		return ""
+	}
+	c.flavorOnce.Do(func() {
+		var version string
+		if err := c.db.QueryRow("SELECT VERSION()").Scan(&version); err == nil {
+			c.flavor = ServerFlavor(MySQL, version)
+		}
+	})
+	return c.flavor
+}
+
+// DialectName is the name of the connection's SQL dialect: the server flavor when there
+// is one, otherwise the database type
+func DialectName(dbType DatabaseType, flavor string) string {
+	if flavor != "" {
+		return flavor
+	}
+	return dbType.String()
+}
@@ -0,0 +1,75 @@
+package core
+
+import "fmt"
+
+// ValueFormatter converts scanned values into a display-friendly form.
+// Format returns false when the formatter does not handle the column or value.
+type ValueFormatter interface {
+	Format(col Column, val Value) (Value, bool)
+}
+
+// ColumnAnnotator is implemented by formatters that change how a column should be
+// labelled in headers, such as the time zone timestamps were converted to
+type ColumnAnnotator interface {
+	Annotate(col Column) string
+}
+
+// ValueFormatterFunc adapts a plain function to the ValueFormatter interface
+type ValueFormatterFunc func(col Column, val Value) (Value, bool)
+
+func (f ValueFormatterFunc) Format(col Column, val Value) (Value, bool) {
+	return f(col, val)
+}
+
+// DefaultValueFormatters returns the formatters applied to every query result
+func DefaultValueFormatters() []ValueFormatter {
+	return []ValueFormatter{
+		GeometryFormatter{},
+	}
+}
+
+// SetFormatters replaces the value formatters applied while iterating rows
+func (r *QueryResult) SetFormatters(formatters ...ValueFormatter) {
+	r.formatters = formatters
+}
+
+// AddFormatter appends a value formatter; earlier formatters take precedence
+func (r *QueryResult) AddFormatter(formatter ValueFormatter) {
+	r.formatters = append(r.formatters, formatter)
+}
+
+// DisplayColumnNames returns column names with any formatter annotations appended
+func (r *QueryResult) DisplayColumnNames() []string {
+	names := r.ColumnNames()
+	for i, col := range r.Columns {
+		for _, formatter := range r.formatters {
+			annotator, ok := formatter.(ColumnAnnotator)
+			if !ok {
+				continue
+			}
+			if note := annotator.Annotate(col); note != "" {
+				names[i] = fmt.Sprintf("%s (%s)", names[i], note)
+				break
+			}
+		}
+	}
+	return names
+}
+
+func (r *QueryResult) formatRow(columns []Column, row []Value) []Value {
+	if len(r.formatters) == 0 {
+		return row
+	}
+	for i, val := range row {
+		if i >= len(columns) || val.IsNull() {
+			continue
+		}
+		for _, formatter := range r.formatters {
+			if formatted, ok := formatter.Format(columns[i], val); ok {
+				row[i] = formatted
+				break
+			}
+		}
+	}
+	return row
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoGeometryColumn is returned when a GeoJSON export finds no spatial column
+var ErrNoGeometryColumn = errors.New("query result has no geometry column")
+
+// SaveQueryResultAsGeoJSON streams a result as a GeoJSON FeatureCollection. The first
+// geometry column becomes the feature geometry and the other columns become properties.
+func SaveQueryResultAsGeoJSON(result *QueryResult, filePath string) (int, error) {
+	count := 0
+	defer result.Close()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return count, fmt.Errorf("failed to create GeoJSON file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+
+	geomIndex := -1
+	for i, col := range result.Columns {
+		if IsGeometryType(col.Type) {
+			geomIndex = i
+			break
+		}
+	}
+
+	if _, err := writer.WriteString(`{"type":"FeatureCollection","features":[`); err != nil {
+		file.Close()
+		return count, err
+	}
+
+	err = result.ForEachRow(func(row []Value) error {
+		// Spatial columns without a type name are detected from the first decoded value
+		if geomIndex < 0 {
+			for i, val := range row {
+				if _, ok := val.(GeometryValue); ok {
+					geomIndex = i
+					break
+				}
+			}
+		}
+
+		feature := map[string]any{"type": "Feature", "geometry": nil}
+		properties := make(map[string]any, len(row))
+		for i, val := range row {
+			if i == geomIndex {
+				if geom, ok := val.(GeometryValue); ok {
+					feature["geometry"] = geom.Geometry.GeoJSON()
+				}
+				continue
+			}
+			if i < len(result.Columns) {
+				properties[result.Columns[i].Name] = geoJSONProperty(val)
+			}
+		}
+		feature["properties"] = properties
+
+		data, err := json.Marshal(feature)
+		if err != nil {
+			return fmt.Errorf("failed to encode feature: %w", err)
+		}
+		if count > 0 {
+			writer.WriteByte(',')
+		}
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		file.Close()
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+
+	writer.WriteString("]}\n")
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return count, err
+	}
+	if err := file.Close(); err != nil {
+		return count, err
+	}
+
+	if geomIndex < 0 {
+		os.Remove(filePath)
+		return 0, ErrNoGeometryColumn
+	}
+
+	return count, nil
+}
+
+func geoJSONProperty(val Value) any {
+	switch v := val.(type) {
+	case NullValue:
+		return nil
+	case IntValue:
+		if v.Null {
+			return nil
+		}
+		return v.Value
+	case FloatValue:
+		if v.Null {
+			return nil
+		}
+		return v.Value
+	case BoolValue:
+		if v.Null {
+			return nil
+		}
+		return v.Value
+	default:
+		if val.IsNull() {
+			return nil
+		}
+		return val.String()
+	}
+}
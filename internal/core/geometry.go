@@ -0,0 +1,411 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Geometry is a decoded spatial value using GeoJSON type names
+type Geometry struct {
+	Type   string        // Point, LineString, Polygon, MultiPoint, MultiLineString, MultiPolygon, GeometryCollection
+	SRID   int           // Spatial reference ID, 0 when unknown
+	Dims   string        // "", "Z", "M" or "ZM"
+	Coords [][]float64   // Point (zero or one coordinate) and LineString
+	Rings  [][][]float64 // Polygon
+	Parts  []*Geometry   // Multi* and GeometryCollection members
+}
+
+var errInvalidWKB = errors.New("invalid WKB geometry")
+
+var wkbTypeNames = map[uint32]string{
+	1: "Point",
+	2: "LineString",
+	3: "Polygon",
+	4: "MultiPoint",
+	5: "MultiLineString",
+	6: "MultiPolygon",
+	7: "GeometryCollection",
+}
+
+// IsGeometryType reports whether a driver column type name holds spatial data
+func IsGeometryType(typeName string) bool {
+	switch strings.ToUpper(typeName) {
+	case "GEOMETRY", "GEOGRAPHY", "POINT", "LINESTRING", "POLYGON",
+		"MULTIPOINT", "MULTILINESTRING", "MULTIPOLYGON", "GEOMETRYCOLLECTION":
+		return true
+	}
+	return false
+}
+
+// DecodeGeometry decodes a spatial value as returned by the drivers: hex EWKB from
+// PostGIS, or SRID-prefixed binary WKB from MySQL when the column is known to be spatial
+func DecodeGeometry(raw string, spatialColumn bool) (*Geometry, error) {
+	if isHexString(raw) {
+		data, err := hex.DecodeString(raw)
+		if err == nil {
+			if geom, err := ParseWKB(data); err == nil {
+				return geom, nil
+			}
+		}
+	}
+
+	if !spatialColumn {
+		return nil, errInvalidWKB
+	}
+
+	data := []byte(raw)
+	// MySQL internal format: 4-byte little-endian SRID followed by WKB
+	if len(data) > 4 {
+		if geom, err := ParseWKB(data[4:]); err == nil {
+			geom.SRID = int(binary.LittleEndian.Uint32(data[:4]))
+			return geom, nil
+		}
+	}
+	return ParseWKB(data)
+}
+
+func isHexString(s string) bool {
+	// The smallest WKB geometry (a 2D point) is 21 bytes
+	if len(s) < 42 || len(s)%2 != 0 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseWKB decodes ISO WKB or PostGIS extended WKB
+func ParseWKB(data []byte) (*Geometry, error) {
+	r := &wkbReader{data: data}
+	geom, err := r.readGeometry()
+	if err != nil {
+		return nil, err
+	}
+	if r.pos != len(r.data) {
+		return nil, errInvalidWKB
+	}
+	return geom, nil
+}
+
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) readUint32() (uint32, error) {
+	if r.pos+4 > len(r.data) {
+		return 0, errInvalidWKB
+	}
+	v := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+
+func (r *wkbReader) readFloat() (float64, error) {
+	if r.pos+8 > len(r.data) {
+		return 0, errInvalidWKB
+	}
+	v := math.Float64frombits(r.order.Uint64(r.data[r.pos:]))
+	r.pos += 8
+	return v, nil
+}
+
+func (r *wkbReader) readGeometry() (*Geometry, error) {
+	if r.pos >= len(r.data) {
+		return nil, errInvalidWKB
+	}
+	switch r.data[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, errInvalidWKB
+	}
+	r.pos++
+
+	code, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	geom := &Geometry{}
+	hasZ := code&0x80000000 != 0
+	hasM := code&0x40000000 != 0
+	if code&0x20000000 != 0 {
+		srid, err := r.readUint32()
+		if err != nil {
+			return nil, err
+		}
+		geom.SRID = int(srid)
+	}
+	code &= 0x0FFFFFFF
+
+	// ISO WKB encodes dimensions in the thousands
+	switch code / 1000 {
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	}
+	code %= 1000
+
+	name, ok := wkbTypeNames[code]
+	if !ok {
+		return nil, errInvalidWKB
+	}
+	geom.Type = name
+	if hasZ {
+		geom.Dims += "Z"
+	}
+	if hasM {
+		geom.Dims += "M"
+	}
+	dims := 2 + len(geom.Dims)
+
+	switch code {
+	case 1:
+		coord, err := r.readCoord(dims)
+		if err != nil {
+			return nil, err
+		}
+		// Empty points are encoded with NaN coordinates
+		if !math.IsNaN(coord[0]) {
+			geom.Coords = [][]float64{coord}
+		}
+	case 2:
+		geom.Coords, err = r.readCoords(dims)
+	case 3:
+		var count uint32
+		if count, err = r.readUint32(); err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			ring, err := r.readCoords(dims)
+			if err != nil {
+				return nil, err
+			}
+			geom.Rings = append(geom.Rings, ring)
+		}
+	default:
+		var count uint32
+		if count, err = r.readUint32(); err != nil {
+			return nil, err
+		}
+		for i := uint32(0); i < count; i++ {
+			part, err := r.readGeometry()
+			if err != nil {
+				return nil, err
+			}
+			geom.Parts = append(geom.Parts, part)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return geom, nil
+}
+
+func (r *wkbReader) readCoord(dims int) ([]float64, error) {
+	coord := make([]float64, dims)
+	for i := range coord {
+		v, err := r.readFloat()
+		if err != nil {
+			return nil, err
+		}
+		coord[i] = v
+	}
+	return coord, nil
+}
+
+func (r *wkbReader) readCoords(dims int) ([][]float64, error) {
+	count, err := r.readUint32()
+	if err != nil {
+		return nil, err
+	}
+	// Guard against corrupt counts before allocating
+	if int(count) > (len(r.data)-r.pos)/(8*dims) {
+		return nil, errInvalidWKB
+	}
+	coords := make([][]float64, count)
+	for i := range coords {
+		if coords[i], err = r.readCoord(dims); err != nil {
+			return nil, err
+		}
+	}
+	return coords, nil
+}
+
+// WKT renders the geometry as Well-Known Text
+func (g *Geometry) WKT() string {
+	name := strings.ToUpper(g.Type)
+	if g.Dims != "" {
+		name += " " + g.Dims
+	}
+	body := g.wktBody()
+	if body == "" {
+		return name + " EMPTY"
+	}
+	return name + " " + body
+}
+
+func (g *Geometry) wktBody() string {
+	switch g.Type {
+	case "Point", "LineString":
+		if len(g.Coords) == 0 {
+			return ""
+		}
+		return "(" + wktCoords(g.Coords) + ")"
+	case "Polygon":
+		if len(g.Rings) == 0 {
+			return ""
+		}
+		rings := make([]string, len(g.Rings))
+		for i, ring := range g.Rings {
+			rings[i] = "(" + wktCoords(ring) + ")"
+		}
+		return "(" + strings.Join(rings, ",") + ")"
+	case "GeometryCollection":
+		if len(g.Parts) == 0 {
+			return ""
+		}
+		parts := make([]string, len(g.Parts))
+		for i, part := range g.Parts {
+			parts[i] = part.WKT()
+		}
+		return "(" + strings.Join(parts, ",") + ")"
+	default:
+		if len(g.Parts) == 0 {
+			return ""
+		}
+		parts := make([]string, len(g.Parts))
+		for i, part := range g.Parts {
+			parts[i] = part.wktBody()
+		}
+		return "(" + strings.Join(parts, ",") + ")"
+	}
+}
+
+func wktCoords(coords [][]float64) string {
+	points := make([]string, len(coords))
+	for i, coord := range coords {
+		values := make([]string, len(coord))
+		for j, v := range coord {
+			values[j] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+		points[i] = strings.Join(values, " ")
+	}
+	return strings.Join(points, ",")
+}
+
+// BBox returns the bounding box as minX, minY, maxX, maxY; ok is false for empty geometries
+func (g *Geometry) BBox() (minX, minY, maxX, maxY float64, ok bool) {
+	minX, minY = math.Inf(1), math.Inf(1)
+	maxX, maxY = math.Inf(-1), math.Inf(-1)
+	g.eachCoord(func(c []float64) {
+		minX, maxX = math.Min(minX, c[0]), math.Max(maxX, c[0])
+		minY, maxY = math.Min(minY, c[1]), math.Max(maxY, c[1])
+		ok = true
+	})
+	return
+}
+
+func (g *Geometry) eachCoord(fn func([]float64)) {
+	for _, c := range g.Coords {
+		fn(c)
+	}
+	for _, ring := range g.Rings {
+		for _, c := range ring {
+			fn(c)
+		}
+	}
+	for _, part := range g.Parts {
+		part.eachCoord(fn)
+	}
+}
+
+// GeoJSON returns the geometry as a GeoJSON geometry object
+func (g *Geometry) GeoJSON() map[string]any {
+	obj := map[string]any{"type": g.Type}
+	switch g.Type {
+	case "Point":
+		if len(g.Coords) == 0 {
+			obj["coordinates"] = []float64{}
+		} else {
+			obj["coordinates"] = g.Coords[0]
+		}
+	case "LineString":
+		obj["coordinates"] = g.Coords
+	case "Polygon":
+		obj["coordinates"] = g.Rings
+	case "GeometryCollection":
+		geometries := make([]map[string]any, len(g.Parts))
+		for i, part := range g.Parts {
+			geometries[i] = part.GeoJSON()
+		}
+		obj["geometries"] = geometries
+	default:
+		coords := make([]any, len(g.Parts))
+		for i, part := range g.Parts {
+			coords[i] = part.GeoJSON()["coordinates"]
+		}
+		obj["coordinates"] = coords
+	}
+	return obj
+}
+
+// GeometryValue is a decoded spatial value displayed as WKT
+type GeometryValue struct {
+	Geometry *Geometry
+	ShowBBox bool
+}
+
+func (v GeometryValue) String() string {
+	text := v.Geometry.WKT()
+	if v.Geometry.SRID != 0 {
+		text = fmt.Sprintf("SRID=%d;%s", v.Geometry.SRID, text)
+	}
+	if v.ShowBBox {
+		if minX, minY, maxX, maxY, ok := v.Geometry.BBox(); ok {
+			text += fmt.Sprintf(" [bbox %g %g, %g %g]", minX, minY, maxX, maxY)
+		}
+	}
+	return text
+}
+
+func (v GeometryValue) IsNull() bool {
+	return v.Geometry == nil
+}
+
+// GeometryFormatter renders spatial columns as WKT instead of raw (hex) WKB
+type GeometryFormatter struct {
+	ShowBBox bool
+}
+
+func (f GeometryFormatter) Format(col Column, val Value) (Value, bool) {
+	spatial := IsGeometryType(col.Type)
+	// PostGIS types are unknown to lib/pq and arrive with an empty type name
+	if !spatial && col.Type != "" {
+		return nil, false
+	}
+	str, ok := val.(StringValue)
+	if !ok {
+		return nil, false
+	}
+	geom, err := DecodeGeometry(str.Value, spatial)
+	if err != nil {
+		return nil, false
+	}
+	return GeometryValue{Geometry: geom, ShowBBox: f.ShowBBox}, true
+}
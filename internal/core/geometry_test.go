@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeGeometry(t *testing.T) {
+	testCases := []struct {
+		name     string
+		raw      string
+		spatial  bool
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "PostGIS EWKB point with SRID",
+			raw:      "0101000020E6100000000000000000F03F0000000000000040",
+			expected: "SRID=4326;POINT (1 2)",
+		},
+		{
+			name:     "Little endian linestring",
+			raw:      "0102000000020000000000000000000000000000000000000000000000000008400000000000001040",
+			expected: "LINESTRING (0 0,3 4)",
+		},
+		{
+			name:     "Big endian polygon",
+			raw:      "0000000003000000010000000400000000000000000000000000000000402400000000000000000000000000004024000000000000401400000000000000000000000000000000000000000000",
+			expected: "POLYGON ((0 0,10 0,10 5,0 0))",
+		},
+		{
+			name:     "EWKB multipoint",
+			raw:      "0104000020e6100000020000000101000000000000000000f03f0000000000000040010100000000000000000008c00000000000001240",
+			expected: "SRID=4326;MULTIPOINT ((1 2),(-3 4.5))",
+		},
+		{
+			name:     "MySQL internal format",
+			raw:      mysqlPoint(4326, 144.5, -37.25),
+			spatial:  true,
+			expected: "SRID=4326;POINT (144.5 -37.25)",
+		},
+		{
+			name:     "Plain hex text is not geometry",
+			raw:      "deadbeefdeadbeefdeadbeefdeadbeefdeadbeefdeadbeef",
+			hasError: true,
+		},
+		{
+			name:     "Regular text",
+			raw:      "hello world",
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			geom, err := DecodeGeometry(tc.raw, tc.spatial)
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("Expected error, got geometry %s", geom.WKT())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := (GeometryValue{Geometry: geom}).String(); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGeometryBBox(t *testing.T) {
+	geom, err := DecodeGeometry("0104000020e6100000020000000101000000000000000000f03f0000000000000040010100000000000000000008c00000000000001240", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	minX, minY, maxX, maxY, ok := geom.BBox()
+	if !ok || minX != -3 || minY != 2 || maxX != 1 || maxY != 4.5 {
+		t.Errorf("Unexpected bbox: %v %v %v %v (%v)", minX, minY, maxX, maxY, ok)
+	}
+
+	value := GeometryValue{Geometry: geom, ShowBBox: true}
+	if !strings.HasSuffix(value.String(), "[bbox -3 2, 1 4.5]") {
+		t.Errorf("Expected bbox summary, got %q", value.String())
+	}
+}
+
+func TestGeometryFormatter(t *testing.T) {
+	formatter := GeometryFormatter{}
+
+	point := StringValue{Value: "0101000020E6100000000000000000F03F0000000000000040"}
+	if _, ok := formatter.Format(Column{Name: "geom", Type: ""}, point); !ok {
+		t.Error("Expected untyped PostGIS column to be formatted")
+	}
+	if _, ok := formatter.Format(Column{Name: "geom", Type: "TEXT"}, point); ok {
+		t.Error("Expected TEXT column to be left untouched")
+	}
+	if _, ok := formatter.Format(Column{Name: "id", Type: "INT"}, IntValue{Value: 1}); ok {
+		t.Error("Expected non-string value to be left untouched")
+	}
+}
+
+func TestGeometryGeoJSON(t *testing.T) {
+	geom, err := DecodeGeometry("0000000003000000010000000400000000000000000000000000000000402400000000000000000000000000004024000000000000401400000000000000000000000000000000000000000000", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(geom.GeoJSON())
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	expected := `{"coordinates":[[[0,0],[10,0],[10,5],[0,0]]],"type":"Polygon"}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func mysqlPoint(srid uint32, x, y float64) string {
+	data := make([]byte, 4+21)
+	binary.LittleEndian.PutUint32(data, srid)
+	data[4] = 1
+	binary.LittleEndian.PutUint32(data[5:], 1)
+	binary.LittleEndian.PutUint64(data[9:], math.Float64bits(x))
+	binary.LittleEndian.PutUint64(data[17:], math.Float64bits(y))
+	return string(data)
+}
@@ -0,0 +1,15 @@
+//go:build kerberos
+
+package core
+
+import (
+	"github.com/lib/pq"
+	"github.com/lib/pq/auth/kerberos"
+)
+
+// Builds tagged kerberos sign in with the ticket from kinit, through lib/pq's provider
+// (Kerberos libraries on Linux and macOS, SSPI on Windows). make build-kerberos adds it.
+func init() {
+	pq.RegisterGSSProvider(func() (pq.GSS, error) { return kerberos.NewGSS() })
+	gssapiAvailable = true
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Histogram bucket limits for /hist
+const (
+	DefaultHistogramBuckets = 10
+	MaxHistogramBuckets     = 100
+)
+
+// Histogram counts the values of a numeric column in equal-width buckets from Min to
+// Max. Every value lands in a bucket; the last one includes Max.
+type Histogram struct {
+	Table   string
+	Column  string
+	Rows    int64
+	Profile ColumnProfile
+	Min     float64
+	Max     float64
+	Width   float64
+	Counts  []int64
+}
+
+// ColumnHistogram computes a histogram on the server: the column's profile statistics
+// give the range, then one grouped query counts each bucket
+func ColumnHistogram(conn Connection, dbType DatabaseType, table, column string, buckets int) (*Histogram, error) {
+	info, err := conn.DescribeTable(table)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(info.Columns, func(c ColumnInfo) bool { return strings.EqualFold(c.Name, column) })
+	if i < 0 {
+		return nil, fmt.Errorf("column %s not found in %s", column, table)
+	}
+	col := info.Columns[i]
+	if classifyColumnType(col.Type) != numericColumn {
+		return nil, fmt.Errorf("column %s is %s, not numeric", col.Name, col.Type)
+	}
+
+	rows, err := queryStrings(conn, ProfileQuery(dbType, table, []ColumnInfo{col}))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, fmt.Errorf("profile query returned no rows")
+	}
+	h := &Histogram{Table: table, Column: col.Name}
+	h.Rows, _ = strconv.ParseInt(rows[0][0], 10, 64)
+	h.Profile = parseProfileValues(dbType, col, h.Rows, rows[0][1:])
+	if h.Rows == h.Profile.Nulls {
+		return h, nil
+	}
+
+	if h.Min, err = strconv.ParseFloat(h.Profile.Min, 64); err != nil {
+		return nil, fmt.Errorf("cannot read minimum %q: %w", h.Profile.Min, err)
+	}
+	if h.Max, err = strconv.ParseFloat(h.Profile.Max, 64); err != nil {
+		return nil, fmt.Errorf("cannot read maximum %q: %w", h.Profile.Max, err)
+	}
+	if h.Max == h.Min {
+		h.Counts = []int64{h.Rows - h.Profile.Nulls}
+		return h, nil
+	}
+
+	h.Width = (h.Max - h.Min) / float64(buckets)
+	h.Counts = make([]int64, buckets)
+	rows, err = queryStrings(conn, HistogramQuery(dbType, table, col.Name, h.Min, h.Width))
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		bucket, err := strconv.ParseFloat(row[0], 64)
+		if err != nil {
+			continue
+		}
+		count, _ := strconv.ParseInt(row[1], 10, 64)
+		// The maximum computes to one past the last bucket, and rounding may do the same
+		h.Counts[min(max(int(bucket), 0), buckets-1)] += count
+	}
+	return h, nil
+}
+
+// HistogramQuery counts a column's values by bucket number, counting from 0 at low.
+// SQLite has no FLOOR without its math extension, but truncating is the same here as
+// the values are never below low.
+func HistogramQuery(dbType DatabaseType, table, column string, low, width float64) string {
+	col := QuoteIdentifier(dbType, column)
+	offset := fmt.Sprintf("(%s - %s) / %s", col, sqlFloat(low), sqlFloat(width))
+	bucket := "FLOOR(" + offset + ")"
+	if dbType == SQLite {
+		bucket = "CAST(" + offset + " AS INTEGER)"
+	}
+	return fmt.Sprintf("SELECT %s AS bucket, COUNT(*) FROM %s WHERE %s IS NOT NULL GROUP BY 1 ORDER BY 1",
+		bucket, QuoteQualifiedName(dbType, table), col)
+}
+
+// sqlFloat writes a number as a decimal literal, so no dialect divides as integers
+func sqlFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// Render draws the histogram as unicode bars, the longest barWidth columns wide, with
+// each bucket's range and count
+func (h *Histogram) Render(barWidth int) string {
+	if len(h.Counts) == 0 {
+		return ""
+	}
+
+	labels := make([]string, len(h.Counts))
+	values := make([]float64, len(h.Counts))
+	for i, count := range h.Counts {
+		values[i] = float64(count)
+		if h.Width == 0 {
+			labels[i] = formatBucketBound(h.Min)
+			continue
+		}
+		end := ")"
+		if i == len(h.Counts)-1 {
+			end = "]"
+		}
+		lo := h.Min + float64(i)*h.Width
+		labels[i] = "[" + formatBucketBound(lo) + ", " + formatBucketBound(lo+h.Width) + end
+	}
+	return RenderBarChart(labels, values, barWidth)
+}
+
+func formatBucketBound(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestColumnHistogram(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER, total REAL, status TEXT)",
+		"INSERT INTO orders VALUES (1, 0, 'a'), (2, 1, 'a'), (3, 2.5, 'a'), (4, 9.99, 'a'), (5, 10, 'a'), (6, NULL, 'a')",
+	)
+
+	h, err := ColumnHistogram(conn, SQLite, "orders", "total", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Rows != 6 || h.Profile.Nulls != 1 || h.Min != 0 || h.Max != 10 || h.Width != 2.5 {
+		t.Fatalf("Unexpected histogram range: %+v", h)
+	}
+	// 2.5 starts the second bucket and the maximum falls in the last
+	if want := []int64{2, 1, 0, 2}; !reflect.DeepEqual(h.Counts, want) {
+		t.Errorf("Expected counts %v, got %v", want, h.Counts)
+	}
+
+	lines := strings.Split(strings.TrimRight(h.Render(8), "\n"), "\n")
+	want := []string{
+		"[0, 2.5)   ████████ 2",
+		"[2.5, 5)   ████     1",
+		"[5, 7.5)            0",
+		"[7.5, 10]  ████████ 2",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("Unexpected rendering:\n%s", strings.Join(lines, "\n"))
+	}
+
+	mustExec(t, conn, "CREATE TABLE flat (v INTEGER)", "INSERT INTO flat VALUES (7), (7)")
+	flat, err := ColumnHistogram(conn, SQLite, "flat", "v", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(flat.Counts, []int64{2}) {
+		t.Errorf("Expected a single bucket for equal values, got %v", flat.Counts)
+	}
+
+	if _, err := ColumnHistogram(conn, SQLite, "orders", "status", 10); err == nil {
+		t.Error("Expected an error for a text column")
+	}
+}
+
+func TestHistogramQuery(t *testing.T) {
+	got := HistogramQuery(PostgreSQL, "app.orders", "total", 5, 2)
+	want := `SELECT FLOOR(("total" - 5.0) / 2.0) AS bucket, COUNT(*) FROM "app"."orders" WHERE "total" IS NOT NULL GROUP BY 1 ORDER BY 1`
+	if got != want {
+		t.Errorf("HistogramQuery() =\n%s\nwant\n%s", got, want)
+	}
+}
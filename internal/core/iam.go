@@ -0,0 +1,291 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Clouds that issue IAM database tokens
+const (
+	IAMProviderAWS = "aws" // RDS and Aurora
+	IAMProviderGCP = "gcp" // Cloud SQL
+)
+
+// defaultGCPTokenCommand prints an access token for the account gcloud is signed in with
+const defaultGCPTokenCommand = "gcloud auth print-access-token"
+
+// Token lifetimes. RDS tokens expire after 15 minutes. gcloud prints its cached access
+// token, which may be close to expiry, so it is asked again more often.
+const (
+	rdsTokenLifetime   = 15 * time.Minute
+	gcpTokenLifetime   = 5 * time.Minute
+	tokenRefreshMargin = time.Minute // Tokens are renewed this long before they expire
+)
+
+// awsCredentials are the keys used to sign RDS tokens
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// tokenSource issues a database password and how long it stays valid
+type tokenSource func() (string, time.Duration, error)
+
+// newTokenSource returns the token issuer for a connection's auth block
+func newTokenSource(config *ConnectionConfig) (tokenSource, error) {
+	auth := config.Auth
+	switch auth.Provider {
+	case IAMProviderAWS:
+		region := firstNonEmpty(auth.Region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+		if region == "" {
+			return nil, errors.New("IAM authentication on AWS needs auth.region or AWS_REGION")
+		}
+		endpoint := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+		return func() (string, time.Duration, error) {
+			creds, err := loadAWSCredentials(auth.Profile)
+			if err != nil {
+				return "", 0, err
+			}
+			return rdsAuthToken(endpoint, region, config.Username, creds, time.Now()), rdsTokenLifetime, nil
+		}, nil
+	case IAMProviderGCP:
+		command := firstNonEmpty(auth.TokenCommand, defaultGCPTokenCommand)
+		return func() (string, time.Duration, error) {
+			token, err := RunSecretCommand(command)
+			if err != nil {
+				return "", 0, fmt.Errorf("failed to get a Cloud SQL access token from %q: %w", command, err)
+			}
+			return token, gcpTokenLifetime, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported IAM provider %q; use %s or %s", auth.Provider, IAMProviderAWS, IAMProviderGCP)
+	}
+}
+
+// tokenCache reuses a token until shortly before it expires
+type tokenCache struct {
+	mu      sync.Mutex
+	source  tokenSource
+	now     func() time.Time
+	token   string
+	expires time.Time
+}
+
+func (c *tokenCache) get() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && c.now().Before(c.expires) {
+		return c.token, nil
+	}
+	token, lifetime, err := c.source()
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expires = c.now().Add(lifetime - tokenRefreshMargin)
+	return token, nil
+}
+
+// tokenConnector opens each new pooled connection with a current token, so the pool
+// keeps connecting after the token it started with has expired
+type tokenConnector struct {
+	driver driver.Driver
+	config *ConnectionConfig
+	tokens *tokenCache
+}
+
+func newTokenConnector(config *ConnectionConfig, drv driver.Driver) (*tokenConnector, error) {
+	source, err := newTokenSource(config)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenConnector{
+		driver: drv,
+		config: config,
+		tokens: &tokenCache{source: source, now: time.Now},
+	}, nil
+}
+
+func (c *tokenConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	token, err := c.tokens.get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM token: %w", err)
+	}
+	_, dsn, err := dataSource(c.config, token)
+	if err != nil {
+		return nil, err
+	}
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+func (c *tokenConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// rdsAuthToken signs an RDS IAM authentication token: a SigV4 presigned connect request
+// for the endpoint, without its scheme
+func rdsAuthToken(endpoint, region, user string, creds awsCredentials, now time.Time) string {
+	now = now.UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	scope := date + "/" + region + "/rds-db/aws4_request"
+
+	params := map[string]string{
+		"Action":              "connect",
+		"DBUser":              user,
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    creds.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          amzDate,
+		"X-Amz-Expires":       strconv.Itoa(int(rdsTokenLifetime.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.SessionToken != "" {
+		params["X-Amz-Security-Token"] = creds.SessionToken
+	}
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = awsEscape(key) + "=" + awsEscape(params[key])
+	}
+	query := strings.Join(pairs, "&")
+
+	emptyPayload := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{
+		"GET", "/", query, "host:" + endpoint + "\n", "host", hex.EncodeToString(emptyPayload[:]),
+	}, "\n")
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hashedRequest[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, "rds-db", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return endpoint + "/?" + query + "&X-Amz-Signature=" + signature
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// awsEscape percent-encodes everything but unreserved characters, as SigV4 requires
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for _, b := range []byte(s) {
+		if b >= 'A' && b <= 'Z' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' ||
+			b == '-' || b == '_' || b == '.' || b == '~' {
+			sb.WriteByte(b)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", b)
+	}
+	return sb.String()
+}
+
+// loadAWSCredentials reads keys from the environment, then from the shared credentials
+// file. Other sources, such as SSO, can be exported to the environment with
+// aws configure export-credentials --format env.
+func loadAWSCredentials(profile string) (awsCredentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return awsCredentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+
+	profile = firstNonEmpty(profile, os.Getenv("AWS_PROFILE"), "default")
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	creds, err := readAWSCredentialsFile(path, profile)
+	if err != nil {
+		return awsCredentials{}, fmt.Errorf("no AWS credentials in the environment or profile %q: %w", profile, err)
+	}
+	return creds, nil
+}
+
+// readAWSCredentialsFile reads one profile of an AWS shared credentials file
+func readAWSCredentialsFile(path, profile string) (awsCredentials, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer file.Close()
+
+	var creds awsCredentials
+	inProfile := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inProfile = strings.TrimSpace(line[1:len(line)-1]) == profile
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inProfile || !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = value
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = value
+		case "aws_session_token":
+			creds.SessionToken = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return awsCredentials{}, err
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return awsCredentials{}, fmt.Errorf("profile %q has no access keys in %s", profile, path)
+	}
+	return creds, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
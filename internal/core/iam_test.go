@@ -0,0 +1,181 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRDSAuthToken(t *testing.T) {
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "session/token"}
+	now := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+	token := rdsAuthToken("db.example.com:5432", "us-east-1", "app_user", creds, now)
+
+	if !strings.HasPrefix(token, "db.example.com:5432/?Action=connect&DBUser=app_user&") {
+		t.Fatalf("token should start with the endpoint and connect action, got %q", token)
+	}
+	for _, want := range []string{
+		"X-Amz-Algorithm=AWS4-HMAC-SHA256",
+		"X-Amz-Credential=AKIDEXAMPLE%2F20240301%2Fus-east-1%2Frds-db%2Faws4_request",
+		"X-Amz-Date=20240301T123000Z",
+		"X-Amz-Expires=900",
+		"X-Amz-Security-Token=session%2Ftoken",
+		"X-Amz-SignedHeaders=host",
+	} {
+		if !strings.Contains(token, want) {
+			t.Errorf("token should contain %q, got %q", want, token)
+		}
+	}
+	if !regexp.MustCompile(`&X-Amz-Signature=[0-9a-f]{64}$`).MatchString(token) {
+		t.Errorf("token should end with a hex signature, got %q", token)
+	}
+	if again := rdsAuthToken("db.example.com:5432", "us-east-1", "app_user", creds, now); again != token {
+		t.Error("tokens for the same inputs should match")
+	}
+	if other := rdsAuthToken("db.example.com:5432", "us-east-1", "app_user", creds, now.Add(time.Second)); other == token {
+		t.Error("tokens signed at different times should differ")
+	}
+}
+
+func TestReadAWSCredentialsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	content := `[default]
+aws_access_key_id = DEFAULTKEY
+aws_secret_access_key = defaultsecret
+
+# CI account
+[ci]
+aws_access_key_id=CIKEY
+aws_secret_access_key=cisecret
+aws_session_token=citoken
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := readAWSCredentialsFile(path, "ci")
+	if err != nil {
+		t.Fatalf("failed to read profile: %v", err)
+	}
+	if creds != (awsCredentials{AccessKeyID: "CIKEY", SecretAccessKey: "cisecret", SessionToken: "citoken"}) {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+
+	creds, err = readAWSCredentialsFile(path, "default")
+	if err != nil || creds.AccessKeyID != "DEFAULTKEY" || creds.SessionToken != "" {
+		t.Errorf("expected the default profile without a session token, got %+v, %v", creds, err)
+	}
+
+	if _, err := readAWSCredentialsFile(path, "missing"); err == nil {
+		t.Error("expected an error for a profile without keys")
+	}
+}
+
+func TestTokenCache(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	issued := 0
+	cache := &tokenCache{
+		now: func() time.Time { return now },
+		source: func() (string, time.Duration, error) {
+			issued++
+			return strings.Repeat("t", issued), rdsTokenLifetime, nil
+		},
+	}
+
+	first, _ := cache.get()
+	now = now.Add(10 * time.Minute)
+	if token, _ := cache.get(); token != first || issued != 1 {
+		t.Errorf("expected the cached token within its lifetime, issued %d", issued)
+	}
+
+	now = now.Add(5 * time.Minute)
+	if token, _ := cache.get(); token == first || issued != 2 {
+		t.Errorf("expected a new token near expiry, issued %d", issued)
+	}
+
+	failing := &tokenCache{
+		now:    time.Now,
+		source: func() (string, time.Duration, error) { return "", 0, errors.New("no credentials") },
+	}
+	if _, err := failing.get(); err == nil {
+		t.Error("expected the source error")
+	}
+}
+
+func TestNewConnection_IAMValidation(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config ConnectionConfig
+	}{
+		{
+			name:   "SQLite",
+			config: ConnectionConfig{DatabaseType: SQLite, Database: ":memory:", Auth: &AuthConfig{Method: AuthIAM, Provider: IAMProviderGCP}},
+		},
+		{
+			name:   "Unknown method",
+			config: ConnectionConfig{DatabaseType: PostgreSQL, Auth: &AuthConfig{Method: "kerberos", Provider: IAMProviderAWS}},
+		},
+		{
+			name:   "Unknown provider",
+			config: ConnectionConfig{DatabaseType: MySQL, Auth: &AuthConfig{Method: AuthIAM, Provider: "azure"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewConnection(&tc.config); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDataSource_QuotesPostgresPassword(t *testing.T) {
+	config := &ConnectionConfig{DatabaseType: PostgreSQL, Host: "localhost", Port: 5432, Username: "app", Database: "app"}
+
+	_, dsn, err := dataSource(config, `it's a pass\word`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(dsn, `password='it\'s a pass\\word'`) || !strings.Contains(dsn, "sslmode=disable") {
+		t.Errorf("unexpected DSN %q", dsn)
+	}
+
+	config.Auth = &AuthConfig{Method: AuthIAM, Provider: IAMProviderAWS}
+	if _, dsn, _ = dataSource(config, "token"); !strings.Contains(dsn, "sslmode=verify-full") {
+		t.Errorf("IAM connections should verify the server certificate, got %q", dsn)
+	}
+	config.Auth.SSLRootCert = "/etc/ssl/rds-global-bundle.pem"
+	if _, dsn, _ = dataSource(config, "token"); !strings.Contains(dsn, "sslrootcert='/etc/ssl/rds-global-bundle.pem'") {
+		t.Errorf("Expected the CA bundle in the DSN, got %q", dsn)
+	}
+}
+
+func TestDataSource_MySQLIAMVerifiesTLS(t *testing.T) {
+	config := &ConnectionConfig{DatabaseType: MySQL, Host: "db.abc.us-east-1.rds.amazonaws.com", Port: 3306, Username: "app",
+		Auth: &AuthConfig{Method: AuthIAM, Provider: IAMProviderAWS}}
+
+	_, dsn, err := dataSource(config, "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(dsn, "skip-verify") || !strings.Contains(dsn, "tls=sqlterm-") || !strings.Contains(dsn, "allowCleartextPasswords=true") {
+		t.Errorf("IAM tokens should only be sent over verified TLS, got %q", dsn)
+	}
+
+	config.Auth.SSLRootCert = filepath.Join(t.TempDir(), "missing.pem")
+	if _, _, err := dataSource(config, "token"); err == nil {
+		t.Error("Expected a missing CA bundle to be reported")
+	}
+	if err := os.WriteFile(config.Auth.SSLRootCert, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := dataSource(config, "token"); err == nil {
+		t.Error("Expected a CA bundle without certificates to be reported")
+	}
+}
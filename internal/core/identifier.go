@@ -0,0 +1,89 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxIdentifierLength is above the longest name any supported dialect allows, so it
+// only rejects input that cannot be a table or column
+const maxIdentifierLength = 256
+
+// ErrInvalidIdentifier is returned for names that cannot be a table or column, such as
+// an empty name or one with control characters
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+// ValidateIdentifier checks a table or column name before it is used in a query. Names
+// from AI output or user input are checked here; quoting makes any other name safe.
+func ValidateIdentifier(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return fmt.Errorf("%w: empty name", ErrInvalidIdentifier)
+	case len(name) > maxIdentifierLength:
+		return fmt.Errorf("%w: name longer than %d bytes", ErrInvalidIdentifier, maxIdentifierLength)
+	}
+	for _, r := range name {
+		if r == 0 || unicode.IsControl(r) {
+			return fmt.Errorf("%w: %q contains a control character", ErrInvalidIdentifier, name)
+		}
+	}
+	return nil
+}
+
+// QuoteIdentifier quotes one table or column name for the dialect: backticks for MySQL,
+// double quotes otherwise, with embedded quote characters doubled. Dots are part of the
+// name; use QuoteQualifiedName for schema.table.
+func QuoteIdentifier(dbType DatabaseType, name string) string {
+	quote := `"`
+	if dbType == MySQL {
+		quote = "`"
+	}
+	return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+}
+
+// QuoteQualifiedName quotes each dot-separated part of a name such as schema.table
+func QuoteQualifiedName(dbType DatabaseType, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdentifier(dbType, part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// QuoteLiteral quotes a string literal for the dialect, for queries that cannot take
+// parameters, such as those run through Connection.Execute
+func QuoteLiteral(dbType DatabaseType, value string) string {
+	value = strings.ReplaceAll(value, "'", "''")
+	// MySQL treats backslashes in literals as escapes unless NO_BACKSLASH_ESCAPES is set,
+	// and ClickHouse always does
+	if dbType == MySQL || dbType == ClickHouse {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + value + "'"
+}
+
+// DialectOf returns the database type of a connection opened by NewConnection, false
+// for other implementations
+func DialectOf(conn Connection) (DatabaseType, bool) {
+	c, ok := conn.(*connection)
+	if !ok {
+		return 0, false
+	}
+	return c.config.DatabaseType, true
+}
+
+// QuoteConnectionIdentifier quotes a name for the connection's dialect, using standard
+// double quotes when the dialect is unknown
+func QuoteConnectionIdentifier(conn Connection, name string) string {
+	return QuoteIdentifier(quotingDialect(conn), name)
+}
+
+// quotingDialect is the connection's dialect, or PostgreSQL, whose quoting is standard SQL
+func quotingDialect(conn Connection) DatabaseType {
+	if dbType, ok := DialectOf(conn); ok {
+		return dbType
+	}
+	return PostgreSQL
+}
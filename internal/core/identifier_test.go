@@ -0,0 +1,98 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		dbType DatabaseType
+		name   string
+		want   string
+	}{
+		{MySQL, "users", "`users`"},
+		{MySQL, "we`ird", "`we``ird`"},
+		{PostgreSQL, "Users", `"Users"`},
+		{PostgreSQL, `a"; DROP TABLE x; --`, `"a""; DROP TABLE x; --"`},
+		{SQLite, "a.b", `"a.b"`},
+	}
+	for _, tt := range tests {
+		if got := QuoteIdentifier(tt.dbType, tt.name); got != tt.want {
+			t.Errorf("QuoteIdentifier(%v, %q) = %s, want %s", tt.dbType, tt.name, got, tt.want)
+		}
+	}
+
+	if got := QuoteQualifiedName(PostgreSQL, "public.Users"); got != `"public"."Users"` {
+		t.Errorf("Expected each part quoted, got %s", got)
+	}
+	if got := QuoteLiteral(MySQL, `it's \`); got != `'it''s \\'` {
+		t.Errorf("Expected quote and backslash escaped for MySQL, got %s", got)
+	}
+	if got := QuoteLiteral(SQLite, `it's \`); got != `'it''s \'` {
+		t.Errorf("Expected only the quote escaped for SQLite, got %s", got)
+	}
+}
+
+func TestValidateIdentifier(t *testing.T) {
+	for _, name := range []string{"users", "order items", `odd"name`, "表"} {
+		if err := ValidateIdentifier(name); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "  ", "a\x00b", "a\nb", strings.Repeat("x", maxIdentifierLength+1)} {
+		if err := ValidateIdentifier(name); !errors.Is(err, ErrInvalidIdentifier) {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestDescribeTableUnusualNames(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		`CREATE TABLE "order items" (id INTEGER PRIMARY KEY, "odd""col" TEXT NOT NULL)`,
+		`CREATE TABLE "it's" (id INTEGER PRIMARY KEY, item_id INTEGER REFERENCES "order items"(id))`,
+	)
+
+	info, err := conn.DescribeTable("order items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Columns) != 2 || info.Columns[1].Name != `odd"col` || len(info.PrimaryKeys) != 1 {
+		t.Errorf("Expected both columns and the key, got %+v", info)
+	}
+
+	info, err = conn.DescribeTable("it's")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.ForeignKeys) != 1 || info.ForeignKeys[0].ReferencedTable != "order items" {
+		t.Errorf("Expected the foreign key to order items, got %+v", info.ForeignKeys)
+	}
+
+	// A name smuggling a statement is only ever looked up, never run
+	info, err = conn.DescribeTable(`x); DROP TABLE "order items"; --`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Columns) != 0 {
+		t.Errorf("Expected no columns for a missing table, got %+v", info.Columns)
+	}
+	if _, err := conn.DescribeTable("order items"); err != nil {
+		t.Errorf("Expected the table to survive, got %v", err)
+	}
+
+	if _, err := conn.DescribeTable(""); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Expected an empty name to be rejected, got %v", err)
+	}
+
+	ddl, err := GenerateDDL(conn, SQLite, "it's")
+	if err != nil || !strings.Contains(ddl.Create, "REFERENCES") {
+		t.Errorf("Expected the DDL of it's, got %+v, %v", ddl, err)
+	}
+	fields, _, err := SampleJSONStructure(conn, "order items", `odd"col`, 5)
+	if err != nil || len(fields) != 0 {
+		t.Errorf("Expected an empty sample from quoted names, got %v, %v", fields, err)
+	}
+}
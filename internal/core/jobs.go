@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus is the state of a background statement
+type JobStatus int
+
+const (
+	JobRunning JobStatus = iota
+	JobDone
+	JobFailed
+	JobCancelled
+)
+
+// Job is a statement running, or run, in the background on a connection of its own
+type Job struct {
+	ID         int
+	Query      string
+	Connection string
+	Started    time.Time
+	Finished   time.Time
+	Status     JobStatus
+	Rows       int
+	Error      string
+	ResultPath string // Markdown file holding the result, once it finished
+}
+
+// Elapsed returns how long the job ran, or has been running
+func (j Job) Elapsed() time.Duration {
+	if j.Finished.IsZero() {
+		return time.Since(j.Started)
+	}
+	return j.Finished.Sub(j.Started)
+}
+
+// JobFunc runs the background statement of job id until ctx is cancelled, returning the
+// rows it produced and where its result was saved
+type JobFunc func(ctx context.Context, id int) (rows int, resultPath string, err error)
+
+// Jobs holds the background statements of a session. It is safe for use by the REPL
+// and the jobs themselves at the same time.
+type Jobs struct {
+	mu      sync.Mutex
+	items   []*Job
+	cancels map[int]context.CancelFunc
+	nextID  int
+}
+
+// Start runs a statement in the background and returns its copy. done is called with
+// the finished job from the job's goroutine.
+func (j *Jobs) Start(query, connection string, run JobFunc, done func(Job)) Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	j.mu.Lock()
+	j.nextID++
+	job := &Job{ID: j.nextID, Query: query, Connection: connection, Started: time.Now()}
+	j.items = append(j.items, job)
+	if j.cancels == nil {
+		j.cancels = make(map[int]context.CancelFunc)
+	}
+	j.cancels[job.ID] = cancel
+	started := *job
+	j.mu.Unlock()
+
+	go func() {
+		rows, path, err := run(ctx, job.ID)
+		finished := j.finish(job.ID, rows, path, err, ctx.Err() != nil)
+		cancel()
+		if done != nil {
+			done(finished)
+		}
+	}()
+	return started
+}
+
+// finish records the outcome of a job. A job cancelled while running ends as cancelled
+// whatever error the driver gave for it.
+func (j *Jobs) finish(id, rows int, path string, err error, cancelled bool) Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.cancels, id)
+	job := j.find(id)
+	job.Finished = time.Now()
+	job.Rows = rows
+	job.ResultPath = path
+	switch {
+	case cancelled:
+		job.Status = JobCancelled
+	case err != nil:
+		job.Status = JobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = JobDone
+	}
+	return *job
+}
+
+// Items returns copies of all jobs, oldest first
+func (j *Jobs) Items() []Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	items := make([]Job, len(j.items))
+	for i, job := range j.items {
+		items[i] = *job
+	}
+	return items
+}
+
+// Get returns a copy of the job with the ID
+func (j *Jobs) Get(id int) (Job, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if job := j.find(id); job != nil {
+		return *job, true
+	}
+	return Job{}, false
+}
+
+// Cancel stops a running job, reporting whether it was running
+func (j *Jobs) Cancel(id int) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	cancel, ok := j.cancels[id]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// CancelAll stops every running job and returns how many there were
+func (j *Jobs) CancelAll() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cancel := range j.cancels {
+		cancel()
+	}
+	return len(j.cancels)
+}
+
+func (j *Jobs) find(id int) *Job {
+	for _, job := range j.items {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// SplitBackground separates a trailing & from a statement, as in "SELECT ...; &",
+// reporting whether it was there. A trailing && is an operator, not a request.
+func SplitBackground(query string) (string, bool) {
+	trimmed := strings.TrimSpace(query)
+	if !strings.HasSuffix(trimmed, "&") || strings.HasSuffix(trimmed, "&&") {
+		return query, false
+	}
+	trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, "&"))
+	trimmed = strings.TrimSpace(strings.TrimSuffix(trimmed, ";"))
+	if trimmed == "" {
+		return query, false
+	}
+	return trimmed, true
+}
+
+// ExecuteContext runs a query that stops when ctx is cancelled. Connections that cannot
+// cancel a running statement run it to the end.
+func ExecuteContext(ctx context.Context, conn Connection, query string) (*QueryResult, error) {
+	c, ok := conn.(*connection)
+	if !ok {
+		return conn.Execute(query)
+	}
+	if c.config.ReadOnly && !IsReadOnlyQuery(query) {
+		return nil, ErrReadOnlyConnection
+	}
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return nil, ClassifyError(fmt.Errorf("failed to execute query: %w", err), c.config.DatabaseType)
+	}
+	return NewQueryResult(rows)
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSplitBackground(t *testing.T) {
+	tests := []struct {
+		input      string
+		query      string
+		background bool
+	}{
+		{"SELECT * FROM users &", "SELECT * FROM users", true},
+		{"SELECT * FROM users; &", "SELECT * FROM users", true},
+		{"SELECT * FROM users;&  ", "SELECT * FROM users", true},
+		{"SELECT * FROM users", "SELECT * FROM users", false},
+		{"SELECT a FROM t WHERE tags && ARRAY['x'] &&", "SELECT a FROM t WHERE tags && ARRAY['x'] &&", false},
+		{"&", "&", false},
+	}
+	for _, tt := range tests {
+		query, background := SplitBackground(tt.input)
+		if query != tt.query || background != tt.background {
+			t.Errorf("SplitBackground(%q) = %q, %v; expected %q, %v", tt.input, query, background, tt.query, tt.background)
+		}
+	}
+}
+
+func TestJobs(t *testing.T) {
+	var jobs Jobs
+	finished := make(chan Job, 2)
+	done := func(job Job) { finished <- job }
+
+	first := jobs.Start("SELECT 1", "local", func(ctx context.Context, id int) (int, string, error) {
+		return 1, "result.md", nil
+	}, done)
+	if first.ID != 1 || first.Status != JobRunning {
+		t.Fatalf("Unexpected job %+v", first)
+	}
+	if job := <-finished; job.Status != JobDone || job.Rows != 1 || job.ResultPath != "result.md" {
+		t.Errorf("Unexpected finished job %+v", job)
+	}
+
+	started := make(chan struct{})
+	second := jobs.Start("SELECT pg_sleep(60)", "local", func(ctx context.Context, id int) (int, string, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, "", errors.New("canceling statement due to user request")
+	}, done)
+	<-started
+	if job, _ := jobs.Get(second.ID); job.Status != JobRunning {
+		t.Errorf("Expected job %d to be running, got %+v", second.ID, job)
+	}
+	if !jobs.Cancel(second.ID) {
+		t.Fatalf("Expected job %d to be cancelled", second.ID)
+	}
+	if job := <-finished; job.Status != JobCancelled || job.Error != "" {
+		t.Errorf("Unexpected cancelled job %+v", job)
+	}
+	if jobs.Cancel(second.ID) || jobs.Cancel(9) {
+		t.Error("Expected only running jobs to be cancelled")
+	}
+
+	failed := jobs.Start("SELECT nope", "local", func(ctx context.Context, id int) (int, string, error) {
+		return 0, "", errors.New("no such column: nope")
+	}, done)
+	if job := <-finished; job.ID != failed.ID || job.Status != JobFailed || job.Error != "no such column: nope" {
+		t.Errorf("Unexpected failed job %+v", job)
+	}
+	if items := jobs.Items(); len(items) != 3 || jobs.CancelAll() != 0 {
+		t.Errorf("Expected 3 finished jobs, got %+v", items)
+	}
+}
+
+func TestExecuteContext(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	result, err := ExecuteContext(context.Background(), conn, "SELECT 1 AS one")
+	if err != nil {
+		t.Fatalf("ExecuteContext failed: %v", err)
+	}
+	for range result.Itor() {
+	}
+	result.Close()
+	if result.RowCount() != 1 || result.Error() != nil {
+		t.Errorf("Expected one row, got %d, %v", result.RowCount(), result.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExecuteContext(ctx, conn, "SELECT 1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled query, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSONField describes a key path discovered in sampled JSON values
+type JSONField struct {
+	Path  string   // Dotted key path, arrays are marked with []
+	Types []string // JSON types observed at this path
+	Count int      // Number of samples containing this path
+}
+
+// IsJSONType reports whether a driver column type name holds JSON documents
+func IsJSONType(typeName string) bool {
+	switch strings.ToUpper(typeName) {
+	case "JSON", "JSONB":
+		return true
+	}
+	return false
+}
+
+// PrettyJSON indents a JSON document, returning false when the input is not valid JSON
+func PrettyJSON(s string) (string, bool) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s, false
+	}
+	return buf.String(), true
+}
+
+// CompactJSON removes insignificant whitespace so JSON fits in a single table cell
+func CompactJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(s)); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// InferJSONStructure walks sampled JSON documents and collects the key paths they contain
+func InferJSONStructure(samples []string) []JSONField {
+	fields := make(map[string]*JSONField)
+
+	for _, sample := range samples {
+		var doc any
+		if err := json.Unmarshal([]byte(sample), &doc); err != nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		walkJSON(doc, "$", fields, seen)
+	}
+
+	result := make([]JSONField, 0, len(fields))
+	for _, field := range fields {
+		sort.Strings(field.Types)
+		result = append(result, *field)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
+
+func walkJSON(node any, path string, fields map[string]*JSONField, seen map[string]bool) {
+	field, ok := fields[path]
+	if !ok {
+		field = &JSONField{Path: path}
+		fields[path] = field
+	}
+	if !seen[path] {
+		field.Count++
+		seen[path] = true
+	}
+
+	typeName := jsonTypeName(node)
+	found := false
+	for _, t := range field.Types {
+		if t == typeName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		field.Types = append(field.Types, typeName)
+	}
+
+	switch v := node.(type) {
+	case map[string]any:
+		for key, child := range v {
+			walkJSON(child, path+"."+key, fields, seen)
+		}
+	case []any:
+		for _, child := range v {
+			walkJSON(child, path+"[]", fields, seen)
+		}
+	}
+}
+
+func jsonTypeName(node any) string {
+	switch node.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// SampleJSONStructure reads non-null values of a JSON column and infers their key structure.
+// The table may be schema-qualified.
+func SampleJSONStructure(conn Connection, table, column string, limit int) ([]JSONField, int, error) {
+	for _, name := range []string{table, column} {
+		if err := ValidateIdentifier(name); err != nil {
+			return nil, 0, err
+		}
+	}
+	dbType := quotingDialect(conn)
+	quotedColumn := QuoteIdentifier(dbType, column)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL LIMIT %d",
+		quotedColumn, QuoteQualifiedName(dbType, table), quotedColumn, limit)
+	result, err := conn.Execute(query)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer result.Close()
+
+	var samples []string
+	for row := range result.Itor() {
+		if len(row) > 0 && !row[0].IsNull() {
+			samples = append(samples, row[0].String())
+		}
+	}
+	if result.Error() != nil {
+		return nil, len(samples), result.Error()
+	}
+
+	return InferJSONStructure(samples), len(samples), nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/i18n"
+)
+
+// newTestSQLiteConnection opens a throwaway SQLite database for tests
+func newTestSQLiteConnection(t *testing.T) Connection {
+	t.Helper()
+	conn, err := NewConnection(&ConnectionConfig{
+		Name:         "test",
+		DatabaseType: SQLite,
+		Database:     filepath.Join(t.TempDir(), "test.db"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open SQLite connection: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// mustExec runs setup statements, draining results so SQLite actually executes them
+func mustExec(t *testing.T, conn Connection, statements ...string) {
+	t.Helper()
+	for _, stmt := range statements {
+		result, err := conn.Execute(stmt)
+		if err != nil {
+			t.Fatalf("Setup statement failed: %v", err)
+		}
+		for range result.Itor() {
+		}
+		result.Close()
+	}
+}
+
+func TestIsJSONType(t *testing.T) {
+	testCases := []struct {
+		name     string
+		typeName string
+		expected bool
+	}{
+		{name: "Postgres json", typeName: "JSON", expected: true},
+		{name: "Postgres jsonb", typeName: "JSONB", expected: true},
+		{name: "Lowercase", typeName: "json", expected: true},
+		{name: "Text", typeName: "TEXT", expected: false},
+		{name: "Empty", typeName: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsJSONType(tc.typeName); got != tc.expected {
+				t.Errorf("IsJSONType(%q) = %v, expected %v", tc.typeName, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	pretty, ok := PrettyJSON(`{"a":1,"b":[true]}`)
+	if !ok {
+		t.Fatal("Expected valid JSON to be pretty-printed")
+	}
+	if !strings.Contains(pretty, "\n  \"a\": 1") {
+		t.Errorf("Expected indented output, got %q", pretty)
+	}
+
+	if _, ok := PrettyJSON("not json"); ok {
+		t.Error("Expected invalid JSON to be rejected")
+	}
+
+	if got := CompactJSON("{ \"a\" : 1 }"); got != `{"a":1}` {
+		t.Errorf("CompactJSON returned %q", got)
+	}
+}
+
+func TestInferJSONStructure(t *testing.T) {
+	samples := []string{
+		`{"id": 1, "tags": ["a", "b"], "address": {"city": "Melbourne"}}`,
+		`{"id": "x2", "address": null}`,
+		`invalid`,
+	}
+
+	fields := InferJSONStructure(samples)
+
+	expected := map[string]struct {
+		types string
+		count int
+	}{
+		"$":              {types: "object", count: 2},
+		"$.id":           {types: "number,string", count: 2},
+		"$.tags":         {types: "array", count: 1},
+		"$.tags[]":       {types: "string", count: 1},
+		"$.address":      {types: "null,object", count: 2},
+		"$.address.city": {types: "string", count: 1},
+	}
+
+	if len(fields) != len(expected) {
+		t.Fatalf("Expected %d fields, got %d: %+v", len(expected), len(fields), fields)
+	}
+
+	for _, field := range fields {
+		want, ok := expected[field.Path]
+		if !ok {
+			t.Errorf("Unexpected path %s", field.Path)
+			continue
+		}
+		if got := strings.Join(field.Types, ","); got != want.types {
+			t.Errorf("Path %s: expected types %s, got %s", field.Path, want.types, got)
+		}
+		if field.Count != want.count {
+			t.Errorf("Path %s: expected count %d, got %d", field.Path, want.count, field.Count)
+		}
+	}
+}
+
+func TestToMarkdownJSONColumns(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatalf("Failed to create i18n manager: %v", err)
+	}
+
+	mustExec(t, conn,
+		"CREATE TABLE events (id INTEGER, payload JSON)",
+		`INSERT INTO events VALUES (1, '{ "kind" : "signup", "meta": {"source": "web"} }')`,
+	)
+
+	result, err := conn.Execute("SELECT id, payload FROM events")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	markdown := ToMarkdown(result, 20, i18nMgr)
+	if !strings.Contains(markdown, `{"kind":"signup","meta":{"source":"web"}}`) {
+		t.Errorf("Expected compact JSON in table cell, got:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "```json\n{\n  \"kind\": \"signup\"") {
+		t.Errorf("Expected pretty-printed JSON block, got:\n%s", markdown)
+	}
+}
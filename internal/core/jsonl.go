@@ -0,0 +1,215 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The value types a JSONL schema header gives columns. Columns of AnyJSONLType, such as
+// expressions SQLite reports no type for, keep the JSON type of each value as fetched.
+const (
+	StringJSONLType    = "string"
+	IntegerJSONLType   = "integer"
+	NumberJSONLType    = "number"
+	BooleanJSONLType   = "boolean"
+	TimestampJSONLType = "timestamp"
+	JSONJSONLType      = "json"
+	AnyJSONLType       = "any"
+)
+
+// JSONLColumn describes a column in the first line of a JSONL export with a schema
+type JSONLColumn struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	DBType   string `json:"db_type,omitempty"`
+	Nullable *bool  `json:"nullable,omitempty"`
+}
+
+// timestampLayouts are the text forms drivers return date and time columns in
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// JSONLColumnType returns the value type a column is written as, from the Go type the
+// driver scans it into or else its database type name
+func JSONLColumnType(col Column) string {
+	switch strings.TrimPrefix(col.ScanType, "sql.Null") {
+	case "int8", "int16", "int32", "int64", "int", "uint8", "uint16", "uint32", "uint64", "uint",
+		"Int16", "Int32", "Int64", "Byte":
+		return IntegerJSONLType
+	case "float32", "float64", "Float64":
+		return NumberJSONLType
+	case "bool", "Bool":
+		return BooleanJSONLType
+	case "time.Time", "Time":
+		return TimestampJSONLType
+	}
+
+	if IsJSONType(col.Type) {
+		return JSONJSONLType
+	}
+	base, _, _ := strings.Cut(strings.ToUpper(col.Type), "(")
+	base = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(base), " UNSIGNED"))
+	switch base {
+	case "":
+		return AnyJSONLType
+	case "INT", "INTEGER", "SMALLINT", "BIGINT", "TINYINT", "MEDIUMINT", "INT2", "INT4", "INT8",
+		"INT16", "INT32", "INT64", "UINT8", "UINT16", "UINT32", "UINT64", "SERIAL", "BIGSERIAL", "SMALLSERIAL":
+		return IntegerJSONLType
+	case "REAL", "FLOAT", "FLOAT4", "FLOAT8", "FLOAT32", "FLOAT64", "DOUBLE", "DOUBLE PRECISION",
+		"DECIMAL", "NUMERIC", "NUMBER":
+		return NumberJSONLType
+	case "BOOL", "BOOLEAN":
+		return BooleanJSONLType
+	case "DATE", "DATETIME", "DATETIME64", "TIMESTAMP", "TIMESTAMPTZ",
+		"TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITHOUT TIME ZONE":
+		return TimestampJSONLType
+	}
+	return StringJSONLType
+}
+
+// jsonlValue encodes a value as its column's type. Text the driver returned for a typed
+// column, as MySQL does for numbers, is parsed; text that does not parse stays a string.
+func jsonlValue(val Value, columnType string) ([]byte, error) {
+	switch v := val.(type) {
+	case TimeValue:
+		if !v.Null {
+			return json.Marshal(v.Value.Format(time.RFC3339Nano))
+		}
+	case StringValue:
+		if !v.Null {
+			if data, ok := parseJSONLText(v.Value, columnType); ok {
+				return data, nil
+			}
+		}
+	}
+	return json.Marshal(geoJSONProperty(val))
+}
+
+// parseJSONLText converts the text of a typed column, keeping decimals exactly as written
+func parseJSONLText(text, columnType string) ([]byte, bool) {
+	switch columnType {
+	case IntegerJSONLType:
+		if _, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return []byte(text), true
+		}
+		if _, err := strconv.ParseUint(text, 10, 64); err == nil {
+			return []byte(text), true
+		}
+	case NumberJSONLType:
+		trimmed := strings.TrimSpace(text)
+		if _, err := strconv.ParseFloat(trimmed, 64); err == nil && json.Valid([]byte(trimmed)) {
+			return []byte(trimmed), true
+		}
+	case BooleanJSONLType:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return []byte(strconv.FormatBool(b)), true
+		}
+	case TimestampJSONLType:
+		for _, layout := range timestampLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				data, _ := json.Marshal(t.Format(time.RFC3339Nano))
+				return data, true
+			}
+		}
+	case JSONJSONLType:
+		if json.Valid([]byte(text)) {
+			return []byte(CompactJSON(text)), true
+		}
+	}
+	return nil, false
+}
+
+// WriteJSONLWithSchema writes a line describing the columns, {"schema":[...]}, and then
+// each row as a JSON object whose values keep their column's type: numbers and booleans
+// unquoted, timestamps as RFC 3339 text and JSON columns embedded
+func WriteJSONLWithSchema(result *QueryResult, w io.Writer) (int, error) {
+	count := 0
+	defer result.Close()
+
+	names := result.DisplayColumnNames()
+	schema := make([]JSONLColumn, len(result.Columns))
+	keys := make([][]byte, len(result.Columns))
+	for i, col := range result.Columns {
+		schema[i] = JSONLColumn{Name: names[i], Type: JSONLColumnType(col), DBType: col.Type, Nullable: col.Nullable}
+		keys[i], _ = json.Marshal(names[i])
+	}
+
+	writer := bufio.NewWriter(w)
+	header, err := json.Marshal(map[string][]JSONLColumn{"schema": schema})
+	if err != nil {
+		return count, err
+	}
+	writer.Write(header)
+	writer.WriteByte('\n')
+
+	err = result.ForEachRow(func(row []Value) error {
+		writer.WriteByte('{')
+		for i, val := range row {
+			if i >= len(keys) {
+				break
+			}
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+			writer.Write(keys[i])
+			writer.WriteByte(':')
+			data, err := jsonlValue(val, schema[i].Type)
+			if err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+			writer.Write(data)
+		}
+		writer.WriteString("}\n")
+		count++
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+	return count, writer.Flush()
+}
+
+// SaveQueryResultAsJSONL writes a result to a file as one JSON object per row, led by a
+// schema line when withSchema is set
+func SaveQueryResultAsJSONL(result *QueryResult, filePath string, withSchema bool) (int, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		result.Close()
+		return 0, fmt.Errorf("failed to create JSONL file: %w", err)
+	}
+	var count int
+	if withSchema {
+		count, err = WriteJSONLWithSchema(result, file)
+	} else {
+		count, err = WriteNDJSON(result, file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
+
+// IsJSONLFile reports whether a path names a JSON Lines file
+func IsJSONLFile(filePath string) bool {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".jsonl", ".ndjson":
+		return true
+	}
+	return false
+}
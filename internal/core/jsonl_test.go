@@ -0,0 +1,133 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveQueryResultAsJSONLWithSchema(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER NOT NULL, total DECIMAL(10,2), paid BOOLEAN, created_at DATETIME, note TEXT)",
+		"INSERT INTO orders VALUES (1, 19.9, 1, '2024-03-01 09:30:00', 'first'), (2, NULL, 0, NULL, '42')",
+	)
+
+	result, err := conn.Execute("SELECT id, total, paid, created_at, note, id * 2 AS doubled FROM orders ORDER BY id")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "orders.jsonl")
+	count, err := SaveQueryResultToFile(result, path)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 rows, got %d", count)
+	}
+
+	result, err = conn.Execute("SELECT id, total, paid, created_at, note, id * 2 AS doubled FROM orders ORDER BY id")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if _, err := SaveQueryResultAsJSONL(result, path, true); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected a schema line and 2 rows, got %q", lines)
+	}
+
+	var header struct {
+		Schema []JSONLColumn `json:"schema"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &header); err != nil {
+		t.Fatalf("Invalid schema line %q: %v", lines[0], err)
+	}
+	var types []string
+	for _, col := range header.Schema {
+		types = append(types, col.Type)
+	}
+	expected := []string{IntegerJSONLType, NumberJSONLType, BooleanJSONLType, TimestampJSONLType, StringJSONLType, AnyJSONLType}
+	if len(types) != len(expected) {
+		t.Fatalf("Expected types %v, got %v", expected, types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("Column %s: expected %s, got %s", header.Schema[i].Name, expected[i], types[i])
+		}
+	}
+	if header.Schema[0].DBType != "INTEGER" {
+		t.Errorf("Expected the database type INTEGER, got %q", header.Schema[0].DBType)
+	}
+
+	want := []string{
+		`{"id":1,"total":19.9,"paid":true,"created_at":"2024-03-01T09:30:00Z","note":"first","doubled":2}`,
+		`{"id":2,"total":null,"paid":false,"created_at":null,"note":"42","doubled":4}`,
+	}
+	for i, line := range lines[1:] {
+		if line != want[i] {
+			t.Errorf("Row %d:\nexpected %s\ngot      %s", i+1, want[i], line)
+		}
+	}
+}
+
+func TestJSONLColumnType(t *testing.T) {
+	testCases := []struct {
+		column   Column
+		expected string
+	}{
+		{Column{Type: "INT4", ScanType: "int32"}, IntegerJSONLType},
+		{Column{Type: "BIGINT UNSIGNED", ScanType: "sql.RawBytes"}, IntegerJSONLType},
+		{Column{Type: "NUMERIC", ScanType: "[]uint8"}, NumberJSONLType},
+		{Column{Type: "TINYINT(1)"}, IntegerJSONLType},
+		{Column{Type: "BOOL", ScanType: "bool"}, BooleanJSONLType},
+		{Column{Type: "TIMESTAMPTZ", ScanType: "time.Time"}, TimestampJSONLType},
+		{Column{Type: "JSONB"}, JSONJSONLType},
+		{Column{Type: "INTERVAL"}, StringJSONLType},
+		{Column{Type: "VARCHAR(20)", ScanType: "sql.NullString"}, StringJSONLType},
+		{Column{}, AnyJSONLType},
+	}
+	for _, tc := range testCases {
+		if got := JSONLColumnType(tc.column); got != tc.expected {
+			t.Errorf("%+v: expected %s, got %s", tc.column, tc.expected, got)
+		}
+	}
+}
+
+func TestJSONLValueFromText(t *testing.T) {
+	testCases := []struct {
+		text       string
+		columnType string
+		expected   string
+	}{
+		{"12345678901234567890", IntegerJSONLType, `12345678901234567890`},
+		{"1234.5600", NumberJSONLType, `1234.5600`},
+		{"NaN", NumberJSONLType, `"NaN"`},
+		{"t", BooleanJSONLType, `true`},
+		{"2024-03-01 09:30:00+11", TimestampJSONLType, `"2024-03-01T09:30:00+11:00"`},
+		{`{"a": 1}`, JSONJSONLType, `{"a":1}`},
+		{"n/a", IntegerJSONLType, `"n/a"`},
+		{"7", StringJSONLType, `"7"`},
+	}
+	for _, tc := range testCases {
+		data, err := jsonlValue(StringValue{Value: tc.text}, tc.columnType)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.text, err)
+		}
+		if string(data) != tc.expected {
+			t.Errorf("%q as %s: expected %s, got %s", tc.text, tc.columnType, tc.expected, data)
+		}
+	}
+}
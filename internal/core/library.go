@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultQueriesDir is the query library used when none is configured
+const DefaultQueriesDir = "queries"
+
+// LibraryQuery is a .sql file in the query library. Its name is the path below the
+// library without the extension, so queries/finance/revenue.sql is finance/revenue.
+// Metadata comes from an optional front matter block of YAML in comments at the top:
+//
+//	-- ---
+//	-- description: Revenue per day for a region
+//	-- owner: finance
+//	-- params: [start_date, region]
+//	-- ---
+//	SELECT ...
+type LibraryQuery struct {
+	Name        string   `yaml:"-"`
+	Path        string   `yaml:"-"`
+	SQL         string   `yaml:"-"` // The file without its front matter
+	Description string   `yaml:"description"`
+	Owner       string   `yaml:"owner"`
+	Params      []string `yaml:"params"`
+}
+
+// LoadLibrary reads every .sql file below dir, sorted by name. A missing directory
+// gives an empty library.
+func LoadLibrary(dir string) ([]LibraryQuery, error) {
+	var queries []LibraryQuery
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != dir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(filepath.Ext(path), ".sql") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		query, err := parseLibraryQuery(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		query.Name = filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+		query.Path = path
+		queries = append(queries, query)
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	slices.SortFunc(queries, func(a, b LibraryQuery) int { return strings.Compare(a.Name, b.Name) })
+	return queries, nil
+}
+
+// parseLibraryQuery splits the front matter from the SQL of a library file
+func parseLibraryQuery(content string) (LibraryQuery, error) {
+	var query LibraryQuery
+	lines := strings.Split(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "-- ---" {
+		query.SQL = strings.TrimSpace(content)
+		return query, nil
+	}
+
+	var front []string
+	for i, line := range lines[1:] {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "-- ---" {
+			if err := yaml.Unmarshal([]byte(strings.Join(front, "\n")), &query); err != nil {
+				return query, fmt.Errorf("invalid front matter: %w", err)
+			}
+			query.SQL = strings.TrimSpace(strings.Join(lines[i+2:], "\n"))
+			return query, nil
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		front = append(front, strings.TrimPrefix(strings.TrimPrefix(line, "--"), " "))
+	}
+	// No closing marker: the comments are part of the query
+	query.SQL = strings.TrimSpace(content)
+	return query, nil
+}
+
+// SearchLibrary returns the queries matching term, best first: names containing it,
+// then names containing its letters in order, then queries whose description, owner
+// or SQL contains it
+func SearchLibrary(queries []LibraryQuery, term string) []LibraryQuery {
+	term = strings.ToLower(strings.TrimSpace(term))
+	type match struct {
+		query LibraryQuery
+		rank  int
+	}
+	var matches []match
+	for _, query := range queries {
+		name := strings.ToLower(query.Name)
+		var rank int
+		switch {
+		case strings.Contains(name, term):
+			rank = 1
+		case isSubsequence(term, name):
+			rank = 2
+		case strings.Contains(strings.ToLower(query.Description), term),
+			strings.Contains(strings.ToLower(query.Owner), term):
+			rank = 3
+		case strings.Contains(strings.ToLower(query.SQL), term):
+			rank = 4
+		default:
+			continue
+		}
+		matches = append(matches, match{query, rank})
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return a.rank - b.rank })
+
+	results := make([]LibraryQuery, len(matches))
+	for i, m := range matches {
+		results[i] = m.query
+	}
+	return results
+}
+
+// isSubsequence reports whether the letters of term appear in s in order, so rvreg
+// finds revenue_by_region
+func isSubsequence(term, s string) bool {
+	for _, r := range s {
+		if term == "" {
+			break
+		}
+		if next, size := utf8.DecodeRuneInString(term); r == next {
+			term = term[size:]
+		}
+	}
+	return term == ""
+}
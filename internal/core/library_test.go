@@ -0,0 +1,78 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadLibrary(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"finance/revenue_by_region.sql": "-- ---\n-- description: Revenue per day for a region\n-- owner: finance\n-- params: [start_date, region]\n-- ---\nSELECT region, SUM(total) FROM orders GROUP BY region;\n",
+		"active_users.sql":              "-- Users seen this week\nSELECT COUNT(*) FROM users WHERE last_seen > now() - interval '7 days';\n",
+		"notes.txt":                     "not a query",
+		".git/hooks/x.sql":              "SELECT 1",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queries, err := LoadLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queries) != 2 || queries[0].Name != "active_users" || queries[1].Name != "finance/revenue_by_region" {
+		t.Fatalf("Unexpected library %+v", queries)
+	}
+	revenue := queries[1]
+	if revenue.Description != "Revenue per day for a region" || revenue.Owner != "finance" ||
+		!slices.Equal(revenue.Params, []string{"start_date", "region"}) {
+		t.Errorf("Unexpected front matter %+v", revenue)
+	}
+	if revenue.SQL != "SELECT region, SUM(total) FROM orders GROUP BY region;" {
+		t.Errorf("Expected the front matter to be removed from the SQL, got %q", revenue.SQL)
+	}
+	if queries[0].Description != "" || queries[0].SQL[:2] != "--" {
+		t.Errorf("Expected a plain comment to stay part of the query, got %+v", queries[0])
+	}
+
+	if missing, err := LoadLibrary(filepath.Join(dir, "missing")); err != nil || missing != nil {
+		t.Errorf("Expected an empty library for a missing directory, got %v, %v", missing, err)
+	}
+}
+
+func TestSearchLibrary(t *testing.T) {
+	queries := []LibraryQuery{
+		{Name: "active_users", SQL: "SELECT COUNT(*) FROM users"},
+		{Name: "finance/revenue_by_region", Description: "Revenue per day", SQL: "SELECT region FROM orders"},
+		{Name: "orders_late", Owner: "ops", SQL: "SELECT * FROM orders WHERE shipped_at IS NULL"},
+	}
+	names := func(results []LibraryQuery) []string {
+		var names []string
+		for _, query := range results {
+			names = append(names, query.Name)
+		}
+		return names
+	}
+
+	testCases := map[string][]string{
+		"orders":  {"orders_late", "finance/revenue_by_region"},
+		"rvreg":   {"finance/revenue_by_region"},
+		"OPS":     {"orders_late"},
+		"users":   {"active_users"},
+		"nothing": nil,
+	}
+	for term, want := range testCases {
+		if got := names(SearchLibrary(queries, term)); !slices.Equal(got, want) {
+			t.Errorf("SearchLibrary(%q) = %v, want %v", term, got, want)
+		}
+	}
+}
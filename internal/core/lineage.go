@@ -0,0 +1,886 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ColumnRef is a column of a table. An empty Table is a column of a query's result, or
+// a column whose table could not be told apart among several.
+type ColumnRef struct {
+	Table  string
+	Column string
+}
+
+func (c ColumnRef) String() string {
+	if c.Table == "" {
+		return c.Column
+	}
+	return c.Table + "." + c.Column
+}
+
+// LineageEdge records that a query computed Target from Sources
+type LineageEdge struct {
+	Target  ColumnRef
+	Sources []ColumnRef
+	Query   string
+}
+
+// Lineage is a column-level map of which columns feed which, built from the SELECT,
+// INSERT ... SELECT and CREATE ... AS SELECT statements of a session
+type Lineage struct {
+	Edges []LineageEdge
+}
+
+// LineageStep is an edge found while tracing, with how many edges away from the column
+// it was found
+type LineageStep struct {
+	LineageEdge
+	Depth int
+}
+
+// maxLineageDepth bounds how far upstream and downstream a column is traced
+const maxLineageDepth = 5
+
+// BuildLineage analyzes queries in the order they ran. Statements that cannot be
+// analyzed are skipped.
+func BuildLineage(queries []string) *Lineage {
+	lineage := &Lineage{}
+	for _, query := range queries {
+		lineage.Edges = append(lineage.Edges, AnalyzeLineage(query)...)
+	}
+	return lineage
+}
+
+// AnalyzeLineage finds the source columns of each column a statement outputs: the
+// result columns of a SELECT, or the table columns written by INSERT ... SELECT and
+// CREATE TABLE|VIEW ... AS SELECT
+func AnalyzeLineage(query string) []LineageEdge {
+	tokens := tokenizeSQL(query)
+	if len(tokens) == 0 {
+		return nil
+	}
+	p := &lineageParser{query: strings.TrimSpace(query)}
+
+	target := ""
+	var targetColumns []string
+	i := 0
+	switch tokens[0].keyword() {
+	case "INSERT":
+		// INSERT INTO table [(columns)] SELECT ...
+		for i < len(tokens) && tokens[i].keyword() != "INTO" {
+			i++
+		}
+		i++
+		target, i = readQualifiedName(tokens, i)
+		if i < len(tokens) && tokens[i].text == "(" {
+			end := matchingParen(tokens, i)
+			for _, tok := range tokens[i+1 : end] {
+				if tok.isIdent() {
+					targetColumns = append(targetColumns, tok.ident())
+				}
+			}
+			i = end + 1
+		}
+	case "CREATE":
+		// CREATE [OR REPLACE] [TEMP] [MATERIALIZED] TABLE|VIEW [IF NOT EXISTS] name [(columns)] AS SELECT ...
+		for i < len(tokens) && tokens[i].keyword() != "TABLE" && tokens[i].keyword() != "VIEW" {
+			i++
+		}
+		i++
+		if i+2 < len(tokens) && tokens[i].keyword() == "IF" {
+			i += 3
+		}
+		target, i = readQualifiedName(tokens, i)
+		if i < len(tokens) && tokens[i].text == "(" {
+			end := matchingParen(tokens, i)
+			for _, tok := range tokens[i+1 : end] {
+				if tok.isIdent() {
+					targetColumns = append(targetColumns, tok.ident())
+				}
+			}
+			i = end + 1
+		}
+		if i < len(tokens) && tokens[i].keyword() == "AS" {
+			i++
+		}
+	case "SELECT", "WITH", "(":
+	default:
+		return nil
+	}
+	if target == "" && tokens[0].keyword() != "SELECT" && tokens[0].keyword() != "WITH" && tokens[0].text != "(" {
+		return nil
+	}
+	if i >= len(tokens) || (tokens[i].keyword() != "SELECT" && tokens[i].keyword() != "WITH" && tokens[i].text != "(") {
+		return nil
+	}
+
+	outputs := p.parseQuery(tokens[i:], nil)
+	var edges []LineageEdge
+	for n, output := range outputs {
+		name := output.name
+		if n < len(targetColumns) {
+			name = targetColumns[n]
+		}
+		if len(output.sources) == 0 || name == "" {
+			continue
+		}
+		edges = append(edges, LineageEdge{
+			Target:  ColumnRef{Table: target, Column: name},
+			Sources: output.sources,
+			Query:   p.query,
+		})
+	}
+	return edges
+}
+
+// Upstream traces the columns that feed a column, following the statements that wrote
+// each source table in turn. The column is table.column, or a bare column name that
+// matches result columns and table columns of that name.
+func (l *Lineage) Upstream(column string) []LineageStep {
+	var steps []LineageStep
+	seen := map[string]bool{}
+	var walk func(ref ColumnRef, depth int, anyTable bool)
+	walk = func(ref ColumnRef, depth int, anyTable bool) {
+		if depth >= maxLineageDepth {
+			return
+		}
+		for _, edge := range l.Edges {
+			if !edge.Target.matches(ref, anyTable) {
+				continue
+			}
+			key := edge.Target.String() + "\x00" + edge.Query
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			steps = append(steps, LineageStep{LineageEdge: edge, Depth: depth})
+			for _, source := range edge.Sources {
+				if source.Table != "" {
+					walk(source, depth+1, false)
+				}
+			}
+		}
+	}
+	ref := parseColumnRef(column)
+	walk(ref, 0, ref.Table == "")
+	return steps
+}
+
+// Downstream traces the columns a column feeds, following tables written from it
+func (l *Lineage) Downstream(column string) []LineageStep {
+	var steps []LineageStep
+	seen := map[string]bool{}
+	var walk func(ref ColumnRef, depth int, anyTable bool)
+	walk = func(ref ColumnRef, depth int, anyTable bool) {
+		if depth >= maxLineageDepth {
+			return
+		}
+		for _, edge := range l.Edges {
+			if !slices.ContainsFunc(edge.Sources, func(source ColumnRef) bool { return source.matches(ref, anyTable) }) {
+				continue
+			}
+			key := edge.Target.String() + "\x00" + edge.Query
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			steps = append(steps, LineageStep{LineageEdge: edge, Depth: depth})
+			if edge.Target.Table != "" {
+				walk(edge.Target, depth+1, false)
+			}
+		}
+	}
+	ref := parseColumnRef(column)
+	walk(ref, 0, ref.Table == "")
+	return steps
+}
+
+// Columns lists the table columns and result columns the lineage knows about
+func (l *Lineage) Columns() []ColumnRef {
+	var columns []ColumnRef
+	add := func(ref ColumnRef) {
+		if ref.Column != "*" && !slices.Contains(columns, ref) {
+			columns = append(columns, ref)
+		}
+	}
+	for _, edge := range l.Edges {
+		add(edge.Target)
+		for _, source := range edge.Sources {
+			add(source)
+		}
+	}
+	return columns
+}
+
+func parseColumnRef(column string) ColumnRef {
+	column = strings.ToLower(strings.TrimSpace(column))
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		return ColumnRef{Table: column[:i], Column: column[i+1:]}
+	}
+	return ColumnRef{Column: column}
+}
+
+// matches compares column references without regard to case. A table written with a
+// schema matches the same table without one. With anyTable only the column must match.
+func (c ColumnRef) matches(other ColumnRef, anyTable bool) bool {
+	if !strings.EqualFold(c.Column, other.Column) && c.Column != "*" {
+		return false
+	}
+	if anyTable {
+		return c.Column != "*"
+	}
+	a, b := strings.ToLower(c.Table), strings.ToLower(other.Table)
+	return a == b || (a != "" && b != "" && (strings.HasSuffix(a, "."+b) || strings.HasSuffix(b, "."+a)))
+}
+
+// lineageQuestionPattern matches chat messages asking where a value comes from
+var lineageQuestionPattern = regexp.MustCompile(`(?i)(where (does|do|did|is|are) .*(come|coming) from|comes? from|derived|lineage|calculated|computed|populated|upstream|来自|从哪|怎么算|如何计算|来源)`)
+
+// LineageContext describes the lineage of the columns a chat message names, when the
+// message asks where a value comes from. It returns "" when neither applies.
+func (l *Lineage) LineageContext(message string) string {
+	if len(l.Edges) == 0 || !lineageQuestionPattern.MatchString(message) {
+		return ""
+	}
+	lower := strings.ToLower(message)
+	var sb strings.Builder
+	mentioned := map[string]bool{}
+	for _, ref := range l.Columns() {
+		name := ref.String()
+		table := strings.ToLower(ref.Table[strings.LastIndex(ref.Table, ".")+1:])
+		// A table column counts when the message names both the column and its table
+		if ref.Table == "" || mentioned[name] || !containsWord(lower, strings.ToLower(ref.Column)) || !containsWord(lower, table) {
+			continue
+		}
+		mentioned[name] = true
+		for _, step := range l.Upstream(name) {
+			fmt.Fprintf(&sb, "%s- %s <- %s\n", strings.Repeat("  ", step.Depth), step.Target, joinColumnRefs(step.Sources))
+		}
+	}
+	for _, edge := range l.Edges {
+		// Result columns named in the message, such as revenue from the last report
+		if edge.Target.Table == "" && containsWord(lower, strings.ToLower(edge.Target.Column)) && !mentioned[edge.Target.Column] {
+			mentioned[edge.Target.Column] = true
+			fmt.Fprintf(&sb, "- result column %s <- %s (%s)\n", edge.Target.Column, joinColumnRefs(edge.Sources), TruncateWidth(strings.Join(strings.Fields(edge.Query), " "), 120))
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	context := sb.String()
+	if len(context) > 2000 {
+		context = strings.ToValidUTF8(context[:2000], "") + "\n..."
+	}
+	return "Column lineage from the queries run this session (target <- sources):\n" + context
+}
+
+func joinColumnRefs(refs []ColumnRef) string {
+	names := make([]string, len(refs))
+	for i, ref := range refs {
+		names[i] = ref.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(text[i:], word)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(word)
+		if (start == 0 || !isIdentByte(text[start-1])) && (end == len(text) || !isIdentByte(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isIdentByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// sqlToken is a word, quoted identifier, string, number or punctuation character
+type sqlToken struct {
+	text   string
+	quoted bool // A "quoted", `quoted` or [quoted] identifier
+	str    bool // A 'string' literal
+	pos    int  // Byte offset in the query, where any opening quote is
+}
+
+func (t sqlToken) keyword() string {
+	if t.quoted || t.str {
+		return ""
+	}
+	return strings.ToUpper(t.text)
+}
+
+func (t sqlToken) isIdent() bool {
+	if t.quoted {
+		return true
+	}
+	if t.str || t.text == "" || !(t.text[0] == '_' || t.text[0] >= 'a' && t.text[0] <= 'z' || t.text[0] >= 'A' && t.text[0] <= 'Z') {
+		return false
+	}
+	return !sqlReservedWords[strings.ToUpper(t.text)]
+}
+
+// ident is the identifier's name: as written when quoted, otherwise in lower case
+func (t sqlToken) ident() string {
+	if t.quoted {
+		return t.text
+	}
+	return strings.ToLower(t.text)
+}
+
+// sqlReservedWords are keywords that are never column or table names in a query
+var sqlReservedWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`SELECT FROM WHERE GROUP BY HAVING ORDER LIMIT OFFSET FETCH UNION ALL
+		EXCEPT INTERSECT DISTINCT AS ON USING JOIN INNER LEFT RIGHT FULL OUTER CROSS NATURAL LATERAL
+		AND OR NOT NULL IS IN LIKE ILIKE BETWEEN EXISTS CASE WHEN THEN ELSE END ASC DESC NULLS FIRST
+		LAST TRUE FALSE INTERVAL OVER PARTITION WINDOW ROWS RANGE PRECEDING FOLLOWING UNBOUNDED
+		CURRENT ROW WITH RECURSIVE INTO VALUES INSERT UPDATE DELETE CREATE TABLE VIEW SET QUALIFY
+		FILTER WITHIN TOP ANY SOME CAST`) {
+		sqlReservedWords[word] = true
+	}
+}
+
+// clauseKeywords end the column list or FROM clause of a SELECT
+var clauseKeywords = []string{"FROM", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "FETCH",
+	"WINDOW", "QUALIFY", "UNION", "EXCEPT", "INTERSECT", "FOR", "RETURNING", "ON", "INTO"}
+
+func tokenizeSQL(query string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			var sb strings.Builder
+			j := i + 1
+			for j < len(query) {
+				if query[j] == closing {
+					// A doubled quote is an escaped one
+					if j+1 < len(query) && query[j+1] == closing && closing != ']' {
+						sb.WriteByte(closing)
+						j += 2
+						continue
+					}
+					break
+				}
+				sb.WriteByte(query[j])
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: sb.String(), quoted: c != '\'', str: c == '\'', pos: i})
+			i = j + 1
+		case c == '$' && dollarTag(query[i:]) != "":
+			// A dollar-quoted string, such as a function body, runs to the same tag
+			tag := dollarTag(query[i:])
+			body := query[i+len(tag):]
+			end := strings.Index(body, tag)
+			if end < 0 {
+				tokens = append(tokens, sqlToken{text: body, str: true, pos: i})
+				return tokens
+			}
+			tokens = append(tokens, sqlToken{text: body[:end], str: true, pos: i})
+			i += len(tag) + end + len(tag)
+		case isIdentByte(c) || c >= 0x80 || c == '$':
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] >= 0x80 || query[j] == '$') {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: query[i:j], pos: i})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: string(c), pos: i})
+			i++
+		}
+	}
+	return tokens
+}
+
+// dollarTag returns the opening tag of a PostgreSQL dollar-quoted string at the start of
+// s, such as $$ or $body$, or "" when s does not start with one. $1 is a parameter.
+func dollarTag(s string) string {
+	j := 1
+	if j < len(s) && (s[j] == '_' || s[j] >= 'a' && s[j] <= 'z' || s[j] >= 'A' && s[j] <= 'Z') {
+		for j < len(s) && isIdentByte(s[j]) {
+			j++
+		}
+	}
+	if j < len(s) && s[j] == '$' {
+		return s[:j+1]
+	}
+	return ""
+}
+
+func matchingParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			if !tokens[i].quoted && !tokens[i].str {
+				depth++
+			}
+		case ")":
+			if !tokens[i].quoted && !tokens[i].str {
+				if depth--; depth == 0 {
+					return i
+				}
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// readQualifiedName reads schema.table from position i, returning the position after it
+func readQualifiedName(tokens []sqlToken, i int) (string, int) {
+	var parts []string
+	for i < len(tokens) && (tokens[i].quoted || tokens[i].keyword() != "" && isIdentByte(tokens[i].text[0])) {
+		parts = append(parts, tokens[i].ident())
+		i++
+		if i < len(tokens) && tokens[i].text == "." && !tokens[i].str {
+			i++
+			continue
+		}
+		break
+	}
+	return strings.Join(parts, "."), i
+}
+
+// lineageOutput is a column a query returns and the columns it is computed from
+type lineageOutput struct {
+	name    string
+	sources []ColumnRef
+}
+
+// relation is a table or derived table in a FROM clause
+type relation struct {
+	table   string          // Base table name
+	derived []lineageOutput // Columns of a subquery or CTE
+}
+
+type lineageParser struct {
+	query string
+}
+
+// parseQuery analyzes a query: an optional WITH list, then SELECTs joined by set operators
+func (p *lineageParser) parseQuery(tokens []sqlToken, ctes map[string][]lineageOutput) []lineageOutput {
+	if len(tokens) > 0 && tokens[0].text == "(" && !tokens[0].str && !tokens[0].quoted {
+		end := matchingParen(tokens, 0)
+		return p.parseQuery(tokens[1:end], ctes)
+	}
+	if len(tokens) > 0 && tokens[0].keyword() == "WITH" {
+		ctes = maps.Clone(ctes)
+		if ctes == nil {
+			ctes = map[string][]lineageOutput{}
+		}
+		i := 1
+		if i < len(tokens) && tokens[i].keyword() == "RECURSIVE" {
+			i++
+		}
+		for i < len(tokens) {
+			name := tokens[i].ident()
+			i++
+			var columns []string
+			if i < len(tokens) && tokens[i].text == "(" {
+				end := matchingParen(tokens, i)
+				for _, tok := range tokens[i+1 : end] {
+					if tok.isIdent() {
+						columns = append(columns, tok.ident())
+					}
+				}
+				i = end + 1
+			}
+			for i < len(tokens) && tokens[i].text != "(" {
+				i++ // AS [NOT] MATERIALIZED
+			}
+			if i >= len(tokens) {
+				return nil
+			}
+			end := matchingParen(tokens, i)
+			outputs := p.parseQuery(tokens[i+1:end], ctes)
+			for n := range outputs {
+				if n < len(columns) {
+					outputs[n].name = columns[n]
+				}
+			}
+			ctes[name] = outputs
+			i = end + 1
+			if i < len(tokens) && tokens[i].text == "," {
+				i++
+				continue
+			}
+			break
+		}
+		tokens = tokens[i:]
+	}
+
+	// SELECT ... UNION SELECT ...: names come from the first, sources from all
+	var outputs []lineageOutput
+	start, depth := 0, 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) {
+			switch tokens[i].text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			kw := tokens[i].keyword()
+			if depth != 0 || (kw != "UNION" && kw != "EXCEPT" && kw != "INTERSECT") {
+				continue
+			}
+		}
+		part := p.selectOutputs(tokens[start:i], ctes)
+		if outputs == nil {
+			outputs = part
+		} else {
+			for n := range min(len(outputs), len(part)) {
+				outputs[n].sources = mergeRefs(outputs[n].sources, part[n].sources)
+			}
+		}
+		start = i + 1
+		for start < len(tokens) && (tokens[start].keyword() == "ALL" || tokens[start].keyword() == "DISTINCT") {
+			start++
+		}
+	}
+	return outputs
+}
+
+// selectOutputs analyzes one SELECT
+func (p *lineageParser) selectOutputs(tokens []sqlToken, ctes map[string][]lineageOutput) []lineageOutput {
+	if len(tokens) > 0 && tokens[0].text == "(" {
+		return p.parseQuery(tokens, ctes)
+	}
+	if len(tokens) == 0 || tokens[0].keyword() != "SELECT" {
+		return nil
+	}
+	i := 1
+	for i < len(tokens) {
+		switch tokens[i].keyword() {
+		case "DISTINCT", "ALL":
+			i++
+			if i < len(tokens) && tokens[i].keyword() == "ON" && i+1 < len(tokens) {
+				i = matchingParen(tokens, i+1) + 1
+			}
+			continue
+		case "TOP":
+			i += 2
+			continue
+		}
+		break
+	}
+
+	listEnd := clauseEnd(tokens, i, "FROM", "INTO", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "WINDOW", "QUALIFY")
+	relations := map[string]relation{}
+	var order []string
+	if listEnd < len(tokens) && tokens[listEnd].keyword() == "FROM" {
+		fromEnd := clauseEnd(tokens, listEnd+1, "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "FETCH", "WINDOW", "QUALIFY", "FOR")
+		relations, order = p.fromRelations(tokens[listEnd+1:fromEnd], ctes)
+	}
+
+	var outputs []lineageOutput
+	for _, item := range splitTopLevel(tokens[i:listEnd], ",") {
+		outputs = append(outputs, p.selectItem(item, relations, order, ctes)...)
+	}
+	return outputs
+}
+
+// clauseEnd finds the first of the keywords at the top level from position i
+func clauseEnd(tokens []sqlToken, i int, keywords ...string) int {
+	depth := 0
+	for ; i < len(tokens); i++ {
+		switch {
+		case tokens[i].str || tokens[i].quoted:
+		case tokens[i].text == "(":
+			depth++
+		case tokens[i].text == ")":
+			depth--
+		}
+		if depth == 0 && slices.Contains(keywords, tokens[i].keyword()) {
+			return i
+		}
+	}
+	return len(tokens)
+}
+
+func splitTopLevel(tokens []sqlToken, sep string) [][]sqlToken {
+	var parts [][]sqlToken
+	depth, start := 0, 0
+	for i, tok := range tokens {
+		switch {
+		case tok.str || tok.quoted:
+		case tok.text == "(":
+			depth++
+		case tok.text == ")":
+			depth--
+		case tok.text == sep && depth == 0:
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+// fromRelations reads the tables and subqueries of a FROM clause by alias
+func (p *lineageParser) fromRelations(tokens []sqlToken, ctes map[string][]lineageOutput) (map[string]relation, []string) {
+	relations := map[string]relation{}
+	var order []string
+	i := 0
+	for i < len(tokens) {
+		var rel relation
+		var name string
+		if tokens[i].text == "(" && !tokens[i].str && !tokens[i].quoted {
+			end := matchingParen(tokens, i)
+			rel.derived = p.parseQuery(tokens[i+1:end], ctes)
+			i = end + 1
+		} else if tokens[i].isIdent() {
+			name, i = readQualifiedName(tokens, i)
+			if derived, ok := ctes[name]; ok {
+				rel.derived = derived
+			} else {
+				rel.table = name
+			}
+		} else {
+			i++
+			continue
+		}
+
+		alias := name
+		if i < len(tokens) && tokens[i].keyword() == "AS" {
+			i++
+		}
+		if i < len(tokens) && tokens[i].isIdent() {
+			alias = tokens[i].ident()
+			i++
+		}
+		if alias == "" {
+			alias = fmt.Sprintf("subquery %d", len(order)+1)
+		}
+		// Unqualified columns resolve against the table name as well as its alias
+		if _, ok := relations[alias]; !ok {
+			order = append(order, alias)
+		}
+		relations[alias] = rel
+		if rel.table != "" && alias != rel.table {
+			short := rel.table[strings.LastIndex(rel.table, ".")+1:]
+			if _, taken := relations[short]; !taken {
+				relations[short] = rel
+			}
+		}
+
+		// Skip join conditions up to the next relation
+		depth := 0
+		for i < len(tokens) {
+			tok := tokens[i]
+			if tok.text == "(" {
+				depth++
+			} else if tok.text == ")" {
+				depth--
+			} else if depth == 0 && (tok.text == "," || tok.keyword() == "JOIN") {
+				i++
+				if i < len(tokens) && tokens[i].keyword() == "LATERAL" {
+					i++
+				}
+				break
+			}
+			i++
+		}
+	}
+	return relations, order
+}
+
+// selectItem analyzes one entry of a SELECT list, which is several columns for *
+func (p *lineageParser) selectItem(tokens []sqlToken, relations map[string]relation, order []string, ctes map[string][]lineageOutput) []lineageOutput {
+	if len(tokens) == 0 {
+		return nil
+	}
+	// * and alias.*
+	if last := tokens[len(tokens)-1]; last.text == "*" && !last.str && !last.quoted &&
+		(len(tokens) == 1 || (len(tokens) == 3 && tokens[1].text == ".")) {
+		aliases := order
+		if len(tokens) == 3 {
+			aliases = []string{tokens[0].ident()}
+		}
+		var outputs []lineageOutput
+		for _, alias := range aliases {
+			rel, ok := relations[alias]
+			switch {
+			case !ok:
+			case rel.derived != nil:
+				outputs = append(outputs, rel.derived...)
+			default:
+				outputs = append(outputs, lineageOutput{name: "*", sources: []ColumnRef{{Table: rel.table, Column: "*"}}})
+			}
+		}
+		return outputs
+	}
+
+	// [AS] alias at the end
+	name := ""
+	expr := tokens
+	n := len(tokens)
+	if n >= 2 && tokens[n-2].keyword() == "AS" && (tokens[n-1].isIdent() || tokens[n-1].str) {
+		name, expr = tokens[n-1].ident(), tokens[:n-2]
+		if tokens[n-1].str {
+			name = tokens[n-1].text
+		}
+	} else if n >= 2 && tokens[n-1].isIdent() && tokens[n-2].text != "." && (tokens[n-2].isIdent() || tokens[n-2].text == ")" || tokens[n-2].keyword() == "END" || tokens[n-2].str) {
+		name, expr = tokens[n-1].ident(), tokens[:n-1]
+	}
+
+	sources, single := p.expressionRefs(expr, relations, order, ctes)
+	if name == "" {
+		switch {
+		case single != "":
+			name = single
+		case len(expr) > 1 && expr[1].text == "(" && expr[0].keyword() != "":
+			// Unnamed function calls are named after the function, as in PostgreSQL
+			name = strings.ToLower(expr[0].text)
+		}
+	}
+	return []lineageOutput{{name: name, sources: sources}}
+}
+
+// expressionRefs finds the columns an expression reads. single is the column name
+// when the expression is nothing but a column.
+func (p *lineageParser) expressionRefs(tokens []sqlToken, relations map[string]relation, order []string, ctes map[string][]lineageOutput) ([]ColumnRef, string) {
+	var refs []ColumnRef
+	single := ""
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok.str:
+			continue
+		case tok.text == "(" && i+1 < len(tokens) && (tokens[i+1].keyword() == "SELECT" || tokens[i+1].keyword() == "WITH"):
+			// A scalar subquery reads whatever its columns read
+			end := matchingParen(tokens, i)
+			for _, output := range p.parseQuery(tokens[i+1:end], ctes) {
+				refs = mergeRefs(refs, output.sources)
+			}
+			i = end
+			continue
+		case tok.text == ":" && i+2 < len(tokens) && tokens[i+1].text == ":":
+			i += 2 // ::type
+			continue
+		case tok.keyword() == "AS" && i+1 < len(tokens):
+			i++ // CAST(x AS type)
+			continue
+		case !tok.isIdent():
+			continue
+		}
+		// Function calls are not columns
+		var parts []string
+		j := i
+		for j < len(tokens) && tokens[j].isIdent() {
+			parts = append(parts, tokens[j].ident())
+			if j+2 < len(tokens) && tokens[j+1].text == "." && !tokens[j+1].str {
+				j += 2
+				continue
+			}
+			break
+		}
+		if j+1 < len(tokens) && tokens[j+1].text == "(" && !tokens[j+1].str {
+			i = j
+			continue
+		}
+		if j+2 < len(tokens) && tokens[j+1].text == "." && tokens[j+2].text == "*" {
+			i = j + 2
+			continue
+		}
+		i = j
+		resolved := resolveColumn(parts, relations, order)
+		refs = mergeRefs(refs, resolved)
+		if len(tokens) == len(parts)*2-1 {
+			single = parts[len(parts)-1]
+		}
+	}
+	return refs, single
+}
+
+// resolveColumn finds the table of a column written column, alias.column or
+// schema.table.column, looking through subqueries and CTEs to their sources
+func resolveColumn(parts []string, relations map[string]relation, order []string) []ColumnRef {
+	column := parts[len(parts)-1]
+	lookup := func(rel relation) []ColumnRef {
+		if rel.derived == nil {
+			return []ColumnRef{{Table: rel.table, Column: column}}
+		}
+		for _, output := range rel.derived {
+			if output.name == column {
+				return output.sources
+			}
+		}
+		for _, output := range rel.derived {
+			// Columns of a * from a table in the subquery
+			if output.name == "*" && len(output.sources) == 1 {
+				return []ColumnRef{{Table: output.sources[0].Table, Column: column}}
+			}
+		}
+		return nil
+	}
+
+	if len(parts) > 1 {
+		qualifier := strings.Join(parts[:len(parts)-1], ".")
+		if rel, ok := relations[qualifier]; ok {
+			return lookup(rel)
+		}
+		return []ColumnRef{{Table: qualifier, Column: column}}
+	}
+	if len(order) == 1 {
+		return lookup(relations[order[0]])
+	}
+	// With several relations, only subqueries and CTEs tell which one has the column
+	for _, alias := range order {
+		if rel := relations[alias]; rel.derived != nil {
+			for _, output := range rel.derived {
+				if output.name == column {
+					return output.sources
+				}
+			}
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+	return []ColumnRef{{Column: column}}
+}
+
+func mergeRefs(refs, more []ColumnRef) []ColumnRef {
+	for _, ref := range more {
+		if !slices.Contains(refs, ref) {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
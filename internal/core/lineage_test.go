@@ -0,0 +1,101 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeLineage(t *testing.T) {
+	testCases := []struct {
+		query string
+		want  map[string][]ColumnRef
+	}{
+		{
+			query: "SELECT o.id, o.price * o.qty AS total, upper(c.name) customer FROM orders o JOIN customers c ON c.id = o.customer_id",
+			want: map[string][]ColumnRef{
+				"id":       {{"orders", "id"}},
+				"total":    {{"orders", "price"}, {"orders", "qty"}},
+				"customer": {{"customers", "name"}},
+			},
+		},
+		{
+			query: "INSERT INTO orders (id, total) SELECT order_id, SUM(price * quantity) FROM order_items GROUP BY order_id",
+			want: map[string][]ColumnRef{
+				"orders.id":    {{"order_items", "order_id"}},
+				"orders.total": {{"order_items", "price"}, {"order_items", "quantity"}},
+			},
+		},
+		{
+			query: `CREATE TABLE revenue AS
+				WITH paid AS (SELECT amount, "User ID" AS user_id FROM public.payments WHERE status = 'paid' -- settled only
+				)
+				SELECT p.user_id, SUM(p.amount) AS revenue, (SELECT max(r.amount) FROM refunds r) AS refunded
+				FROM paid p`,
+			want: map[string][]ColumnRef{
+				"revenue.user_id":  {{"public.payments", "User ID"}},
+				"revenue.revenue":  {{"public.payments", "amount"}},
+				"revenue.refunded": {{"refunds", "amount"}},
+			},
+		},
+		{
+			query: "SELECT sub.n, CAST(sub.d AS text) d FROM (SELECT a + b AS n, created_at d FROM t1 UNION ALL SELECT c, updated_at FROM t2) sub",
+			want: map[string][]ColumnRef{
+				"n": {{"t1", "a"}, {"t1", "b"}, {"t2", "c"}},
+				"d": {{"t1", "created_at"}, {"t2", "updated_at"}},
+			},
+		},
+		{query: "UPDATE orders SET total = 0", want: map[string][]ColumnRef{}},
+	}
+
+	for _, tc := range testCases {
+		got := map[string][]ColumnRef{}
+		for _, edge := range AnalyzeLineage(tc.query) {
+			got[edge.Target.String()] = edge.Sources
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("AnalyzeLineage(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestLineageTrace(t *testing.T) {
+	lineage := BuildLineage([]string{
+		"INSERT INTO order_items (price, quantity) SELECT unit_price, qty FROM staging_items",
+		"INSERT INTO orders (id, total) SELECT order_id, SUM(price * quantity) FROM order_items GROUP BY order_id",
+		"SELECT date(o.created_at) AS day, SUM(o.total) AS daily_revenue FROM orders o GROUP BY 1",
+	})
+
+	var upstream []string
+	for _, step := range lineage.Upstream("orders.total") {
+		upstream = append(upstream, strings.Repeat(" ", step.Depth)+step.Target.String()+" <- "+joinColumnRefs(step.Sources))
+	}
+	want := []string{
+		"orders.total <- order_items.price, order_items.quantity",
+		" order_items.price <- staging_items.unit_price",
+		" order_items.quantity <- staging_items.qty",
+	}
+	if !reflect.DeepEqual(upstream, want) {
+		t.Errorf("Upstream = %q, want %q", upstream, want)
+	}
+
+	var downstream []string
+	for _, step := range lineage.Downstream("staging_items.qty") {
+		downstream = append(downstream, step.Target.String())
+	}
+	if !reflect.DeepEqual(downstream, []string{"order_items.quantity", "orders.total", "daily_revenue"}) {
+		t.Errorf("Unexpected downstream %v", downstream)
+	}
+
+	context := lineage.LineageContext("Where does the total in orders come from?")
+	if !strings.Contains(context, "orders.total <- order_items.price, order_items.quantity") ||
+		!strings.Contains(context, "order_items.quantity <- staging_items.qty") {
+		t.Errorf("Unexpected context %q", context)
+	}
+	if context := lineage.LineageContext("How is daily_revenue calculated?"); !strings.Contains(context, "result column daily_revenue <- orders.total") {
+		t.Errorf("Unexpected context %q", context)
+	}
+	if context := lineage.LineageContext("Show the orders total by month"); context != "" {
+		t.Errorf("Expected no context for a question that is not about lineage, got %q", context)
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockWait is a session waiting on a lock held by another session
+type LockWait struct {
+	BlockedPID    string
+	BlockedUser   string
+	BlockedQuery  string
+	Wait          time.Duration
+	BlockingPID   string
+	BlockingUser  string
+	BlockingQuery string
+	BlockingState string
+	LockType      string
+}
+
+// BlockingNode is a session in a blocking chain; Blocked lists the sessions waiting on it
+type BlockingNode struct {
+	PID      string
+	User     string
+	Query    string
+	State    string
+	Wait     time.Duration // How long this session has waited, zero for chain roots
+	LockType string
+	Blocked  []*BlockingNode
+}
+
+const postgresLockWaitsQuery = `SELECT blocked.pid, COALESCE(blocked.usename, ''), COALESCE(blocked.query, ''),
+	COALESCE(EXTRACT(EPOCH FROM (now() - blocked.query_start)), 0),
+	blocking.pid, COALESCE(blocking.usename, ''), COALESCE(blocking.query, ''), COALESCE(blocking.state, ''),
+	COALESCE(blocked.wait_event_type || ':' || blocked.wait_event, '')
+FROM pg_stat_activity blocked
+JOIN LATERAL unnest(pg_blocking_pids(blocked.pid)) AS b(pid) ON true
+JOIN pg_stat_activity blocking ON blocking.pid = b.pid
+ORDER BY blocking.pid, blocked.pid`
+
+const mysqlLockWaitsQuery = `SELECT waiting_pid, '', COALESCE(waiting_query, ''), wait_age_secs,
+	blocking_pid, '', COALESCE(blocking_query, ''), '', CONCAT(locked_type, ' on ', locked_table)
+FROM sys.innodb_lock_waits
+ORDER BY blocking_pid, waiting_pid`
+
+// ListLockWaits returns the current lock waits from the dialect's system views
+func ListLockWaits(conn Connection, dbType DatabaseType) ([]LockWait, error) {
+	var query string
+	switch dbType {
+	case PostgreSQL:
+		query = postgresLockWaitsQuery
+	case MySQL:
+		query = mysqlLockWaitsQuery
+	default:
+		return nil, ErrUnsupportedDatabase
+	}
+
+	result, err := conn.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var waits []LockWait
+	for row := range result.Itor() {
+		seconds, _ := strconv.ParseFloat(row[3].String(), 64)
+		waits = append(waits, LockWait{
+			BlockedPID:    row[0].String(),
+			BlockedUser:   row[1].String(),
+			BlockedQuery:  row[2].String(),
+			Wait:          time.Duration(seconds * float64(time.Second)),
+			BlockingPID:   row[4].String(),
+			BlockingUser:  row[5].String(),
+			BlockingQuery: row[6].String(),
+			BlockingState: row[7].String(),
+			LockType:      row[8].String(),
+		})
+	}
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	return waits, nil
+}
+
+// BuildBlockingTree groups lock waits into chains rooted at sessions that are not
+// themselves waiting. Sessions caught in a cycle are rooted at their lowest PID.
+func BuildBlockingTree(waits []LockWait) []*BlockingNode {
+	nodes := make(map[string]*BlockingNode)
+	node := func(pid, user, query string) *BlockingNode {
+		n, ok := nodes[pid]
+		if !ok {
+			n = &BlockingNode{PID: pid}
+			nodes[pid] = n
+		}
+		if n.User == "" {
+			n.User = user
+		}
+		if n.Query == "" {
+			n.Query = query
+		}
+		return n
+	}
+
+	edges := make(map[string][]string)
+	waiting := make(map[string]bool)
+	for _, w := range waits {
+		blocking := node(w.BlockingPID, w.BlockingUser, w.BlockingQuery)
+		if blocking.State == "" {
+			blocking.State = w.BlockingState
+		}
+		blocked := node(w.BlockedPID, w.BlockedUser, w.BlockedQuery)
+		blocked.Wait = w.Wait
+		blocked.LockType = w.LockType
+
+		edges[w.BlockingPID] = append(edges[w.BlockingPID], w.BlockedPID)
+		waiting[w.BlockedPID] = true
+	}
+
+	pids := make([]string, 0, len(nodes))
+	for pid := range nodes {
+		pids = append(pids, pid)
+	}
+	sort.Slice(pids, func(i, j int) bool { return comparePIDs(pids[i], pids[j]) })
+
+	visited := make(map[string]bool)
+	var attach func(pid string) *BlockingNode
+	attach = func(pid string) *BlockingNode {
+		visited[pid] = true
+		n := nodes[pid]
+		for _, child := range edges[pid] {
+			if !visited[child] {
+				n.Blocked = append(n.Blocked, attach(child))
+			}
+		}
+		return n
+	}
+
+	var roots []*BlockingNode
+	for _, pid := range pids {
+		if !waiting[pid] && !visited[pid] {
+			roots = append(roots, attach(pid))
+		}
+	}
+	// Anything left is part of a wait cycle
+	for _, pid := range pids {
+		if !visited[pid] {
+			roots = append(roots, attach(pid))
+		}
+	}
+	return roots
+}
+
+// comparePIDs orders numeric PIDs numerically and everything else lexically
+func comparePIDs(a, b string) bool {
+	x, errX := strconv.ParseInt(a, 10, 64)
+	y, errY := strconv.ParseInt(b, 10, 64)
+	if errX == nil && errY == nil {
+		return x < y
+	}
+	return a < b
+}
+
+// RenderBlockingTree draws blocking chains as blocker → blocked trees with their SQL text
+func RenderBlockingTree(roots []*BlockingNode) string {
+	var sb strings.Builder
+	for _, root := range roots {
+		renderBlockingNode(&sb, root, "", "")
+	}
+	return sb.String()
+}
+
+func renderBlockingNode(sb *strings.Builder, node *BlockingNode, prefix, childPrefix string) {
+	sb.WriteString(prefix)
+	if node.Wait > 0 || node.LockType != "" {
+		sb.WriteString("⏳ ")
+	} else {
+		sb.WriteString("🔒 ")
+	}
+	sb.WriteString(node.PID)
+	if node.User != "" {
+		sb.WriteString(" " + node.User)
+	}
+	if node.State != "" {
+		sb.WriteString(" (" + node.State + ")")
+	}
+	if node.Wait > 0 {
+		sb.WriteString(fmt.Sprintf(" waiting %v", node.Wait.Round(time.Second)))
+	}
+	if node.LockType != "" {
+		sb.WriteString(" [" + node.LockType + "]")
+	}
+	sb.WriteString("\n")
+
+	if query := TruncateWidth(strings.Join(strings.Fields(node.Query), " "), 120); query != "" {
+		sb.WriteString(childPrefix + "   " + query + "\n")
+	}
+
+	for i, child := range node.Blocked {
+		branch, next := "├─ ", "│  "
+		if i == len(node.Blocked)-1 {
+			branch, next = "└─ ", "   "
+		}
+		renderBlockingNode(sb, child, childPrefix+branch, childPrefix+next)
+	}
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildBlockingTree(t *testing.T) {
+	waits := []LockWait{
+		{BlockedPID: "20", BlockedQuery: "UPDATE orders SET status = 'paid'", Wait: 30 * time.Second, LockType: "Lock:transactionid",
+			BlockingPID: "10", BlockingUser: "alice", BlockingQuery: "BEGIN; UPDATE orders SET total = 0", BlockingState: "idle in transaction"},
+		{BlockedPID: "30", BlockedQuery: "ALTER TABLE orders ADD COLUMN note text", Wait: 5 * time.Second, LockType: "Lock:relation",
+			BlockingPID: "20", BlockingQuery: "UPDATE orders SET status = 'paid'"},
+		{BlockedPID: "40", Wait: time.Second, BlockingPID: "10"},
+	}
+
+	roots := BuildBlockingTree(waits)
+	if len(roots) != 1 || roots[0].PID != "10" {
+		t.Fatalf("Expected a single chain rooted at 10, got %+v", roots)
+	}
+	if len(roots[0].Blocked) != 2 || roots[0].Blocked[0].PID != "20" || roots[0].Blocked[0].Blocked[0].PID != "30" {
+		t.Errorf("Unexpected chain shape: %s", RenderBlockingTree(roots))
+	}
+
+	rendered := RenderBlockingTree(roots)
+	expected := []string{
+		"🔒 10 alice (idle in transaction)\n",
+		"├─ ⏳ 20 waiting 30s [Lock:transactionid]\n",
+		"│  └─ ⏳ 30 waiting 5s [Lock:relation]\n",
+		"ALTER TABLE orders ADD COLUMN note text",
+		"└─ ⏳ 40 waiting 1s\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(rendered, line) {
+			t.Errorf("Expected %q in:\n%s", line, rendered)
+		}
+	}
+}
+
+func TestBuildBlockingTreeCycle(t *testing.T) {
+	waits := []LockWait{
+		{BlockedPID: "2", BlockingPID: "1"},
+		{BlockedPID: "1", BlockingPID: "2"},
+	}
+
+	roots := BuildBlockingTree(waits)
+	if len(roots) != 1 || roots[0].PID != "1" || len(roots[0].Blocked) != 1 {
+		t.Errorf("Expected cycle to be rooted at the lowest PID, got %s", RenderBlockingTree(roots))
+	}
+}
@@ -0,0 +1,219 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMigrationsDir is the directory checked for migration files
+const DefaultMigrationsDir = "migrations"
+
+// MigrationsTable records which migrations have been applied
+const MigrationsTable = "schema_migrations"
+
+// migrationFilePattern matches 001_name.sql, 001_name.up.sql and 001_name.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+?)(\.up|\.down)?\.sql$`)
+
+// Migration is one numbered migration, with its optional rollback script
+type Migration struct {
+	Version   int64
+	Name      string
+	UpPath    string
+	DownPath  string     // Empty when the migration cannot be rolled back
+	AppliedAt *time.Time // Nil while pending
+}
+
+// Applied reports whether the migration has been run
+func (m Migration) Applied() bool {
+	return m.AppliedAt != nil
+}
+
+// MigrationsDirExists reports whether dir exists and is a directory
+func MigrationsDirExists(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// LoadMigrations reads the migration files in dir, ordered by version
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if match[3] == ".down" {
+			m.DownPath = path
+		} else {
+			if m.UpPath != "" {
+				return nil, fmt.Errorf("migration version %d has more than one up script", version)
+			}
+			m.UpPath = path
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpPath == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// EnsureMigrationsTable creates the schema_migrations table when it does not exist
+func EnsureMigrationsTable(conn Connection) error {
+	db := conn.(*connection).db
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS " + MigrationsTable +
+		" (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)")
+	return err
+}
+
+// MigrationStatus loads the migrations in dir and marks those recorded in
+// schema_migrations. It only reads: without the table, nothing has been applied yet.
+func MigrationStatus(conn Connection, dir string) ([]Migration, error) {
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := conn.ListTables()
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(tables, func(table string) bool { return strings.EqualFold(table, MigrationsTable) }) {
+		return migrations, nil
+	}
+
+	rows, err := conn.(*connection).db.Query("SELECT version, applied_at FROM " + MigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range migrations {
+		if at, ok := applied[migrations[i].Version]; ok {
+			migrations[i].AppliedAt = &at
+		}
+	}
+	return migrations, nil
+}
+
+// ApplyMigration runs a migration's up script and records it in one transaction, so a
+// failing statement leaves neither schema changes nor a schema_migrations row behind.
+// Databases with non-transactional DDL (MySQL) may still keep statements that ran.
+// schema_migrations is created on the first migration applied.
+func ApplyMigration(conn Connection, m Migration) error {
+	if conn.(*connection).config.ReadOnly {
+		return ErrReadOnlyConnection
+	}
+	if err := EnsureMigrationsTable(conn); err != nil {
+		return err
+	}
+	return runMigrationScript(conn, m.UpPath, func(exec func(string, ...any) error) error {
+		return exec("INSERT INTO "+MigrationsTable+" (version, name, applied_at) VALUES ("+placeholders(conn, 3)+")",
+			m.Version, m.Name, time.Now().UTC())
+	})
+}
+
+// RevertMigration runs a migration's down script and removes its record in one transaction
+func RevertMigration(conn Connection, m Migration) error {
+	if m.DownPath == "" {
+		return fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+	}
+	return runMigrationScript(conn, m.DownPath, func(exec func(string, ...any) error) error {
+		return exec("DELETE FROM "+MigrationsTable+" WHERE version = "+placeholders(conn, 1), m.Version)
+	})
+}
+
+func runMigrationScript(conn Connection, path string, record func(exec func(string, ...any) error) error) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	c := conn.(*connection)
+	if c.config.ReadOnly {
+		return ErrReadOnlyConnection
+	}
+	tx, err := c.db.Begin()
+	if err != nil {
+		return err
+	}
+	exec := func(query string, args ...any) error {
+		_, err := tx.Exec(query, args...)
+		return err
+	}
+
+	for _, stmt := range SplitStatements(string(content)) {
+		if err := exec(stmt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	if err := record(exec); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// placeholders returns n bind parameters in the connection's dialect
+func placeholders(conn Connection, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		if conn.(*connection).config.DatabaseType == PostgreSQL {
+			params[i] = fmt.Sprintf("$%d", i+1)
+		} else {
+			params[i] = "?"
+		}
+	}
+	return strings.Join(params, ", ")
+}
+
+// SplitStatements splits a SQL script, such as a migration or an @ file, into its
+// statements. Semicolons in strings, quoted names, dollar-quoted bodies and comments do
+// not end a statement, and comments between statements are dropped.
+func SplitStatements(content string) []string {
+	return splitStatementText(content)
+}
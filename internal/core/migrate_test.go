@@ -0,0 +1,211 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMigrations(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    map[string]string
+		expected []string
+		hasError bool
+	}{
+		{
+			name: "Ordered by version with optional down scripts",
+			files: map[string]string{
+				"010_add_index.sql":       "",
+				"002_users.up.sql":        "",
+				"002_users.down.sql":      "",
+				"README.md":               "",
+				"001_init.sql":            "",
+				"not_a_migration.sql":     "",
+				"003_orders.up.sql":       "",
+				"003_orders.down.sql.bak": "",
+			},
+			expected: []string{"init", "users", "orders", "add_index"},
+		},
+		{
+			name:     "Down script without up",
+			files:    map[string]string{"001_init.down.sql": ""},
+			hasError: true,
+		},
+		{
+			name:     "Version reused",
+			files:    map[string]string{"001_init.sql": "", "001_other.sql": ""},
+			hasError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			migrations, err := LoadMigrations(writeMigrations(t, tc.files))
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", migrations)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for _, m := range migrations {
+				names = append(names, m.Name)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, names)
+			}
+			if migrations[1].DownPath == "" || migrations[0].DownPath != "" {
+				t.Errorf("Expected only users to have a down script, got %+v", migrations)
+			}
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "Script",
+			content:  "-- create\nCREATE TABLE a (\n  id INTEGER\n);\n\nINSERT INTO a VALUES (1);\nINSERT INTO a VALUES (2)",
+			expected: []string{"CREATE TABLE a (\n  id INTEGER\n)", "INSERT INTO a VALUES (1)", "INSERT INTO a VALUES (2)"},
+		},
+		{
+			name:     "Several on a line",
+			content:  "SELECT * FROM users; SELECT * FROM posts;",
+			expected: []string{"SELECT * FROM users", "SELECT * FROM posts"},
+		},
+		{
+			name:     "Comment after the semicolon",
+			content:  "SELECT 1; -- first\nSELECT 2; /* second */",
+			expected: []string{"SELECT 1", "SELECT 2"},
+		},
+		{
+			name:     "Semicolons in strings and names",
+			content:  "INSERT INTO notes VALUES ('a; b', \"c;d\");\nSELECT 1",
+			expected: []string{"INSERT INTO notes VALUES ('a; b', \"c;d\")", "SELECT 1"},
+		},
+		{
+			name: "Dollar-quoted body",
+			content: "CREATE FUNCTION one() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql;\n" +
+				"DO $body$ BEGIN PERFORM one(); END $body$;",
+			expected: []string{
+				"CREATE FUNCTION one() RETURNS int AS $$\nBEGIN\n  RETURN 1;\nEND;\n$$ LANGUAGE plpgsql",
+				"DO $body$ BEGIN PERFORM one(); END $body$",
+			},
+		},
+		{
+			name:     "Parameters are not dollar quotes",
+			content:  "SELECT $1; SELECT $2",
+			expected: []string{"SELECT $1", "SELECT $2"},
+		},
+		{
+			name:     "Only comments",
+			content:  "-- This is a comment\n-- Another comment",
+			expected: nil,
+		},
+		{
+			name:     "Empty",
+			content:  "",
+			expected: nil,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SplitStatements(tc.content); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+
+	// Lines are not limited in length
+	long := "SELECT '" + strings.Repeat("x", 100<<10) + "';\nSELECT 2;"
+	if got := SplitStatements(long); len(got) != 2 || got[1] != "SELECT 2" {
+		t.Errorf("Expected a long line to be split whole, got %d statements", len(got))
+	}
+}
+
+func BenchmarkSplitStatements(b *testing.B) {
+	content := "SELECT * FROM users; SELECT * FROM posts; SELECT COUNT(*) FROM comments;"
+	for i := 0; i < b.N; i++ {
+		SplitStatements(content)
+	}
+}
+
+func TestApplyAndRevertMigrations(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	dir := writeMigrations(t, map[string]string{
+		"001_users.up.sql":   "CREATE TABLE users (id INTEGER PRIMARY KEY);\nINSERT INTO users VALUES (1);",
+		"001_users.down.sql": "DROP TABLE users;",
+		"002_broken.sql":     "CREATE TABLE orders (id INTEGER);\nINSERT INTO missing VALUES (1);",
+	})
+
+	migrations, err := MigrationStatus(conn, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Applied() {
+		t.Fatalf("Expected 2 pending migrations, got %+v", migrations)
+	}
+	// Status only reads; the table is created by the first migration applied
+	if tables, _ := conn.ListTables(); slices.Contains(tables, MigrationsTable) {
+		t.Errorf("Expected status to leave %s uncreated, got tables %v", MigrationsTable, tables)
+	}
+
+	if err := ApplyMigration(conn, migrations[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := ApplyMigration(conn, migrations[1]); err == nil {
+		t.Fatal("Expected broken migration to fail")
+	}
+
+	migrations, err = MigrationStatus(conn, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !migrations[0].Applied() || migrations[1].Applied() {
+		t.Errorf("Expected only the first migration applied, got %+v", migrations)
+	}
+	// The failed migration was rolled back, including its first statement
+	if tables, _ := conn.ListTables(); slices.Contains(tables, "orders") {
+		t.Errorf("Expected orders to be rolled back, got tables %v", tables)
+	}
+
+	if err := RevertMigration(conn, migrations[0]); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := RevertMigration(conn, migrations[1]); err == nil {
+		t.Error("Expected error reverting a migration without a down script")
+	}
+
+	migrations, err = MigrationStatus(conn, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if migrations[0].Applied() {
+		t.Errorf("Expected users to be reverted, got %+v", migrations[0])
+	}
+	if tables, _ := conn.ListTables(); slices.Contains(tables, "users") {
+		t.Errorf("Expected users table to be dropped, got tables %v", tables)
+	}
+}
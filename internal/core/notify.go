@@ -0,0 +1,52 @@
+package core
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// DefaultNotifyAfter is how long a query runs before its end is announced
+const DefaultNotifyAfter = 30 * time.Second
+
+// Ways of announcing that a long query has ended
+const (
+	NotifyBell    = "bell"    // Ring the terminal bell
+	NotifyDesktop = "desktop" // Show a desktop notification
+	NotifyBoth    = "both"
+)
+
+// Scripts showing a notification whose title and message are in the environment, so
+// neither needs quoting
+const (
+	osascriptNotify  = `display notification (system attribute "SQLTERM_NOTIFY_MESSAGE") with title (system attribute "SQLTERM_NOTIFY_TITLE")`
+	powershellNotify = `Add-Type -AssemblyName System.Windows.Forms
+$icon = New-Object System.Windows.Forms.NotifyIcon
+$icon.Icon = [System.Drawing.SystemIcons]::Information
+$icon.Visible = $true
+$icon.ShowBalloonTip(10000, $env:SQLTERM_NOTIFY_TITLE, $env:SQLTERM_NOTIFY_MESSAGE, 'Info')
+Start-Sleep -Seconds 10
+$icon.Dispose()`
+)
+
+// DesktopNotify shows a desktop notification: through osascript on macOS, a PowerShell
+// balloon on Windows and notify-send elsewhere. It returns once the notifier has
+// started, without waiting for the notification to close.
+func DesktopNotify(title, message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", osascriptNotify)
+	case "windows":
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", powershellNotify)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=sqlterm", title, message)
+	}
+	cmd.Env = append(os.Environ(), "SQLTERM_NOTIFY_TITLE="+title, "SQLTERM_NOTIFY_MESSAGE="+message)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// objectPartSize is how much of an object is buffered before it is uploaded as one
+// part. S3 parts must be at least 5 MiB and GCS chunks a multiple of 256 KiB; S3 allows
+// 10,000 parts, so objects of up to about 80 GB can be written.
+var objectPartSize = 8 << 20
+
+// gcsUploadURL is the Cloud Storage upload endpoint, a variable so tests can replace it
+var gcsUploadURL = "https://storage.googleapis.com/upload/storage/v1/b"
+
+var objectHTTPClient = &http.Client{Timeout: 5 * time.Minute}
+
+// IsObjectStoreTarget reports whether an export target is an object in S3
+// (s3://bucket/key) or Google Cloud Storage (gs://bucket/key)
+func IsObjectStoreTarget(target string) bool {
+	scheme, _, ok := strings.Cut(target, "://")
+	return ok && (strings.EqualFold(scheme, "s3") || strings.EqualFold(scheme, "gs"))
+}
+
+// ExportToObjectStore uploads a result to s3://bucket/key or gs://bucket/key while
+// rows are fetched, so nothing is written to the local disk. Keys ending in .ndjson or
+// .jsonl get one JSON object per row and others CSV; a further .gz gzips the object.
+// Credentials come from the environment: AWS keys or profile as for IAM database
+// authentication, and for GCS $GOOGLE_OAUTH_ACCESS_TOKEN or gcloud.
+func ExportToObjectStore(result *QueryResult, target string) (int, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" || strings.Trim(u.Path, "/") == "" {
+		result.Close()
+		return 0, fmt.Errorf("object target %q should be s3://bucket/key or gs://bucket/key", target)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	var upload objectUploader
+	if strings.EqualFold(u.Scheme, "s3") {
+		upload, err = newS3Uploader(bucket, key)
+	} else {
+		upload, err = newGCSUploader(bucket, key)
+	}
+	if err != nil {
+		result.Close()
+		return 0, err
+	}
+	object := &objectWriter{upload: upload, buf: make([]byte, 0, objectPartSize)}
+
+	var w io.Writer = object
+	name := strings.ToLower(key)
+	var gz *gzip.Writer
+	if strings.HasSuffix(name, ".gz") {
+		gz = gzip.NewWriter(object)
+		w = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	var count int
+	switch path.Ext(name) {
+	case ".ndjson", ".jsonl":
+		count, err = WriteNDJSON(result, w)
+	default:
+		count, err = WriteCSV(result, w)
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		// Leave no partial object behind
+		upload.abort()
+		return count, err
+	}
+	return count, object.Close()
+}
+
+// WriteCSV writes a result as CSV with a header row
+func WriteCSV(result *QueryResult, w io.Writer) (int, error) {
+	count := 0
+	defer result.Close()
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(result.DisplayColumnNames()); err != nil {
+		return count, fmt.Errorf("failed to write CSV headers: %w", err)
+	}
+	err := result.ForEachRow(func(row []Value) error {
+		record := make([]string, len(row))
+		for i, val := range row {
+			record[i] = val.String()
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// objectUploader sends an object in parts. The last part, which may be empty, is sent
+// with last set and finishes the object.
+type objectUploader interface {
+	uploadPart(part []byte, last bool) error
+	abort()
+}
+
+// objectWriter buffers an object into parts of objectPartSize for an uploader
+type objectWriter struct {
+	upload objectUploader
+	buf    []byte
+}
+
+func (w *objectWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), cap(w.buf)-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.upload.uploadPart(w.buf, false); err != nil {
+				return written, err
+			}
+			w.buf = w.buf[:0]
+		}
+	}
+	return written, nil
+}
+
+func (w *objectWriter) Close() error {
+	if err := w.upload.uploadPart(w.buf, true); err != nil {
+		w.upload.abort()
+		return err
+	}
+	return nil
+}
+
+// s3Uploader writes an object with a single PUT when it fits in one part, and as a
+// multipart upload otherwise
+type s3Uploader struct {
+	objectURL string
+	region    string
+	creds     awsCredentials
+	uploadID  string
+	etags     []string
+}
+
+func newS3Uploader(bucket, key string) (*s3Uploader, error) {
+	creds, err := loadAWSCredentials("")
+	if err != nil {
+		return nil, err
+	}
+	region := firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), "us-east-1")
+	var escaped []string
+	for _, segment := range strings.Split(key, "/") {
+		escaped = append(escaped, awsEscape(segment))
+	}
+	objectURL := "https://" + bucket + ".s3." + region + ".amazonaws.com/" + strings.Join(escaped, "/")
+	// S3 compatible stores, such as MinIO, take path style addresses
+	if endpoint := firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")); endpoint != "" {
+		objectURL = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + strings.Join(escaped, "/")
+	}
+	return &s3Uploader{objectURL: objectURL, region: region, creds: creds}, nil
+}
+
+func (s *s3Uploader) uploadPart(part []byte, last bool) error {
+	if s.uploadID == "" && last {
+		_, _, err := s.request(http.MethodPut, "", part)
+		return err
+	}
+	if s.uploadID == "" {
+		_, body, err := s.request(http.MethodPost, "uploads=", nil)
+		if err != nil {
+			return err
+		}
+		var created struct {
+			UploadID string `xml:"UploadId"`
+		}
+		if err := xml.Unmarshal(body, &created); err != nil || created.UploadID == "" {
+			return fmt.Errorf("s3 did not start a multipart upload: %s", body)
+		}
+		s.uploadID = created.UploadID
+	}
+
+	if len(part) > 0 || len(s.etags) == 0 {
+		query := "partNumber=" + strconv.Itoa(len(s.etags)+1) + "&uploadId=" + awsEscape(s.uploadID)
+		header, _, err := s.request(http.MethodPut, query, part)
+		if err != nil {
+			return err
+		}
+		s.etags = append(s.etags, header.Get("ETag"))
+	}
+	if !last {
+		return nil
+	}
+
+	var complete bytes.Buffer
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, etag := range s.etags {
+		fmt.Fprintf(&complete, "<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, etag)
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+	_, body, err := s.request(http.MethodPost, "uploadId="+awsEscape(s.uploadID), complete.Bytes())
+	if err != nil {
+		return err
+	}
+	// A completion that fails after it has started still answers 200, with an error body
+	if bytes.Contains(body, []byte("<Error>")) {
+		return s3Error(http.StatusOK, body)
+	}
+	return nil
+}
+
+func (s *s3Uploader) abort() {
+	if s.uploadID != "" {
+		s.request(http.MethodDelete, "uploadId="+awsEscape(s.uploadID), nil)
+	}
+}
+
+// request sends a signed request for the object and returns the response headers and body
+func (s *s3Uploader) request(method, query string, payload []byte) (http.Header, []byte, error) {
+	target := s.objectURL
+	if query != "" {
+		target += "?" + query
+	}
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+	signAWSRequest(req, payload, s.region, "s3", s.creds, time.Now())
+
+	resp, err := objectHTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, nil, s3Error(resp.StatusCode, body)
+	}
+	return resp.Header, body, nil
+}
+
+func s3Error(status int, body []byte) error {
+	var failure struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("s3: %s: %s (%d)", failure.Code, failure.Message, status)
+	}
+	return fmt.Errorf("s3: %s", http.StatusText(status))
+}
+
+// signAWSRequest adds a SigV4 Authorization header for the host, date, security token
+// and payload hash headers
+func signAWSRequest(req *http.Request, payload []byte, region, service string, creds awsCredentials, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	amzDate := now.Format("20060102T150405Z")
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	payloadHash := sha256.Sum256(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	var pairs []string
+	for key, values := range req.URL.Query() {
+		for _, value := range values {
+			pairs = append(pairs, awsEscape(key)+"="+awsEscape(value))
+		}
+	}
+	sort.Strings(pairs)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method, req.URL.EscapedPath(), strings.Join(pairs, "&"), canonicalHeaders.String(), signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	hashedRequest := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(hashedRequest[:]),
+	}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, hex.EncodeToString(hmacSHA256(key, stringToSign))))
+}
+
+// gcsUploader writes an object with a resumable upload, one chunk per part
+type gcsUploader struct {
+	bucket, key string
+	token       string
+	session     string
+	offset      int
+}
+
+func newGCSUploader(bucket, key string) (*gcsUploader, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		var err error
+		if token, err = RunSecretCommand(defaultGCPTokenCommand); err != nil {
+			return nil, fmt.Errorf("failed to get a Cloud Storage access token from %q: %w", defaultGCPTokenCommand, err)
+		}
+	}
+	return &gcsUploader{bucket: bucket, key: key, token: token}, nil
+}
+
+func (g *gcsUploader) uploadPart(part []byte, last bool) error {
+	if g.session == "" {
+		target := gcsUploadURL + "/" + url.PathEscape(g.bucket) + "/o?uploadType=resumable&name=" + url.QueryEscape(g.key)
+		resp, err := g.send(http.MethodPost, target, nil, "")
+		if err != nil {
+			return err
+		}
+		if g.session = resp.Header.Get("Location"); g.session == "" {
+			return errors.New("gcs did not start a resumable upload")
+		}
+	}
+
+	// Chunks before the last give the total size as *
+	total := "*"
+	if last {
+		total = strconv.Itoa(g.offset + len(part))
+	}
+	contentRange := "bytes */" + total
+	if len(part) > 0 {
+		contentRange = fmt.Sprintf("bytes %d-%d/%s", g.offset, g.offset+len(part)-1, total)
+	}
+	if _, err := g.send(http.MethodPut, g.session, part, contentRange); err != nil {
+		return err
+	}
+	g.offset += len(part)
+	return nil
+}
+
+func (g *gcsUploader) abort() {
+	if g.session != "" {
+		g.send(http.MethodDelete, g.session, nil, "")
+	}
+}
+
+func (g *gcsUploader) send(method, target string, payload []byte, contentRange string) (*http.Response, error) {
+	req, err := http.NewRequest(method, target, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	if contentRange != "" {
+		req.Header.Set("Content-Range", contentRange)
+	}
+
+	resp, err := objectHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	// 308 asks for the next chunk of a resumable upload
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusPermanentRedirect && method != http.MethodDelete {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if jsonErr := json.Unmarshal(body, &failure); jsonErr == nil && failure.Error.Message != "" {
+			return nil, fmt.Errorf("gcs: %s (%d)", failure.Error.Message, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("gcs: %s", resp.Status)
+	}
+	return resp, nil
+}
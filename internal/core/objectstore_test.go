@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestExportToObjectStoreS3(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn, "CREATE TABLE n (id INTEGER, label TEXT)")
+	for i := 0; i < 20; i++ {
+		mustExec(t, conn, fmt.Sprintf("INSERT INTO n VALUES (%d, 'row number %d')", i, i))
+	}
+
+	var mu sync.Mutex
+	parts := map[string][]byte{}
+	var object []byte
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		query := r.URL.Query()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			w.Write([]byte("<InitiateMultipartUploadResult><UploadId>up-1</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut && query.Get("uploadId") == "up-1":
+			parts[query.Get("partNumber")] = body
+			w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+		case r.Method == http.MethodPost && query.Get("uploadId") == "up-1":
+			for i := 1; i <= len(parts); i++ {
+				object = append(object, parts[fmt.Sprint(i)]...)
+			}
+			w.Write([]byte("<CompleteMultipartUploadResult/>"))
+		case r.Method == http.MethodPut:
+			object = body
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	defer func(size int) { objectPartSize = size }(objectPartSize)
+	objectPartSize = 64
+
+	result, err := conn.Execute("SELECT id, label FROM n ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ExportToObjectStore(result, "s3://reports/daily/report.csv.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 20 {
+		t.Errorf("Expected 20 rows, got %d", rows)
+	}
+	if len(parts) < 2 {
+		t.Errorf("Expected a multipart upload, got requests %v", requests)
+	}
+	if requests[0] != "POST /reports/daily/report.csv.gz" {
+		t.Errorf("Unexpected object path %q", requests[0])
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(object))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(gz)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 21 || lines[0] != "id,label" || lines[20] != "19,row number 19" {
+		t.Errorf("Unexpected object content\n%s", content)
+	}
+
+	// A small result is sent with one PUT
+	objectPartSize = 8 << 20
+	requests = nil
+	result, err = conn.Execute("SELECT id, label FROM n WHERE id = 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportToObjectStore(result, "s3://reports/one.ndjson"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || string(object) != `{"id":3,"label":"row number 3"}`+"\n" {
+		t.Errorf("Unexpected single upload %v: %q", requests, object)
+	}
+}
+
+func TestExportToObjectStoreGCS(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn, "CREATE TABLE n (id INTEGER)", "INSERT INTO n VALUES (1), (2), (3)")
+
+	var object []byte
+	var ranges []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gcs-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodPost:
+			if r.URL.Path != "/b/exports/o" || r.URL.Query().Get("name") != "q1/ids.csv" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Location", server.URL+"/session/1")
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			object = append(object, body...)
+			ranges = append(ranges, r.Header.Get("Content-Range"))
+			if strings.HasSuffix(r.Header.Get("Content-Range"), "/*") {
+				w.WriteHeader(http.StatusPermanentRedirect)
+			}
+		}
+	}))
+	defer server.Close()
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "gcs-token")
+	defer func(url string, size int) { gcsUploadURL, objectPartSize = url, size }(gcsUploadURL, objectPartSize)
+	gcsUploadURL, objectPartSize = server.URL+"/b", 4
+
+	result, err := conn.Execute("SELECT id FROM n ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportToObjectStore(result, "gs://exports/q1/ids.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if string(object) != "id\n1\n2\n3\n" {
+		t.Errorf("Unexpected object %q", object)
+	}
+	want := []string{"bytes 0-3/*", "bytes 4-7/*", "bytes 8-8/9"}
+	if strings.Join(ranges, ",") != strings.Join(want, ",") {
+		t.Errorf("Unexpected chunks %v, want %v", ranges, want)
+	}
+}
@@ -0,0 +1,337 @@
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/i18n"
+)
+
+// DefaultPingSamples is how many times /ping measures each phase
+const DefaultPingSamples = 5
+
+// Phases of a connection that PingConnection times separately
+const (
+	PhaseTCP   = "tcp"   // Opening the TCP connection
+	PhaseTLS   = "tls"   // Negotiating TLS on it
+	PhaseAuth  = "auth"  // The rest of logging in: the whole login less TCP and TLS
+	PhaseQuery = "query" // A SELECT 1 on a logged-in connection
+)
+
+// PingPhase holds the durations measured for one phase, one per sample
+type PingPhase struct {
+	Name    string
+	Samples []time.Duration
+}
+
+// Min returns the shortest sample
+func (p PingPhase) Min() time.Duration {
+	if len(p.Samples) == 0 {
+		return 0
+	}
+	return slices.Min(p.Samples)
+}
+
+// Max returns the longest sample
+func (p PingPhase) Max() time.Duration {
+	if len(p.Samples) == 0 {
+		return 0
+	}
+	return slices.Max(p.Samples)
+}
+
+// Avg returns the mean of the samples
+func (p PingPhase) Avg() time.Duration {
+	if len(p.Samples) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, sample := range p.Samples {
+		sum += sample
+	}
+	return sum / time.Duration(len(p.Samples))
+}
+
+// PingReport is the result of PingConnection
+type PingReport struct {
+	Samples  int           // Samples asked for
+	Address  string        // host:port, or the database file
+	Resolved string        // The address the host name resolved to and was dialled
+	Resolve  time.Duration // DNS lookup of the host; 0 when none was needed
+	Phases   []PingPhase   // In the order they happen; TCP and TLS only for network databases
+	Err      error         // Of the first sample that failed
+	Failure  FailureKind
+}
+
+// Phase returns the samples of a phase, and whether it was measured
+func (r *PingReport) Phase(name string) (PingPhase, bool) {
+	for _, phase := range r.Phases {
+		if phase.Name == name {
+			return phase, true
+		}
+	}
+	return PingPhase{}, false
+}
+
+// UsesTLS reports whether connections made with the config negotiate TLS
+func UsesTLS(config *ConnectionConfig) bool {
+	switch config.DatabaseType {
+	case PostgreSQL:
+		sslMode, _ := postgresAuthParams(config.Auth)
+		return sslMode != "disable"
+	case MySQL:
+		return config.Auth != nil && config.Auth.Method == AuthIAM
+	case ClickHouse:
+		return config.SSL
+	}
+	return false
+}
+
+// PingConnection times the phases of connecting to a database over several samples: the
+// TCP connect, the TLS handshake, logging in and a SELECT 1 round trip, so a slow network
+// can be told apart from a slow server. Each sample opens fresh connections; the first
+// failure stops the measurement. Each step is bounded by timeout.
+func PingConnection(config *ConnectionConfig, samples int, timeout time.Duration) *PingReport {
+	if samples <= 0 {
+		samples = DefaultPingSamples
+	}
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+	report := &PingReport{Samples: samples, Address: config.Database}
+	network := !config.DatabaseType.IsFileBased()
+	secure := network && UsesTLS(config)
+
+	tcp, tlsPhase := PingPhase{Name: PhaseTCP}, PingPhase{Name: PhaseTLS}
+	auth, query := PingPhase{Name: PhaseAuth}, PingPhase{Name: PhaseQuery}
+	// finish keeps the samples taken so far, also when one failed
+	finish := func(err error) *PingReport {
+		if network {
+			report.Phases = append(report.Phases, tcp)
+			if secure {
+				report.Phases = append(report.Phases, tlsPhase)
+			}
+		}
+		report.Phases = append(report.Phases, auth, query)
+		report.Err = err
+		report.Failure = ClassifyConnectionError(err)
+		return report
+	}
+
+	dial := ""
+	if !network {
+		// Opening a missing database file silently creates it
+		if _, err := os.Stat(config.Database); err != nil {
+			return finish(err)
+		}
+	} else {
+		report.Address = net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+		dial = report.Address
+		if net.ParseIP(config.Host) == nil {
+			start := time.Now()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			addrs, err := net.DefaultResolver.LookupHost(ctx, config.Host)
+			cancel()
+			if err != nil {
+				return finish(err)
+			}
+			report.Resolve, report.Resolved = time.Since(start), addrs[0]
+			// Later dials use the address, so TCP times leave out DNS
+			dial = net.JoinHostPort(addrs[0], strconv.Itoa(config.Port))
+		}
+	}
+
+	for range samples {
+		var transport time.Duration
+		if network {
+			start := time.Now()
+			conn, err := net.DialTimeout("tcp", dial, timeout)
+			if err != nil {
+				return finish(err)
+			}
+			transport = time.Since(start)
+			tcp.Samples = append(tcp.Samples, transport)
+			if secure {
+				handshake, err := timeTLSHandshake(conn, config, timeout)
+				if err != nil {
+					conn.Close()
+					return finish(err)
+				}
+				tlsPhase.Samples = append(tlsPhase.Samples, handshake)
+				transport += handshake
+			}
+			conn.Close()
+		}
+
+		login, roundTrip, err := timeLogin(config, timeout)
+		if err != nil {
+			return finish(err)
+		}
+		auth.Samples = append(auth.Samples, max(login-transport, 0))
+		query.Samples = append(query.Samples, roundTrip)
+	}
+	return finish(nil)
+}
+
+// timeLogin opens a connection and returns how long logging in took, then how long a
+// SELECT 1 on it took
+func timeLogin(config *ConnectionConfig, timeout time.Duration) (login, query time.Duration, err error) {
+	conn, err := NewConnection(config)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer conn.Close()
+	db := conn.(*connection).db
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	err = db.PingContext(ctx)
+	cancel()
+	if err != nil {
+		return 0, 0, err
+	}
+	login = time.Since(start)
+
+	start = time.Now()
+	ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	var one int
+	err = db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+	cancel()
+	return login, time.Since(start), err
+}
+
+// timeTLSHandshake upgrades a fresh connection to TLS the way the database's protocol
+// does, returning how long that took. The certificate is not checked: logging in does
+// that, and here only the time matters.
+func timeTLSHandshake(conn net.Conn, config *ConnectionConfig, timeout time.Duration) (time.Duration, error) {
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(timeout)); err != nil {
+		return 0, err
+	}
+	switch config.DatabaseType {
+	case PostgreSQL:
+		if err := requestPostgresTLS(conn); err != nil {
+			return 0, err
+		}
+	case MySQL:
+		if err := requestMySQLTLS(conn); err != nil {
+			return 0, err
+		}
+	}
+	client := tls.Client(conn, &tls.Config{ServerName: config.Host, InsecureSkipVerify: true})
+	if err := client.Handshake(); err != nil {
+		return 0, fmt.Errorf("tls handshake: %w", err)
+	}
+	return time.Since(start), nil
+}
+
+// requestPostgresTLS sends PostgreSQL's SSLRequest, which the server answers with S when
+// it will go on with a TLS handshake
+func requestPostgresTLS(conn net.Conn) error {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], 80877103)
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != 'S' {
+		return errors.New("the server does not accept SSL connections")
+	}
+	return nil
+}
+
+// MySQL capability flags used in the SSL request
+const (
+	mysqlClientLongPassword       = 0x00000001
+	mysqlClientProtocol41         = 0x00000200
+	mysqlClientSSL                = 0x00000800
+	mysqlClientSecureConnection   = 0x00008000
+	mysqlMaxPacketSize            = 1<<24 - 1
+	mysqlCharsetUTF8MB4GeneralCI  = 45
+	mysqlSSLRequestPayloadLength  = 32
+	mysqlGreetingCapabilityOffset = 1 + 4 + 8 + 1 // After the version: thread id, salt and filler
+)
+
+// requestMySQLTLS reads MySQL's greeting and answers it with an SSL request, after which
+// the server expects a TLS handshake
+func requestMySQLTLS(conn net.Conn) error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	greeting := make([]byte, int(header[0])|int(header[1])<<8|int(header[2])<<16)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return err
+	}
+	if len(greeting) > 0 && greeting[0] == 0xff {
+		return errors.New("the server refused the connection")
+	}
+	// The server version is NUL-terminated after the protocol version
+	versionEnd := 1
+	for versionEnd < len(greeting) && greeting[versionEnd] != 0 {
+		versionEnd++
+	}
+	offset := versionEnd + mysqlGreetingCapabilityOffset
+	if offset+2 > len(greeting) {
+		return errors.New("unexpected MySQL greeting")
+	}
+	if binary.LittleEndian.Uint16(greeting[offset:])&mysqlClientSSL == 0 {
+		return errors.New("the server does not accept SSL connections")
+	}
+
+	packet := make([]byte, 4+mysqlSSLRequestPayloadLength)
+	packet[0] = mysqlSSLRequestPayloadLength
+	packet[3] = header[3] + 1
+	binary.LittleEndian.PutUint32(packet[4:], mysqlClientLongPassword|mysqlClientProtocol41|mysqlClientSSL|mysqlClientSecureConnection)
+	binary.LittleEndian.PutUint32(packet[8:], mysqlMaxPacketSize)
+	packet[12] = mysqlCharsetUTF8MB4GeneralCI
+	_, err := conn.Write(packet)
+	return err
+}
+
+// FormatPingReport renders a report as one line per phase with its shortest, mean and
+// longest sample, followed by what the times suggest or a hint for the failure
+func FormatPingReport(report *PingReport, i18nMgr *i18n.Manager) string {
+	var sb strings.Builder
+	if report.Resolved != "" {
+		sb.WriteString(i18nMgr.GetWithArgs("ping_resolve_line", report.Resolved, report.Resolve.Round(time.Microsecond)))
+	}
+	for _, phase := range report.Phases {
+		if len(phase.Samples) == 0 {
+			continue
+		}
+		sb.WriteString(i18nMgr.GetWithArgs("ping_phase_line", PadRight(i18nMgr.Get("ping_phase_"+phase.Name), 16),
+			phase.Min().Round(time.Microsecond), phase.Avg().Round(time.Microsecond), phase.Max().Round(time.Microsecond), len(phase.Samples)))
+	}
+
+	if report.Err != nil {
+		done := 0
+		if query, ok := report.Phase(PhaseQuery); ok {
+			done = len(query.Samples)
+		}
+		sb.WriteString(i18nMgr.GetWithArgs("ping_failed", done, report.Samples, report.Err))
+		sb.WriteString(i18nMgr.Get("test_hint_" + string(report.Failure)))
+		return sb.String()
+	}
+	query, _ := report.Phase(PhaseQuery)
+	if tcp, ok := report.Phase(PhaseTCP); ok {
+		sb.WriteString(i18nMgr.GetWithArgs("ping_summary_network", tcp.Avg().Round(time.Microsecond), query.Avg().Round(time.Microsecond)))
+	} else {
+		sb.WriteString(i18nMgr.GetWithArgs("ping_summary_local", query.Avg().Round(time.Microsecond)))
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPingConnection(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "ping.db")
+	conn, err := NewConnection(&ConnectionConfig{Name: "setup", DatabaseType: SQLite, Database: dbPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Ping(); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	report := PingConnection(&ConnectionConfig{DatabaseType: SQLite, Database: dbPath}, 3, time.Second)
+	if report.Err != nil {
+		t.Fatalf("Ping failed: %v", report.Err)
+	}
+	if len(report.Phases) != 2 || report.Phases[0].Name != PhaseAuth || len(report.Phases[1].Samples) != 3 {
+		t.Errorf("Expected 3 samples of auth and query, got %+v", report.Phases)
+	}
+	query, _ := report.Phase(PhaseQuery)
+	if query.Min() > query.Avg() || query.Avg() > query.Max() {
+		t.Errorf("Samples out of order: %v", query.Samples)
+	}
+
+	missing := PingConnection(&ConnectionConfig{DatabaseType: SQLite, Database: filepath.Join(t.TempDir(), "missing.db")}, 1, time.Second)
+	if missing.Failure != FailureDatabase {
+		t.Errorf("Expected a missing file to fail as database, got %q", missing.Failure)
+	}
+}
+
+func TestPingConnectionNetwork(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	report := PingConnection(&ConnectionConfig{DatabaseType: PostgreSQL, Host: "127.0.0.1", Port: addr.Port, Database: "db", Username: "u"}, 2, time.Second)
+	if report.Err == nil {
+		t.Fatal("Expected logging in to a server that hangs up to fail")
+	}
+	if tcp, ok := report.Phase(PhaseTCP); !ok || len(tcp.Samples) != 1 {
+		t.Errorf("Expected the TCP connect to be timed before the login failed, got %+v", report.Phases)
+	}
+	if _, ok := report.Phase(PhaseTLS); ok {
+		t.Error("Expected no TLS phase without TLS")
+	}
+}
+
+func TestRequestTLS(t *testing.T) {
+	client, server := net.Pipe()
+	go func() {
+		request := make([]byte, 8)
+		io.ReadFull(server, request)
+		if binary.BigEndian.Uint32(request[4:]) == 80877103 {
+			server.Write([]byte("N"))
+		}
+	}()
+	if err := requestPostgresTLS(client); err == nil {
+		t.Error("Expected a server answering N to refuse TLS")
+	}
+	client.Close()
+
+	client, server = net.Pipe()
+	defer client.Close()
+	received := make(chan []byte, 1)
+	go func() {
+		greeting := []byte{10}
+		greeting = append(greeting, "8.0.36\x00"...)
+		greeting = append(greeting, make([]byte, 4+8+1)...)
+		greeting = binary.LittleEndian.AppendUint16(greeting, mysqlClientSSL|mysqlClientProtocol41)
+		server.Write(append([]byte{byte(len(greeting)), 0, 0, 0}, greeting...))
+		packet := make([]byte, 4+mysqlSSLRequestPayloadLength)
+		io.ReadFull(server, packet)
+		received <- packet
+	}()
+	if err := requestMySQLTLS(client); err != nil {
+		t.Fatalf("SSL request failed: %v", err)
+	}
+	packet := <-received
+	if packet[0] != mysqlSSLRequestPayloadLength || packet[3] != 1 || binary.LittleEndian.Uint32(packet[4:])&mysqlClientSSL == 0 {
+		t.Errorf("Unexpected SSL request packet %v", packet)
+	}
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PipeStep is a client-side step applied to result rows, written after the query as
+// | distinct, | head 100, | columns a,b,c or | sort -total,name
+type PipeStep struct {
+	Name string
+	Args []string
+}
+
+// SplitPipeline separates trailing | steps from a query. Only a whole, valid step
+// after " | " is taken, so SQL using | or || as an operator is left alone.
+func SplitPipeline(query string) (string, []PipeStep) {
+	var steps []PipeStep
+	for {
+		i := strings.LastIndex(query, " | ")
+		if i < 0 {
+			break
+		}
+		step, ok := parsePipeStep(query[i+3:])
+		if !ok {
+			break
+		}
+		steps = append(steps, step)
+		query = strings.TrimSpace(query[:i])
+	}
+	slices.Reverse(steps)
+	return query, steps
+}
+
+func parsePipeStep(text string) (PipeStep, bool) {
+	fields := strings.Fields(strings.TrimSuffix(strings.TrimSpace(text), ";"))
+	if len(fields) == 0 {
+		return PipeStep{}, false
+	}
+	name := strings.ToLower(fields[0])
+	args := strings.FieldsFunc(strings.Join(fields[1:], " "), func(r rune) bool { return r == ',' || r == ' ' })
+	switch name {
+	case "distinct":
+		return PipeStep{Name: name}, len(args) == 0
+	case "head":
+		if len(args) != 1 {
+			return PipeStep{}, false
+		}
+		n, err := strconv.Atoi(args[0])
+		return PipeStep{Name: name, Args: args}, err == nil && n >= 0
+	case "columns", "sort":
+		for _, arg := range args {
+			if strings.TrimPrefix(arg, "-") == "" || strings.ContainsAny(arg, "'\"()") {
+				return PipeStep{}, false
+			}
+		}
+		return PipeStep{Name: name, Args: args}, len(args) > 0
+	}
+	return PipeStep{}, false
+}
+
+// Pipe adds steps applied to the rows as they are read. distinct, head and columns
+// stream; sort holds every row until the last is fetched. Column names are matched
+// without regard to case, and an unknown one is an error.
+func (r *QueryResult) Pipe(steps ...PipeStep) error {
+	for _, step := range steps {
+		switch step.Name {
+		case "distinct":
+			r.steps = append(r.steps, distinctRows)
+		case "head":
+			n, _ := strconv.Atoi(step.Args[0])
+			r.steps = append(r.steps, func(rows iter.Seq[[]Value]) iter.Seq[[]Value] {
+				return headRows(rows, n)
+			})
+		case "columns":
+			indexes := make([]int, len(step.Args))
+			columns := make([]Column, len(step.Args))
+			for i, name := range step.Args {
+				index, err := r.columnIndex(name)
+				if err != nil {
+					return err
+				}
+				indexes[i], columns[i] = index, r.Columns[index]
+			}
+			if r.scanned == nil {
+				r.scanned = r.Columns
+			}
+			r.Columns = columns
+			r.steps = append(r.steps, func(rows iter.Seq[[]Value]) iter.Seq[[]Value] {
+				return projectRows(rows, indexes)
+			})
+		case "sort":
+			var keys []sortKey
+			for _, arg := range step.Args {
+				name, descending := strings.CutPrefix(arg, "-")
+				index, err := r.columnIndex(name)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, sortKey{index: index, descending: descending})
+			}
+			r.steps = append(r.steps, func(rows iter.Seq[[]Value]) iter.Seq[[]Value] {
+				return sortRows(rows, keys)
+			})
+		default:
+			return fmt.Errorf("unknown pipe step %q", step.Name)
+		}
+	}
+	return nil
+}
+
+func (r *QueryResult) columnIndex(name string) (int, error) {
+	for i, col := range r.Columns {
+		if strings.EqualFold(col.Name, name) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("no column %q in the result", name)
+}
+
+func distinctRows(rows iter.Seq[[]Value]) iter.Seq[[]Value] {
+	return func(yield func([]Value) bool) {
+		seen := make(map[string]bool)
+		for row := range rows {
+			var key strings.Builder
+			for _, val := range row {
+				// NULL and the empty string are different values
+				if val.IsNull() {
+					key.WriteString("\x00N")
+				} else {
+					key.WriteString("\x00V" + val.String())
+				}
+			}
+			if seen[key.String()] {
+				continue
+			}
+			seen[key.String()] = true
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// headRows stops reading after n rows, so the rest are never fetched
+func headRows(rows iter.Seq[[]Value], n int) iter.Seq[[]Value] {
+	return func(yield func([]Value) bool) {
+		if n == 0 {
+			return
+		}
+		count := 0
+		for row := range rows {
+			count++
+			if !yield(row) || count >= n {
+				return
+			}
+		}
+	}
+}
+
+func projectRows(rows iter.Seq[[]Value], indexes []int) iter.Seq[[]Value] {
+	return func(yield func([]Value) bool) {
+		for row := range rows {
+			projected := make([]Value, len(indexes))
+			for i, index := range indexes {
+				if index < len(row) {
+					projected[i] = row[index]
+				} else {
+					projected[i] = NullValue{}
+				}
+			}
+			if !yield(projected) {
+				return
+			}
+		}
+	}
+}
+
+type sortKey struct {
+	index      int
+	descending bool
+}
+
+func sortRows(rows iter.Seq[[]Value], keys []sortKey) iter.Seq[[]Value] {
+	return func(yield func([]Value) bool) {
+		all := slices.Collect(rows)
+		slices.SortStableFunc(all, func(a, b []Value) int {
+			for _, key := range keys {
+				c := compareValues(a[key.index], b[key.index])
+				if key.descending {
+					c = -c
+				}
+				if c != 0 {
+					return c
+				}
+			}
+			return 0
+		})
+		for _, row := range all {
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// compareValues orders numbers by value, times by instant and other values as text.
+// NULLs sort first, so they come last in descending order.
+func compareValues(a, b Value) int {
+	switch {
+	case a.IsNull() && b.IsNull():
+		return 0
+	case a.IsNull():
+		return -1
+	case b.IsNull():
+		return 1
+	}
+	if x, ok := numericValue(a); ok {
+		if y, ok := numericValue(b); ok {
+			return cmp.Compare(x, y)
+		}
+	}
+	if x, ok := a.(TimeValue); ok {
+		if y, ok := b.(TimeValue); ok {
+			return x.Value.Compare(y.Value)
+		}
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+// numericValue reads a number, including decimals some drivers return as text
+func numericValue(v Value) (float64, bool) {
+	switch n := v.(type) {
+	case IntValue:
+		return float64(n.Value), true
+	case FloatValue:
+		return n.Value, true
+	case StringValue:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n.Value), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
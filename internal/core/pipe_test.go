@@ -0,0 +1,92 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitPipeline(t *testing.T) {
+	testCases := []struct {
+		line  string
+		query string
+		steps []PipeStep
+	}{
+		{
+			line:  "SELECT * FROM orders | distinct | sort -total, name | head 100",
+			query: "SELECT * FROM orders",
+			steps: []PipeStep{{Name: "distinct"}, {Name: "sort", Args: []string{"-total", "name"}}, {Name: "head", Args: []string{"100"}}},
+		},
+		{
+			line:  "SELECT id, total FROM orders | columns id;",
+			query: "SELECT id, total FROM orders",
+			steps: []PipeStep{{Name: "columns", Args: []string{"id"}}},
+		},
+		{line: "SELECT first || ' | ' || last FROM users", query: "SELECT first || ' | ' || last FROM users"},
+		{line: "SELECT flags | 4 FROM t", query: "SELECT flags | 4 FROM t"},
+		{line: "SELECT * FROM t WHERE note = 'x | head 5'", query: "SELECT * FROM t WHERE note = 'x | head 5'"},
+		{line: "SELECT * FROM t | head lots", query: "SELECT * FROM t | head lots"},
+	}
+	for _, tc := range testCases {
+		query, steps := SplitPipeline(tc.line)
+		if query != tc.query || !reflect.DeepEqual(steps, tc.steps) {
+			t.Errorf("SplitPipeline(%q) = %q, %v, want %q, %v", tc.line, query, steps, tc.query, tc.steps)
+		}
+	}
+}
+
+func TestQueryResultPipe(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER, region TEXT, total REAL)",
+		`INSERT INTO orders VALUES (1, 'APAC', 10), (2, 'EMEA', 250), (3, 'APAC', 10), (4, NULL, 99.5), (5, 'AMER', 250)`,
+	)
+
+	run := func(line string) ([]string, [][]string) {
+		t.Helper()
+		query, steps := SplitPipeline(line)
+		result, err := conn.Execute(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer result.Close()
+		if err := result.Pipe(steps...); err != nil {
+			t.Fatal(err)
+		}
+		var rows [][]string
+		for row := range result.Itor() {
+			cells := make([]string, len(row))
+			for i, val := range row {
+				cells[i] = val.String()
+				if val.IsNull() {
+					cells[i] = "NULL"
+				}
+			}
+			rows = append(rows, cells)
+		}
+		return result.ColumnNames(), rows
+	}
+
+	columns, rows := run("SELECT * FROM orders ORDER BY id | columns region, total | distinct | sort -total,region")
+	if !reflect.DeepEqual(columns, []string{"region", "total"}) {
+		t.Errorf("Unexpected columns %v", columns)
+	}
+	want := [][]string{{"AMER", "250"}, {"EMEA", "250"}, {"NULL", "99.5"}, {"APAC", "10"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Unexpected rows %v, want %v", rows, want)
+	}
+
+	_, rows = run("SELECT id FROM orders ORDER BY id | head 2")
+	if !reflect.DeepEqual(rows, [][]string{{"1"}, {"2"}}) {
+		t.Errorf("Unexpected head rows %v", rows)
+	}
+
+	result, err := conn.Execute("SELECT id FROM orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	if err := result.Pipe(PipeStep{Name: "sort", Args: []string{"missing"}}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("Expected an unknown column error, got %v", err)
+	}
+}
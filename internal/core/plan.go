@@ -0,0 +1,404 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MisestimateFactor is how far actual rows may drift from the planner's estimate
+// before a node is flagged
+const MisestimateFactor = 10.0
+
+// PlanNode is one operation of a query plan, normalised across databases
+type PlanNode struct {
+	Operation  string
+	Relation   string
+	Detail     string
+	Cost       float64 // Total cost in planner units, 0 when unknown
+	PlanRows   float64
+	ActualRows float64
+	Loops      float64
+	HasCost    bool
+	HasActual  bool
+	SeqScan    bool
+	Children   []*PlanNode
+}
+
+// Misestimated reports whether actual rows differ from the estimate by MisestimateFactor or more
+func (n *PlanNode) Misestimated() bool {
+	if !n.HasActual {
+		return false
+	}
+	// Postgres reports both values per loop, so they compare directly
+	estimate, got := max(n.PlanRows, 1), max(n.ActualRows, 1)
+	return got/estimate >= MisestimateFactor || estimate/got >= MisestimateFactor
+}
+
+// Walk visits the node and its descendants depth first
+func (n *PlanNode) Walk(fn func(*PlanNode)) {
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// EstimatedRows returns the planner's estimate of the rows a statement touches, taken
+// from the first node with one since the node modifying rows often has none
+func (n *PlanNode) EstimatedRows() float64 {
+	var rows float64
+	n.Walk(func(node *PlanNode) {
+		if rows == 0 && node.PlanRows > 0 {
+			rows = node.PlanRows
+		}
+	})
+	return rows
+}
+
+// ExplainStatement returns the EXPLAIN statement producing a machine-readable plan
+func ExplainStatement(dbType DatabaseType, query string, analyze bool) (string, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	switch dbType {
+	case PostgreSQL:
+		if analyze {
+			return "EXPLAIN (ANALYZE, FORMAT JSON) " + query, nil
+		}
+		return "EXPLAIN (FORMAT JSON) " + query, nil
+	case MySQL:
+		if analyze {
+			return "", fmt.Errorf("EXPLAIN ANALYZE has no JSON output on MySQL")
+		}
+		return "EXPLAIN FORMAT=JSON " + query, nil
+	case SQLite:
+		return "EXPLAIN QUERY PLAN " + query, nil
+	default:
+		return "", fmt.Errorf("unsupported database type: %v", dbType)
+	}
+}
+
+// ExplainQuery runs EXPLAIN for query and parses the plan into a tree.
+// analyze executes the query (Postgres only), so callers must only pass statements that read data.
+func ExplainQuery(conn Connection, dbType DatabaseType, query string, analyze bool) (*PlanNode, error) {
+	statement, err := ExplainStatement(dbType, query, analyze)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := conn.Execute(statement)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var rows [][]Value
+	for row := range result.Itor() {
+		rows = append(rows, row)
+	}
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+
+	switch dbType {
+	case PostgreSQL:
+		return ParsePostgresPlan(joinFirstColumn(rows))
+	case MySQL:
+		return ParseMySQLPlan(joinFirstColumn(rows))
+	default:
+		return buildSQLitePlan(rows)
+	}
+}
+
+func joinFirstColumn(rows [][]Value) string {
+	var sb strings.Builder
+	for _, row := range rows {
+		if len(row) > 0 {
+			sb.WriteString(row[0].String())
+		}
+	}
+	return sb.String()
+}
+
+// ParsePostgresPlan parses the output of EXPLAIN (FORMAT JSON)
+func ParsePostgresPlan(data string) (*PlanNode, error) {
+	var doc []struct {
+		Plan map[string]any `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	if len(doc) == 0 || doc[0].Plan == nil {
+		return nil, fmt.Errorf("plan output is empty")
+	}
+	return postgresNode(doc[0].Plan), nil
+}
+
+func postgresNode(plan map[string]any) *PlanNode {
+	node := &PlanNode{
+		Operation: jsonString(plan["Node Type"]),
+		Relation:  jsonString(plan["Relation Name"]),
+	}
+	if alias := jsonString(plan["Alias"]); alias != "" && alias != node.Relation {
+		node.Relation = strings.TrimSpace(node.Relation + " " + alias)
+	}
+	if join := jsonString(plan["Join Type"]); join != "" {
+		node.Operation = join + " " + node.Operation
+	}
+	node.SeqScan = node.Operation == "Seq Scan"
+
+	var details []string
+	if index := jsonString(plan["Index Name"]); index != "" {
+		details = append(details, "using "+index)
+	}
+	for _, key := range []string{"Index Cond", "Hash Cond", "Merge Cond", "Join Filter", "Filter", "Sort Key", "Group Key"} {
+		if value := jsonString(plan[key]); value != "" {
+			details = append(details, strings.ToLower(key)+": "+value)
+		}
+	}
+	node.Detail = strings.Join(details, "; ")
+
+	node.Cost, node.HasCost = jsonNumber(plan["Total Cost"])
+	node.PlanRows, _ = jsonNumber(plan["Plan Rows"])
+	node.ActualRows, node.HasActual = jsonNumber(plan["Actual Rows"])
+	node.Loops, _ = jsonNumber(plan["Actual Loops"])
+
+	if children, ok := plan["Plans"].([]any); ok {
+		for _, child := range children {
+			if childPlan, ok := child.(map[string]any); ok {
+				node.Children = append(node.Children, postgresNode(childPlan))
+			}
+		}
+	}
+	return node
+}
+
+// mysqlOperations names the MySQL plan wrappers that become tree nodes
+var mysqlOperations = map[string]string{
+	"ordering_operation":         "Sort",
+	"grouping_operation":         "Group",
+	"duplicates_removal":         "Distinct",
+	"windowing":                  "Window",
+	"materialized_from_subquery": "Materialize",
+	"union_result":               "Union",
+}
+
+// ParseMySQLPlan parses the output of EXPLAIN FORMAT=JSON
+func ParseMySQLPlan(data string) (*PlanNode, error) {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse plan: %w", err)
+	}
+	block, ok := doc["query_block"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("plan output has no query_block")
+	}
+	return mysqlBlock("Query Block", block), nil
+}
+
+func mysqlBlock(operation string, block map[string]any) *PlanNode {
+	node := &PlanNode{Operation: operation}
+	if cost, ok := block["cost_info"].(map[string]any); ok {
+		node.Cost, node.HasCost = jsonNumber(cost["query_cost"])
+	}
+	if flag, ok := block["using_filesort"].(bool); ok && flag {
+		node.Detail = "using filesort"
+	}
+	if flag, ok := block["using_temporary_table"].(bool); ok && flag {
+		node.Detail = strings.TrimPrefix(node.Detail+"; using temporary table", "; ")
+	}
+
+	// Visit keys in a stable order so rendering is deterministic
+	keys := make([]string, 0, len(block))
+	for key := range block {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := block[key].(type) {
+		case map[string]any:
+			switch {
+			case key == "table":
+				node.Children = append(node.Children, mysqlTable(value))
+			case key == "query_block":
+				node.Children = append(node.Children, mysqlBlock("Subquery", value))
+			case mysqlOperations[key] != "":
+				node.Children = append(node.Children, mysqlBlock(mysqlOperations[key], value))
+			}
+		case []any:
+			for _, item := range value {
+				child, ok := item.(map[string]any)
+				if !ok {
+					continue
+				}
+				if table, ok := child["table"].(map[string]any); ok {
+					node.Children = append(node.Children, mysqlTable(table))
+				} else if inner, ok := child["query_block"].(map[string]any); ok {
+					node.Children = append(node.Children, mysqlBlock("Subquery", inner))
+				}
+			}
+		}
+	}
+	return node
+}
+
+func mysqlTable(table map[string]any) *PlanNode {
+	access := jsonString(table["access_type"])
+	node := &PlanNode{
+		Operation: mysqlAccessName(access),
+		Relation:  jsonString(table["table_name"]),
+		SeqScan:   access == "ALL",
+	}
+
+	var details []string
+	if key := jsonString(table["key"]); key != "" {
+		details = append(details, "using "+key)
+	}
+	if condition := jsonString(table["attached_condition"]); condition != "" {
+		details = append(details, "filter: "+condition)
+	}
+	node.Detail = strings.Join(details, "; ")
+
+	node.PlanRows, _ = jsonNumber(table["rows_examined_per_scan"])
+	if cost, ok := table["cost_info"].(map[string]any); ok {
+		node.Cost, node.HasCost = jsonNumber(cost["prefix_cost"])
+	}
+	if inner, ok := table["materialized_from_subquery"].(map[string]any); ok {
+		if block, ok := inner["query_block"].(map[string]any); ok {
+			node.Children = append(node.Children, mysqlBlock("Materialize", block))
+		}
+	}
+	return node
+}
+
+func mysqlAccessName(access string) string {
+	switch access {
+	case "ALL":
+		return "Full Table Scan"
+	case "index":
+		return "Full Index Scan"
+	case "range":
+		return "Index Range Scan"
+	case "ref", "eq_ref", "ref_or_null":
+		return "Index Lookup"
+	case "const", "system":
+		return "Constant Lookup"
+	case "":
+		return "Table"
+	default:
+		return access
+	}
+}
+
+// buildSQLitePlan assembles EXPLAIN QUERY PLAN rows (id, parent, notused, detail) into a tree
+func buildSQLitePlan(rows [][]Value) (*PlanNode, error) {
+	root := &PlanNode{Operation: "Query Plan"}
+	nodes := map[string]*PlanNode{"0": root}
+
+	for _, row := range rows {
+		if len(row) < 4 {
+			return nil, fmt.Errorf("unexpected EXPLAIN QUERY PLAN output")
+		}
+		detail := row[3].String()
+		node := &PlanNode{Operation: detail}
+		if rest, ok := strings.CutPrefix(detail, "SCAN "); ok && !strings.Contains(detail, " INDEX ") {
+			node.Operation = "Full Table Scan"
+			node.Relation = rest
+			node.SeqScan = true
+		}
+
+		parent, ok := nodes[row[1].String()]
+		if !ok {
+			parent = root
+		}
+		parent.Children = append(parent.Children, node)
+		nodes[row[0].String()] = node
+	}
+	return root, nil
+}
+
+// RenderPlan draws the plan as an indented tree, marking full scans and misestimates
+func RenderPlan(root *PlanNode) string {
+	var sb strings.Builder
+	renderPlanNode(&sb, root, "", "")
+	return sb.String()
+}
+
+func renderPlanNode(sb *strings.Builder, node *PlanNode, prefix, childPrefix string) {
+	sb.WriteString(prefix)
+	sb.WriteString(node.Operation)
+	if node.Relation != "" && !strings.Contains(node.Operation, node.Relation) {
+		sb.WriteString(" on " + node.Relation)
+	}
+
+	var stats []string
+	if node.HasCost {
+		stats = append(stats, "cost="+formatPlanNumber(node.Cost))
+	}
+	if node.PlanRows > 0 || node.HasActual {
+		stats = append(stats, "rows="+formatPlanNumber(node.PlanRows))
+	}
+	if node.HasActual {
+		actual := "actual=" + formatPlanNumber(node.ActualRows)
+		if node.Loops > 1 {
+			actual += " loops=" + formatPlanNumber(node.Loops)
+		}
+		stats = append(stats, actual)
+	}
+	if len(stats) > 0 {
+		sb.WriteString(" (" + strings.Join(stats, " ") + ")")
+	}
+	if node.SeqScan {
+		sb.WriteString(" 🐢")
+	}
+	if node.Misestimated() {
+		sb.WriteString(" ⚠️")
+	}
+	sb.WriteString("\n")
+
+	if node.Detail != "" && node.Detail != node.Operation {
+		sb.WriteString(childPrefix + "   " + node.Detail + "\n")
+	}
+
+	for i, child := range node.Children {
+		last := i == len(node.Children)-1
+		branch, next := "├─ ", "│  "
+		if last {
+			branch, next = "└─ ", "   "
+		}
+		renderPlanNode(sb, child, childPrefix+branch, childPrefix+next)
+	}
+}
+
+func formatPlanNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, ", ")
+	default:
+		return ""
+	}
+}
+
+// jsonNumber reads a number that may be encoded as a JSON number or string (MySQL uses strings)
+func jsonNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePostgresPlan(t *testing.T) {
+	data := `[{"Plan": {"Node Type": "Hash Join", "Join Type": "Inner", "Total Cost": 45.5, "Plan Rows": 10,
+		"Actual Rows": 5000, "Actual Loops": 1, "Hash Cond": "(o.user_id = u.id)",
+		"Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o", "Total Cost": 20, "Plan Rows": 1000, "Actual Rows": 1000, "Actual Loops": 1},
+			{"Node Type": "Index Scan", "Relation Name": "users", "Alias": "u", "Index Name": "users_pkey", "Total Cost": 8.3, "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 1}
+		]}}]`
+
+	root, err := ParsePostgresPlan(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if root.Operation != "Inner Hash Join" || len(root.Children) != 2 {
+		t.Fatalf("Unexpected root: %+v", root)
+	}
+	if !root.Misestimated() {
+		t.Error("Expected root to be flagged as misestimated")
+	}
+	if !root.Children[0].SeqScan || root.Children[0].Relation != "orders o" {
+		t.Errorf("Expected seq scan on orders, got %+v", root.Children[0])
+	}
+	if root.Children[1].SeqScan || root.Children[1].Misestimated() {
+		t.Errorf("Index scan should not be flagged: %+v", root.Children[1])
+	}
+
+	rendered := RenderPlan(root)
+	expected := []string{
+		"Inner Hash Join (cost=45.5 rows=10 actual=5000) ⚠️\n",
+		"├─ Seq Scan on orders o (cost=20 rows=1000 actual=1000) 🐢\n",
+		"└─ Index Scan on users u (cost=8.3 rows=1 actual=1)\n",
+		"      using users_pkey\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(rendered, line) {
+			t.Errorf("Expected %q in rendered plan:\n%s", line, rendered)
+		}
+	}
+}
+
+func TestParseMySQLPlan(t *testing.T) {
+	data := `{"query_block": {"select_id": 1, "cost_info": {"query_cost": "12.50"},
+		"ordering_operation": {"using_filesort": true,
+			"nested_loop": [
+				{"table": {"table_name": "o", "access_type": "ALL", "rows_examined_per_scan": 100, "cost_info": {"prefix_cost": "10.25"}}},
+				{"table": {"table_name": "u", "access_type": "eq_ref", "key": "PRIMARY", "rows_examined_per_scan": 1}}
+			]}}}`
+
+	root, err := ParseMySQLPlan(data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if root.Cost != 12.5 || len(root.Children) != 1 {
+		t.Fatalf("Unexpected root: %+v", root)
+	}
+	sort := root.Children[0]
+	if sort.Operation != "Sort" || sort.Detail != "using filesort" || len(sort.Children) != 2 {
+		t.Fatalf("Unexpected sort node: %+v", sort)
+	}
+	if scan := sort.Children[0]; !scan.SeqScan || scan.Operation != "Full Table Scan" || scan.Cost != 10.25 {
+		t.Errorf("Unexpected scan node: %+v", scan)
+	}
+	if lookup := sort.Children[1]; lookup.SeqScan || lookup.Detail != "using PRIMARY" {
+		t.Errorf("Unexpected lookup node: %+v", lookup)
+	}
+}
+
+func TestExplainQuerySQLite(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER)",
+	)
+
+	root, err := ExplainQuery(conn, SQLite, "SELECT * FROM orders o JOIN users u ON u.id = o.user_id;", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var scans []string
+	root.Walk(func(node *PlanNode) {
+		if node.SeqScan {
+			scans = append(scans, node.Relation)
+		}
+	})
+	if len(scans) != 1 || !strings.HasPrefix(scans[0], "o") {
+		t.Errorf("Expected a single full scan of orders, got %v\n%s", scans, RenderPlan(root))
+	}
+}
+
+func TestExplainStatement(t *testing.T) {
+	if _, err := ExplainStatement(MySQL, "SELECT 1", true); err == nil {
+		t.Error("Expected MySQL analyze to be rejected")
+	}
+	statement, err := ExplainStatement(PostgreSQL, "SELECT 1;", true)
+	if err != nil || statement != "EXPLAIN (ANALYZE, FORMAT JSON) SELECT 1" {
+		t.Errorf("Unexpected statement %q (%v)", statement, err)
+	}
+}
@@ -0,0 +1,285 @@
+package core
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PreImageNull is how NULL is written in a pre-image CSV, as COPY writes it
+const PreImageNull = `\N`
+
+// ErrNoPreImage is returned when no pre-image is left to undo
+var ErrNoPreImage = errors.New("no pre-image to undo")
+
+// PreImage describes the rows an UPDATE or DELETE was about to change, saved before it
+// ran so the change can be reversed. The rows are in a CSV file or a backup table.
+type PreImage struct {
+	Created     time.Time `json:"created"`
+	Statement   string    `json:"statement"`
+	Kind        string    `json:"kind"`                   // UPDATE or DELETE
+	Table       string    `json:"table"`                  // As written in the statement, such as public.users
+	Key         []string  `json:"key,omitempty"`          // Primary key columns; an UPDATE cannot be undone without them
+	File        string    `json:"file,omitempty"`         // CSV of the rows, NULL written as PreImageNull
+	Types       []string  `json:"types,omitempty"`        // Types of the CSV's columns, to write values back as literals
+	BackupTable string    `json:"backup_table,omitempty"` // Table holding the rows instead of a file
+	Rows        int       `json:"rows"`
+	Undone      bool      `json:"undone,omitempty"`
+	manifest    string    // Path the description is saved at
+}
+
+// PreImageName returns the name shared by a pre-image's files and backup table
+func PreImageName(created time.Time) string {
+	return fmt.Sprintf("sqlterm_preimage_%s_%03d", created.Format("20060102_150405"), created.Nanosecond()/int(time.Millisecond))
+}
+
+// PreImageTableQuery returns the statement copying the rows a modification changes into
+// a backup table
+func PreImageTableQuery(dbType DatabaseType, m Modification, backupTable string) (string, error) {
+	switch dbType {
+	case MySQL, PostgreSQL, SQLite, DuckDB:
+		return fmt.Sprintf("CREATE TABLE %s AS %s", QuoteIdentifier(dbType, backupTable), m.Select("*")), nil
+	default:
+		return "", ErrUnsupportedDatabase
+	}
+}
+
+// WritePreImageCSV writes the rows of a result to a CSV file, returning how many it wrote.
+// Times keep their fractional seconds and binary values are written in hex.
+func WritePreImageCSV(result *QueryResult, path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	columns := make([]string, len(result.Columns))
+	for i, column := range result.Columns {
+		columns[i] = column.Name
+	}
+	if err := writer.Write(columns); err != nil {
+		return 0, err
+	}
+	rows := 0
+	err = result.ForEachRow(func(row []Value) error {
+		record := make([]string, len(row))
+		for i, value := range row {
+			if value.IsNull() {
+				record[i] = PreImageNull
+			} else {
+				record[i] = preImageText(result.Columns[i], value)
+			}
+		}
+		rows++
+		return writer.Write(record)
+	})
+	if err != nil {
+		return rows, err
+	}
+	writer.Flush()
+	return rows, writer.Error()
+}
+
+// ReadPreImageCSV reads the columns and rows of a pre-image CSV; nil values are NULL.
+// types are the columns' types as saved with the pre-image, if any.
+func ReadPreImageCSV(path string, types []string) ([]Column, [][]*string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	names, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read pre-image header: %w", err)
+	}
+	columns := make([]Column, len(names))
+	for i, name := range names {
+		columns[i].Name = name
+		if i < len(types) {
+			columns[i].Type = types[i]
+		}
+	}
+	var rows [][]*string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		row := make([]*string, len(record))
+		for i, value := range record {
+			if value != PreImageNull {
+				row[i] = &record[i]
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows, nil
+}
+
+// ReadPreImageRows reads the rows of a query result the way ReadPreImageCSV does, for
+// pre-images kept in a backup table
+func ReadPreImageRows(result *QueryResult) ([]Column, [][]*string, error) {
+	var rows [][]*string
+	err := result.ForEachRow(func(values []Value) error {
+		row := make([]*string, len(values))
+		for i, value := range values {
+			if !value.IsNull() {
+				text := preImageText(result.Columns[i], value)
+				row[i] = &text
+			}
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	return result.Columns, rows, err
+}
+
+// isTimeColumn reports whether a column's values are saved in a pre-image as RFC 3339 times
+func isTimeColumn(col Column) bool {
+	return IsTimestampType(col.Type) || isDateType(col)
+}
+
+// preImageText writes a value the way preImageValue reads it back
+func preImageText(col Column, value Value) string {
+	if tv, ok := value.(TimeValue); ok && isTimeColumn(col) {
+		return tv.Value.Format(time.RFC3339Nano)
+	}
+	if isBinaryType(col) {
+		return hex.EncodeToString([]byte(value.String()))
+	}
+	return value.String()
+}
+
+// preImageValue reads a value written by preImageText; text that does not parse as the
+// column's type is kept as a string
+func preImageValue(col Column, text string) Value {
+	if isTimeColumn(col) {
+		if t, err := time.Parse(time.RFC3339Nano, text); err == nil {
+			return TimeValue{Value: t}
+		}
+	}
+	if isBinaryType(col) {
+		if data, err := hex.DecodeString(text); err == nil {
+			return StringValue{Value: string(data)}
+		}
+	}
+	return StringValue{Value: text}
+}
+
+// UndoStatements returns the statements that put the rows back: INSERTs for a DELETE,
+// and UPDATEs by primary key for an UPDATE. An UPDATE that changed the key itself
+// cannot be reversed this way.
+func (p *PreImage) UndoStatements(dbType DatabaseType, columns []Column, rows [][]*string) ([]string, error) {
+	literal := func(i int, value *string) string {
+		if value == nil {
+			return "NULL"
+		}
+		return SQLLiteral(dbType, preImageValue(columns[i], *value), columns[i])
+	}
+	quoted := make([]string, len(columns))
+	index := make(map[string]int, len(columns))
+	for i, column := range columns {
+		quoted[i] = QuoteIdentifier(dbType, column.Name)
+		index[strings.ToLower(column.Name)] = i
+	}
+
+	var statements []string
+	switch p.Kind {
+	case "DELETE":
+		for _, row := range rows {
+			values := make([]string, len(row))
+			for i, value := range row {
+				values[i] = literal(i, value)
+			}
+			statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+				p.Table, strings.Join(quoted, ", "), strings.Join(values, ", ")))
+		}
+	case "UPDATE":
+		if len(p.Key) == 0 {
+			return nil, fmt.Errorf("%s has no primary key to find the updated rows by", p.Table)
+		}
+		isKey := make(map[int]bool, len(p.Key))
+		for _, key := range p.Key {
+			i, ok := index[strings.ToLower(key)]
+			if !ok {
+				return nil, fmt.Errorf("key column %s is missing from the pre-image", key)
+			}
+			isKey[i] = true
+		}
+		for _, row := range rows {
+			var sets, conditions []string
+			for i, value := range row {
+				if isKey[i] {
+					conditions = append(conditions, quoted[i]+" = "+literal(i, value))
+				} else {
+					sets = append(sets, quoted[i]+" = "+literal(i, value))
+				}
+			}
+			if len(sets) == 0 {
+				continue
+			}
+			statements = append(statements, fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+				p.Table, strings.Join(sets, ", "), strings.Join(conditions, " AND ")))
+		}
+	default:
+		return nil, fmt.Errorf("cannot undo a %s statement", p.Kind)
+	}
+	return statements, nil
+}
+
+// SavePreImage writes the description of a pre-image to dir as <name>.json
+func SavePreImage(dir string, p *PreImage) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if p.manifest == "" {
+		p.manifest = filepath.Join(dir, PreImageName(p.Created)+".json")
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p.manifest, append(data, '\n'), 0600)
+}
+
+// LastPreImage returns the newest pre-image in dir that has not been undone
+func LastPreImage(dir string) (*PreImage, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// Names carry the time they were taken, so the newest sorts last
+	sort.Sort(sort.Reverse(sort.StringSlice(matches)))
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var p PreImage
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if !p.Undone {
+			p.manifest = path
+			return &p, nil
+		}
+	}
+	return nil, ErrNoPreImage
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPreImageUndoStatements(t *testing.T) {
+	name := "ann"
+	rows := [][]*string{{ptr("1"), &name, nil}}
+	columns := []Column{{Name: "id"}, {Name: "name"}, {Name: "note"}}
+
+	deleted := &PreImage{Kind: "DELETE", Table: "users"}
+	got, err := deleted.UndoStatements(PostgreSQL, columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`INSERT INTO users ("id", "name", "note") VALUES ('1', 'ann', NULL)`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DELETE undo = %q, want %q", got, want)
+	}
+
+	updated := &PreImage{Kind: "UPDATE", Table: "users", Key: []string{"ID"}}
+	got, err = updated.UndoStatements(MySQL, columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"UPDATE users SET `name` = 'ann', `note` = NULL WHERE `id` = '1'"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UPDATE undo = %q, want %q", got, want)
+	}
+
+	updated.Key = nil
+	if _, err := updated.UndoStatements(MySQL, columns, rows); err == nil {
+		t.Error("Expected an UPDATE without a key to be refused")
+	}
+}
+
+func TestPreImageCSV(t *testing.T) {
+	conn, err := NewConnection(&ConnectionConfig{Name: "test", DatabaseType: SQLite, Database: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	mustExec(t, conn, "CREATE TABLE events (id INTEGER PRIMARY KEY, at DATETIME, data BLOB)")
+	mustExec(t, conn, "INSERT INTO events VALUES (1, '2024-01-15 10:30:00.123456+00:00', X'00FF27')")
+
+	result, err := conn.Execute("SELECT * FROM events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	for _, column := range result.Columns {
+		types = append(types, column.Type)
+	}
+	path := filepath.Join(t.TempDir(), "events.csv")
+	if _, err := WritePreImageCSV(result, path); err != nil {
+		t.Fatal(err)
+	}
+	result.Close()
+
+	// Sub-second times and bytes that are not text survive the round trip
+	columns, rows, err := ReadPreImageCSV(path, types)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deleted := &PreImage{Kind: "DELETE", Table: "events"}
+	got, err := deleted.UndoStatements(SQLite, columns, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`INSERT INTO events ("id", "at", "data") VALUES ('1', '2024-01-15 10:30:00.123456+00:00', X'00ff27')`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DELETE undo = %q, want %q", got, want)
+	}
+}
+
+func TestLastPreImage(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LastPreImage(dir); err != ErrNoPreImage {
+		t.Fatalf("Expected ErrNoPreImage, got %v", err)
+	}
+
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	older := &PreImage{Created: created, Kind: "DELETE", Table: "a"}
+	newer := &PreImage{Created: created.Add(time.Second), Kind: "DELETE", Table: "b"}
+	for _, p := range []*PreImage{older, newer} {
+		if err := SavePreImage(dir, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if p, err := LastPreImage(dir); err != nil || p.Table != "b" {
+		t.Fatalf("Expected the newest pre-image, got %+v, %v", p, err)
+	}
+
+	newer.Undone = true
+	if err := SavePreImage(dir, newer); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := LastPreImage(dir); err != nil || p.Table != "a" {
+		t.Errorf("Expected undone pre-images to be skipped, got %+v, %v", p, err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(matches) != 2 {
+		t.Errorf("Expected saving again to overwrite, got %v", matches)
+	}
+}
+
+func ptr(s string) *string { return &s }
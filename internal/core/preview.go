@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultPreviewThreshold is the estimated table size above which SELECT * of the whole
+// table shows a preview first
+const DefaultPreviewThreshold = 100000
+
+// PreviewRows is how many rows that preview shows
+const PreviewRows = 100
+
+// BareTableScan reports whether a query reads every row and column of one table, as
+// SELECT * FROM table does, returning the table as written and its name without quotes,
+// such as public.users. Any other clause, join or column list makes ok false.
+func BareTableScan(query string) (table, name string, ok bool) {
+	tokens := tokenizeSQL(query)
+	if n := clauseEnd(tokens, 0, ";"); n < len(tokens) {
+		if n != len(tokens)-1 {
+			return "", "", false
+		}
+		tokens = tokens[:n]
+	}
+	if len(tokens) < 4 || tokens[0].keyword() != "SELECT" || tokens[1].text != "*" || tokens[1].quoted ||
+		tokens[2].keyword() != "FROM" {
+		return "", "", false
+	}
+
+	var parts []string
+	for i := 3; i < len(tokens); i++ {
+		tok := tokens[i]
+		expectName := (i-3)%2 == 0
+		switch {
+		case expectName && (tok.quoted || tok.isIdent()):
+			parts = append(parts, tok.text)
+		case !expectName && tok.text == "." && !tok.str && !tok.quoted:
+		default:
+			return "", "", false
+		}
+	}
+	if (len(tokens)-3)%2 == 0 {
+		// Ends with a dot
+		return "", "", false
+	}
+	return strings.TrimSpace(query[tokens[3].pos:tokenEnd(query, tokens[len(tokens)-1])]), strings.Join(parts, "."), true
+}
+
+// tokenEnd returns the byte offset just after a token, including any closing quote
+func tokenEnd(query string, tok sqlToken) int {
+	if !tok.quoted && !tok.str {
+		return tok.pos + len(tok.text)
+	}
+	// Doubled quotes inside make the written token longer than its text
+	end := tok.pos + 1
+	closing := query[tok.pos]
+	if closing == '[' {
+		closing = ']'
+	}
+	for end < len(query) {
+		if query[end] == closing {
+			if end+1 < len(query) && query[end+1] == closing && closing != ']' {
+				end += 2
+				continue
+			}
+			return end + 1
+		}
+		end++
+	}
+	return len(query)
+}
+
+// PreviewQuery returns the query reading the first PreviewRows rows of a table
+func PreviewQuery(table string) string {
+	return fmt.Sprintf("SELECT * FROM %s LIMIT %d", table, PreviewRows)
+}
@@ -0,0 +1,48 @@
+package core
+
+import "testing"
+
+func TestBareTableScan(t *testing.T) {
+	testCases := []struct {
+		query string
+		table string
+		name  string
+	}{
+		{"SELECT * FROM events", "events", "events"},
+		{"select * from public.events; ", "public.events", "public.events"},
+		{`SELECT * FROM "My ""Big"" Table" -- everything`, `"My ""Big"" Table"`, `My "Big" Table`},
+		{"SELECT * FROM `db`.`events`", "`db`.`events`", "db.events"},
+		{"SELECT * FROM events LIMIT 10", "", ""},
+		{"SELECT * FROM events WHERE id = 1", "", ""},
+		{"SELECT id FROM events", "", ""},
+		{"SELECT * FROM events e", "", ""},
+		{"SELECT * FROM a, b", "", ""},
+		{"SELECT * FROM events; DELETE FROM events", "", ""},
+		{"SELECT * FROM public.", "", ""},
+	}
+	for _, tc := range testCases {
+		table, name, ok := BareTableScan(tc.query)
+		if table != tc.table || name != tc.name || ok != (tc.table != "") {
+			t.Errorf("BareTableScan(%q) = %q, %q, %v, expected %q, %q", tc.query, table, name, ok, tc.table, tc.name)
+		}
+	}
+	if got := PreviewQuery(`"My Table"`); got != `SELECT * FROM "My Table" LIMIT 100` {
+		t.Errorf("Unexpected preview query %s", got)
+	}
+}
+
+func TestEstimateTableRowsSQLite(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT)",
+		"CREATE INDEX events_kind ON events (kind)",
+		"INSERT INTO events (kind) VALUES ('a'), ('b'), ('a')",
+	)
+	if rows := EstimateTableRows(conn, SQLite, "events"); rows != 0 {
+		t.Errorf("Expected no estimate before ANALYZE, got %d", rows)
+	}
+	mustExec(t, conn, "ANALYZE")
+	if rows := EstimateTableRows(conn, SQLite, "main.events"); rows != 3 {
+		t.Errorf("Expected an estimate of 3 rows, got %d", rows)
+	}
+}
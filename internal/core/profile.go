@@ -0,0 +1,327 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sqlterm/internal/i18n"
+)
+
+// ProfileTopValues is how many of the most common values a profile lists per column
+const ProfileTopValues = 5
+
+// TableProfile is the profile of some or all columns of a table
+type TableProfile struct {
+	Table   string
+	Rows    int64
+	Columns []ColumnProfile
+}
+
+// ColumnProfile summarises the values of one column. Distinct is -1 for types that are
+// only counted, and Min and Max are empty when they do not apply or every value is NULL.
+type ColumnProfile struct {
+	Column       string
+	Type         string
+	Nulls        int64
+	Distinct     int64
+	Min          string
+	Max          string
+	Distribution string // Mean and spread of numbers, or lengths of text; empty for other types
+	TopValues    []ValueCount
+}
+
+// ValueCount is a value and how many rows have it
+type ValueCount struct {
+	Value string
+	Count int64
+}
+
+type columnKind int
+
+const (
+	otherColumn columnKind = iota
+	numericColumn
+	textColumn
+	temporalColumn
+	booleanColumn
+)
+
+var (
+	numericTypePattern  = regexp.MustCompile(`^(unsigned )?((tiny|small|medium|big|huge)?int(eger)?\d*|u?int\d+|decimal|numeric|real|float\d*|double|number|money|(small|big)?serial)\b`)
+	textTypePattern     = regexp.MustCompile(`^((var)?char|character|n?varchar|nchar|(tiny|medium|long)?text|citext|string|fixedstring|clob|enum)\b`)
+	temporalTypePattern = regexp.MustCompile(`^(date|datetime|time|timestamp|timestamptz|year)`)
+	booleanTypePattern  = regexp.MustCompile(`^(bool|boolean|bit)\b`)
+)
+
+// classifyColumnType groups a declared column type by the statistics that apply to
+// it. ClickHouse wrappers such as Nullable(...) are looked through. Other types, such
+// as JSON or arrays, may not support comparison in every dialect, so they are only
+// counted.
+func classifyColumnType(sqlType string) columnKind {
+	t := strings.ToLower(strings.TrimSpace(sqlType))
+	for _, wrapper := range []string{"nullable(", "lowcardinality("} {
+		t = strings.TrimPrefix(t, wrapper)
+	}
+	switch {
+	case numericTypePattern.MatchString(t):
+		return numericColumn
+	case textTypePattern.MatchString(t):
+		return textColumn
+	case temporalTypePattern.MatchString(t):
+		return temporalColumn
+	case booleanTypePattern.MatchString(t):
+		return booleanColumn
+	default:
+		return otherColumn
+	}
+}
+
+func lengthFunction(dbType DatabaseType) string {
+	switch dbType {
+	case MySQL:
+		return "CHAR_LENGTH"
+	case ClickHouse:
+		return "lengthUTF8"
+	default:
+		return "LENGTH"
+	}
+}
+
+// stddevFunction returns the population standard deviation aggregate, empty for SQLite
+// which has none
+func stddevFunction(dbType DatabaseType) string {
+	switch dbType {
+	case SQLite:
+		return ""
+	case ClickHouse:
+		return "stddevPop"
+	default:
+		return "STDDEV_POP"
+	}
+}
+
+// profileExpressions returns the aggregates computed for a column, in the order
+// parseProfileValues reads them
+func profileExpressions(dbType DatabaseType, column ColumnInfo) []string {
+	col := QuoteIdentifier(dbType, column.Name)
+	kind := classifyColumnType(column.Type)
+	exprs := []string{fmt.Sprintf("COUNT(%s)", col)}
+	if kind == otherColumn {
+		return exprs
+	}
+	exprs = append(exprs, fmt.Sprintf("COUNT(DISTINCT %s)", col))
+	if kind == booleanColumn {
+		return exprs
+	}
+	exprs = append(exprs, fmt.Sprintf("MIN(%s)", col), fmt.Sprintf("MAX(%s)", col))
+
+	switch kind {
+	case numericColumn:
+		exprs = append(exprs, fmt.Sprintf("AVG(%s)", col))
+		if stddev := stddevFunction(dbType); stddev != "" {
+			exprs = append(exprs, fmt.Sprintf("%s(%s)", stddev, col))
+		}
+	case textColumn:
+		length := lengthFunction(dbType)
+		exprs = append(exprs,
+			fmt.Sprintf("MIN(%s(%s))", length, col),
+			fmt.Sprintf("MAX(%s(%s))", length, col),
+			fmt.Sprintf("AVG(%s(%s))", length, col))
+	}
+	return exprs
+}
+
+// ProfileQuery builds the single aggregate query behind a profile: the row count, then
+// the aggregates of each column in turn
+func ProfileQuery(dbType DatabaseType, table string, columns []ColumnInfo) string {
+	exprs := []string{"COUNT(*)"}
+	for _, column := range columns {
+		exprs = append(exprs, profileExpressions(dbType, column)...)
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), QuoteQualifiedName(dbType, table))
+}
+
+// ProfileTable profiles the named columns of a table, or all of them when none are
+// named. The statistics come from one aggregate query; the most common values need a
+// grouped query per column, skipped for columns whose values are all distinct.
+func ProfileTable(conn Connection, dbType DatabaseType, table string, columnNames []string) (*TableProfile, error) {
+	info, err := conn.DescribeTable(table)
+	if err != nil {
+		return nil, err
+	}
+	if len(info.Columns) == 0 {
+		return nil, fmt.Errorf("table %s not found", table)
+	}
+
+	columns := info.Columns
+	if len(columnNames) > 0 {
+		columns = nil
+		for _, name := range columnNames {
+			i := slices.IndexFunc(info.Columns, func(c ColumnInfo) bool { return strings.EqualFold(c.Name, name) })
+			if i < 0 {
+				return nil, fmt.Errorf("column %s not found in %s", name, table)
+			}
+			columns = append(columns, info.Columns[i])
+		}
+	}
+
+	rows, err := queryStrings(conn, ProfileQuery(dbType, table, columns))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("profile query returned no rows")
+	}
+	row := rows[0]
+
+	profile := &TableProfile{Table: table}
+	profile.Rows, _ = strconv.ParseInt(row[0], 10, 64)
+	pos := 1
+	for _, column := range columns {
+		n := len(profileExpressions(dbType, column))
+		if pos+n > len(row) {
+			return nil, fmt.Errorf("profile query returned %d values, expected more", len(row))
+		}
+		profile.Columns = append(profile.Columns, parseProfileValues(dbType, column, profile.Rows, row[pos:pos+n]))
+		pos += n
+	}
+
+	for i := range profile.Columns {
+		cp := &profile.Columns[i]
+		if nonNull := profile.Rows - cp.Nulls; nonNull == 0 || cp.Distinct < 0 || cp.Distinct == nonNull {
+			continue
+		}
+		if cp.TopValues, err = topValues(conn, dbType, table, cp.Column); err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+// parseProfileValues reads a column's aggregates, as listed by profileExpressions
+func parseProfileValues(dbType DatabaseType, column ColumnInfo, rows int64, values []string) ColumnProfile {
+	nonNull, _ := strconv.ParseInt(values[0], 10, 64)
+	cp := ColumnProfile{Column: column.Name, Type: column.Type, Nulls: rows - nonNull, Distinct: -1}
+	if len(values) == 1 {
+		return cp
+	}
+	cp.Distinct, _ = strconv.ParseInt(values[1], 10, 64)
+	if len(values) == 2 || nonNull == 0 {
+		return cp
+	}
+	cp.Min, cp.Max = values[2], values[3]
+
+	switch classifyColumnType(column.Type) {
+	case numericColumn:
+		cp.Distribution = "mean " + formatStat(values[4])
+		if stddevFunction(dbType) != "" {
+			cp.Distribution += ", stddev " + formatStat(values[5])
+		}
+	case textColumn:
+		cp.Distribution = fmt.Sprintf("length %s–%s, mean %s", values[4], values[5], formatStat(values[6]))
+	}
+	return cp
+}
+
+// formatStat shortens an average or deviation to a readable precision
+func formatStat(value string) string {
+	f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return value
+	}
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
+
+func topValues(conn Connection, dbType DatabaseType, table, column string) ([]ValueCount, error) {
+	col := QuoteIdentifier(dbType, column)
+	query := fmt.Sprintf("SELECT %s, COUNT(*) FROM %s WHERE %s IS NOT NULL GROUP BY %s ORDER BY 2 DESC, 1 LIMIT %d",
+		col, QuoteQualifiedName(dbType, table), col, col, ProfileTopValues)
+	rows, err := queryStrings(conn, query)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]ValueCount, 0, len(rows))
+	for _, row := range rows {
+		count, _ := strconv.ParseInt(row[1], 10, 64)
+		values = append(values, ValueCount{Value: row[0], Count: count})
+	}
+	return values, nil
+}
+
+// NullPercent is the share of rows where the column is NULL
+func (cp ColumnProfile) NullPercent(rows int64) float64 {
+	if rows == 0 {
+		return 0
+	}
+	return float64(cp.Nulls) * 100 / float64(rows)
+}
+
+// Summary describes the column in one line, as context for the AI
+func (cp ColumnProfile) Summary(rows int64) string {
+	parts := []string{fmt.Sprintf("%.1f%% null", cp.NullPercent(rows))}
+	if cp.Distinct >= 0 {
+		parts = append(parts, fmt.Sprintf("%d distinct", cp.Distinct))
+	}
+	if cp.Min != "" || cp.Max != "" {
+		parts = append(parts, fmt.Sprintf("range %s to %s", truncateProfileValue(cp.Min), truncateProfileValue(cp.Max)))
+	}
+	if cp.Distribution != "" {
+		parts = append(parts, cp.Distribution)
+	}
+	if len(cp.TopValues) > 0 {
+		parts = append(parts, "common values "+formatTopValues(cp.TopValues))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatTopValues(values []ValueCount) string {
+	items := make([]string, len(values))
+	for i, v := range values {
+		items[i] = fmt.Sprintf("%s (%d)", truncateProfileValue(v.Value), v.Count)
+	}
+	return strings.Join(items, ", ")
+}
+
+// truncateProfileValue keeps long text values from swamping a profile
+func truncateProfileValue(value string) string {
+	const maxLen = 40
+	if r := []rune(value); len(r) > maxLen {
+		return string(r[:maxLen-1]) + "…"
+	}
+	return value
+}
+
+// FormatProfile renders a profile as markdown: a table of statistics, then the most
+// common values of each column
+func FormatProfile(profile *TableProfile, i18nMgr *i18n.Manager) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", i18nMgr.GetWithArgs("profile_title", profile.Table))
+	fmt.Fprintf(&sb, "%s\n\n", i18nMgr.GetWithArgs("profile_rows", profile.Rows))
+	fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s | %s | %s |\n|---|---|---|---:|---:|---|---|\n",
+		i18nMgr.Get("profile_column_column"), i18nMgr.Get("profile_column_type"), i18nMgr.Get("profile_column_nulls"),
+		i18nMgr.Get("profile_column_distinct"), i18nMgr.Get("profile_column_min"), i18nMgr.Get("profile_column_max"),
+		i18nMgr.Get("profile_column_distribution"))
+	for _, cp := range profile.Columns {
+		distinct := "–"
+		if cp.Distinct >= 0 {
+			distinct = strconv.FormatInt(cp.Distinct, 10)
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %.1f%% | %s | %s | %s | %s |\n", markdownCell(cp.Column), markdownCell(cp.Type),
+			cp.NullPercent(profile.Rows), distinct, markdownCell(truncateProfileValue(cp.Min)),
+			markdownCell(truncateProfileValue(cp.Max)), markdownCell(cp.Distribution))
+	}
+
+	var top strings.Builder
+	for _, cp := range profile.Columns {
+		if len(cp.TopValues) > 0 {
+			fmt.Fprintf(&top, "- **%s**: %s\n", cp.Column, formatTopValues(cp.TopValues))
+		}
+	}
+	if top.Len() > 0 {
+		fmt.Fprintf(&sb, "\n## %s\n\n%s", i18nMgr.Get("profile_top_values"), top.String())
+	}
+	return sb.String()
+}
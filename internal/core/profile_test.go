@@ -0,0 +1,86 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"sqlterm/internal/i18n"
+)
+
+func TestClassifyColumnType(t *testing.T) {
+	testCases := map[string]columnKind{
+		"INTEGER":                     numericColumn,
+		"bigint unsigned":             numericColumn,
+		"double precision":            numericColumn,
+		"Nullable(Float64)":           numericColumn,
+		"decimal(10,2)":               numericColumn,
+		"character varying":           textColumn,
+		"LowCardinality(String)":      textColumn,
+		"enum('a','b')":               textColumn,
+		"timestamp without time zone": temporalColumn,
+		"DateTime64(3)":               temporalColumn,
+		"boolean":                     booleanColumn,
+		"interval":                    otherColumn,
+		"point":                       otherColumn,
+		"jsonb":                       otherColumn,
+		"":                            otherColumn,
+	}
+	for sqlType, want := range testCases {
+		if got := classifyColumnType(sqlType); got != want {
+			t.Errorf("classifyColumnType(%q) = %d, want %d", sqlType, got, want)
+		}
+	}
+}
+
+func TestProfileTable(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER, status TEXT, total REAL, meta BLOB)",
+		"INSERT INTO orders VALUES (1, 'new', 10, NULL), (2, 'new', 20, NULL), (3, 'paid', NULL, NULL), (4, NULL, 30, NULL)",
+	)
+
+	profile, err := ProfileTable(conn, SQLite, "orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Rows != 4 || len(profile.Columns) != 4 {
+		t.Fatalf("Expected 4 rows and 4 columns, got %+v", profile)
+	}
+
+	id, status, total, meta := profile.Columns[0], profile.Columns[1], profile.Columns[2], profile.Columns[3]
+	if id.Distinct != 4 || id.Min != "1" || id.Max != "4" || id.TopValues != nil {
+		t.Errorf("Unexpected id profile: %+v", id)
+	}
+	if status.Nulls != 1 || status.Distinct != 2 || status.Distribution != "length 3–4, mean 3.33333" {
+		t.Errorf("Unexpected status profile: %+v", status)
+	}
+	if want := []ValueCount{{"new", 2}, {"paid", 1}}; !reflect.DeepEqual(status.TopValues, want) {
+		t.Errorf("Expected top values %v, got %v", want, status.TopValues)
+	}
+	if total.Distribution != "mean 20" || total.NullPercent(profile.Rows) != 25 {
+		t.Errorf("Unexpected total profile: %+v", total)
+	}
+	if meta.Distinct != -1 || meta.Nulls != 4 {
+		t.Errorf("Expected meta to be only counted, got %+v", meta)
+	}
+	if got := status.Summary(profile.Rows); got != "25.0% null, 2 distinct, range new to paid, length 3–4, mean 3.33333, common values new (2), paid (1)" {
+		t.Errorf("Unexpected summary: %s", got)
+	}
+
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md := FormatProfile(profile, i18nMgr); !strings.Contains(md, "| status | TEXT | 25.0% | 2 | new | paid |") {
+		t.Errorf("Expected a status row in the markdown, got\n%s", md)
+	}
+
+	one, err := ProfileTable(conn, SQLite, "orders", []string{"TOTAL"})
+	if err != nil || len(one.Columns) != 1 || one.Columns[0].Column != "total" {
+		t.Errorf("Expected only the total column, got %+v, %v", one, err)
+	}
+	if _, err := ProfileTable(conn, SQLite, "orders", []string{"missing"}); err == nil {
+		t.Error("Expected an error for a missing column")
+	}
+}
@@ -0,0 +1,422 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// ErrReadOnlyConnection is returned when a write statement is sent over a read-only connection
+var ErrReadOnlyConnection = errors.New("connection is read-only: only queries that read data are allowed")
+
+var readOnlyKeywords = []string{
+	"SELECT", "WITH", "SHOW", "EXPLAIN", "DESCRIBE", "DESC", "VALUES", "TABLE", "PRAGMA",
+}
+
+// IsReadOnlyQuery reports whether a statement only reads data, judged by its leading keyword
+func IsReadOnlyQuery(query string) bool {
+	return ClassifyStatement(query) == StatementRead
+}
+
+// Statement classes, as used by the production safety prompts and policy files
+const (
+	StatementRead        = "read"        // SELECT, SHOW, EXPLAIN and other statements that only read
+	StatementDML         = "dml"         // INSERT, UPDATE, DELETE and other changes to rows
+	StatementDDL         = "ddl"         // CREATE, ALTER, DROP and other changes to the schema
+	StatementDCL         = "dcl"         // GRANT and REVOKE
+	StatementTransaction = "transaction" // BEGIN, COMMIT, ROLLBACK and savepoints
+	StatementOther       = "other"       // Anything else, such as SET, CALL or VACUUM
+)
+
+// StatementClasses lists the statement classes in the order they are documented
+var StatementClasses = []string{
+	StatementRead, StatementDML, StatementDDL, StatementDCL, StatementTransaction, StatementOther,
+}
+
+var statementClasses = map[string][]string{
+	StatementDML:         {"INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "UPSERT", "COPY"},
+	StatementDDL:         {"CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME"},
+	StatementDCL:         {"GRANT", "REVOKE"},
+	StatementTransaction: {"BEGIN", "START", "COMMIT", "END", "ROLLBACK", "SAVEPOINT", "RELEASE"},
+}
+
+// ClassifyStatement returns the class of a statement, judged by its leading keyword.
+// Statements that start like a read are classed by the change they hide, if any, and
+// input holding several statements, which drivers such as lib/pq run in one go, by the
+// strongest class among them.
+func ClassifyStatement(query string) string {
+	class := ""
+	for _, statement := range splitStatementText(query) {
+		if c := classifyOne(statement); class == "" || classStrength(c) > classStrength(class) {
+			class = c
+		}
+	}
+	if class == "" {
+		return StatementOther
+	}
+	return class
+}
+
+// classStrength orders the classes from reads to schema changes, for classing input
+// that holds several statements
+func classStrength(class string) int {
+	return slices.Index([]string{
+		StatementRead, StatementTransaction, StatementOther, StatementDCL, StatementDML, StatementDDL,
+	}, class)
+}
+
+// splitStatementText splits input at the semicolons between its statements, ignoring
+// those inside strings, quoted names, dollar-quoted bodies and comments, and drops
+// empty ones. Each statement runs from its first token, so comments before it go.
+func splitStatementText(query string) []string {
+	var statements []string
+	start := -1
+	add := func(stop int) {
+		if start >= 0 {
+			statements = append(statements, strings.TrimSpace(query[start:stop]))
+			start = -1
+		}
+	}
+	for _, tok := range tokenizeSQL(query) {
+		switch {
+		case tok.text == ";" && !tok.str && !tok.quoted:
+			add(tok.pos)
+		case start < 0:
+			start = tok.pos
+		}
+	}
+	add(len(query))
+	return statements
+}
+
+// classifyOne classes a single statement
+func classifyOne(query string) string {
+	keyword := leadingKeyword(query)
+	if keyword == "EXPLAIN" {
+		// EXPLAIN ANALYZE runs the statement it explains
+		if wrapped, ok := explainAnalyzed(query); ok {
+			return classifyOne(wrapped)
+		}
+		return StatementRead
+	}
+	if slices.Contains(readOnlyKeywords, keyword) {
+		if class := hiddenWrite(keyword, query); class != "" {
+			return class
+		}
+		return StatementRead
+	}
+	for class, keywords := range statementClasses {
+		if slices.Contains(keywords, keyword) {
+			return class
+		}
+	}
+	return StatementOther
+}
+
+// explainAnalyzed returns the statement an EXPLAIN runs, when its options ask for
+// ANALYZE either bare, as in EXPLAIN ANALYZE VERBOSE, or in PostgreSQL's parenthesised
+// list, as in EXPLAIN (ANALYZE, BUFFERS). ok is false for plain EXPLAINs.
+func explainAnalyzed(query string) (string, bool) {
+	tokens := tokenizeSQL(query)
+	analyze := false
+	for i := 1; i < len(tokens); i++ {
+		kw := tokens[i].keyword()
+		switch {
+		case i == 1 && tokens[i].text == "(":
+			end := matchingParen(tokens, i)
+			for j := i + 1; j < end; j++ {
+				if k := tokens[j].keyword(); k == "ANALYZE" || k == "ANALYSE" {
+					next := tokens[j+1].keyword()
+					analyze = next != "FALSE" && next != "OFF" && next != "0"
+				}
+			}
+			i = end
+		case kw == "ANALYZE" || kw == "ANALYSE":
+			analyze = true
+		case slices.Contains(readOnlyKeywords, kw) || slices.Contains(statementKeywords, kw):
+			return query[tokens[i].pos:], analyze
+		}
+	}
+	return "", false
+}
+
+// cteWriteKeywords start the statements a writable CTE can run
+var cteWriteKeywords = []string{"INSERT", "UPDATE", "DELETE", "MERGE"}
+
+// hiddenWrite returns the class of the change a statement that starts like a read
+// makes: DML for a WITH whose queries insert, update, delete or merge, as PostgreSQL's
+// writable CTEs and MySQL 8's WITH ... DELETE do, and DDL for SELECT ... INTO, which
+// creates a table (or, on MySQL, writes a file). It returns "" for true reads, which
+// include MySQL's SELECT ... INTO @variable.
+func hiddenWrite(keyword, query string) string {
+	if keyword != "WITH" && keyword != "SELECT" {
+		return ""
+	}
+	tokens := tokenizeSQL(query)
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		switch kw := tok.keyword(); {
+		case keyword == "WITH" && slices.Contains(cteWriteKeywords, kw):
+			// FOR UPDATE only locks the rows a query reads
+			if kw == "UPDATE" && i > 0 && tokens[i-1].keyword() == "FOR" {
+				continue
+			}
+			return StatementDML
+		case kw == "INTO" && depth == 0:
+			if i+1 < len(tokens) && tokens[i+1].text == "@" {
+				continue
+			}
+			return StatementDDL
+		}
+	}
+	return ""
+}
+
+// execKeywords start the changes that run through Exec when they return no rows
+var execKeywords = []string{"INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "UPSERT"}
+
+// changesOnly reports whether input is a single change to rows that returns none, which
+// can run through Exec so the driver reports how many rows it affected
+func changesOnly(query string) bool {
+	if !slices.Contains(execKeywords, leadingKeyword(query)) || len(splitStatementText(query)) != 1 {
+		return false
+	}
+	for _, tok := range tokenizeSQL(query) {
+		if kw := tok.keyword(); kw == "RETURNING" || kw == "OUTPUT" {
+			return false
+		}
+	}
+	return true
+}
+
+// LeadingKeyword returns the upper-cased first keyword of a statement, skipping comments
+func LeadingKeyword(query string) string {
+	return leadingKeyword(query)
+}
+
+// StatementKeywords returns the leading keyword of each statement in input, taking the
+// statement an EXPLAIN ANALYZE runs in place of the EXPLAIN
+func StatementKeywords(query string) []string {
+	var keywords []string
+	for _, statement := range splitStatementText(query) {
+		keyword := leadingKeyword(statement)
+		if keyword == "EXPLAIN" {
+			if wrapped, ok := explainAnalyzed(statement); ok {
+				keywords = append(keywords, keyword)
+				keyword = leadingKeyword(wrapped)
+			}
+		}
+		keywords = append(keywords, keyword)
+	}
+	return keywords
+}
+
+// statementKeywords start the statements IsSQLStatement recognises, on top of readOnlyKeywords
+var statementKeywords = []string{
+	"INSERT", "UPDATE", "DELETE", "REPLACE", "MERGE", "UPSERT", "CREATE", "ALTER", "DROP",
+	"TRUNCATE", "RENAME", "GRANT", "REVOKE", "BEGIN", "START", "COMMIT", "ROLLBACK",
+	"SAVEPOINT", "RELEASE", "SET", "USE", "CALL", "ANALYZE", "ANALYSE", "VACUUM", "REINDEX",
+	"ATTACH", "DETACH", "COPY", "LOCK",
+}
+
+// IsSQLStatement reports whether input starts with a keyword that begins an SQL statement
+func IsSQLStatement(input string) bool {
+	keyword := leadingKeyword(input)
+	return slices.Contains(readOnlyKeywords, keyword) || slices.Contains(statementKeywords, keyword)
+}
+
+// TransactionChange reports whether a statement opens or ends a transaction. Rolling
+// back to a savepoint leaves the transaction open.
+func TransactionChange(query string) (opens, ends bool) {
+	switch leadingKeyword(query) {
+	case "BEGIN":
+		return true, false
+	case "START":
+		return strings.Contains(strings.ToUpper(query), "TRANSACTION"), false
+	case "COMMIT", "END":
+		return false, true
+	case "ROLLBACK":
+		rest := strings.Fields(strings.ToUpper(strings.TrimSpace(query)))
+		return false, len(rest) < 2 || !strings.HasPrefix(rest[1], "TO")
+	}
+	return false, false
+}
+
+// leadingKeyword returns the first SQL keyword, skipping comments and opening parentheses
+func leadingKeyword(query string) string {
+	q := strings.TrimSpace(query)
+	for {
+		switch {
+		case strings.HasPrefix(q, "--"):
+			if idx := strings.Index(q, "\n"); idx >= 0 {
+				q = strings.TrimSpace(q[idx+1:])
+			} else {
+				q = ""
+			}
+		case strings.HasPrefix(q, "/*"):
+			if idx := strings.Index(q, "*/"); idx >= 0 {
+				q = strings.TrimSpace(q[idx+2:])
+			} else {
+				q = ""
+			}
+		case strings.HasPrefix(q, "("):
+			q = strings.TrimSpace(q[1:])
+		default:
+			end := strings.IndexFunc(q, func(r rune) bool {
+				return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+			})
+			if end < 0 {
+				end = len(q)
+			}
+			return strings.ToUpper(q[:end])
+		}
+	}
+}
+
+// Modification is a single-table UPDATE or DELETE, split into the parts needed to look
+// at the rows it changes before it runs
+type Modification struct {
+	Kind   string // UPDATE or DELETE
+	Table  string // The table as written, such as public.users
+	Name   string // The table's name without schema or quotes, for catalog lookups
+	Target string // The table with any alias, for a FROM clause
+	Where  string // The condition without WHERE; empty when every row changes
+}
+
+// ParseModification reads a single-table UPDATE or DELETE. ok is false for other
+// statements and for forms a query over the one table cannot predict: joins, USING,
+// FROM in an UPDATE, LIMIT and MySQL's modifiers.
+func ParseModification(query string) (m Modification, ok bool) {
+	m, _, _, ok = splitModification(query)
+	return m, ok
+}
+
+// splitModification parses a modification like ParseModification, also returning the
+// statement's text before its WHERE clause and after it, from any ORDER BY or RETURNING
+func splitModification(query string) (m Modification, head, tail string, ok bool) {
+	tokens := tokenizeSQL(query)
+	end := len(query)
+	if n := clauseEnd(tokens, 0, ";"); n < len(tokens) {
+		end = tokens[n].pos
+		tokens = tokens[:n]
+	}
+	text := func(from, to int) string {
+		stop := end
+		if to < len(tokens) {
+			stop = tokens[to].pos
+		}
+		return strings.TrimSpace(query[tokens[from].pos:stop])
+	}
+	if len(tokens) < 2 {
+		return m, "", "", false
+	}
+
+	var tableStart, tableEnd, rest int
+	m.Kind = tokens[0].keyword()
+	switch m.Kind {
+	case "DELETE":
+		if tokens[1].keyword() != "FROM" {
+			return m, "", "", false
+		}
+		tableStart = 2
+		tableEnd = clauseEnd(tokens, tableStart, "WHERE", "USING", "ORDER", "LIMIT", "RETURNING")
+		rest = tableEnd
+	case "UPDATE":
+		tableStart = 1
+		tableEnd = clauseEnd(tokens, tableStart, "SET")
+		if tableEnd == len(tokens) {
+			return m, "", "", false
+		}
+		rest = clauseEnd(tokens, tableEnd, "WHERE", "FROM", "ORDER", "LIMIT", "RETURNING")
+	default:
+		return m, "", "", false
+	}
+	if tableEnd <= tableStart {
+		return m, "", "", false
+	}
+	for _, tok := range tokens[tableStart:tableEnd] {
+		switch tok.keyword() {
+		case ",", "JOIN", "IGNORE", "LOW_PRIORITY", "QUICK":
+			return m, "", "", false
+		}
+	}
+	if clauseEnd(tokens, rest, "USING", "FROM", "LIMIT") < len(tokens) {
+		return m, "", "", false
+	}
+
+	m.Target = text(tableStart, tableEnd)
+	// The name runs to the first token that is neither part of it nor a dot
+	nameStart := tableStart
+	if tokens[nameStart].keyword() == "ONLY" && nameStart+1 < tableEnd {
+		nameStart++
+	}
+	nameEnd := nameStart + 1
+	for nameEnd+1 < tableEnd && tokens[nameEnd].text == "." && !tokens[nameEnd].str && !tokens[nameEnd].quoted {
+		nameEnd += 2
+	}
+	m.Table = text(nameStart, nameEnd)
+	m.Name = tokens[nameEnd-1].text
+
+	tailStart := rest
+	if rest < len(tokens) && tokens[rest].keyword() == "WHERE" {
+		if rest+1 == len(tokens) {
+			return m, "", "", false
+		}
+		tailStart = clauseEnd(tokens, rest+1, "ORDER", "RETURNING")
+		m.Where = text(rest+1, tailStart)
+	}
+	head = strings.TrimSpace(query[:end])
+	if rest < len(tokens) {
+		head = strings.TrimSpace(query[:tokens[rest].pos])
+	}
+	if tailStart < len(tokens) {
+		tail = text(tailStart, len(tokens))
+	}
+	return m, head, tail, true
+}
+
+// Select returns a SELECT of columns over the rows the statement changes
+func (m Modification) Select(columns string) string {
+	query := "SELECT " + columns + " FROM " + m.Target
+	if m.Where != "" {
+		query += " WHERE " + m.Where
+	}
+	return query
+}
+
+// RestrictModification returns an UPDATE or DELETE changing only the rows that also
+// match condition, which is ANDed to any WHERE clause it has
+func RestrictModification(query, condition string) (string, bool) {
+	m, head, tail, ok := splitModification(query)
+	if !ok {
+		return "", false
+	}
+	return strings.TrimSpace(head + " WHERE " + m.restrict(condition).Where + " " + tail), true
+}
+
+// restrict returns the modification with condition ANDed to its WHERE clause
+func (m Modification) restrict(condition string) Modification {
+	switch {
+	case condition == "":
+	case m.Where == "":
+		m.Where = condition
+	default:
+		m.Where = "(" + m.Where + ") AND " + condition
+	}
+	return m
+}
+
+// AffectedRowsQuery returns a SELECT counting the rows an UPDATE or DELETE would change
+func AffectedRowsQuery(query string) (string, bool) {
+	m, ok := ParseModification(query)
+	if !ok {
+		return "", false
+	}
+	return m.Select("count(*)"), true
+}
@@ -0,0 +1,252 @@
+package core
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected bool
+	}{
+		{name: "Select", query: "SELECT * FROM users", expected: true},
+		{name: "Lowercase select", query: "  select 1", expected: true},
+		{name: "CTE", query: "WITH x AS (SELECT 1) SELECT * FROM x", expected: true},
+		{name: "Writable CTE", query: "WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", expected: false},
+		{name: "Select into", query: "SELECT * INTO users_copy FROM users", expected: false},
+		{name: "Parenthesised select", query: "(SELECT 1) UNION (SELECT 2)", expected: true},
+		{name: "Leading comments", query: "-- report\n/* v2 */ SELECT 1", expected: true},
+		{name: "Explain", query: "EXPLAIN SELECT 1", expected: true},
+		{name: "Insert", query: "INSERT INTO users VALUES (1)", expected: false},
+		{name: "Update", query: "update users set name = 'x'", expected: false},
+		{name: "Drop", query: "DROP TABLE users", expected: false},
+		{name: "Commented out select", query: "-- SELECT 1\nDELETE FROM users", expected: false},
+		{name: "Empty", query: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsReadOnlyQuery(tc.query); got != tc.expected {
+				t.Errorf("IsReadOnlyQuery(%q) = %v, expected %v", tc.query, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestIsSQLStatement(t *testing.T) {
+	for _, input := range []string{
+		"SELECT 1", "  select * from users", "-- note\nINSERT INTO t VALUES (1)",
+		"update users set name = 'x'", "CREATE TABLE t (id INT)", "BEGIN", "pragma table_info(t)",
+	} {
+		if !IsSQLStatement(input) {
+			t.Errorf("Expected %q to be recognised as SQL", input)
+		}
+	}
+	for _, input := range []string{
+		"how many users signed up last week", "Selecting rows", "", "?SELECT 1",
+	} {
+		if IsSQLStatement(input) {
+			t.Errorf("Expected %q not to be recognised as SQL", input)
+		}
+	}
+}
+
+func TestClassifyStatement(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", StatementRead},
+		{"-- cleanup\ndelete from users", StatementDML},
+		{"COPY users FROM '/tmp/users.csv'", StatementDML},
+		{"DROP TABLE users", StatementDDL},
+		{"GRANT SELECT ON users TO reporting", StatementDCL},
+		{"ROLLBACK", StatementTransaction},
+		{"VACUUM", StatementOther},
+		{"", StatementOther},
+		// Statements that start like a read but write
+		{"WITH d AS (DELETE FROM users WHERE id = 1 RETURNING *) SELECT * FROM d", StatementDML},
+		{"WITH old AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM old)", StatementDML},
+		{"with n as (insert into audit (note) values ('x') returning id) select id from n", StatementDML},
+		{"WITH t AS (SELECT 1) MERGE INTO users USING t ON true WHEN MATCHED THEN DELETE", StatementDML},
+		{"SELECT * INTO users_backup FROM users", StatementDDL},
+		{"SELECT id FROM users INTO OUTFILE '/tmp/users.csv'", StatementDDL},
+		{"WITH x AS (SELECT 1 AS a) SELECT * INTO copy FROM x", StatementDDL},
+		// Reads that only mention those keywords
+		{"WITH x AS (SELECT * FROM users FOR UPDATE) SELECT * FROM x", StatementRead},
+		{"WITH x AS (SELECT 'DELETE FROM users' AS q) SELECT q FROM x", StatementRead},
+		{"SELECT id FROM users WHERE id IN (SELECT user_id FROM orders)", StatementRead},
+		{"SELECT \"into\" FROM notes", StatementRead},
+		{"SELECT id INTO @last_id FROM users ORDER BY id DESC LIMIT 1", StatementRead},
+		// EXPLAIN ANALYZE runs the statement it explains
+		{"EXPLAIN ANALYZE DELETE FROM users", StatementDML},
+		{"explain analyze verbose update users set name = 'x'", StatementDML},
+		{"EXPLAIN (ANALYZE, BUFFERS) UPDATE users SET name = 'x'", StatementDML},
+		{"EXPLAIN (ANALYZE) CREATE TABLE copy AS SELECT * FROM users", StatementDDL},
+		{"EXPLAIN ANALYZE SELECT * FROM users", StatementRead},
+		{"EXPLAIN (ANALYZE false) DELETE FROM users", StatementRead},
+		{"EXPLAIN DELETE FROM users", StatementRead},
+		// Several statements are classed by the strongest among them
+		{"SELECT 1; DELETE FROM users", StatementDML},
+		{"SELECT 1; DROP TABLE users; DELETE FROM users", StatementDDL},
+		{"BEGIN; SELECT 1", StatementTransaction},
+		{"SELECT 1;", StatementRead},
+		{"SELECT ';' AS s; SELECT 2", StatementRead},
+		{"SELECT '(' AS s; DELETE FROM users", StatementDML},
+		{";", StatementOther},
+	}
+	for _, tc := range testCases {
+		if got := ClassifyStatement(tc.query); got != tc.expected {
+			t.Errorf("ClassifyStatement(%q) = %q, expected %q", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestStatementKeywords(t *testing.T) {
+	got := StatementKeywords("SELECT 1; -- then\ndrop table users; EXPLAIN ANALYZE DELETE FROM users; EXPLAIN SELECT 1")
+	expected := []string{"SELECT", "DROP", "EXPLAIN", "DELETE", "EXPLAIN"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("StatementKeywords = %q, expected %q", got, expected)
+	}
+}
+
+func TestRowsAffected(t *testing.T) {
+	conn, err := NewConnection(&ConnectionConfig{Name: "test", DatabaseType: SQLite, Database: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	defer conn.Close()
+	mustExec(t, conn, "CREATE TABLE users (id INTEGER PRIMARY KEY, active INTEGER)", "INSERT INTO users (active) VALUES (1), (1), (0)")
+
+	result, err := conn.Execute("UPDATE users SET active = 0 WHERE active = 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, ok := result.RowsAffected(); !ok || affected != 2 {
+		t.Errorf("Expected 2 rows affected, got %d, %v", affected, ok)
+	}
+	result.Close()
+
+	// Changes that return rows are read like queries
+	result, err = conn.Execute("DELETE FROM users WHERE id = 1 RETURNING id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := 0
+	for range result.Itor() {
+		rows++
+	}
+	result.Close()
+	if _, ok := result.RowsAffected(); ok || rows != 1 {
+		t.Errorf("Expected the deleted row returned, got %d rows", rows)
+	}
+}
+
+func TestTransactionChange(t *testing.T) {
+	testCases := []struct {
+		query       string
+		opens, ends bool
+	}{
+		{"BEGIN", true, false},
+		{"start transaction read only", true, false},
+		{"COMMIT;", false, true},
+		{"ROLLBACK", false, true},
+		{"ROLLBACK TO SAVEPOINT before_import", false, false},
+		{"rollback work", false, true},
+		{"UPDATE users SET name = 'x'", false, false},
+	}
+	for _, tc := range testCases {
+		opens, ends := TransactionChange(tc.query)
+		if opens != tc.opens || ends != tc.ends {
+			t.Errorf("TransactionChange(%q) = %v, %v, expected %v, %v", tc.query, opens, ends, tc.opens, tc.ends)
+		}
+	}
+}
+
+func TestReadOnlyConnection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	writable, err := NewConnection(&ConnectionConfig{Name: "rw", DatabaseType: SQLite, Database: path})
+	if err != nil {
+		t.Fatalf("Failed to open connection: %v", err)
+	}
+	mustExec(t, writable, "CREATE TABLE users (id INTEGER)")
+	writable.Close()
+
+	conn, err := NewConnection(&ConnectionConfig{Name: "ro", DatabaseType: SQLite, Database: path, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to open read-only connection: %v", err)
+	}
+	defer conn.Close()
+
+	for _, statement := range []string{
+		"INSERT INTO users VALUES (1)",
+		"WITH old AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM old)",
+	} {
+		if _, err := conn.Execute(statement); !errors.Is(err, ErrReadOnlyConnection) {
+			t.Errorf("Expected ErrReadOnlyConnection for %q, got %v", statement, err)
+		}
+	}
+
+	result, err := conn.Execute("SELECT COUNT(*) FROM users")
+	if err != nil {
+		t.Fatalf("Read query failed on read-only connection: %v", err)
+	}
+	result.Close()
+}
+
+func TestParseModification(t *testing.T) {
+	m, ok := ParseModification(`UPDATE ONLY public."Users" AS u SET name = 'x' WHERE u.id = 1`)
+	expected := Modification{Kind: "UPDATE", Table: `public."Users"`, Name: "Users", Target: `ONLY public."Users" AS u`, Where: "u.id = 1"}
+	if !ok || m != expected {
+		t.Errorf("ParseModification = %+v, %v, expected %+v", m, ok, expected)
+	}
+}
+
+func TestAffectedRowsQuery(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{"DELETE FROM users WHERE id = 1", "SELECT count(*) FROM users WHERE id = 1"},
+		{"delete from public.users u where u.name = 'a;b' RETURNING id;", "SELECT count(*) FROM public.users u WHERE u.name = 'a;b'"},
+		{"UPDATE users SET name = 'x', note = (SELECT n FROM notes LIMIT 1) WHERE id IN (SELECT id FROM banned)",
+			"SELECT count(*) FROM users WHERE id IN (SELECT id FROM banned)"},
+		{"UPDATE \"Users\" SET active = false", "SELECT count(*) FROM \"Users\""},
+		{"-- cleanup\nDELETE FROM logs WHERE created < now() ORDER BY created", "SELECT count(*) FROM logs WHERE created < now()"},
+		{"DELETE FROM logs LIMIT 10", ""},
+		{"UPDATE users SET name = o.name FROM orders o WHERE o.user_id = users.id", ""},
+		{"UPDATE users u JOIN orders o ON o.user_id = u.id SET u.total = o.total", ""},
+		{"DELETE FROM users USING banned WHERE banned.id = users.id", ""},
+		{"DELETE users FROM users JOIN banned ON banned.id = users.id", ""},
+		{"INSERT INTO users VALUES (1)", ""},
+	}
+	for _, tc := range testCases {
+		got, ok := AffectedRowsQuery(tc.query)
+		if got != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("AffectedRowsQuery(%q) = %q, %v, expected %q", tc.query, got, ok, tc.expected)
+		}
+	}
+}
+
+func TestRestrictModification(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{"DELETE FROM events WHERE created_at < '2023-01-01';", "DELETE FROM events WHERE (created_at < '2023-01-01') AND id <= 10"},
+		{"UPDATE users SET active = false", "UPDATE users SET active = false WHERE id <= 10"},
+		{"DELETE FROM logs WHERE a = 1 OR b = 2 RETURNING id", "DELETE FROM logs WHERE (a = 1 OR b = 2) AND id <= 10 RETURNING id"},
+		{"UPDATE t SET n = 0 ORDER BY id", "UPDATE t SET n = 0 WHERE id <= 10 ORDER BY id"},
+		{"DELETE FROM logs LIMIT 10", ""},
+	}
+	for _, tc := range testCases {
+		got, ok := RestrictModification(tc.query, "id <= 10")
+		if got != tc.expected || ok != (tc.expected != "") {
+			t.Errorf("RestrictModification(%q) = %q, %v, expected %q", tc.query, got, ok, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replica is a read replica of a connection's database. It shares the connection's
+// credentials and database name.
+type Replica struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port,omitempty"` // The primary's port when unset
+}
+
+// Address is the replica's host:port, with the primary's port when it has none
+func (r Replica) Address(primaryPort int) string {
+	port := r.Port
+	if port == 0 {
+		port = primaryPort
+	}
+	return net.JoinHostPort(r.Host, strconv.Itoa(port))
+}
+
+// ParseReplica reads host or host:port
+func ParseReplica(address string) (Replica, error) {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		// No port given
+		if address == "" || strings.ContainsAny(address, " /") {
+			return Replica{}, fmt.Errorf("invalid replica address %q", address)
+		}
+		return Replica{Host: address}, nil
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || host == "" {
+		return Replica{}, fmt.Errorf("invalid replica address %q", address)
+	}
+	return Replica{Host: host, Port: port}, nil
+}
+
+// ReplicaStatus is how far a replica is behind its primary
+type ReplicaStatus struct {
+	Address string
+	Lag     time.Duration
+	Err     error // Set when the lag could not be read
+}
+
+// replicaKeywords start the statements RouteQuery sends to a replica, unless they write
+// as writable CTEs do. SHOW, EXPLAIN and the like stay on the primary, since they
+// describe the server they run on.
+var replicaKeywords = []string{"SELECT", "WITH", "VALUES", "TABLE"}
+
+// lockingReadPattern matches SELECT ... FOR UPDATE and FOR SHARE, which take row locks
+// and so need the primary
+var lockingReadPattern = regexp.MustCompile(`(?i)\bFOR\s+(?:NO\s+KEY\s+)?(?:UPDATE|SHARE|KEY\s+SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b`)
+
+// openReplicas opens a pool per configured replica, with the primary's settings
+func (c *connection) openReplicas() error {
+	if c.config.DatabaseType != MySQL && c.config.DatabaseType != PostgreSQL {
+		return fmt.Errorf("read replicas are only supported for MySQL and PostgreSQL connections")
+	}
+	for _, replica := range c.config.Replicas {
+		config := *c.config
+		config.Host = replica.Host
+		if replica.Port != 0 {
+			config.Port = replica.Port
+		}
+		config.Replicas = nil
+		// Only reads are routed to replicas; let the client say so if one slips through
+		config.ReadOnly = true
+
+		db, _, err := openDB(&config)
+		if err != nil {
+			return fmt.Errorf("failed to open replica %s: %w", replica.Host, err)
+		}
+		c.replicas = append(c.replicas, newConnection(db, &config))
+	}
+	return nil
+}
+
+// RouteQuery returns the connection a statement should run on: the next replica in turn
+// for queries that only read data, when the connection has replicas, and conn itself
+// otherwise, including while the session is pinned or inside a transaction, whose reads
+// must see its own uncommitted changes
+func RouteQuery(conn Connection, query string, inTransaction bool) Connection {
+	c, ok := conn.(*connection)
+	if !ok || len(c.replicas) == 0 || c.pinned.Load() || inTransaction {
+		return conn
+	}
+	if !slices.Contains(replicaKeywords, leadingKeyword(query)) || ClassifyStatement(query) != StatementRead ||
+		lockingReadPattern.MatchString(literalPattern.ReplaceAllString(query, "''")) {
+		return conn
+	}
+	next := c.nextReplica.Add(1) - 1
+	return c.replicas[int(next)%len(c.replicas)]
+}
+
+// ReplicaLag reports the replication lag of each of the connection's replicas, nil
+// when it has none
+func ReplicaLag(conn Connection) []ReplicaStatus {
+	c, ok := conn.(*connection)
+	if !ok {
+		return nil
+	}
+	var statuses []ReplicaStatus
+	for _, replica := range c.replicas {
+		lag, err := replicationLag(replica)
+		statuses = append(statuses, ReplicaStatus{Address: replicaAddress(replica), Lag: lag, Err: err})
+	}
+	return statuses
+}
+
+func replicaAddress(c *connection) string {
+	return net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
+}
+
+// errNotReplicating is returned for a replica whose replication is stopped or that is
+// not a replica at all
+var errNotReplicating = errors.New("not replicating")
+
+func replicationLag(replica *connection) (time.Duration, error) {
+	switch replica.config.DatabaseType {
+	case MySQL:
+		// SHOW SLAVE STATUS is the name before MySQL 8.0.22 and on MariaDB
+		result, err := replica.Execute("SHOW REPLICA STATUS")
+		if err != nil {
+			if result, err = replica.Execute("SHOW SLAVE STATUS"); err != nil {
+				return 0, err
+			}
+		}
+		defer result.Close()
+		column := slices.IndexFunc(result.ColumnNames(), func(name string) bool {
+			return name == "Seconds_Behind_Source" || name == "Seconds_Behind_Master"
+		})
+		lag := time.Duration(-1)
+		err = result.ForEachRow(func(row []Value) error {
+			if column < 0 || row[column].IsNull() {
+				return nil
+			}
+			seconds, err := strconv.ParseFloat(row[column].String(), 64)
+			lag = time.Duration(seconds * float64(time.Second))
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+		if lag < 0 {
+			return 0, errNotReplicating
+		}
+		return lag, nil
+	case PostgreSQL:
+		// A replica that has replayed everything it received is caught up, however old
+		// the last transaction is
+		rows, err := queryStrings(replica, `
+			SELECT CASE
+				WHEN NOT pg_is_in_recovery() THEN NULL
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+			END`)
+		if err != nil {
+			return 0, err
+		}
+		if len(rows) == 0 || rows[0][0] == "" {
+			return 0, errNotReplicating
+		}
+		seconds, err := strconv.ParseFloat(rows[0][0], 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	default:
+		return 0, fmt.Errorf("unsupported database type: %v", replica.config.DatabaseType)
+	}
+}
@@ -0,0 +1,86 @@
+package core
+
+import "testing"
+
+func TestRouteQuery(t *testing.T) {
+	primary := &connection{config: &ConnectionConfig{DatabaseType: PostgreSQL}}
+	first := &connection{config: &ConnectionConfig{DatabaseType: PostgreSQL, Host: "replica-1"}}
+	second := &connection{config: &ConnectionConfig{DatabaseType: PostgreSQL, Host: "replica-2"}}
+	primary.replicas = []*connection{first, second}
+
+	testCases := []struct {
+		query    string
+		expected Connection
+	}{
+		{"SELECT * FROM users", first},
+		{"WITH recent AS (SELECT 1) SELECT * FROM recent", second},
+		{"select id from orders", first},
+		{"UPDATE users SET name = 'a'", primary},
+		{"SELECT * FROM users FOR UPDATE", primary},
+		{"SELECT * FROM jobs FOR NO KEY UPDATE SKIP LOCKED", primary},
+		{"SELECT 'for update' AS note", second},
+		{"SHOW server_version", primary},
+		{"EXPLAIN SELECT 1", primary},
+		{"WITH d AS (DELETE FROM users RETURNING *) SELECT * FROM d", primary},
+		{"SELECT * INTO users_copy FROM users", primary},
+	}
+
+	for _, tc := range testCases {
+		if got := RouteQuery(primary, tc.query, false); got != tc.expected {
+			t.Errorf("RouteQuery(%q) went to %s, expected %s", tc.query,
+				got.(*connection).config.Host, tc.expected.(*connection).config.Host)
+		}
+	}
+
+	// Reads inside a transaction must see its changes, which only the primary has
+	if got := RouteQuery(primary, "SELECT * FROM users", true); got != Connection(primary) {
+		t.Errorf("Expected a read inside a transaction to stay on the primary, went to %s", got.(*connection).config.Host)
+	}
+
+	// Without replicas everything stays on the connection
+	single := &connection{config: &ConnectionConfig{DatabaseType: MySQL}}
+	if got := RouteQuery(single, "SELECT 1", false); got != Connection(single) {
+		t.Error("Expected a connection without replicas to run its own queries")
+	}
+}
+
+func TestParseReplica(t *testing.T) {
+	testCases := []struct {
+		address  string
+		expected Replica
+		wantErr  bool
+	}{
+		{"db-replica", Replica{Host: "db-replica"}, false},
+		{"db-replica:5433", Replica{Host: "db-replica", Port: 5433}, false},
+		{"[::1]:3307", Replica{Host: "::1", Port: 3307}, false},
+		{"db-replica:abc", Replica{}, true},
+		{"", Replica{}, true},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseReplica(tc.address)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseReplica(%q) error = %v, wantErr %v", tc.address, err, tc.wantErr)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("ParseReplica(%q) = %+v, expected %+v", tc.address, got, tc.expected)
+		}
+	}
+
+	if got := (Replica{Host: "db-replica"}).Address(5432); got != "db-replica:5432" {
+		t.Errorf("Expected the primary's port, got %s", got)
+	}
+}
+
+func TestReplicasRequireServerDatabase(t *testing.T) {
+	_, err := NewConnection(&ConnectionConfig{
+		Name:         "local",
+		DatabaseType: SQLite,
+		Database:     ":memory:",
+		Replicas:     []Replica{{Host: "elsewhere"}},
+	})
+	if err == nil {
+		t.Error("Expected replicas on a SQLite connection to be rejected")
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// RetryPolicy controls automatic retries of read-only statements after transient errors
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts including the first; 1 disables retries
+	Backoff     time.Duration // Delay before the first retry, doubled on each further retry
+	MaxBackoff  time.Duration // Upper bound for the delay
+}
+
+// DefaultRetryPolicy returns the policy used when none is configured
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     200 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+	}
+}
+
+// Delay returns the wait before the given retry (1 for the first retry)
+func (p RetryPolicy) Delay(retry int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < retry && (p.MaxBackoff <= 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+// RetryFunc is notified before each retry with the attempt that failed
+type RetryFunc func(attempt, maxAttempts int, delay time.Duration, err error)
+
+// ExecuteWithRetry runs query on conn, retrying read-only statements that fail with a
+// transient error. Statements that modify data are never retried since they may have
+// partially applied; that includes writable CTEs and SELECT ... INTO, which start like
+// reads.
+func ExecuteWithRetry(conn Connection, query string, policy RetryPolicy, onRetry RetryFunc) (*QueryResult, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 || ClassifyStatement(query) != StatementRead {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := conn.Execute(query)
+		if err == nil || attempt >= maxAttempts || !IsTransientError(err) {
+			return result, err
+		}
+
+		delay := policy.Delay(attempt)
+		if onRetry != nil {
+			onRetry(attempt, maxAttempts, delay, err)
+		}
+		time.Sleep(delay)
+	}
+}
+
+// IsTransientError reports whether err is likely to succeed on retry: deadlocks,
+// serialization failures and dropped connections
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", "40P01", "55P03", "57P01":
+			// serialization_failure, deadlock_detected, lock_not_available, admin_shutdown
+			return true
+		}
+		// Class 08: connection exceptions
+		return strings.HasPrefix(string(pqErr.Code), "08")
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1205, 1213: // lock wait timeout, deadlock
+			return true
+		}
+		return false
+	}
+
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "database is locked") || strings.Contains(msg, "connection reset")
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// flakyConnection fails the first failures calls to Execute with err
+type flakyConnection struct {
+	Connection
+	failures int
+	err      error
+	calls    int
+}
+
+func (c *flakyConnection) Execute(query string) (*QueryResult, error) {
+	c.calls++
+	if c.calls <= c.failures {
+		return nil, fmt.Errorf("failed to execute query: %w", c.err)
+	}
+	return &QueryResult{}, nil
+}
+
+func TestExecuteWithRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	deadlock := &pq.Error{Code: "40P01"}
+
+	testCases := []struct {
+		name          string
+		query         string
+		failures      int
+		err           error
+		expectCalls   int
+		expectRetries int
+		expectError   bool
+	}{
+		{name: "Succeeds after deadlock", query: "SELECT 1", failures: 2, err: deadlock, expectCalls: 3, expectRetries: 2},
+		{name: "Gives up after max attempts", query: "SELECT 1", failures: 5, err: deadlock, expectCalls: 3, expectRetries: 2, expectError: true},
+		{name: "Writes are not retried", query: "UPDATE t SET a = 1", failures: 1, err: deadlock, expectCalls: 1, expectError: true},
+		{name: "Writable CTEs are not retried", query: "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d", failures: 1, err: deadlock, expectCalls: 1, expectError: true},
+		{name: "SELECT INTO is not retried", query: "SELECT * INTO t_copy FROM t", failures: 1, err: &pq.Error{Code: "40001"}, expectCalls: 1, expectError: true},
+		{name: "Permanent errors are not retried", query: "SELECT 1", failures: 1, err: errors.New("syntax error"), expectCalls: 1, expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := &flakyConnection{failures: tc.failures, err: tc.err}
+			retries := 0
+			_, err := ExecuteWithRetry(conn, tc.query, policy, func(attempt, maxAttempts int, delay time.Duration, err error) {
+				retries++
+				if attempt != retries || maxAttempts != 3 {
+					t.Errorf("Unexpected attempt %d/%d", attempt, maxAttempts)
+				}
+			})
+
+			if (err != nil) != tc.expectError {
+				t.Errorf("Expected error %v, got %v", tc.expectError, err)
+			}
+			if conn.calls != tc.expectCalls {
+				t.Errorf("Expected %d calls, got %d", tc.expectCalls, conn.calls)
+			}
+			if retries != tc.expectRetries {
+				t.Errorf("Expected %d retries, got %d", tc.expectRetries, retries)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "Postgres serialization failure", err: &pq.Error{Code: "40001"}, expected: true},
+		{name: "Postgres connection failure", err: &pq.Error{Code: "08006"}, expected: true},
+		{name: "Postgres syntax error", err: &pq.Error{Code: "42601"}, expected: false},
+		{name: "MySQL deadlock", err: &mysql.MySQLError{Number: 1213}, expected: true},
+		{name: "MySQL unknown column", err: &mysql.MySQLError{Number: 1054}, expected: false},
+		{name: "Bad connection", err: fmt.Errorf("wrapped: %w", driver.ErrBadConn), expected: true},
+		{name: "SQLite busy", err: errors.New("database is locked"), expected: true},
+		{name: "Nil", err: nil, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsTransientError(tc.err); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.Delay(i + 1); got != want {
+			t.Errorf("Retry %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SampleTableSampleMinRows is the estimated table size from which PostgreSQL samples
+// with TABLESAMPLE rather than sorting every row by random()
+const SampleTableSampleMinRows = 100000
+
+// sampleOversample is how many times more rows TABLESAMPLE keeps than asked for, so the
+// LIMIT is nearly always filled despite BERNOULLI's variance
+const sampleOversample = 4
+
+// SampleOptions describe a random sample of a table
+type SampleOptions struct {
+	Table     string
+	Rows      int
+	Stratify  string // Column to take Rows rows from each value of, empty for a plain sample
+	Estimated int64  // Estimated table rows, 0 when unknown; see EstimateTableRows
+}
+
+// randomFunction returns the dialect's random number function
+func randomFunction(dbType DatabaseType) string {
+	switch dbType {
+	case MySQL:
+		return "RAND()"
+	case ClickHouse:
+		return "rand()"
+	default:
+		return "random()"
+	}
+}
+
+// SampleQuery builds the dialect's random sampling query. DuckDB uses its reservoir
+// sample, and PostgreSQL TABLESAMPLE on large tables; elsewhere rows are ordered by a
+// random number. A stratified sample takes up to Rows rows for each value of the
+// column, which appears as an extra sample_row column where the dialect cannot filter
+// on the window function directly.
+func SampleQuery(dbType DatabaseType, opts SampleOptions) string {
+	table := QuoteQualifiedName(dbType, opts.Table)
+	random := randomFunction(dbType)
+
+	if opts.Stratify != "" {
+		column := QuoteIdentifier(dbType, opts.Stratify)
+		switch dbType {
+		case ClickHouse:
+			return fmt.Sprintf("SELECT * FROM %s ORDER BY %s LIMIT %d BY %s", table, random, opts.Rows, column)
+		case DuckDB:
+			return fmt.Sprintf("SELECT * FROM %s QUALIFY row_number() OVER (PARTITION BY %s ORDER BY %s) <= %d",
+				table, column, random, opts.Rows)
+		default:
+			return fmt.Sprintf("SELECT * FROM (SELECT t.*, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS sample_row FROM %s t) sampled WHERE sample_row <= %d",
+				column, random, table, opts.Rows)
+		}
+	}
+
+	switch {
+	case dbType == DuckDB:
+		return fmt.Sprintf("SELECT * FROM %s USING SAMPLE reservoir(%d ROWS)", table, opts.Rows)
+	case dbType == PostgreSQL && opts.Estimated >= SampleTableSampleMinRows && opts.Estimated > int64(opts.Rows)*sampleOversample:
+		percent := float64(opts.Rows) * sampleOversample * 100 / float64(opts.Estimated)
+		return fmt.Sprintf("SELECT * FROM %s TABLESAMPLE BERNOULLI (%s) ORDER BY %s LIMIT %d",
+			table, strconv.FormatFloat(percent, 'g', 4, 64), random, opts.Rows)
+	default:
+		return fmt.Sprintf("SELECT * FROM %s ORDER BY %s LIMIT %d", table, random, opts.Rows)
+	}
+}
+
+// EstimateTableRows returns the row count a database keeps in its statistics for a
+// table, without counting: the planner's estimate on PostgreSQL, the catalog's on MySQL,
+// DuckDB and ClickHouse, and ANALYZE's on SQLite. Unknown tables and tables without
+// statistics give 0.
+func EstimateTableRows(conn Connection, dbType DatabaseType, table string) int64 {
+	name := table
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		name = table[i+1:]
+	}
+	var query string
+	switch dbType {
+	case PostgreSQL:
+		query = fmt.Sprintf("SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass(%s)",
+			QuoteLiteral(dbType, QuoteQualifiedName(dbType, table)))
+	case MySQL:
+		query = fmt.Sprintf("SELECT TABLE_ROWS FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = %s",
+			QuoteLiteral(dbType, name))
+	case DuckDB:
+		query = fmt.Sprintf("SELECT estimated_size FROM duckdb_tables() WHERE table_name = %s", QuoteLiteral(dbType, name))
+	case ClickHouse:
+		query = fmt.Sprintf("SELECT total_rows FROM system.tables WHERE database = currentDatabase() AND name = %s",
+			QuoteLiteral(dbType, name))
+	case SQLite:
+		// The first number of a stat is the table's row count
+		query = fmt.Sprintf("SELECT stat FROM sqlite_stat1 WHERE tbl = %s LIMIT 1", QuoteLiteral(dbType, name))
+	default:
+		return 0
+	}
+	rows, err := queryStrings(conn, query)
+	if err != nil || len(rows) == 0 || len(rows[0]) == 0 {
+		return 0
+	}
+	fields := strings.Fields(rows[0][0])
+	if len(fields) == 0 {
+		return 0
+	}
+	estimate, _ := strconv.ParseInt(fields[0], 10, 64)
+	return max(estimate, 0)
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestSampleQuery(t *testing.T) {
+	testCases := []struct {
+		name   string
+		dbType DatabaseType
+		opts   SampleOptions
+		want   string
+	}{
+		{
+			name:   "mysql",
+			dbType: MySQL,
+			opts:   SampleOptions{Table: "orders", Rows: 10},
+			want:   "SELECT * FROM `orders` ORDER BY RAND() LIMIT 10",
+		},
+		{
+			name:   "small postgres table sorts",
+			dbType: PostgreSQL,
+			opts:   SampleOptions{Table: "orders", Rows: 10, Estimated: 5000},
+			want:   `SELECT * FROM "orders" ORDER BY random() LIMIT 10`,
+		},
+		{
+			name:   "large postgres table uses tablesample",
+			dbType: PostgreSQL,
+			opts:   SampleOptions{Table: "app.orders", Rows: 100, Estimated: 1000000},
+			want:   `SELECT * FROM "app"."orders" TABLESAMPLE BERNOULLI (0.04) ORDER BY random() LIMIT 100`,
+		},
+		{
+			name:   "duckdb reservoir",
+			dbType: DuckDB,
+			opts:   SampleOptions{Table: "orders", Rows: 5},
+			want:   `SELECT * FROM "orders" USING SAMPLE reservoir(5 ROWS)`,
+		},
+		{
+			name:   "clickhouse stratified",
+			dbType: ClickHouse,
+			opts:   SampleOptions{Table: "orders", Rows: 5, Stratify: "status"},
+			want:   `SELECT * FROM "orders" ORDER BY rand() LIMIT 5 BY "status"`,
+		},
+		{
+			name:   "duckdb stratified",
+			dbType: DuckDB,
+			opts:   SampleOptions{Table: "orders", Rows: 5, Stratify: "status"},
+			want:   `SELECT * FROM "orders" QUALIFY row_number() OVER (PARTITION BY "status" ORDER BY random()) <= 5`,
+		},
+		{
+			name:   "postgres stratified ignores tablesample",
+			dbType: PostgreSQL,
+			opts:   SampleOptions{Table: "orders", Rows: 5, Stratify: "status", Estimated: 1000000},
+			want:   `SELECT * FROM (SELECT t.*, ROW_NUMBER() OVER (PARTITION BY "status" ORDER BY random()) AS sample_row FROM "orders" t) sampled WHERE sample_row <= 5`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := SampleQuery(tc.dbType, tc.opts); got != tc.want {
+				t.Errorf("SampleQuery() =\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSampleQuerySQLite(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER, status TEXT)",
+		"INSERT INTO orders VALUES (1, 'new'), (2, 'new'), (3, 'new'), (4, 'paid'), (5, 'paid'), (6, 'void')",
+	)
+
+	rows, err := queryStrings(conn, SampleQuery(SQLite, SampleOptions{Table: "orders", Rows: 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Errorf("Expected 4 sampled rows, got %d", len(rows))
+	}
+
+	rows, err = queryStrings(conn, SampleQuery(SQLite, SampleOptions{Table: "orders", Rows: 2, Stratify: "status"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	perStatus := map[string]int{}
+	for _, row := range rows {
+		perStatus[row[1]]++
+	}
+	if perStatus["new"] != 2 || perStatus["paid"] != 2 || perStatus["void"] != 1 {
+		t.Errorf("Expected up to 2 rows per status, got %v", perStatus)
+	}
+}
@@ -0,0 +1,307 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Catalog queries that describe every table at once. Each returns the table name first.
+const (
+	mysqlSchemaColumnsQuery = `
+		SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, COLUMN_DEFAULT, EXTRA
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME NOT IN (
+			SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'SEQUENCE')
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`
+	mysqlSchemaPrimaryKeysQuery = `
+		SELECT TABLE_NAME, COLUMN_NAME
+		FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = DATABASE() AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`
+	mysqlSchemaConstraintsQuery = `
+		SELECT tc.TABLE_NAME, tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE, kcu.COLUMN_NAME, cc.CHECK_CLAUSE
+		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+		LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+			ON tc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND tc.TABLE_NAME = kcu.TABLE_NAME
+			AND tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+		LEFT JOIN INFORMATION_SCHEMA.CHECK_CONSTRAINTS cc
+			ON tc.CONSTRAINT_SCHEMA = cc.CONSTRAINT_SCHEMA AND tc.CONSTRAINT_NAME = cc.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.CONSTRAINT_TYPE IN ('UNIQUE', 'CHECK')`
+	// TiDB ignores CHECK constraints unless tidb_enable_check_constraint is on, and
+	// before 7.2 has no CHECK_CONSTRAINTS table to join
+	tidbSchemaConstraintsQuery = `
+		SELECT tc.TABLE_NAME, tc.CONSTRAINT_NAME, tc.CONSTRAINT_TYPE, kcu.COLUMN_NAME, NULL
+		FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+		JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+			ON tc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA AND tc.TABLE_NAME = kcu.TABLE_NAME
+			AND tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+		WHERE tc.TABLE_SCHEMA = DATABASE() AND tc.CONSTRAINT_TYPE = 'UNIQUE'`
+	mysqlSchemaForeignKeysQuery = `
+		SELECT kcu.TABLE_NAME, kcu.CONSTRAINT_NAME, kcu.COLUMN_NAME, kcu.REFERENCED_TABLE_NAME,
+		       kcu.REFERENCED_COLUMN_NAME, rc.DELETE_RULE, rc.UPDATE_RULE
+		FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+		JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS rc
+			ON kcu.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA AND kcu.CONSTRAINT_NAME = rc.CONSTRAINT_NAME
+		WHERE kcu.TABLE_SCHEMA = DATABASE() AND kcu.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY kcu.TABLE_NAME, kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION`
+
+	// information_schema.columns lists views too; ListTables only returns tables
+	postgresSchemaColumnsQuery = `
+		SELECT c.table_name, c.column_name, c.data_type, c.is_nullable, '', c.column_default, ''
+		FROM information_schema.columns c
+		JOIN pg_tables t ON t.schemaname = c.table_schema AND t.tablename = c.table_name
+		WHERE c.table_schema = 'public'
+		ORDER BY c.table_name, c.ordinal_position`
+	postgresSchemaPrimaryKeysQuery = `
+		SELECT tc.table_name, kcu.column_name
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+		WHERE tc.table_schema = 'public' AND tc.constraint_type = 'PRIMARY KEY'
+		ORDER BY tc.table_name, kcu.ordinal_position`
+	postgresSchemaConstraintsQuery = `
+		SELECT tc.table_name, tc.constraint_name, tc.constraint_type, kcu.column_name, cc.check_clause
+		FROM information_schema.table_constraints tc
+		LEFT JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_schema = kcu.constraint_schema AND tc.constraint_name = kcu.constraint_name
+		LEFT JOIN information_schema.check_constraints cc
+			ON tc.constraint_schema = cc.constraint_schema AND tc.constraint_name = cc.constraint_name
+		WHERE tc.table_schema = 'public' AND tc.constraint_type IN ('UNIQUE', 'CHECK')`
+	postgresSchemaForeignKeysQuery = `
+		SELECT tc.table_name, tc.constraint_name, kcu.column_name, ccu.table_name, ccu.column_name,
+		       rc.delete_rule, rc.update_rule
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON tc.constraint_schema = kcu.constraint_schema AND tc.constraint_name = kcu.constraint_name
+		JOIN information_schema.constraint_column_usage ccu
+			ON ccu.constraint_schema = tc.constraint_schema AND ccu.constraint_name = tc.constraint_name
+		JOIN information_schema.referential_constraints rc
+			ON tc.constraint_schema = rc.constraint_schema AND tc.constraint_name = rc.constraint_name
+		WHERE tc.table_schema = 'public' AND tc.constraint_type = 'FOREIGN KEY'
+		ORDER BY tc.table_name, tc.constraint_name, kcu.ordinal_position`
+
+	// DuckDB reports constraints through its own table functions. Columns are aliased
+	// because the shell prints each row as an object keyed by column name.
+	duckdbSchemaColumnsQuery = `
+		SELECT table_name, column_name, data_type, is_nullable, '' AS column_key,
+		       column_default, '' AS extra
+		FROM information_schema.columns
+		WHERE table_schema = 'main'
+		ORDER BY table_name, ordinal_position`
+	duckdbSchemaPrimaryKeysQuery = `
+		SELECT table_name, unnest(constraint_column_names) AS column_name
+		FROM duckdb_constraints()
+		WHERE schema_name = 'main' AND constraint_type = 'PRIMARY KEY'`
+	duckdbSchemaConstraintsQuery = `
+		SELECT table_name, constraint_type || '_' || constraint_index AS constraint_name, constraint_type,
+		       array_to_string(constraint_column_names, ', ') AS column_name, constraint_text
+		FROM duckdb_constraints()
+		WHERE schema_name = 'main' AND constraint_type IN ('UNIQUE', 'CHECK')`
+	// ClickHouse keeps its catalog in system tables; the sorting key stands in for a
+	// primary key
+	clickhouseSchemaColumnsQuery = `
+		SELECT table, name, type, if(startsWith(type, 'Nullable('), 'YES', 'NO') AS nullable,
+		       if(is_in_primary_key, 'PRI', '') AS key, nullIf(default_expression, '') AS default,
+		       default_kind AS extra
+		FROM system.columns
+		WHERE database = currentDatabase()
+		ORDER BY table, position`
+	clickhouseSchemaPrimaryKeysQuery = `
+		SELECT table, name
+		FROM system.columns
+		WHERE database = currentDatabase() AND is_in_primary_key
+		ORDER BY table, position`
+
+	// The pragma table-valued functions need SQLite 3.16 or later
+	sqliteSchemaColumnsQuery = `
+		SELECT m.name, p.name, p.type, p."notnull", p.pk, p.dflt_value
+		FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table'
+		ORDER BY m.name, p.cid`
+	sqliteSchemaForeignKeysQuery = `
+		SELECT m.name, f.id, f."from", f."table", f."to", f.on_delete, f.on_update
+		FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table'
+		ORDER BY m.name, f.id, f.seq`
+)
+
+// LoadSchema describes every table with a handful of catalog queries, where
+// DescribeTable needs several per table. Tables are keyed by name.
+func LoadSchema(conn Connection, dbType DatabaseType) (map[string]*TableInfo, error) {
+	switch dbType {
+	case MySQL:
+		constraintsQuery := mysqlSchemaConstraintsQuery
+		if FlavorOf(conn) == FlavorTiDB {
+			constraintsQuery = tidbSchemaConstraintsQuery
+		}
+		return loadInformationSchema(conn, mysqlSchemaColumnsQuery, mysqlSchemaPrimaryKeysQuery,
+			constraintsQuery, mysqlSchemaForeignKeysQuery)
+	case PostgreSQL:
+		return loadInformationSchema(conn, postgresSchemaColumnsQuery, postgresSchemaPrimaryKeysQuery,
+			postgresSchemaConstraintsQuery, postgresSchemaForeignKeysQuery)
+	case SQLite:
+		return loadSQLiteSchema(conn)
+	case DuckDB:
+		return loadInformationSchema(conn, duckdbSchemaColumnsQuery, duckdbSchemaPrimaryKeysQuery,
+			duckdbSchemaConstraintsQuery, "")
+	case ClickHouse:
+		return loadInformationSchema(conn, clickhouseSchemaColumnsQuery, clickhouseSchemaPrimaryKeysQuery,
+			"", "")
+	default:
+		return nil, fmt.Errorf("unsupported database type: %v", dbType)
+	}
+}
+
+// loadInformationSchema runs the column, primary key, constraint and foreign key queries
+// of MySQL or PostgreSQL. Like DescribeTable, it leaves out constraints the server
+// cannot report, such as CHECK constraints before MySQL 8.0.16. An empty constraint or
+// foreign key query skips them, for databases such as ClickHouse that have none to report.
+func loadInformationSchema(conn Connection, columnsQuery, primaryKeysQuery, constraintsQuery, foreignKeysQuery string) (map[string]*TableInfo, error) {
+	rows, err := queryValues(conn, columnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load columns: %w", err)
+	}
+	tables := make(map[string]*TableInfo)
+	for _, row := range rows {
+		column := ColumnInfo{
+			Name:     row[1].String(),
+			Type:     row[2].String(),
+			Nullable: row[3].String() == "YES",
+			Key:      row[4].String(),
+			Extra:    row[6].String(),
+		}
+		if !row[5].IsNull() {
+			defaultStr := row[5].String()
+			column.Default = &defaultStr
+		}
+		table := schemaTable(tables, row[0].String())
+		table.Columns = append(table.Columns, column)
+	}
+
+	rows, err = queryValues(conn, primaryKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load primary keys: %w", err)
+	}
+	for _, row := range rows {
+		if table, ok := tables[row[0].String()]; ok {
+			table.PrimaryKeys = append(table.PrimaryKeys, row[1].String())
+		}
+	}
+
+	if constraintsQuery != "" {
+		if rows, err := queryValues(conn, constraintsQuery); err == nil {
+			for _, row := range rows {
+				if table, ok := tables[row[0].String()]; ok {
+					table.Constraints = append(table.Constraints, ConstraintInfo{
+						Name:   row[1].String(),
+						Type:   row[2].String(),
+						Column: row[3].String(),
+						Check:  row[4].String(),
+					})
+				}
+			}
+		}
+	}
+
+	if foreignKeysQuery == "" {
+		return tables, nil
+	}
+	rows, err = queryValues(conn, foreignKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load foreign keys: %w", err)
+	}
+	for _, row := range rows {
+		if table, ok := tables[row[0].String()]; ok {
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKeyInfo{
+				Name:             row[1].String(),
+				Column:           row[2].String(),
+				ReferencedTable:  row[3].String(),
+				ReferencedColumn: row[4].String(),
+				OnDelete:         row[5].String(),
+				OnUpdate:         row[6].String(),
+			})
+		}
+	}
+	return tables, nil
+}
+
+// loadSQLiteSchema reads every table's pragmas through a join with sqlite_master
+func loadSQLiteSchema(conn Connection) (map[string]*TableInfo, error) {
+	rows, err := queryValues(conn, sqliteSchemaColumnsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load columns: %w", err)
+	}
+	tables := make(map[string]*TableInfo)
+	primaryKeys := make(map[string]map[int]string)
+	for _, row := range rows {
+		name := row[0].String()
+		column := ColumnInfo{
+			Name:     row[1].String(),
+			Type:     row[2].String(),
+			Nullable: row[3].String() == "0",
+		}
+		if !row[5].IsNull() {
+			defaultStr := row[5].String()
+			column.Default = &defaultStr
+		}
+		// pk is the column's position in the primary key, 0 when it is not part of it
+		if position, _ := strconv.Atoi(row[4].String()); position > 0 {
+			column.Key = "PRI"
+			if primaryKeys[name] == nil {
+				primaryKeys[name] = make(map[int]string)
+			}
+			primaryKeys[name][position] = column.Name
+		}
+		table := schemaTable(tables, name)
+		table.Columns = append(table.Columns, column)
+	}
+	for name, positions := range primaryKeys {
+		for position := 1; position <= len(positions); position++ {
+			tables[name].PrimaryKeys = append(tables[name].PrimaryKeys, positions[position])
+		}
+	}
+
+	rows, err = queryValues(conn, sqliteSchemaForeignKeysQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load foreign keys: %w", err)
+	}
+	for _, row := range rows {
+		if table, ok := tables[row[0].String()]; ok {
+			table.ForeignKeys = append(table.ForeignKeys, ForeignKeyInfo{
+				Name:             "fk_" + row[1].String(),
+				Column:           row[2].String(),
+				ReferencedTable:  row[3].String(),
+				ReferencedColumn: row[4].String(),
+				OnDelete:         row[5].String(),
+				OnUpdate:         row[6].String(),
+			})
+		}
+	}
+	return tables, nil
+}
+
+func schemaTable(tables map[string]*TableInfo, name string) *TableInfo {
+	table, ok := tables[name]
+	if !ok {
+		table = &TableInfo{Name: name, Columns: make([]ColumnInfo, 0)}
+		tables[name] = table
+	}
+	return table
+}
+
+// queryValues is queryStrings keeping the values, so NULL can be told apart from an empty string
+func queryValues(conn Connection, query string) ([][]Value, error) {
+	result, err := conn.Execute(query)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	var rows [][]Value
+	err = result.ForEachRow(func(row []Value) error {
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
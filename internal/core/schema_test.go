@@ -0,0 +1,52 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadSchemaSQLite(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, status TEXT DEFAULT 'active')",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE, note TEXT)",
+		"CREATE TABLE order_items (order_id INTEGER REFERENCES orders(id), line INTEGER, sku TEXT, PRIMARY KEY (order_id, line))",
+	)
+
+	tables, err := LoadSchema(conn, SQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 3 {
+		t.Fatalf("Expected 3 tables, got %d", len(tables))
+	}
+
+	users := tables["users"]
+	if users.Columns[1].Nullable || !users.Columns[2].Nullable {
+		t.Errorf("Expected email NOT NULL and status nullable, got %+v", users.Columns)
+	}
+	if users.Columns[2].Default == nil || *users.Columns[2].Default != "'active'" || users.Columns[1].Default != nil {
+		t.Errorf("Expected only status to have a default, got %+v", users.Columns)
+	}
+
+	if pks := tables["order_items"].PrimaryKeys; !reflect.DeepEqual(pks, []string{"order_id", "line"}) {
+		t.Errorf("Expected the composite key in key order, got %v", pks)
+	}
+
+	fks := tables["orders"].ForeignKeys
+	if len(fks) != 1 || fks[0].Column != "user_id" || fks[0].ReferencedTable != "users" ||
+		fks[0].ReferencedColumn != "id" || fks[0].OnDelete != "CASCADE" || fks[0].Name != "fk_0" {
+		t.Errorf("Expected orders.user_id to reference users.id on delete cascade, got %+v", fks)
+	}
+
+	// The bulk loader agrees with describing each table
+	for name, table := range tables {
+		described, err := conn.DescribeTable(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(table.Columns, described.Columns) || !reflect.DeepEqual(table.ForeignKeys, described.ForeignKeys) {
+			t.Errorf("%s: LoadSchema %+v differs from DescribeTable %+v", name, table, described)
+		}
+	}
+}
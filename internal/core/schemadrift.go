@@ -0,0 +1,265 @@
+package core
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Catalog queries listing every index with its table, name and definition
+const (
+	mysqlIndexesQuery = `
+		SELECT TABLE_NAME, INDEX_NAME,
+		       CONCAT(IF(NON_UNIQUE = 0, 'UNIQUE ', ''), INDEX_TYPE, ' (',
+		              GROUP_CONCAT(COLUMN_NAME ORDER BY SEQ_IN_INDEX SEPARATOR ', '), ')')
+		FROM INFORMATION_SCHEMA.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE()
+		GROUP BY TABLE_NAME, INDEX_NAME, NON_UNIQUE, INDEX_TYPE
+		ORDER BY TABLE_NAME, INDEX_NAME`
+	postgresIndexesListQuery = `
+		SELECT tablename, indexname, indexdef
+		FROM pg_indexes
+		WHERE schemaname = 'public'
+		ORDER BY tablename, indexname`
+	// Indexes SQLite creates for UNIQUE and PRIMARY KEY constraints have no statement
+	sqliteIndexesQuery = `
+		SELECT tbl_name, name, COALESCE(sql, 'automatic')
+		FROM sqlite_master
+		WHERE type = 'index'
+		ORDER BY tbl_name, name`
+	duckdbIndexesQuery = `
+		SELECT table_name, index_name, COALESCE(sql, '') AS sql
+		FROM duckdb_indexes()
+		WHERE schema_name = 'main'
+		ORDER BY table_name, index_name`
+	clickhouseIndexesQuery = `
+		SELECT table, name, type || ' ' || expr
+		FROM system.data_skipping_indices
+		WHERE database = currentDatabase()
+		ORDER BY table, name`
+)
+
+// IndexInfo is an index of a table, with its definition as the server reports it
+type IndexInfo struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// SchemaSnapshot is the full schema of a connection at one point in time
+type SchemaSnapshot struct {
+	Version    int                    `json:"version"`
+	Connection string                 `json:"connection"`
+	Taken      time.Time              `json:"taken"`
+	Tables     map[string]*TableInfo  `json:"tables"`
+	Indexes    map[string][]IndexInfo `json:"indexes,omitempty"` // Keyed by table
+}
+
+// TakeSchemaSnapshot reads the tables, columns, keys, constraints and indexes of a
+// connection. Indexes are left out when the server cannot list them.
+func TakeSchemaSnapshot(conn Connection, dbType DatabaseType) (*SchemaSnapshot, error) {
+	tables, err := LoadSchema(conn, dbType)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &SchemaSnapshot{Taken: time.Now(), Tables: tables}
+	if indexes, err := LoadIndexes(conn, dbType); err == nil {
+		snapshot.Indexes = indexes
+	}
+	return snapshot, nil
+}
+
+// LoadIndexes lists the indexes of every table, keyed by table
+func LoadIndexes(conn Connection, dbType DatabaseType) (map[string][]IndexInfo, error) {
+	var query string
+	switch dbType {
+	case MySQL:
+		query = mysqlIndexesQuery
+	case PostgreSQL:
+		query = postgresIndexesListQuery
+	case SQLite:
+		query = sqliteIndexesQuery
+	case DuckDB:
+		query = duckdbIndexesQuery
+	case ClickHouse:
+		query = clickhouseIndexesQuery
+	default:
+		return nil, fmt.Errorf("unsupported database type: %v", dbType)
+	}
+	rows, err := queryStrings(conn, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexes: %w", err)
+	}
+	indexes := make(map[string][]IndexInfo)
+	for _, row := range rows {
+		indexes[row[0]] = append(indexes[row[0]], IndexInfo{Name: row[1], Definition: row[2]})
+	}
+	return indexes, nil
+}
+
+// Kinds of schema change
+const (
+	SchemaAdded   = "added"
+	SchemaRemoved = "removed"
+	SchemaChanged = "changed"
+)
+
+// SchemaChange is one difference between two schemas. Object is table, column, primary
+// key, foreign key, constraint or index; Old and New describe it before and after.
+type SchemaChange struct {
+	Kind   string
+	Object string
+	Table  string
+	Name   string
+	Old    string
+	New    string
+}
+
+// DiffSchemas lists what changed from one snapshot to the next, by table
+func DiffSchemas(before, after *SchemaSnapshot) []SchemaChange {
+	var changes []SchemaChange
+	for _, name := range sortedUnion(before.Tables, after.Tables) {
+		old, oldOK := before.Tables[name]
+		cur, curOK := after.Tables[name]
+		switch {
+		case !oldOK:
+			changes = append(changes, SchemaChange{Kind: SchemaAdded, Object: "table", Table: name, New: fmt.Sprintf("%d columns", len(cur.Columns))})
+			continue
+		case !curOK:
+			changes = append(changes, SchemaChange{Kind: SchemaRemoved, Object: "table", Table: name, Old: fmt.Sprintf("%d columns", len(old.Columns))})
+			continue
+		}
+
+		changes = append(changes, diffDefinitions(name, "column", columnDefinitions(old), columnDefinitions(cur))...)
+		if a, b := strings.Join(old.PrimaryKeys, ", "), strings.Join(cur.PrimaryKeys, ", "); a != b {
+			changes = append(changes, SchemaChange{Kind: changeKind(a, b), Object: "primary key", Table: name, Old: a, New: b})
+		}
+		changes = append(changes, diffDefinitions(name, "foreign key", foreignKeyDefinitions(old), foreignKeyDefinitions(cur))...)
+		changes = append(changes, diffDefinitions(name, "constraint", constraintDefinitions(old), constraintDefinitions(cur))...)
+		// Snapshots taken where indexes could not be listed say nothing about them
+		if before.Indexes != nil && after.Indexes != nil {
+			changes = append(changes, diffDefinitions(name, "index", indexDefinitions(before.Indexes[name]), indexDefinitions(after.Indexes[name]))...)
+		}
+	}
+	return changes
+}
+
+// changeKind tells an added or removed definition from a changed one
+func changeKind(old, cur string) string {
+	switch {
+	case old == "":
+		return SchemaAdded
+	case cur == "":
+		return SchemaRemoved
+	default:
+		return SchemaChanged
+	}
+}
+
+// diffDefinitions compares named definitions of one kind of object in a table
+func diffDefinitions(table, object string, before, after map[string]string) []SchemaChange {
+	var changes []SchemaChange
+	for _, name := range sortedUnion(before, after) {
+		old, cur := before[name], after[name]
+		if old != cur {
+			changes = append(changes, SchemaChange{Kind: changeKind(old, cur), Object: object, Table: table, Name: name, Old: old, New: cur})
+		}
+	}
+	return changes
+}
+
+func sortedUnion[V any](a, b map[string]V) []string {
+	keys := slices.Collect(maps.Keys(a))
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func columnDefinitions(table *TableInfo) map[string]string {
+	definitions := make(map[string]string, len(table.Columns))
+	for _, col := range table.Columns {
+		definition := col.Type
+		if !col.Nullable {
+			definition += " NOT NULL"
+		}
+		if col.Default != nil {
+			definition += " DEFAULT " + *col.Default
+		}
+		if col.Extra != "" {
+			definition += " " + col.Extra
+		}
+		definitions[col.Name] = definition
+	}
+	return definitions
+}
+
+// foreignKeyDefinitions gathers the columns of each foreign key, which the catalog
+// reports one row per column. SQLite numbers its keys, as fk_0, fk_1, and renumbers them
+// when one is added, so those are known by their first column and referenced table.
+func foreignKeyDefinitions(table *TableInfo) map[string]string {
+	var order []string
+	keys := map[string][]ForeignKeyInfo{}
+	for _, fk := range table.ForeignKeys {
+		if _, ok := keys[fk.Name]; !ok {
+			order = append(order, fk.Name)
+		}
+		keys[fk.Name] = append(keys[fk.Name], fk)
+	}
+	definitions := make(map[string]string, len(order))
+	for _, name := range order {
+		var columns, referenced []string
+		for _, fk := range keys[name] {
+			columns = append(columns, fk.Column)
+			referenced = append(referenced, fk.ReferencedColumn)
+		}
+		first := keys[name][0]
+		if number, ok := strings.CutPrefix(name, "fk_"); name == "" || ok && strings.Trim(number, "0123456789") == "" {
+			name = first.Column + " → " + first.ReferencedTable
+		}
+		definitions[name] = fmt.Sprintf("(%s) REFERENCES %s(%s) ON DELETE %s ON UPDATE %s", strings.Join(columns, ", "),
+			first.ReferencedTable, strings.Join(referenced, ", "), first.OnDelete, first.OnUpdate)
+	}
+	return definitions
+}
+
+// constraintDefinitions gathers the columns of each UNIQUE and CHECK constraint
+func constraintDefinitions(table *TableInfo) map[string]string {
+	var order []string
+	grouped := map[string][]ConstraintInfo{}
+	for _, c := range table.Constraints {
+		if _, ok := grouped[c.Name]; !ok {
+			order = append(order, c.Name)
+		}
+		grouped[c.Name] = append(grouped[c.Name], c)
+	}
+	definitions := make(map[string]string, len(order))
+	for _, name := range order {
+		first := grouped[name][0]
+		if first.Check != "" {
+			definitions[name] = first.Type + " " + first.Check
+			continue
+		}
+		var columns []string
+		for _, c := range grouped[name] {
+			if c.Column != "" && !slices.Contains(columns, c.Column) {
+				columns = append(columns, c.Column)
+			}
+		}
+		slices.Sort(columns)
+		definitions[name] = first.Type + " (" + strings.Join(columns, ", ") + ")"
+	}
+	return definitions
+}
+
+func indexDefinitions(indexes []IndexInfo) map[string]string {
+	definitions := make(map[string]string, len(indexes))
+	for _, index := range indexes {
+		definitions[index.Name] = index.Definition
+	}
+	return definitions
+}
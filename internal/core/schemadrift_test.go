@@ -0,0 +1,62 @@
+package core
+
+import "testing"
+
+func TestDiffSchemas(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email VARCHAR(100), note TEXT)",
+		"CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), total REAL)",
+		"CREATE TABLE legacy (id INTEGER)",
+	)
+	before, err := TakeSchemaSnapshot(conn, SQLite)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(before.Tables) != 3 || before.Indexes == nil {
+		t.Fatalf("Unexpected snapshot %+v", before)
+	}
+	if changes := DiffSchemas(before, before); len(changes) != 0 {
+		t.Errorf("Expected no changes against itself, got %+v", changes)
+	}
+
+	mustExec(t, conn,
+		"DROP TABLE legacy",
+		"CREATE TABLE audit (id INTEGER)",
+		"ALTER TABLE users DROP COLUMN note",
+		"ALTER TABLE users ADD COLUMN phone TEXT NOT NULL DEFAULT ''",
+		"CREATE INDEX idx_orders_user ON orders (user_id)",
+	)
+	after, err := TakeSchemaSnapshot(conn, SQLite)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	expected := []SchemaChange{
+		{Kind: SchemaAdded, Object: "table", Table: "audit", New: "1 columns"},
+		{Kind: SchemaRemoved, Object: "table", Table: "legacy", Old: "1 columns"},
+		{Kind: SchemaAdded, Object: "index", Table: "orders", Name: "idx_orders_user", New: "CREATE INDEX idx_orders_user ON orders (user_id)"},
+		{Kind: SchemaRemoved, Object: "column", Table: "users", Name: "note", Old: "TEXT"},
+		{Kind: SchemaAdded, Object: "column", Table: "users", Name: "phone", New: "TEXT NOT NULL DEFAULT ''"},
+	}
+	changes := DiffSchemas(before, after)
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for i, change := range changes {
+		if change != expected[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, expected[i], change)
+		}
+	}
+
+	// A changed foreign key, primary key or constraint is reported on its own
+	after.Tables["orders"].ForeignKeys[0].OnDelete = "CASCADE"
+	after.Tables["orders"].PrimaryKeys = nil
+	after.Tables["orders"].Constraints = []ConstraintInfo{{Name: "orders_total_key", Type: "UNIQUE", Column: "total"}}
+	changes = DiffSchemas(before, after)[2:5]
+	if changes[0].Object != "primary key" || changes[0].Kind != SchemaRemoved ||
+		changes[1].Object != "foreign key" || changes[1].Name != "user_id → users" || changes[1].Kind != SchemaChanged ||
+		changes[2].Object != "constraint" || changes[2].New != "UNIQUE (total)" {
+		t.Errorf("Unexpected key and constraint changes %+v", changes)
+	}
+}
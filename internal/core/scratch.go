@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScratchTableQuery returns the statement that materializes a query into a temporary
+// table. Temporary tables live on one server session, so the caller should pin the
+// connection with PinSession first.
+func ScratchTableQuery(dbType DatabaseType, name, query string) (string, error) {
+	if err := ValidateIdentifier(name); err != nil {
+		return "", err
+	}
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	switch dbType {
+	case MySQL:
+		return fmt.Sprintf("CREATE TEMPORARY TABLE %s AS %s", QuoteIdentifier(dbType, name), query), nil
+	case PostgreSQL, SQLite:
+		return fmt.Sprintf("CREATE TEMP TABLE %s AS %s", QuoteIdentifier(dbType, name), query), nil
+	default:
+		return "", ErrUnsupportedDatabase
+	}
+}
+
+// DropScratchTableQuery returns the statement that drops a temporary table made by
+// ScratchTableQuery
+func DropScratchTableQuery(dbType DatabaseType, name string) string {
+	if dbType == MySQL {
+		return "DROP TEMPORARY TABLE IF EXISTS " + QuoteIdentifier(dbType, name)
+	}
+	return "DROP TABLE IF EXISTS " + QuoteIdentifier(dbType, name)
+}
+
+// pinSessionTimeout is how long PinSession waits for busy connections to be returned
+const pinSessionTimeout = 10 * time.Second
+
+// PinSession keeps every statement on one server session while pin is set, so
+// temporary tables stay visible: the pool is limited to a single connection and reads
+// are no longer routed to replicas
+func PinSession(conn Connection, pin bool) error {
+	c, ok := conn.(*connection)
+	if !ok {
+		return ErrUnsupportedDatabase
+	}
+	if !pin {
+		c.pinned.Store(false)
+		c.db.SetMaxOpenConns(0)
+		return nil
+	}
+
+	// Idle connections are closed straight away, but one in use is only closed when it
+	// is returned, and that could be the one a temporary table was just made on
+	c.db.SetMaxOpenConns(1)
+	deadline := time.Now().Add(pinSessionTimeout)
+	for c.db.Stats().OpenConnections > 1 {
+		if time.Now().After(deadline) {
+			c.db.SetMaxOpenConns(0)
+			return fmt.Errorf("timed out waiting for other queries on the connection to finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.pinned.Store(true)
+	return nil
+}
@@ -0,0 +1,54 @@
+package core
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScratchTable(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE orders (id INTEGER, status TEXT)",
+		"INSERT INTO orders VALUES (1, 'new'), (2, 'paid'), (3, 'paid')",
+	)
+
+	query, err := ScratchTableQuery(SQLite, "scratch_1", "SELECT * FROM orders WHERE status = 'paid';")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `CREATE TEMP TABLE "scratch_1" AS SELECT * FROM orders WHERE status = 'paid'`; query != want {
+		t.Errorf("ScratchTableQuery() = %q, want %q", query, want)
+	}
+
+	if err := PinSession(conn, true); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, conn, query)
+	// Each query runs on the pinned session, so the temporary table stays visible
+	for range 3 {
+		rows, err := queryStrings(conn, "SELECT COUNT(*) FROM scratch_1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rows[0][0] != "2" {
+			t.Errorf("Expected 2 rows in the scratch table, got %s", rows[0][0])
+		}
+	}
+	mustExec(t, conn, DropScratchTableQuery(SQLite, "scratch_1"))
+	if _, err := conn.Execute("SELECT * FROM scratch_1"); err == nil {
+		t.Error("Expected the scratch table to be dropped")
+	}
+	if err := PinSession(conn, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := DropScratchTableQuery(MySQL, "scratch_2"); got != "DROP TEMPORARY TABLE IF EXISTS `scratch_2`" {
+		t.Errorf("Unexpected MySQL drop statement %q", got)
+	}
+	if _, err := ScratchTableQuery(DuckDB, "scratch_1", "SELECT 1"); !errors.Is(err, ErrUnsupportedDatabase) {
+		t.Errorf("Expected ErrUnsupportedDatabase for DuckDB, got %v", err)
+	}
+	if _, err := ScratchTableQuery(PostgreSQL, " ", "SELECT 1"); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Expected an empty table name to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// secretCommandTimeout bounds a command that prints a secret, which may wait for the
+// user to unlock a password manager or sign in
+const secretCommandTimeout = 2 * time.Minute
+
+// RunSecretCommand runs a command through the shell and returns the first line it
+// prints, the way pass, the 1Password CLI and gcloud print secrets
+func RunSecretCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	// Password managers may ask to be unlocked on the terminal
+	cmd.Stdin = os.Stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	secret, _, _ := strings.Cut(string(out), "\n")
+	return strings.TrimSuffix(secret, "\r"), nil
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SensitiveFile is the per-connection list of sensitive columns in the connection's
+// session directory
+const SensitiveFile = "sensitive.yaml"
+
+// MaskedValue replaces the values of sensitive columns
+const MaskedValue = "******"
+
+// SensitiveColumns are the columns of a connection holding personal or otherwise
+// sensitive data. Each is table.column, or a bare column name for that column in any
+// table.
+type SensitiveColumns struct {
+	Columns []string `yaml:"columns"`
+}
+
+// LoadSensitiveColumns reads a sensitive column file. A missing file tags no columns.
+func LoadSensitiveColumns(path string) (*SensitiveColumns, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &SensitiveColumns{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sensitive SensitiveColumns
+	if err := yaml.Unmarshal(data, &sensitive); err != nil {
+		return nil, err
+	}
+	for i, column := range sensitive.Columns {
+		if ref := parseColumnRef(column); ref.Column == "" || ref.Column == "*" {
+			return nil, fmt.Errorf("entry %d is not a column: %q", i+1, column)
+		}
+	}
+	return &sensitive, nil
+}
+
+// SaveSensitiveColumns writes a sensitive column file, creating its directory if needed
+func SaveSensitiveColumns(path string, sensitive *SensitiveColumns) error {
+	data, err := yaml.Marshal(sensitive)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Add tags a column, reporting false when it was already tagged
+func (s *SensitiveColumns) Add(column string) bool {
+	ref := parseColumnRef(column)
+	if slices.ContainsFunc(s.Columns, func(c string) bool { return parseColumnRef(c) == ref }) {
+		return false
+	}
+	s.Columns = append(s.Columns, ref.String())
+	return true
+}
+
+// Remove untags a column, reporting false when it was not tagged
+func (s *SensitiveColumns) Remove(column string) bool {
+	ref := parseColumnRef(column)
+	n := len(s.Columns)
+	s.Columns = slices.DeleteFunc(s.Columns, func(c string) bool { return parseColumnRef(c) == ref })
+	return len(s.Columns) < n
+}
+
+// Tagged reports whether a table's column is sensitive. An empty table, for a column
+// whose table is unknown, matches the column tagged in any table.
+func (s *SensitiveColumns) Tagged(table, column string) bool {
+	if s == nil {
+		return false
+	}
+	ref := ColumnRef{Table: table, Column: column}
+	for _, c := range s.Columns {
+		tag := parseColumnRef(c)
+		if !strings.EqualFold(tag.Column, column) {
+			continue
+		}
+		if tag.Table == "" || table == "" || ref.matches(tag, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mask returns a formatter hiding the result columns of a query that read a sensitive
+// column, following aliases and expressions through the query's lineage. A result
+// column the lineage cannot account for is masked when its name is tagged in any table.
+// It returns nil when no column needs masking.
+func (s *SensitiveColumns) Mask(query string, columns []Column) *MaskFormatter {
+	if s == nil || len(s.Columns) == 0 {
+		return nil
+	}
+
+	sources := map[string][]ColumnRef{}
+	var starTables []string
+	for _, edge := range AnalyzeLineage(query) {
+		// INSERT ... SELECT and CREATE ... AS SELECT write their columns rather than return them
+		if edge.Target.Table != "" {
+			continue
+		}
+		if edge.Target.Column == "*" {
+			for _, source := range edge.Sources {
+				starTables = append(starTables, source.Table)
+			}
+			continue
+		}
+		name := strings.ToLower(edge.Target.Column)
+		sources[name] = append(sources[name], edge.Sources...)
+	}
+
+	masked := map[string]bool{}
+	for _, col := range columns {
+		name := strings.ToLower(col.Name)
+		refs, known := sources[name]
+		sensitive := slices.ContainsFunc(refs, func(ref ColumnRef) bool {
+			return ref.Column != "*" && s.Tagged(ref.Table, ref.Column)
+		})
+		sensitive = sensitive || slices.ContainsFunc(starTables, func(table string) bool {
+			return s.Tagged(table, col.Name)
+		})
+		if !known && len(starTables) == 0 {
+			sensitive = sensitive || s.Tagged("", col.Name)
+		}
+		if sensitive {
+			masked[name] = true
+		}
+	}
+	if len(masked) == 0 {
+		return nil
+	}
+	return &MaskFormatter{Columns: masked}
+}
+
+// MaskFormatter replaces the values of sensitive result columns with MaskedValue
+type MaskFormatter struct {
+	Columns map[string]bool // Lower-case names of the masked columns
+}
+
+// Masked reports whether a result column is masked
+func (f *MaskFormatter) Masked(name string) bool {
+	return f != nil && f.Columns[strings.ToLower(name)]
+}
+
+func (f *MaskFormatter) Format(col Column, val Value) (Value, bool) {
+	if !f.Masked(col.Name) {
+		return nil, false
+	}
+	return StringValue{Value: MaskedValue}, true
+}
+
+func (f *MaskFormatter) Annotate(col Column) string {
+	if f.Masked(col.Name) {
+		return "masked"
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSensitiveColumnsMask(t *testing.T) {
+	sensitive := &SensitiveColumns{Columns: []string{"users.email", "ssn"}}
+	columns := func(names ...string) []Column {
+		var cols []Column
+		for _, name := range names {
+			cols = append(cols, Column{Name: name})
+		}
+		return cols
+	}
+
+	testCases := []struct {
+		query    string
+		columns  []Column
+		expected []string
+	}{
+		{"SELECT * FROM users", columns("id", "email", "ssn"), []string{"email", "ssn"}},
+		{"SELECT * FROM public.users", columns("id", "email"), []string{"email"}},
+		{"SELECT * FROM orders", columns("id", "email"), nil},
+		{"SELECT u.email AS contact, o.total FROM users u JOIN orders o ON o.user_id = u.id", columns("contact", "total"), []string{"contact"}},
+		{"SELECT lower(email) FROM users", columns("lower"), []string{"lower"}},
+		{"SELECT o.email FROM orders o", columns("email"), nil},
+		{"SHOW TABLES", columns("email"), []string{"email"}},
+		{"SELECT id FROM users", columns("id"), nil},
+	}
+	for _, tc := range testCases {
+		mask := sensitive.Mask(tc.query, tc.columns)
+		var masked []string
+		for _, col := range tc.columns {
+			if mask.Masked(col.Name) {
+				masked = append(masked, col.Name)
+			}
+		}
+		if !slices.Equal(masked, tc.expected) {
+			t.Errorf("%s: expected %v masked, got %v", tc.query, tc.expected, masked)
+		}
+	}
+
+	mask := sensitive.Mask("SELECT email FROM users", columns("email"))
+	if val, ok := mask.Format(Column{Name: "Email"}, StringValue{Value: "ann@example.com"}); !ok || val.String() != MaskedValue {
+		t.Errorf("Expected the email to be masked, got %v", val)
+	}
+	if mask.Annotate(Column{Name: "email"}) != "masked" {
+		t.Error("Expected the masked column to be annotated")
+	}
+}
+
+func TestSensitiveColumnsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prod", SensitiveFile)
+	sensitive, err := LoadSensitiveColumns(path)
+	if err != nil || len(sensitive.Columns) != 0 {
+		t.Fatalf("Expected no sensitive columns, got %+v, %v", sensitive, err)
+	}
+	if !sensitive.Add("Users.Email") || sensitive.Add("users.email") || !sensitive.Add("ssn") {
+		t.Fatalf("Unexpected tags %v", sensitive.Columns)
+	}
+	if err := SaveSensitiveColumns(path, sensitive); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadSensitiveColumns(path)
+	if err != nil || !slices.Equal(loaded.Columns, []string{"users.email", "ssn"}) {
+		t.Fatalf("Unexpected loaded columns %+v, %v", loaded, err)
+	}
+	if !loaded.Tagged("users", "email") || !loaded.Tagged("customers", "ssn") || loaded.Tagged("orders", "email") {
+		t.Error("Unexpected Tagged results")
+	}
+	if !loaded.Remove("users.email") || loaded.Remove("users.email") || loaded.Tagged("users", "email") {
+		t.Errorf("Unexpected columns after removal %v", loaded.Columns)
+	}
+}
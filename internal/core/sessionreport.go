@@ -0,0 +1,208 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"sqlterm/internal/i18n"
+)
+
+// sessionReportRows is how many rows of each result a session report shows
+const sessionReportRows = 10
+
+// sessionReportAnswerLength caps each AI answer in a session report, in characters
+const sessionReportAnswerLength = 2000
+
+// SessionReport is what happened on a connection since it was opened, for /export-session
+type SessionReport struct {
+	Connection string
+	Database   string
+	Started    time.Time
+	Queries    []ReportQuery
+	Exchanges  []ReportExchange
+}
+
+// ReportQuery is one executed statement with the first rows of its result
+type ReportQuery struct {
+	Query     string
+	StartedAt time.Time
+	Duration  time.Duration
+	Rows      int
+	Error     string
+	Columns   []string
+	Sample    [][]string
+}
+
+// ReportExchange is one question to the AI and its answer
+type ReportExchange struct {
+	Timestamp time.Time
+	Question  string
+	Answer    string
+	Model     string
+	Latency   time.Duration
+}
+
+// reportItem is a query or an AI exchange on the report's timeline
+type reportItem struct {
+	at       time.Time
+	query    *ReportQuery
+	exchange *ReportExchange
+}
+
+// FormatSessionReport renders a session as one markdown document: a summary, a table of
+// contents and then queries and AI exchanges in the order they happened
+func FormatSessionReport(report *SessionReport, i18nMgr *i18n.Manager) string {
+	var items []reportItem
+	failed := 0
+	var queryTime time.Duration
+	for i := range report.Queries {
+		query := &report.Queries[i]
+		items = append(items, reportItem{at: query.StartedAt, query: query})
+		queryTime += query.Duration
+		if query.Error != "" {
+			failed++
+		}
+	}
+	for i := range report.Exchanges {
+		items = append(items, reportItem{at: report.Exchanges[i].Timestamp, exchange: &report.Exchanges[i]})
+	}
+	slices.SortStableFunc(items, func(a, b reportItem) int { return a.at.Compare(b.at) })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s - %s\n\n", i18nMgr.Get("session_report_title"), report.Connection)
+	fmt.Fprintf(&sb, "**%s:** %s  \n", i18nMgr.Get("connection_header"), report.Connection)
+	if report.Database != "" {
+		fmt.Fprintf(&sb, "**%s:** %s  \n", i18nMgr.Get("field_database"), report.Database)
+	}
+	fmt.Fprintf(&sb, "**%s:** %s  \n", i18nMgr.Get("session_report_started"), report.Started.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&sb, "**%s:** %s\n\n", i18nMgr.Get("session_report_exported"), time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&sb, "%s\n\n", i18nMgr.GetWithArgs("session_report_summary",
+		len(report.Queries), failed, queryTime.Round(time.Millisecond), len(report.Exchanges)))
+
+	if len(items) == 0 {
+		fmt.Fprintf(&sb, "%s\n", i18nMgr.Get("session_report_empty"))
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "## %s\n\n", i18nMgr.Get("session_report_contents"))
+	for i, item := range items {
+		var status, title string
+		switch {
+		case item.exchange != nil:
+			status, title = "💬", reportTitle(item.exchange.Question)
+		case item.query.Error != "":
+			status, title = "❌", "`"+reportTitle(item.query.Query)+"`"
+		default:
+			status, title = "✅", "`"+reportTitle(item.query.Query)+"`"
+		}
+		fmt.Fprintf(&sb, "%d. %s [%s](#item-%d) - %s\n", i+1, status, title, i+1, item.at.Format("15:04:05"))
+	}
+
+	for i, item := range items {
+		fmt.Fprintf(&sb, "\n<a id=\"item-%d\"></a>\n\n", i+1)
+		if item.query != nil {
+			writeReportQuery(&sb, i+1, item.query, i18nMgr)
+		} else {
+			writeReportExchange(&sb, i+1, item.exchange, i18nMgr)
+		}
+	}
+	return sb.String()
+}
+
+func writeReportQuery(sb *strings.Builder, n int, query *ReportQuery, i18nMgr *i18n.Manager) {
+	fmt.Fprintf(sb, "## %d. %s - %s\n\n", n, i18nMgr.Get("session_report_query"), query.StartedAt.Format("15:04:05"))
+	fmt.Fprintf(sb, "```sql\n%s\n```\n\n", strings.TrimSpace(query.Query))
+	if query.Error != "" {
+		fmt.Fprintf(sb, "❌ %s\n\n*⏱ %v*\n", query.Error, query.Duration.Round(time.Millisecond))
+		return
+	}
+	fmt.Fprintf(sb, "*⏱ %v · %s*\n", query.Duration.Round(time.Millisecond), i18nMgr.GetWithArgs("session_report_rows", query.Rows))
+	if len(query.Columns) == 0 || len(query.Sample) == 0 {
+		return
+	}
+
+	sb.WriteString("\n|")
+	for _, column := range query.Columns {
+		fmt.Fprintf(sb, " %s |", markdownCell(column))
+	}
+	sb.WriteString("\n|" + strings.Repeat("---|", len(query.Columns)) + "\n")
+	rows := query.Sample[:min(len(query.Sample), sessionReportRows)]
+	for _, row := range rows {
+		sb.WriteString("|")
+		for _, cell := range row {
+			fmt.Fprintf(sb, " %s |", markdownCell(cell))
+		}
+		sb.WriteString("\n")
+	}
+	if query.Rows > len(rows) {
+		fmt.Fprintf(sb, "\n*%s*\n", i18nMgr.GetWithArgs("session_report_rows_shown", len(rows), query.Rows))
+	}
+}
+
+func writeReportExchange(sb *strings.Builder, n int, exchange *ReportExchange, i18nMgr *i18n.Manager) {
+	fmt.Fprintf(sb, "## %d. %s - %s\n\n", n, i18nMgr.Get("session_report_ai"), exchange.Timestamp.Format("15:04:05"))
+	for _, line := range strings.Split(strings.TrimSpace(exchange.Question), "\n") {
+		fmt.Fprintf(sb, "> %s\n", line)
+	}
+	answer := strings.TrimSpace(exchange.Answer)
+	if utf8.RuneCountInString(answer) > sessionReportAnswerLength {
+		answer = string([]rune(answer)[:sessionReportAnswerLength]) + " …"
+	}
+	fmt.Fprintf(sb, "\n%s\n\n*%s · ⏱ %v*\n", answer, exchange.Model, exchange.Latency.Round(time.Millisecond))
+}
+
+// reportTitle shortens a query or question to one line for the table of contents
+func reportTitle(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) > 80 {
+		text = string([]rune(text)[:77]) + "..."
+	}
+	return strings.NewReplacer("`", "", "[", "(", "]", ")").Replace(text)
+}
+
+// MarkdownToHTML converts markdown to a standalone HTML page with a light stylesheet, so
+// a report can be opened in a browser or shared as one file
+func MarkdownToHTML(title, markdown string) (string, error) {
+	converter := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		// Reports link their table of contents to <a id> anchors
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+	var body bytes.Buffer
+	if err := converter.Convert([]byte(markdown), &body); err != nil {
+		return "", err
+	}
+	// Query results and AI answers end up in the page, so only safe markup is kept
+	sanitized := bluemonday.UGCPolicy().SanitizeBytes(body.Bytes())
+	return fmt.Sprintf(htmlPage, html.EscapeString(title), sanitized), nil
+}
+
+const htmlPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; line-height: 1.5; color: #24292f; }
+pre { background: #f6f8fa; padding: 1em; overflow-x: auto; border-radius: 6px; }
+code { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 0.9em; }
+table { border-collapse: collapse; margin: 1em 0; display: block; overflow-x: auto; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: left; }
+th { background: #f6f8fa; }
+blockquote { margin: 0; padding: 0 1em; color: #57606a; border-left: 4px solid #d0d7de; }
+h2 { border-bottom: 1px solid #d8dee4; padding-bottom: 0.3em; }
+</style>
+</head>
+<body>
+%s</body>
+</html>
+`
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"sqlterm/internal/i18n"
+)
+
+func TestFormatSessionReport(t *testing.T) {
+	i18nMgr, err := i18n.NewManager("en_au")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := time.Date(2024, 5, 1, 9, 0, 0, 0, time.Local)
+	report := &SessionReport{
+		Connection: "prod",
+		Database:   "shop",
+		Started:    started,
+		Queries: []ReportQuery{
+			{
+				Query: "SELECT id, note FROM orders", StartedAt: started.Add(time.Minute), Duration: 12 * time.Millisecond,
+				Rows: 12, Columns: []string{"id", "note"}, Sample: [][]string{{"1", "a|b"}, {"2", "NULL"}},
+			},
+			{Query: "SELECT * FROM missing", StartedAt: started.Add(3 * time.Minute), Error: "no such table: missing"},
+		},
+		Exchanges: []ReportExchange{
+			{Timestamp: started.Add(2 * time.Minute), Question: "Which orders are late?", Answer: "Use `shipped_at`.", Model: "openai/gpt-4o"},
+		},
+	}
+
+	md := FormatSessionReport(report, i18nMgr)
+	for _, want := range []string{
+		"# Session Report - prod\n",
+		"Queries: 2 (1 failed), taking 12ms in total · AI exchanges: 1",
+		"1. ✅ [`SELECT id, note FROM orders`](#item-1) - 09:01:00\n",
+		"2. 💬 [Which orders are late?](#item-2) - 09:02:00\n",
+		"3. ❌ [`SELECT * FROM missing`](#item-3) - 09:03:00\n",
+		"<a id=\"item-1\"></a>\n\n## 1. Query - 09:01:00\n\n```sql\nSELECT id, note FROM orders\n```",
+		"| 1 | a\\|b |",
+		"*First 2 of 12 rows*",
+		"> Which orders are late?\n",
+		"❌ no such table: missing",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected report to contain %q, got\n%s", want, md)
+		}
+	}
+
+	empty := FormatSessionReport(&SessionReport{Connection: "prod", Started: started}, i18nMgr)
+	if !strings.Contains(empty, "Nothing has run in this session yet.") || strings.Contains(empty, "## Contents") {
+		t.Errorf("Unexpected report for an empty session:\n%s", empty)
+	}
+}
+
+func TestMarkdownToHTML(t *testing.T) {
+	page, err := MarkdownToHTML("Report <1>", "<a id=\"item-1\"></a>\n\n## Title\n\n| a |\n|---|\n| <script>alert(1)</script> |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<title>Report &lt;1&gt;</title>", `<a id="item-1">`, "<h2>Title</h2>", "<table>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected page to contain %q, got\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "<script>") {
+		t.Errorf("Expected scripts to be removed, got\n%s", page)
+	}
+}
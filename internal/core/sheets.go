@@ -0,0 +1,338 @@
+package core
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Google endpoints, variables so tests can point them at a local server
+var (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	sheetsAPIURL   = "https://sheets.googleapis.com/v4/spreadsheets"
+)
+
+const (
+	sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+	// sheetsBatchRows is how many rows are sent to the Sheets API per request
+	sheetsBatchRows = 5000
+	// sheetsAuthTimeout bounds how long authorization waits for the browser sign in
+	sheetsAuthTimeout = 5 * time.Minute
+)
+
+var sheetsHTTPClient = &http.Client{Timeout: time.Minute}
+
+// ErrSheetsNotAuthorized is returned when a Google Sheets export has no refresh token
+var ErrSheetsNotAuthorized = errors.New("google sheets is not authorized")
+
+// SheetsCredentials is the OAuth client and refresh token used to write to Google Sheets
+type SheetsCredentials struct {
+	ClientID     string
+	ClientSecret string
+	RefreshToken string
+}
+
+// IsSheetsTarget reports whether an export target is a Google Sheet, written
+// sheets://<spreadsheet-id>/<tab>
+func IsSheetsTarget(target string) bool {
+	return len(target) > len("sheets://") && strings.EqualFold(target[:len("sheets://")], "sheets://")
+}
+
+// ParseSheetsTarget splits sheets://<spreadsheet-id>/<tab> into the spreadsheet ID, as
+// in the sheet's URL, and the tab the result replaces
+func ParseSheetsTarget(target string) (string, string, error) {
+	if !IsSheetsTarget(target) {
+		return "", "", fmt.Errorf("%q is not a sheets:// target", target)
+	}
+	id, tab, _ := strings.Cut(target[len("sheets://"):], "/")
+	if id == "" || tab == "" {
+		return "", "", fmt.Errorf("sheets target %q should be sheets://<spreadsheet-id>/<tab>", target)
+	}
+	return id, tab, nil
+}
+
+// SheetURL is the browser address of a spreadsheet
+func SheetURL(spreadsheetID string) string {
+	return "https://docs.google.com/spreadsheets/d/" + spreadsheetID
+}
+
+// AuthorizeSheets runs the OAuth flow for installed apps: showURL is given the address
+// to sign in at, and Google sends the browser back to a local listener with the code,
+// which is exchanged for a refresh token
+func AuthorizeSheets(clientID, clientSecret string, showURL func(string)) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	redirectURI := "http://" + listener.Addr().String()
+
+	state, verifier := randomToken(), randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+	query := url.Values{
+		"client_id":             {clientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {sheetsScope},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	type callback struct {
+		code string
+		err  error
+	}
+	done := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("state") != state {
+			http.Error(w, "unexpected authorization response", http.StatusBadRequest)
+			return
+		}
+		result := callback{code: params.Get("code")}
+		if msg := params.Get("error"); msg != "" || result.code == "" {
+			result.err = fmt.Errorf("authorization was not granted: %s", msg)
+		}
+		fmt.Fprintln(w, "sqlterm: authorization finished, you can close this tab.")
+		select {
+		case done <- result:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	showURL(googleAuthURL + "?" + query.Encode())
+
+	var result callback
+	select {
+	case result = <-done:
+	case <-time.After(sheetsAuthTimeout):
+		return "", errors.New("timed out waiting for authorization")
+	}
+	if result.err != nil {
+		return "", result.err
+	}
+
+	token, err := requestGoogleToken(url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.code},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return "", err
+	}
+	if token.RefreshToken == "" {
+		return "", errors.New("google returned no refresh token")
+	}
+	return token.RefreshToken, nil
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+type googleToken struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+func requestGoogleToken(form url.Values) (*googleToken, error) {
+	resp, err := sheetsHTTPClient.PostForm(googleTokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var token googleToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, fmt.Errorf("unexpected token response (%s): %w", resp.Status, err)
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("%s: %s", token.Error, token.ErrorDescription)
+	}
+	return &token, nil
+}
+
+// sheetsAccessToken trades the refresh token for a short-lived access token
+func sheetsAccessToken(creds SheetsCredentials) (string, error) {
+	if creds.RefreshToken == "" {
+		return "", ErrSheetsNotAuthorized
+	}
+	token, err := requestGoogleToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {creds.ClientID},
+		"client_secret": {creds.ClientSecret},
+		"refresh_token": {creds.RefreshToken},
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.AccessToken, nil
+}
+
+// sheetsClient makes Sheets API calls for one spreadsheet
+type sheetsClient struct {
+	spreadsheetID string
+	accessToken   string
+}
+
+// sheetsAPIError is returned for a failed Sheets API call, with Google's message
+type sheetsAPIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *sheetsAPIError) Error() string {
+	return fmt.Sprintf("google sheets: %s (%d)", e.Message, e.StatusCode)
+}
+
+func (c *sheetsClient) call(method, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, sheetsAPIURL+"/"+url.PathEscape(c.spreadsheetID)+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := sheetsHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var failure struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&failure)
+	if failure.Error.Message == "" {
+		failure.Error.Message = resp.Status
+	}
+	return &sheetsAPIError{StatusCode: resp.StatusCode, Message: failure.Error.Message}
+}
+
+// quoteSheetName quotes a tab name for A1 notation, which on its own is the whole tab
+func quoteSheetName(tab string) string {
+	return "'" + strings.ReplaceAll(tab, "'", "''") + "'"
+}
+
+// sheetsRange is the A1 range starting at column A of a row
+func sheetsRange(tab string, row int) string {
+	return fmt.Sprintf("%s!A%d", quoteSheetName(tab), row)
+}
+
+// prepareTab empties a tab, adding it to the spreadsheet when it does not exist
+func (c *sheetsClient) prepareTab(tab string) error {
+	err := c.call(http.MethodPost, "/values/"+url.PathEscape(quoteSheetName(tab))+":clear", struct{}{})
+	var apiErr *sheetsAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return err
+	}
+	// A range on a missing tab cannot be parsed
+	return c.call(http.MethodPost, ":batchUpdate", map[string]any{
+		"requests": []any{map[string]any{"addSheet": map[string]any{"properties": map[string]string{"title": tab}}}},
+	})
+}
+
+func (c *sheetsClient) writeRows(tab string, row int, values [][]any) error {
+	rng := sheetsRange(tab, row)
+	return c.call(http.MethodPut, "/values/"+url.PathEscape(rng)+"?valueInputOption=RAW", map[string]any{
+		"range":          rng,
+		"majorDimension": "ROWS",
+		"values":         values,
+	})
+}
+
+// ExportToSheet replaces the contents of a Google Sheets tab with a result, a header
+// row followed by the rows. Values are written as they are, so text that looks like a
+// formula is not evaluated.
+func ExportToSheet(result *QueryResult, target string, creds SheetsCredentials) (int, error) {
+	count := 0
+	defer result.Close()
+
+	id, tab, err := ParseSheetsTarget(target)
+	if err != nil {
+		return count, err
+	}
+	accessToken, err := sheetsAccessToken(creds)
+	if err != nil {
+		return count, err
+	}
+	client := &sheetsClient{spreadsheetID: id, accessToken: accessToken}
+	if err := client.prepareTab(tab); err != nil {
+		return count, err
+	}
+
+	header := make([]any, len(result.Columns))
+	for i, name := range result.DisplayColumnNames() {
+		header[i] = name
+	}
+	batch := [][]any{header}
+	next := 1
+	flush := func() error {
+		if err := client.writeRows(tab, next, batch); err != nil {
+			return err
+		}
+		next += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err = result.ForEachRow(func(row []Value) error {
+		values := make([]any, len(row))
+		for i, val := range row {
+			if values[i] = geoJSONProperty(val); values[i] == nil {
+				values[i] = ""
+			}
+		}
+		batch = append(batch, values)
+		count++
+		if len(batch) == sheetsBatchRows {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
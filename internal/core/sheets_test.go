@@ -0,0 +1,104 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSheetsTarget(t *testing.T) {
+	id, tab, err := ParseSheetsTarget("sheets://1AbC-xyz/Weekly Revenue")
+	if err != nil || id != "1AbC-xyz" || tab != "Weekly Revenue" {
+		t.Errorf("ParseSheetsTarget() = %q, %q, %v", id, tab, err)
+	}
+	for _, target := range []string{"sheets://1AbC-xyz", "sheets:///tab", "results.csv"} {
+		if _, _, err := ParseSheetsTarget(target); err == nil {
+			t.Errorf("Expected an error for %q", target)
+		}
+	}
+}
+
+func TestExportToSheet(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE revenue (region TEXT, total REAL)",
+		"INSERT INTO revenue VALUES ('APAC', 12.5), ('=EMEA', NULL)",
+	)
+
+	var calls []string
+	var written any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			r.ParseForm()
+			if r.Form.Get("refresh_token") != "refresh" {
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Bad Request"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "access"})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch {
+		case strings.HasSuffix(r.URL.Path, ":clear"):
+			// The tab does not exist yet
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"code": 400, "message": "Unable to parse range: 'Q1 ''24'"}}`))
+		case r.Method == http.MethodPut:
+			var body struct {
+				Values any `json:"values"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			written = body.Values
+			w.Write([]byte(`{}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+	defer func(token, api string) { googleTokenURL, sheetsAPIURL = token, api }(googleTokenURL, sheetsAPIURL)
+	googleTokenURL, sheetsAPIURL = server.URL+"/token", server.URL+"/v4/spreadsheets"
+
+	result, err := conn.Execute("SELECT region, total FROM revenue ORDER BY total DESC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := ExportToSheet(result, "sheets://sheet-1/Q1 '24", SheetsCredentials{ClientID: "id", ClientSecret: "secret", RefreshToken: "refresh"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 {
+		t.Errorf("Expected 2 rows exported, got %d", rows)
+	}
+
+	wantCalls := []string{
+		"POST /v4/spreadsheets/sheet-1/values/'Q1 ''24':clear",
+		"POST /v4/spreadsheets/sheet-1:batchUpdate",
+		"PUT /v4/spreadsheets/sheet-1/values/'Q1 ''24'!A1",
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Errorf("Unexpected API calls\n%v\nwant\n%v", calls, wantCalls)
+	}
+	wantValues := []any{
+		[]any{"region", "total"},
+		[]any{"APAC", 12.5},
+		[]any{"=EMEA", ""},
+	}
+	if !reflect.DeepEqual(written, wantValues) {
+		t.Errorf("Unexpected values %v, want %v", written, wantValues)
+	}
+
+	result, err = conn.Execute("SELECT region FROM revenue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ExportToSheet(result, "sheets://sheet-1/tab", SheetsCredentials{RefreshToken: "expired"}); err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Expected the token error, got %v", err)
+	}
+}
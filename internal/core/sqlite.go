@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Attachment is a database file attached to a SQLite connection under a schema name, so
+// its tables are queried as schema.table
+type Attachment struct {
+	Path   string `yaml:"path"`
+	Schema string `yaml:"as"`
+}
+
+// sqliteConnector opens SQLite connections with the configured extensions loaded and
+// the attachments in place. ATTACH only lasts as long as the connection, so it is run
+// again on every connection the pool opens.
+type sqliteConnector struct {
+	dsn      string
+	readOnly bool
+	driver   *sqlite3.SQLiteDriver
+
+	mu          sync.Mutex
+	attachments []Attachment
+}
+
+func newSQLiteConnector(config *ConnectionConfig, dsn string) *sqliteConnector {
+	c := &sqliteConnector{
+		dsn:         dsn,
+		readOnly:    config.ReadOnly,
+		attachments: slices.Clone(config.Attachments),
+	}
+	c.driver = &sqlite3.SQLiteDriver{
+		Extensions:  config.Extensions,
+		ConnectHook: c.attachAll,
+	}
+	return c
+}
+
+func (c *sqliteConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c *sqliteConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// attachStatement opens attachments of read-only connections read-only as well
+func (c *sqliteConnector) attachStatement(attachment Attachment) string {
+	path := attachment.Path
+	if c.readOnly {
+		path = "file:" + strings.NewReplacer("%", "%25", "?", "%3F", "#", "%23").Replace(path) + "?mode=ro"
+	}
+	return fmt.Sprintf("ATTACH DATABASE %s AS %s", QuoteLiteral(SQLite, path), QuoteIdentifier(SQLite, attachment.Schema))
+}
+
+// schemas returns the attached schema names
+func (c *sqliteConnector) schemas() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	schemas := make([]string, len(c.attachments))
+	for i, attachment := range c.attachments {
+		schemas[i] = attachment.Schema
+	}
+	return schemas
+}
+
+// Attach attaches a database file to a SQLite connection for the rest of the session.
+// Connections already open are closed so every connection the pool hands out has it,
+// unless the session is pinned with PinSession.
+func Attach(conn Connection, path, schema string) error {
+	c, ok := conn.(*connection)
+	if !ok || c.sqlite == nil {
+		return fmt.Errorf("attaching databases is only supported on SQLite connections")
+	}
+	if err := ValidateIdentifier(schema); err != nil {
+		return err
+	}
+	if strings.EqualFold(schema, "main") || strings.EqualFold(schema, "temp") ||
+		slices.ContainsFunc(c.sqlite.schemas(), func(s string) bool { return strings.EqualFold(s, schema) }) {
+		return fmt.Errorf("schema %s is already in use", schema)
+	}
+
+	c.sqlite.mu.Lock()
+	c.sqlite.attachments = append(c.sqlite.attachments, Attachment{Path: path, Schema: schema})
+	c.sqlite.mu.Unlock()
+
+	var err error
+	if c.pinned.Load() {
+		// Recycling would lose the pinned session's temporary tables; attach to it instead
+		_, err = c.db.Exec(c.sqlite.attachStatement(Attachment{Path: path, Schema: schema}))
+	} else {
+		c.db.SetMaxIdleConns(0)
+		c.db.SetMaxIdleConns(2) // database/sql's default
+		err = c.db.Ping()
+	}
+	if err != nil {
+		c.sqlite.mu.Lock()
+		c.sqlite.attachments = c.sqlite.attachments[:len(c.sqlite.attachments)-1]
+		c.sqlite.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Attachments lists the databases attached to a SQLite connection, nil for other
+// connections
+func Attachments(conn Connection) []Attachment {
+	c, ok := conn.(*connection)
+	if !ok || c.sqlite == nil {
+		return nil
+	}
+	c.sqlite.mu.Lock()
+	defer c.sqlite.mu.Unlock()
+	return slices.Clone(c.sqlite.attachments)
+}
+
+// sqliteTableName splits schema.table when the schema is attached, so tables of attached
+// databases can be described; other names are tables of the main database
+func (c *connection) sqliteTableName(name string) (table, schema string) {
+	if c.sqlite != nil {
+		if prefix, rest, ok := strings.Cut(name, "."); ok {
+			if strings.EqualFold(prefix, "main") || slices.Contains(c.sqlite.schemas(), prefix) {
+				return rest, prefix
+			}
+		}
+	}
+	return name, "main"
+}
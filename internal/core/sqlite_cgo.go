@@ -0,0 +1,21 @@
+//go:build cgo
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// attachAll runs the attachments on each new connection, as its ConnectHook
+func (c *sqliteConnector) attachAll(conn *sqlite3.SQLiteConn) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, attachment := range c.attachments {
+		if _, err := conn.Exec(c.attachStatement(attachment), nil); err != nil {
+			return fmt.Errorf("failed to attach %s as %s: %w", attachment.Path, attachment.Schema, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !cgo
+
+package core
+
+import "github.com/mattn/go-sqlite3"
+
+// attachAll is never called without cgo: go-sqlite3 then refuses to open connections,
+// so SQLite is unavailable in such builds while the other databases work
+func (c *sqliteConnector) attachAll(conn *sqlite3.SQLiteConn) error {
+	return nil
+}
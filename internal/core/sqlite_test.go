@@ -0,0 +1,49 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAttach(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "archive.db")
+	archive, err := NewConnection(&ConnectionConfig{Name: "archive", DatabaseType: SQLite, Database: archivePath})
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	mustExec(t, archive, "CREATE TABLE orders (id INTEGER PRIMARY KEY, total REAL NOT NULL)")
+	archive.Close()
+
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)")
+
+	if err := Attach(conn, archivePath, "archive"); err != nil {
+		t.Fatalf("Attach failed: %v", err)
+	}
+	if err := Attach(conn, archivePath, "main"); err == nil {
+		t.Error("Expected attaching as main to fail")
+	}
+
+	tables, err := conn.ListTables()
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	if expected := []string{"users", "archive.orders"}; !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Expected tables %v, got %v", expected, tables)
+	}
+
+	info, err := conn.DescribeTable("archive.orders")
+	if err != nil {
+		t.Fatalf("DescribeTable failed: %v", err)
+	}
+	if len(info.Columns) != 2 || !reflect.DeepEqual(info.PrimaryKeys, []string{"id"}) {
+		t.Errorf("Unexpected description of archive.orders: %+v", info)
+	}
+
+	// Connections the pool opens later attach the database too
+	mustExec(t, conn, "INSERT INTO archive.orders (total) VALUES (9.5)")
+	if attachments := Attachments(conn); len(attachments) != 1 || attachments[0].Schema != "archive" {
+		t.Errorf("Unexpected attachments %v", attachments)
+	}
+}
@@ -0,0 +1,199 @@
+package core
+
+import (
+	"container/list"
+	"database/sql"
+	"strings"
+	"sync"
+)
+
+// defaultStatementCacheSize is how many prepared statements a connection keeps
+const defaultStatementCacheSize = 64
+
+// StatementCacheStats reports how often repeated queries reused a prepared statement
+type StatementCacheStats struct {
+	Hits      int64 // Executions that reused a prepared statement
+	Misses    int64 // Executions that ran without one
+	Evictions int64 // Statements closed to make room
+	Size      int   // Statements currently prepared
+	Capacity  int
+}
+
+// HitRate returns the share of executions that reused a statement, 0 before any ran
+func (s StatementCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// StatementStats returns a connection's statement cache metrics, false when the
+// connection has no cache
+func StatementStats(conn Connection) (StatementCacheStats, bool) {
+	c, ok := conn.(*connection)
+	if !ok || c.stmts == nil {
+		return StatementCacheStats{}, false
+	}
+	return c.stmts.stats(), true
+}
+
+// stmtEntry is a query seen on the connection. A query is only prepared when it runs a
+// second time, so one-off statements do not pay for an extra round trip.
+type stmtEntry struct {
+	query        string
+	stmt         *sql.Stmt
+	unpreparable bool // The driver rejected it, e.g. several statements in one string
+}
+
+// stmtCache keeps the most recently used queries by SQL text
+type stmtCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // Front is most recently used
+	hits     int64
+	misses   int64
+	evicted  int64
+}
+
+func newStmtCache(capacity int) *stmtCache {
+	return &stmtCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// query runs a query through its cached statement, preparing one on the second run
+func (c *stmtCache) query(db *sql.DB, query string) (*sql.Rows, error) {
+	stmt := c.statement(db, query)
+	if stmt == nil {
+		return db.Query(query)
+	}
+	rows, err := stmt.Query()
+	if err != nil {
+		// Prepare it again next time, in case the statement no longer fits the schema
+		c.forget(query)
+		if isStaleStatement(err) {
+			return db.Query(query)
+		}
+	}
+	return rows, err
+}
+
+// exec runs a statement for its effect the way query runs one for its rows
+func (c *stmtCache) exec(db *sql.DB, query string) (sql.Result, error) {
+	stmt := c.statement(db, query)
+	if stmt == nil {
+		return db.Exec(query)
+	}
+	res, err := stmt.Exec()
+	if err != nil {
+		c.forget(query)
+		if isStaleStatement(err) {
+			return db.Exec(query)
+		}
+	}
+	return res, err
+}
+
+// staleStatementMarkers identify errors from a statement prepared before the schema
+// changed, or closed by eviction while in use. Running the query afresh fixes them.
+var staleStatementMarkers = []string{
+	"cached plan must not change result type", // PostgreSQL
+	"needs to be re-prepared",                 // MySQL error 1615
+	"statement is closed",                     // database/sql
+}
+
+func isStaleStatement(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, marker := range staleStatementMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *stmtCache) forget(query string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[query]; ok {
+		if stmt := element.Value.(*stmtEntry).stmt; stmt != nil {
+			stmt.Close()
+		}
+		c.order.Remove(element)
+		delete(c.entries, query)
+	}
+}
+
+func (c *stmtCache) statement(db *sql.DB, query string) *sql.Stmt {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[query]
+	if !ok {
+		c.misses++
+		c.entries[query] = c.order.PushFront(&stmtEntry{query: query})
+		c.evict()
+		return nil
+	}
+
+	c.order.MoveToFront(element)
+	entry := element.Value.(*stmtEntry)
+	if entry.stmt != nil {
+		c.hits++
+		return entry.stmt
+	}
+	c.misses++
+	if entry.unpreparable {
+		return nil
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		entry.unpreparable = true
+		return nil
+	}
+	entry.stmt = stmt
+	return stmt
+}
+
+// evict drops the least recently used queries beyond capacity. Closing a statement
+// with open rows is safe; database/sql finishes the close once the rows are closed.
+func (c *stmtCache) evict() {
+	for c.order.Len() > c.capacity {
+		element := c.order.Back()
+		entry := element.Value.(*stmtEntry)
+		if entry.stmt != nil {
+			entry.stmt.Close()
+			c.evicted++
+		}
+		c.order.Remove(element)
+		delete(c.entries, entry.query)
+	}
+}
+
+func (c *stmtCache) stats() StatementCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size := 0
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		if element.Value.(*stmtEntry).stmt != nil {
+			size++
+		}
+	}
+	return StatementCacheStats{Hits: c.hits, Misses: c.misses, Evictions: c.evicted, Size: size, Capacity: c.capacity}
+}
+
+// close releases every prepared statement
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		if entry := element.Value.(*stmtEntry); entry.stmt != nil {
+			entry.stmt.Close()
+		}
+	}
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+}
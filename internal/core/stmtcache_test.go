@@ -0,0 +1,81 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStatementCache(t *testing.T) {
+	conn, err := NewConnection(&ConnectionConfig{
+		Name:               "test",
+		DatabaseType:       SQLite,
+		Database:           filepath.Join(t.TempDir(), "test.db"),
+		StatementCacheSize: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	mustExec(t, conn, "CREATE TABLE t (id INTEGER)", "INSERT INTO t VALUES (1), (2)")
+
+	count := func(query string) int {
+		t.Helper()
+		result, err := conn.Execute(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer result.Close()
+		n := 0
+		for range result.Itor() {
+			n++
+		}
+		return n
+	}
+
+	// Prepared on the second run and reused from the third
+	for i := 0; i < 4; i++ {
+		if n := count("SELECT id FROM t"); n != 2 {
+			t.Fatalf("Expected 2 rows, got %d", n)
+		}
+	}
+	stats, ok := StatementStats(conn)
+	if !ok {
+		t.Fatal("Expected a statement cache")
+	}
+	// The two setup statements ran once each, so they missed too
+	if stats.Hits != 2 || stats.Misses != 4 || stats.Size != 1 || stats.HitRate() != 2.0/6 {
+		t.Errorf("Expected 2 hits and 4 misses with 1 statement, got %+v", stats)
+	}
+
+	// Results still follow the data after reuse
+	mustExec(t, conn, "INSERT INTO t VALUES (3)")
+	if n := count("SELECT id FROM t"); n != 3 {
+		t.Errorf("Expected 3 rows after the insert, got %d", n)
+	}
+
+	// The least recently used statement is closed beyond capacity
+	count("SELECT id FROM t WHERE id > 1")
+	count("SELECT id FROM t WHERE id > 1")
+	count("SELECT id FROM t WHERE id > 2")
+	if stats, _ := StatementStats(conn); stats.Evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %+v", stats)
+	}
+	if n := count("SELECT id FROM t"); n != 3 {
+		t.Errorf("Expected the evicted query to run again, got %d rows", n)
+	}
+
+	// A negative size turns the cache off
+	disabled, err := NewConnection(&ConnectionConfig{
+		Name:               "disabled",
+		DatabaseType:       SQLite,
+		Database:           filepath.Join(t.TempDir(), "disabled.db"),
+		StatementCacheSize: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer disabled.Close()
+	if _, ok := StatementStats(disabled); ok {
+		t.Error("Expected no cache when disabled")
+	}
+}
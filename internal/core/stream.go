@@ -0,0 +1,188 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// kafkaCommands are the Kafka command line producers tried in order. Kafka targets are
+// fed through one rather than a client library, as with the DuckDB shell.
+var kafkaCommands = []string{"kcat", "kafkacat"}
+
+// streamSchemes are the export targets rows are streamed to as NDJSON instead of being
+// written to a file
+var streamSchemes = []string{"unix", "pipe", "tcp", "kafka"}
+
+// ErrKafkaClientNotFound is returned when a kafka:// export finds no kcat to produce with
+var ErrKafkaClientNotFound = errors.New("kafka export needs kcat (or kafkacat) on the PATH")
+
+// IsStreamTarget reports whether an export target is a stream, such as
+// tcp://host:port or kafka://broker/topic, rather than a file
+func IsStreamTarget(target string) bool {
+	scheme, _, ok := strings.Cut(target, "://")
+	if !ok {
+		return false
+	}
+	for _, s := range streamSchemes {
+		if strings.EqualFold(scheme, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// OpenStream connects to a stream target:
+//
+//	unix:///run/feed.sock   a UNIX domain socket
+//	pipe:///tmp/feed        a named pipe (FIFO), opened for writing
+//	tcp://host:port         a TCP endpoint
+//	kafka://broker/topic    a Kafka topic, one message per row; brokers may be comma separated
+func OpenStream(target string) (io.WriteCloser, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stream target %q: %w", target, err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "unix":
+		return net.Dial("unix", streamPath(u))
+	case "pipe":
+		return os.OpenFile(streamPath(u), os.O_WRONLY, 0)
+	case "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("tcp target %q has no host:port", target)
+		}
+		return net.Dial("tcp", u.Host)
+	case "kafka":
+		topic := strings.Trim(u.Path, "/")
+		if u.Host == "" || topic == "" || strings.Contains(topic, "/") {
+			return nil, fmt.Errorf("kafka target %q should be kafka://broker/topic", target)
+		}
+		return openKafkaProducer(u.Host, topic)
+	}
+	return nil, fmt.Errorf("unsupported stream target %q", target)
+}
+
+// streamPath is the file system path of a unix:// or pipe:// target, which may be
+// written with three slashes (unix:///run/feed.sock) or relative (pipe://feed)
+func streamPath(u *url.URL) string {
+	return u.Host + u.Path
+}
+
+// kafkaProducer writes to the standard input of a kcat producer, which sends each line
+// as a message. Close waits for kcat to flush the messages it has.
+type kafkaProducer struct {
+	io.WriteCloser
+	cmd    *exec.Cmd
+	stderr strings.Builder
+}
+
+func openKafkaProducer(brokers, topic string) (*kafkaProducer, error) {
+	var binary string
+	for _, name := range kafkaCommands {
+		if path, err := exec.LookPath(name); err == nil {
+			binary = path
+			break
+		}
+	}
+	if binary == "" {
+		return nil, ErrKafkaClientNotFound
+	}
+
+	p := &kafkaProducer{cmd: exec.Command(binary, "-P", "-b", brokers, "-t", topic)}
+	p.cmd.Stderr = &p.stderr
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.WriteCloser = stdin
+	if err := p.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *kafkaProducer) Close() error {
+	p.WriteCloser.Close()
+	if err := p.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(p.stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// WriteNDJSON writes each row of a result as one JSON object per line, with keys in
+// column order. Numbers and booleans keep their JSON types and JSON columns are
+// embedded rather than quoted.
+func WriteNDJSON(result *QueryResult, w io.Writer) (int, error) {
+	count := 0
+	defer result.Close()
+
+	keys := make([][]byte, len(result.Columns))
+	jsonColumns := make([]bool, len(result.Columns))
+	for i, name := range result.DisplayColumnNames() {
+		keys[i], _ = json.Marshal(name)
+		jsonColumns[i] = IsJSONType(result.Columns[i].Type)
+	}
+
+	writer := bufio.NewWriter(w)
+	err := result.ForEachRow(func(row []Value) error {
+		writer.WriteByte('{')
+		for i, val := range row {
+			if i >= len(keys) {
+				break
+			}
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+			writer.Write(keys[i])
+			writer.WriteByte(':')
+			if jsonColumns[i] && !val.IsNull() && json.Valid([]byte(val.String())) {
+				writer.WriteString(CompactJSON(val.String()))
+				continue
+			}
+			data, err := json.Marshal(geoJSONProperty(val))
+			if err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+			writer.Write(data)
+		}
+		writer.WriteString("}\n")
+		// Flush per row so downstream consumers see rows as they are fetched
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+	return count, nil
+}
+
+// StreamQueryResult sends a result as NDJSON to a stream target
+func StreamQueryResult(result *QueryResult, target string) (int, error) {
+	stream, err := OpenStream(target)
+	if err != nil {
+		result.Close()
+		return 0, err
+	}
+	count, err := WriteNDJSON(result, stream)
+	if closeErr := stream.Close(); err == nil {
+		err = closeErr
+	}
+	return count, err
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestIsStreamTarget(t *testing.T) {
+	testCases := map[string]bool{
+		"tcp://localhost:9000":       true,
+		"unix:///run/feed.sock":      true,
+		"pipe:///tmp/feed":           true,
+		"KAFKA://broker:9092/orders": true,
+		"results.csv":                false,
+		"https://example.com/x":      false,
+		"out/tcp.csv":                false,
+	}
+	for target, want := range testCases {
+		if got := IsStreamTarget(target); got != want {
+			t.Errorf("IsStreamTarget(%q) = %v, want %v", target, got, want)
+		}
+	}
+}
+
+func TestStreamQueryResult(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE events (id INTEGER, name TEXT, score REAL, payload JSON)",
+		`INSERT INTO events VALUES (1, 'signup', 1.5, '{"plan": "pro"}'), (2, NULL, NULL, NULL)`,
+	)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer c.Close()
+		data, _ := io.ReadAll(c)
+		received <- string(data)
+	}()
+
+	result, err := conn.Execute("SELECT id, name, score, payload FROM events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := StreamQueryResult(result, "tcp://"+listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 {
+		t.Errorf("Expected 2 rows streamed, got %d", rows)
+	}
+
+	want := `{"id":1,"name":"signup","score":1.5,"payload":{"plan":"pro"}}
+{"id":2,"name":null,"score":null,"payload":null}
+`
+	if got := <-received; got != want {
+		t.Errorf("Unexpected NDJSON\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestOpenStreamInvalidTargets(t *testing.T) {
+	for _, target := range []string{"tcp://", "kafka://broker:9092", "kafka://broker/a/b"} {
+		if _, err := OpenStream(target); err == nil || !strings.Contains(err.Error(), target) {
+			t.Errorf("Expected an error naming %q, got %v", target, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package core
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// unknownNamePatterns find the missing table or column in each driver's error message
+var unknownNamePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)column "?([^"\s]+)"? does not exist`),        // PostgreSQL
+	regexp.MustCompile(`(?i)relation "([^"]+)" does not exist`),          // PostgreSQL
+	regexp.MustCompile(`(?i)unknown column '([^']+)'`),                   // MySQL
+	regexp.MustCompile(`(?i)table '([^']+)' doesn't exist`),              // MySQL
+	regexp.MustCompile(`(?i)no such (?:column|table): ([^\s,]+)`),        // SQLite
+	regexp.MustCompile(`(?i)referenced column "([^"]+)" not found`),      // DuckDB
+	regexp.MustCompile(`(?i)table with name ([^\s!]+) does not exist`),   // DuckDB
+	regexp.MustCompile(`(?i)missing columns: '([^']+)'`),                 // ClickHouse
+	regexp.MustCompile(`(?i)table ([^\s']+) (?:doesn't|does not) exist`), // ClickHouse
+}
+
+// UnknownName returns the table or column an unknown table or column error names,
+// without any schema or alias in front of it
+func UnknownName(err error) (string, bool) {
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || (queryErr.Kind != ErrorUnknownColumn && queryErr.Kind != ErrorUnknownTable) {
+		return "", false
+	}
+	for _, pattern := range unknownNamePatterns {
+		if match := pattern.FindStringSubmatch(queryErr.Err.Error()); match != nil {
+			name := match[1]
+			return name[strings.LastIndex(name, ".")+1:], true
+		}
+	}
+	return "", false
+}
+
+// ClosestNames returns up to limit candidates that look like a misspelling of name:
+// within a few edits of it, or containing it, closest first. Matching ignores case, so a
+// name that differs only in case is suggested first.
+func ClosestNames(name string, candidates []string, limit int) []string {
+	type match struct {
+		name     string
+		distance int
+	}
+	lower := strings.ToLower(name)
+	maxDistance := max(1, len(name)/3)
+	var matches []match
+	for _, candidate := range candidates {
+		if candidate == name || slices.ContainsFunc(matches, func(m match) bool { return m.name == candidate }) {
+			continue
+		}
+		distance := editDistance(lower, strings.ToLower(candidate))
+		if distance > maxDistance && !(len(lower) >= 3 && strings.Contains(strings.ToLower(candidate), lower)) {
+			continue
+		}
+		matches = append(matches, match{candidate, distance})
+	}
+	slices.SortStableFunc(matches, func(a, b match) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	var names []string
+	for _, m := range matches[:min(limit, len(matches))] {
+		names = append(names, m.name)
+	}
+	return names
+}
+
+// editDistance counts the single-character insertions, deletions, substitutions and
+// swaps of neighbouring characters that turn a into b, so "modle" is one edit from "model"
+func editDistance(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	rows := [3][]int{make([]int, len(y)+1), make([]int, len(y)+1), make([]int, len(y)+1)}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		beforePrevious, previous, current := rows[0], rows[1], rows[2]
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = min(min(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			if i > 1 && j > 1 && x[i-1] == y[j-2] && x[i-2] == y[j-1] {
+				current[j] = min(current[j], beforePrevious[j-2]+1)
+			}
+		}
+		rows[0], rows[1], rows[2] = previous, current, beforePrevious
+	}
+	return rows[1][len(y)]
+}
+
+// plainIdentifier matches names that need no quoting in any dialect
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// ReplaceIdentifier replaces every use of the identifier old in a query with name,
+// leaving string literals and comments alone. The new name is quoted when it was
+// quoted before or needs quoting.
+func ReplaceIdentifier(dialect DatabaseType, query, old, name string) string {
+	var sb strings.Builder
+	replacement := name
+	if !plainIdentifier.MatchString(name) {
+		replacement = QuoteIdentifier(dialect, name)
+	}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			sb.WriteString(query[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			sb.WriteString(query[i : i+end+4])
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for j < len(query) && query[j] != closing {
+				j++
+			}
+			if c != '\'' && strings.EqualFold(query[i+1:min(j, len(query))], old) {
+				sb.WriteString(QuoteIdentifier(dialect, name))
+			} else {
+				sb.WriteString(query[i:min(j+1, len(query))])
+			}
+			i = j + 1
+		case isIdentByte(c) || c == '$':
+			j := i
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '$') {
+				j++
+			}
+			if strings.EqualFold(query[i:j], old) {
+				sb.WriteString(replacement)
+			} else {
+				sb.WriteString(query[i:j])
+			}
+			i = j
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestUnknownName(t *testing.T) {
+	testCases := []struct {
+		err  error
+		name string
+	}{
+		{&pq.Error{Code: "42P01", Message: `relation "customer_order" does not exist`}, "customer_order"},
+		{&pq.Error{Code: "42703", Message: `column o.totl does not exist`}, "totl"},
+		{&mysql.MySQLError{Number: 1054, Message: "Unknown column 'totl' in 'field list'"}, "totl"},
+		{&mysql.MySQLError{Number: 1146, Message: "Table 'shop.custmers' doesn't exist"}, "custmers"},
+		{errors.New("no such table: main.custmers"), "custmers"},
+	}
+	for _, tc := range testCases {
+		name, ok := UnknownName(ClassifyError(tc.err, SQLite))
+		if !ok || name != tc.name {
+			t.Errorf("UnknownName(%v) = %q, %v, want %q", tc.err, name, ok, tc.name)
+		}
+	}
+	if _, ok := UnknownName(ClassifyError(errors.New("near \"SELEC\": syntax error"), SQLite)); ok {
+		t.Error("A syntax error names no unknown table or column")
+	}
+
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn, "CREATE TABLE customer_orders (id INTEGER, total REAL)")
+	_, err := conn.Execute("SELECT o.totl FROM customer_orders o")
+	if name, ok := UnknownName(err); !ok || name != "totl" {
+		t.Errorf("Expected totl from SQLite's error, got %q (%v)", name, err)
+	}
+}
+
+func TestClosestNames(t *testing.T) {
+	tables := []string{"customers", "customer_orders", "orders", "order_items", "OrderTotals", "products"}
+	testCases := []struct {
+		name string
+		want []string
+	}{
+		{"customer_order", []string{"customer_orders"}},
+		{"custmers", []string{"customers"}},
+		{"ordertotals", []string{"OrderTotals"}},
+		{"order", []string{"orders", "OrderTotals", "order_items"}},
+		{"invoices", nil},
+	}
+	for _, tc := range testCases {
+		if got := ClosestNames(tc.name, tables, 3); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ClosestNames(%q) = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestReplaceIdentifier(t *testing.T) {
+	testCases := []struct {
+		dialect   DatabaseType
+		query     string
+		old, name string
+		want      string
+	}{
+		{PostgreSQL, "SELECT o.totl FROM orders o WHERE note = 'totl' -- totl", "totl", "total",
+			"SELECT o.total FROM orders o WHERE note = 'totl' -- totl"},
+		{PostgreSQL, "SELECT * FROM ordertotals", "ordertotals", "OrderTotals", `SELECT * FROM "OrderTotals"`},
+		{MySQL, "SELECT * FROM `custmers` c", "custmers", "customers", "SELECT * FROM `customers` c"},
+	}
+	for _, tc := range testCases {
+		if got := ReplaceIdentifier(tc.dialect, tc.query, tc.old, tc.name); got != tc.want {
+			t.Errorf("ReplaceIdentifier(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
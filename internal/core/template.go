@@ -0,0 +1,120 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateKeywords are Go template actions that look like a {{name}} session variable
+var templateKeywords = []string{"end", "else", "break", "continue", "nil", "true", "false"}
+
+// IsSQLTemplate reports whether an SQL file uses Go template actions, such as
+// {{ if .include_archived }}, rather than only {{name}} session variables
+func IsSQLTemplate(content string) bool {
+	rest := variableRefPattern.ReplaceAllStringFunc(content, func(ref string) string {
+		if slices.Contains(templateKeywords, variableRefPattern.FindStringSubmatch(ref)[1]) {
+			return ref
+		}
+		return ""
+	})
+	return strings.Contains(rest, "{{")
+}
+
+// parseSQLTemplate parses an SQL file as a Go template. {{name}} session variables are
+// kept as they are, for ExpandVariables to fill in when each statement runs. Templates
+// can use quote to make an SQL string literal, and split and join for lists:
+//
+//	{{ range $i, $r := split "," .regions }}{{ if $i }}, {{ end }}{{ quote $r }}{{ end }}
+func parseSQLTemplate(content string, dbType DatabaseType) (*template.Template, error) {
+	protected := variableRefPattern.ReplaceAllStringFunc(content, func(ref string) string {
+		if slices.Contains(templateKeywords, variableRefPattern.FindStringSubmatch(ref)[1]) {
+			return ref
+		}
+		return `{{"` + ref + `"}}`
+	})
+	funcs := template.FuncMap{
+		"quote": func(value string) string { return QuoteLiteral(dbType, value) },
+		"split": func(sep, value string) []string { return strings.Split(value, sep) },
+		"join":  func(sep string, values []string) string { return strings.Join(values, sep) },
+	}
+	return template.New("sql").Funcs(funcs).Option("missingkey=error").Parse(protected)
+}
+
+// SQLTemplateFields lists the values an SQL template needs, the .name fields it refers
+// to outside of range and with blocks, in order of first use
+func SQLTemplateFields(content string) ([]string, error) {
+	tmpl, err := parseSQLTemplate(content, SQLite)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	var walk func(node parse.Node, topLevel bool)
+	walk = func(node parse.Node, topLevel bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, topLevel)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, topLevel)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				for _, arg := range cmd.Args {
+					walk(arg, topLevel)
+				}
+			}
+		case *parse.FieldNode:
+			if topLevel && !slices.Contains(fields, n.Ident[0]) {
+				fields = append(fields, n.Ident[0])
+			}
+		case *parse.IfNode:
+			walk(n.Pipe, topLevel)
+			walk(n.List, topLevel)
+			walk(n.ElseList, topLevel)
+		case *parse.RangeNode:
+			// The body runs with . set to each element
+			walk(n.Pipe, topLevel)
+			walk(n.List, false)
+			walk(n.ElseList, topLevel)
+		case *parse.WithNode:
+			walk(n.Pipe, topLevel)
+			walk(n.List, false)
+			walk(n.ElseList, topLevel)
+		}
+	}
+	walk(tmpl.Tree.Root, true)
+	return fields, nil
+}
+
+// RenderSQLTemplate runs an SQL template with the given values. true and false become
+// booleans, so {{ if .include_archived }} is skipped for false.
+func RenderSQLTemplate(content string, dbType DatabaseType, values map[string]string) (string, error) {
+	tmpl, err := parseSQLTemplate(content, dbType)
+	if err != nil {
+		return "", err
+	}
+	data := make(map[string]any, len(values))
+	for name, value := range values {
+		switch strings.ToLower(value) {
+		case "true":
+			data[name] = true
+		case "false":
+			data[name] = false
+		default:
+			data[name] = value
+		}
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
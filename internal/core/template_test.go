@@ -0,0 +1,55 @@
+package core
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRenderSQLTemplate(t *testing.T) {
+	content := `SELECT * FROM orders
+WHERE created_at >= '{{ .since }}' AND id > {{last_id}}
+{{ if not .include_archived }}AND archived = false{{ end }}
+{{ with .regions }}AND region IN ({{ range $i, $r := split "," . }}{{ if $i }}, {{ end }}{{ quote $r }}{{ end }}){{ end }};`
+
+	if !IsSQLTemplate(content) {
+		t.Fatal("Expected the file to be a template")
+	}
+	if IsSQLTemplate("SELECT * FROM orders WHERE id > {{last_id}} AND region = '{{ region }}'") {
+		t.Error("Expected session variables alone not to make a template")
+	}
+
+	fields, err := SQLTemplateFields(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"since", "include_archived", "regions"}; !slices.Equal(fields, want) {
+		t.Errorf("SQLTemplateFields() = %v, want %v", fields, want)
+	}
+
+	got, err := RenderSQLTemplate(content, PostgreSQL, map[string]string{
+		"since": "2024-01-01", "include_archived": "false", "regions": "APAC,O'Hare",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `SELECT * FROM orders
+WHERE created_at >= '2024-01-01' AND id > {{last_id}}
+AND archived = false
+AND region IN ('APAC', 'O''Hare');`
+	if got != want {
+		t.Errorf("RenderSQLTemplate() =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = RenderSQLTemplate(content, PostgreSQL, map[string]string{"since": "2024-01-01", "include_archived": "true", "regions": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(got, "archived = false") || strings.Contains(got, "region IN") {
+		t.Errorf("Expected the optional filters to be left out, got\n%s", got)
+	}
+
+	if _, err := RenderSQLTemplate(content, PostgreSQL, map[string]string{"since": "2024-01-01"}); err == nil {
+		t.Error("Expected an error for a missing value")
+	}
+}
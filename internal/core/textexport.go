@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// fixedWidthGap separates the columns of a fixed-width export
+const fixedWidthGap = "  "
+
+// isNumericColumn reports whether a column's values are right-aligned in text tables
+func isNumericColumn(col Column) bool {
+	t := JSONLColumnType(col)
+	return t == IntegerJSONLType || t == NumberJSONLType
+}
+
+// tableCell returns a value as one line of text, with JSON columns compacted
+func tableCell(val Value, jsonColumn bool) string {
+	cell := val.String()
+	if jsonColumn && !val.IsNull() {
+		cell = CompactJSON(cell)
+	}
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(cell)
+}
+
+// SaveQueryResultAsMarkdownTable writes a result to a file as a GitHub-flavoured markdown
+// table, row by row as they are fetched. Numeric columns are right-aligned.
+func SaveQueryResultAsMarkdownTable(result *QueryResult, filePath string) (int, error) {
+	count := 0
+	defer result.Close()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return count, fmt.Errorf("failed to create markdown file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	escape := strings.NewReplacer("|", `\|`)
+
+	jsonColumns := make([]bool, len(result.Columns))
+	var header, separator strings.Builder
+	for i, name := range result.DisplayColumnNames() {
+		jsonColumns[i] = IsJSONType(result.Columns[i].Type)
+		header.WriteString("| " + escape.Replace(name) + " ")
+		if isNumericColumn(result.Columns[i]) {
+			separator.WriteString("|---:")
+		} else {
+			separator.WriteString("|---")
+		}
+	}
+	writer.WriteString(header.String() + "|\n" + separator.String() + "|\n")
+
+	err = result.ForEachRow(func(row []Value) error {
+		for i, val := range row {
+			if i >= len(jsonColumns) {
+				break
+			}
+			writer.WriteString("| " + escape.Replace(tableCell(val, jsonColumns[i])) + " ")
+		}
+		if _, err := writer.WriteString("|\n"); err != nil {
+			return fmt.Errorf("failed to write markdown row: %w", err)
+		}
+		count++
+		return nil
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+	return count, nil
+}
+
+// SaveQueryResultAsFixedWidth writes a result to a file as plain-text columns padded to
+// their widest value, with numeric columns right-aligned. Widths are only known once every
+// row is read, so the rows are spooled to a temporary file rather than held in memory.
+func SaveQueryResultAsFixedWidth(result *QueryResult, filePath string) (int, error) {
+	count := 0
+	defer result.Close()
+
+	spool, err := os.CreateTemp("", "sqlterm-fixed-width-*.csv")
+	if err != nil {
+		return count, fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	headers := result.DisplayColumnNames()
+	widths := make([]int, len(headers))
+	jsonColumns := make([]bool, len(headers))
+	for i, header := range headers {
+		widths[i] = DisplayWidth(header)
+		jsonColumns[i] = IsJSONType(result.Columns[i].Type)
+	}
+
+	spooled := csv.NewWriter(spool)
+	err = result.ForEachRow(func(row []Value) error {
+		record := make([]string, len(headers))
+		for i, val := range row {
+			if i >= len(record) {
+				break
+			}
+			record[i] = tableCell(val, jsonColumns[i])
+			widths[i] = max(widths[i], DisplayWidth(record[i]))
+		}
+		if err := spooled.Write(record); err != nil {
+			return fmt.Errorf("failed to spool row: %w", err)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return count, fmt.Errorf("failed to fetch data: %w", err)
+		}
+		return count, err
+	}
+	spooled.Flush()
+	if err := spooled.Error(); err != nil {
+		return count, fmt.Errorf("failed to spool row: %w", err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return count, err
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return count, fmt.Errorf("failed to create fixed-width file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+	rightAligned := make([]bool, len(headers))
+	for i := range headers {
+		rightAligned[i] = isNumericColumn(result.Columns[i])
+	}
+	writeLine := func(cells []string) {
+		var line strings.Builder
+		for i, cell := range cells {
+			if i > 0 {
+				line.WriteString(fixedWidthGap)
+			}
+			if pad := widths[i] - DisplayWidth(cell); pad > 0 && rightAligned[i] {
+				line.WriteString(strings.Repeat(" ", pad) + cell)
+			} else {
+				line.WriteString(PadRight(cell, widths[i]))
+			}
+		}
+		writer.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+
+	writeLine(headers)
+	rules := make([]string, len(widths))
+	for i, width := range widths {
+		rules[i] = strings.Repeat("-", width)
+	}
+	writeLine(rules)
+
+	reader := csv.NewReader(spool)
+	reader.FieldsPerRecord = len(headers)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return count, fmt.Errorf("failed to read spooled row: %w", err)
+		}
+		writeLine(record)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return count, err
+	}
+	return count, file.Close()
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTextExportResult(t *testing.T) *QueryResult {
+	t.Helper()
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE items (id INTEGER, name TEXT, price DECIMAL(10,2))",
+		"INSERT INTO items VALUES (1, 'pen', 2.5), (12, 'a|b', NULL), (3, 'two\nlines', 100)",
+	)
+	result, err := conn.Execute("SELECT id, name, price FROM items ORDER BY rowid")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	return result
+}
+
+func TestSaveQueryResultToFileMarkdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.md")
+	count, err := SaveQueryResultToFile(newTextExportResult(t), path)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 rows, got %d", count)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "| id | name | price |\n" +
+		"|---:|---|---:|\n" +
+		"| 1 | pen | 2.5 |\n" +
+		"| 12 | a\\|b |  |\n" +
+		"| 3 | two lines | 100 |\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
+
+func TestSaveQueryResultAsFixedWidth(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.txt")
+	count, err := SaveQueryResultAsFixedWidth(newTextExportResult(t), path)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 rows, got %d", count)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "id  name       price\n" +
+		"--  ---------  -----\n" +
+		" 1  pen          2.5\n" +
+		"12  a|b\n" +
+		" 3  two lines    100\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, data)
+	}
+}
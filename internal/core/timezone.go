@@ -0,0 +1,58 @@
+package core
+
+import (
+	"strings"
+	"time"
+)
+
+// ParseTimezone resolves a display time zone setting: "utc", "local" or an IANA name
+func ParseTimezone(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "utc":
+		return time.UTC, nil
+	case "local":
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// IsTimestampType reports whether a driver column type name holds date-time values.
+// Plain DATE columns are excluded since shifting them across zones changes the day.
+func IsTimestampType(typeName string) bool {
+	upper := strings.ToUpper(typeName)
+	return strings.Contains(upper, "TIMESTAMP") || strings.Contains(upper, "DATETIME")
+}
+
+// TimezoneFormatter converts timestamp values into a configured time zone
+type TimezoneFormatter struct {
+	Location *time.Location
+	Label    string // Zone name shown in column headers
+}
+
+// NewTimezoneFormatter creates a formatter for a display time zone setting
+func NewTimezoneFormatter(name string) (*TimezoneFormatter, error) {
+	loc, err := ParseTimezone(name)
+	if err != nil {
+		return nil, err
+	}
+	label := loc.String()
+	if strings.EqualFold(name, "local") {
+		label = "local " + time.Now().In(loc).Format("MST")
+	}
+	return &TimezoneFormatter{Location: loc, Label: label}, nil
+}
+
+func (f *TimezoneFormatter) Format(col Column, val Value) (Value, bool) {
+	tv, ok := val.(TimeValue)
+	if !ok || !IsTimestampType(col.Type) {
+		return nil, false
+	}
+	return TimeValue{Value: tv.Value.In(f.Location)}, true
+}
+
+func (f *TimezoneFormatter) Annotate(col Column) string {
+	if IsTimestampType(col.Type) {
+		return f.Label
+	}
+	return ""
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimezone(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+		hasError bool
+	}{
+		{name: "UTC", input: "utc", expected: "UTC"},
+		{name: "Local", input: "local", expected: "Local"},
+		{name: "IANA zone", input: "Australia/Melbourne", expected: "Australia/Melbourne"},
+		{name: "Invalid zone", input: "Mars/Olympus", hasError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			loc, err := ParseTimezone(tc.input)
+			if tc.hasError {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if loc.String() != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, loc.String())
+			}
+		})
+	}
+}
+
+func TestTimezoneFormatter(t *testing.T) {
+	formatter, err := NewTimezoneFormatter("Australia/Melbourne")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	utc := time.Date(2024, 1, 15, 0, 30, 0, 0, time.UTC)
+	formatted, ok := formatter.Format(Column{Name: "created_at", Type: "TIMESTAMPTZ"}, TimeValue{Value: utc})
+	if !ok {
+		t.Fatal("Expected timestamp to be formatted")
+	}
+	if formatted.String() != "2024-01-15 11:30:00+1100" {
+		t.Errorf("Unexpected converted time: %s", formatted.String())
+	}
+
+	if _, ok := formatter.Format(Column{Name: "name", Type: "TEXT"}, StringValue{Value: "x"}); ok {
+		t.Error("Expected non-time value to be left untouched")
+	}
+	// Drivers return DATE values as midnight UTC; converting them would change the day
+	if _, ok := formatter.Format(Column{Name: "birthday", Type: "DATE"}, TimeValue{Value: utc}); ok {
+		t.Error("Expected DATE values to be left untouched")
+	}
+
+	if note := formatter.Annotate(Column{Name: "created_at", Type: "DATETIME"}); note != "Australia/Melbourne" {
+		t.Errorf("Unexpected annotation: %q", note)
+	}
+	if note := formatter.Annotate(Column{Name: "birthday", Type: "DATE"}); note != "" {
+		t.Errorf("DATE columns should not be annotated, got %q", note)
+	}
+}
+
+func TestTimezoneCSVExport(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE events (id INTEGER, created_at DATETIME)",
+		"INSERT INTO events VALUES (1, '2024-06-01 12:00:00')",
+	)
+
+	result, err := conn.Execute("SELECT id, created_at FROM events")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	formatter, err := NewTimezoneFormatter("utc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result.AddFormatter(formatter)
+
+	path := filepath.Join(t.TempDir(), "events.csv")
+	if _, err := SaveQueryResultToFile(result, path); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if lines[0] != "id,created_at (UTC)" {
+		t.Errorf("Expected annotated header, got %q", lines[0])
+	}
+	if lines[1] != "1,2024-06-01 12:00:00+0000" {
+		t.Errorf("Unexpected row: %q", lines[1])
+	}
+}
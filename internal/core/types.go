@@ -2,10 +2,16 @@ package core
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"iter"
+	"math"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
 )
 
 type DatabaseType int
@@ -14,6 +20,8 @@ const (
 	MySQL DatabaseType = iota
 	PostgreSQL
 	SQLite
+	DuckDB
+	ClickHouse
 )
 
 func (dt DatabaseType) String() string {
@@ -24,11 +32,39 @@ func (dt DatabaseType) String() string {
 		return "postgres"
 	case SQLite:
 		return "sqlite"
+	case DuckDB:
+		return "duckdb"
+	case ClickHouse:
+		return "clickhouse"
 	default:
 		return "unknown"
 	}
 }
 
+// IsFileBased reports whether the database is a local file rather than a server
+func (dt DatabaseType) IsFileBased() bool {
+	return dt == SQLite || dt == DuckDB
+}
+
+// UnmarshalYAML reads a database type saved as its number, or written by hand as a
+// name ParseDatabaseType accepts
+func (dt *DatabaseType) UnmarshalYAML(node *yaml.Node) error {
+	var n int
+	if err := node.Decode(&n); err == nil {
+		if n < int(MySQL) || n > int(ClickHouse) {
+			return fmt.Errorf("unknown database type %d. Supported types: 0 (mysql), 1 (postgres), 2 (sqlite), 3 (duckdb), 4 (clickhouse)", n)
+		}
+		*dt = DatabaseType(n)
+		return nil
+	}
+	parsed, err := ParseDatabaseType(node.Value)
+	if err != nil {
+		return err
+	}
+	*dt = parsed
+	return nil
+}
+
 func ParseDatabaseType(s string) (DatabaseType, error) {
 	switch strings.ToLower(s) {
 	case "mysql":
@@ -37,8 +73,12 @@ func ParseDatabaseType(s string) (DatabaseType, error) {
 		return PostgreSQL, nil
 	case "sqlite", "sqlite3":
 		return SQLite, nil
+	case "duckdb":
+		return DuckDB, nil
+	case "clickhouse":
+		return ClickHouse, nil
 	default:
-		return 0, fmt.Errorf("unsupported database type: %s. Supported types: mysql, postgres, sqlite", s)
+		return 0, fmt.Errorf("unsupported database type: %s. Supported types: mysql, postgres, sqlite, duckdb, clickhouse", s)
 	}
 }
 
@@ -48,7 +88,9 @@ func GetDefaultPort(dbType DatabaseType) int {
 		return 3306
 	case PostgreSQL:
 		return 5432
-	case SQLite:
+	case ClickHouse:
+		return 8123 // HTTP interface
+	case SQLite, DuckDB:
 		return 0
 	default:
 		return 0
@@ -63,7 +105,69 @@ type ConnectionConfig struct {
 	Database     string       `yaml:"database"`
 	Username     string       `yaml:"username"`
 	Password     string       `yaml:"password,omitempty"`
-	SSL          bool         `yaml:"ssl"`
+	// Shell command printing the password on its first line, e.g. pass show db/prod
+	PasswordCommand string            `yaml:"password_command,omitempty"`
+	AskPassword     bool              `yaml:"ask_password,omitempty"` // Prompt for the password on each connect
+	Auth            *AuthConfig       `yaml:"auth,omitempty"`         // Cloud IAM tokens instead of a password
+	SSL             bool              `yaml:"ssl"`
+	ReadOnly        bool              `yaml:"read_only,omitempty"`
+	Tags            map[string]string `yaml:"tags,omitempty"` // e.g. env: prod, team: billing
+	// Prepared statements kept for repeated queries; 0 uses the default, negative disables
+	StatementCacheSize int `yaml:"statement_cache_size,omitempty"`
+	// SQLite only: extension libraries loaded and database files attached on connect
+	Extensions  []string     `yaml:"extensions,omitempty"`
+	Attachments []Attachment `yaml:"attach,omitempty"`
+	// Read replicas that SELECT statements are spread over; see RouteQuery
+	Replicas []Replica `yaml:"replicas,omitempty"`
+	// How UPDATE and DELETE report the rows they will change before asking to go on:
+	// count, explain or empty for not at all
+	EstimateRows string `yaml:"estimate_rows,omitempty"`
+}
+
+// Ways of estimating the rows an UPDATE or DELETE changes, for estimate_rows
+const (
+	EstimateRowsCount   = "count"   // Run SELECT count(*) with the statement's WHERE clause
+	EstimateRowsExplain = "explain" // Use the planner's estimate, cheaper on large tables
+)
+
+// HasTag matches a "key=value" filter against the tags, or a bare filter against any key or value
+func (c *ConnectionConfig) HasTag(filter string) bool {
+	if key, value, ok := strings.Cut(filter, "="); ok {
+		return strings.EqualFold(c.Tags[key], value)
+	}
+	for key, value := range c.Tags {
+		if strings.EqualFold(key, filter) || strings.EqualFold(value, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatTags renders the tags as sorted key=value pairs
+func (c *ConnectionConfig) FormatTags() string {
+	pairs := make([]string, 0, len(c.Tags))
+	for key, value := range c.Tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// Environment returns the env tag of the connection, if any
+func (c *ConnectionConfig) Environment() string {
+	if env, ok := c.Tags["env"]; ok {
+		return env
+	}
+	return c.Tags["environment"]
+}
+
+// IsProduction reports whether the connection is tagged as a production environment
+func (c *ConnectionConfig) IsProduction() bool {
+	switch strings.ToLower(c.Environment()) {
+	case "prod", "production":
+		return true
+	}
+	return false
 }
 
 type Value interface {
@@ -135,6 +239,22 @@ func (b BoolValue) IsNull() bool {
 	return b.Null
 }
 
+type TimeValue struct {
+	Value time.Time
+	Null  bool
+}
+
+func (t TimeValue) String() string {
+	if t.Null {
+		return ""
+	}
+	return t.Value.Format("2006-01-02 15:04:05-0700")
+}
+
+func (t TimeValue) IsNull() bool {
+	return t.Null
+}
+
 type NullValue struct{}
 
 func (n NullValue) String() string {
@@ -146,14 +266,37 @@ func (n NullValue) IsNull() bool {
 }
 
 type Column struct {
-	Name string
-	Type string
+	Name      string
+	Type      string // Database type name as the driver reports it, such as VARCHAR
+	ScanType  string // Go type the driver scans values into, such as int64; empty when unknown
+	Nullable  *bool  // Whether the column allows NULL; nil when the driver cannot tell
+	Length    int64  // Declared length of variable-length types; 0 when unknown or unbounded
+	Precision int64  // Precision and scale of decimal types; 0 when unknown
+	Scale     int64
+}
+
+// ObservedColumn is what the fetched rows showed of a column
+type ObservedColumn struct {
+	MaxWidth int  // Longest value, in characters
+	Nulls    bool // At least one value was NULL
 }
 
 type QueryResult struct {
-	Columns []Column
-	rows    *sql.Rows
-	err     error
+	Columns    []Column
+	rows       *sql.Rows
+	err        error
+	formatters []ValueFormatter
+	rowCount   int
+	onClose    func(*QueryResult)
+	sampleSize int
+	sample     [][]Value
+	observed   []ObservedColumn
+	// scanned are the columns the query returns when pipe steps have changed Columns
+	scanned []Column
+	steps   []func(iter.Seq[[]Value]) iter.Seq[[]Value]
+	// affected is the count a statement run through Exec reported, when counted is set
+	affected int64
+	counted  bool
 }
 
 func (r *QueryResult) ColumnNames() []string {
@@ -177,20 +320,96 @@ func NewQueryResult(rows *sql.Rows) (*QueryResult, error) {
 	for i, tp := range columnTypes {
 		columns[i] = Column{
 			Name: columnNames[i],
-			Type: tp.Name(),
+			Type: tp.DatabaseTypeName(),
+		}
+		if scanType := tp.ScanType(); scanType != nil {
+			columns[i].ScanType = scanType.String()
+		}
+		if nullable, ok := tp.Nullable(); ok {
+			columns[i].Nullable = &nullable
+		}
+		if length, ok := tp.Length(); ok && length < math.MaxInt32 {
+			columns[i].Length = length
+		}
+		if precision, scale, ok := tp.DecimalSize(); ok {
+			columns[i].Precision, columns[i].Scale = precision, scale
 		}
 	}
 
 	return &QueryResult{
-		Columns: columns,
-		rows:    rows,
+		Columns:    columns,
+		rows:       rows,
+		formatters: DefaultValueFormatters(),
 	}, nil
 }
 
+// newExecResult returns the result of a statement run for its effect, which has no rows
+// but may know how many rows it changed
+func newExecResult(res sql.Result) *QueryResult {
+	affected, err := res.RowsAffected()
+	return &QueryResult{formatters: DefaultValueFormatters(), affected: affected, counted: err == nil}
+}
+
+// RowsAffected returns how many rows an INSERT, UPDATE or DELETE changed. ok is false
+// for queries that return rows and drivers that cannot tell.
+func (r *QueryResult) RowsAffected() (int64, bool) {
+	return r.affected, r.counted
+}
+
 func (r *QueryResult) Close() error {
+	if r.onClose != nil {
+		onClose := r.onClose
+		r.onClose = nil
+		onClose(r)
+	}
+	if r.rows == nil {
+		return nil
+	}
 	return r.rows.Close()
 }
 
+// OnClose registers a callback run once when the result is closed, after rows were consumed
+func (r *QueryResult) OnClose(fn func(*QueryResult)) {
+	r.onClose = fn
+}
+
+// RowCount returns the number of rows fetched so far
+func (r *QueryResult) RowCount() int {
+	return r.rowCount
+}
+
+// KeepSample retains up to n of the first formatted rows as they stream past
+func (r *QueryResult) KeepSample(n int) {
+	r.sampleSize = n
+}
+
+// Sample returns the rows retained by KeepSample
+func (r *QueryResult) Sample() [][]Value {
+	return r.sample
+}
+
+// Observed returns what the rows fetched so far showed of each column
+func (r *QueryResult) Observed() []ObservedColumn {
+	return r.observed
+}
+
+// observe widens the observed columns to fit a row
+func (r *QueryResult) observe(row []Value) {
+	if r.observed == nil {
+		r.observed = make([]ObservedColumn, len(r.Columns))
+	}
+	for i, value := range row {
+		if i >= len(r.observed) {
+			break
+		}
+		if value.IsNull() {
+			r.observed[i].Nulls = true
+		} else if width := utf8.RuneCountInString(value.String()); width > r.observed[i].MaxWidth {
+			r.observed[i].MaxWidth = width
+		}
+	}
+}
+
 func assambleRow(columns []Column, rows *sql.Rows) ([]Value, error) {
 	values := make([]any, len(columns))
 	valuePtrs := make([]any, len(columns))
@@ -220,8 +439,8 @@ func assambleRow(columns []Column, rows *sql.Rows) ([]Value, error) {
 			case bool:
 				row[i] = BoolValue{Value: v}
 			case time.Time:
-				// Format datetime/timestamp to "2006-01-02 15:04:05-0700"
-				row[i] = StringValue{Value: v.Format("2006-01-02 15:04:05-0700")}
+				// Keep the time so display formatters can convert zones
+				row[i] = TimeValue{Value: v}
 			default:
 				row[i] = StringValue{Value: fmt.Sprintf("%v", v)}
 			}
@@ -230,18 +449,51 @@ func assambleRow(columns []Column, rows *sql.Rows) ([]Value, error) {
 	return row, nil
 }
 
+// Itor yields the formatted rows, after any pipe steps
 func (r *QueryResult) Itor() iter.Seq[[]Value] {
+	rows := r.fetch()
+	for _, step := range r.steps {
+		rows = step(rows)
+	}
+	return func(yield func([]Value) bool) {
+		for row := range rows {
+			if len(r.sample) < r.sampleSize {
+				r.sample = append(r.sample, row)
+			}
+			r.observe(row)
+			if !yield(row) {
+				return
+			}
+		}
+	}
+}
+
+// fetch yields the rows of the query as they are scanned, counting and formatting them
+func (r *QueryResult) fetch() iter.Seq[[]Value] {
+	columns := r.Columns
+	if r.scanned != nil {
+		columns = r.scanned
+	}
 	return func(yield func([]Value) bool) {
+		if r.rows == nil {
+			return
+		}
 		for r.rows.Next() {
-			row, err := assambleRow(r.Columns, r.rows)
+			row, err := assambleRow(columns, r.rows)
 			if err != nil {
 				r.err = err
 				return
 			}
-			if !yield(row) {
+			r.rowCount++
+			if !yield(r.formatRow(columns, row)) {
 				return
 			}
 		}
+		// Errors met while stepping through rows, such as a failed constraint when
+		// SQLite runs an INSERT, only show here
+		if err := r.rows.Err(); err != nil {
+			r.err = err
+		}
 	}
 }
 
@@ -249,6 +501,24 @@ func (r *QueryResult) Error() error {
 	return r.err
 }
 
+// ErrStopRows ends ForEachRow early without reporting an error
+var ErrStopRows = errors.New("stop reading rows")
+
+// ForEachRow passes rows to fn as they are fetched, never holding more than one, so
+// exporters handle results of any size. It stops at the first error from fn, or
+// quietly when fn returns ErrStopRows, and otherwise returns the fetch error if any.
+func (r *QueryResult) ForEachRow(fn func(row []Value) error) error {
+	for row := range r.Itor() {
+		if err := fn(row); err != nil {
+			if errors.Is(err, ErrStopRows) {
+				return nil
+			}
+			return err
+		}
+	}
+	return r.Error()
+}
+
 type TableInfo struct {
 	Name        string
 	Columns     []ColumnInfo
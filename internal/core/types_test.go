@@ -1,6 +1,8 @@
 package core
 
 import (
+	"errors"
+	"strings"
 	"testing"
 )
 
@@ -47,6 +49,12 @@ func TestParseDatabaseType(t *testing.T) {
 			expected: SQLite,
 			hasError: false,
 		},
+		{
+			name:     "DuckDB",
+			input:    "duckdb",
+			expected: DuckDB,
+			hasError: false,
+		},
 		{
 			name:     "Invalid type",
 			input:    "mongodb",
@@ -136,6 +144,11 @@ func TestDatabaseType_String(t *testing.T) {
 			dbType:   SQLite,
 			expected: "sqlite",
 		},
+		{
+			name:     "DuckDB string representation",
+			dbType:   DuckDB,
+			expected: "duckdb",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -380,3 +393,100 @@ func TestGenerateNumberedCSVPath(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionConfigTags(t *testing.T) {
+	cfg := &ConnectionConfig{
+		Name: "billing-prod",
+		Tags: map[string]string{"env": "prod", "team": "billing"},
+	}
+
+	testCases := []struct {
+		name     string
+		filter   string
+		expected bool
+	}{
+		{name: "Value match", filter: "prod", expected: true},
+		{name: "Key match", filter: "team", expected: true},
+		{name: "Key value match", filter: "team=billing", expected: true},
+		{name: "Key value case insensitive", filter: "env=PROD", expected: true},
+		{name: "Key value mismatch", filter: "env=staging", expected: false},
+		{name: "Unknown tag", filter: "analytics", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := cfg.HasTag(tc.filter); got != tc.expected {
+				t.Errorf("HasTag(%q) = %v, expected %v", tc.filter, got, tc.expected)
+			}
+		})
+	}
+
+	if !cfg.IsProduction() {
+		t.Error("Expected env=prod connection to be production")
+	}
+	if cfg.FormatTags() != "env=prod, team=billing" {
+		t.Errorf("Unexpected tag format: %s", cfg.FormatTags())
+	}
+
+	untagged := &ConnectionConfig{Name: "dev"}
+	if untagged.IsProduction() || untagged.HasTag("prod") || untagged.Environment() != "" {
+		t.Error("Untagged connection should not match any tag")
+	}
+}
+
+func TestQueryResultKeepSample(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	result, err := conn.Execute("SELECT 1 UNION ALL SELECT 2 UNION ALL SELECT 3")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	result.KeepSample(2)
+	for range result.Itor() {
+	}
+	result.Close()
+
+	sample := result.Sample()
+	if len(sample) != 2 || sample[0][0].String() != "1" || sample[1][0].String() != "2" {
+		t.Errorf("Expected first 2 rows, got %v", sample)
+	}
+	if result.RowCount() != 3 {
+		t.Errorf("Expected 3 rows counted, got %d", result.RowCount())
+	}
+}
+
+func TestQueryResult_ForEachRow(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn, "CREATE TABLE t (id INTEGER)", "INSERT INTO t VALUES (1), (2), (3)")
+
+	read := func(fn func(row []Value) error) (int, error) {
+		result, err := conn.Execute("SELECT id FROM t ORDER BY id")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer result.Close()
+		err = result.ForEachRow(fn)
+		return result.RowCount(), err
+	}
+
+	var ids []string
+	if n, err := read(func(row []Value) error {
+		ids = append(ids, row[0].String())
+		return nil
+	}); err != nil || n != 3 || strings.Join(ids, ",") != "1,2,3" {
+		t.Errorf("Expected rows 1,2,3, got %v (%d rows, %v)", ids, n, err)
+	}
+
+	if n, err := read(func(row []Value) error {
+		if row[0].String() == "2" {
+			return ErrStopRows
+		}
+		return nil
+	}); err != nil || n != 2 {
+		t.Errorf("Expected ErrStopRows to stop quietly after 2 rows, got %d rows, %v", n, err)
+	}
+
+	failure := errors.New("disk full")
+	if n, err := read(func(row []Value) error { return failure }); !errors.Is(err, failure) || n != 1 {
+		t.Errorf("Expected the callback error after 1 row, got %d rows, %v", n, err)
+	}
+}
@@ -0,0 +1,39 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// variableNamePattern is what a session variable may be called
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// variableRefPattern finds {{name}} references, allowing spaces inside the braces
+var variableRefPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ValidVariableName reports whether name can be set and referenced as {{name}}
+func ValidVariableName(name string) bool {
+	return variableNamePattern.MatchString(name)
+}
+
+// ExpandVariables replaces each {{name}} in a query with the variable's value as is, so
+// text values need quotes around the reference: '{{name}}'. A reference to a variable
+// that is not set is an error rather than being left in the query.
+func ExpandVariables(query string, vars map[string]string) (string, error) {
+	var missing string
+	expanded := variableRefPattern.ReplaceAllStringFunc(query, func(ref string) string {
+		name := variableRefPattern.FindStringSubmatch(ref)[1]
+		value, ok := vars[name]
+		if !ok {
+			if missing == "" {
+				missing = name
+			}
+			return ref
+		}
+		return value
+	})
+	if missing != "" {
+		return "", fmt.Errorf("variable {{%s}} is not set", missing)
+	}
+	return expanded, nil
+}
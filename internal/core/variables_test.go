@@ -0,0 +1,52 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandVariables(t *testing.T) {
+	vars := map[string]string{"last_id": "42", "region": "APAC"}
+
+	testCases := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr string
+	}{
+		{
+			name:  "number and quoted text",
+			query: "SELECT * FROM orders WHERE id > {{last_id}} AND region = '{{ region }}'",
+			want:  "SELECT * FROM orders WHERE id > 42 AND region = 'APAC'",
+		},
+		{
+			name:  "no references",
+			query: "SELECT '{' || '}'",
+			want:  "SELECT '{' || '}'",
+		},
+		{
+			name:    "unknown variable",
+			query:   "SELECT {{last_id}} + {{missing}}",
+			wantErr: "{{missing}} is not set",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ExpandVariables(tc.query, vars)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Errorf("ExpandVariables() = %q, %v; want %q", got, err, tc.want)
+			}
+		})
+	}
+
+	if ValidVariableName("1st") || ValidVariableName("a-b") || !ValidVariableName("_id2") {
+		t.Error("Unexpected variable name validation")
+	}
+}
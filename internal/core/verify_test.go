@@ -0,0 +1,79 @@
+package core
+
+import "testing"
+
+func TestVerifyTables(t *testing.T) {
+	left := newTestSQLiteConnection(t)
+	right := newTestSQLiteConnection(t)
+	for _, conn := range []Connection{left, right} {
+		mustExec(t, conn, "CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT)")
+		mustExec(t, conn, "INSERT INTO events VALUES (1, 'a'), (2, 'b'), (3, 'c'), (4, 'd'), (5, 'e')")
+	}
+
+	report, err := VerifyTables(left, right, "events", "events", []string{"id"}, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !report.OK() || len(report.Chunks) != 3 {
+		t.Errorf("Expected 3 matching chunks, got %+v", report)
+	}
+
+	// Change a row in the middle chunk and add one past the end
+	mustExec(t, right, "UPDATE events SET kind = 'x' WHERE id = 3", "INSERT INTO events VALUES (9, 'z')")
+
+	report, err = VerifyTables(left, right, "events", "events", []string{"id"}, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.OK() || report.LeftCount != 5 || report.RightCount != 6 {
+		t.Errorf("Expected count mismatch, got %d vs %d", report.LeftCount, report.RightCount)
+	}
+
+	mismatched := report.Mismatched()
+	if len(mismatched) != 2 {
+		t.Fatalf("Expected 2 mismatched chunks, got %+v", mismatched)
+	}
+	if mismatched[0].FirstKey[0] != "3" || mismatched[0].LastKey[0] != "4" {
+		t.Errorf("Expected chunk 3..4 to differ, got %+v", mismatched[0])
+	}
+	if mismatched[1].LastKey != nil || mismatched[1].LeftRows != 1 || mismatched[1].RightRows != 2 {
+		t.Errorf("Expected open-ended final chunk with the extra row, got %+v", mismatched[1])
+	}
+}
+
+func TestVerifyTablesRightOnly(t *testing.T) {
+	left := newTestSQLiteConnection(t)
+	right := newTestSQLiteConnection(t)
+	mustExec(t, left, "CREATE TABLE t (id INTEGER PRIMARY KEY)")
+	mustExec(t, right, "CREATE TABLE t (id INTEGER PRIMARY KEY)", "INSERT INTO t VALUES (1)")
+
+	report, err := VerifyTables(left, right, "t", "t", []string{"id"}, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.OK() || len(report.Chunks) != 1 || report.Chunks[0].RightRows != 1 {
+		t.Errorf("Expected a single mismatched chunk, got %+v", report.Chunks)
+	}
+}
+
+func TestVerifyTablesQuotedNames(t *testing.T) {
+	left := newTestSQLiteConnection(t)
+	right := newTestSQLiteConnection(t)
+	for _, conn := range []Connection{left, right} {
+		mustExec(t, conn, `CREATE TABLE "order" ("group" INTEGER, "line no" INTEGER, total TEXT, PRIMARY KEY ("group", "line no"))`)
+		mustExec(t, conn, `INSERT INTO "order" VALUES (1, 1, 'a'), (1, 2, 'b'), (2, 1, 'c')`)
+	}
+	mustExec(t, right, `UPDATE "order" SET total = 'x' WHERE "group" = 2`)
+
+	report, err := VerifyTables(left, right, "order", "order", []string{"group", "line no"}, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	mismatched := report.Mismatched()
+	if len(mismatched) != 1 || mismatched[0].FirstKey[0] != "2" || mismatched[0].LastKey != nil {
+		t.Errorf("Expected only the final chunk to differ, got %+v", report.Chunks)
+	}
+	if count, err := CountRows(left, "order"); err != nil || count != 3 {
+		t.Errorf("Expected 3 rows, got %d (%v)", count, err)
+	}
+}
@@ -0,0 +1,27 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// DisplayWidth returns the number of terminal columns a string occupies. CJK and other
+// full-width characters take two columns, so byte or rune counts misalign tables.
+func DisplayWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// PadRight pads s with spaces to the given display width
+func PadRight(s string, width int) string {
+	if pad := width - DisplayWidth(s); pad > 0 {
+		return s + strings.Repeat(" ", pad)
+	}
+	return s
+}
+
+// TruncateWidth shortens s to at most width display columns, ending with "..." when cut.
+// It never splits a multi-byte character.
+func TruncateWidth(s string, width int) string {
+	return runewidth.Truncate(s, width, "...")
+}
@@ -0,0 +1,33 @@
+package core
+
+import "testing"
+
+func TestDisplayWidth(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		width    int
+		padded   string
+		truncate string
+	}{
+		{name: "ASCII", input: "name", width: 4, padded: "name    ", truncate: "name"},
+		{name: "CJK is double width", input: "名称", width: 4, padded: "名称    ", truncate: "名称"},
+		{name: "Mixed", input: "id编号", width: 6, padded: "id编号  ", truncate: "id编号"},
+		{name: "Full-width punctuation", input: "（值）", width: 6, padded: "（值）  ", truncate: "（值）"},
+		{name: "Truncated on character boundary", input: "用户名称列表", width: 12, padded: "用户名称列表", truncate: "用户..."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DisplayWidth(tc.input); got != tc.width {
+				t.Errorf("Expected width %d, got %d", tc.width, got)
+			}
+			if got := PadRight(tc.input, 8); got != tc.padded {
+				t.Errorf("Expected padded %q, got %q", tc.padded, got)
+			}
+			if got := TruncateWidth(tc.input, 8); got != tc.truncate {
+				t.Errorf("Expected truncated %q, got %q", tc.truncate, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// xlsxMaxRows and xlsxMaxCellLength are Excel's limits for a worksheet
+	xlsxMaxRows       = 1048576
+	xlsxMaxCellLength = 32767
+)
+
+// ErrTooManyRowsForXLSX is returned when a result does not fit in one worksheet
+var ErrTooManyRowsForXLSX = errors.New("result has more rows than an Excel worksheet holds")
+
+// xlsxParts are the workbook files around the worksheet, which is streamed
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Result" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`},
+}
+
+// SaveQueryResultAsXLSX streams a result into an Excel workbook with one worksheet, a
+// header row followed by the rows. Numbers and booleans are stored as such and other
+// values as text.
+func SaveQueryResultAsXLSX(result *QueryResult, filePath string) (int, error) {
+	count := 0
+	defer result.Close()
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return count, fmt.Errorf("failed to create XLSX file: %w", err)
+	}
+	archive := zip.NewWriter(file)
+	fail := func(err error) (int, error) {
+		archive.Close()
+		file.Close()
+		os.Remove(filePath)
+		return count, err
+	}
+
+	for _, part := range xlsxParts {
+		w, err := archive.Create(part.name)
+		if err != nil {
+			return fail(err)
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return fail(err)
+		}
+	}
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return fail(err)
+	}
+	writer := bufio.NewWriter(sheet)
+	writer.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	header := make([]Value, len(result.Columns))
+	for i, name := range result.DisplayColumnNames() {
+		header[i] = StringValue{Value: name}
+	}
+	writeXLSXRow(writer, 1, header)
+	err = result.ForEachRow(func(row []Value) error {
+		if count+2 > xlsxMaxRows {
+			return ErrTooManyRowsForXLSX
+		}
+		count++
+		writeXLSXRow(writer, count+1, row)
+		return nil
+	})
+	if err != nil {
+		if result.Error() != nil {
+			return fail(fmt.Errorf("failed to fetch data: %w", err))
+		}
+		return fail(err)
+	}
+
+	writer.WriteString("</sheetData></worksheet>")
+	if err := writer.Flush(); err != nil {
+		return fail(err)
+	}
+	if err := archive.Close(); err != nil {
+		return fail(err)
+	}
+	return count, file.Close()
+}
+
+func writeXLSXRow(w *bufio.Writer, number int, row []Value) {
+	fmt.Fprintf(w, `<row r="%d">`, number)
+	for i, val := range row {
+		ref := xlsxColumn(i) + strconv.Itoa(number)
+		switch v := val.(type) {
+		case IntValue:
+			if !v.Null {
+				fmt.Fprintf(w, `<c r="%s"><v>%d</v></c>`, ref, v.Value)
+			}
+		case FloatValue:
+			if !v.Null {
+				fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v.Value, 'g', -1, 64))
+			}
+		case BoolValue:
+			if !v.Null {
+				b := 0
+				if v.Value {
+					b = 1
+				}
+				fmt.Fprintf(w, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+			}
+		default:
+			if val.IsNull() {
+				continue
+			}
+			text := val.String()
+			if len(text) > xlsxMaxCellLength {
+				text = strings.ToValidUTF8(text[:xlsxMaxCellLength], "")
+			}
+			fmt.Fprintf(w, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+			xml.EscapeText(w, []byte(text))
+			w.WriteString("</t></is></c>")
+		}
+	}
+	w.WriteString("</row>")
+}
+
+// xlsxColumn is the letter name of a zero-based column: A, B, ..., Z, AA, AB, ...
+func xlsxColumn(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package core
+
+import (
+	"archive/zip"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveQueryResultAsXLSX(t *testing.T) {
+	conn := newTestSQLiteConnection(t)
+	mustExec(t, conn,
+		"CREATE TABLE sales (region TEXT, total REAL, orders INTEGER)",
+		"INSERT INTO sales VALUES ('APAC <east> & co', 12.5, 3), (NULL, NULL, 4)",
+	)
+	result, err := conn.Execute("SELECT region, total, orders FROM sales ORDER BY orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "report.xlsx")
+	rows, err := SaveQueryResultToFile(result, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows != 2 {
+		t.Errorf("Expected 2 rows, got %d", rows)
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	var sheet string
+	for _, file := range archive.File {
+		if file.Name == "xl/worksheets/sheet1.xml" {
+			r, _ := file.Open()
+			data, _ := io.ReadAll(r)
+			r.Close()
+			sheet = string(data)
+		}
+	}
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr"><is><t xml:space="preserve">region</t></is></c>`,
+		`<t xml:space="preserve">APAC &lt;east&gt; &amp; co</t>`,
+		`<c r="B2"><v>12.5</v></c><c r="C2"><v>3</v></c>`,
+		`<row r="3"><c r="C3"><v>4</v></c></row>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("Expected worksheet to contain %s, got\n%s", want, sheet)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumn(index); got != want {
+			t.Errorf("xlsxColumn(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
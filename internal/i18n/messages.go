@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 )
 
 //go:embed *.json
@@ -34,13 +35,13 @@ func NewManager(language string) (*Manager, error) {
 		return nil, fmt.Errorf("language cannot be empty")
 	}
 
+	messages, err := loadMessages()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load messages: %w", err)
+	}
 	manager := &Manager{
 		currentLanguage: language,
-		messages:        make(map[string]map[string]string),
-	}
-
-	if err := manager.loadMessages(); err != nil {
-		return nil, fmt.Errorf("failed to load messages: %w", err)
+		messages:        messages,
 	}
 
 	// Validate that the requested language exists
@@ -51,13 +52,15 @@ func NewManager(language string) (*Manager, error) {
 	return manager, nil
 }
 
-// loadMessages loads all message files from embedded filesystem
-func (m *Manager) loadMessages() error {
+// loadMessages parses the embedded message files on first use. Managers share the
+// result, which is never modified, so creating one after the first is cheap.
+var loadMessages = sync.OnceValues(func() (map[string]map[string]string, error) {
 	files, err := messageFiles.ReadDir(".")
 	if err != nil {
-		return fmt.Errorf("failed to read message files: %w", err)
+		return nil, fmt.Errorf("failed to read message files: %w", err)
 	}
 
+	all := make(map[string]map[string]string)
 	for _, file := range files {
 		if !strings.HasSuffix(file.Name(), ".json") {
 			continue
@@ -68,25 +71,25 @@ func (m *Manager) loadMessages() error {
 
 		content, err := messageFiles.ReadFile(file.Name())
 		if err != nil {
-			return fmt.Errorf("failed to read message file %s: %w", file.Name(), err)
+			return nil, fmt.Errorf("failed to read message file %s: %w", file.Name(), err)
 		}
 
 		var messages Messages
 		if err := json.Unmarshal(content, &messages); err != nil {
-			return fmt.Errorf("failed to parse message file %s: %w", file.Name(), err)
+			return nil, fmt.Errorf("failed to parse message file %s: %w", file.Name(), err)
 		}
 
 		// Build message map for this language
-		langMessages := make(map[string]string)
+		langMessages := make(map[string]string, len(messages.Messages))
 		for _, msg := range messages.Messages {
 			langMessages[msg.ID] = msg.Text
 		}
 
-		m.messages[language] = langMessages
+		all[language] = langMessages
 	}
 
-	return nil
-}
+	return all, nil
+})
 
 // Get retrieves a localized message by ID
 func (m *Manager) Get(messageID string) string {
@@ -108,6 +111,15 @@ func (m *Manager) Get(messageID string) string {
 	return fmt.Sprintf("[%s]", messageID)
 }
 
+// Has reports whether a message exists in the current language or in English
+func (m *Manager) Has(messageID string) bool {
+	if _, exists := m.messages[m.currentLanguage][messageID]; exists {
+		return true
+	}
+	_, exists := m.messages["en_au"][messageID]
+	return exists
+}
+
 // GetWithArgs retrieves a localized message by ID and formats it with arguments
 func (m *Manager) GetWithArgs(messageID string, args ...interface{}) string {
 	message := m.Get(messageID)
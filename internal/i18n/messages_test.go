@@ -1,6 +1,7 @@
 package i18n
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -284,6 +285,77 @@ func TestManager_MessageConsistency(t *testing.T) {
 	}
 }
 
+func TestManager_FormatParity(t *testing.T) {
+	// Every translation must take the English message's arguments, in its order, or
+	// Printf shows %!d(string=...) and the like instead of the text
+	messages, err := loadMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, english := range messages["en_au"] {
+		args := sampleArgs(english)
+		for language, texts := range messages {
+			text, ok := texts[id]
+			if !ok {
+				continue
+			}
+			if len(args) == 0 {
+				// Not a format: headings such as "Null %" are printed as they are
+				if len(sampleArgs(text)) != 0 {
+					t.Errorf("%s message %s takes arguments the English one does not: %q", language, id, text)
+				}
+				continue
+			}
+			if out := fmt.Errorf(text, args...).Error(); strings.Contains(out, "%!") {
+				t.Errorf("%s message %s does not take the English arguments: %q", language, id, out)
+			}
+		}
+	}
+}
+
+// sampleArgs returns an argument of the verb's type for each argument a format takes
+func sampleArgs(format string) []any {
+	var args []any
+	next := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.ContainsRune("+-# 0123456789.", rune(format[i])) {
+			i++
+		}
+		if i < len(format) && format[i] == '[' {
+			end := strings.IndexByte(format[i:], ']')
+			fmt.Sscanf(format[i+1:i+end], "%d", &next)
+			next--
+			i += end + 1
+		}
+		if i >= len(format) || format[i] == '%' {
+			continue
+		}
+		var arg any
+		switch format[i] {
+		case 'd', 'x', 'X', 'o', 'c', 'b':
+			arg = 7
+		case 'f', 'g', 'e':
+			arg = 1.5
+		case 't':
+			arg = true
+		case 'w':
+			arg = errors.New("sample")
+		default:
+			arg = "sample"
+		}
+		for len(args) <= next {
+			args = append(args, nil)
+		}
+		args[next] = arg
+		next++
+	}
+	return args
+}
+
 func TestManager_ErrorHandling(t *testing.T) {
 	manager, err := NewManager("en_au")
 	if err != nil {
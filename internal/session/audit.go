@@ -0,0 +1,221 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AuditEntry is one executed statement in the audit log. Entries are chained: each
+// carries the hash of the one before, so an edited, removed or reordered line breaks
+// the chain from that point on.
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`              // Operating system user running sqlterm
+	Host       string    `json:"host"`              // Machine sqlterm runs on
+	Connection string    `json:"connection"`        // Saved connection name
+	DBUser     string    `json:"db_user,omitempty"` // User the connection logs in as
+	Database   string    `json:"database,omitempty"`
+	Statement  string    `json:"statement"`
+	Rows       int       `json:"rows"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Approver   string    `json:"approver,omitempty"` // Second person who approved the statement, see core.ApprovalBundle
+	Approval   string    `json:"approval,omitempty"` // ID of the approval bundle
+	Prev       string    `json:"prev"`               // Hash of the previous entry; empty for the first
+	Hash       string    `json:"hash"`               // SHA-256 of this entry with an empty hash
+}
+
+// AuditLog appends entries as JSON lines to a file, to syslog, or both. Several
+// processes may append to the same file: each entry is chained to the file's last line
+// while holding a lock on it.
+type AuditLog struct {
+	mu     sync.Mutex
+	file   *os.File
+	syslog io.WriteCloser
+}
+
+// OpenAuditLog opens the audit log file for appending, creating it if needed, and
+// connects to syslog when asked. An empty path only writes to syslog.
+func OpenAuditLog(path string, useSyslog bool) (*AuditLog, error) {
+	log := &AuditLog{}
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		// A log whose last line is not an entry could not be chained to
+		if _, err := lastHash(file); err != nil {
+			file.Close()
+			return nil, err
+		}
+		log.file = file
+	}
+	if useSyslog {
+		writer, err := openSyslog()
+		if err != nil {
+			log.Close()
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		log.syslog = writer
+	}
+	return log, nil
+}
+
+// Record chains an entry to the one before it and appends it. The file stays locked from
+// reading its last entry to writing the new one, so another sqlterm appending to the
+// same log cannot slip an entry in between.
+func (l *AuditLog) Record(entry AuditEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Time = entry.Time.UTC()
+	if l.file != nil {
+		if err := lockFile(l.file); err != nil {
+			return fmt.Errorf("failed to lock audit log: %w", err)
+		}
+		defer unlockFile(l.file)
+		prev, err := lastHash(l.file)
+		if err != nil {
+			return err
+		}
+		entry.Prev = prev
+	}
+	hash, err := entry.hash()
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+	}
+	if l.syslog != nil {
+		if _, err := l.syslog.Write(line); err != nil {
+			return fmt.Errorf("failed to write audit log to syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the file and the syslog connection
+func (l *AuditLog) Close() error {
+	var errs []error
+	if l.file != nil {
+		errs = append(errs, l.file.Close())
+	}
+	if l.syslog != nil {
+		errs = append(errs, l.syslog.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// hash returns the SHA-256 of the entry as JSON with an empty Hash
+func (e AuditEntry) hash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// VerifyAuditLog checks the hash chain of an audit log file, returning the number of
+// entries when it is intact and the first broken line otherwise
+func VerifyAuditLog(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64<<20)
+	prev := ""
+	count := 0
+	for scanner.Scan() {
+		count++
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return count - 1, fmt.Errorf("line %d: not an audit entry: %w", count, err)
+		}
+		if entry.Prev != prev {
+			return count - 1, fmt.Errorf("line %d: does not follow the entry before it; entries were removed, reordered or inserted", count)
+		}
+		if hash, err := entry.hash(); err != nil || hash != entry.Hash {
+			return count - 1, fmt.Errorf("line %d: content does not match its hash; the entry was edited", count)
+		}
+		prev = entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return count, err
+	}
+	return count, nil
+}
+
+// lastHash returns the hash of the last entry in an audit log file, or "" when it is empty
+func lastHash(file *os.File) (string, error) {
+	last, err := lastLine(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if len(last) == 0 {
+		return "", nil
+	}
+	var entry AuditEntry
+	if err := json.Unmarshal(last, &entry); err != nil {
+		return "", fmt.Errorf("failed to read the last audit log entry: %w", err)
+	}
+	return entry.Hash, nil
+}
+
+// lastLine returns the last non-empty line of a file, reading backwards from the end
+// so large logs are not read in full
+func lastLine(file *os.File) ([]byte, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	const chunk = 64 << 10
+	var tail []byte
+	for offset := end; offset > 0; {
+		size := int64(chunk)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+		buf := make([]byte, size)
+		if _, err := file.ReadAt(buf, offset); err != nil {
+			return nil, err
+		}
+		tail = append(buf, tail...)
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if offset == 0 {
+			return trimmed, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package session
+
+import (
+	"errors"
+	"io"
+)
+
+// openSyslog fails where there is no syslog; write the audit log to a file instead
+func openSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not available on this system")
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+
+	log, err := OpenAuditLog(path, false)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	for _, statement := range []string{"SELECT 1", "UPDATE orders SET status = 'paid' WHERE id = 7"} {
+		if err := log.Record(AuditEntry{Time: time.Now(), User: "ana", Connection: "prod", Statement: statement, Rows: 1}); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	log.Close()
+
+	// A reopened log continues the chain
+	log, err = OpenAuditLog(path, false)
+	if err != nil {
+		t.Fatalf("Failed to reopen audit log: %v", err)
+	}
+	if err := log.Record(AuditEntry{Time: time.Now(), Connection: "prod", Statement: "DELETE FROM sessions", Error: "permission denied"}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	log.Close()
+
+	// Two logs open on the same file, as two sqlterms would have, keep one chain
+	first, err := OpenAuditLog(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := OpenAuditLog(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, log := range []*AuditLog{first, second, first} {
+		if err := log.Record(AuditEntry{Time: time.Now(), Connection: "prod", Statement: fmt.Sprintf("SELECT %d", i)}); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+	first.Close()
+	second.Close()
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the audit log readable by its owner only, got %v", info.Mode().Perm())
+	}
+	if count, err := VerifyAuditLog(path); err != nil || count != 6 {
+		t.Fatalf("Expected 6 intact entries, got %d, %v", count, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	lines := strings.SplitAfter(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	edited := strings.Replace(string(data), "id = 7", "id = 8", 1)
+	os.WriteFile(path, []byte(edited), 0600)
+	if count, err := VerifyAuditLog(path); err == nil || count != 1 || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected the edited second entry found, got %d, %v", count, err)
+	}
+
+	os.WriteFile(path, []byte(lines[0]+lines[2]), 0600)
+	if _, err := VerifyAuditLog(path); err == nil || !strings.Contains(err.Error(), "removed") {
+		t.Errorf("Expected the removed entry found, got %v", err)
+	}
+}
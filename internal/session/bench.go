@@ -0,0 +1,56 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sqlterm/internal/core"
+)
+
+// BenchFile holds one line of JSON per /bench run on a connection
+const BenchFile = "benchmarks.jsonl"
+
+// SaveBenchmark appends a benchmark to its connection's results
+func (m *Manager) SaveBenchmark(result *core.BenchResult) error {
+	if err := m.EnsureSessionDir(result.Connection); err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(m.GetSessionDir(result.Connection), BenchFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// LoadBenchmarks reads the benchmarks saved for a connection, oldest first
+func (m *Manager) LoadBenchmarks(connectionName string) ([]core.BenchResult, error) {
+	file, err := os.Open(filepath.Join(m.GetSessionDir(connectionName), BenchFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []core.BenchResult
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var result core.BenchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			return results, fmt.Errorf("%s line %d: %w", BenchFile, line, err)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
@@ -0,0 +1,28 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+func TestBenchmarks(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	if results, err := manager.LoadBenchmarks("prod"); err != nil || results != nil {
+		t.Fatalf("Expected no benchmarks, got %v, %v", results, err)
+	}
+	for _, query := range []string{"SELECT 1", "SELECT 2"} {
+		result := &core.BenchResult{Query: query, Connection: "prod", Runs: 10, Avg: 5 * time.Millisecond}
+		if err := manager.SaveBenchmark(result); err != nil {
+			t.Fatalf("Failed to save benchmark: %v", err)
+		}
+	}
+	results, err := manager.LoadBenchmarks("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[1].Query != "SELECT 2" || results[0].Avg != 5*time.Millisecond {
+		t.Errorf("Unexpected benchmarks %+v", results)
+	}
+}
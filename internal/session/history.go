@@ -0,0 +1,138 @@
+package session
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GlobalHistoryFile is the line editor history used while no connection is open
+const GlobalHistoryFile = "global_history.txt"
+
+// HistoryMatch is a line of a history file containing a search term
+type HistoryMatch struct {
+	Connection string    // Empty for the global history
+	Line       string    // The line as typed
+	LastRun    time.Time // When the query log last ran it; zero for commands and unlogged lines
+}
+
+// HistoryFile returns the line editor history of a connection, or the global history
+// for an empty name
+func (m *Manager) HistoryFile(connectionName string) string {
+	if connectionName == "" {
+		return filepath.Join(m.dataDir, "sessions", GlobalHistoryFile)
+	}
+	return filepath.Join(m.GetSessionDir(connectionName), "history.txt")
+}
+
+// HistoryConnections lists the connections that have a history file
+func (m *Manager) HistoryConnections() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(m.dataDir, "sessions"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(m.HistoryFile(entry.Name())); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// SearchHistory returns the distinct lines of the named histories ("" is the global
+// one) containing term, ignoring case. Each connection's lines are in the order they
+// were last typed, with the time its query log last ran them.
+func (m *Manager) SearchHistory(term string, connectionNames []string) ([]HistoryMatch, error) {
+	term = strings.ToLower(term)
+	var matches []HistoryMatch
+	for _, name := range connectionNames {
+		lines, err := readHistoryFile(m.HistoryFile(name))
+		if err != nil {
+			return nil, err
+		}
+		var found []HistoryMatch
+		for _, line := range lines {
+			if strings.Contains(strings.ToLower(line), term) {
+				found = append(found, HistoryMatch{Connection: name, Line: line})
+			}
+		}
+		if len(found) > 0 && name != "" {
+			m.addLastRun(name, found)
+		}
+		matches = append(matches, found...)
+	}
+	return matches, nil
+}
+
+// readHistoryFile returns the distinct lines of a history file, each where it was last
+// typed. A missing file has no lines.
+func readHistoryFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	last := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		last[line] = len(lines)
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	distinct := lines[:0]
+	for i, line := range lines {
+		if last[line] == i {
+			distinct = append(distinct, line)
+		}
+	}
+	return distinct, nil
+}
+
+// addLastRun looks the matches up in the connection's query log, which holds the
+// statements without the /exec typed before them. A connection without a log is left
+// without times.
+func (m *Manager) addLastRun(connectionName string, matches []HistoryMatch) {
+	path := filepath.Join(m.GetSessionDir(connectionName), "session.db")
+	if _, err := os.Stat(path); err != nil {
+		return
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	for i := range matches {
+		query := strings.TrimSpace(strings.TrimPrefix(matches[i].Line, "/exec "))
+		var startedAt time.Time
+		err := db.QueryRow("SELECT started_at FROM query_log WHERE query = ? ORDER BY id DESC LIMIT 1", query).Scan(&startedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			// An older session database without a query log has no times to offer
+			return
+		}
+		matches[i].LastRun = startedAt
+	}
+}
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSearchHistory(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+
+	log, err := manager.OpenQueryLog("shop")
+	if err != nil {
+		t.Fatalf("Failed to open query log: %v", err)
+	}
+	ran := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	if err := log.Record(QueryLogEntry{Query: "SELECT * FROM order_items;", StartedAt: ran}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	log.Close()
+
+	files := map[string]string{
+		"shop": "/exec SELECT * FROM order_items;\n/describe ORDER_ITEMS\n/tables\n/exec SELECT * FROM order_items;\n",
+		"":     "/connect shop\n/help order_items\n",
+	}
+	for name, content := range files {
+		path := manager.HistoryFile(name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names, err := manager.HistoryConnections()
+	if err != nil || len(names) != 1 || names[0] != "shop" {
+		t.Fatalf("Expected the shop history, got %v, %v", names, err)
+	}
+
+	matches, err := manager.SearchHistory("order_items", append(names, ""))
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("Expected 3 distinct matches, got %+v", matches)
+	}
+	if matches[0].Line != "/describe ORDER_ITEMS" || !matches[0].LastRun.IsZero() {
+		t.Errorf("Expected the command first and without a time, got %+v", matches[0])
+	}
+	if matches[1].Line != "/exec SELECT * FROM order_items;" || !matches[1].LastRun.Equal(ran) {
+		t.Errorf("Expected the query last with the time it ran, got %+v", matches[1])
+	}
+	if matches[2].Connection != "" || matches[2].Line != "/help order_items" {
+		t.Errorf("Expected the global match, got %+v", matches[2])
+	}
+}
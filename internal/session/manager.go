@@ -13,24 +13,39 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// Manager keeps the per-connection session folders under <data dir>/sessions
 type Manager struct {
-	configDir string
-	i18nMgr   *i18n.Manager
+	dataDir string
+	i18nMgr *i18n.Manager
 }
 
 type SessionConfig struct {
 	CleanupRetentionDays int `yaml:"cleanup_retention_days"`
 }
 
-func NewManager(configDir string, i18nMgr *i18n.Manager) *Manager {
+func NewManager(dataDir string, i18nMgr *i18n.Manager) *Manager {
 	return &Manager{
-		configDir: configDir,
-		i18nMgr:   i18nMgr,
+		dataDir: dataDir,
+		i18nMgr: i18nMgr,
 	}
 }
 
 func (m *Manager) GetSessionDir(connectionName string) string {
-	return filepath.Join(m.configDir, "sessions", connectionName)
+	return filepath.Join(m.dataDir, "sessions", connectionName)
+}
+
+// RenameSessionDir moves a connection's results, AI index and settings to follow a
+// renamed connection. A connection without session data is left alone.
+func (m *Manager) RenameSessionDir(oldName, newName string) error {
+	oldDir := m.GetSessionDir(oldName)
+	if _, err := os.Stat(oldDir); os.IsNotExist(err) {
+		return nil
+	}
+	newDir := m.GetSessionDir(newName)
+	if _, err := os.Stat(newDir); err == nil {
+		return fmt.Errorf("session directory %s already exists", newDir)
+	}
+	return os.Rename(oldDir, newDir)
 }
 
 func (m *Manager) EnsureSessionDir(connectionName string) error {
@@ -26,8 +26,8 @@ func TestNewManager(t *testing.T) {
 		t.Fatal("NewManager returned nil")
 	}
 
-	if manager.configDir != tmpDir {
-		t.Errorf("Expected configDir to be '%s', got '%s'", tmpDir, manager.configDir)
+	if manager.dataDir != tmpDir {
+		t.Errorf("Expected dataDir to be '%s', got '%s'", tmpDir, manager.dataDir)
 	}
 }
 
@@ -115,6 +115,35 @@ func TestManager_EnsureSessionDir(t *testing.T) {
 	}
 }
 
+func TestManager_RenameSessionDir(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+
+	// Nothing to move for a connection that was never opened
+	if err := manager.RenameSessionDir("never-used", "other"); err != nil {
+		t.Errorf("Expected no error without session data, got %v", err)
+	}
+
+	if err := manager.EnsureSessionDir("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.RenameSessionDir("prod", "production"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(manager.GetSessionDir("production"), "session.yaml")); err != nil {
+		t.Errorf("Expected the session data under the new name: %v", err)
+	}
+	if _, err := os.Stat(manager.GetSessionDir("prod")); !os.IsNotExist(err) {
+		t.Error("Expected the old session directory to be gone")
+	}
+
+	if err := manager.EnsureSessionDir("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if err := manager.RenameSessionDir("staging", "production"); err == nil {
+		t.Error("Expected renaming onto existing session data to fail")
+	}
+}
+
 func TestManager_EnsureSessionDir_CreatesConfig(t *testing.T) {
 	tmpDir := t.TempDir()
 	manager := createTestManager(t, tmpDir)
@@ -359,3 +388,40 @@ func BenchmarkManager_getSessionConfig(b *testing.B) {
 		manager.getSessionConfig("bench-connection")
 	}
 }
+
+func TestManager_Recovery(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+
+	if state, err := manager.LoadRecovery(); err != nil || state != nil {
+		t.Fatalf("Expected no recovery state before a crash, got %+v, %v", state, err)
+	}
+
+	saved := &RecoveryState{
+		Connection:    "prod",
+		Input:         "/exec SELECT * FROM orders WHERE",
+		InTransaction: true,
+		Conversation:  []byte(`{"original_query":"top customers"}`),
+		Panic:         "runtime error: index out of range",
+		SavedAt:       time.Now(),
+	}
+	if err := manager.SaveRecovery(saved); err != nil {
+		t.Fatal(err)
+	}
+	state, err := manager.LoadRecovery()
+	if err != nil || state == nil {
+		t.Fatalf("Expected the saved state, got %v", err)
+	}
+	if state.Input != saved.Input || !state.InTransaction || string(state.Conversation) != string(saved.Conversation) {
+		t.Errorf("Expected the state as saved, got %+v", state)
+	}
+
+	if err := manager.ClearRecovery(); err != nil {
+		t.Fatal(err)
+	}
+	if state, _ := manager.LoadRecovery(); state != nil {
+		t.Errorf("Expected the state to be gone once cleared, got %+v", state)
+	}
+	if err := manager.ClearRecovery(); err != nil {
+		t.Errorf("Expected clearing twice to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// QueryLogEntry is one executed statement
+type QueryLogEntry struct {
+	ID        int64
+	Query     string
+	StartedAt time.Time
+	Duration  time.Duration
+	Rows      int
+	Error     string
+}
+
+// QueryLog records executed statements in the per-connection session database
+type QueryLog struct {
+	db        *sql.DB
+	sessionID string
+}
+
+// OpenQueryLog opens (creating if needed) the query log in the connection's session directory.
+// Entries recorded through the returned log are grouped under a new session ID.
+func (m *Manager) OpenQueryLog(connectionName string) (*QueryLog, error) {
+	if err := m.EnsureSessionDir(connectionName); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", filepath.Join(m.GetSessionDir(connectionName), "session.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS query_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id TEXT NOT NULL,
+			query TEXT NOT NULL,
+			started_at DATETIME NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			row_count INTEGER NOT NULL,
+			error TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_query_log_duration ON query_log(session_id, duration_ms DESC);
+	`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize query log: %w", err)
+	}
+
+	return &QueryLog{
+		db:        db,
+		sessionID: strconv.FormatInt(time.Now().UnixNano(), 36),
+	}, nil
+}
+
+// Record appends an entry to the log
+func (l *QueryLog) Record(entry QueryLogEntry) error {
+	_, err := l.db.Exec(
+		"INSERT INTO query_log (session_id, query, started_at, duration_ms, row_count, error) VALUES (?, ?, ?, ?, ?, ?)",
+		l.sessionID, entry.Query, entry.StartedAt, entry.Duration.Milliseconds(), entry.Rows, entry.Error,
+	)
+	return err
+}
+
+// Slowest returns the slowest statements, from the current session only unless allSessions is set
+func (l *QueryLog) Slowest(limit int, allSessions bool) ([]QueryLogEntry, error) {
+	query := "SELECT id, query, started_at, duration_ms, row_count, error FROM query_log"
+	args := []any{}
+	if !allSessions {
+		query += " WHERE session_id = ?"
+		args = append(args, l.sessionID)
+	}
+	query += " ORDER BY duration_ms DESC, id LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []QueryLogEntry
+	for rows.Next() {
+		var entry QueryLogEntry
+		var durationMs int64
+		if err := rows.Scan(&entry.ID, &entry.Query, &entry.StartedAt, &durationMs, &entry.Rows, &entry.Error); err != nil {
+			return nil, err
+		}
+		entry.Duration = time.Duration(durationMs) * time.Millisecond
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// Succeeded returns the statements that ran without error in the order they ran, from
+// the current session only unless allSessions is set
+func (l *QueryLog) Succeeded(allSessions bool) ([]string, error) {
+	query := "SELECT query FROM query_log WHERE error = ''"
+	args := []any{}
+	if !allSessions {
+		query += " AND session_id = ?"
+		args = append(args, l.sessionID)
+	}
+	query += " ORDER BY id"
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var queries []string
+	for rows.Next() {
+		var query string
+		if err := rows.Scan(&query); err != nil {
+			return nil, err
+		}
+		queries = append(queries, query)
+	}
+	return queries, rows.Err()
+}
+
+// Close closes the session database
+func (l *QueryLog) Close() error {
+	return l.db.Close()
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryLog_Slowest(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+
+	previous, err := manager.OpenQueryLog("test_conn")
+	if err != nil {
+		t.Fatalf("Failed to open query log: %v", err)
+	}
+	if err := previous.Record(QueryLogEntry{Query: "SELECT old", StartedAt: time.Now(), Duration: time.Hour}); err != nil {
+		t.Fatalf("Failed to record: %v", err)
+	}
+	previous.Close()
+
+	log, err := manager.OpenQueryLog("test_conn")
+	if err != nil {
+		t.Fatalf("Failed to reopen query log: %v", err)
+	}
+	defer log.Close()
+
+	entries := []QueryLogEntry{
+		{Query: "SELECT 1", Duration: 5 * time.Millisecond, Rows: 1},
+		{Query: "SELECT * FROM big", Duration: 2 * time.Second, Rows: 100000},
+		{Query: "SELECT broken", Duration: 300 * time.Millisecond, Error: "syntax error"},
+	}
+	for _, entry := range entries {
+		entry.StartedAt = time.Now()
+		if err := log.Record(entry); err != nil {
+			t.Fatalf("Failed to record: %v", err)
+		}
+	}
+
+	slowest, err := log.Slowest(2, false)
+	if err != nil {
+		t.Fatalf("Failed to read slowest: %v", err)
+	}
+	if len(slowest) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(slowest))
+	}
+	if slowest[0].Query != "SELECT * FROM big" || slowest[0].Rows != 100000 || slowest[0].Duration != 2*time.Second {
+		t.Errorf("Unexpected slowest entry: %+v", slowest[0])
+	}
+	if slowest[1].Error != "syntax error" {
+		t.Errorf("Expected error to be kept, got %+v", slowest[1])
+	}
+
+	all, err := log.Slowest(10, true)
+	if err != nil {
+		t.Fatalf("Failed to read all sessions: %v", err)
+	}
+	if len(all) != 4 || all[0].Query != "SELECT old" {
+		t.Errorf("Expected earlier sessions to be included with --all, got %+v", all)
+	}
+
+	succeeded, err := log.Succeeded(false)
+	if err != nil {
+		t.Fatalf("Failed to read succeeded: %v", err)
+	}
+	if len(succeeded) != 2 || succeeded[0] != "SELECT 1" || succeeded[1] != "SELECT * FROM big" {
+		t.Errorf("Expected this session's successful statements in order, got %v", succeeded)
+	}
+}
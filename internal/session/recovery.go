@@ -0,0 +1,66 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// RecoveryState is what the REPL was in the middle of when it crashed, saved so the
+// next launch can offer it back
+type RecoveryState struct {
+	Connection    string          `json:"connection,omitempty"`
+	Input         string          `json:"input,omitempty"` // Line being run, or the multi-line /exec typed so far
+	InTransaction bool            `json:"in_transaction,omitempty"`
+	Conversation  json.RawMessage `json:"conversation,omitempty"` // The AI conversation context
+	Panic         string          `json:"panic"`
+	Stack         string          `json:"stack,omitempty"`
+	SavedAt       time.Time       `json:"saved_at"`
+}
+
+// RecoveryPath returns where crash state is kept; it is shared by all connections
+// because the next launch may not connect to the same database
+func (m *Manager) RecoveryPath() string {
+	return filepath.Join(m.dataDir, "sessions", "recovery.json")
+}
+
+// SaveRecovery writes crash state, replacing any saved before
+func (m *Manager) SaveRecovery(state *RecoveryState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal recovery state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.RecoveryPath()), 0755); err != nil {
+		return err
+	}
+	// The input and conversation can hold data from the database
+	return os.WriteFile(m.RecoveryPath(), data, 0600)
+}
+
+// LoadRecovery returns the state saved by a crash, or nil when the last run ended normally
+func (m *Manager) LoadRecovery() (*RecoveryState, error) {
+	data, err := os.ReadFile(m.RecoveryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state RecoveryState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse recovery state: %w", err)
+	}
+	return &state, nil
+}
+
+// ClearRecovery removes saved crash state once it has been offered
+func (m *Manager) ClearRecovery() error {
+	err := os.Remove(m.RecoveryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// SchemaSnapshotDir holds a connection's schema snapshots, one JSON file per version
+// named <version>-<date>-<time>.json
+const SchemaSnapshotDir = "schema"
+
+// schemaSnapshotTime is the layout of the time in a snapshot's file name
+const schemaSnapshotTime = "20060102-150405"
+
+// SchemaSnapshotFile is a saved schema snapshot, as its file name describes it
+type SchemaSnapshotFile struct {
+	Version int
+	Taken   time.Time
+	Path    string
+}
+
+// SaveSchemaSnapshot saves a snapshot of its connection's schema as the next version,
+// which it sets on the snapshot, returning the file written
+func (m *Manager) SaveSchemaSnapshot(snapshot *core.SchemaSnapshot) (string, error) {
+	files, err := m.ListSchemaSnapshots(snapshot.Connection)
+	if err != nil {
+		return "", err
+	}
+	snapshot.Version = 1
+	if len(files) > 0 {
+		snapshot.Version = files[len(files)-1].Version + 1
+	}
+
+	dir := filepath.Join(m.GetSessionDir(snapshot.Connection), SchemaSnapshotDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%04d-%s.json", snapshot.Version, snapshot.Taken.Local().Format(schemaSnapshotTime)))
+	return path, os.WriteFile(path, data, 0644)
+}
+
+// ListSchemaSnapshots lists the schema snapshots saved for a connection, oldest first
+func (m *Manager) ListSchemaSnapshots(connectionName string) ([]SchemaSnapshotFile, error) {
+	dir := filepath.Join(m.GetSessionDir(connectionName), SchemaSnapshotDir)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []SchemaSnapshotFile
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		version, taken, ok := strings.Cut(name, "-")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(version)
+		if err != nil {
+			continue
+		}
+		at, err := time.ParseInLocation(schemaSnapshotTime, taken, time.Local)
+		if err != nil {
+			continue
+		}
+		files = append(files, SchemaSnapshotFile{Version: n, Taken: at, Path: filepath.Join(dir, entry.Name())})
+	}
+	slices.SortFunc(files, func(a, b SchemaSnapshotFile) int { return a.Version - b.Version })
+	return files, nil
+}
+
+// LoadSchemaSnapshot reads a saved schema snapshot
+func LoadSchemaSnapshot(path string) (*core.SchemaSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot core.SchemaSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return &snapshot, nil
+}
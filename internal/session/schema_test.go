@@ -0,0 +1,43 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+func TestSchemaSnapshots(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	if files, err := manager.ListSchemaSnapshots("dev"); err != nil || files != nil {
+		t.Fatalf("Expected no snapshots, got %v, %v", files, err)
+	}
+
+	taken := time.Date(2024, 3, 1, 9, 30, 0, 0, time.Local)
+	for _, table := range []string{"users", "orders"} {
+		snapshot := &core.SchemaSnapshot{
+			Connection: "dev",
+			Taken:      taken,
+			Tables:     map[string]*core.TableInfo{table: {Name: table, Columns: []core.ColumnInfo{{Name: "id", Type: "INTEGER"}}}},
+		}
+		if _, err := manager.SaveSchemaSnapshot(snapshot); err != nil {
+			t.Fatalf("Failed to save snapshot: %v", err)
+		}
+		taken = taken.Add(time.Hour)
+	}
+
+	files, err := manager.ListSchemaSnapshots("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[1].Version != 2 || !files[1].Taken.Equal(taken.Add(-time.Hour)) {
+		t.Fatalf("Unexpected snapshots %+v", files)
+	}
+	snapshot, err := LoadSchemaSnapshot(files[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Version != 2 || snapshot.Tables["orders"] == nil || snapshot.Tables["orders"].Columns[0].Type != "INTEGER" {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+}
@@ -0,0 +1,93 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sqlterm/internal/core"
+)
+
+// SummaryFile holds one line of JSON per finished session of a connection
+const SummaryFile = "summaries.jsonl"
+
+// SessionStats counts what a session did on its connection, for /status and the summary
+// written when it ends
+type SessionStats struct {
+	Connection    string         `json:"connection"`
+	User          string         `json:"user,omitempty"`
+	Started       time.Time      `json:"started"`
+	Ended         time.Time      `json:"ended"`
+	Statements    map[string]int `json:"statements"` // By StatementType
+	Failed        int            `json:"failed"`
+	RowsFetched   int64          `json:"rows_fetched"`
+	Exports       int            `json:"exports"`
+	ExportedRows  int64          `json:"exported_rows"`
+	ExportedBytes int64          `json:"exported_bytes"` // Of exports to local files; other targets are not sized
+}
+
+// NewSessionStats starts counting a session on a connection
+func NewSessionStats(connection, user string, started time.Time) *SessionStats {
+	return &SessionStats{Connection: connection, User: user, Started: started, Statements: map[string]int{}}
+}
+
+// StatementType names a statement for the statistics: its keyword for reads and row
+// changes, such as SELECT or INSERT, and its class for the rest, such as DDL
+func StatementType(query string) string {
+	class := core.ClassifyStatement(query)
+	if class == core.StatementRead || class == core.StatementDML {
+		if keyword := core.LeadingKeyword(query); keyword != "" {
+			return keyword
+		}
+	}
+	return strings.ToUpper(class)
+}
+
+// RecordStatement counts an executed statement and, for reads, the rows it returned
+func (s *SessionStats) RecordStatement(query string, rows int, err error) {
+	if err != nil {
+		s.Failed++
+		return
+	}
+	s.Statements[StatementType(query)]++
+	if core.ClassifyStatement(query) == core.StatementRead {
+		s.RowsFetched += int64(rows)
+	}
+}
+
+// RecordExport counts rows written to an export target; size is 0 when unknown
+func (s *SessionStats) RecordExport(rows int, size int64) {
+	s.Exports++
+	s.ExportedRows += int64(rows)
+	s.ExportedBytes += size
+}
+
+// Total returns how many statements ran without error
+func (s *SessionStats) Total() int {
+	total := 0
+	for _, n := range s.Statements {
+		total += n
+	}
+	return total
+}
+
+// WriteSessionSummary appends a finished session's statistics to the connection's
+// summaries, next to its query log
+func (m *Manager) WriteSessionSummary(stats *SessionStats) error {
+	if err := m.EnsureSessionDir(stats.Connection); err != nil {
+		return err
+	}
+	data, err := json.Marshal(stats)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filepath.Join(m.GetSessionDir(stats.Connection), SummaryFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
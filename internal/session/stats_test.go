@@ -0,0 +1,62 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionStats(t *testing.T) {
+	manager := createTestManager(t, t.TempDir())
+	started := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	stats := NewSessionStats("prod", "alice", started)
+
+	stats.RecordStatement("SELECT * FROM users", 10, nil)
+	stats.RecordStatement("with t as (select 1) select * from t", 1, nil)
+	stats.RecordStatement("UPDATE users SET active = 0", 3, nil)
+	stats.RecordStatement("ALTER TABLE users ADD COLUMN note TEXT", 0, nil)
+	stats.RecordStatement("SELECT broken", 0, errors.New("syntax error"))
+	stats.RecordExport(10, 512)
+
+	want := map[string]int{"SELECT": 1, "WITH": 1, "UPDATE": 1, "DDL": 1}
+	for statementType, n := range want {
+		if stats.Statements[statementType] != n {
+			t.Errorf("Expected %d %s, got %v", n, statementType, stats.Statements)
+		}
+	}
+	if stats.Total() != 4 || stats.Failed != 1 {
+		t.Errorf("Expected 4 statements and 1 failure, got %d and %d", stats.Total(), stats.Failed)
+	}
+	if stats.RowsFetched != 11 {
+		t.Errorf("Expected only reads to count as fetched rows, got %d", stats.RowsFetched)
+	}
+	if stats.Exports != 1 || stats.ExportedRows != 10 || stats.ExportedBytes != 512 {
+		t.Errorf("Unexpected export totals %+v", stats)
+	}
+
+	stats.Ended = started.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := manager.WriteSessionSummary(stats); err != nil {
+			t.Fatalf("Failed to write summary: %v", err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(manager.GetSessionDir("prod"), SummaryFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per session, got %q", data)
+	}
+	var written SessionStats
+	if err := json.Unmarshal([]byte(lines[0]), &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.User != "alice" || written.Statements["UPDATE"] != 1 || !written.Ended.Equal(stats.Ended) {
+		t.Errorf("Unexpected summary %+v", written)
+	}
+}